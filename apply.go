@@ -0,0 +1,162 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var applyUsageStr = `
+Usage: memphis-broker apply [options]
+
+Apply Options:
+    -f, --file <file>                Manifest file (yaml/json) describing the desired resources
+        --url <url>                  Memphis REST API address (default: http://localhost:9000)
+        --token <token>              JWT to authenticate with (default: $MEMPHIS_TOKEN)
+        --user <user>                Username to login with in case no token has been provided
+        --pass <password>            Password to login with in case no token has been provided
+        --totp-code <code>           Two factor authentication code of the user (default: $MEMPHIS_TOTP_CODE)
+        --dry-run                    Only print the changes and drift, do not apply them
+        --prune                      Remove resources which are not part of the manifest
+`
+
+func applyUsage() {
+	fmt.Printf("%s\n", applyUsageStr)
+	os.Exit(0)
+}
+
+func postJson(url, token string, body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		var errResp struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = resp.Status
+		}
+		return nil, errors.New(errResp.Message)
+	}
+	return respBody, nil
+}
+
+// getAuthToken returns the given token or logs in with the given credentials in case there is none,
+// users with two factor authentication enabled have to provide a current code as well
+func getAuthToken(url, token, username, password, totpCode string) (string, error) {
+	if token != "" {
+		return token, nil
+	}
+	if username == "" || password == "" {
+		return "", errors.New("either a token or a user and password have to be provided")
+	}
+	body := map[string]string{"username": username, "password": password}
+	if totpCode != "" {
+		body["totp_code"] = totpCode
+	}
+	resp, err := postJson(url+"/api/usermgmt/login", "", body)
+	if err != nil {
+		if totpCode == "" && strings.Contains(err.Error(), "Two factor authentication") {
+			return "", errors.New("login failed: " + err.Error() + ", provide it using --totp-code")
+		}
+		return "", errors.New("login failed: " + err.Error())
+	}
+	var loginResp struct {
+		Jwt                    string `json:"jwt"`
+		TotpEnrollmentRequired bool   `json:"totp_enrollment_required"`
+	}
+	if err = json.Unmarshal(resp, &loginResp); err != nil {
+		return "", err
+	}
+	if loginResp.TotpEnrollmentRequired {
+		return "", errors.New("login failed: two factor authentication is enforced, enroll the user through the dashboard first")
+	}
+	return loginResp.Jwt, nil
+}
+
+// runApply sends a manifest file to the apply endpoint of a running broker
+func runApply(args []string) error {
+	var file, url, token, username, password, totpCode string
+	var dryRun, prune bool
+
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	fs.Usage = applyUsage
+	fs.StringVar(&file, "f", "", "Manifest file.")
+	fs.StringVar(&file, "file", "", "Manifest file.")
+	fs.StringVar(&url, "url", "http://localhost:9000", "Memphis REST API address.")
+	fs.StringVar(&token, "token", os.Getenv("MEMPHIS_TOKEN"), "JWT to authenticate with.")
+	fs.StringVar(&username, "user", "", "Username to login with.")
+	fs.StringVar(&password, "pass", "", "Password to login with.")
+	fs.StringVar(&totpCode, "totp-code", os.Getenv("MEMPHIS_TOTP_CODE"), "Two factor authentication code.")
+	fs.BoolVar(&dryRun, "dry-run", false, "Only print the changes and drift.")
+	fs.BoolVar(&prune, "prune", false, "Remove resources which are not part of the manifest.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if file == "" {
+		return errors.New("a manifest file has to be provided using -f")
+	}
+	url = strings.TrimSuffix(url, "/")
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	token, err = getAuthToken(url, token, username, password, totpCode)
+	if err != nil {
+		return err
+	}
+
+	resp, err := postJson(url+"/api/manifests/apply", token, map[string]interface{}{
+		"manifest": string(content),
+		"dry_run":  dryRun,
+		"prune":    prune,
+	})
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err = json.Indent(&out, resp, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var backupUsageStr = `
+Usage: memphis-broker backup|restore [options]
+
+Backup/Restore Options:
+    -f, --file <file>                Archive to write the backup into / to restore from
+        --url <url>                  Memphis REST API address (default: http://localhost:9000)
+        --token <token>              JWT of the root user (default: $MEMPHIS_TOKEN)
+        --user <user>                Username to login with in case no token has been provided
+        --pass <password>            Password to login with in case no token has been provided
+        --totp-code <code>           Two factor authentication code of the user (default: $MEMPHIS_TOTP_CODE)
+`
+
+func backupUsage() {
+	fmt.Printf("%s\n", backupUsageStr)
+	os.Exit(0)
+}
+
+func parseBackupFlags(name string, args []string) (file, url, token string, err error) {
+	var username, password, totpCode string
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = backupUsage
+	fs.StringVar(&file, "f", "", "Archive file.")
+	fs.StringVar(&file, "file", "", "Archive file.")
+	fs.StringVar(&url, "url", "http://localhost:9000", "Memphis REST API address.")
+	fs.StringVar(&token, "token", os.Getenv("MEMPHIS_TOKEN"), "JWT to authenticate with.")
+	fs.StringVar(&username, "user", "", "Username to login with.")
+	fs.StringVar(&password, "pass", "", "Password to login with.")
+	fs.StringVar(&totpCode, "totp-code", os.Getenv("MEMPHIS_TOTP_CODE"), "Two factor authentication code.")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if file == "" {
+		err = errors.New("an archive file has to be provided using -f")
+		return
+	}
+	url = strings.TrimSuffix(url, "/")
+	token, err = getAuthToken(url, token, username, password, totpCode)
+	return
+}
+
+func readErrorResponse(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &errResp)
+	if errResp.Message == "" {
+		errResp.Message = resp.Status
+	}
+	return errors.New(errResp.Message)
+}
+
+// runBackup downloads a backup archive from a running broker
+func runBackup(args []string) error {
+	file, url, token, err := parseBackupFlags("backup", args)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url+"/api/backup/createBackup", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return readErrorResponse(resp)
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	n, err := io.Copy(out, resp.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup of %d bytes has been written to %s\n", n, file)
+	return nil
+}
+
+// runRestore uploads a backup archive into a running broker
+func runRestore(args []string) error {
+	file, url, token, err := parseBackupFlags("restore", args)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// stream the archive instead of loading it into memory
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", file)
+		if err == nil {
+			_, err = io.Copy(part, in)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, url+"/api/backup/restoreBackup", pr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return readErrorResponse(resp)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(body))
+	return nil
+}
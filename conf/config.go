@@ -22,6 +22,7 @@ type Configuration struct {
 	MEMPHIS_VERSION                string
 	DEV_ENV                        string
 	HTTP_PORT                      string
+	SCHEMA_REGISTRY_PORT           string
 	WS_PORT                        int
 	WS_TLS                         bool
 	WS_TOKEN                       string
@@ -55,6 +56,7 @@ type Configuration struct {
 	SANDBOX_SLACK_BOT_TOKEN        string
 	SANDBOX_SLACK_CHANNEL_ID       string
 	SANDBOX_UI_URL                 string
+	STATION_KEYS_FILE              string
 }
 
 func GetConfig() Configuration {
@@ -21,13 +21,25 @@ func InitializeHttpServer(s *server.Server) {
 	configuration := conf.GetConfig()
 
 	handlers := server.Handlers{
-		Producers:  server.ProducersHandler{S: s},
-		Consumers:  server.ConsumersHandler{S: s},
-		AuditLogs:  server.AuditLogsHandler{},
-		Stations:   server.StationsHandler{S: s},
-		Monitoring: server.MonitoringHandler{S: s},
-		PoisonMsgs: server.PoisonMessagesHandler{S: s},
-		Schemas:    server.SchemasHandler{S: s},
+		Producers:      server.ProducersHandler{S: s},
+		Consumers:      server.ConsumersHandler{S: s},
+		AuditLogs:      server.AuditLogsHandler{},
+		Stations:       server.StationsHandler{S: s},
+		Monitoring:     server.MonitoringHandler{S: s},
+		PoisonMsgs:     server.PoisonMessagesHandler{S: s},
+		Schemas:        server.SchemasHandler{S: s},
+		Manifests:      server.ManifestsHandler{S: s},
+		Backup:         server.BackupHandler{S: s},
+		Tenants:        server.TenantsHandler{S: s},
+		SchemaRegistry: server.SchemaRegistryHandler{S: s},
+		Connections:    server.ConnectionsHandler{S: s},
+		Kv:             server.KvHandler{S: s},
+		Usage:          server.UsageHandler{S: s},
+	}
+
+	if configuration.SCHEMA_REGISTRY_PORT != "" {
+		schemaRegistryServer := routes.InitializeSchemaRegistryRoutes(&handlers)
+		go schemaRegistryServer.Run("0.0.0.0:" + configuration.SCHEMA_REGISTRY_PORT)
 	}
 
 	httpServer := routes.InitializeHttpRoutes(&handlers)
@@ -25,4 +25,5 @@ func InitializeMonitoringRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	monitoringRoutes.GET("/getStationOverviewData", monitoringHandler.GetStationOverviewData)
 	monitoringRoutes.GET("/getSystemLogs", monitoringHandler.GetSystemLogs)
 	monitoringRoutes.GET("/downloadSystemLogs", monitoringHandler.DownloadSystemLogs)
+	monitoringRoutes.GET("/getRateLimitViolations", monitoringHandler.GetRateLimitViolations)
 }
@@ -44,12 +44,18 @@ func InitializeHttpRoutes(handlers *server.Handlers) *gin.Engine {
 	InitializeStationsRoutes(mainRouter, handlers)
 	InitializeProducersRoutes(mainRouter, handlers)
 	InitializeConsumersRoutes(mainRouter, handlers)
+	InitializeConnectionsRoutes(mainRouter, handlers)
 	InitializeMonitoringRoutes(mainRouter, handlers)
 	InitializeTagsRoutes(mainRouter, handlers)
 	InitializeSchemasRoutes(mainRouter, handlers)
 	InitializeSandboxRoutes(mainRouter)
 	InitializeIntegrationsRoutes(mainRouter, handlers)
 	InitializeConfigurationsRoutes(mainRouter, handlers)
+	InitializeManifestsRoutes(mainRouter, handlers)
+	InitializeBackupRoutes(mainRouter, handlers)
+	InitializeTenantsRoutes(mainRouter, handlers)
+	InitializeKvRoutes(mainRouter, handlers)
+	InitializeUsageRoutes(mainRouter, handlers)
 	ui.InitializeUIRoutes(router)
 
 	mainRouter.GET("/status", func(c *gin.Context) {
@@ -25,6 +25,8 @@ func InitializeStationsRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	stationsRoutes.GET("/getAllStations", stationsHandler.GetAllStations)
 	stationsRoutes.GET("/getStations", stationsHandler.GetStations)
 	stationsRoutes.GET("/getPoisonMessageJourney", stationsHandler.GetPoisonMessageJourney)
+	stationsRoutes.GET("/getMessageJourney", stationsHandler.GetMessageJourney)
+	stationsRoutes.GET("/getStationMetrics", stationsHandler.GetStationMetrics)
 	stationsRoutes.POST("/createStation", stationsHandler.CreateStation)
 	stationsRoutes.POST("/resendPoisonMessages", stationsHandler.ResendPoisonMessages)
 	stationsRoutes.DELETE("/removeStation", stationsHandler.RemoveStation)
@@ -33,5 +35,10 @@ func InitializeStationsRoutes(router *gin.RouterGroup, h *server.Handlers) {
 	stationsRoutes.GET("/getUpdatesForSchemaByStation", stationsHandler.GetUpdatesForSchemaByStation)
 	stationsRoutes.GET("/tierdStorageClicked", stationsHandler.TierdStorageClicked) // TODO to be deleted
 	stationsRoutes.PUT("/updateDlsConfig", stationsHandler.UpdateDlsConfig)
+	stationsRoutes.PUT("/updateRateLimits", stationsHandler.UpdateRateLimits)
+	stationsRoutes.PUT("/promoteMirror", stationsHandler.PromoteMirror)
+	stationsRoutes.PUT("/updateSources", stationsHandler.UpdateSources)
+	stationsRoutes.POST("/rotateEncryptionKey", stationsHandler.RotateStationKey)
+	stationsRoutes.POST("/shredStation", stationsHandler.ShredStation)
 	stationsRoutes.POST("/dropDlsMessages", stationsHandler.DropDlsMessages)
 }
@@ -38,4 +38,12 @@ func InitializeUserMgmtRoutes(router *gin.RouterGroup) {
 	userMgmtRoutes.POST("/skipGetStarted", userMgmtHandler.SkipGetStarted)
 	userMgmtRoutes.GET("/getFilterDetails", userMgmtHandler.GetFilterDetails)
 	userMgmtRoutes.PUT("/changePassword", userMgmtHandler.ChangePassword)
+	userMgmtRoutes.PUT("/updateUserRateLimits", userMgmtHandler.UpdateUserRateLimits)
+	userMgmtRoutes.POST("/enrollTotp", userMgmtHandler.EnrollTotp)
+	userMgmtRoutes.POST("/verifyTotp", userMgmtHandler.VerifyTotp)
+	userMgmtRoutes.POST("/regenerateTotpRecoveryCodes", userMgmtHandler.RegenerateTotpRecoveryCodes)
+	userMgmtRoutes.POST("/disableTotp", userMgmtHandler.DisableTotp)
+	userMgmtRoutes.PUT("/resetUserTotp", userMgmtHandler.ResetUserTotp)
+	userMgmtRoutes.PUT("/enforceTotp", userMgmtHandler.EnforceTotp)
+	userMgmtRoutes.GET("/getTotpEnforcement", userMgmtHandler.GetTotpEnforcement)
 }
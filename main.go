@@ -79,6 +79,12 @@ Cluster Options:
         --cluster_advertise <string> Cluster URL to advertise to other servers
         --connect_retries <number>   For implicit routes, number of connect retries
 
+Subcommands:
+    apply                            Apply a manifest of stations, schemas, tags, users and integrations
+                                     (run "apply -h" for its options)
+    backup                           Download a backup archive of the metadata and the stations' streams
+    restore                          Restore a backup archive into a fresh deployment
+
 Common Options:
     -h, --help                       Show this message
     -v, --version                    Show version
@@ -133,6 +139,8 @@ func runMemphis(s *server.Server) db.DbInstance {
 
 	// run only on the leader
 	go s.KillZombieResources()
+	go s.SampleStationsMetrics()
+	go s.ScheduleUsageReports()
 
 	// For backward compatibility
 	err = s.AlignOldStations()
@@ -155,6 +163,24 @@ func runMemphis(s *server.Server) db.DbInstance {
 func main() {
 	exe := "nats-server"
 
+	if len(os.Args) > 1 {
+		var cmd func([]string) error
+		switch os.Args[1] {
+		case "apply":
+			cmd = runApply
+		case "backup":
+			cmd = runBackup
+		case "restore":
+			cmd = runRestore
+		}
+		if cmd != nil {
+			if err := cmd(os.Args[2:]); err != nil {
+				server.PrintAndDie(fmt.Sprintf("%s: %s", os.Args[1], err))
+			}
+			os.Exit(0)
+		}
+	}
+
 	// Create a FlagSet and sets the usage
 	fs := flag.NewFlagSet(exe, flag.ExitOnError)
 	fs.Usage = usage
@@ -38,6 +38,12 @@ var noNeedAuthRoutes = []string{
 
 var refreshTokenRoute string = "/api/usermgmt/refreshtoken"
 
+// routes a user can reach while the enforced 2FA enrollment is still pending
+var totpEnrollmentRoutes = []string{
+	"/api/usermgmt/enrolltotp",
+	"/api/usermgmt/verifytotp",
+}
+
 var configuration = conf.GetConfig()
 
 func isAuthNeeded(path string) bool {
@@ -50,6 +56,16 @@ func isAuthNeeded(path string) bool {
 	return true
 }
 
+func isTotpEnrollmentRoute(path string) bool {
+	for _, route := range totpEnrollmentRoutes {
+		if route == path {
+			return true
+		}
+	}
+
+	return false
+}
+
 func extractToken(authHeader string) (string, error) {
 	if authHeader == "" {
 		return "", errors.New("unsupported auth header")
@@ -82,13 +98,19 @@ func verifyToken(tokenString string, secret string) (models.User, error) {
 
 	userId, _ := primitive.ObjectIDFromHex(claims["user_id"].(string))
 	creationDate, _ := time.Parse("2006-01-02T15:04:05.000Z", claims["creation_date"].(string))
+	tenantName, _ := claims["tenant_name"].(string)
+	totpVerified, _ := claims["totp_verified"].(bool)
+	totpEnrollmentRequired, _ := claims["totp_enrollment_required"].(bool)
 	user := models.User{
-		ID:              userId,
-		Username:        claims["username"].(string),
-		UserType:        claims["user_type"].(string),
-		CreationDate:    creationDate,
-		AlreadyLoggedIn: claims["already_logged_in"].(bool),
-		AvatarId:        int(claims["avatar_id"].(float64)),
+		ID:                     userId,
+		Username:               claims["username"].(string),
+		UserType:               claims["user_type"].(string),
+		CreationDate:           creationDate,
+		AlreadyLoggedIn:        claims["already_logged_in"].(bool),
+		AvatarId:               int(claims["avatar_id"].(float64)),
+		TenantName:             tenantName,
+		TotpVerified:           totpVerified,
+		TotpEnrollmentRequired: totpEnrollmentRequired,
 	}
 
 	return user, nil
@@ -112,6 +134,10 @@ func Authenticate(c *gin.Context) {
 			c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
 			return
 		}
+		if user.TotpEnrollmentRequired && !isTotpEnrollmentRoute(path) {
+			c.AbortWithStatusJSON(403, gin.H{"message": "Two factor authentication enrollment is required", "totp_enrollment_required": true})
+			return
+		}
 
 		c.Set("user", user)
 	} else if path == refreshTokenRoute {
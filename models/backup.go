@@ -0,0 +1,42 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package models
+
+import "time"
+
+type BackupInfo struct {
+	Version      string         `json:"version"`
+	CreationDate time.Time      `json:"creation_date"`
+	CreatedBy    string         `json:"created_by"`
+	Collections  []string       `json:"collections"`
+	Streams      []BackupStream `json:"streams"`
+}
+
+type BackupStream struct {
+	Name         string `json:"name"`
+	StationName  string `json:"station_name,omitempty"`
+	KvBucketName string `json:"kv_bucket_name,omitempty"`
+	TenantName   string `json:"tenant_name"`
+	Messages     uint64 `json:"messages"`
+	Bytes        uint64 `json:"bytes"`
+}
+
+type BackupStationKey struct {
+	StationName string `json:"station_name"`
+	TenantName  string `json:"tenant_name"`
+	Key         []byte `json:"key"`
+}
+
+type RestoreBackupResponse struct {
+	Collections map[string]int `json:"collections"`
+	Streams     []BackupStream `json:"streams"`
+}
@@ -14,8 +14,11 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type EditClusterConfigSchema struct {
-	PMRetention   int `json:"pm_retention" binding:"required"`
-	LogsRetention int `json:"logs_retention" binding:"required"`
+	PMRetention        int        `json:"pm_retention" binding:"required"`
+	LogsRetention      int        `json:"logs_retention" binding:"required"`
+	ProducerRateLimits RateLimits `json:"producer_rate_limits"`
+	SuspectGraceSec    int        `json:"liveness_suspect_grace_sec"`
+	DisconnectGraceSec int        `json:"liveness_disconnect_grace_sec"`
 }
 
 type GlobalConfigurationsUpdate struct {
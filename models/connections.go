@@ -18,9 +18,44 @@ import (
 )
 
 type Connection struct {
-	ID            primitive.ObjectID `json:"id" bson:"_id"`
-	CreatedByUser string             `json:"created_by_user" bson:"created_by_user"`
-	IsActive      bool               `json:"is_active" bson:"is_active"`
-	CreationDate  time.Time          `json:"creation_date" bson:"creation_date"`
-	ClientAddress string             `json:"client_address" bson:"client_address"`
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedByUser  string             `json:"created_by_user" bson:"created_by_user"`
+	IsActive       bool               `json:"is_active" bson:"is_active"`
+	CreationDate   time.Time          `json:"creation_date" bson:"creation_date"`
+	ClientAddress  string             `json:"client_address" bson:"client_address"`
+	LastSeen       time.Time          `json:"last_seen" bson:"last_seen"`
+	State          string             `json:"state" bson:"state"`
+	TenantName     string             `json:"tenant_name" bson:"tenant_name"`
+	SdkLang        string             `json:"sdk_lang" bson:"sdk_lang"`
+	SdkVersion     string             `json:"sdk_version" bson:"sdk_version"`
+	ClientName     string             `json:"client_name" bson:"client_name"`
+	DisconnectedBy string             `json:"disconnected_by,omitempty" bson:"disconnected_by,omitempty"`
+}
+
+type ConnectionMetadata struct {
+	SdkLang    string
+	SdkVersion string
+	ClientName string
+}
+
+type ConnectionStats struct {
+	ConnectionId  string `json:"connection_id"`
+	Broker        string `json:"broker"`
+	RTT           string `json:"rtt"`
+	Subscriptions int    `json:"subscriptions"`
+	InMsgs        int64  `json:"in_msgs"`
+	OutMsgs       int64  `json:"out_msgs"`
+	InBytes       int64  `json:"in_bytes"`
+	OutBytes      int64  `json:"out_bytes"`
+}
+
+type ExtendedConnection struct {
+	Connection
+	Stats     *ConnectionStats   `json:"stats"`
+	Producers []ExtendedProducer `json:"producers"`
+	Consumers []ExtendedConsumer `json:"consumers"`
+}
+
+type DisconnectConnectionSchema struct {
+	ConnectionId string `json:"connection_id" binding:"required"`
 }
@@ -30,34 +30,71 @@ type Consumer struct {
 	CreationDate     time.Time          `json:"creation_date" bson:"creation_date"`
 	IsDeleted        bool               `json:"is_deleted" bson:"is_deleted"`
 	MaxMsgDeliveries int                `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
+	// RedeliveryBackoff is nil when the group redelivers on a flat max_ack_time_ms
+	RedeliveryBackoff *RedeliveryBackoff `json:"redelivery_backoff,omitempty" bson:"redelivery_backoff,omitempty"`
+	ContentFilter     *ContentFilter     `json:"content_filter,omitempty" bson:"content_filter,omitempty"`
+}
+
+// ContentFilter is evaluated by the broker before delivery, messages which do not match
+// are acked on behalf of the consumer group. Exactly one of Header or JsonPath is set.
+// Operator is one of eq/neq/contains/exists/not_exists/gt/gte/lt/lte.
+type ContentFilter struct {
+	Header   string `json:"header,omitempty" bson:"header,omitempty"`
+	JsonPath string `json:"json_path,omitempty" bson:"json_path,omitempty"`
+	Operator string `json:"operator" bson:"operator"`
+	Value    string `json:"value,omitempty" bson:"value,omitempty"`
+}
+
+type ContentFilterStats struct {
+	Matched uint64 `json:"matched" bson:"matched"`
+	Skipped uint64 `json:"skipped" bson:"skipped"`
+}
+
+// RedeliveryBackoff describes how the ack window grows between redeliveries.
+// Policy is either "exponential" (InitialDelayMs * Multiplier^n, capped at MaxDelayMs)
+// or "stepped" (an explicit StepsMs list, the last step repeats).
+type RedeliveryBackoff struct {
+	Policy         string  `json:"policy" bson:"policy"`
+	InitialDelayMs int64   `json:"initial_delay_ms,omitempty" bson:"initial_delay_ms,omitempty"`
+	Multiplier     float64 `json:"multiplier,omitempty" bson:"multiplier,omitempty"`
+	MaxDelayMs     int64   `json:"max_delay_ms,omitempty" bson:"max_delay_ms,omitempty"`
+	StepsMs        []int64 `json:"steps_ms,omitempty" bson:"steps_ms,omitempty"`
 }
 
 type ExtendedConsumer struct {
-	Name             string    `json:"name" bson:"name"`
-	CreatedByUser    string    `json:"created_by_user" bson:"created_by_user"`
-	CreationDate     time.Time `json:"creation_date" bson:"creation_date"`
-	IsActive         bool      `json:"is_active" bson:"is_active"`
-	IsDeleted        bool      `json:"is_deleted" bson:"is_deleted"`
-	ClientAddress    string    `json:"client_address" bson:"client_address"`
-	ConsumersGroup   string    `json:"consumers_group" bson:"consumers_group"`
-	MaxAckTimeMs     int64     `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
-	MaxMsgDeliveries int       `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
-	StationName      string    `json:"station_name" bson:"station_name"`
+	Name              string             `json:"name" bson:"name"`
+	CreatedByUser     string             `json:"created_by_user" bson:"created_by_user"`
+	CreationDate      time.Time          `json:"creation_date" bson:"creation_date"`
+	IsActive          bool               `json:"is_active" bson:"is_active"`
+	IsDeleted         bool               `json:"is_deleted" bson:"is_deleted"`
+	ClientAddress     string             `json:"client_address" bson:"client_address"`
+	ConsumersGroup    string             `json:"consumers_group" bson:"consumers_group"`
+	MaxAckTimeMs      int64              `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
+	MaxMsgDeliveries  int                `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
+	RedeliveryBackoff *RedeliveryBackoff `json:"redelivery_backoff,omitempty" bson:"redelivery_backoff,omitempty"`
+	ContentFilter     *ContentFilter     `json:"content_filter,omitempty" bson:"content_filter,omitempty"`
+	StationName       string             `json:"station_name" bson:"station_name"`
+	State             string             `json:"state" bson:"state"`
+	LastSeen          time.Time          `json:"last_seen" bson:"last_seen"`
 }
 
 type Cg struct {
-	Name                  string             `json:"name" bson:"name"`
-	UnprocessedMessages   int                `json:"unprocessed_messages" bson:"unprocessed_messages"`
-	PoisonMessages        int                `json:"poison_messages" bson:"poison_messages"`
-	IsActive              bool               `json:"is_active" bson:"is_active"`
-	IsDeleted             bool               `json:"is_deleted" bson:"is_deleted"`
-	InProcessMessages     int                `json:"in_process_messages" bson:"in_process_messages"`
-	MaxAckTimeMs          int64              `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
-	MaxMsgDeliveries      int                `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
-	ConnectedConsumers    []ExtendedConsumer `json:"connected_consumers" bson:"connected_consumers"`
-	DisconnectedConsumers []ExtendedConsumer `json:"disconnected_consumers" bson:"disconnected_consumers"`
-	DeletedConsumers      []ExtendedConsumer `json:"deleted_consumers" bson:"deleted_consumers"`
-	LastStatusChangeDate  time.Time          `json:"last_status_change_date" bson:"last_status_change_date"`
+	Name                  string              `json:"name" bson:"name"`
+	UnprocessedMessages   int                 `json:"unprocessed_messages" bson:"unprocessed_messages"`
+	PoisonMessages        int                 `json:"poison_messages" bson:"poison_messages"`
+	IsActive              bool                `json:"is_active" bson:"is_active"`
+	IsDeleted             bool                `json:"is_deleted" bson:"is_deleted"`
+	InProcessMessages     int                 `json:"in_process_messages" bson:"in_process_messages"`
+	MaxAckTimeMs          int64               `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
+	MaxMsgDeliveries      int                 `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
+	RedeliveryBackoff     *RedeliveryBackoff  `json:"redelivery_backoff,omitempty" bson:"redelivery_backoff,omitempty"`
+	RedeliveryScheduleMs  []int64             `json:"redelivery_schedule_ms,omitempty" bson:"redelivery_schedule_ms,omitempty"`
+	ContentFilter         *ContentFilter      `json:"content_filter,omitempty" bson:"content_filter,omitempty"`
+	ContentFilterStats    *ContentFilterStats `json:"content_filter_stats,omitempty" bson:"content_filter_stats,omitempty"`
+	ConnectedConsumers    []ExtendedConsumer  `json:"connected_consumers" bson:"connected_consumers"`
+	DisconnectedConsumers []ExtendedConsumer  `json:"disconnected_consumers" bson:"disconnected_consumers"`
+	DeletedConsumers      []ExtendedConsumer  `json:"deleted_consumers" bson:"deleted_consumers"`
+	LastStatusChangeDate  time.Time           `json:"last_status_change_date" bson:"last_status_change_date"`
 }
 
 type GetAllConsumersByStationSchema struct {
@@ -65,13 +102,15 @@ type GetAllConsumersByStationSchema struct {
 }
 
 type CreateConsumerSchema struct {
-	Name             string `json:"name" binding:"required"`
-	StationName      string `json:"station_name" binding:"required"`
-	ConnectionId     string `json:"connection_id" binding:"required"`
-	ConsumerType     string `json:"consumer_type" binding:"required"`
-	ConsumersGroup   string `json:"consumers_group"`
-	MaxAckTimeMs     int64  `json:"max_ack_time_ms"`
-	MaxMsgDeliveries int    `json:"max_msg_deliveries"`
+	Name              string             `json:"name" binding:"required"`
+	StationName       string             `json:"station_name" binding:"required"`
+	ConnectionId      string             `json:"connection_id" binding:"required"`
+	ConsumerType      string             `json:"consumer_type" binding:"required"`
+	ConsumersGroup    string             `json:"consumers_group"`
+	MaxAckTimeMs      int64              `json:"max_ack_time_ms"`
+	MaxMsgDeliveries  int                `json:"max_msg_deliveries"`
+	RedeliveryBackoff *RedeliveryBackoff `json:"redelivery_backoff"`
+	ContentFilter     *ContentFilter     `json:"content_filter"`
 }
 
 type DestroyConsumerSchema struct {
@@ -80,11 +119,13 @@ type DestroyConsumerSchema struct {
 }
 
 type CgMember struct {
-	Name             string `json:"name" bson:"name"`
-	ClientAddress    string `json:"client_address" bson:"client_address"`
-	IsActive         bool   `json:"is_active" bson:"is_active"`
-	IsDeleted        bool   `json:"is_deleted" bson:"is_deleted"`
-	CreatedByUser    string `json:"created_by_user" bson:"created_by_user"`
-	MaxMsgDeliveries int    `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
-	MaxAckTimeMs     int64  `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
+	Name             string    `json:"name" bson:"name"`
+	ClientAddress    string    `json:"client_address" bson:"client_address"`
+	IsActive         bool      `json:"is_active" bson:"is_active"`
+	IsDeleted        bool      `json:"is_deleted" bson:"is_deleted"`
+	CreatedByUser    string    `json:"created_by_user" bson:"created_by_user"`
+	MaxMsgDeliveries int       `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
+	MaxAckTimeMs     int64     `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
+	State            string    `json:"state" bson:"state"`
+	LastSeen         time.Time `json:"last_seen" bson:"last_seen"`
 }
@@ -32,31 +32,38 @@ type MsgHeader struct {
 }
 
 type MessagePayload struct {
-	TimeSent time.Time         `json:"time_sent"`
-	Size     int               `json:"size"`
-	Data     string            `json:"data"`
-	Headers  map[string]string `json:"headers"`
+	TimeSent    time.Time         `json:"time_sent"`
+	Size        int               `json:"size"`
+	Data        string            `json:"data"`
+	Headers     map[string]string `json:"headers"`
+	Chunked     bool              `json:"chunked"`
+	ChunksTotal int               `json:"chunks_total"`
 }
 
 type MessagePayloadDls struct {
-	TimeSent time.Time         `json:"time_sent"`
-	Size     int               `json:"size"`
-	Data     string            `json:"data"`
-	Headers  map[string]string `json:"headers"`
+	TimeSent    time.Time         `json:"time_sent"`
+	Size        int               `json:"size"`
+	Data        string            `json:"data"`
+	Headers     map[string]string `json:"headers"`
+	Chunked     bool              `json:"chunked"`
+	ChunksTotal int               `json:"chunks_total"`
 }
 
 type PoisonedCg struct {
-	CgName              string     `json:"cg_name" bson:"cg_name"`
-	PoisoningTime       time.Time  `json:"poisoning_time" bson:"poisoning_time"`
-	DeliveriesCount     int        `json:"deliveries_count" bson:"deliveries_count"`
-	UnprocessedMessages int        `json:"unprocessed_messages" bson:"unprocessed_messages"`
-	MaxAckTimeMs        int64      `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
-	InProcessMessages   int        `json:"in_process_messages" bson:"in_process_messages"`
-	TotalPoisonMessages int        `json:"total_poison_messages" bson:"total_poison_messages"`
-	MaxMsgDeliveries    int        `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
-	CgMembers           []CgMember `json:"cg_members" bson:"cg_members"`
-	IsActive            bool       `json:"is_active" bson:"is_active"`
-	IsDeleted           bool       `json:"is_deleted" bson:"is_deleted"`
+	CgName              string    `json:"cg_name" bson:"cg_name"`
+	PoisoningTime       time.Time `json:"poisoning_time" bson:"poisoning_time"`
+	DeliveriesCount     int       `json:"deliveries_count" bson:"deliveries_count"`
+	UnprocessedMessages int       `json:"unprocessed_messages" bson:"unprocessed_messages"`
+	MaxAckTimeMs        int64     `json:"max_ack_time_ms" bson:"max_ack_time_ms"`
+	InProcessMessages   int       `json:"in_process_messages" bson:"in_process_messages"`
+	TotalPoisonMessages int       `json:"total_poison_messages" bson:"total_poison_messages"`
+	MaxMsgDeliveries    int       `json:"max_msg_deliveries" bson:"max_msg_deliveries"`
+	// The backoff and ack windows in effect when the message was poisoned
+	RedeliveryBackoff    *RedeliveryBackoff `json:"redelivery_backoff,omitempty" bson:"redelivery_backoff,omitempty"`
+	RedeliveryScheduleMs []int64            `json:"redelivery_schedule_ms,omitempty" bson:"redelivery_schedule_ms,omitempty"`
+	CgMembers            []CgMember         `json:"cg_members" bson:"cg_members"`
+	IsActive             bool               `json:"is_active" bson:"is_active"`
+	IsDeleted            bool               `json:"is_deleted" bson:"is_deleted"`
 }
 
 type DlsMessage struct {
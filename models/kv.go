@@ -0,0 +1,94 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type KvBucket struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id"`
+	Name          string             `json:"name" bson:"name"`
+	History       int                `json:"history" bson:"history"`
+	TTLSec        int                `json:"ttl_in_sec" bson:"ttl_in_sec"`
+	StorageType   string             `json:"storage_type" bson:"storage_type"`
+	Replicas      int                `json:"replicas" bson:"replicas"`
+	CreatedByUser string             `json:"created_by_user" bson:"created_by_user"`
+	CreationDate  time.Time          `json:"creation_date" bson:"creation_date"`
+	IsDeleted     bool               `json:"is_deleted" bson:"is_deleted"`
+	TenantName    string             `json:"tenant_name" bson:"tenant_name"`
+}
+
+type ExtendedKvBucket struct {
+	ID            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name"`
+	History       int                `json:"history"`
+	TTLSec        int                `json:"ttl_in_sec"`
+	StorageType   string             `json:"storage_type"`
+	Replicas      int                `json:"replicas"`
+	CreatedByUser string             `json:"created_by_user"`
+	CreationDate  time.Time          `json:"creation_date"`
+	TotalKeys     int                `json:"total_keys"`
+	TotalBytes    uint64             `json:"total_bytes"`
+	LastUpdate    time.Time          `json:"last_update"`
+	Tags          []CreateTag        `json:"tags"`
+}
+
+type KvEntry struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	Revision  uint64    `json:"revision"`
+	Operation string    `json:"operation"`
+	Created   time.Time `json:"created"`
+}
+
+type GetKvBucketResponse struct {
+	Bucket  ExtendedKvBucket `json:"bucket"`
+	Entries []KvEntry        `json:"entries"`
+}
+
+type CreateKvBucketSchema struct {
+	Name        string      `json:"name" binding:"required"`
+	History     int         `json:"history" binding:"min=0"`
+	TTLSec      int         `json:"ttl_in_sec" binding:"min=0"`
+	StorageType string      `json:"storage_type"`
+	Replicas    int         `json:"replicas"`
+	Tags        []CreateTag `json:"tags"`
+}
+
+type RemoveKvBucketSchema struct {
+	BucketNames []string `json:"bucket_names" binding:"required"`
+}
+
+type GetKvBucketSchema struct {
+	BucketName string `form:"bucket_name" json:"bucket_name" binding:"required"`
+}
+
+type KvKeySchema struct {
+	BucketName string `form:"bucket_name" json:"bucket_name" binding:"required"`
+	Key        string `form:"key" json:"key" binding:"required"`
+}
+
+type KvPutSchema struct {
+	BucketName       string  `json:"bucket_name" binding:"required"`
+	Key              string  `json:"key" binding:"required"`
+	Value            string  `json:"value"`
+	ExpectedRevision *uint64 `json:"expected_revision"`
+}
+
+type KvDeleteSchema struct {
+	BucketName string `json:"bucket_name" binding:"required"`
+	Key        string `json:"key" binding:"required"`
+	Purge      bool   `json:"purge"`
+}
@@ -0,0 +1,48 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package models
+
+import "time"
+
+type StationMetricsSample struct {
+	StreamName   string            `json:"stream_name"`
+	Time         time.Time         `json:"time"`
+	Messages     uint64            `json:"messages"`
+	Bytes        uint64            `json:"bytes"`
+	LastSeq      uint64            `json:"last_seq"`
+	DlsMessages  uint64            `json:"dls_messages"`
+	DlsBytes     uint64            `json:"dls_bytes"`
+	CgsLag       map[string]uint64 `json:"cgs_lag"`
+	CgsDelivered map[string]uint64 `json:"cgs_delivered"`
+}
+
+type StationMetricsPoint struct {
+	Time        time.Time         `json:"time"`
+	IngestRate  float64           `json:"ingest_rate"`
+	Messages    uint64            `json:"messages"`
+	Bytes       uint64            `json:"bytes"`
+	DlsMessages uint64            `json:"dls_messages"`
+	DlsGrowth   int64             `json:"dls_growth"`
+	CgsLag      map[string]uint64 `json:"cgs_lag"`
+}
+
+type GetStationMetricsSchema struct {
+	StationName string `form:"station_name" json:"station_name" binding:"required"`
+	Range       string `form:"range" json:"range" binding:"required"`
+}
+
+type StationMetricsResponse struct {
+	StationName string                `json:"station_name"`
+	Range       string                `json:"range"`
+	StepSec     int                   `json:"step_sec"`
+	Points      []StationMetricsPoint `json:"points"`
+}
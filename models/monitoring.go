@@ -27,6 +27,13 @@ type MainOverviewData struct {
 	Stations         []ExtendedStation `json:"stations"`
 }
 
+type WSEvent struct {
+	Event       string `json:"event"`
+	TenantName  string `json:"tenant_name,omitempty"`
+	StationName string `json:"station_name,omitempty"`
+	Data        any    `json:"data,omitempty"`
+}
+
 type GetStationOverviewDataSchema struct {
 	StationName string `form:"station_name" json:"station_name"  binding:"required"`
 }
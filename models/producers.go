@@ -40,6 +40,9 @@ type ExtendedProducer struct {
 	IsActive      bool               `json:"is_active" bson:"is_active"`
 	IsDeleted     bool               `json:"is_deleted" bson:"is_deleted"`
 	ClientAddress string             `json:"client_address" bson:"client_address"`
+	State         string             `json:"state" bson:"state"`
+	LastSeen      time.Time          `json:"last_seen" bson:"last_seen"`
+	TenantName    string             `json:"-" bson:"tenant_name"`
 }
 
 type GetAllProducersByStationSchema struct {
@@ -0,0 +1,46 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package models
+
+import "time"
+
+// RateLimits of zero mean unlimited
+type RateLimits struct {
+	MsgsPerSec  int `json:"msgs_per_sec" bson:"msgs_per_sec" binding:"min=0"`
+	BytesPerSec int `json:"bytes_per_sec" bson:"bytes_per_sec" binding:"min=0"`
+}
+
+type UpdateStationRateLimitsSchema struct {
+	StationName string     `json:"station_name" binding:"required"`
+	RateLimits  RateLimits `json:"rate_limits"`
+}
+
+type UpdateUserRateLimitsSchema struct {
+	Username   string     `json:"username" binding:"required"`
+	RateLimits RateLimits `json:"rate_limits"`
+}
+
+type RateLimitsUpdate struct {
+	TenantName  string     `json:"tenant_name"`
+	StationName string     `json:"station_name"`
+	Username    string     `json:"username"`
+	RateLimits  RateLimits `json:"rate_limits"`
+}
+
+type RateLimitViolation struct {
+	Scope         string    `json:"scope"`
+	Name          string    `json:"name"`
+	StationName   string    `json:"station_name"`
+	TenantName    string    `json:"tenant_name"`
+	Count         uint64    `json:"count"`
+	LastViolation time.Time `json:"last_violation"`
+}
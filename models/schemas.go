@@ -18,9 +18,11 @@ import (
 )
 
 type Schema struct {
-	ID   primitive.ObjectID `json:"id" bson:"_id"`
-	Name string             `json:"name" bson:"name"`
-	Type string             `json:"type" bson:"type"`
+	ID                 primitive.ObjectID `json:"id" bson:"_id"`
+	Name               string             `json:"name" bson:"name"`
+	Type               string             `json:"type" bson:"type"`
+	TenantName         string             `json:"tenant_name" bson:"tenant_name"`
+	CompatibilityLevel string             `json:"compatibility_level" bson:"compatibility_level"`
 }
 
 type SchemaVersion struct {
@@ -33,6 +35,7 @@ type SchemaVersion struct {
 	SchemaId          primitive.ObjectID `json:"schema_id" bson:"schema_id"`
 	MessageStructName string             `json:"message_struct_name" bson:"message_struct_name"`
 	Descriptor        string             `json:"-" bson:"descriptor"`
+	RegistryId        int                `json:"registry_id" bson:"registry_id"`
 }
 
 type CreateNewSchema struct {
@@ -111,3 +114,20 @@ type ValidateSchema struct {
 	SchemaType    string `json:"schema_type"`
 	SchemaContent string `json:"schema_content"`
 }
+
+type RegistrySchema struct {
+	Schema     string `json:"schema" binding:"required"`
+	SchemaType string `json:"schemaType"`
+}
+
+type RegistrySchemaVersion struct {
+	Subject    string `json:"subject"`
+	Id         int    `json:"id"`
+	Version    int    `json:"version"`
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+type RegistryCompatibilityConfig struct {
+	Compatibility string `json:"compatibility" binding:"required"`
+}
@@ -51,6 +51,7 @@ type MessageDetails struct {
 	ConnectionId string            `json:"connection_id" bson:"connection_id"`
 	Size         int               `json:"size" bson:"size"`
 	Headers      map[string]string `json:"headers" bson:"headers"`
+	Chunked      bool              `json:"chunked" bson:"chunked"`
 }
 
 type Station struct {
@@ -71,6 +72,16 @@ type Station struct {
 	IdempotencyWindow int64              `json:"idempotency_window_in_ms" bson:"idempotency_window_in_ms"`
 	IsNative          bool               `json:"is_native" bson:"is_native"`
 	DlsConfiguration  DlsConfiguration   `json:"dls_configuration" bson:"dls_configuration"`
+	TenantName        string             `json:"tenant_name" bson:"tenant_name"`
+	RateLimits        RateLimits         `json:"rate_limits" bson:"rate_limits"`
+	PartitionsNumber  int                `json:"partitions_number" bson:"partitions_number"`
+	Mirror            StationMirror      `json:"mirror" bson:"mirror"`
+	Sources           []StationSource    `json:"sources" bson:"sources"`
+	Encrypted         bool               `json:"encrypted" bson:"encrypted"`
+	KeyRotationDate   time.Time          `json:"key_rotation_date" bson:"key_rotation_date"`
+	Compression       string             `json:"compression" bson:"compression"`
+	Compacted         bool               `json:"compacted" bson:"compacted"`
+	MaxMsgsPerKey     int                `json:"max_msgs_per_key" bson:"max_msgs_per_key"`
 }
 
 type GetStationResponseSchema struct {
@@ -91,6 +102,15 @@ type GetStationResponseSchema struct {
 	IdempotencyWindow int                `json:"idempotency_window_in_ms" bson:"idempotency_window_in_ms"`
 	IsNative          bool               `json:"is_native" bson:"is_native"`
 	DlsConfiguration  DlsConfiguration   `json:"dls_configuration" bson:"dls_configuration"`
+	RateLimits        RateLimits         `json:"rate_limits" bson:"rate_limits"`
+	PartitionsNumber  int                `json:"partitions_number" bson:"partitions_number"`
+	Mirror            StationMirror      `json:"mirror" bson:"mirror"`
+	Sources           []StationSource    `json:"sources" bson:"sources"`
+	Encrypted         bool               `json:"encrypted" bson:"encrypted"`
+	KeyRotationDate   time.Time          `json:"key_rotation_date" bson:"key_rotation_date"`
+	Compression       string             `json:"compression" bson:"compression"`
+	Compacted         bool               `json:"compacted" bson:"compacted"`
+	MaxMsgsPerKey     int                `json:"max_msgs_per_key" bson:"max_msgs_per_key"`
 }
 
 type ExtendedStation struct {
@@ -139,6 +159,78 @@ type CreateStationSchema struct {
 	SchemaName        string           `json:"schema_name"`
 	IdempotencyWindow int64            `json:"idempotency_window_in_ms"`
 	DlsConfiguration  DlsConfiguration `json:"dls_configuration"`
+	RateLimits        RateLimits       `json:"rate_limits"`
+	PartitionsNumber  int              `json:"partitions_number" binding:"min=0"`
+	Mirror            MirrorSchema     `json:"mirror"`
+	Sources           []StationSource  `json:"sources"`
+	Encrypted         bool             `json:"encrypted"`
+	Compression       string           `json:"compression"`
+	Compacted         bool             `json:"compacted"`
+	MaxMsgsPerKey     int              `json:"max_msgs_per_key" binding:"min=0"`
+}
+
+// StationMirror is set on stations that replicate another station, the domain is used to reach a station over a leafnode
+type StationMirror struct {
+	StationName   string    `json:"station_name" bson:"station_name"`
+	Domain        string    `json:"domain" bson:"domain"`
+	IsPromoted    bool      `json:"is_promoted" bson:"is_promoted"`
+	PromotionDate time.Time `json:"promotion_date" bson:"promotion_date"`
+}
+
+type MirrorSchema struct {
+	StationName string `json:"station_name"`
+	Domain      string `json:"domain"`
+}
+
+type MirrorDetails struct {
+	StationName string `json:"station_name"`
+	Domain      string `json:"domain"`
+	IsPromoted  bool   `json:"is_promoted"`
+	Lag         uint64 `json:"lag"`
+	ActiveMs    int64  `json:"active_ms"`
+	Error       string `json:"error"`
+}
+
+// StationSource is a station aggregated into another station, the filter subject is relative to the source station (e.g. final.2)
+type StationSource struct {
+	StationName   string `json:"station_name" bson:"station_name"`
+	Domain        string `json:"domain" bson:"domain"`
+	FilterSubject string `json:"filter_subject" bson:"filter_subject"`
+}
+
+type SourceDetails struct {
+	StationName   string `json:"station_name"`
+	Domain        string `json:"domain"`
+	FilterSubject string `json:"filter_subject"`
+	Lag           uint64 `json:"lag"`
+	ActiveMs      int64  `json:"active_ms"`
+	Error         string `json:"error"`
+}
+
+type StationTopology struct {
+	Sources      []SourceDetails `json:"sources"`
+	AggregatedBy []string        `json:"aggregated_by"`
+}
+
+type UpdateStationSourcesSchema struct {
+	StationName string          `json:"station_name" binding:"required"`
+	Sources     []StationSource `json:"sources"`
+}
+
+type PromoteMirrorSchema struct {
+	StationName string `json:"station_name" binding:"required"`
+}
+
+type StationEncryptionKeySchema struct {
+	StationName string `json:"station_name" binding:"required"`
+}
+
+// StationKeyUpdate is broadcasted to all the brokers so they keep the same keys for encrypted stations
+type StationKeyUpdate struct {
+	Action      string   `json:"action"`
+	AccountName string   `json:"account_name"`
+	StreamNames []string `json:"stream_names"`
+	Key         []byte   `json:"key"`
 }
 
 type DlsConfiguration struct {
@@ -177,6 +269,43 @@ type GetMessageDetailsSchema struct {
 	StationName string `form:"station_name" json:"station_name" binding:"required"`
 }
 
+type GetMessageJourneySchema struct {
+	StationName string `form:"station_name" json:"station_name" binding:"required"`
+	MessageSeq  int    `form:"message_seq" json:"message_seq" binding:"required"`
+}
+
+type MessageJourneyProducer struct {
+	Name         string `json:"name"`
+	ConnectionId string `json:"connection_id"`
+}
+
+type MessageJourneyCg struct {
+	CgName          string    `json:"cg_name"`
+	AckState        string    `json:"ack_state"`
+	DeliveriesCount int       `json:"deliveries_count"`
+	LastDelivery    time.Time `json:"last_delivery"`
+	AckLatencyMs    int64     `json:"ack_latency_ms"`
+}
+
+type MessageJourneyEvent struct {
+	Type            string    `json:"type"`
+	CgName          string    `json:"cg_name"`
+	Time            time.Time `json:"time"`
+	DeliveriesCount int       `json:"deliveries_count"`
+	AckLatencyMs    int64     `json:"ack_latency_ms"`
+}
+
+type MessageJourney struct {
+	StationName    string                 `json:"station_name"`
+	MessageSeq     int                    `json:"message_seq"`
+	Subject        string                 `json:"subject"`
+	Size           int                    `json:"size"`
+	IngestTime     time.Time              `json:"ingest_time"`
+	Producer       MessageJourneyProducer `json:"producer"`
+	ConsumerGroups []MessageJourneyCg     `json:"consumer_groups"`
+	Events         []MessageJourneyEvent  `json:"events"`
+}
+
 type UseSchema struct {
 	StationNames []string `json:"station_names" binding:"required"`
 	SchemaName   string   `json:"schema_name" binding:"required"`
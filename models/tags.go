@@ -16,12 +16,13 @@ import (
 )
 
 type Tag struct {
-	ID       primitive.ObjectID   `json:"id" bson:"_id"`
-	Name     string               `json:"name" bson:"name"`
-	Color    string               `json:"color" bson:"color"`
-	Users    []primitive.ObjectID `json:"users" bson:"users"`
-	Stations []primitive.ObjectID `json:"stations" bson:"stations"`
-	Schemas  []primitive.ObjectID `json:"schemas" bson:"schemas"`
+	ID        primitive.ObjectID   `json:"id" bson:"_id"`
+	Name      string               `json:"name" bson:"name"`
+	Color     string               `json:"color" bson:"color"`
+	Users     []primitive.ObjectID `json:"users" bson:"users"`
+	Stations  []primitive.ObjectID `json:"stations" bson:"stations"`
+	Schemas   []primitive.ObjectID `json:"schemas" bson:"schemas"`
+	KvBuckets []primitive.ObjectID `json:"kv_buckets" bson:"kv_buckets"`
 }
 
 type CreateTag struct {
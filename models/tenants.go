@@ -0,0 +1,71 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TenantLimits struct {
+	MaxMemory    int64 `json:"max_memory" bson:"max_memory"`
+	MaxStorage   int64 `json:"max_storage" bson:"max_storage"`
+	MaxStreams   int   `json:"max_streams" bson:"max_streams"`
+	MaxConsumers int   `json:"max_consumers" bson:"max_consumers"`
+}
+
+type Tenant struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id"`
+	Name          string             `json:"name" bson:"name"`
+	CreatedByUser string             `json:"created_by_user" bson:"created_by_user"`
+	CreationDate  time.Time          `json:"creation_date" bson:"creation_date"`
+	Limits        TenantLimits       `json:"limits" bson:"limits"`
+}
+
+type TenantUsage struct {
+	Memory    uint64 `json:"memory"`
+	Storage   uint64 `json:"storage"`
+	Streams   int    `json:"streams"`
+	Consumers int    `json:"consumers"`
+}
+
+type ExtendedTenant struct {
+	ID            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name"`
+	CreatedByUser string             `json:"created_by_user"`
+	CreationDate  time.Time          `json:"creation_date"`
+	Limits        TenantLimits       `json:"limits"`
+	Usage         TenantUsage        `json:"usage"`
+	StationsCount int                `json:"stations_count"`
+	UsersCount    int                `json:"users_count"`
+}
+
+type CreateTenantSchema struct {
+	Name   string       `json:"name" binding:"required,min=1,max=32"`
+	Limits TenantLimits `json:"limits"`
+}
+
+type UpdateTenantLimitsSchema struct {
+	Name   string       `json:"name" binding:"required"`
+	Limits TenantLimits `json:"limits"`
+}
+
+type RemoveTenantSchema struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// TenantUpdate is broadcasted to all the brokers so every one of them serves the same tenant accounts
+type TenantUpdate struct {
+	Action string `json:"action"`
+	Tenant Tenant `json:"tenant"`
+}
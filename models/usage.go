@@ -0,0 +1,76 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Usage struct {
+	AvgBytesStored uint64 `json:"avg_bytes_stored" bson:"avg_bytes_stored"`
+	MessagesIn     uint64 `json:"messages_in" bson:"messages_in"`
+	MessagesOut    uint64 `json:"messages_out" bson:"messages_out"`
+	DlsMessages    uint64 `json:"dls_messages" bson:"dls_messages"`
+	AvgDlsBytes    uint64 `json:"avg_dls_bytes" bson:"avg_dls_bytes"`
+}
+
+type StationDailyUsage struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	TenantName  string             `json:"tenant_name" bson:"tenant_name"`
+	StationName string             `json:"station_name" bson:"station_name"`
+	Date        time.Time          `json:"date" bson:"date"`
+	Usage       `bson:",inline"`
+}
+
+type StationUsage struct {
+	StationName   string   `json:"station_name" bson:"station_name"`
+	CreatedByUser string   `json:"created_by_user" bson:"created_by_user"`
+	Tags          []string `json:"tags" bson:"tags"`
+	Usage         `bson:",inline"`
+}
+
+type UsageReportRow struct {
+	Name  string `json:"name"`
+	Usage `bson:",inline"`
+}
+
+type UsageReport struct {
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	GroupBy string           `json:"group_by"`
+	Rows    []UsageReportRow `json:"rows"`
+}
+
+type UsageSnapshot struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	TenantName   string             `json:"tenant_name" bson:"tenant_name"`
+	Period       string             `json:"period" bson:"period"`
+	From         time.Time          `json:"from" bson:"from"`
+	To           time.Time          `json:"to" bson:"to"`
+	Stations     []StationUsage     `json:"stations" bson:"stations"`
+	CreationDate time.Time          `json:"creation_date" bson:"creation_date"`
+}
+
+type GetUsageReportSchema struct {
+	From    string `form:"from" json:"from" binding:"required"`
+	To      string `form:"to" json:"to" binding:"required"`
+	GroupBy string `form:"group_by" json:"group_by"`
+	Format  string `form:"format" json:"format"`
+}
+
+type GetUsageSnapshotSchema struct {
+	Period  string `form:"period" json:"period" binding:"required"`
+	GroupBy string `form:"group_by" json:"group_by"`
+	Format  string `form:"format" json:"format"`
+}
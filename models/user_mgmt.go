@@ -30,6 +30,15 @@ type User struct {
 	FullName        string             `json:"full_name" bson:"full_name"`
 	Subscribtion    bool               `json:"subscription" bson:"subscription"`
 	SkipGetStarted  bool               `json:"skip_get_started" bson:"skip_get_started"`
+	TenantName      string             `json:"tenant_name" bson:"tenant_name"`
+	RateLimits      RateLimits         `json:"rate_limits" bson:"rate_limits"`
+	TotpEnabled     bool               `json:"totp_enabled" bson:"totp_enabled"`
+	TotpSecret      string             `json:"-" bson:"totp_secret"`
+	RecoveryCodes   []string           `json:"-" bson:"totp_recovery_codes"`
+	TotpLastStep    int64              `json:"-" bson:"totp_last_step"`
+	// session claims, taken from the token and never stored
+	TotpVerified           bool `json:"-" bson:"-"`
+	TotpEnrollmentRequired bool `json:"-" bson:"-"`
 }
 
 type Image struct {
@@ -47,6 +56,7 @@ type AddUserSchema struct {
 	AvatarId     int    `json:"avatar_id"`
 	FullName     string `json:"full_name"`
 	Subscribtion bool   `json:"subscription"`
+	TenantName   string `json:"tenant_name"`
 }
 
 type AuthenticateNatsSchema struct {
@@ -55,8 +65,27 @@ type AuthenticateNatsSchema struct {
 }
 
 type LoginSchema struct {
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+	TotpCode     string `json:"totp_code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type VerifyTotpSchema struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTotpSchema struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type ResetUserTotpSchema struct {
 	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+}
+
+type EnforceTotpSchema struct {
+	Enforce bool `json:"enforce"`
 }
 
 type RemoveUserSchema struct {
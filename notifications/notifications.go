@@ -25,6 +25,7 @@ var IntegrationsCollection *mongo.Collection
 const PoisonMAlert = "poison_message_alert"
 const SchemaVAlert = "schema_validation_fail_alert"
 const DisconEAlert = "disconnection_events_alert"
+const RateLAlert = "rate_limit_alert"
 
 func SendNotification(title string, message string, msgType string) error {
 	for k, f := range NotificationFunctionsMap {
@@ -56,7 +56,7 @@ func clearSlackCache() {
 
 func CacheSlackDetails(keys map[string]string, properties map[string]bool) {
 	var authToken, channelID string
-	var poisonMessageAlert, schemaValidationFailAlert, disconnectionEventsAlert bool
+	var poisonMessageAlert, schemaValidationFailAlert, disconnectionEventsAlert, rateLimitAlert bool
 	var slackIntegration models.SlackIntegration
 
 	slackIntegration, ok := NotificationIntegrationsMap["slack"].(models.SlackIntegration)
@@ -73,6 +73,7 @@ func CacheSlackDetails(keys map[string]string, properties map[string]bool) {
 		poisonMessageAlert = false
 		schemaValidationFailAlert = false
 		disconnectionEventsAlert = false
+		rateLimitAlert = false
 	}
 	authToken, ok = keys["auth_token"]
 	if !ok {
@@ -96,6 +97,10 @@ func CacheSlackDetails(keys map[string]string, properties map[string]bool) {
 	if !ok {
 		disconnectionEventsAlert = false
 	}
+	rateLimitAlert, ok = properties[RateLAlert]
+	if !ok {
+		rateLimitAlert = false
+	}
 	if slackIntegration.Keys["auth_token"] != authToken {
 		slackIntegration.Keys["auth_token"] = authToken
 		if authToken != "" {
@@ -107,6 +112,7 @@ func CacheSlackDetails(keys map[string]string, properties map[string]bool) {
 	slackIntegration.Properties[PoisonMAlert] = poisonMessageAlert
 	slackIntegration.Properties[SchemaVAlert] = schemaValidationFailAlert
 	slackIntegration.Properties[DisconEAlert] = disconnectionEventsAlert
+	slackIntegration.Properties[RateLAlert] = rateLimitAlert
 	slackIntegration.Name = "slack"
 	NotificationIntegrationsMap["slack"] = slackIntegration
 }
@@ -761,12 +761,14 @@ func (s *Server) processClientOrLeafAuthentication(c *client, opts *Options) boo
 			if !strings.Contains(c.opts.Name, connectItemSep) {
 				// if the Name field does not contain '::' this is native NATS SDK
 				tokenSplit := strings.Split(c.opts.Token, connectItemSep)
-				if len(tokenSplit) != 2 {
+				if len(tokenSplit) != 2 || !comparePasswords(token, tokenSplit[1]) {
 					return false
 				}
-				return comparePasswords(token, tokenSplit[1])
+			} else if !comparePasswords(token, c.opts.Token) {
+				return false
 			}
-			return comparePasswords(token, c.opts.Token)
+			// the user behind the token decides the tenant account the client is bound to
+			return s.registerMemphisClientAccount(c)
 		} else if username != _EMPTY_ {
 			if username != c.opts.Username {
 				return false
@@ -34,6 +34,10 @@ const INTEGRATIONS_UPDATES_SUBJ = "$memphis_integration_updates"
 const CONFIGURATIONS_UPDATES_SUBJ = "$memphis_configurations_updates"
 const NOTIFICATION_EVENTS_SUBJ = "$memphis_notifications"
 const PM_RESEND_ACK_SUBJ = "$memphis_pm_acks"
+const CONNECTIONS_STATS_SUBJ = "$memphis_connections_stats"
+const CONNECTIONS_DISCONNECT_SUBJ = "$memphis_connections_disconnect"
+const STATION_KEYS_UPDATES_SUBJ = "$memphis_station_keys_updates"
+const TENANTS_UPDATES_SUBJ = "$memphis_tenants_updates"
 
 func (s *Server) ListenForZombieConnCheckRequests() error {
 	_, err := s.subscribeOnGlobalAcc(CONN_STATUS_SUBJ, CONN_STATUS_SUBJ+"_sid", func(_ *client, subject, reply string, msg []byte) {
@@ -64,6 +68,85 @@ func (s *Server) ListenForZombieConnCheckRequests() error {
 	return nil
 }
 
+func (s *Server) ListenForConnectionsStatsRequests() error {
+	_, err := s.subscribeOnGlobalAcc(CONNECTIONS_STATS_SUBJ, CONNECTIONS_STATS_SUBJ+"_sid"+s.Name(), func(_ *client, subject, reply string, msg []byte) {
+		go func(msg []byte) {
+			bytes, err := json.Marshal(s.localConnectionsStats())
+			if err != nil {
+				s.Errorf("ListenForConnectionsStatsRequests: " + err.Error())
+				return
+			}
+			s.respondOnGlobalAcc(reply, bytes)
+		}(copyBytes(msg))
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) ListenForConnectionsDisconnectRequests() error {
+	_, err := s.subscribeOnGlobalAcc(CONNECTIONS_DISCONNECT_SUBJ, CONNECTIONS_DISCONNECT_SUBJ+"_sid"+s.Name(), func(_ *client, subject, reply string, msg []byte) {
+		go func(msg []byte) {
+			// the raw message still holds its trailing CRLF
+			connectionId, err := primitive.ObjectIDFromHex(strings.TrimSuffix(string(msg), CR_LF))
+			if err != nil {
+				s.Errorf("ListenForConnectionsDisconnectRequests: " + err.Error())
+				return
+			}
+			s.disconnectLocalConnection(connectionId)
+		}(copyBytes(msg))
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) ListenForStationKeysUpdates() error {
+	_, err := s.subscribeOnGlobalAcc(STATION_KEYS_UPDATES_SUBJ, STATION_KEYS_UPDATES_SUBJ+"_sid"+s.Name(), func(_ *client, subject, reply string, msg []byte) {
+		go func(msg []byte) {
+			var update models.StationKeyUpdate
+			err := json.Unmarshal(msg, &update)
+			if err == nil {
+				err = s.applyStationKeyUpdate(update)
+			}
+			if err != nil {
+				s.Errorf("ListenForStationKeysUpdates: " + err.Error())
+				s.respondOnGlobalAcc(reply, []byte(err.Error()))
+				return
+			}
+			s.respondOnGlobalAcc(reply, []byte{})
+		}(copyBytes(msg))
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) ListenForTenantsUpdates() error {
+	_, err := s.subscribeOnGlobalAcc(TENANTS_UPDATES_SUBJ, TENANTS_UPDATES_SUBJ+"_sid"+s.Name(), func(_ *client, subject, reply string, msg []byte) {
+		go func(msg []byte) {
+			var update models.TenantUpdate
+			err := json.Unmarshal(msg, &update)
+			if err == nil {
+				err = s.applyTenantUpdate(update)
+			}
+			if err != nil {
+				s.Errorf("ListenForTenantsUpdates: " + err.Error())
+				s.respondOnGlobalAcc(reply, []byte(err.Error()))
+				return
+			}
+			s.respondOnGlobalAcc(reply, []byte{})
+		}(copyBytes(msg))
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (s *Server) ListenForIntegrationsUpdateEvents() error {
 	_, err := s.subscribeOnGlobalAcc(INTEGRATIONS_UPDATES_SUBJ, INTEGRATIONS_UPDATES_SUBJ+"_sid"+s.Name(), func(_ *client, subject, reply string, msg []byte) {
 		go func(msg []byte) {
@@ -102,6 +185,20 @@ func (s *Server) ListenForConfogurationsUpdateEvents() error {
 			switch strings.ToLower(configurationsUpdate.Type) {
 			case "pm_retention":
 				POISON_MSGS_RETENTION_IN_HOURS = int(configurationsUpdate.Update.(float64))
+			case "liveness_suspect_grace_sec":
+				LIVENESS_SUSPECT_GRACE_SEC = int(configurationsUpdate.Update.(float64))
+			case "liveness_disconnect_grace_sec":
+				LIVENESS_DISCONNECT_GRACE_SEC = int(configurationsUpdate.Update.(float64))
+			case "station_rate_limits", "user_rate_limits", "producer_rate_limits":
+				err = handleRateLimitsUpdate(strings.ToLower(configurationsUpdate.Type), configurationsUpdate.Update)
+				if err != nil {
+					s.Errorf("ListenForConfogurationsUpdateEvents: " + err.Error())
+				}
+			case "station_partitions":
+				err = handleStationPartitionsUpdate(configurationsUpdate.Update)
+				if err != nil {
+					s.Errorf("ListenForConfogurationsUpdateEvents: " + err.Error())
+				}
 			default:
 				return
 			}
@@ -138,7 +235,7 @@ func (s *Server) ListenForNotificationEvents() error {
 	return nil
 }
 
-func ackPoisonMsgV0(msgId string, cgName string) error {
+func ackPoisonMsgV0(tenantName, msgId string, cgName string) error {
 	splitId := strings.Split(msgId, dlsMsgSep)
 	stationName := splitId[0]
 	sn, err := StationNameFromStr(stationName)
@@ -152,7 +249,7 @@ func ackPoisonMsgV0(msgId string, cgName string) error {
 	internalCgName := replaceDelimiters(cgName)
 	filter := GetDlsSubject("poison", sn.Intern(), msgId, internalCgName)
 	timeout := 30 * time.Second
-	msgs, err := serv.memphisGetMessagesByFilter(streamName, filter, 0, amount, timeout)
+	msgs, err := serv.memphisGetMessagesByFilter(tenantName, streamName, filter, 0, amount, timeout)
 
 	if len(msgs) != 1 {
 		return errors.New("message was not found")
@@ -165,15 +262,16 @@ func ackPoisonMsgV0(msgId string, cgName string) error {
 		return err
 	}
 
-	err = serv.memphisRemoveConsumer(streamName, durableName)
+	err = serv.memphisRemoveConsumer(tenantName, streamName, durableName)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (s *Server) ListenForPoisonMsgAcks() error {
-	err := s.queueSubscribe(PM_RESEND_ACK_SUBJ, PM_RESEND_ACK_SUBJ+"_group", func(_ *client, subject, reply string, msg []byte) {
+func (s *Server) ListenForPoisonMsgAcks(acc *Account) error {
+	tenantName := tenantNameFromAccount(acc)
+	err := s.queueSubscribeOnAcc(acc, PM_RESEND_ACK_SUBJ, PM_RESEND_ACK_SUBJ+"_group", func(_ *client, subject, reply string, msg []byte) {
 		go func(msg []byte) {
 			var msgToAck models.PmAckMsg
 			err := json.Unmarshal(msg, &msgToAck)
@@ -183,7 +281,7 @@ func (s *Server) ListenForPoisonMsgAcks() error {
 			}
 			//This check for backward compatability
 			if msgToAck.CgName != "" {
-				err = ackPoisonMsgV0(msgToAck.ID, msgToAck.CgName)
+				err = ackPoisonMsgV0(tenantName, msgToAck.ID, msgToAck.CgName)
 				if err != nil {
 					s.Errorf("ListenForPoisonMsgAcks: " + err.Error())
 					return
@@ -202,7 +300,7 @@ func (s *Server) ListenForPoisonMsgAcks() error {
 					s.Errorf("ListenForPoisonMsgAcks: " + err.Error())
 					return
 				}
-				_, err = s.memphisDeleteMsgFromStream(streamName, uint64(seq))
+				_, err = s.memphisDeleteMsgFromStream(tenantName, streamName, uint64(seq))
 				if err != nil {
 					s.Errorf("ListenForPoisonMsgAcks: " + err.Error())
 					return
@@ -218,12 +316,32 @@ func (s *Server) ListenForPoisonMsgAcks() error {
 }
 
 func (s *Server) StartBackgroundTasks() error {
-	s.ListenForPoisonMessages()
+	s.ListenForPoisonMessages(s.GlobalAccount())
 	err := s.ListenForZombieConnCheckRequests()
 	if err != nil {
 		return errors.New("Failed subscribing for zombie conns check requests: " + err.Error())
 	}
 
+	err = s.ListenForConnectionsStatsRequests()
+	if err != nil {
+		return errors.New("Failed subscribing for connections stats requests: " + err.Error())
+	}
+
+	err = s.ListenForConnectionsDisconnectRequests()
+	if err != nil {
+		return errors.New("Failed subscribing for connections disconnect requests: " + err.Error())
+	}
+
+	err = s.ListenForStationKeysUpdates()
+	if err != nil {
+		return errors.New("Failed subscribing for station keys updates: " + err.Error())
+	}
+
+	err = s.ListenForTenantsUpdates()
+	if err != nil {
+		return errors.New("Failed subscribing for tenants updates: " + err.Error())
+	}
+
 	err = s.ListenForIntegrationsUpdateEvents()
 	if err != nil {
 		return errors.New("Failed subscribing for integrations updates: " + err.Error())
@@ -234,16 +352,23 @@ func (s *Server) StartBackgroundTasks() error {
 		return errors.New("Failed subscribing for schema validation updates: " + err.Error())
 	}
 
-	err = s.ListenForPoisonMsgAcks()
+	err = s.ListenForPoisonMsgAcks(s.GlobalAccount())
 	if err != nil {
 		return errors.New("Failed subscribing for poison message acks: " + err.Error())
 	}
 
+	err = s.ListenForMessageJourneyEvents(s.GlobalAccount())
+	if err != nil {
+		return errors.New("Failed subscribing for message journey events: " + err.Error())
+	}
+
 	err = s.ListenForConfogurationsUpdateEvents()
 	if err != nil {
 		return errors.New("Failed subscribing for confogurations update: " + err.Error())
 	}
 
+	go s.ReportConnectionsLiveness()
+
 	filter := bson.M{"key": "ui_url"}
 	var systemKey models.SystemKey
 	err = systemKeysCollection.FindOne(context.TODO(), filter).Decode(&systemKey)
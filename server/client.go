@@ -285,6 +285,8 @@ type memphisClientInfo struct {
 	username     string
 	connectionId primitive.ObjectID `json:"connection_id,omitempty"`
 	isNative     bool
+	lastSeen     time.Time // last client PING or PONG
+	reportedSeen time.Time // last seen time already written to the db
 }
 
 type rrTracking struct {
@@ -2200,6 +2202,7 @@ func (c *client) processPing() {
 	// Record this to suppress us sending one if this
 	// is within a given time interval for activity.
 	c.ping.last = time.Now()
+	c.memphisInfo.lastSeen = c.ping.last
 
 	// If not a CLIENT, we are done. Also the CONNECT should
 	// have been received, but make sure it is so before proceeding
@@ -2252,6 +2255,7 @@ func (c *client) processPong() {
 	c.mu.Lock()
 	c.ping.out = 0
 	c.rtt = computeRTT(c.rttStart)
+	c.memphisInfo.lastSeen = time.Now()
 	srv := c.srv
 	reorderGWs := c.kind == GATEWAY && c.gw.outbound
 	c.mu.Unlock()
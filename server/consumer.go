@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -44,6 +45,8 @@ type ConsumerInfo struct {
 	NumPending     uint64          `json:"num_pending"`
 	Cluster        *ClusterInfo    `json:"cluster,omitempty"`
 	PushBound      bool            `json:"push_bound,omitempty"`
+	// Content filter statistics since the consumer started on this server.
+	ContentFilter *ContentFilterStats `json:"content_filter,omitempty"`
 }
 
 type ConsumerConfig struct {
@@ -85,6 +88,9 @@ type ConsumerConfig struct {
 
 	// Don't add to general clients.
 	Direct bool `json:"direct,omitempty"`
+
+	// Non-matching messages are acked by the server on behalf of the consumer group.
+	ContentFilter *ContentFilter `json:"content_filter,omitempty"`
 }
 
 // SequenceInfo has both the consumer and the stream sequence and last activity.
@@ -265,6 +271,9 @@ type consumer struct {
 	active            bool
 	replay            bool
 	filterWC          bool
+	cfilter           *contentFilter
+	cfMatched         uint64
+	cfSkipped         uint64
 	dtmr              *time.Timer
 	gwdtmr            *time.Timer
 	dthresh           time.Duration
@@ -376,6 +385,10 @@ func checkConsumerCfg(
 		return NewJSConsumerMaxDeliverBackoffError()
 	}
 
+	if _, err := compileContentFilter(config.ContentFilter); err != nil {
+		return NewJSConsumerCreateError(err)
+	}
+
 	if len(config.Description) > JSMaxDescriptionLen {
 		return NewJSConsumerDescriptionTooLongError(JSMaxDescriptionLen)
 	}
@@ -688,6 +701,9 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 		o.filterWC = true
 	}
 
+	// Content filter, already validated by checkConsumerCfg.
+	o.cfilter, _ = compileContentFilter(config.ContentFilter)
+
 	// already under lock, mset.Name() would deadlock
 	o.stream = mset.cfg.Name
 	o.ackEventT = JSMetricConsumerAckPre + "." + o.stream + "." + o.name
@@ -1490,6 +1506,15 @@ func (o *consumer) updateConfig(cfg *ConsumerConfig) error {
 	if cfg.MaxDeliver != o.cfg.MaxDeliver {
 		o.maxdc = uint64(cfg.MaxDeliver)
 	}
+	// Check for content filter changes.
+	if !isSameContentFilter(cfg.ContentFilter, o.cfg.ContentFilter) {
+		cf, err := compileContentFilter(cfg.ContentFilter)
+		if err != nil {
+			return err
+		}
+		o.cfilter, o.cfMatched, o.cfSkipped = cf, 0, 0
+		o.signalNewMessages()
+	}
 
 	// Record new config for others that do not need special handling.
 	// Allowed but considered no-op, [Description, SampleFrequency, MaxWaiting, HeadersOnly]
@@ -2117,6 +2142,7 @@ func (o *consumer) infoWithSnap(snap bool) *ConsumerInfo {
 		NumPending:     o.streamNumPending(),
 		PushBound:      o.isPushMode() && o.active,
 		Cluster:        ci,
+		ContentFilter:  o.contentFilterStats(),
 	}
 	// Adjust active based on non-zero etc. Also make UTC here.
 	if !o.ldt.IsZero() {
@@ -3044,6 +3070,7 @@ func (o *consumer) loopAndGatherMsgs(qch chan struct{}) {
 	mset.mu.RUnlock()
 
 	var err error
+	var skipped int
 
 	// Deliver all the msgs we have now, once done or on a condition, we wait for new ones.
 	for {
@@ -3076,6 +3103,22 @@ func (o *consumer) loopAndGatherMsgs(qch chan struct{}) {
 		// Grab our next msg.
 		pmsg, dc, err = o.getNextMsg()
 
+		// Messages which do not match the content filter are acked on behalf of the group.
+		if err == nil && pmsg != nil && o.cfilter != nil {
+			if o.filterOutMsg(pmsg, dc, rp) {
+				if skipped++; skipped%maxContentFilterSkips == 0 {
+					select {
+					case <-qch:
+						return
+					default:
+					}
+					runtime.Gosched()
+				}
+				continue
+			}
+			skipped = 0
+		}
+
 		// On error either wait or return.
 		if err != nil || pmsg == nil {
 			// If we are stalled here in pull mode, invalidate all requests that have had deliveries.
@@ -3140,6 +3183,9 @@ func (o *consumer) loopAndGatherMsgs(qch chan struct{}) {
 			}
 		}
 
+		if o.cfilter != nil && dc == 1 {
+			o.cfMatched++
+		}
 		// Do actual delivery.
 		o.deliverMsg(dsubj, pmsg, dc, rp)
 
@@ -39,6 +39,7 @@ import (
 	mrand "math/rand"
 
 	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
 	"github.com/minio/highwayhash"
 	"golang.org/x/crypto/chacha20"
 	"golang.org/x/crypto/chacha20poly1305"
@@ -86,6 +87,7 @@ type fileStore struct {
 	cfg     FileStreamInfo
 	fcfg    FileStoreConfig
 	prf     keyGen
+	oldprf  keyGen
 	aek     cipher.AEAD
 	lmb     *msgBlock
 	blks    []*msgBlock
@@ -140,6 +142,8 @@ type msgBlock struct {
 	fch     chan struct{}
 	qch     chan struct{}
 	lchk    [8]byte
+	cmp     StoreCompression // Compression of the block on disk.
+	cbytes  uint64           // Bytes on disk of a compressed block, rbytes stays the uncompressed size.
 	closed  bool
 }
 
@@ -176,6 +180,8 @@ const (
 	blkScan = "%d.blk"
 	// used for compacted blocks that are staged.
 	newScan = "%d.new"
+	// used for block encryption keys that are staged during a key rotation.
+	newKeyScan = "%d.key.new"
 	// used to scan index file names.
 	indexScan = "%d.idx"
 	// used to load per subject meta information.
@@ -246,6 +252,12 @@ func newFileStore(fcfg FileStoreConfig, cfg StreamConfig) (*fileStore, error) {
 }
 
 func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created time.Time, prf keyGen) (*fileStore, error) {
+	return newFileStoreWithKeys(fcfg, cfg, created, prf, nil)
+}
+
+// Same as newFileStoreWithCreated but keys that can not be opened with prf fall back to oldprf.
+// This is used while a key rotation of the store has not completed.
+func newFileStoreWithKeys(fcfg FileStoreConfig, cfg StreamConfig, created time.Time, prf, oldprf keyGen) (*fileStore, error) {
 	if cfg.Name == _EMPTY_ {
 		return nil, fmt.Errorf("name required")
 	}
@@ -284,9 +296,10 @@ func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created tim
 	fs := &fileStore{
 		fcfg: fcfg,
 		cfg:  FileStreamInfo{Created: created, StreamConfig: cfg},
-		psmc: make(map[string]uint64),
-		prf:  prf,
-		qch:  make(chan struct{}),
+		psmc:   make(map[string]uint64),
+		prf:    prf,
+		oldprf: oldprf,
+		qch:    make(chan struct{}),
 	}
 
 	// Set flush in place to AsyncFlush which by default is false.
@@ -478,6 +491,114 @@ func (fs *fileStore) genEncryptionKeys(context string) (aek cipher.AEAD, bek *ch
 	return aek, bek, seed, kek.Seal(nonce, nonce, seed, nil), nil
 }
 
+// Open an encrypted asset key seed with the key encryption key derived from the context and prf.
+func openEncryptionKey(prf keyGen, ekey []byte, context string) ([]byte, error) {
+	rb, err := prf([]byte(context))
+	if err != nil {
+		return nil, err
+	}
+	kek, err := chacha20poly1305.NewX(rb)
+	if err != nil {
+		return nil, err
+	}
+	ns := kek.NonceSize()
+	if len(ekey) < ns {
+		return nil, errBadKeySize
+	}
+	return kek.Open(nil, ekey[:ns], ekey[ns:], nil)
+}
+
+// Open an encrypted asset key seed, keys that were not rotated yet are opened with the previous prf.
+func (fs *fileStore) openEncryptionKey(ekey []byte, context string) ([]byte, error) {
+	seed, err := openEncryptionKey(fs.prf, ekey, context)
+	if err != nil && fs.oldprf != nil {
+		if oseed, oerr := openEncryptionKey(fs.oldprf, ekey, context); oerr == nil {
+			return oseed, nil
+		}
+	}
+	return seed, err
+}
+
+// Re-seal the seed in a key file with the key encryption key derived from prf.
+// The assets protected by the seed stay as they are, so the key file is the only thing that changes.
+// Key files already sealed with prf are left alone.
+func rewrapKeyFile(keyFile, context string, prf, oldprf keyGen) error {
+	ekey, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	if _, err := openEncryptionKey(prf, ekey, context); err == nil {
+		return nil
+	}
+	if oldprf == nil {
+		return errNoEncryption
+	}
+	seed, err := openEncryptionKey(oldprf, ekey, context)
+	if err != nil {
+		return err
+	}
+	rb, err := prf([]byte(context))
+	if err != nil {
+		return err
+	}
+	kek, err := chacha20poly1305.NewX(rb)
+	if err != nil {
+		return err
+	}
+	nonce := append(make([]byte, 0, len(ekey)), ekey[:kek.NonceSize()]...)
+	encrypted := kek.Seal(nonce, nonce, seed, nil)
+
+	// Replace the key file in one step, a partially written key would lose the assets.
+	tmp := keyFile + ".tmp"
+	if err := writeFileSynced(tmp, encrypted); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, keyFile)
+}
+
+// Write out a file and make sure it is on disk before returning.
+func writeFileSynced(name string, buf []byte) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaultFilePerms)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Finish or undo the key rotation of a block that was interrupted.
+// The re-encrypted block is staged before its new key, so a staged key without a staged block
+// means the block was already moved into place and the key has to follow, otherwise the old
+// block and key are still in place and the staged files are dropped.
+// Lock should be held.
+func (fs *fileStore) recoverStagedBlockKey(index uint64) error {
+	mdir := filepath.Join(fs.fcfg.StoreDir, msgDir)
+	nkfn := filepath.Join(mdir, fmt.Sprintf(newKeyScan, index))
+	if _, err := os.Stat(nkfn); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	nmfn := filepath.Join(mdir, fmt.Sprintf(newScan, index))
+	if _, err := os.Stat(nmfn); err == nil {
+		// The key goes first, see above.
+		if err := os.Remove(nkfn); err != nil {
+			return err
+		}
+		return os.Remove(nmfn)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(nkfn, filepath.Join(mdir, fmt.Sprintf(keyScan, index)))
+}
+
 // Write out meta and the checksum.
 // Lock should be held.
 func (fs *fileStore) writeStreamMeta() error {
@@ -602,6 +723,9 @@ func (fs *fileStore) recoverMsgBlock(fi os.FileInfo, index uint64) (*msgBlock, e
 
 	// Check if encryption is enabled.
 	if fs.prf != nil {
+		if err := fs.recoverStagedBlockKey(mb.index); err != nil {
+			return nil, err
+		}
 		ekey, err := ioutil.ReadFile(filepath.Join(mdir, fmt.Sprintf(keyScan, mb.index)))
 		if err != nil {
 			// We do not seem to have keys even though we should. Could be a plaintext conversion.
@@ -615,20 +739,12 @@ func (fs *fileStore) recoverMsgBlock(fi os.FileInfo, index uint64) (*msgBlock, e
 				return nil, errBadKeySize
 			}
 			// Recover key encryption key.
-			rb, err := fs.prf([]byte(fmt.Sprintf("%s:%d", fs.cfg.Name, mb.index)))
-			if err != nil {
-				return nil, err
-			}
-			kek, err := chacha20poly1305.NewX(rb)
+			seed, err := fs.openEncryptionKey(ekey, fmt.Sprintf("%s:%d", fs.cfg.Name, mb.index))
 			if err != nil {
 				return nil, err
 			}
-			ns := kek.NonceSize()
-			seed, err := kek.Open(nil, ekey[:ns], ekey[ns:], nil)
-			if err != nil {
-				return nil, err
-			}
-			mb.seed, mb.nonce = seed, ekey[:ns]
+			ns := chacha20poly1305.NonceSizeX
+			mb.seed, mb.nonce, mb.kfn = seed, ekey[:ns], filepath.Join(mdir, fmt.Sprintf(keyScan, mb.index))
 			if mb.aek, err = chacha20poly1305.NewX(seed); err != nil {
 				return nil, err
 			}
@@ -688,6 +804,10 @@ func (fs *fileStore) recoverMsgBlock(fi os.FileInfo, index uint64) (*msgBlock, e
 	if mb.rbytes >= checksumSize {
 		file.ReadAt(lchk[:], fi.Size()-checksumSize)
 	}
+	// Check if the block was compressed when it was sealed.
+	if alg, rawLen, ok := mb.readCompressionHeader(file); ok {
+		mb.cmp, mb.cbytes, mb.rbytes = alg, mb.rbytes, rawLen
+	}
 	file.Close()
 
 	// Read our index file. Use this as source of truth if possible.
@@ -796,6 +916,9 @@ func (mb *msgBlock) rebuildStateLocked() (*LostStreamData, error) {
 		mb.bek.XORKeyStream(buf, buf)
 	}
 
+	if buf, err = mb.decompressIfNeeded(buf); err != nil {
+		return nil, err
+	}
 	mb.rbytes = uint64(len(buf))
 
 	addToDmap := func(seq uint64) {
@@ -811,6 +934,10 @@ func (mb *msgBlock) rebuildStateLocked() (*LostStreamData, error) {
 	var le = binary.LittleEndian
 
 	truncate := func(index uint32) {
+		// Indexes point into the uncompressed messages, not the file.
+		if mb.cmp != NoCompression {
+			return
+		}
 		var fd *os.File
 		if mb.mfd != nil {
 			fd = mb.mfd
@@ -1644,6 +1771,11 @@ func (fs *fileStore) newMsgBlockForWrite() (*msgBlock, error) {
 			}
 			lmb.mu.Unlock()
 		}
+
+		// The block is sealed now, compress it in the background if configured.
+		if alg := fs.cfg.Compression; alg != NoCompression {
+			go lmb.compress(alg)
+		}
 	}
 
 	mb := &msgBlock{fs: fs, index: index, cexp: fs.fcfg.CacheExpire}
@@ -1741,6 +1873,370 @@ func (fs *fileStore) genEncryptionKeysForBlock(mb *msgBlock) error {
 	return nil
 }
 
+// Re-encrypt all message blocks with keys derived from prf, the stream and consumer meta keys are re-sealed with it.
+// Blocks are processed one at a time so writes to the stream are only held up for a single block.
+// Until this returns, keys that were not rotated yet are opened with the previous prf, also after a restart
+// if the store is created with it, in which case calling this again picks up where the rotation stopped.
+func (fs *fileStore) rotateEncryptionKeys(prf keyGen) error {
+	fs.mu.Lock()
+	if fs.closed {
+		fs.mu.Unlock()
+		return ErrStoreClosed
+	}
+	if fs.prf == nil || prf == nil {
+		fs.mu.Unlock()
+		return errNoEncryption
+	}
+	if fs.oldprf == nil {
+		fs.oldprf = fs.prf
+	}
+	fs.prf = prf
+	oldprf := fs.oldprf
+	if err := rewrapKeyFile(filepath.Join(fs.fcfg.StoreDir, JetStreamMetaFileKey), fs.cfg.Name, prf, oldprf); err != nil {
+		fs.mu.Unlock()
+		return err
+	}
+	blks := append([]*msgBlock(nil), fs.blks...)
+	cfs := append([]ConsumerStore(nil), fs.cfs...)
+	fs.mu.Unlock()
+
+	for _, mb := range blks {
+		fs.mu.Lock()
+		if fs.closed {
+			fs.mu.Unlock()
+			return ErrStoreClosed
+		}
+		err := fs.reencryptBlock(mb)
+		fs.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cs := range cfs {
+		if o, ok := cs.(*consumerFileStore); ok {
+			if err := o.rotateEncryptionKeys(prf, oldprf); err != nil {
+				return err
+			}
+		}
+	}
+
+	fs.mu.Lock()
+	fs.oldprf = nil
+	fs.mu.Unlock()
+	return nil
+}
+
+// Re-encrypt a message block with new keys.
+// The new key file is staged and only moved into place after the re-encrypted block,
+// see recoverStagedBlockKey for how an interrupted rotation is handled.
+// Lock should be held.
+func (fs *fileStore) reencryptBlock(mb *msgBlock) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.closed {
+		return nil
+	}
+	context := fmt.Sprintf("%s:%d", fs.cfg.Name, mb.index)
+	// Already done if we are resuming a rotation.
+	if ekey, err := ioutil.ReadFile(mb.kfn); err == nil {
+		if _, err := openEncryptionKey(fs.prf, ekey, context); err == nil {
+			return nil
+		}
+	}
+	// Make sure everything is on disk first.
+	if _, err := mb.flushPendingMsgsLocked(); err != nil {
+		return err
+	}
+	buf, err := mb.loadBlock(nil)
+	if err != nil {
+		return err
+	}
+	if mb.bek != nil && len(buf) > 0 {
+		rbek, err := chacha20.NewUnauthenticatedCipher(mb.seed, mb.nonce)
+		if err != nil {
+			return err
+		}
+		rbek.XORKeyStream(buf, buf)
+	}
+
+	key, bek, seed, encrypted, err := fs.genEncryptionKeys(context)
+	if err != nil {
+		return err
+	}
+	// Encrypting with the new block cipher leaves it positioned at the end of the block for appends.
+	bek.XORKeyStream(buf, buf)
+
+	hadFD := mb.mfd != nil
+	mb.closeFDsLockedNoCheck()
+
+	mdir := filepath.Join(fs.fcfg.StoreDir, msgDir)
+	nmfn := filepath.Join(mdir, fmt.Sprintf(newScan, mb.index))
+	nkfn := filepath.Join(mdir, fmt.Sprintf(newKeyScan, mb.index))
+	kfn := filepath.Join(mdir, fmt.Sprintf(keyScan, mb.index))
+	if err := writeFileSynced(nmfn, buf); err != nil {
+		os.Remove(nmfn)
+		return err
+	}
+	if err := writeFileSynced(nkfn, encrypted); err != nil {
+		os.Remove(nkfn)
+		os.Remove(nmfn)
+		return err
+	}
+	if err := os.Rename(nmfn, mb.mfn); err != nil {
+		// The staged key has to go first, without the staged block it would be used on recovery.
+		os.Remove(nkfn)
+		os.Remove(nmfn)
+		return err
+	}
+	// The block is now encrypted with the new key, even if the rename below fails recovery will move it into place.
+	mb.aek, mb.bek, mb.seed, mb.nonce, mb.kfn = key, bek, seed, encrypted[:key.NonceSize()], kfn
+	if err := os.Rename(nkfn, kfn); err != nil {
+		return err
+	}
+
+	if hadFD {
+		if err := mb.enableForWriting(fs.fip); err != nil {
+			return err
+		}
+	}
+	// The index file is sealed with the block key.
+	return mb.writeIndexInfoLocked()
+}
+
+// Replace the block file with buf, which is already encrypted if needed.
+// Lock should be held.
+func (mb *msgBlock) writeBlockFileLocked(buf []byte) error {
+	mb.closeFDsLockedNoCheck()
+
+	// We will write to a new file and mv/rename it in case of failure.
+	mfn := filepath.Join(filepath.Join(mb.fs.fcfg.StoreDir, msgDir), fmt.Sprintf(newScan, mb.index))
+	if err := ioutil.WriteFile(mfn, buf, defaultFilePerms); err != nil {
+		os.Remove(mfn)
+		return err
+	}
+	if err := os.Rename(mfn, mb.mfn); err != nil {
+		os.Remove(mfn)
+		return err
+	}
+	return nil
+}
+
+// Compressed blocks start with a magic that can not be mistaken for a record length,
+// followed by the algorithm and the uncompressed length. The last record checksum is kept at the end.
+var compressedBlockMagic = []byte{0x16, 'M', 'C', 'B'}
+
+const compressedBlockMaxHdrLen = 4 + 1 + binary.MaxVarintLen64
+
+var (
+	zstdEncoder     *zstd.Encoder
+	zstdDecoder     *zstd.Decoder
+	zstdEncoderOnce sync.Once
+	zstdDecoderOnce sync.Once
+)
+
+func getZstdEncoder() *zstd.Encoder {
+	zstdEncoderOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+	})
+	return zstdEncoder
+}
+
+func getZstdDecoder() *zstd.Decoder {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+	return zstdDecoder
+}
+
+// Returns the algorithm, uncompressed length and header length of a compressed block.
+func parseCompressedBlockHeader(buf []byte) (StoreCompression, uint64, int, bool) {
+	if len(buf) < len(compressedBlockMagic)+2 || !bytes.Equal(buf[:len(compressedBlockMagic)], compressedBlockMagic) {
+		return NoCompression, 0, 0, false
+	}
+	alg := StoreCompression(buf[len(compressedBlockMagic)])
+	if alg != S2Compression && alg != ZstdCompression {
+		return NoCompression, 0, 0, false
+	}
+	rawLen, n := binary.Uvarint(buf[len(compressedBlockMagic)+1:])
+	if n <= 0 {
+		return NoCompression, 0, 0, false
+	}
+	return alg, rawLen, len(compressedBlockMagic) + 1 + n, true
+}
+
+func compressBlockBuf(alg StoreCompression, buf []byte) ([]byte, error) {
+	if len(buf) < checksumSize {
+		return nil, errCorruptState
+	}
+	hdr := append(append([]byte(nil), compressedBlockMagic...), byte(alg))
+	hdr = binary.AppendUvarint(hdr, uint64(len(buf)))
+	switch alg {
+	case S2Compression:
+		hdr = append(hdr, s2.Encode(nil, buf)...)
+	case ZstdCompression:
+		hdr = getZstdEncoder().EncodeAll(buf, hdr)
+	default:
+		return nil, fmt.Errorf("unknown compression %v", alg)
+	}
+	return append(hdr, buf[len(buf)-checksumSize:]...), nil
+}
+
+// Detect a compressed block from the start of its file and return its uncompressed size.
+// Lock should be held.
+func (mb *msgBlock) readCompressionHeader(file *os.File) (StoreCompression, uint64, bool) {
+	var hdr [compressedBlockMaxHdrLen]byte
+	n, _ := file.ReadAt(hdr[:], 0)
+	buf := hdr[:n]
+	if mb.bek != nil && len(buf) > 0 {
+		rbek, err := chacha20.NewUnauthenticatedCipher(mb.seed, mb.nonce)
+		if err != nil {
+			return NoCompression, 0, false
+		}
+		rbek.XORKeyStream(buf, buf)
+	}
+	alg, rawLen, _, ok := parseCompressedBlockHeader(buf)
+	return alg, rawLen, ok
+}
+
+// Returns the uncompressed messages of a decrypted block.
+// Lock should be held.
+func (mb *msgBlock) decompressIfNeeded(buf []byte) ([]byte, error) {
+	alg, rawLen, hl, ok := parseCompressedBlockHeader(buf)
+	if !ok {
+		mb.cmp, mb.cbytes = NoCompression, 0
+		return buf, nil
+	}
+	if len(buf) < hl+checksumSize {
+		return nil, errCorruptState
+	}
+	payload := buf[hl : len(buf)-checksumSize]
+	var out []byte
+	var err error
+	switch alg {
+	case S2Compression:
+		out, err = s2.Decode(nil, payload)
+	case ZstdCompression:
+		out, err = getZstdDecoder().DecodeAll(payload, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(out)) != rawLen {
+		return nil, errCorruptState
+	}
+	mb.cmp, mb.cbytes = alg, uint64(len(buf))
+	return out, nil
+}
+
+// Compress a sealed block on disk. The cache and the index keep working on the uncompressed messages.
+func (mb *msgBlock) compress(alg StoreCompression) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.closed || mb.cmp != NoCompression {
+		return nil
+	}
+	if _, err := mb.flushPendingMsgsLocked(); err != nil {
+		return err
+	}
+	buf, err := mb.loadBlock(nil)
+	if err != nil || len(buf) == 0 {
+		return err
+	}
+	if mb.bek != nil {
+		rbek, err := chacha20.NewUnauthenticatedCipher(mb.seed, mb.nonce)
+		if err != nil {
+			return err
+		}
+		rbek.XORKeyStream(buf, buf)
+	}
+	if _, _, _, ok := parseCompressedBlockHeader(buf); ok {
+		return nil
+	}
+	cbuf, err := compressBlockBuf(alg, buf)
+	if err != nil {
+		return err
+	}
+	// Not worth it.
+	if len(cbuf) >= len(buf) {
+		return nil
+	}
+	if mb.bek != nil {
+		rbek, err := chacha20.NewUnauthenticatedCipher(mb.seed, mb.nonce)
+		if err != nil {
+			return err
+		}
+		rbek.XORKeyStream(cbuf, cbuf)
+	}
+	if err := mb.closeFDsLocked(); err != nil {
+		return err
+	}
+	if err := mb.writeBlockFileLocked(cbuf); err != nil {
+		return err
+	}
+	mb.cmp, mb.cbytes, mb.rbytes = alg, uint64(len(cbuf)), uint64(len(buf))
+	return nil
+}
+
+// Rewrite a compressed block uncompressed so its records can be modified in place.
+// Lock should be held.
+func (mb *msgBlock) decompressOnDiskLocked() error {
+	buf, err := mb.loadBlock(nil)
+	if err != nil {
+		return err
+	}
+	if mb.bek != nil && len(buf) > 0 {
+		rbek, err := chacha20.NewUnauthenticatedCipher(mb.seed, mb.nonce)
+		if err != nil {
+			return err
+		}
+		rbek.XORKeyStream(buf, buf)
+	}
+	if buf, err = mb.decompressIfNeeded(buf); err != nil {
+		return err
+	}
+	// Encrypting with a new block cipher leaves it positioned at the end of the block for appends.
+	if mb.bek != nil && len(buf) > 0 {
+		if mb.bek, err = chacha20.NewUnauthenticatedCipher(mb.seed, mb.nonce); err != nil {
+			return err
+		}
+		mb.bek.XORKeyStream(buf, buf)
+	}
+	if err := mb.writeBlockFileLocked(buf); err != nil {
+		return err
+	}
+	mb.cmp, mb.cbytes, mb.rbytes = NoCompression, 0, uint64(len(buf))
+	return nil
+}
+
+// Report how well the message blocks compress on disk, nil if nothing was compressed.
+func (fs *fileStore) compressionStats() *CompressionStats {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	stats := &CompressionStats{Algorithm: fs.cfg.Compression}
+	for _, mb := range fs.blks {
+		mb.mu.RLock()
+		stats.RawBytes += mb.rbytes
+		if mb.cmp != NoCompression {
+			stats.CompressedBlocks++
+			stats.StoredBytes += mb.cbytes
+		} else {
+			stats.StoredBytes += mb.rbytes
+		}
+		mb.mu.RUnlock()
+	}
+	if stats.Algorithm == NoCompression && stats.CompressedBlocks == 0 {
+		return nil
+	}
+	if stats.StoredBytes > 0 {
+		stats.Ratio = float64(stats.RawBytes) / float64(stats.StoredBytes)
+	}
+	return stats
+}
+
 // Stores a raw message with expected sequence number and timestamp.
 // Lock should be held.
 func (fs *fileStore) storeRawMsg(subj string, hdr, msg []byte, seq uint64, ts int64) error {
@@ -2305,11 +2801,17 @@ func (mb *msgBlock) compact() {
 	}
 
 	// Close cache and index file and wipe delete map, then rebuild.
+	cmp := mb.cmp
 	mb.clearCacheAndOffset()
 	mb.removeIndexFileLocked()
 	mb.deleteDmap()
 	mb.rebuildStateLocked()
 
+	// The compacted block is written uncompressed.
+	if cmp != NoCompression {
+		go mb.compress(cmp)
+	}
+
 	// If we entered with the msgs loaded make sure to reload them.
 	if wasLoaded {
 		mb.loadMsgsWithLock()
@@ -2500,6 +3002,12 @@ func (mb *msgBlock) eraseMsg(seq uint64, ri, rl int) error {
 
 	// Disk
 	if mb.cache.off+mb.cache.wp > ri {
+		// Records can only be rewritten in place when not compressed.
+		if mb.cmp != NoCompression {
+			if err := mb.decompressOnDiskLocked(); err != nil {
+				return err
+			}
+		}
 		mfd, err := os.OpenFile(mb.mfn, os.O_RDWR, defaultFilePerms)
 		if err != nil {
 			return err
@@ -2534,6 +3042,18 @@ func (mb *msgBlock) truncate(sm *StoreMsg) (nmsgs, nbytes uint64, err error) {
 
 	mb.mu.Lock()
 
+	// Records can only be truncated when not compressed.
+	if mb.cmp != NoCompression {
+		if err := mb.decompressOnDiskLocked(); err != nil {
+			mb.mu.Unlock()
+			return 0, 0, err
+		}
+		if err := mb.enableForWriting(mb.fs.fip); err != nil {
+			mb.mu.Unlock()
+			return 0, 0, err
+		}
+	}
+
 	checkDmap := len(mb.dmap) > 0
 	var smv StoreMsg
 
@@ -2905,6 +3425,14 @@ func (mb *msgBlock) writeMsgRecord(rl, seq uint64, subj string, mhdr, msg []byte
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
+	// Appends go to an uncompressed block, e.g. when a compressed block is the last one after a restart.
+	if mb.cmp != NoCompression {
+		if err := mb.decompressOnDiskLocked(); err != nil {
+			return err
+		}
+		mb.clearCacheAndOffset()
+	}
+
 	// Make sure we have a cache setup.
 	if mb.cache == nil {
 		mb.setupWriteCache(nil)
@@ -3538,6 +4066,10 @@ checkCache:
 		rbek.XORKeyStream(buf, buf)
 	}
 
+	if buf, err = mb.decompressIfNeeded(buf); err != nil {
+		return err
+	}
+
 	if err := mb.indexCacheBuf(buf); err != nil {
 		if err == errCorruptState {
 			fs := mb.fs
@@ -4558,6 +5090,13 @@ func (fs *fileStore) Compact(seq uint64) (uint64, error) {
 			}
 			smb.clearCacheAndOffset()
 			smb.rbytes = uint64(len(nbuf))
+			// The head is written uncompressed, compress it again unless more messages are appended to it.
+			if cmp := smb.cmp; cmp != NoCompression {
+				smb.cmp, smb.cbytes = NoCompression, 0
+				if smb != fs.lmb {
+					go smb.compress(cmp)
+				}
+			}
 		}
 	}
 
@@ -5368,16 +5907,9 @@ func (fs *fileStore) ConsumerStore(name string, cfg *ConsumerConfig) (ConsumerSt
 	if o.prf != nil {
 		if ekey, err := ioutil.ReadFile(filepath.Join(odir, JetStreamMetaFileKey)); err == nil {
 			// Recover key encryption key.
-			rb, err := fs.prf([]byte(fs.cfg.Name + tsep + o.name))
-			if err != nil {
-				return nil, err
-			}
-			kek, err := chacha20poly1305.NewX(rb)
-			if err != nil {
-				return nil, err
-			}
-			ns := kek.NonceSize()
-			seed, err := kek.Open(nil, ekey[:ns], ekey[ns:], nil)
+			fs.mu.RLock()
+			seed, err := fs.openEncryptionKey(ekey, fs.cfg.Name+tsep+o.name)
+			fs.mu.RUnlock()
 			if err != nil {
 				return nil, err
 			}
@@ -5828,6 +6360,22 @@ func (o *consumerFileStore) updateConfig(cfg ConsumerConfig) error {
 	return o.writeConsumerMeta()
 }
 
+// Re-seal the consumer meta key with a key encryption key derived from prf, the meta and state stay as they are.
+func (o *consumerFileStore) rotateEncryptionKeys(prf, oldprf keyGen) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+	keyFile := filepath.Join(o.odir, JetStreamMetaFileKey)
+	if err := rewrapKeyFile(keyFile, o.fs.cfg.Name+tsep+o.name, prf, oldprf); err != nil {
+		return err
+	}
+	o.prf = prf
+	return nil
+}
+
 // Write out the consumer meta data, i.e. state.
 // Lock should be held.
 func (cfs *consumerFileStore) writeConsumerMeta() error {
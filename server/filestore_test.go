@@ -4024,3 +4024,277 @@ func TestFileStoreShortIndexWriteBug(t *testing.T) {
 		t.Fatalf("Expected first sequence of 101 vs %d", state.FirstSeq)
 	}
 }
+
+func TestFileStoreStationKeyRotation(t *testing.T) {
+	if id := stationKeyId("$G", fmt.Sprintf(dlsStreamName, "orders")); id != stationKeyId("$G", "orders") {
+		t.Fatalf("Expected the DLS stream to share the station key, got %q", id)
+	}
+
+	storeDir := t.TempDir()
+
+	oldPrf, newPrf := stationKeyGen([]byte("old-station-key"), "$G"), stationKeyGen([]byte("new-station-key"), "$G")
+	fcfg, cfg := FileStoreConfig{StoreDir: storeDir, BlockSize: 256}, StreamConfig{Name: "orders", Storage: FileStorage}
+	fs, err := newFileStoreWithCreated(fcfg, cfg, time.Now(), oldPrf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fs.Stop()
+
+	subj, msg := "orders.final", []byte("Hello World")
+	for i := 0; i < 20; i++ {
+		fs.StoreMsg(subj, nil, msg)
+	}
+	if err := fs.rotateEncryptionKeys(newPrf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		fs.StoreMsg(subj, nil, msg)
+	}
+	fs.Stop()
+
+	if fs, err = newFileStoreWithCreated(fcfg, cfg, time.Now(), oldPrf); err == nil {
+		fs.Stop()
+		t.Fatalf("Expected the old key to no longer open the store")
+	}
+	fs, err = newFileStoreWithCreated(fcfg, cfg, time.Now(), newPrf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fs.Stop()
+	if state := fs.State(); state.Msgs != 25 {
+		t.Fatalf("Expected 25 msgs, got %d", state.Msgs)
+	}
+	for seq := uint64(1); seq <= 25; seq++ {
+		sm, err := fs.LoadMsg(seq, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error loading seq %d: %v", seq, err)
+		}
+		if !bytes.Equal(sm.msg, msg) {
+			t.Fatalf("Expected msg %q at seq %d, got %q", msg, seq, sm.msg)
+		}
+	}
+}
+
+func TestFileStoreStationKeyRotationCrash(t *testing.T) {
+	storeDir := t.TempDir()
+
+	oldPrf, newPrf := stationKeyGen([]byte("old-station-key"), "$G"), stationKeyGen([]byte("new-station-key"), "$G")
+	fcfg, cfg := FileStoreConfig{StoreDir: storeDir, BlockSize: 256}, StreamConfig{Name: "orders", Storage: FileStorage}
+	fs, err := newFileStoreWithCreated(fcfg, cfg, time.Now(), oldPrf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fs.Stop()
+
+	subj, msg := "orders.final", []byte("Hello World")
+	for i := 0; i < 20; i++ {
+		fs.StoreMsg(subj, nil, msg)
+	}
+	if _, err := fs.ConsumerStore("dlc", &ConsumerConfig{AckPolicy: AckExplicit}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fs.numMsgBlocks() < 4 {
+		t.Fatalf("Expected at least 4 blocks, got %d", fs.numMsgBlocks())
+	}
+
+	// Rotate the stream meta and the first block only, then crash in the middle of the next two blocks.
+	fs.mu.Lock()
+	fs.oldprf, fs.prf = fs.prf, newPrf
+	if err := rewrapKeyFile(filepath.Join(storeDir, JetStreamMetaFileKey), cfg.Name, newPrf, oldPrf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := fs.reencryptBlock(fs.blks[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mdir := filepath.Join(storeDir, msgDir)
+
+	// Crashed before the re-encrypted block was moved into place.
+	staged := fs.blks[1].index
+	os.WriteFile(filepath.Join(mdir, fmt.Sprintf(newScan, staged)), []byte("partial block"), defaultFilePerms)
+	os.WriteFile(filepath.Join(mdir, fmt.Sprintf(newKeyScan, staged)), []byte("partial key"), defaultFilePerms)
+
+	// Crashed after the re-encrypted block was moved into place but before its key.
+	moved := fs.blks[2].index
+	kfn := filepath.Join(mdir, fmt.Sprintf(keyScan, moved))
+	oldKey, err := os.ReadFile(kfn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := fs.reencryptBlock(fs.blks[2]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.Rename(kfn, filepath.Join(mdir, fmt.Sprintf(newKeyScan, moved))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	os.WriteFile(kfn, oldKey, defaultFilePerms)
+	fs.mu.Unlock()
+	fs.Stop()
+
+	checkMsgs := func(fs *fileStore) {
+		t.Helper()
+		if state := fs.State(); state.Msgs != 20 {
+			t.Fatalf("Expected 20 msgs, got %d", state.Msgs)
+		}
+		for seq := uint64(1); seq <= 20; seq++ {
+			sm, err := fs.LoadMsg(seq, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error loading seq %d: %v", seq, err)
+			}
+			if !bytes.Equal(sm.msg, msg) {
+				t.Fatalf("Expected msg %q at seq %d, got %q", msg, seq, sm.msg)
+			}
+		}
+		if _, err := fs.ConsumerStore("dlc", &ConsumerConfig{AckPolicy: AckExplicit}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// The new key alone can not open the blocks that were not rotated yet.
+	if fs, err = newFileStoreWithCreated(fcfg, cfg, time.Now(), newPrf); err == nil {
+		fs.Stop()
+		t.Fatalf("Expected the new key alone to not open the store")
+	}
+	fs, err = newFileStoreWithKeys(fcfg, cfg, time.Now(), newPrf, oldPrf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkMsgs(fs)
+	for _, index := range []uint64{staged, moved} {
+		for _, scan := range []string{newScan, newKeyScan} {
+			if _, err := os.Stat(filepath.Join(mdir, fmt.Sprintf(scan, index))); !os.IsNotExist(err) {
+				t.Fatalf("Expected staged file %q to be gone", fmt.Sprintf(scan, index))
+			}
+		}
+	}
+
+	// Resume the rotation, after which the previous key is no longer needed.
+	if err := fs.rotateEncryptionKeys(newPrf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fs.Stop()
+	fs, err = newFileStoreWithCreated(fcfg, cfg, time.Now(), newPrf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fs.Stop()
+	checkMsgs(fs)
+}
+
+func TestFileStoreCompression(t *testing.T) {
+	for _, alg := range []StoreCompression{S2Compression, ZstdCompression} {
+		t.Run(alg.String(), func(t *testing.T) {
+			storeDir := t.TempDir()
+
+			prf := stationKeyGen([]byte("station-key"), "$G")
+			fcfg, cfg := FileStoreConfig{StoreDir: storeDir, BlockSize: 4096}, StreamConfig{Name: "orders", Storage: FileStorage, Compression: alg}
+			fs, err := newFileStoreWithCreated(fcfg, cfg, time.Now(), prf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer fs.Stop()
+
+			subj, msg := "orders.final", bytes.Repeat([]byte(`{"order_id":1,"status":"shipped"}`), 10)
+			for i := 0; i < 100; i++ {
+				fs.StoreMsg(subj, nil, msg)
+			}
+			checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+				stats := fs.compressionStats()
+				if stats == nil || stats.CompressedBlocks != fs.numMsgBlocks()-1 {
+					return fmt.Errorf("expected all sealed blocks to be compressed, got %+v", stats)
+				}
+				if stats.Ratio <= 1 {
+					return fmt.Errorf("expected a compression ratio above 1, got %f", stats.Ratio)
+				}
+				return nil
+			})
+			fs.Stop()
+
+			// Make sure the blocks are rebuilt from the compressed files.
+			idxFiles, _ := filepath.Glob(filepath.Join(storeDir, msgDir, "*.idx"))
+			for _, idxFile := range idxFiles {
+				os.Remove(idxFile)
+			}
+			fs, err = newFileStoreWithCreated(fcfg, cfg, time.Now(), prf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer fs.Stop()
+			fs.StoreMsg(subj, nil, msg)
+			if state := fs.State(); state.Msgs != 101 {
+				t.Fatalf("Expected 101 msgs, got %d", state.Msgs)
+			}
+			for seq := uint64(1); seq <= 101; seq++ {
+				sm, err := fs.LoadMsg(seq, nil)
+				if err != nil {
+					t.Fatalf("Unexpected error loading seq %d: %v", seq, err)
+				}
+				if !bytes.Equal(sm.msg, msg) {
+					t.Fatalf("Unexpected msg at seq %d", seq)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreCompressionCompactHead(t *testing.T) {
+	storeDir := t.TempDir()
+	fcfg, cfg := FileStoreConfig{StoreDir: storeDir, BlockSize: 4096}, StreamConfig{Name: "orders", Storage: FileStorage, Compression: S2Compression}
+	fs, err := newFileStoreWithCreated(fcfg, cfg, time.Now(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fs.Stop()
+
+	subj, msg := "orders.final", bytes.Repeat([]byte(`{"order_id":1,"status":"shipped"}`), 10)
+	for i := 0; i < 50; i++ {
+		fs.StoreMsg(subj, nil, msg)
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if stats := fs.compressionStats(); stats == nil || stats.CompressedBlocks != fs.numMsgBlocks()-1 {
+			return fmt.Errorf("expected all sealed blocks to be compressed, got %+v", stats)
+		}
+		return nil
+	})
+
+	fs.mu.RLock()
+	mb := fs.blks[0]
+	fs.mu.RUnlock()
+	mb.mu.RLock()
+	rawSize, diskSize, lastSeq := mb.rbytes, mb.cbytes, mb.last.seq
+	mb.mu.RUnlock()
+	if diskSize == 0 || rawSize <= diskSize {
+		t.Fatalf("Expected the raw size %d of a compressed block above its size on disk %d", rawSize, diskSize)
+	}
+
+	// Reclaiming the head rewrites the block uncompressed.
+	if _, err := fs.Compact(lastSeq - 1); err != nil {
+		t.Fatalf("Unexpected error compacting: %v", err)
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		mb.mu.RLock()
+		defer mb.mu.RUnlock()
+		if mb.rbytes >= rawSize || mb.bytes > mb.rbytes {
+			return fmt.Errorf("expected the raw size of the reclaimed head, got %d out of %d", mb.rbytes, rawSize)
+		}
+		if mb.cmp != S2Compression || mb.cbytes == 0 || mb.cbytes >= mb.rbytes {
+			return fmt.Errorf("expected the reclaimed head to be compressed again, got %v with %d bytes on disk", mb.cmp, mb.cbytes)
+		}
+		return nil
+	})
+	fs.Stop()
+
+	fs, err = newFileStoreWithCreated(fcfg, cfg, time.Now(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer fs.Stop()
+	for seq := lastSeq - 1; seq <= 50; seq++ {
+		sm, err := fs.LoadMsg(seq, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error loading seq %d: %v", seq, err)
+		}
+		if !bytes.Equal(sm.msg, msg) {
+			t.Fatalf("Unexpected msg at seq %d", seq)
+		}
+	}
+}
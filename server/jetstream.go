@@ -229,32 +229,30 @@ func (s *Server) jsKeyGen(info string) keyGen {
 }
 
 // Decode the encrypted metafile.
-func (s *Server) decryptMeta(ekey, buf []byte, acc, context string) ([]byte, error) {
+func (s *Server) decryptMeta(ekey, buf []byte, acc, stream, context string) ([]byte, error) {
 	if len(ekey) != metaKeySize {
 		return nil, errors.New("bad encryption key")
 	}
-	prf := s.jsKeyGen(acc)
+	prf := s.streamKeyGen(acc, stream)
 	if prf == nil {
 		return nil, errNoEncryption
 	}
-	rb, err := prf([]byte(context))
+	seed, err := openEncryptionKey(prf, ekey, context)
 	if err != nil {
-		return nil, err
-	}
-	kek, err := chacha20poly1305.NewX(rb)
-	if err != nil {
-		return nil, err
-	}
-	ns := kek.NonceSize()
-	seed, err := kek.Open(nil, ekey[:ns], ekey[ns:], nil)
-	if err != nil {
-		return nil, err
+		// The key may not be re-sealed yet if a station key rotation was interrupted.
+		oldprf := s.streamPreviousKeyGen(acc, stream)
+		if oldprf == nil {
+			return nil, err
+		}
+		if seed, err = openEncryptionKey(oldprf, ekey, context); err != nil {
+			return nil, err
+		}
 	}
 	aek, err := chacha20poly1305.NewX(seed[:])
 	if err != nil {
 		return nil, err
 	}
-	ns = kek.NonceSize()
+	ns := aek.NonceSize()
 	plain, err := aek.Open(nil, buf[:ns], buf[ns:], nil)
 	if err != nil {
 		return nil, err
@@ -1158,7 +1156,7 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits) erro
 				continue
 			}
 			// Decode the buffer before proceeding.
-			if buf, err = s.decryptMeta(key, buf, a.Name, fi.Name()); err != nil {
+			if buf, err = s.decryptMeta(key, buf, a.Name, fi.Name(), fi.Name()); err != nil {
 				s.Warnf("  Error decrypting our stream metafile: %v", err)
 				continue
 			}
@@ -1249,7 +1247,7 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits) erro
 			if key, err := ioutil.ReadFile(filepath.Join(e.odir, ofi.Name(), JetStreamMetaFileKey)); err == nil {
 				s.Debugf("  Consumer metafile is encrypted, reading encrypted keyfile")
 				// Decode the buffer before proceeding.
-				if buf, err = s.decryptMeta(key, buf, a.Name, e.mset.name()+tsep+ofi.Name()); err != nil {
+				if buf, err = s.decryptMeta(key, buf, a.Name, e.mset.name(), e.mset.name()+tsep+ofi.Name()); err != nil {
 					s.Warnf("  Error decrypting our consumer metafile: %v", err)
 					continue
 				}
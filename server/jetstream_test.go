@@ -18968,3 +18968,35 @@ func TestJetStreamMirrorUpdatesNotSupported(t *testing.T) {
 		t.Fatalf("Expected error %q, got %q", NewJSStreamMirrorNotUpdatableError(), err)
 	}
 }
+
+func TestJetStreamMirrorPromotion(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+	defer s.Shutdown()
+
+	acc := s.GlobalAccount()
+	_, err := acc.addStream(&StreamConfig{Name: "SOURCE", Subjects: []string{"src"}, Storage: MemoryStorage})
+	require_NoError(t, err)
+	_, err = acc.addStream(&StreamConfig{Name: "OTHER", Subjects: []string{"other"}, Storage: MemoryStorage})
+	require_NoError(t, err)
+	mset, err := acc.addStream(&StreamConfig{Name: "M", Mirror: &StreamSource{Name: "SOURCE"}, Storage: MemoryStorage})
+	require_NoError(t, err)
+
+	cfg := mset.config()
+	cfg.Mirror = &StreamSource{Name: "OTHER"}
+	if err := mset.update(&cfg); err == nil || !strings.Contains(err.Error(), NewJSStreamMirrorNotUpdatableError().Error()) {
+		t.Fatalf("Expected error %q, got %v", NewJSStreamMirrorNotUpdatableError(), err)
+	}
+
+	cfg.Mirror = nil
+	cfg.Subjects = []string{"m"}
+	require_NoError(t, mset.update(&cfg))
+	if mset.isMirror() {
+		t.Fatalf("Expected the promoted stream to stop mirroring")
+	}
+	if ncfg := mset.config(); ncfg.Mirror != nil || len(ncfg.Subjects) != 1 || ncfg.Subjects[0] != "m" {
+		t.Fatalf("Unexpected config after promotion: %+v", ncfg)
+	}
+}
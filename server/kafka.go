@@ -0,0 +1,2027 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"memphis-broker/models"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kafka API keys served by the listener
+const (
+	kafkaApiProduce          int16 = 0
+	kafkaApiFetch            int16 = 1
+	kafkaApiListOffsets      int16 = 2
+	kafkaApiMetadata         int16 = 3
+	kafkaApiOffsetCommit     int16 = 8
+	kafkaApiOffsetFetch      int16 = 9
+	kafkaApiFindCoordinator  int16 = 10
+	kafkaApiJoinGroup        int16 = 11
+	kafkaApiHeartbeat        int16 = 12
+	kafkaApiLeaveGroup       int16 = 13
+	kafkaApiSyncGroup        int16 = 14
+	kafkaApiSaslHandshake    int16 = 17
+	kafkaApiApiVersions      int16 = 18
+	kafkaApiSaslAuthenticate int16 = 36
+)
+
+// Kafka protocol error codes
+const (
+	kafkaErrUnknownServerError         int16 = -1
+	kafkaErrNone                       int16 = 0
+	kafkaErrOffsetOutOfRange           int16 = 1
+	kafkaErrCorruptMessage             int16 = 2
+	kafkaErrUnknownTopicOrPartition    int16 = 3
+	kafkaErrRequestTimedOut            int16 = 7
+	kafkaErrInvalidTopic               int16 = 17
+	kafkaErrIllegalGeneration          int16 = 22
+	kafkaErrInconsistentGroupProtocol  int16 = 23
+	kafkaErrInvalidGroupId             int16 = 24
+	kafkaErrUnknownMemberId            int16 = 25
+	kafkaErrInvalidSessionTimeout      int16 = 26
+	kafkaErrRebalanceInProgress        int16 = 27
+	kafkaErrUnsupportedSaslMechanism   int16 = 33
+	kafkaErrIllegalSaslState           int16 = 34
+	kafkaErrUnsupportedVersion         int16 = 35
+	kafkaErrPolicyViolation            int16 = 44
+	kafkaErrSaslAuthenticationFailed   int16 = 58
+	kafkaErrUnsupportedCompressionType int16 = 76
+)
+
+const (
+	kafkaNodeId                 = int32(0)
+	kafkaMaxRequestSize         = 100 * 1024 * 1024
+	kafkaSaslPlain              = "PLAIN"
+	kafkaConsumerProtocolType   = "consumer"
+	kafkaReplySubjectTemplate   = "$memphis_kafka_reply_%s"
+	kafkaProducerHeaderValue    = "kafka"
+	kafkaMinSessionTimeout      = 6 * time.Second
+	kafkaMaxSessionTimeout      = 5 * time.Minute
+	kafkaGroupsCheckInterval    = time.Second
+	kafkaFetchPollInterval      = 50 * time.Millisecond
+	kafkaDefaultProduceTimeout  = 30 * time.Second
+	kafkaCompressionCodecMask   = 0x07
+	kafkaCompressionGzip        = 1
+	kafkaRecordBatchMagic       = 2
+	kafkaRecordBatchHeaderSize  = 61
+	kafkaRecordBatchCrcOffset   = 21
+	kafkaEarliestOffset         = int64(-2)
+	kafkaLatestOffset           = int64(-1)
+	kafkaInternalHeadersPrefix  = "$memphis"
+	kafkaMemberIdPrefix         = "kafka-"
+	kafkaMemberIdRandomPartSize = 10
+)
+
+// kafkaApiVersionRange is the range of versions of an API the listener is able to parse and encode,
+// only versions which are not using the flexible encoding are served
+type kafkaApiVersionRange struct {
+	apiKey     int16
+	minVersion int16
+	maxVersion int16
+}
+
+var kafkaSupportedApis = []kafkaApiVersionRange{
+	{kafkaApiProduce, 3, 3},
+	{kafkaApiFetch, 4, 4},
+	{kafkaApiListOffsets, 1, 1},
+	{kafkaApiMetadata, 1, 1},
+	{kafkaApiOffsetCommit, 2, 2},
+	{kafkaApiOffsetFetch, 1, 1},
+	{kafkaApiFindCoordinator, 1, 1},
+	{kafkaApiJoinGroup, 2, 2},
+	{kafkaApiHeartbeat, 1, 1},
+	{kafkaApiLeaveGroup, 1, 1},
+	{kafkaApiSyncGroup, 1, 1},
+	{kafkaApiSaslHandshake, 1, 1},
+	{kafkaApiApiVersions, 0, 2},
+	{kafkaApiSaslAuthenticate, 0, 0},
+}
+
+var (
+	errKafkaShortBuffer        = errors.New("kafka: malformed request")
+	errKafkaUnsupportedRequest = errors.New("kafka: unsupported request")
+	errKafkaNotAuthenticated   = errors.New("kafka: client is not authenticated")
+)
+
+type srvKafka struct {
+	mu          sync.Mutex
+	listener    net.Listener
+	listenerErr error
+	conns       map[*kafkaConn]struct{}
+	groups      map[string]*kafkaGroup
+}
+
+func isKafkaApiVersionSupported(apiKey, apiVersion int16) bool {
+	for _, api := range kafkaSupportedApis {
+		if api.apiKey == apiKey {
+			return apiVersion >= api.minVersion && apiVersion <= api.maxVersion
+		}
+	}
+	return false
+}
+
+// validateKafkaOptions refuses a listener which would receive SASL PLAIN credentials in clear
+// unless no_tls is explicitly set
+func validateKafkaOptions(o *Options) error {
+	ko := &o.Kafka
+	if ko.Port == 0 {
+		return nil
+	}
+	if ko.TLSConfig == nil && !ko.NoTLS {
+		return errors.New("kafka requires TLS configuration since SASL PLAIN credentials are sent in clear, set no_tls to run without it")
+	}
+	return nil
+}
+
+func (s *Server) startKafka() {
+	sopts := s.getOpts()
+	o := &sopts.Kafka
+
+	port := o.Port
+	if port == -1 {
+		port = 0
+	}
+	hp := net.JoinHostPort(o.Host, strconv.Itoa(port))
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return
+	}
+	hl, err := net.Listen("tcp", hp)
+	s.kafka.listenerErr = err
+	if err != nil {
+		s.mu.Unlock()
+		s.Fatalf("Unable to listen for Kafka connections: %v", err)
+		return
+	}
+	if port == 0 {
+		o.Port = hl.Addr().(*net.TCPAddr).Port
+	}
+	s.kafka.listener = hl
+	s.kafka.conns = make(map[*kafkaConn]struct{})
+	s.kafka.groups = make(map[string]*kafkaGroup)
+	s.Noticef("Listening for Kafka clients on kafka://%s:%d", o.Host, o.Port)
+	if o.TLSConfig == nil {
+		s.Warnf("Kafka not configured with TLS. DO NOT USE IN PRODUCTION!")
+	}
+	go s.acceptConnections(hl, "Kafka", func(conn net.Conn) { s.createKafkaConn(conn) }, nil)
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		s.expireKafkaMembersLoop()
+	})
+	s.mu.Unlock()
+}
+
+// closeKafkaConns is called on shutdown after the listener has been closed
+func (s *Server) closeKafkaConns() {
+	s.kafka.mu.Lock()
+	conns := make([]*kafkaConn, 0, len(s.kafka.conns))
+	for kc := range s.kafka.conns {
+		conns = append(conns, kc)
+	}
+	s.kafka.mu.Unlock()
+	for _, kc := range conns {
+		kc.nc.Close()
+	}
+}
+
+// getKafkaAdvertise returns the host and port Kafka clients are told to connect to
+func (s *Server) getKafkaAdvertise() (string, int32) {
+	o := s.getOpts().Kafka
+	if o.Advertise != _EMPTY_ {
+		host, port, err := parseHostPort(o.Advertise, o.Port)
+		if err == nil {
+			return host, int32(port)
+		}
+	}
+	host := o.Host
+	if host == _EMPTY_ || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return host, int32(o.Port)
+}
+
+type kafkaReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *kafkaReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.buf) {
+		r.err = errKafkaShortBuffer
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *kafkaReader) int8() int8 {
+	b := r.read(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (r *kafkaReader) int16() int16 {
+	b := r.read(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *kafkaReader) int32() int32 {
+	b := r.read(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *kafkaReader) int64() int64 {
+	b := r.read(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *kafkaReader) string() string {
+	l := r.int16()
+	if l < 0 {
+		return _EMPTY_
+	}
+	return string(r.read(int(l)))
+}
+
+func (r *kafkaReader) bytes() []byte {
+	l := r.int32()
+	if l < 0 {
+		return nil
+	}
+	return r.read(int(l))
+}
+
+// arrayLen returns the number of elements of an array, null arrays are returned as -1
+func (r *kafkaReader) arrayLen() int {
+	l := r.int32()
+	if r.err != nil {
+		return 0
+	}
+	if int(l) > len(r.buf)-r.pos {
+		r.err = errKafkaShortBuffer
+		return 0
+	}
+	return int(l)
+}
+
+func (r *kafkaReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		r.err = errKafkaShortBuffer
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *kafkaReader) varBytes() []byte {
+	l := r.varint()
+	if l < 0 {
+		return nil
+	}
+	return r.read(int(l))
+}
+
+type kafkaWriter struct {
+	buf []byte
+}
+
+func (w *kafkaWriter) int8(v int8) {
+	w.buf = append(w.buf, byte(v))
+}
+
+func (w *kafkaWriter) bool(v bool) {
+	if v {
+		w.int8(1)
+	} else {
+		w.int8(0)
+	}
+}
+
+func (w *kafkaWriter) int16(v int16) {
+	w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v))
+}
+
+func (w *kafkaWriter) int32(v int32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v))
+}
+
+func (w *kafkaWriter) int64(v int64) {
+	w.buf = binary.BigEndian.AppendUint64(w.buf, uint64(v))
+}
+
+func (w *kafkaWriter) string(v string) {
+	w.int16(int16(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *kafkaWriter) nullableString(v string) {
+	if v == _EMPTY_ {
+		w.int16(-1)
+		return
+	}
+	w.string(v)
+}
+
+func (w *kafkaWriter) bytes(v []byte) {
+	if v == nil {
+		w.int32(-1)
+		return
+	}
+	w.int32(int32(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *kafkaWriter) arrayLen(l int) {
+	w.int32(int32(l))
+}
+
+func (w *kafkaWriter) varint(v int64) {
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *kafkaWriter) varBytes(v []byte) {
+	if v == nil {
+		w.varint(-1)
+		return
+	}
+	w.varint(int64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+type kafkaHeader struct {
+	key   string
+	value []byte
+}
+
+type kafkaRecord struct {
+	offset    int64
+	timestamp int64
+	key       []byte
+	value     []byte
+	headers   []kafkaHeader
+}
+
+var kafkaCrcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// decodeKafkaRecordBatches decodes the v2 record batches produced by a client, older message formats are rejected
+func decodeKafkaRecordBatches(data []byte) ([]kafkaRecord, int16) {
+	var records []kafkaRecord
+	r := &kafkaReader{buf: data}
+	for r.pos < len(data) && r.err == nil {
+		r.int64() // base offset, assigned by the broker
+		batchLength := int(r.int32())
+		batch := r.read(batchLength)
+		if r.err != nil {
+			return nil, kafkaErrCorruptMessage
+		}
+		br := &kafkaReader{buf: batch}
+		br.int32() // partition leader epoch
+		if br.int8() != kafkaRecordBatchMagic {
+			return nil, kafkaErrCorruptMessage
+		}
+		crc := uint32(br.int32())
+		if br.err != nil || crc32.Checksum(batch[br.pos:], kafkaCrcTable) != crc {
+			return nil, kafkaErrCorruptMessage
+		}
+		attributes := br.int16()
+		br.int32() // last offset delta
+		baseTimestamp := br.int64()
+		br.int64() // max timestamp
+		br.int64() // producer id
+		br.int16() // producer epoch
+		br.int32() // base sequence
+		count := br.arrayLen()
+		if br.err != nil {
+			return nil, kafkaErrCorruptMessage
+		}
+
+		recordsData := batch[br.pos:]
+		switch attributes & kafkaCompressionCodecMask {
+		case 0:
+		case kafkaCompressionGzip:
+			zr, err := gzip.NewReader(bytes.NewReader(recordsData))
+			if err != nil {
+				return nil, kafkaErrCorruptMessage
+			}
+			recordsData, err = io.ReadAll(zr)
+			if err != nil {
+				return nil, kafkaErrCorruptMessage
+			}
+		default:
+			return nil, kafkaErrUnsupportedCompressionType
+		}
+
+		rr := &kafkaReader{buf: recordsData}
+		for i := 0; i < count; i++ {
+			record := rr.read(int(rr.varint()))
+			if rr.err != nil {
+				return nil, kafkaErrCorruptMessage
+			}
+			recr := &kafkaReader{buf: record}
+			recr.int8() // attributes
+			timestamp := baseTimestamp + recr.varint()
+			recr.varint() // offset delta
+			key := recr.varBytes()
+			value := recr.varBytes()
+			headersCount := int(recr.varint())
+			headers := make([]kafkaHeader, 0, headersCount)
+			for j := 0; j < headersCount && recr.err == nil; j++ {
+				hkey := string(recr.varBytes())
+				headers = append(headers, kafkaHeader{key: hkey, value: recr.varBytes()})
+			}
+			if recr.err != nil {
+				return nil, kafkaErrCorruptMessage
+			}
+			records = append(records, kafkaRecord{timestamp: timestamp, key: key, value: value, headers: headers})
+		}
+	}
+	if r.err != nil {
+		return nil, kafkaErrCorruptMessage
+	}
+	return records, kafkaErrNone
+}
+
+// encodeKafkaRecordBatch encodes the records into a single uncompressed v2 record batch,
+// stream sequences are used as offsets so the deltas are not necessarily contiguous
+func encodeKafkaRecordBatch(records []kafkaRecord) []byte {
+	if len(records) == 0 {
+		return nil
+	}
+	base := records[0]
+	last := records[len(records)-1]
+	maxTimestamp := base.timestamp
+	body := &kafkaWriter{}
+	for _, record := range records {
+		if record.timestamp > maxTimestamp {
+			maxTimestamp = record.timestamp
+		}
+		rec := &kafkaWriter{}
+		rec.int8(0)
+		rec.varint(record.timestamp - base.timestamp)
+		rec.varint(record.offset - base.offset)
+		rec.varBytes(record.key)
+		rec.varBytes(record.value)
+		rec.varint(int64(len(record.headers)))
+		for _, header := range record.headers {
+			rec.varBytes([]byte(header.key))
+			rec.varBytes(header.value)
+		}
+		body.varint(int64(len(rec.buf)))
+		body.buf = append(body.buf, rec.buf...)
+	}
+
+	w := &kafkaWriter{buf: make([]byte, 0, kafkaRecordBatchHeaderSize+len(body.buf))}
+	w.int64(base.offset)
+	w.int32(int32(kafkaRecordBatchHeaderSize - 12 + len(body.buf)))
+	w.int32(0) // partition leader epoch
+	w.int8(kafkaRecordBatchMagic)
+	w.int32(0) // crc, computed below
+	w.int16(0) // attributes
+	w.int32(int32(last.offset - base.offset))
+	w.int64(base.timestamp)
+	w.int64(maxTimestamp)
+	w.int64(-1) // producer id
+	w.int16(-1) // producer epoch
+	w.int32(-1) // base sequence
+	w.arrayLen(len(records))
+	w.buf = append(w.buf, body.buf...)
+	crc := crc32.Checksum(w.buf[kafkaRecordBatchCrcOffset:], kafkaCrcTable)
+	binary.BigEndian.PutUint32(w.buf[kafkaRecordBatchCrcOffset-4:], crc)
+	return w.buf
+}
+
+type kafkaConn struct {
+	srv           *Server
+	nc            net.Conn
+	clientId      string
+	authenticated bool
+	saslStarted   bool
+	username      string
+	tenantName    string
+	acc           *Account
+	connectionId  primitive.ObjectID
+	replyPrefix   string
+	replySub      *subscription
+	mu            sync.Mutex
+	pendingAcks   map[string]chan *JSPubAckResponse
+	lastSeen      time.Time
+	reportedSeen  time.Time
+}
+
+func (s *Server) createKafkaConn(conn net.Conn) {
+	opts := s.getOpts()
+	if tlsConfig := opts.Kafka.TLSConfig; tlsConfig != nil {
+		tlsConn := tls.Server(conn, tlsConfig.Clone())
+		timeout := secondsToDuration(opts.Kafka.TLSTimeout)
+		conn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			s.Debugf("Kafka client %s: TLS handshake error: %v", conn.RemoteAddr().String(), err)
+			conn.Close()
+			return
+		}
+		conn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	kc := &kafkaConn{
+		srv:         s,
+		nc:          conn,
+		pendingAcks: make(map[string]chan *JSPubAckResponse),
+	}
+	s.kafka.mu.Lock()
+	s.kafka.conns[kc] = struct{}{}
+	s.kafka.mu.Unlock()
+
+	authTimeout := secondsToDuration(opts.Kafka.AuthTimeout)
+	if authTimeout == 0 {
+		authTimeout = secondsToDuration(opts.AuthTimeout)
+	}
+	if authTimeout > 0 {
+		time.AfterFunc(authTimeout, func() {
+			kc.mu.Lock()
+			authenticated := kc.authenticated
+			kc.mu.Unlock()
+			if !authenticated {
+				kc.nc.Close()
+			}
+		})
+	}
+
+	err := kc.readLoop()
+	if err != nil && err != io.EOF && !isConnectionClosedErr(err) {
+		s.Debugf("Kafka client %s: %v", conn.RemoteAddr().String(), err)
+	}
+	kc.close()
+}
+
+func isConnectionClosedErr(err error) bool {
+	return errors.Is(err, net.ErrClosed) || strings.Contains(err.Error(), "connection reset")
+}
+
+func (kc *kafkaConn) close() {
+	s := kc.srv
+	kc.nc.Close()
+	s.kafka.mu.Lock()
+	delete(s.kafka.conns, kc)
+	s.kafka.mu.Unlock()
+	if kc.replySub != nil {
+		s.unsubscribeOnAcc(kc.acc, kc.replySub)
+	}
+	if !kc.connectionId.IsZero() {
+		mci := memphisClientInfo{username: kc.username, connectionId: kc.connectionId}
+		if err := mci.updateDisconnection(); err != nil {
+			s.Errorf("Kafka client " + kc.clientId + ": " + err.Error())
+		}
+	}
+}
+
+func (kc *kafkaConn) readLoop() error {
+	br := bufio.NewReader(kc.nc)
+	sizeBuf := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(br, sizeBuf); err != nil {
+			return err
+		}
+		size := int(binary.BigEndian.Uint32(sizeBuf))
+		if size < 8 || size > kafkaMaxRequestSize {
+			return errKafkaShortBuffer
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return err
+		}
+		kc.mu.Lock()
+		kc.lastSeen = time.Now()
+		kc.mu.Unlock()
+
+		r := &kafkaReader{buf: payload}
+		apiKey := r.int16()
+		apiVersion := r.int16()
+		correlationId := r.int32()
+		kc.clientId = r.string()
+		if r.err != nil {
+			return r.err
+		}
+
+		var resp *kafkaWriter
+		var err error
+		if !isKafkaApiVersionSupported(apiKey, apiVersion) {
+			if apiKey != kafkaApiApiVersions {
+				return errKafkaUnsupportedRequest
+			}
+			// unsupported ApiVersions versions are answered with a v0 response so the client can downgrade
+			resp = kc.apiVersionsResponse(0, kafkaErrUnsupportedVersion)
+		} else {
+			if !kc.isAuthenticated() && apiKey != kafkaApiApiVersions && apiKey != kafkaApiSaslHandshake && apiKey != kafkaApiSaslAuthenticate {
+				return errKafkaNotAuthenticated
+			}
+			resp, err = kc.handleRequest(apiKey, apiVersion, r)
+			if err != nil && err != errKafkaNotAuthenticated {
+				return err
+			}
+		}
+		if resp == nil {
+			// a produce request with acks=0 is not answered
+			continue
+		}
+
+		frame := &kafkaWriter{buf: make([]byte, 0, 8+len(resp.buf))}
+		frame.int32(int32(4 + len(resp.buf)))
+		frame.int32(correlationId)
+		frame.buf = append(frame.buf, resp.buf...)
+		if _, werr := kc.nc.Write(frame.buf); werr != nil {
+			return werr
+		}
+		if err != nil {
+			// a failed authentication is answered before the connection is closed
+			return err
+		}
+	}
+}
+
+func (kc *kafkaConn) isAuthenticated() bool {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	return kc.authenticated
+}
+
+func (kc *kafkaConn) handleRequest(apiKey, apiVersion int16, r *kafkaReader) (*kafkaWriter, error) {
+	var resp *kafkaWriter
+	switch apiKey {
+	case kafkaApiApiVersions:
+		resp = kc.apiVersionsResponse(apiVersion, kafkaErrNone)
+	case kafkaApiSaslHandshake:
+		resp = kc.handleSaslHandshake(r)
+	case kafkaApiSaslAuthenticate:
+		var authenticated bool
+		resp, authenticated = kc.handleSaslAuthenticate(r)
+		if !authenticated {
+			return resp, errKafkaNotAuthenticated
+		}
+	case kafkaApiMetadata:
+		resp = kc.handleMetadata(r)
+	case kafkaApiProduce:
+		resp = kc.handleProduce(r)
+	case kafkaApiFetch:
+		resp = kc.handleFetch(r)
+	case kafkaApiListOffsets:
+		resp = kc.handleListOffsets(r)
+	case kafkaApiFindCoordinator:
+		resp = kc.handleFindCoordinator(r)
+	case kafkaApiJoinGroup:
+		resp = kc.handleJoinGroup(r)
+	case kafkaApiSyncGroup:
+		resp = kc.handleSyncGroup(r)
+	case kafkaApiHeartbeat:
+		resp = kc.handleHeartbeat(r)
+	case kafkaApiLeaveGroup:
+		resp = kc.handleLeaveGroup(r)
+	case kafkaApiOffsetCommit:
+		resp = kc.handleOffsetCommit(r)
+	case kafkaApiOffsetFetch:
+		resp = kc.handleOffsetFetch(r)
+	default:
+		return nil, errKafkaUnsupportedRequest
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return resp, nil
+}
+
+func (kc *kafkaConn) apiVersionsResponse(apiVersion int16, errorCode int16) *kafkaWriter {
+	w := &kafkaWriter{}
+	w.int16(errorCode)
+	w.arrayLen(len(kafkaSupportedApis))
+	for _, api := range kafkaSupportedApis {
+		w.int16(api.apiKey)
+		w.int16(api.minVersion)
+		w.int16(api.maxVersion)
+	}
+	if apiVersion >= 1 {
+		w.int32(0) // throttle time
+	}
+	return w
+}
+
+func (kc *kafkaConn) handleSaslHandshake(r *kafkaReader) *kafkaWriter {
+	mechanism := r.string()
+	w := &kafkaWriter{}
+	if mechanism != kafkaSaslPlain {
+		w.int16(kafkaErrUnsupportedSaslMechanism)
+	} else {
+		kc.saslStarted = true
+		w.int16(kafkaErrNone)
+	}
+	w.arrayLen(1)
+	w.string(kafkaSaslPlain)
+	return w
+}
+
+// handleSaslAuthenticate authenticates PLAIN credentials, the username is a Memphis application user
+// and the password is the connection token, the same credentials the Memphis SDKs connect with
+func (kc *kafkaConn) handleSaslAuthenticate(r *kafkaReader) (*kafkaWriter, bool) {
+	authBytes := r.bytes()
+	w := &kafkaWriter{}
+	fail := func(errorCode int16, message string) (*kafkaWriter, bool) {
+		kc.srv.Warnf("Kafka client " + kc.clientId + ": " + message)
+		w.int16(errorCode)
+		w.nullableString(message)
+		w.bytes([]byte{})
+		return w, false
+	}
+	if !kc.saslStarted || kc.isAuthenticated() {
+		return fail(kafkaErrIllegalSaslState, "SaslAuthenticate has to follow a PLAIN SaslHandshake")
+	}
+	parts := strings.Split(string(authBytes), "\x00")
+	if len(parts) != 3 {
+		return fail(kafkaErrSaslAuthenticationFailed, "Invalid PLAIN credentials")
+	}
+	username := strings.ToLower(parts[1])
+	password := parts[2]
+	if !comparePasswords(kc.srv.getOpts().Authorization, password) {
+		return fail(kafkaErrSaslAuthenticationFailed, "Authentication failed for user "+username)
+	}
+	exist, user, err := IsUserExist(username)
+	if err != nil {
+		kc.srv.Errorf("Kafka client " + kc.clientId + ": User " + username + ": " + err.Error())
+		return fail(kafkaErrSaslAuthenticationFailed, "Authentication failed for user "+username)
+	}
+	if !exist || (user.UserType != "root" && user.UserType != "application") {
+		return fail(kafkaErrSaslAuthenticationFailed, "Authentication failed for user "+username)
+	}
+
+	tenantName := getUserTenantName(user)
+	acc, err := kc.srv.getTenantAccount(tenantName)
+	if err != nil {
+		kc.srv.Errorf("Kafka client " + kc.clientId + ": User " + username + ": " + err.Error())
+		return fail(kafkaErrSaslAuthenticationFailed, "Authentication failed for user "+username)
+	}
+	connectionId := primitive.NewObjectID()
+	err = connectionsHandler.CreateConnection(username, kc.nc.RemoteAddr().String(), connectionId, models.ConnectionMetadata{SdkLang: "kafka", ClientName: kc.clientId})
+	if err != nil {
+		kc.srv.Errorf("Kafka client " + kc.clientId + ": User " + username + ": " + err.Error())
+		return fail(kafkaErrSaslAuthenticationFailed, "Authentication failed for user "+username)
+	}
+	kc.replyPrefix = fmt.Sprintf(kafkaReplySubjectTemplate, nuid.Next())
+	sub, err := kc.srv.subscribeOnAcc(acc, kc.replyPrefix+".*", kc.replyPrefix+"_sid", kc.handlePubAck)
+	if err != nil {
+		kc.srv.Errorf("Kafka client " + kc.clientId + ": User " + username + ": " + err.Error())
+		return fail(kafkaErrSaslAuthenticationFailed, "Authentication failed for user "+username)
+	}
+
+	kc.mu.Lock()
+	kc.authenticated = true
+	kc.username = username
+	kc.tenantName = tenantName
+	kc.acc = acc
+	kc.connectionId = connectionId
+	kc.replySub = sub
+	kc.mu.Unlock()
+
+	w.int16(kafkaErrNone)
+	w.nullableString(_EMPTY_)
+	w.bytes([]byte{})
+	return w, true
+}
+
+func (kc *kafkaConn) handlePubAck(_ *client, subject, _ string, msg []byte) {
+	var resp JSPubAckResponse
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		resp.Error = &ApiError{Code: 500, Description: err.Error()}
+	}
+	kc.mu.Lock()
+	ch, ok := kc.pendingAcks[subject]
+	delete(kc.pendingAcks, subject)
+	kc.mu.Unlock()
+	if ok {
+		ch <- &resp
+	}
+}
+
+// getKafkaTopicStation maps a topic onto the station with the same name, stations are created on demand
+// the same way the Memphis SDKs create them when autoCreate is set
+func (kc *kafkaConn) getKafkaTopicStation(topic string, autoCreate bool) (models.Station, int16) {
+	sn, err := StationNameFromStr(topic)
+	if err != nil {
+		return models.Station{}, kafkaErrInvalidTopic
+	}
+	exist, station, err := IsStationExist(sn, kc.tenantName)
+	if err != nil {
+		kc.srv.Errorf("Kafka client " + kc.clientId + ": Station " + topic + ": " + err.Error())
+		return models.Station{}, kafkaErrUnknownServerError
+	}
+	if exist {
+		return station, kafkaErrNone
+	}
+	if !autoCreate {
+		return models.Station{}, kafkaErrUnknownTopicOrPartition
+	}
+
+	station, created, err := CreateDefaultStation(kc.srv, sn, kc.username, kc.tenantName)
+	if err != nil {
+		kc.srv.Errorf("Kafka client " + kc.clientId + ": creating default station " + topic + ": " + err.Error())
+		return models.Station{}, kafkaErrUnknownServerError
+	}
+	if created {
+		message := "Station " + sn.Ext() + " has been created by user " + kc.username + " through the Kafka listener"
+		kc.srv.Noticef(message)
+		var auditLogs []interface{}
+		newAuditLog := models.AuditLog{
+			ID:            primitive.NewObjectID(),
+			StationName:   sn.Ext(),
+			Message:       message,
+			CreatedByUser: kc.username,
+			CreationDate:  time.Now(),
+			UserType:      "application",
+		}
+		auditLogs = append(auditLogs, newAuditLog)
+		if err = CreateAuditLogs(auditLogs); err != nil {
+			kc.srv.Errorf("Kafka client " + kc.clientId + ": Station " + topic + ": " + err.Error())
+		}
+	}
+	return station, kafkaErrNone
+}
+
+// getKafkaPartitionsNumber returns the number of Kafka partitions of a station, a station which is not
+// partitioned is served as a single partition topic
+func getKafkaPartitionsNumber(station models.Station) int {
+	if isPartitioned(station.PartitionsNumber) {
+		return station.PartitionsNumber
+	}
+	return 1
+}
+
+func (kc *kafkaConn) handleMetadata(r *kafkaReader) *kafkaWriter {
+	topicsCount := r.arrayLen()
+	var topics []string
+	for i := 0; i < topicsCount; i++ {
+		topics = append(topics, r.string())
+	}
+
+	type topicMetadata struct {
+		name       string
+		errorCode  int16
+		partitions int
+	}
+	var metadata []topicMetadata
+	if topicsCount < 0 {
+		var stations []models.Station
+		cursor, err := stationsCollection.Find(context.TODO(), bson.M{"tenant_name": kc.tenantName, "is_deleted": false})
+		if err == nil {
+			err = cursor.All(context.TODO(), &stations)
+		}
+		if err != nil {
+			kc.srv.Errorf("Kafka client " + kc.clientId + ": listing stations: " + err.Error())
+		}
+		for _, station := range stations {
+			metadata = append(metadata, topicMetadata{name: station.Name, partitions: getKafkaPartitionsNumber(station)})
+		}
+	} else {
+		for _, topic := range topics {
+			station, errorCode := kc.getKafkaTopicStation(topic, true)
+			metadata = append(metadata, topicMetadata{name: topic, errorCode: errorCode, partitions: getKafkaPartitionsNumber(station)})
+		}
+	}
+
+	host, port := kc.srv.getKafkaAdvertise()
+	w := &kafkaWriter{}
+	w.arrayLen(1)
+	w.int32(kafkaNodeId)
+	w.string(host)
+	w.int32(port)
+	w.nullableString(_EMPTY_) // rack
+	w.int32(kafkaNodeId)      // controller id
+	w.arrayLen(len(metadata))
+	for _, topic := range metadata {
+		w.int16(topic.errorCode)
+		w.string(topic.name)
+		w.bool(false) // is internal
+		if topic.errorCode != kafkaErrNone {
+			w.arrayLen(0)
+			continue
+		}
+		w.arrayLen(topic.partitions)
+		for partition := 0; partition < topic.partitions; partition++ {
+			w.int16(kafkaErrNone)
+			w.int32(int32(partition))
+			w.int32(kafkaNodeId)
+			w.arrayLen(1)
+			w.int32(kafkaNodeId)
+			w.arrayLen(1)
+			w.int32(kafkaNodeId)
+		}
+	}
+	return w
+}
+
+// getKafkaPartitionSubject returns the subject the messages of a Kafka partition are stored under
+func getKafkaPartitionSubject(sn StationName, station models.Station, partition int) string {
+	if isPartitioned(station.PartitionsNumber) {
+		return getPartitionSubject(sn.Intern(), partition)
+	}
+	return sn.Intern() + ".final"
+}
+
+// getKafkaPartitionFilter returns the subject filter used to read a Kafka partition
+func getKafkaPartitionFilter(sn StationName, station models.Station, partition int) string {
+	if isPartitioned(station.PartitionsNumber) {
+		return getPartitionSubject(sn.Intern(), partition)
+	}
+	if filter := getStationFilterSubject(sn, station); filter != _EMPTY_ {
+		return filter
+	}
+	return fwcs
+}
+
+func (kc *kafkaConn) getProducerName() string {
+	if kc.clientId == _EMPTY_ {
+		return kafkaProducerHeaderValue
+	}
+	return kc.clientId
+}
+
+func (kc *kafkaConn) publish(subject string, record kafkaRecord, withAck bool) chan *JSPubAckResponse {
+	hdr := map[string]string{
+		"$memphis_connectionId": kc.connectionId.Hex(),
+		"$memphis_producedBy":   kc.getProducerName(),
+	}
+	for _, header := range record.headers {
+		if strings.HasPrefix(header.key, kafkaInternalHeadersPrefix) {
+			continue
+		}
+		hdr[header.key] = string(header.value)
+	}
+	if record.key != nil {
+		hdr[partitionKeyHeader] = string(record.key)
+		// a record without a value is a Kafka tombstone
+		if record.value == nil {
+			hdr[compactionTombstoneHeader] = "true"
+		}
+	}
+
+	var ackCh chan *JSPubAckResponse
+	reply := _EMPTY_
+	if withAck {
+		reply = kc.replyPrefix + "." + nuid.Next()
+		ackCh = make(chan *JSPubAckResponse, 1)
+		kc.mu.Lock()
+		kc.pendingAcks[reply] = ackCh
+		kc.mu.Unlock()
+	}
+	kc.srv.sendInternalAccountMsgWithReply(kc.acc, subject, reply, hdr, record.value, true)
+	return ackCh
+}
+
+func (kc *kafkaConn) handleProduce(r *kafkaReader) *kafkaWriter {
+	r.string() // transactional id
+	acks := r.int16()
+	timeout := time.Duration(r.int32()) * time.Millisecond
+	if timeout <= 0 {
+		timeout = kafkaDefaultProduceTimeout
+	}
+
+	type partitionResult struct {
+		partition  int32
+		errorCode  int16
+		baseOffset int64
+		acks       []chan *JSPubAckResponse
+	}
+	type topicResult struct {
+		name       string
+		partitions []*partitionResult
+	}
+
+	topicsCount := r.arrayLen()
+	topics := make([]topicResult, 0, topicsCount)
+	for i := 0; i < topicsCount && r.err == nil; i++ {
+		topic := topicResult{name: r.string()}
+		station, stationErr := kc.getKafkaTopicStation(topic.name, true)
+		if stationErr == kafkaErrNone && isActiveMirror(station) {
+			kc.srv.Warnf("Kafka client " + kc.clientId + ": Station " + topic.name + " is a mirror and can not be produced to")
+			stationErr = kafkaErrPolicyViolation
+		}
+		sn, _ := StationNameFromStr(topic.name)
+		partitionsCount := r.arrayLen()
+		for j := 0; j < partitionsCount && r.err == nil; j++ {
+			result := &partitionResult{partition: r.int32(), errorCode: stationErr, baseOffset: -1}
+			data := r.bytes()
+			topic.partitions = append(topic.partitions, result)
+			if result.errorCode != kafkaErrNone {
+				continue
+			}
+			if result.partition < 0 || int(result.partition) >= getKafkaPartitionsNumber(station) {
+				result.errorCode = kafkaErrUnknownTopicOrPartition
+				continue
+			}
+			records, errorCode := decodeKafkaRecordBatches(data)
+			if errorCode != kafkaErrNone {
+				result.errorCode = errorCode
+				continue
+			}
+			subject := getKafkaPartitionSubject(sn, station, int(result.partition))
+			for _, record := range records {
+				if ackCh := kc.publish(subject, record, acks != 0); ackCh != nil {
+					result.acks = append(result.acks, ackCh)
+				}
+			}
+		}
+		topics = append(topics, topic)
+	}
+	if acks == 0 {
+		return nil
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for _, topic := range topics {
+		for _, result := range topic.partitions {
+			for _, ackCh := range result.acks {
+				if result.errorCode != kafkaErrNone {
+					break
+				}
+				select {
+				case ack := <-ackCh:
+					if ack.Error != nil || ack.PubAck == nil {
+						result.errorCode = kafkaErrUnknownServerError
+						if ack.Error != nil {
+							kc.srv.Warnf("Kafka client " + kc.clientId + ": Station " + topic.name + ": " + ack.Error.Description)
+						}
+						continue
+					}
+					if result.baseOffset < 0 {
+						result.baseOffset = int64(ack.PubAck.Sequence)
+					}
+				case <-deadline.C:
+					result.errorCode = kafkaErrRequestTimedOut
+				}
+			}
+		}
+	}
+	// acks which did not arrive in time are dropped, requests of a connection are served one at a time
+	kc.mu.Lock()
+	kc.pendingAcks = make(map[string]chan *JSPubAckResponse)
+	kc.mu.Unlock()
+
+	w := &kafkaWriter{}
+	w.arrayLen(len(topics))
+	for _, topic := range topics {
+		w.string(topic.name)
+		w.arrayLen(len(topic.partitions))
+		for _, result := range topic.partitions {
+			w.int32(result.partition)
+			w.int16(result.errorCode)
+			w.int64(result.baseOffset)
+			w.int64(-1) // log append time
+		}
+	}
+	w.int32(0) // throttle time
+	return w
+}
+
+func kafkaRecordFromStoreMsg(sm *StoreMsg) kafkaRecord {
+	record := kafkaRecord{
+		offset:    int64(sm.seq),
+		timestamp: sm.ts / int64(time.Millisecond),
+		value:     copyBytes(sm.msg),
+	}
+	if len(sm.hdr) == 0 {
+		return record
+	}
+	headers, err := DecodeHeader(sm.hdr)
+	if err != nil {
+		return record
+	}
+	for key, value := range headers {
+		if key == partitionKeyHeader {
+			record.key = []byte(value)
+			continue
+		}
+		if strings.HasPrefix(key, kafkaInternalHeadersPrefix) {
+			continue
+		}
+		record.headers = append(record.headers, kafkaHeader{key: key, value: []byte(value)})
+	}
+	return record
+}
+
+type kafkaPartitionRead struct {
+	errorCode     int16
+	highWatermark int64
+	records       []kafkaRecord
+	size          int
+}
+
+// readKafkaPartition reads the messages of a partition starting at the offset, offsets are stream sequences
+func (kc *kafkaConn) readKafkaPartition(topic string, partition int32, offset int64, maxBytes int) kafkaPartitionRead {
+	station, errorCode := kc.getKafkaTopicStation(topic, false)
+	if errorCode != kafkaErrNone {
+		return kafkaPartitionRead{errorCode: errorCode, highWatermark: -1}
+	}
+	if partition < 0 || int(partition) >= getKafkaPartitionsNumber(station) {
+		return kafkaPartitionRead{errorCode: kafkaErrUnknownTopicOrPartition, highWatermark: -1}
+	}
+	sn, _ := StationNameFromStr(station.Name)
+	mset, err := kc.acc.lookupStream(sn.Intern())
+	if err != nil {
+		return kafkaPartitionRead{errorCode: kafkaErrUnknownTopicOrPartition, highWatermark: -1}
+	}
+
+	result := kafkaPartitionRead{highWatermark: int64(mset.state().LastSeq + 1)}
+	if offset < 0 || offset > result.highWatermark {
+		result.errorCode = kafkaErrOffsetOutOfRange
+		return result
+	}
+	filter := getKafkaPartitionFilter(sn, station, int(partition))
+	wc := subjectHasWildcard(filter)
+	start := uint64(offset)
+	for result.size < maxBytes || len(result.records) == 0 {
+		sm, _, err := mset.store.LoadNextMsg(filter, wc, start, &StoreMsg{})
+		if err != nil {
+			break
+		}
+		result.records = append(result.records, kafkaRecordFromStoreMsg(sm))
+		result.size += len(sm.hdr) + len(sm.msg)
+		start = sm.seq + 1
+	}
+	return result
+}
+
+func (kc *kafkaConn) handleFetch(r *kafkaReader) *kafkaWriter {
+	r.int32() // replica id
+	maxWait := time.Duration(r.int32()) * time.Millisecond
+	minBytes := int(r.int32())
+	maxBytes := int(r.int32())
+	r.int8() // isolation level
+
+	type fetchPartition struct {
+		partition int32
+		offset    int64
+		maxBytes  int
+	}
+	type fetchTopic struct {
+		name       string
+		partitions []fetchPartition
+	}
+	topicsCount := r.arrayLen()
+	topics := make([]fetchTopic, 0, topicsCount)
+	for i := 0; i < topicsCount && r.err == nil; i++ {
+		topic := fetchTopic{name: r.string()}
+		partitionsCount := r.arrayLen()
+		for j := 0; j < partitionsCount && r.err == nil; j++ {
+			topic.partitions = append(topic.partitions, fetchPartition{partition: r.int32(), offset: r.int64(), maxBytes: int(r.int32())})
+		}
+		topics = append(topics, topic)
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(maxWait)
+	var reads [][]kafkaPartitionRead
+	for {
+		total := 0
+		reads = make([][]kafkaPartitionRead, len(topics))
+		for i, topic := range topics {
+			for _, partition := range topic.partitions {
+				budget := partition.maxBytes
+				if remaining := maxBytes - total; remaining < budget {
+					budget = remaining
+				}
+				read := kafkaPartitionRead{highWatermark: -1}
+				if budget > 0 || total == 0 {
+					read = kc.readKafkaPartition(topic.name, partition.partition, partition.offset, budget)
+				}
+				total += read.size
+				reads[i] = append(reads[i], read)
+			}
+		}
+		if total > 0 || total >= minBytes || !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(kafkaFetchPollInterval)
+	}
+
+	w := &kafkaWriter{}
+	w.int32(0) // throttle time
+	w.arrayLen(len(topics))
+	for i, topic := range topics {
+		w.string(topic.name)
+		w.arrayLen(len(topic.partitions))
+		for j, partition := range topic.partitions {
+			read := reads[i][j]
+			w.int32(partition.partition)
+			w.int16(read.errorCode)
+			w.int64(read.highWatermark)
+			w.int64(read.highWatermark) // last stable offset
+			w.arrayLen(0)               // aborted transactions
+			w.bytes(encodeKafkaRecordBatch(read.records))
+		}
+	}
+	return w
+}
+
+func (kc *kafkaConn) handleListOffsets(r *kafkaReader) *kafkaWriter {
+	r.int32() // replica id
+	w := &kafkaWriter{}
+	topicsCount := r.arrayLen()
+	w.arrayLen(topicsCount)
+	for i := 0; i < topicsCount && r.err == nil; i++ {
+		topic := r.string()
+		w.string(topic)
+		partitionsCount := r.arrayLen()
+		w.arrayLen(partitionsCount)
+		for j := 0; j < partitionsCount && r.err == nil; j++ {
+			partition := r.int32()
+			timestamp := r.int64()
+			errorCode, ts, offset := kc.listPartitionOffset(topic, partition, timestamp)
+			w.int32(partition)
+			w.int16(errorCode)
+			w.int64(ts)
+			w.int64(offset)
+		}
+	}
+	return w
+}
+
+// listPartitionOffset resolves the earliest and latest offsets and the first offset at or after a timestamp
+func (kc *kafkaConn) listPartitionOffset(topic string, partition int32, timestamp int64) (int16, int64, int64) {
+	station, errorCode := kc.getKafkaTopicStation(topic, false)
+	if errorCode != kafkaErrNone {
+		return errorCode, -1, -1
+	}
+	if partition < 0 || int(partition) >= getKafkaPartitionsNumber(station) {
+		return kafkaErrUnknownTopicOrPartition, -1, -1
+	}
+	sn, _ := StationNameFromStr(station.Name)
+	mset, err := kc.acc.lookupStream(sn.Intern())
+	if err != nil {
+		return kafkaErrUnknownTopicOrPartition, -1, -1
+	}
+	state := mset.state()
+	latest := int64(state.LastSeq + 1)
+	if timestamp == kafkaLatestOffset {
+		return kafkaErrNone, -1, latest
+	}
+
+	start := state.FirstSeq
+	if timestamp != kafkaEarliestOffset {
+		start = mset.store.GetSeqFromTime(time.UnixMilli(timestamp))
+	}
+	filter := getKafkaPartitionFilter(sn, station, int(partition))
+	sm, _, err := mset.store.LoadNextMsg(filter, subjectHasWildcard(filter), start, &StoreMsg{})
+	if err != nil {
+		if timestamp == kafkaEarliestOffset {
+			return kafkaErrNone, -1, latest
+		}
+		return kafkaErrNone, -1, -1
+	}
+	if timestamp == kafkaEarliestOffset {
+		return kafkaErrNone, -1, int64(sm.seq)
+	}
+	return kafkaErrNone, sm.ts / int64(time.Millisecond), int64(sm.seq)
+}
+
+const (
+	kafkaGroupEmpty = iota
+	kafkaGroupPreparingRebalance
+	kafkaGroupCompletingRebalance
+	kafkaGroupStable
+)
+
+type kafkaProtocol struct {
+	name     string
+	metadata []byte
+}
+
+type kafkaJoinResult struct {
+	errorCode  int16
+	generation int32
+	protocol   string
+	leader     string
+	memberId   string
+	members    []kafkaProtocolMember
+}
+
+type kafkaProtocolMember struct {
+	id       string
+	metadata []byte
+}
+
+type kafkaMember struct {
+	id               string
+	conn             *kafkaConn
+	sessionTimeout   time.Duration
+	rebalanceTimeout time.Duration
+	protocols        []kafkaProtocol
+	assignment       []byte
+	lastHeartbeat    time.Time
+	joinCh           chan kafkaJoinResult
+}
+
+// kafkaGroup is the coordinator state of a Kafka group, the group is mapped onto the Memphis consumer group
+// with the same name at every station its members are assigned to
+type kafkaGroup struct {
+	mu             sync.Mutex
+	tenantName     string
+	cgName         string
+	state          int
+	generation     int32
+	protocolType   string
+	protocol       string
+	leader         string
+	members        map[string]*kafkaMember
+	rebalanceTimer *time.Timer
+	syncCh         chan struct{}
+}
+
+func getKafkaCgName(groupId string) (string, error) {
+	cgName := strings.ToLower(groupId)
+	if cgName == _EMPTY_ {
+		return _EMPTY_, errors.New("group id can not be empty")
+	}
+	if err := validateConsumerName(cgName); err != nil {
+		return _EMPTY_, err
+	}
+	return cgName, nil
+}
+
+func newKafkaMemberId() string {
+	return kafkaMemberIdPrefix + strings.ToLower(nuid.Next()[:kafkaMemberIdRandomPartSize])
+}
+
+func (s *Server) getKafkaGroup(tenantName, cgName string, create bool) *kafkaGroup {
+	key := tenantStreamKey(tenantName, cgName)
+	s.kafka.mu.Lock()
+	defer s.kafka.mu.Unlock()
+	g, ok := s.kafka.groups[key]
+	if !ok && create {
+		g = &kafkaGroup{tenantName: tenantName, cgName: cgName, members: make(map[string]*kafkaMember)}
+		s.kafka.groups[key] = g
+	}
+	return g
+}
+
+// prepareRebalance asks all the members to rejoin, members which do not rejoin in time are removed.
+// Lock should be held.
+func (g *kafkaGroup) prepareRebalance(s *Server, timeout time.Duration) {
+	if g.state == kafkaGroupPreparingRebalance {
+		return
+	}
+	if g.syncCh != nil {
+		close(g.syncCh)
+		g.syncCh = nil
+	}
+	g.state = kafkaGroupPreparingRebalance
+	g.rebalanceTimer = time.AfterFunc(timeout, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.state == kafkaGroupPreparingRebalance {
+			g.completeJoin(s)
+		}
+	})
+}
+
+// Lock should be held.
+func (g *kafkaGroup) allJoined() bool {
+	for _, m := range g.members {
+		if m.joinCh == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Lock should be held.
+func (g *kafkaGroup) removeMember(s *Server, id string) {
+	delete(g.members, id)
+	if g.leader == id {
+		g.leader = _EMPTY_
+	}
+	go s.removeKafkaMemberConsumers(id)
+}
+
+// completeJoin starts a new generation with the members which rejoined and sends the members
+// list to the leader so it can compute the assignment.
+// Lock should be held.
+func (g *kafkaGroup) completeJoin(s *Server) {
+	if g.rebalanceTimer != nil {
+		g.rebalanceTimer.Stop()
+		g.rebalanceTimer = nil
+	}
+	for id, m := range g.members {
+		if m.joinCh == nil {
+			g.removeMember(s, id)
+		}
+	}
+	if len(g.members) == 0 {
+		g.state = kafkaGroupEmpty
+		return
+	}
+	if _, ok := g.members[g.leader]; !ok {
+		for id := range g.members {
+			if g.leader == _EMPTY_ || id < g.leader {
+				g.leader = id
+			}
+		}
+	}
+
+	g.protocol = _EMPTY_
+	for _, candidate := range g.members[g.leader].protocols {
+		supported := true
+		for _, m := range g.members {
+			if getKafkaProtocolMetadata(m.protocols, candidate.name) == nil {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			g.protocol = candidate.name
+			break
+		}
+	}
+	if g.protocol == _EMPTY_ {
+		for id, m := range g.members {
+			m.joinCh <- kafkaJoinResult{errorCode: kafkaErrInconsistentGroupProtocol, generation: -1, memberId: id}
+			g.removeMember(s, id)
+		}
+		g.state = kafkaGroupEmpty
+		return
+	}
+
+	g.generation++
+	g.state = kafkaGroupCompletingRebalance
+	g.syncCh = make(chan struct{})
+	var members []kafkaProtocolMember
+	for id, m := range g.members {
+		members = append(members, kafkaProtocolMember{id: id, metadata: getKafkaProtocolMetadata(m.protocols, g.protocol)})
+	}
+	for id, m := range g.members {
+		m.assignment = nil
+		m.lastHeartbeat = time.Now()
+		result := kafkaJoinResult{generation: g.generation, protocol: g.protocol, leader: g.leader, memberId: id}
+		if id == g.leader {
+			result.members = members
+		}
+		m.joinCh <- result
+		m.joinCh = nil
+	}
+}
+
+func getKafkaProtocolMetadata(protocols []kafkaProtocol, name string) []byte {
+	for _, protocol := range protocols {
+		if protocol.name == name {
+			if protocol.metadata == nil {
+				return []byte{}
+			}
+			return protocol.metadata
+		}
+	}
+	return nil
+}
+
+func (kc *kafkaConn) joinGroup(groupId, memberId, protocolType string, sessionTimeout, rebalanceTimeout time.Duration, protocols []kafkaProtocol) kafkaJoinResult {
+	s := kc.srv
+	cgName, err := getKafkaCgName(groupId)
+	if err != nil {
+		s.Warnf("Kafka client " + kc.clientId + ": Group " + groupId + ": " + err.Error())
+		return kafkaJoinResult{errorCode: kafkaErrInvalidGroupId, generation: -1, memberId: memberId}
+	}
+	if sessionTimeout < kafkaMinSessionTimeout || sessionTimeout > kafkaMaxSessionTimeout {
+		return kafkaJoinResult{errorCode: kafkaErrInvalidSessionTimeout, generation: -1, memberId: memberId}
+	}
+	if rebalanceTimeout < sessionTimeout {
+		rebalanceTimeout = sessionTimeout
+	}
+
+	g := s.getKafkaGroup(kc.tenantName, cgName, true)
+	g.mu.Lock()
+	if g.state != kafkaGroupEmpty && g.protocolType != protocolType {
+		g.mu.Unlock()
+		return kafkaJoinResult{errorCode: kafkaErrInconsistentGroupProtocol, generation: -1, memberId: memberId}
+	}
+	m, ok := g.members[memberId]
+	if memberId == _EMPTY_ {
+		m = &kafkaMember{id: newKafkaMemberId()}
+		g.members[m.id] = m
+	} else if !ok {
+		g.mu.Unlock()
+		return kafkaJoinResult{errorCode: kafkaErrUnknownMemberId, generation: -1, memberId: memberId}
+	}
+	m.conn = kc
+	m.sessionTimeout = sessionTimeout
+	m.rebalanceTimeout = rebalanceTimeout
+	m.protocols = protocols
+	m.lastHeartbeat = time.Now()
+	m.joinCh = make(chan kafkaJoinResult, 1)
+	joinCh := m.joinCh
+	g.protocolType = protocolType
+	g.prepareRebalance(s, rebalanceTimeout)
+	if g.allJoined() {
+		g.completeJoin(s)
+	}
+	g.mu.Unlock()
+
+	select {
+	case result := <-joinCh:
+		return result
+	case <-time.After(rebalanceTimeout + sessionTimeout):
+		return kafkaJoinResult{errorCode: kafkaErrRebalanceInProgress, generation: -1, memberId: m.id}
+	}
+}
+
+func (kc *kafkaConn) handleJoinGroup(r *kafkaReader) *kafkaWriter {
+	groupId := r.string()
+	sessionTimeout := time.Duration(r.int32()) * time.Millisecond
+	rebalanceTimeout := time.Duration(r.int32()) * time.Millisecond
+	memberId := r.string()
+	protocolType := r.string()
+	protocolsCount := r.arrayLen()
+	var protocols []kafkaProtocol
+	for i := 0; i < protocolsCount && r.err == nil; i++ {
+		protocols = append(protocols, kafkaProtocol{name: r.string(), metadata: r.bytes()})
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	result := kc.joinGroup(groupId, memberId, protocolType, sessionTimeout, rebalanceTimeout, protocols)
+	w := &kafkaWriter{}
+	w.int32(0) // throttle time
+	w.int16(result.errorCode)
+	w.int32(result.generation)
+	w.string(result.protocol)
+	w.string(result.leader)
+	w.string(result.memberId)
+	w.arrayLen(len(result.members))
+	for _, member := range result.members {
+		w.string(member.id)
+		w.bytes(member.metadata)
+	}
+	return w
+}
+
+func (kc *kafkaConn) syncGroup(groupId string, generation int32, memberId string, assignments map[string][]byte) (int16, []byte) {
+	s := kc.srv
+	cgName, err := getKafkaCgName(groupId)
+	if err != nil {
+		return kafkaErrInvalidGroupId, nil
+	}
+	g := s.getKafkaGroup(kc.tenantName, cgName, false)
+	if g == nil {
+		return kafkaErrUnknownMemberId, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	m, ok := g.members[memberId]
+	if !ok {
+		return kafkaErrUnknownMemberId, nil
+	}
+	if generation != g.generation {
+		return kafkaErrIllegalGeneration, nil
+	}
+
+	switch g.state {
+	case kafkaGroupStable:
+		return kafkaErrNone, m.assignment
+	case kafkaGroupCompletingRebalance:
+		if memberId == g.leader {
+			for id, assignment := range assignments {
+				if member, ok := g.members[id]; ok {
+					member.assignment = assignment
+				}
+			}
+			g.state = kafkaGroupStable
+			close(g.syncCh)
+			g.syncCh = nil
+			go s.registerKafkaGroupConsumers(g.tenantName, g.cgName, g.assignedTopics())
+			return kafkaErrNone, m.assignment
+		}
+		syncCh := g.syncCh
+		g.mu.Unlock()
+		select {
+		case <-syncCh:
+		case <-time.After(m.sessionTimeout):
+		}
+		g.mu.Lock()
+		if g.state != kafkaGroupStable || generation != g.generation {
+			return kafkaErrRebalanceInProgress, nil
+		}
+		return kafkaErrNone, m.assignment
+	default:
+		return kafkaErrRebalanceInProgress, nil
+	}
+}
+
+// assignedTopics decodes the consumer protocol assignments of the members.
+// Lock should be held.
+func (g *kafkaGroup) assignedTopics() map[*kafkaMember][]string {
+	assigned := make(map[*kafkaMember][]string, len(g.members))
+	if g.protocolType != kafkaConsumerProtocolType {
+		return assigned
+	}
+	for _, m := range g.members {
+		r := &kafkaReader{buf: m.assignment}
+		r.int16() // version
+		topicsCount := r.arrayLen()
+		for i := 0; i < topicsCount && r.err == nil; i++ {
+			topic := r.string()
+			partitionsCount := r.arrayLen()
+			for j := 0; j < partitionsCount && r.err == nil; j++ {
+				r.int32()
+			}
+			if r.err == nil {
+				assigned[m] = append(assigned[m], topic)
+			}
+		}
+	}
+	return assigned
+}
+
+// registerKafkaGroupConsumers registers the members of a Kafka group as the consumers of the Memphis
+// consumer group at the stations they are assigned to, so the group shows up like any other consumer group
+func (s *Server) registerKafkaGroupConsumers(tenantName, cgName string, assigned map[*kafkaMember][]string) {
+	for m, topics := range assigned {
+		for _, topic := range topics {
+			sn, err := StationNameFromStr(topic)
+			if err != nil {
+				continue
+			}
+			exist, station, err := IsStationExist(sn, tenantName)
+			if err != nil || !exist {
+				continue
+			}
+			newConsumer := models.Consumer{
+				ID:             primitive.NewObjectID(),
+				Name:           m.id,
+				StationId:      station.ID,
+				Type:           "application",
+				ConnectionId:   m.conn.connectionId,
+				CreatedByUser:  m.conn.username,
+				ConsumersGroup: cgName,
+				IsActive:       true,
+				CreationDate:   time.Now(),
+			}
+			if err = s.CreateConsumer(newConsumer, station); err != nil {
+				s.Errorf("registerKafkaGroupConsumers: Group " + cgName + " at station " + topic + ": " + err.Error())
+				continue
+			}
+			filter := bson.M{"name": newConsumer.Name, "station_id": station.ID, "is_active": true, "is_deleted": false}
+			update := bson.M{
+				"$setOnInsert": bson.M{
+					"_id":                newConsumer.ID,
+					"type":               newConsumer.Type,
+					"connection_id":      newConsumer.ConnectionId,
+					"created_by_user":    newConsumer.CreatedByUser,
+					"consumers_group":    newConsumer.ConsumersGroup,
+					"creation_date":      newConsumer.CreationDate,
+					"max_ack_time_ms":    newConsumer.MaxAckTimeMs,
+					"max_msg_deliveries": newConsumer.MaxMsgDeliveries,
+				},
+			}
+			opts := options.Update().SetUpsert(true)
+			updateResults, err := consumersCollection.UpdateOne(context.TODO(), filter, update, opts)
+			if err != nil {
+				s.Errorf("registerKafkaGroupConsumers: Group " + cgName + " at station " + topic + ": " + err.Error())
+				continue
+			}
+			if updateResults.MatchedCount == 0 {
+				s.memphisWSPublishEvent(tenantName, memphisWS_Event_CgMemberJoined, sn.Ext(), newConsumer)
+			}
+		}
+	}
+}
+
+func (s *Server) removeKafkaMemberConsumers(memberId string) {
+	_, err := consumersCollection.UpdateMany(context.TODO(),
+		bson.M{"name": memberId, "is_active": true},
+		bson.M{"$set": bson.M{"is_active": false, "is_deleted": true}},
+	)
+	if err != nil {
+		s.Errorf("removeKafkaMemberConsumers: Member " + memberId + ": " + err.Error())
+	}
+}
+
+func (kc *kafkaConn) handleSyncGroup(r *kafkaReader) *kafkaWriter {
+	groupId := r.string()
+	generation := r.int32()
+	memberId := r.string()
+	assignmentsCount := r.arrayLen()
+	assignments := make(map[string][]byte, assignmentsCount)
+	for i := 0; i < assignmentsCount && r.err == nil; i++ {
+		id := r.string()
+		assignments[id] = r.bytes()
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	errorCode, assignment := kc.syncGroup(groupId, generation, memberId, assignments)
+	if assignment == nil {
+		assignment = []byte{}
+	}
+	w := &kafkaWriter{}
+	w.int32(0) // throttle time
+	w.int16(errorCode)
+	w.bytes(assignment)
+	return w
+}
+
+// checkKafkaMember validates the generation of a member, a commit with generation -1 comes from a
+// consumer which manages its partitions without the group membership
+func (kc *kafkaConn) checkKafkaMember(cgName string, generation int32, memberId string, heartbeat bool) int16 {
+	g := kc.srv.getKafkaGroup(kc.tenantName, cgName, false)
+	if g == nil {
+		if generation < 0 {
+			return kafkaErrNone
+		}
+		return kafkaErrUnknownMemberId
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	m, ok := g.members[memberId]
+	if !ok {
+		if generation < 0 && len(g.members) == 0 {
+			return kafkaErrNone
+		}
+		return kafkaErrUnknownMemberId
+	}
+	if generation != g.generation {
+		return kafkaErrIllegalGeneration
+	}
+	if heartbeat {
+		m.lastHeartbeat = time.Now()
+	}
+	if g.state == kafkaGroupPreparingRebalance {
+		return kafkaErrRebalanceInProgress
+	}
+	return kafkaErrNone
+}
+
+func (kc *kafkaConn) handleHeartbeat(r *kafkaReader) *kafkaWriter {
+	groupId := r.string()
+	generation := r.int32()
+	memberId := r.string()
+
+	errorCode := kafkaErrInvalidGroupId
+	if cgName, err := getKafkaCgName(groupId); err == nil {
+		errorCode = kc.checkKafkaMember(cgName, generation, memberId, true)
+	}
+	w := &kafkaWriter{}
+	w.int32(0) // throttle time
+	w.int16(errorCode)
+	return w
+}
+
+func (kc *kafkaConn) handleLeaveGroup(r *kafkaReader) *kafkaWriter {
+	groupId := r.string()
+	memberId := r.string()
+
+	errorCode := kafkaErrNone
+	cgName, err := getKafkaCgName(groupId)
+	if err != nil {
+		errorCode = kafkaErrInvalidGroupId
+	} else if g := kc.srv.getKafkaGroup(kc.tenantName, cgName, false); g == nil {
+		errorCode = kafkaErrUnknownMemberId
+	} else {
+		g.mu.Lock()
+		if _, ok := g.members[memberId]; !ok {
+			errorCode = kafkaErrUnknownMemberId
+		} else {
+			g.leaveMember(kc.srv, memberId)
+		}
+		g.mu.Unlock()
+	}
+	w := &kafkaWriter{}
+	w.int32(0) // throttle time
+	w.int16(errorCode)
+	return w
+}
+
+// leaveMember removes a member and rebalances the remaining members.
+// Lock should be held.
+func (g *kafkaGroup) leaveMember(s *Server, memberId string) {
+	g.removeMember(s, memberId)
+	if len(g.members) == 0 {
+		if g.rebalanceTimer != nil {
+			g.rebalanceTimer.Stop()
+			g.rebalanceTimer = nil
+		}
+		if g.syncCh != nil {
+			close(g.syncCh)
+			g.syncCh = nil
+		}
+		g.state = kafkaGroupEmpty
+		return
+	}
+	var timeout time.Duration
+	for _, m := range g.members {
+		if m.rebalanceTimeout > timeout {
+			timeout = m.rebalanceTimeout
+		}
+	}
+	g.prepareRebalance(s, timeout)
+	if g.allJoined() {
+		g.completeJoin(s)
+	}
+}
+
+// expireKafkaMembersLoop removes the members which stopped sending heartbeats
+func (s *Server) expireKafkaMembersLoop() {
+	ticker := time.NewTicker(kafkaGroupsCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		case <-ticker.C:
+		}
+		s.kafka.mu.Lock()
+		groups := make([]*kafkaGroup, 0, len(s.kafka.groups))
+		for _, g := range s.kafka.groups {
+			groups = append(groups, g)
+		}
+		s.kafka.mu.Unlock()
+
+		now := time.Now()
+		for _, g := range groups {
+			g.mu.Lock()
+			for id, m := range g.members {
+				if m.joinCh == nil && now.Sub(m.lastHeartbeat) > m.sessionTimeout {
+					s.Debugf("Kafka group %s: member %s session expired", g.cgName, id)
+					g.leaveMember(s, id)
+				}
+			}
+			g.mu.Unlock()
+		}
+	}
+}
+
+func (kc *kafkaConn) handleFindCoordinator(r *kafkaReader) *kafkaWriter {
+	r.string() // key
+	r.int8()   // key type
+	host, port := kc.srv.getKafkaAdvertise()
+	w := &kafkaWriter{}
+	w.int32(0) // throttle time
+	w.int16(kafkaErrNone)
+	w.nullableString(_EMPTY_)
+	w.int32(kafkaNodeId)
+	w.string(host)
+	w.int32(port)
+	return w
+}
+
+// getKafkaGroupConsumer returns the durable consumer of the Memphis consumer group which keeps the
+// committed offset of a partition, the durable is created when create is set and it does not exist yet
+func (kc *kafkaConn) getKafkaGroupConsumer(cgName, topic string, partition int32, create bool) (*consumer, int16) {
+	station, errorCode := kc.getKafkaTopicStation(topic, false)
+	if errorCode != kafkaErrNone {
+		return nil, errorCode
+	}
+	if partition < 0 || int(partition) >= getKafkaPartitionsNumber(station) {
+		return nil, kafkaErrUnknownTopicOrPartition
+	}
+	sn, _ := StationNameFromStr(station.Name)
+	mset, err := kc.acc.lookupStream(sn.Intern())
+	if err != nil {
+		return nil, kafkaErrUnknownTopicOrPartition
+	}
+	durable := getInternalConsumerName(cgName)
+	if isPartitioned(station.PartitionsNumber) {
+		durable = getPartitionDurableName(cgName, int(partition))
+	}
+	o := mset.lookupConsumer(durable)
+	if o == nil && create {
+		err = kc.srv.CreateConsumer(models.Consumer{Name: cgName, ConsumersGroup: cgName}, station)
+		if err != nil {
+			kc.srv.Errorf("Kafka client " + kc.clientId + ": Group " + cgName + " at station " + topic + ": " + err.Error())
+			return nil, kafkaErrUnknownServerError
+		}
+		o = mset.lookupConsumer(durable)
+	}
+	return o, kafkaErrNone
+}
+
+func (kc *kafkaConn) handleOffsetCommit(r *kafkaReader) *kafkaWriter {
+	groupId := r.string()
+	generation := r.int32()
+	memberId := r.string()
+	r.int64() // retention time
+
+	groupErr := kafkaErrInvalidGroupId
+	cgName, err := getKafkaCgName(groupId)
+	if err == nil {
+		groupErr = kc.checkKafkaMember(cgName, generation, memberId, false)
+	}
+
+	w := &kafkaWriter{}
+	topicsCount := r.arrayLen()
+	w.arrayLen(topicsCount)
+	for i := 0; i < topicsCount && r.err == nil; i++ {
+		topic := r.string()
+		w.string(topic)
+		partitionsCount := r.arrayLen()
+		w.arrayLen(partitionsCount)
+		for j := 0; j < partitionsCount && r.err == nil; j++ {
+			partition := r.int32()
+			offset := r.int64()
+			r.string() // metadata
+			errorCode := groupErr
+			if errorCode == kafkaErrNone {
+				var o *consumer
+				o, errorCode = kc.getKafkaGroupConsumer(cgName, topic, partition, true)
+				if o != nil && offset > 0 {
+					// moves the ack floor of the consumer group, committed offsets do not move backwards
+					o.purge(uint64(offset), 0)
+				}
+			}
+			w.int32(partition)
+			w.int16(errorCode)
+		}
+	}
+	return w
+}
+
+func (kc *kafkaConn) handleOffsetFetch(r *kafkaReader) *kafkaWriter {
+	groupId := r.string()
+	cgName, cgErr := getKafkaCgName(groupId)
+
+	w := &kafkaWriter{}
+	topicsCount := r.arrayLen()
+	w.arrayLen(topicsCount)
+	for i := 0; i < topicsCount && r.err == nil; i++ {
+		topic := r.string()
+		w.string(topic)
+		partitionsCount := r.arrayLen()
+		w.arrayLen(partitionsCount)
+		for j := 0; j < partitionsCount && r.err == nil; j++ {
+			partition := r.int32()
+			offset := int64(-1)
+			errorCode := kafkaErrInvalidGroupId
+			if cgErr == nil {
+				var o *consumer
+				o, errorCode = kc.getKafkaGroupConsumer(cgName, topic, partition, false)
+				if o != nil {
+					if info := o.info(); info != nil && info.AckFloor.Stream > 0 {
+						offset = int64(info.AckFloor.Stream + 1)
+					}
+				}
+			}
+			w.int32(partition)
+			w.int64(offset)
+			w.nullableString(_EMPTY_)
+			w.int16(errorCode)
+		}
+	}
+	return w
+}
@@ -0,0 +1,132 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKafkaRecordBatch(t *testing.T) {
+	records := []kafkaRecord{
+		{offset: 10, timestamp: 1000, key: []byte("order-1"), value: []byte("created"), headers: []kafkaHeader{{key: "source", value: []byte("eu")}}},
+		{offset: 14, timestamp: 1005, value: []byte("updated")},
+	}
+	batch := encodeKafkaRecordBatch(records)
+	decoded, errorCode := decodeKafkaRecordBatches(batch)
+	if errorCode != kafkaErrNone {
+		t.Fatalf("Unexpected error code decoding the batch: %d", errorCode)
+	}
+	if len(decoded) != len(records) {
+		t.Fatalf("Expected %d records, got %d", len(records), len(decoded))
+	}
+	for i, record := range decoded {
+		if string(record.value) != string(records[i].value) || string(record.key) != string(records[i].key) || record.timestamp != records[i].timestamp {
+			t.Fatalf("Record %d does not match: %+v", i, record)
+		}
+	}
+	if decoded[1].key != nil || len(decoded[0].headers) != 1 || decoded[0].headers[0].key != "source" {
+		t.Fatalf("Unexpected keys or headers: %+v", decoded)
+	}
+
+	batch[len(batch)-1] ^= 0xff
+	if _, errorCode = decodeKafkaRecordBatches(batch); errorCode != kafkaErrCorruptMessage {
+		t.Fatalf("Expected a corrupted batch to be rejected, got %d", errorCode)
+	}
+}
+
+func TestKafkaValidateOptions(t *testing.T) {
+	o := DefaultOptions()
+	o.Kafka.Port = -1
+	if err := validateKafkaOptions(o); err == nil {
+		t.Fatalf("Expected a Kafka listener without TLS to be rejected")
+	}
+	o.Kafka.NoTLS = true
+	if err := validateKafkaOptions(o); err != nil {
+		t.Fatalf("Unexpected error with no_tls set: %v", err)
+	}
+	o.Kafka.NoTLS = false
+	tlsConfig, err := GenTLSConfig(&TLSConfigOpts{CertFile: "../test/configs/certs/server-cert.pem", KeyFile: "../test/configs/certs/server-key.pem"})
+	if err != nil {
+		t.Fatalf("Unexpected error generating TLS config: %v", err)
+	}
+	o.Kafka.TLSConfig = tlsConfig
+	if err := validateKafkaOptions(o); err != nil {
+		t.Fatalf("Unexpected error with TLS configured: %v", err)
+	}
+}
+
+func TestKafkaSaslOverTLS(t *testing.T) {
+	o := DefaultOptions()
+	o.Kafka.Host = "127.0.0.1"
+	o.Kafka.Port = -1
+	tlsConfig, err := GenTLSConfig(&TLSConfigOpts{CertFile: "../test/configs/certs/server-cert.pem", KeyFile: "../test/configs/certs/server-key.pem"})
+	if err != nil {
+		t.Fatalf("Unexpected error generating TLS config: %v", err)
+	}
+	o.Kafka.TLSConfig = tlsConfig
+	s := RunServer(o)
+	defer s.Shutdown()
+
+	addr := net.JoinHostPort(o.Kafka.Host, strconv.Itoa(o.Kafka.Port))
+	w := &kafkaWriter{}
+	w.int16(kafkaApiSaslHandshake)
+	w.int16(1)
+	w.int32(7)
+	w.string("test")
+	w.string(kafkaSaslPlain)
+	frame := &kafkaWriter{}
+	frame.int32(int32(len(w.buf)))
+	frame.buf = append(frame.buf, w.buf...)
+
+	// a client which does not start with a TLS handshake is dropped
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	defer nc.Close()
+	nc.Write(frame.buf)
+	nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(nc, make([]byte, 4)); err == nil {
+		t.Fatalf("Expected a plain connection to be closed")
+	}
+
+	tc, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Unexpected error connecting over TLS: %v", err)
+	}
+	defer tc.Close()
+	if _, err := tc.Write(frame.buf); err != nil {
+		t.Fatalf("Unexpected error writing: %v", err)
+	}
+	tc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	sizeBuf := make([]byte, 4)
+	if _, err := io.ReadFull(tc, sizeBuf); err != nil {
+		t.Fatalf("Unexpected error reading: %v", err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(sizeBuf))
+	if _, err := io.ReadFull(tc, resp); err != nil {
+		t.Fatalf("Unexpected error reading: %v", err)
+	}
+	r := &kafkaReader{buf: resp}
+	if correlationId := r.int32(); correlationId != 7 {
+		t.Fatalf("Unexpected correlation id %d", correlationId)
+	}
+	if errorCode := r.int16(); errorCode != kafkaErrNone {
+		t.Fatalf("Unexpected SaslHandshake error code %d", errorCode)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"errors"
+	"memphis-broker/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const maxStationSources = 32
+
+func isAggregateStation(station models.Station) bool {
+	return len(station.Sources) > 0
+}
+
+func getStreamSource(stationName, domain, filterSubject string) *StreamSource {
+	sn, err := StationNameFromStr(stationName)
+	if err != nil {
+		return nil
+	}
+	source := &StreamSource{Name: sn.Intern()}
+	if filterSubject != _EMPTY_ {
+		source.FilterSubject = sn.Intern() + tsep + filterSubject
+	}
+	if domain != _EMPTY_ {
+		source.External = &ExternalStream{ApiPrefix: "$JS." + domain + ".API"}
+	}
+	return source
+}
+
+func getStreamSources(sources []models.StationSource) []*StreamSource {
+	var streamSources []*StreamSource
+	for _, source := range sources {
+		if streamSource := getStreamSource(source.StationName, source.Domain, source.FilterSubject); streamSource != nil {
+			streamSources = append(streamSources, streamSource)
+		}
+	}
+	return streamSources
+}
+
+// validateSources normalizes the sources of an aggregate station,
+// sources of other domains are reached over a leafnode and can not be validated
+func validateSources(sn StationName, tenantName string, sources []models.StationSource, mirror models.MirrorSchema, partitionsNumber int) ([]models.StationSource, error) {
+	if len(sources) == 0 {
+		return []models.StationSource{}, nil
+	}
+	if len(sources) > maxStationSources {
+		return nil, errors.New("an aggregate station can not have more than 32 sources")
+	}
+	if mirror.StationName != _EMPTY_ {
+		return nil, errors.New("a mirror can not aggregate other stations")
+	}
+	if isPartitioned(partitionsNumber) {
+		return nil, errors.New("an aggregate station can not be partitioned")
+	}
+
+	validated := []models.StationSource{}
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		sourceName, err := StationNameFromStr(source.StationName)
+		if err != nil {
+			return nil, err
+		}
+		key := source.Domain + "/" + sourceName.Ext()
+		if seen[key] {
+			return nil, errors.New("station " + sourceName.Ext() + " appears more than once in the sources")
+		}
+		seen[key] = true
+
+		filterSubject := strings.Trim(source.FilterSubject, tsep)
+		if strings.ContainsAny(filterSubject, " \t") {
+			return nil, errors.New("the filter subject of source " + sourceName.Ext() + " is not valid")
+		}
+		if source.Domain == _EMPTY_ {
+			if sourceName.Ext() == sn.Ext() {
+				return nil, errors.New("a station can not aggregate itself")
+			}
+			exist, _, err := IsStationExist(sourceName, tenantName)
+			if err != nil {
+				return nil, err
+			}
+			if !exist {
+				return nil, errors.New("station " + sourceName.Ext() + " does not exist")
+			}
+		}
+		validated = append(validated, models.StationSource{StationName: sourceName.Ext(), Domain: source.Domain, FilterSubject: filterSubject})
+	}
+	return validated, nil
+}
+
+// getStationTopology returns the stations aggregated into the station with their lag and the stations aggregating it
+func (s *Server) getStationTopology(station models.Station) (models.StationTopology, error) {
+	topology := models.StationTopology{Sources: []models.SourceDetails{}, AggregatedBy: []string{}}
+	if isAggregateStation(station) {
+		sn, err := StationNameFromStr(station.Name)
+		if err != nil {
+			return topology, err
+		}
+		streamInfo, err := s.memphisStreamInfo(station.TenantName, sn.Intern())
+		if err != nil {
+			return topology, err
+		}
+		for _, source := range station.Sources {
+			details := models.SourceDetails{StationName: source.StationName, Domain: source.Domain, FilterSubject: source.FilterSubject}
+			streamSource := getStreamSource(source.StationName, source.Domain, source.FilterSubject)
+			for _, sourceInfo := range streamInfo.Sources {
+				if streamSource == nil || sourceInfo.Name != streamSource.Name || (sourceInfo.External == nil) != (streamSource.External == nil) {
+					continue
+				}
+				if sourceInfo.External != nil && sourceInfo.External.ApiPrefix != streamSource.External.ApiPrefix {
+					continue
+				}
+				details.Lag = sourceInfo.Lag
+				details.ActiveMs = sourceInfo.Active.Milliseconds()
+				if sourceInfo.Error != nil {
+					details.Error = sourceInfo.Error.Description
+				}
+			}
+			topology.Sources = append(topology.Sources, details)
+		}
+	}
+
+	var aggregates []models.Station
+	cursor, err := stationsCollection.Find(context.TODO(), bson.M{"tenant_name": station.TenantName, "is_deleted": false, "sources": bson.M{"$elemMatch": bson.M{"station_name": station.Name, "domain": _EMPTY_}}})
+	if err != nil {
+		return topology, err
+	}
+	if err = cursor.All(context.TODO(), &aggregates); err != nil {
+		return topology, err
+	}
+	for _, aggregate := range aggregates {
+		topology.AggregatedBy = append(topology.AggregatedBy, aggregate.Name)
+	}
+	return topology, nil
+}
+
+func (s *Server) updateStationSources(station models.Station, sources []models.StationSource) error {
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+	station.Sources = sources
+	err = s.memphisUpdateStream(station.TenantName, getStationStreamConfig(sn, station))
+	if err != nil {
+		return err
+	}
+	_, err = stationsCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": station.ID},
+		bson.M{"$set": bson.M{"sources": sources}},
+	)
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+)
+
+const (
+	compactionKeyHeader       = "$memphis_key"
+	compactionTombstoneHeader = "$memphis_tombstone"
+	defaultMaxMsgsPerKey      = 1
+	maxMsgsPerKeyLimit        = 64
+	maxCompactionKeyLen       = 256
+)
+
+func validateCompaction(maxMsgsPerKey int, partitionsNumber int, mirror models.MirrorSchema, sources []models.StationSource) error {
+	if maxMsgsPerKey < 0 || maxMsgsPerKey > maxMsgsPerKeyLimit {
+		return fmt.Errorf("max messages per key has to be between 1 and %d, or 0 for the default of %d", maxMsgsPerKeyLimit, defaultMaxMsgsPerKey)
+	}
+	if isPartitioned(partitionsNumber) {
+		return errors.New("compacted stations can not be partitioned, messages of a key are already kept in order")
+	}
+	if mirror.StationName != _EMPTY_ || len(sources) > 0 {
+		return errors.New("mirror and aggregate stations can not be compacted")
+	}
+	return nil
+}
+
+func getStationMaxMsgsPerKey(station models.Station) int64 {
+	if !station.Compacted {
+		return -1
+	}
+	if station.MaxMsgsPerKey <= 0 {
+		return defaultMaxMsgsPerKey
+	}
+	return int64(station.MaxMsgsPerKey)
+}
+
+// isCompactedStream relies on stations limiting the messages per subject only when they are compacted
+func isCompactedStream(cfg *StreamConfig) bool {
+	return cfg.MaxMsgsPer > 0 && cfg.AllowRollup
+}
+
+// getCompactedKeySubject encodes the key so any key can be used as a single subject token
+func getCompactedKeySubject(streamName string, key []byte) string {
+	return streamName + ".final." + base64.RawURLEncoding.EncodeToString(key)
+}
+
+// compactedSubject returns the subject a message produced into a compacted station is stored under,
+// tombstones roll up the subject of their key so only the delete marker is kept
+func compactedSubject(streamName, subject string, hdr []byte) (string, []byte, error) {
+	if subject != streamName+".final" {
+		return subject, hdr, nil
+	}
+	if len(getHeader(JSMsgRollup, hdr)) > 0 {
+		return _EMPTY_, nil, fmt.Errorf("rollup headers are not allowed, use the %s header to delete a key", compactionTombstoneHeader)
+	}
+
+	key := getHeader(compactionKeyHeader, hdr)
+	if len(key) == 0 {
+		// Kafka producers set the partition key
+		key = getHeader(partitionKeyHeader, hdr)
+	}
+	if len(key) == 0 {
+		return _EMPTY_, nil, fmt.Errorf("messages produced into a compacted station require the %s header", compactionKeyHeader)
+	}
+	if len(key) > maxCompactionKeyLen {
+		return _EMPTY_, nil, fmt.Errorf("message key can not be longer than %d bytes", maxCompactionKeyLen)
+	}
+
+	if len(getHeader(compactionTombstoneHeader, hdr)) > 0 {
+		hdr = genHeader(hdr, JSMsgRollup, JSMsgRollupSubject)
+	}
+	return getCompactedKeySubject(streamName, key), hdr, nil
+}
@@ -0,0 +1,357 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentFilterOpEq        = "eq"
+	contentFilterOpNeq       = "neq"
+	contentFilterOpContains  = "contains"
+	contentFilterOpExists    = "exists"
+	contentFilterOpNotExists = "not_exists"
+	contentFilterOpGt        = "gt"
+	contentFilterOpGte       = "gte"
+	contentFilterOpLt        = "lt"
+	contentFilterOpLte       = "lte"
+
+	// consecutive non-matching messages a consumer skips before giving way to the other goroutines
+	maxContentFilterSkips = 1024
+)
+
+// ContentFilter and ContentFilterStats are part of the consumer config and info
+type ContentFilter = models.ContentFilter
+type ContentFilterStats = models.ContentFilterStats
+
+// contentFilter is the compiled form of a consumer group's ContentFilter
+type contentFilter struct {
+	header   string
+	path     []jsonPathSegment
+	operator string
+	value    string
+	number   float64
+	isNumber bool
+}
+
+type jsonPathSegment struct {
+	key   string
+	index int // -1 when the segment is an object key
+}
+
+func compileContentFilter(cf *ContentFilter) (*contentFilter, error) {
+	if cf == nil {
+		return nil, nil
+	}
+	if (cf.Header == _EMPTY_) == (cf.JsonPath == _EMPTY_) {
+		return nil, errors.New("content filter has to define either a header or a json_path")
+	}
+
+	f := &contentFilter{header: cf.Header, operator: strings.ToLower(cf.Operator), value: cf.Value}
+	if cf.JsonPath != _EMPTY_ {
+		path, err := parseJsonPath(cf.JsonPath)
+		if err != nil {
+			return nil, err
+		}
+		f.path = path
+	}
+
+	switch f.operator {
+	case contentFilterOpExists, contentFilterOpNotExists, contentFilterOpContains:
+	case contentFilterOpEq, contentFilterOpNeq:
+		if number, err := strconv.ParseFloat(f.value, 64); err == nil {
+			f.number, f.isNumber = number, true
+		}
+	case contentFilterOpGt, contentFilterOpGte, contentFilterOpLt, contentFilterOpLte:
+		number, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("content filter operator %v requires a numeric value", f.operator)
+		}
+		f.number, f.isNumber = number, true
+	default:
+		return nil, errors.New("content filter operator has to be one of the following eq/neq/contains/exists/not_exists/gt/gte/lt/lte")
+	}
+	return f, nil
+}
+
+// parseJsonPath accepts dotted paths with array indexes, e.g. $.order.items[0].sku
+func parseJsonPath(path string) ([]jsonPathSegment, error) {
+	p := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if p == _EMPTY_ {
+		return nil, fmt.Errorf("json_path %v does not select any field", path)
+	}
+
+	var segments []jsonPathSegment
+	for _, part := range strings.Split(p, ".") {
+		key := part
+		var indexes []int
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+			for rest := part[i:]; rest != _EMPTY_; {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("json_path %v is not valid", path)
+				}
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("json_path %v has an invalid array index", path)
+				}
+				indexes = append(indexes, index)
+				rest = rest[end+1:]
+			}
+		}
+		if key == _EMPTY_ && len(indexes) == 0 {
+			return nil, fmt.Errorf("json_path %v has an empty field name", path)
+		}
+		if key != _EMPTY_ {
+			segments = append(segments, jsonPathSegment{key: key, index: -1})
+		}
+		for _, index := range indexes {
+			segments = append(segments, jsonPathSegment{index: index})
+		}
+	}
+	return segments, nil
+}
+
+func (f *contentFilter) matches(hdr, msg []byte) bool {
+	var value string
+	var found bool
+	if f.header != _EMPTY_ {
+		if len(hdr) > 0 {
+			if headers, err := DecodeHeader(hdr); err == nil {
+				value, found = headers[f.header]
+			}
+		}
+	} else {
+		value, found = lookupJsonPath(msg, f.path)
+	}
+
+	switch f.operator {
+	case contentFilterOpExists:
+		return found
+	case contentFilterOpNotExists:
+		return !found
+	case contentFilterOpNeq:
+		return !found || !f.equals(value)
+	}
+	if !found {
+		return false
+	}
+
+	switch f.operator {
+	case contentFilterOpEq:
+		return f.equals(value)
+	case contentFilterOpContains:
+		return strings.Contains(value, f.value)
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	switch f.operator {
+	case contentFilterOpGt:
+		return number > f.number
+	case contentFilterOpGte:
+		return number >= f.number
+	case contentFilterOpLt:
+		return number < f.number
+	case contentFilterOpLte:
+		return number <= f.number
+	}
+	return false
+}
+
+func (f *contentFilter) equals(value string) bool {
+	if value == f.value {
+		return true
+	}
+	if f.isNumber {
+		number, err := strconv.ParseFloat(value, 64)
+		return err == nil && number == f.number
+	}
+	return false
+}
+
+// lookupJsonPath returns the selected field as a string, non-JSON payloads never match.
+// The payload is scanned token by token and only the selected value is decoded
+func lookupJsonPath(msg []byte, path []jsonPathSegment) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+	for _, segment := range path {
+		if !seekJsonPathSegment(dec, segment) {
+			return _EMPTY_, false
+		}
+	}
+	var node interface{}
+	if err := dec.Decode(&node); err != nil {
+		return _EMPTY_, false
+	}
+
+	switch v := node.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "null", true
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return _EMPTY_, false
+		}
+		return string(raw), true
+	}
+}
+
+// seekJsonPathSegment advances the decoder to the value selected by the segment within the next value
+func seekJsonPathSegment(dec *json.Decoder, segment jsonPathSegment) bool {
+	tok, err := dec.Token()
+	if err != nil {
+		return false
+	}
+	if segment.index < 0 {
+		if tok != json.Delim('{') {
+			return false
+		}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return false
+			}
+			if key == segment.key {
+				return true
+			}
+			if err = skipJsonValue(dec); err != nil {
+				return false
+			}
+		}
+		return false
+	}
+
+	if tok != json.Delim('[') {
+		return false
+	}
+	for i := 0; dec.More(); i++ {
+		if i == segment.index {
+			return true
+		}
+		if err = skipJsonValue(dec); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+func skipJsonValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func isSameContentFilter(a, b *models.ContentFilter) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// filterOutMsg evaluates the content filter with the lock released since it may scan the whole payload.
+// Returns true with the lock released in case the message has been skipped, otherwise the lock is held again.
+// Lock should be held.
+func (o *consumer) filterOutMsg(pmsg *jsPubMsg, dc uint64, rp RetentionPolicy) bool {
+	f := o.cfilter
+	o.mu.Unlock()
+	matches := f.matches(pmsg.hdr, pmsg.msg)
+	o.mu.Lock()
+	// the consumer may have been closed meanwhile
+	if o.mset == nil {
+		pmsg.returnToPool()
+		o.mu.Unlock()
+		return true
+	}
+	if matches {
+		return false
+	}
+
+	sseq, dseq := o.skipFilteredMsg(pmsg, dc, rp)
+	pmsg.returnToPool()
+	o.mu.Unlock()
+	if dseq > 0 {
+		o.processAckMsg(sseq, dseq, dc, false)
+	}
+	return true
+}
+
+// Counts a message which does not match the content filter as delivered without sending it,
+// like deliverMsg does, so the ack that follows is stored and replicated like any other.
+// Returns the sequences to pass to processAckMsg once the lock is released, a zero dseq means no ack is needed.
+// Lock should be held.
+func (o *consumer) skipFilteredMsg(pmsg *jsPubMsg, dc uint64, rp RetentionPolicy) (uint64, uint64) {
+	// Update our cached num pending.
+	if dc == 1 && o.npcm > 0 {
+		o.npc--
+	}
+	dseq, sseq := o.dseq, pmsg.seq
+	o.dseq++
+	o.cfSkipped++
+
+	ap := o.cfg.AckPolicy
+	if ap == AckExplicit || ap == AckAll {
+		o.trackPending(sseq, dseq)
+	} else {
+		o.adflr, o.asflr = dseq, sseq
+	}
+	o.updateDelivered(dseq, sseq, dc, pmsg.ts)
+
+	if ap == AckNone {
+		if rp != LimitsPolicy {
+			if o.node == nil || o.cfg.Direct {
+				o.mset.ackq.push(sseq)
+			} else {
+				o.updateAcks(dseq, sseq)
+			}
+		}
+		return sseq, 0
+	}
+	return sseq, dseq
+}
+
+// Read lock should be held.
+func (o *consumer) contentFilterStats() *ContentFilterStats {
+	if o.cfilter == nil {
+		return nil
+	}
+	return &ContentFilterStats{Matched: o.cfMatched, Skipped: o.cfSkipped}
+}
@@ -42,6 +42,13 @@ type Handlers struct {
 	Schemas        SchemasHandler
 	Integrations   IntegrationsHandler
 	Configurations ConfigurationsHandler
+	Manifests      ManifestsHandler
+	Backup         BackupHandler
+	Tenants        TenantsHandler
+	SchemaRegistry SchemaRegistryHandler
+	Connections    ConnectionsHandler
+	Kv             KvHandler
+	Usage          UsageHandler
 }
 
 var usersCollection *mongo.Collection
@@ -58,6 +65,10 @@ var schemaVersionCollection *mongo.Collection
 var sandboxUsersCollection *mongo.Collection
 var integrationsCollection *mongo.Collection
 var configurationsCollection *mongo.Collection
+var tenantsCollection *mongo.Collection
+var kvBucketsCollection *mongo.Collection
+var stationsUsageCollection *mongo.Collection
+var usageSnapshotsCollection *mongo.Collection
 var serv *Server
 var configuration = conf.GetConfig()
 
@@ -74,7 +85,7 @@ type srvMemphis struct {
 }
 
 type memphisWS struct {
-	subscriptions map[string]memphisWSReqFiller
+	subscriptions map[string]*memphisWSSubscription
 	webSocketMu   sync.Mutex
 	quitCh        chan struct{}
 }
@@ -101,12 +112,62 @@ func (s *Server) InitializeMemphisHandlers(dbInstance db.DbInstance) {
 	sandboxUsersCollection = db.GetCollection("sandbox_users", serv.memphis.dbClient)
 	integrationsCollection = db.GetCollection("integrations", dbInstance.Client)
 	configurationsCollection = db.GetCollection("configurations", dbInstance.Client)
+	tenantsCollection = db.GetCollection("tenants", dbInstance.Client)
+	kvBucketsCollection = db.GetCollection("kv_buckets", dbInstance.Client)
+	stationsUsageCollection = db.GetCollection("stations_usage", dbInstance.Client)
+	usageSnapshotsCollection = db.GetCollection("usage_snapshots", dbInstance.Client)
 
-	s.initializeSDKHandlers()
+	s.initializeSDKHandlers(s.GlobalAccount())
 	s.initializeConfigurations()
+	err := s.initializeTenants()
+	if err != nil {
+		s.Errorf("InitializeMemphisHandlers: failed initializing tenants: " + err.Error())
+	}
+	err = s.initializeRateLimits()
+	if err != nil {
+		s.Errorf("InitializeMemphisHandlers: failed initializing rate limits: " + err.Error())
+	}
+	err = s.initializeStationPartitions()
+	if err != nil {
+		s.Errorf("InitializeMemphisHandlers: failed initializing station partitions: " + err.Error())
+	}
 	s.initWS()
 }
 
+// showableError is returned by the logic shared between the REST handlers and the manifests
+// when its message can be shown to the user as is, any other error is reported as a server error
+type showableError struct {
+	statusCode int
+	msg        string
+}
+
+func (e *showableError) Error() string {
+	return e.msg
+}
+
+func newShowableError(msg string) error {
+	return &showableError{statusCode: configuration.SHOWABLE_ERROR_STATUS_CODE, msg: msg}
+}
+
+func newShowableErrorWithStatus(statusCode int, msg string) error {
+	return &showableError{statusCode: statusCode, msg: msg}
+}
+
+func isShowableError(err error) bool {
+	var se *showableError
+	return errors.As(err, &se)
+}
+
+// abortWithError aborts a request with an error returned from the shared handlers logic, the error is already logged
+func abortWithError(c *gin.Context, err error) {
+	var se *showableError
+	if errors.As(err, &se) {
+		c.AbortWithStatusJSON(se.statusCode, gin.H{"message": se.msg})
+		return
+	}
+	c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+}
+
 func getUserDetailsFromMiddleware(c *gin.Context) (models.User, error) {
 	user, _ := c.Get("user")
 	userModel := user.(models.User)
@@ -128,10 +189,11 @@ func IsUserExist(username string) (bool, models.User, error) {
 	return true, user, nil
 }
 
-func IsStationExist(sn StationName) (bool, models.Station, error) {
+func IsStationExist(sn StationName, tenantName string) (bool, models.Station, error) {
 	stationName := sn.Ext()
 	filter := bson.M{
-		"name": stationName,
+		"name":        stationName,
+		"tenant_name": tenantName,
 		"$or": []interface{}{
 			bson.M{"is_deleted": false},
 			bson.M{"is_deleted": bson.M{"$exists": false}},
@@ -147,6 +209,22 @@ func IsStationExist(sn StationName) (bool, models.Station, error) {
 	return true, station, nil
 }
 
+func IsKvBucketExist(bucketName, tenantName string) (bool, models.KvBucket, error) {
+	filter := bson.M{
+		"name":        bucketName,
+		"tenant_name": tenantName,
+		"is_deleted":  false,
+	}
+	var bucket models.KvBucket
+	err := kvBucketsCollection.FindOne(context.TODO(), filter).Decode(&bucket)
+	if err == mongo.ErrNoDocuments {
+		return false, bucket, nil
+	} else if err != nil {
+		return false, bucket, err
+	}
+	return true, bucket, nil
+}
+
 func IsTagExist(tagName string) (bool, models.Tag, error) {
 	filter := bson.M{
 		"name": tagName,
@@ -197,7 +275,7 @@ func IsProducerExist(producerName string, stationId primitive.ObjectID) (bool, m
 	return true, producer, nil
 }
 
-func CreateDefaultStation(s *Server, sn StationName, username string) (models.Station, bool, error) {
+func CreateDefaultStation(s *Server, sn StationName, username, tenantName string) (models.Station, bool, error) {
 	var newStation models.Station
 	stationName := sn.Ext()
 	newStation = models.Station{
@@ -218,7 +296,8 @@ func CreateDefaultStation(s *Server, sn StationName, username string) (models.St
 			Poison:      true,
 			Schemaverse: true,
 		},
-		IsNative: true,
+		IsNative:   true,
+		TenantName: tenantName,
 	}
 
 	err := s.CreateStream(sn, newStation)
@@ -231,7 +310,7 @@ func CreateDefaultStation(s *Server, sn StationName, username string) (models.St
 		return newStation, false, err
 	}
 
-	filter := bson.M{"name": newStation.Name, "is_deleted": false}
+	filter := bson.M{"name": newStation.Name, "tenant_name": newStation.TenantName, "is_deleted": false}
 	update := bson.M{
 		"$setOnInsert": bson.M{
 			"_id":                      newStation.ID,
@@ -248,6 +327,7 @@ func CreateDefaultStation(s *Server, sn StationName, username string) (models.St
 			"idempotency_window_in_ms": newStation.IdempotencyWindow,
 			"is_native":                newStation.IsNative,
 			"dls_configuration":        newStation.DlsConfiguration,
+			"tenant_name":              newStation.TenantName,
 		},
 	}
 	opts := options.Update().SetUpsert(true)
@@ -258,6 +338,7 @@ func CreateDefaultStation(s *Server, sn StationName, username string) (models.St
 	if updateResults.MatchedCount > 0 {
 		return newStation, false, nil
 	}
+	s.memphisWSPublishEvent(tenantName, memphisWS_Event_StationCreated, stationName, newStation)
 
 	return newStation, true, nil
 }
@@ -323,9 +404,10 @@ func revertDelimiters(name string) string {
 	return strings.Replace(name, delimiterReplacement, delimiterToReplace, -1)
 }
 
-func IsSchemaExist(schemaName string) (bool, models.Schema, error) {
+func IsSchemaExist(schemaName, tenantName string) (bool, models.Schema, error) {
 	filter := bson.M{
-		"name": schemaName}
+		"name":        schemaName,
+		"tenant_name": tenantName}
 	var schema models.Schema
 	err := schemasCollection.FindOne(context.TODO(), filter).Decode(&schema)
 	if err == mongo.ErrNoDocuments {
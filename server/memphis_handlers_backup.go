@@ -0,0 +1,644 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"memphis-broker/analytics"
+	"memphis-broker/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	backupInfoFileName    = "backup.json"
+	backupStationKeysFile = "station_keys.json"
+	backupMetadataDir     = "metadata/"
+	backupStreamsDir      = "streams/"
+	backupStreamConfigExt = ".json"
+	backupStreamDataExt   = ".snapshot"
+)
+
+type BackupHandler struct{ S *Server }
+
+// the unique field every collection is restored by, so defaults created on a fresh deployment get replaced
+var backupCollectionKeys = map[string]string{
+	"tenants":         "name",
+	"stations":        "_id",
+	"schemas":         "_id",
+	"schema_versions": "_id",
+	"tags":            "name",
+	"users":           "username",
+	"integrations":    "name",
+	"configurations":  "key",
+	"kv_buckets":      "_id",
+}
+
+// secrets which never leave the deployment, users restored from a backup have to enroll to 2FA again
+var backupCollectionsExcludedFields = map[string][]string{
+	"users": {"totp_enabled", "totp_secret", "totp_recovery_codes", "totp_last_step"},
+}
+
+var backupCollectionsOrder = []string{"tenants", "stations", "schemas", "schema_versions", "tags", "users", "integrations", "configurations", "kv_buckets"}
+
+func getBackupCollection(name string) *mongo.Collection {
+	switch name {
+	case "tenants":
+		return tenantsCollection
+	case "stations":
+		return stationsCollection
+	case "schemas":
+		return schemasCollection
+	case "schema_versions":
+		return schemaVersionCollection
+	case "tags":
+		return tagsCollection
+	case "users":
+		return usersCollection
+	case "integrations":
+		return integrationsCollection
+	case "configurations":
+		return configurationsCollection
+	case "kv_buckets":
+		return kvBucketsCollection
+	}
+	return nil
+}
+
+func validateRootUser(c *gin.Context) (models.User, bool) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil || user.UserType != "root" {
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return user, false
+	}
+	return user, true
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, r)
+	return err
+}
+
+func writeTarJson(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeTarEntry(tw, name, int64(len(data)), bytes.NewReader(data))
+}
+
+func dumpCollection(collection *mongo.Collection, excludedFields []string) ([]json.RawMessage, error) {
+	projection := bson.M{}
+	for _, field := range excludedFields {
+		projection[field] = 0
+	}
+	cursor, err := collection.Find(context.TODO(), bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.Raw
+	if err = cursor.All(context.TODO(), &docs); err != nil {
+		return nil, err
+	}
+	// canonical extended json keeps object ids and dates intact
+	dump := []json.RawMessage{}
+	for _, doc := range docs {
+		data, err := bson.MarshalExtJSON(doc, true, false)
+		if err != nil {
+			return nil, err
+		}
+		dump = append(dump, data)
+	}
+	return dump, nil
+}
+
+// addStreamToBackup appends a stream with its current state, streams which do not exist are skipped
+func (s *Server) addStreamToBackup(streams []models.BackupStream, stream models.BackupStream, optional bool) ([]models.BackupStream, error) {
+	streamInfo, err := s.memphisStreamInfo(stream.TenantName, stream.Name)
+	if err != nil {
+		if IsNatsErr(err, JSStreamNotFoundErr) {
+			if optional {
+				return streams, nil
+			}
+			s.Warnf("getStreamsToBackup: stream " + stream.Name + " does not exist, skipping it")
+			return streams, nil
+		}
+		return nil, err
+	}
+	stream.Messages = streamInfo.State.Msgs
+	stream.Bytes = streamInfo.State.Bytes
+	return append(streams, stream), nil
+}
+
+func (s *Server) getStreamsToBackup() ([]models.BackupStream, error) {
+	var stations []models.Station
+	filter := bson.M{"$or": []interface{}{
+		bson.M{"is_deleted": bson.M{"$exists": false}},
+		bson.M{"is_deleted": false},
+	}}
+	cursor, err := stationsCollection.Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &stations); err != nil {
+		return nil, err
+	}
+
+	streams := []models.BackupStream{}
+	for _, station := range stations {
+		sn, err := StationNameFromStr(station.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, streamName := range []string{sn.Intern(), fmt.Sprintf(dlsStreamName, sn.Intern())} {
+			streams, err = s.addStreamToBackup(streams, models.BackupStream{
+				Name:        streamName,
+				StationName: station.Name,
+				TenantName:  station.TenantName,
+			}, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// the objects stream holds the chunks claim-check messages point to, it only exists once a large payload was uploaded
+		streams, err = s.addStreamToBackup(streams, models.BackupStream{
+			Name:        getObjectsStreamName(sn.Intern()),
+			StationName: station.Name,
+			TenantName:  station.TenantName,
+		}, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buckets []models.KvBucket
+	cursor, err = kvBucketsCollection.Find(context.TODO(), bson.M{"is_deleted": false})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &buckets); err != nil {
+		return nil, err
+	}
+	for _, bucket := range buckets {
+		streams, err = s.addStreamToBackup(streams, models.BackupStream{
+			Name:         getKvBucketStreamName(bucket.Name),
+			KvBucketName: bucket.Name,
+			TenantName:   bucket.TenantName,
+		}, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return streams, nil
+}
+
+// getStationKeysToBackup returns the keys of the encrypted stations, their streams are restored encrypted with the same keys.
+// The snapshots in the archive are decrypted anyway, so the archive has to be protected either way,
+// and shredding a station does not reach the archives taken before it
+func (s *Server) getStationKeysToBackup() ([]models.BackupStationKey, error) {
+	var stations []models.Station
+	cursor, err := stationsCollection.Find(context.TODO(), bson.M{"encrypted": true, "is_deleted": false})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &stations); err != nil {
+		return nil, err
+	}
+
+	keys := []models.BackupStationKey{}
+	for _, station := range stations {
+		sn, err := StationNameFromStr(station.Name)
+		if err != nil {
+			return nil, err
+		}
+		acc, err := s.getTenantAccount(station.TenantName)
+		if err != nil {
+			return nil, err
+		}
+		key, err := s.getStationKeyProvider().GetKey(stationKeyId(acc.Name, sn.Intern()))
+		if err != nil {
+			return nil, errors.New("key of station " + station.Name + ": " + err.Error())
+		}
+		keys = append(keys, models.BackupStationKey{StationName: station.Name, TenantName: station.TenantName, Key: key})
+	}
+	return keys, nil
+}
+
+// restoreStationKeys creates the keys of the encrypted stations on all the brokers, they have to exist before the streams are restored
+func (s *Server) restoreStationKeys(keys []models.BackupStationKey) error {
+	for _, stationKey := range keys {
+		if stationKey.TenantName == _EMPTY_ {
+			stationKey.TenantName = globalTenantName
+		}
+		sn, err := StationNameFromStr(stationKey.StationName)
+		if err != nil {
+			return err
+		}
+		update, err := s.newStationKeyUpdate(stationKeyActionPut, stationKey.TenantName, sn, stationKey.Key)
+		if err != nil {
+			return err
+		}
+		if err = s.broadcastStationKeyUpdate(update); err != nil {
+			return errors.New("Failed restoring the key of station " + stationKey.StationName + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// CreateBackup writes a tar archive holding the metadata collections, the keys of the encrypted stations and a snapshot of every station's streams
+func (s *Server) CreateBackup(w io.Writer, username string) (models.BackupInfo, error) {
+	version, err := ioutil.ReadFile("version.conf")
+	if err != nil {
+		return models.BackupInfo{}, err
+	}
+
+	streams, err := s.getStreamsToBackup()
+	if err != nil {
+		return models.BackupInfo{}, err
+	}
+	stationKeys, err := s.getStationKeysToBackup()
+	if err != nil {
+		return models.BackupInfo{}, err
+	}
+
+	// snapshots are staged on disk since tar entries require their size up front
+	tmpDir, err := ioutil.TempDir("", "memphis-backup-")
+	if err != nil {
+		return models.BackupInfo{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	restoreRequests := make([]JSApiStreamRestoreRequest, len(streams))
+	for i, stream := range streams {
+		file, err := os.Create(filepath.Join(tmpDir, fmt.Sprintf("%d%s", i, backupStreamDataExt)))
+		if err != nil {
+			return models.BackupInfo{}, err
+		}
+		resp, err := s.memphisStreamSnapshot(stream.TenantName, stream.Name, file)
+		file.Close()
+		if err != nil {
+			return models.BackupInfo{}, errors.New("snapshot of stream " + stream.Name + " failed: " + err.Error())
+		}
+		restoreRequests[i] = JSApiStreamRestoreRequest{Config: *resp.Config, State: *resp.State}
+	}
+
+	info := models.BackupInfo{
+		Version:      strings.TrimSpace(string(version)),
+		CreationDate: time.Now(),
+		CreatedBy:    username,
+		Collections:  backupCollectionsOrder,
+		Streams:      streams,
+	}
+
+	tw := tar.NewWriter(w)
+	if err = writeTarJson(tw, backupInfoFileName, info); err != nil {
+		return models.BackupInfo{}, err
+	}
+	for _, name := range backupCollectionsOrder {
+		dump, err := dumpCollection(getBackupCollection(name), backupCollectionsExcludedFields[name])
+		if err != nil {
+			return models.BackupInfo{}, err
+		}
+		if err = writeTarJson(tw, backupMetadataDir+name+".json", dump); err != nil {
+			return models.BackupInfo{}, err
+		}
+	}
+	if err = writeTarJson(tw, backupStationKeysFile, stationKeys); err != nil {
+		return models.BackupInfo{}, err
+	}
+	for i, stream := range streams {
+		if err = writeTarJson(tw, getBackupStreamEntry(stream)+backupStreamConfigExt, restoreRequests[i]); err != nil {
+			return models.BackupInfo{}, err
+		}
+		file, err := os.Open(filepath.Join(tmpDir, fmt.Sprintf("%d%s", i, backupStreamDataExt)))
+		if err != nil {
+			return models.BackupInfo{}, err
+		}
+		fileInfo, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return models.BackupInfo{}, err
+		}
+		err = writeTarEntry(tw, getBackupStreamEntry(stream)+backupStreamDataExt, fileInfo.Size(), file)
+		file.Close()
+		if err != nil {
+			return models.BackupInfo{}, err
+		}
+	}
+
+	return info, tw.Close()
+}
+
+// streams of different tenants may share a name, so every tenant gets its own directory
+func getBackupStreamEntry(stream models.BackupStream) string {
+	return backupStreamsDir + stream.TenantName + "/" + stream.Name
+}
+
+// parseBackupStreamEntry returns the tenant and the stream of a stream entry,
+// archives created before multi tenancy hold the streams of the global tenant only
+func parseBackupStreamEntry(entryName, ext string) (string, string) {
+	entry := strings.TrimSuffix(strings.TrimPrefix(entryName, backupStreamsDir), ext)
+	tenantName, streamName, found := strings.Cut(entry, "/")
+	if !found {
+		return globalTenantName, entry
+	}
+	return tenantName, streamName
+}
+
+func restoreCollection(name string, dump []json.RawMessage) (int, error) {
+	collection := getBackupCollection(name)
+	key := backupCollectionKeys[name]
+	for _, rawDoc := range dump {
+		var doc bson.D
+		err := bson.UnmarshalExtJSON(rawDoc, true, &doc)
+		if err != nil {
+			return 0, err
+		}
+		value, found := doc.Map()[key]
+		if !found {
+			return 0, fmt.Errorf("document in %s is missing the %s field", name, key)
+		}
+		_, err = collection.DeleteMany(context.TODO(), bson.M{key: value})
+		if err != nil {
+			return 0, err
+		}
+		_, err = collection.InsertOne(context.TODO(), doc)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(dump), nil
+}
+
+func (s *Server) restoreTenants(dump []json.RawMessage) error {
+	for _, rawDoc := range dump {
+		var tenant models.Tenant
+		err := bson.UnmarshalExtJSON(rawDoc, true, &tenant)
+		if err != nil {
+			return err
+		}
+		_, err = s.registerTenantAccount(tenant)
+		if err != nil {
+			return errors.New("Failed registering tenant " + tenant.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// RestoreBackup rebuilds the streams and metadata of an archive created by CreateBackup,
+// streams are restored first so the metadata never points to a station without a stream
+func (s *Server) RestoreBackup(r io.Reader) (models.RestoreBackupResponse, error) {
+	response := models.RestoreBackupResponse{
+		Collections: make(map[string]int),
+		Streams:     []models.BackupStream{},
+	}
+
+	filter := bson.M{"$or": []interface{}{
+		bson.M{"is_deleted": bson.M{"$exists": false}},
+		bson.M{"is_deleted": false},
+	}}
+	stationsCount, err := stationsCollection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return response, err
+	}
+	if stationsCount > 0 {
+		return response, errors.New("A backup can only be restored into a deployment without stations")
+	}
+
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil || header.Name != backupInfoFileName {
+		return response, errors.New("The uploaded file is not a Memphis backup")
+	}
+	var info models.BackupInfo
+	if err = json.NewDecoder(tr).Decode(&info); err != nil {
+		return response, errors.New("The uploaded file is not a Memphis backup")
+	}
+	streamsByEntry := make(map[string]models.BackupStream)
+	for _, stream := range info.Streams {
+		if stream.TenantName == _EMPTY_ {
+			stream.TenantName = globalTenantName
+		}
+		streamsByEntry[getBackupStreamEntry(stream)] = stream
+	}
+
+	dumps := make(map[string][]json.RawMessage)
+	restoreRequests := make(map[string]JSApiStreamRestoreRequest)
+	for {
+		header, err = tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return response, err
+		}
+
+		switch {
+		case strings.HasPrefix(header.Name, backupMetadataDir):
+			name := strings.TrimSuffix(strings.TrimPrefix(header.Name, backupMetadataDir), ".json")
+			if _, ok := backupCollectionKeys[name]; !ok {
+				s.Warnf("RestoreBackup: unknown collection " + name + " in backup, skipping it")
+				continue
+			}
+			var dump []json.RawMessage
+			if err = json.NewDecoder(tr).Decode(&dump); err != nil {
+				return response, err
+			}
+			dumps[name] = dump
+			// tenant accounts have to exist before their streams are restored
+			if name == "tenants" {
+				if err = s.restoreTenants(dump); err != nil {
+					return response, err
+				}
+			}
+		case header.Name == backupStationKeysFile:
+			var keys []models.BackupStationKey
+			if err = json.NewDecoder(tr).Decode(&keys); err != nil {
+				return response, err
+			}
+			if err = s.restoreStationKeys(keys); err != nil {
+				return response, err
+			}
+		case strings.HasPrefix(header.Name, backupStreamsDir) && strings.HasSuffix(header.Name, backupStreamConfigExt):
+			tenantName, streamName := parseBackupStreamEntry(header.Name, backupStreamConfigExt)
+			var req JSApiStreamRestoreRequest
+			if err = json.NewDecoder(tr).Decode(&req); err != nil {
+				return response, err
+			}
+			restoreRequests[tenantName+"/"+streamName] = req
+		case strings.HasPrefix(header.Name, backupStreamsDir) && strings.HasSuffix(header.Name, backupStreamDataExt):
+			tenantName, streamName := parseBackupStreamEntry(header.Name, backupStreamDataExt)
+			req, ok := restoreRequests[tenantName+"/"+streamName]
+			if !ok {
+				return response, errors.New("Missing configuration for stream " + streamName)
+			}
+			err = s.memphisStreamRestore(tenantName, req.Config, req.State, tr)
+			if err != nil {
+				return response, err
+			}
+			response.Streams = append(response.Streams, streamsByEntry[backupStreamsDir+tenantName+"/"+streamName])
+		}
+	}
+
+	for _, name := range backupCollectionsOrder {
+		dump, ok := dumps[name]
+		if !ok {
+			continue
+		}
+		count, err := restoreCollection(name, dump)
+		if err != nil {
+			return response, errors.New("Failed restoring " + name + ": " + err.Error())
+		}
+		response.Collections[name] = count
+	}
+
+	s.reloadRestoredConfigurations()
+	return response, nil
+}
+
+// reloadRestoredConfigurations propagates the restored configurations and integrations to all the brokers in the cluster
+func (s *Server) reloadRestoredConfigurations() {
+	var pmRetention models.ConfigurationsIntValue
+	err := configurationsCollection.FindOne(context.TODO(), bson.M{"key": "pm_retention"}).Decode(&pmRetention)
+	if err == nil {
+		err = changePMRetention(pmRetention.Value)
+		if err != nil {
+			s.Errorf("reloadRestoredConfigurations: " + err.Error())
+		}
+	}
+	var logsRetention models.ConfigurationsIntValue
+	err = configurationsCollection.FindOne(context.TODO(), bson.M{"key": "logs_retention"}).Decode(&logsRetention)
+	if err == nil {
+		err = changeLogsRetention(logsRetention.Value)
+		if err != nil {
+			s.Errorf("reloadRestoredConfigurations: " + err.Error())
+		}
+	}
+
+	var integrations []models.Integration
+	cursor, err := integrationsCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		s.Errorf("reloadRestoredConfigurations: " + err.Error())
+		return
+	}
+	if err = cursor.All(context.TODO(), &integrations); err != nil {
+		s.Errorf("reloadRestoredConfigurations: " + err.Error())
+		return
+	}
+	for _, integration := range integrations {
+		integrationUpdate := models.CreateIntegrationSchema{
+			Name:       integration.Name,
+			Keys:       integration.Keys,
+			Properties: integration.Properties,
+			UIUrl:      UI_url,
+		}
+		msg, err := json.Marshal(integrationUpdate)
+		if err != nil {
+			s.Errorf("reloadRestoredConfigurations: " + err.Error())
+			continue
+		}
+		err = s.sendInternalAccountMsgWithReply(s.GlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+		if err != nil {
+			s.Errorf("reloadRestoredConfigurations: " + err.Error())
+		}
+	}
+}
+
+func (bh BackupHandler) CreateBackup(c *gin.Context) {
+	user, ok := validateRootUser(c)
+	if !ok {
+		return
+	}
+
+	file, err := ioutil.TempFile("", "memphis-backup-*.tar")
+	if err != nil {
+		serv.Errorf("CreateBackup: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = bh.S.CreateBackup(file, user.Username)
+	file.Close()
+	if err != nil {
+		serv.Errorf("CreateBackup: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	serv.Noticef("Backup has been created by " + user.Username)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analytics.SendEvent(user.Username, "user-create-backup")
+	}
+
+	c.FileAttachment(file.Name(), fmt.Sprintf("memphis-backup-%s.tar", time.Now().Format("2006-01-02-15-04-05")))
+}
+
+func (bh BackupHandler) RestoreBackup(c *gin.Context) {
+	user, ok := validateRootUser(c)
+	if !ok {
+		return
+	}
+
+	uploadedFile, err := c.FormFile("file")
+	if err != nil {
+		serv.Warnf("RestoreBackup: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Could not complete uploading your file, please check your file"})
+		return
+	}
+	file, err := uploadedFile.Open()
+	if err != nil {
+		serv.Errorf("RestoreBackup: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	defer file.Close()
+
+	response, err := bh.S.RestoreBackup(file)
+	if err != nil {
+		serv.Warnf("RestoreBackup: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	serv.Noticef("Backup has been restored by " + user.Username)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analytics.SendEvent(user.Username, "user-restore-backup")
+	}
+
+	c.IndentedJSON(200, response)
+}
@@ -14,6 +14,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"memphis-broker/models"
 	"memphis-broker/utils"
@@ -74,6 +75,29 @@ func (s *Server) initializeConfigurations() {
 	} else {
 		LOGS_RETENTION_IN_DAYS = logsRetention.Value
 	}
+	var producerMsgsPerSec, producerBytesPerSec models.ConfigurationsIntValue
+	err = configurationsCollection.FindOne(context.TODO(), bson.M{"key": "producer_msgs_per_sec"}).Decode(&producerMsgsPerSec)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.Errorf("initializeConfigurations: " + err.Error())
+	}
+	err = configurationsCollection.FindOne(context.TODO(), bson.M{"key": "producer_bytes_per_sec"}).Decode(&producerBytesPerSec)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.Errorf("initializeConfigurations: " + err.Error())
+	}
+	setProducerRateLimits(models.RateLimits{MsgsPerSec: producerMsgsPerSec.Value, BytesPerSec: producerBytesPerSec.Value})
+	var suspectGrace, disconnectGrace models.ConfigurationsIntValue
+	err = configurationsCollection.FindOne(context.TODO(), bson.M{"key": "liveness_suspect_grace_sec"}).Decode(&suspectGrace)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.Errorf("initializeConfigurations: " + err.Error())
+	} else if err == nil {
+		LIVENESS_SUSPECT_GRACE_SEC = suspectGrace.Value
+	}
+	err = configurationsCollection.FindOne(context.TODO(), bson.M{"key": "liveness_disconnect_grace_sec"}).Decode(&disconnectGrace)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.Errorf("initializeConfigurations: " + err.Error())
+	} else if err == nil {
+		LIVENESS_DISCONNECT_GRACE_SEC = disconnectGrace.Value
+	}
 }
 
 func (ch ConfigurationsHandler) EditClusterConfig(c *gin.Context) {
@@ -98,8 +122,79 @@ func (ch ConfigurationsHandler) EditClusterConfig(c *gin.Context) {
 			return
 		}
 	}
+	if getProducerRateLimits() != body.ProducerRateLimits {
+		err := changeProducerRateLimits(body.ProducerRateLimits)
+		if err != nil {
+			serv.Errorf("EditConfigurations: " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+	}
 
-	c.IndentedJSON(200, gin.H{"pm_retention": POISON_MSGS_RETENTION_IN_HOURS, "logs_retention": LOGS_RETENTION_IN_DAYS})
+	suspectGrace, disconnectGrace := LIVENESS_SUSPECT_GRACE_SEC, LIVENESS_DISCONNECT_GRACE_SEC
+	if body.SuspectGraceSec != 0 {
+		suspectGrace = body.SuspectGraceSec
+	}
+	if body.DisconnectGraceSec != 0 {
+		disconnectGrace = body.DisconnectGraceSec
+	}
+	if suspectGrace != LIVENESS_SUSPECT_GRACE_SEC || disconnectGrace != LIVENESS_DISCONNECT_GRACE_SEC {
+		err := validateLivenessGrace(suspectGrace, disconnectGrace)
+		if err != nil {
+			serv.Warnf("EditConfigurations: " + err.Error())
+			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		err = changeLivenessGrace(suspectGrace, disconnectGrace)
+		if err != nil {
+			serv.Errorf("EditConfigurations: " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+	}
+
+	c.IndentedJSON(200, clusterConfigResponse())
+}
+
+func clusterConfigResponse() gin.H {
+	return gin.H{
+		"pm_retention":                  POISON_MSGS_RETENTION_IN_HOURS,
+		"logs_retention":                LOGS_RETENTION_IN_DAYS,
+		"producer_rate_limits":          getProducerRateLimits(),
+		"liveness_suspect_grace_sec":    LIVENESS_SUSPECT_GRACE_SEC,
+		"liveness_disconnect_grace_sec": LIVENESS_DISCONNECT_GRACE_SEC,
+	}
+}
+
+func validateLivenessGrace(suspectGrace, disconnectGrace int) error {
+	if suspectGrace < livenessMinGraceSec {
+		return fmt.Errorf("liveness suspect grace period can not be less than %v seconds", livenessMinGraceSec)
+	}
+	if disconnectGrace <= suspectGrace {
+		return errors.New("liveness disconnect grace period has to be greater than the suspect grace period")
+	}
+	return nil
+}
+
+func changeLivenessGrace(suspectGrace, disconnectGrace int) error {
+	LIVENESS_SUSPECT_GRACE_SEC = suspectGrace
+	LIVENESS_DISCONNECT_GRACE_SEC = disconnectGrace
+	opts := options.Update().SetUpsert(true)
+	for key, value := range map[string]int{"liveness_suspect_grace_sec": suspectGrace, "liveness_disconnect_grace_sec": disconnectGrace} {
+		_, err := configurationsCollection.UpdateOne(context.TODO(), bson.M{"key": key}, bson.M{"$set": bson.M{"value": value}}, opts)
+		if err != nil {
+			return err
+		}
+		msg, err := json.Marshal(models.ConfigurationsUpdate{Type: key, Update: value})
+		if err != nil {
+			return err
+		}
+		err = serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), CONFIGURATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func changePMRetention(pmRetention int) error {
@@ -150,7 +245,7 @@ func changePMRetention(pmRetention int) error {
 		} else {
 			storage = FileStorage
 		}
-		err = serv.memphisUpdateStream(&StreamConfig{
+		err = serv.memphisUpdateStream(station.TenantName, &StreamConfig{
 			Name:      streamName,
 			Subjects:  []string{streamName + ".>"},
 			Retention: LimitsPolicy,
@@ -179,7 +274,7 @@ func changeLogsRetention(logsRetention int) error {
 		return err
 	}
 	retentionDur := time.Duration(LOGS_RETENTION_IN_DAYS) * time.Hour * 24
-	err = serv.memphisUpdateStream(&StreamConfig{
+	err = serv.memphisUpdateStream(globalTenantName, &StreamConfig{
 		Name:         syslogsStreamName,
 		Subjects:     []string{syslogsStreamName + ".>"},
 		Retention:    LimitsPolicy,
@@ -194,7 +289,21 @@ func changeLogsRetention(logsRetention int) error {
 	return nil
 }
 
+func changeProducerRateLimits(limits models.RateLimits) error {
+	setProducerRateLimits(limits)
+	opts := options.Update().SetUpsert(true)
+	_, err := configurationsCollection.UpdateOne(context.TODO(), bson.M{"key": "producer_msgs_per_sec"}, bson.M{"$set": bson.M{"value": limits.MsgsPerSec}}, opts)
+	if err != nil {
+		return err
+	}
+	_, err = configurationsCollection.UpdateOne(context.TODO(), bson.M{"key": "producer_bytes_per_sec"}, bson.M{"$set": bson.M{"value": limits.BytesPerSec}}, opts)
+	if err != nil {
+		return err
+	}
+	return broadcastRateLimitsUpdate("producer_rate_limits", models.RateLimitsUpdate{RateLimits: limits})
+}
+
 func (ch ConfigurationsHandler) GetClusterConfig(c *gin.Context) {
-	c.IndentedJSON(200, gin.H{"pm_retention": POISON_MSGS_RETENTION_IN_HOURS, "logs_retention": LOGS_RETENTION_IN_DAYS})
+	c.IndentedJSON(200, clusterConfigResponse())
 }
 
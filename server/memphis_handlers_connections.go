@@ -17,18 +17,21 @@ import (
 	"memphis-broker/analytics"
 	"memphis-broker/models"
 	"memphis-broker/notifications"
+	"memphis-broker/utils"
 
 	"context"
 	"errors"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-type ConnectionsHandler struct{}
+type ConnectionsHandler struct{ S *Server }
 
 var connectionsHandler ConnectionsHandler
 var producersHandler ProducersHandler
@@ -37,6 +40,7 @@ var consumersHandler ConsumersHandler
 const (
 	connectItemSep                      = "::"
 	connectConfigUpdatesSubjectTemplate = CONFIGURATIONS_UPDATES_SUBJ + ".init.%s"
+	connectionsStatsTimeout             = 2 * time.Second
 )
 
 func updateNewClientWithConfig(c *client, connId string) {
@@ -65,31 +69,75 @@ func sendConnectUpdate(c *client, ccu models.GlobalConfigurationsUpdate, connId
 	s.sendInternalAccountMsg(c.acc, subject, rawMsg)
 }
 
-func handleConnectMessage(client *client) error {
-	splittedMemphisInfo := strings.Split(client.opts.Name, connectItemSep)
-
-	var (
-		isNativeMemphisClient bool
-		username              string
-		objID                 primitive.ObjectID
-	)
+// getMemphisClientUsername returns the user a client connects with, Memphis SDKs send "<connectionId>::<username>"
+// as the client name and NATS SDKs send "<username>::<token>" as the token
+func getMemphisClientUsername(c *client) (string, bool, error) {
+	splittedMemphisInfo := strings.Split(c.opts.Name, connectItemSep)
 	switch len(splittedMemphisInfo) {
 	case 2:
-		// normal Memphis SDK carry on to the rest of the function
-		isNativeMemphisClient = true
-		username = strings.ToLower(splittedMemphisInfo[1])
+		return strings.ToLower(splittedMemphisInfo[1]), true, nil
 	case 1:
-		// NATS SDK, means we extract username from the token field
-		isNativeMemphisClient = false
-		splittedToken := strings.Split(client.opts.Token, connectItemSep)
+		splittedToken := strings.Split(c.opts.Token, connectItemSep)
 		if len(splittedToken) != 2 {
-			client.Warnf("handleConnectMessage: missing username or token")
-			return errors.New("missing username or token")
+			return _EMPTY_, false, errors.New("missing username or token")
 		}
-		username = strings.ToLower(splittedToken[0])
+		return strings.ToLower(splittedToken[0]), false, nil
 	default:
-		client.Warnf("handleConnectMessage: missing username or connectionId")
-		return errors.New("missing username or connectionId")
+		return _EMPTY_, false, errors.New("missing username or connectionId")
+	}
+}
+
+// getApplicationUserTenant returns the tenant the clients of a user produce and consume in
+var getApplicationUserTenant = func(username string) (string, error) {
+	exist, user, err := IsUserExist(username)
+	if err != nil {
+		return _EMPTY_, err
+	}
+	if !exist {
+		return _EMPTY_, errors.New("User " + username + " does not exist")
+	}
+	if user.UserType != "root" && user.UserType != "application" {
+		return _EMPTY_, errors.New("Please use a user of type Root/Application and not Management")
+	}
+	return getUserTenantName(user), nil
+}
+
+// registerMemphisClientAccount binds an authenticated client to the account of its user's tenant,
+// it is called before the connection is registered with the global account
+func (s *Server) registerMemphisClientAccount(c *client) bool {
+	if c.clientType() != NATS || strings.Contains(c.opts.Name, "MEMPHIS HTTP LOGGER") {
+		return true
+	}
+	username, _, err := getMemphisClientUsername(c)
+	if err != nil {
+		c.Warnf("registerMemphisClientAccount: " + err.Error())
+		return false
+	}
+	tenantName, err := getApplicationUserTenant(username)
+	if err != nil {
+		c.Warnf("registerMemphisClientAccount: " + err.Error())
+		return false
+	}
+	if tenantName == globalTenantName {
+		return true
+	}
+	acc, err := s.getTenantAccount(tenantName)
+	if err == nil {
+		err = c.registerWithAccount(acc)
+	}
+	if err != nil {
+		c.Errorf("registerMemphisClientAccount: User " + username + ": " + err.Error())
+		return false
+	}
+	return true
+}
+
+func handleConnectMessage(client *client) error {
+	var objID primitive.ObjectID
+	username, isNativeMemphisClient, err := getMemphisClientUsername(client)
+	if err != nil {
+		client.Warnf("handleConnectMessage: " + err.Error())
+		return err
 	}
 
 	exist, user, err := IsUserExist(username)
@@ -109,7 +157,7 @@ func handleConnectMessage(client *client) error {
 	}
 
 	if isNativeMemphisClient {
-		objIdString := splittedMemphisInfo[0]
+		objIdString := strings.Split(client.opts.Name, connectItemSep)[0]
 		objID, err = primitive.ObjectIDFromHex(objIdString)
 		if err != nil {
 			errMsg := "User " + username + ": " + err.Error()
@@ -117,15 +165,22 @@ func handleConnectMessage(client *client) error {
 			return err
 		}
 
-		exist, _, err = IsConnectionExist(objID)
+		var conn models.Connection
+		exist, conn, err = IsConnectionExist(objID)
 		if err != nil {
 			errMsg := "User " + username + ": " + err.Error()
 			client.Errorf("handleConnectMessage: " + errMsg)
 			return err
 		}
 
+		metadata := models.ConnectionMetadata{SdkLang: client.opts.Lang, SdkVersion: client.opts.Version, ClientName: client.opts.Name}
 		if exist {
-			err = connectionsHandler.ReliveConnection(primitive.ObjectID(objID))
+			if conn.DisconnectedBy != _EMPTY_ {
+				errMsg := "Connection " + objIdString + " has been disconnected by user " + conn.DisconnectedBy
+				client.Warnf("handleConnectMessage: " + errMsg)
+				return errors.New(errMsg)
+			}
+			err = connectionsHandler.ReliveConnection(primitive.ObjectID(objID), metadata)
 			if err != nil {
 				errMsg := "User " + username + ": " + err.Error()
 				client.Errorf("handleConnectMessage: " + errMsg)
@@ -145,7 +200,7 @@ func handleConnectMessage(client *client) error {
 				return err
 			}
 		} else {
-			err := connectionsHandler.CreateConnection(username, client.RemoteAddress().String(), objID)
+			err := connectionsHandler.CreateConnection(username, client.RemoteAddress().String(), objID, metadata)
 			if err != nil {
 				errMsg := "User " + username + ": " + err.Error()
 				client.Errorf("handleConnectMessage: " + errMsg)
@@ -175,9 +230,9 @@ func handleConnectMessage(client *client) error {
 	return nil
 }
 
-func (ch ConnectionsHandler) CreateConnection(username, clientAddress string, connectionId primitive.ObjectID) error {
+func (ch ConnectionsHandler) CreateConnection(username, clientAddress string, connectionId primitive.ObjectID, metadata models.ConnectionMetadata) error {
 	username = strings.ToLower(username)
-	exist, _, err := IsUserExist(username)
+	exist, user, err := IsUserExist(username)
 	if err != nil {
 		errMsg := "User " + username + ": " + err.Error()
 		serv.Errorf("CreateConnection error: " + errMsg)
@@ -194,6 +249,12 @@ func (ch ConnectionsHandler) CreateConnection(username, clientAddress string, co
 		IsActive:      true,
 		CreationDate:  time.Now(),
 		ClientAddress: clientAddress,
+		LastSeen:      time.Now(),
+		State:         livenessStateActive,
+		TenantName:    getUserTenantName(user),
+		SdkLang:       metadata.SdkLang,
+		SdkVersion:    metadata.SdkVersion,
+		ClientName:    metadata.ClientName,
 	}
 
 	_, err = connectionsCollection.InsertOne(context.TODO(), newConnection)
@@ -205,10 +266,17 @@ func (ch ConnectionsHandler) CreateConnection(username, clientAddress string, co
 	return nil
 }
 
-func (ch ConnectionsHandler) ReliveConnection(connectionId primitive.ObjectID) error {
+func (ch ConnectionsHandler) ReliveConnection(connectionId primitive.ObjectID, metadata models.ConnectionMetadata) error {
 	_, err := connectionsCollection.UpdateOne(context.TODO(),
 		bson.M{"_id": connectionId},
-		bson.M{"$set": bson.M{"is_active": true}},
+		bson.M{"$set": bson.M{
+			"is_active":   true,
+			"state":       livenessStateActive,
+			"last_seen":   time.Now(),
+			"sdk_lang":    metadata.SdkLang,
+			"sdk_version": metadata.SdkVersion,
+			"client_name": metadata.ClientName,
+		}},
 	)
 	if err != nil {
 		serv.Errorf("ReliveConnection error: " + err.Error())
@@ -226,18 +294,18 @@ func (mci *memphisClientInfo) updateDisconnection() error {
 	ctx := context.TODO()
 	_, err := connectionsCollection.UpdateOne(ctx,
 		bson.M{"_id": mci.connectionId},
-		bson.M{"$set": bson.M{"is_active": false}},
+		bson.M{"$set": bson.M{"is_active": false, "state": livenessStateDisconnected}},
 	)
 	if err != nil {
 		return err
 	}
 	var producers []models.ExtendedProducer
 	cursor, err := producersCollection.Aggregate(context.TODO(), mongo.Pipeline{
-		bson.D{{"$match", bson.D{{"connection_id", mci.connectionId}, {"is_active", true}}}},
-		bson.D{{"$lookup", bson.D{{"from", "stations"}, {"localField", "station_id"}, {"foreignField", "_id"}, {"as", "station"}}}},
-		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
-		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}},
+		bson.D{{Key: "$match", Value: bson.D{{Key: "connection_id", Value: mci.connectionId}, {Key: "is_active", Value: true}}}},
+		bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "stations"}, {Key: "localField", Value: "station_id"}, {Key: "foreignField", Value: "_id"}, {Key: "as", Value: "station"}}}},
+		bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$station"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}, {Key: "type", Value: 1}, {Key: "connection_id", Value: 1}, {Key: "created_by_user", Value: 1}, {Key: "creation_date", Value: 1}, {Key: "is_active", Value: 1}, {Key: "is_deleted", Value: 1}, {Key: "station_name", Value: "$station.name"}, {Key: "tenant_name", Value: "$station.tenant_name"}, {Key: "client_address", Value: "$connection.client_address"}}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "station", Value: 0}, {Key: "connection", Value: 0}}}},
 	})
 	if err != nil {
 		return err
@@ -258,16 +326,19 @@ func (mci *memphisClientInfo) updateDisconnection() error {
 
 		for i := 0; i < len(producers); i++ {
 			producerNames = producerNames + "Producer: " + producers[i].Name + " Station: " + producers[i].StationName + "\n"
+			producers[i].IsActive = false
+			producers[i].State = livenessStateDisconnected
+			serv.memphisWSPublishEvent(producers[i].TenantName, memphisWS_Event_ProducerDisconnect, producers[i].StationName, producers[i])
 		}
 	}
 
 	var consumers []models.ExtendedConsumer
 	cursor, err = consumersCollection.Aggregate(context.TODO(), mongo.Pipeline{
-		bson.D{{"$match", bson.D{{"connection_id", mci.connectionId}, {"is_active", true}}}},
-		bson.D{{"$lookup", bson.D{{"from", "stations"}, {"localField", "station_id"}, {"foreignField", "_id"}, {"as", "station"}}}},
-		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
-		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}}})
+		bson.D{{Key: "$match", Value: bson.D{{Key: "connection_id", Value: mci.connectionId}, {Key: "is_active", Value: true}}}},
+		bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "stations"}, {Key: "localField", Value: "station_id"}, {Key: "foreignField", Value: "_id"}, {Key: "as", Value: "station"}}}},
+		bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$station"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}, {Key: "type", Value: 1}, {Key: "connection_id", Value: 1}, {Key: "created_by_user", Value: 1}, {Key: "creation_date", Value: 1}, {Key: "is_active", Value: 1}, {Key: "is_deleted", Value: 1}, {Key: "station_name", Value: "$station.name"}, {Key: "client_address", Value: "$connection.client_address"}}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "station", Value: 0}, {Key: "connection", Value: 0}}}}})
 	if err != nil {
 		return err
 	}
@@ -302,3 +373,273 @@ func (mci *memphisClientInfo) updateDisconnection() error {
 	serv.Noticef("Client has been disconnected from Memphis")
 	return err
 }
+
+// localConnectionsStats returns the runtime stats of the memphis connections served by this broker
+func (s *Server) localConnectionsStats() []models.ConnectionStats {
+	stats := []models.ConnectionStats{}
+	s.mu.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for _, c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+	for _, c := range clients {
+		c.mu.Lock()
+		if !c.memphisInfo.connectionId.IsZero() {
+			stats = append(stats, models.ConnectionStats{
+				ConnectionId:  c.memphisInfo.connectionId.Hex(),
+				Broker:        s.memphis.serverID,
+				RTT:           c.getRTT().String(),
+				Subscriptions: len(c.subs),
+				InMsgs:        atomic.LoadInt64(&c.inMsgs),
+				OutMsgs:       c.outMsgs,
+				InBytes:       atomic.LoadInt64(&c.inBytes),
+				OutBytes:      c.outBytes,
+			})
+		}
+		c.mu.Unlock()
+	}
+
+	s.kafka.mu.Lock()
+	for kc := range s.kafka.conns {
+		kc.mu.Lock()
+		if !kc.connectionId.IsZero() {
+			stats = append(stats, models.ConnectionStats{ConnectionId: kc.connectionId.Hex(), Broker: s.memphis.serverID})
+		}
+		kc.mu.Unlock()
+	}
+	s.kafka.mu.Unlock()
+	return stats
+}
+
+// collectConnectionsStats gathers the runtime stats of the memphis connections from all the brokers
+func (s *Server) collectConnectionsStats() (map[string]models.ConnectionStats, error) {
+	brokersCount := s.NumRoutes() + 1
+	replySubject := CONNECTIONS_STATS_SUBJ + "_reply_" + s.memphis.nuid.Next()
+	respCh := make(chan []byte, brokersCount)
+	sub, err := s.subscribeOnGlobalAcc(replySubject, replySubject+"_sid", createReplyHandler(s, respCh))
+	if err != nil {
+		return nil, err
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), CONNECTIONS_STATS_SUBJ, replySubject, nil, _EMPTY_, true)
+
+	stats := make(map[string]models.ConnectionStats)
+	timeout := time.After(connectionsStatsTimeout)
+	for i := 0; i < brokersCount; i++ {
+		select {
+		case msg := <-respCh:
+			var brokerStats []models.ConnectionStats
+			err = json.Unmarshal(msg, &brokerStats)
+			if err != nil {
+				return nil, err
+			}
+			for _, connStats := range brokerStats {
+				stats[connStats.ConnectionId] = connStats
+			}
+		case <-timeout:
+			return stats, nil
+		}
+	}
+	return stats, nil
+}
+
+// disconnectLocalConnection closes the clients of a memphis connection served by this broker
+func (s *Server) disconnectLocalConnection(connectionId primitive.ObjectID) {
+	s.mu.Lock()
+	clients := make([]*client, 0)
+	for _, c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+	for _, c := range clients {
+		c.mu.Lock()
+		match := c.memphisInfo.connectionId == connectionId
+		c.mu.Unlock()
+		if match {
+			c.sendErrAndErr("Connection has been disconnected by the user")
+			c.closeConnection(ClientClosed)
+		}
+	}
+
+	s.kafka.mu.Lock()
+	for kc := range s.kafka.conns {
+		kc.mu.Lock()
+		if kc.connectionId == connectionId {
+			// the read loop fails and cleans the connection up
+			kc.nc.Close()
+		}
+		kc.mu.Unlock()
+	}
+	s.kafka.mu.Unlock()
+}
+
+func (ch ConnectionsHandler) GetActiveConnections(c *gin.Context) {
+	tenantName := getTenantNameFromMiddleware(c)
+	filter := bson.M{"is_active": true, "tenant_name": tenantName}
+	if tenantName == globalTenantName {
+		// connections created before the tenant was stored belong to the global tenant
+		filter = bson.M{"is_active": true, "$or": []interface{}{
+			bson.M{"tenant_name": tenantName},
+			bson.M{"tenant_name": bson.M{"$exists": false}},
+		}}
+	}
+	var connections []models.Connection
+	cursor, err := connectionsCollection.Find(context.TODO(), filter)
+	if err != nil {
+		serv.Errorf("GetActiveConnections: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if err = cursor.All(context.TODO(), &connections); err != nil {
+		serv.Errorf("GetActiveConnections: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if len(connections) == 0 {
+		c.IndentedJSON(200, []string{})
+		return
+	}
+
+	connectionIds := make([]primitive.ObjectID, 0, len(connections))
+	for _, conn := range connections {
+		connectionIds = append(connectionIds, conn.ID)
+	}
+	var producers []models.ExtendedProducer
+	cursor, err = producersCollection.Aggregate(context.TODO(), mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "connection_id", Value: bson.D{{Key: "$in", Value: connectionIds}}}, {Key: "is_active", Value: true}}}},
+		bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "stations"}, {Key: "localField", Value: "station_id"}, {Key: "foreignField", Value: "_id"}, {Key: "as", Value: "station"}}}},
+		bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$station"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}, {Key: "type", Value: 1}, {Key: "connection_id", Value: 1}, {Key: "created_by_user", Value: 1}, {Key: "creation_date", Value: 1}, {Key: "is_active", Value: 1}, {Key: "is_deleted", Value: 1}, {Key: "station_name", Value: "$station.name"}}}},
+	})
+	if err == nil {
+		err = cursor.All(context.TODO(), &producers)
+	}
+	if err != nil {
+		serv.Errorf("GetActiveConnections: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	var consumers []struct {
+		models.ExtendedConsumer `bson:",inline"`
+		ConnectionId            primitive.ObjectID `bson:"connection_id"`
+	}
+	cursor, err = consumersCollection.Aggregate(context.TODO(), mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "connection_id", Value: bson.D{{Key: "$in", Value: connectionIds}}}, {Key: "is_active", Value: true}}}},
+		bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "stations"}, {Key: "localField", Value: "station_id"}, {Key: "foreignField", Value: "_id"}, {Key: "as", Value: "station"}}}},
+		bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$station"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "name", Value: 1}, {Key: "connection_id", Value: 1}, {Key: "created_by_user", Value: 1}, {Key: "consumers_group", Value: 1}, {Key: "creation_date", Value: 1}, {Key: "is_active", Value: 1}, {Key: "is_deleted", Value: 1}, {Key: "max_ack_time_ms", Value: 1}, {Key: "max_msg_deliveries", Value: 1}, {Key: "station_name", Value: "$station.name"}}}},
+	})
+	if err == nil {
+		err = cursor.All(context.TODO(), &consumers)
+	}
+	if err != nil {
+		serv.Errorf("GetActiveConnections: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	stats, err := ch.S.collectConnectionsStats()
+	if err != nil {
+		serv.Errorf("GetActiveConnections: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	response := make([]models.ExtendedConnection, 0, len(connections))
+	byId := make(map[primitive.ObjectID]int)
+	for i, conn := range connections {
+		conn.State = livenessState(conn.IsActive, false, conn.State)
+		extConn := models.ExtendedConnection{
+			Connection: conn,
+			Producers:  []models.ExtendedProducer{},
+			Consumers:  []models.ExtendedConsumer{},
+		}
+		if connStats, ok := stats[conn.ID.Hex()]; ok {
+			extConn.Stats = &connStats
+		}
+		response = append(response, extConn)
+		byId[conn.ID] = i
+	}
+	for _, producer := range producers {
+		i := byId[producer.ConnectionId]
+		producer.State = response[i].State
+		response[i].Producers = append(response[i].Producers, producer)
+	}
+	for _, consumer := range consumers {
+		i := byId[consumer.ConnectionId]
+		consumer.ExtendedConsumer.State = response[i].State
+		response[i].Consumers = append(response[i].Consumers, consumer.ExtendedConsumer)
+	}
+
+	c.IndentedJSON(200, response)
+}
+
+func (ch ConnectionsHandler) DisconnectConnection(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.DisconnectConnectionSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	connectionId, err := primitive.ObjectIDFromHex(body.ConnectionId)
+	if err != nil {
+		serv.Warnf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Invalid connection id"})
+		return
+	}
+	exist, conn, err := IsConnectionExist(connectionId)
+	if err != nil {
+		serv.Errorf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	tenantName := getTenantNameFromMiddleware(c)
+	if !exist || (conn.TenantName != tenantName && !(conn.TenantName == _EMPTY_ && tenantName == globalTenantName)) {
+		errMsg := "Connection " + body.ConnectionId + " does not exist"
+		serv.Warnf("DisconnectConnection: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+
+	// the connection is marked first so the client can not relive it by reconnecting
+	_, err = connectionsCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": connectionId},
+		bson.M{"$set": bson.M{"is_active": false, "state": livenessStateDisconnected, "disconnected_by": user.Username}},
+	)
+	if err != nil {
+		serv.Errorf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = ch.S.sendInternalAccountMsgWithReply(ch.S.GlobalAccount(), CONNECTIONS_DISCONNECT_SUBJ, _EMPTY_, nil, []byte(connectionId.Hex()), true)
+	if err != nil {
+		serv.Errorf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = producersHandler.KillProducers(connectionId)
+	if err != nil {
+		serv.Errorf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	err = consumersHandler.KillConsumers(connectionId)
+	if err != nil {
+		serv.Errorf("DisconnectConnection: Connection " + body.ConnectionId + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	serv.Noticef("Connection " + body.ConnectionId + " has been disconnected by user " + user.Username)
+	c.IndentedJSON(200, gin.H{})
+}
@@ -15,6 +15,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"sort"
 
 	"memphis-broker/analytics"
@@ -35,6 +37,10 @@ type ConsumersHandler struct{ S *Server }
 
 const (
 	consumerObjectName = "Consumer"
+
+	redeliveryBackoffExponential = "exponential"
+	redeliveryBackoffStepped     = "stepped"
+	maxRedeliveryWindowMs        = 24 * 60 * 60 * 1000 // 1 day
 )
 
 func validateConsumerName(consumerName string) error {
@@ -48,6 +54,109 @@ func validateConsumerType(consumerType string) error {
 	return nil
 }
 
+func validateRedeliveryBackoff(backoff *models.RedeliveryBackoff, maxMsgDeliveries int) error {
+	if backoff == nil {
+		return nil
+	}
+	backoff.Policy = strings.ToLower(backoff.Policy)
+
+	// the first delivery always waits max_ack_time_ms and JetStream requires
+	// max deliveries to be greater than the number of ack windows
+	retries := getEffectiveMaxMsgDeliveries(maxMsgDeliveries) - 2
+	if retries < 1 {
+		return errors.New("A redelivery backoff requires max_msg_deliveries of at least 3")
+	}
+
+	switch backoff.Policy {
+	case redeliveryBackoffExponential:
+		if len(backoff.StepsMs) > 0 {
+			return errors.New("steps_ms is supported only by the stepped redelivery backoff")
+		}
+		if backoff.InitialDelayMs < 0 || backoff.InitialDelayMs > maxRedeliveryWindowMs {
+			return fmt.Errorf("initial_delay_ms has to be between 0 and %v", maxRedeliveryWindowMs)
+		}
+		if backoff.MaxDelayMs < 0 || backoff.MaxDelayMs > maxRedeliveryWindowMs {
+			return fmt.Errorf("max_delay_ms has to be between 0 and %v", maxRedeliveryWindowMs)
+		}
+		if backoff.Multiplier != 0 && (backoff.Multiplier < 1 || backoff.Multiplier > 10) {
+			return errors.New("multiplier has to be between 1 and 10")
+		}
+	case redeliveryBackoffStepped:
+		if len(backoff.StepsMs) == 0 {
+			return errors.New("The stepped redelivery backoff requires at least one step")
+		}
+		if len(backoff.StepsMs) > retries {
+			return fmt.Errorf("The stepped redelivery backoff has %v steps but max_msg_deliveries of %v allows at most %v", len(backoff.StepsMs), getEffectiveMaxMsgDeliveries(maxMsgDeliveries), retries)
+		}
+		for _, step := range backoff.StepsMs {
+			if step <= 0 || step > maxRedeliveryWindowMs {
+				return fmt.Errorf("Redelivery backoff steps have to be between 1 and %v", maxRedeliveryWindowMs)
+			}
+		}
+	default:
+		return errors.New("Redelivery backoff policy has to be one of the following exponential/stepped")
+	}
+	return nil
+}
+
+// getRedeliveryScheduleMs returns the ack window of every delivery but the last one,
+// starting with max_ack_time_ms, or nil when the group has no backoff
+func getRedeliveryScheduleMs(backoff *models.RedeliveryBackoff, maxAckTimeMs int64, maxMsgDeliveries int) []int64 {
+	if backoff == nil {
+		return nil
+	}
+	ackWindow := getEffectiveMaxAckTimeMs(maxAckTimeMs)
+	retries := getEffectiveMaxMsgDeliveries(maxMsgDeliveries) - 2
+	if retries < 1 {
+		return nil
+	}
+
+	schedule := []int64{ackWindow}
+	switch backoff.Policy {
+	case redeliveryBackoffExponential:
+		window := float64(backoff.InitialDelayMs)
+		if window <= 0 {
+			window = float64(ackWindow)
+		}
+		multiplier := backoff.Multiplier
+		if multiplier == 0 {
+			multiplier = 2
+		}
+		maxWindow := backoff.MaxDelayMs
+		if maxWindow <= 0 {
+			maxWindow = maxRedeliveryWindowMs
+		}
+		for i := 0; i < retries; i++ {
+			schedule = append(schedule, int64(math.Min(window, float64(maxWindow))))
+			window *= multiplier
+		}
+	case redeliveryBackoffStepped:
+		for i, step := range backoff.StepsMs {
+			if i == retries {
+				break
+			}
+			schedule = append(schedule, step)
+		}
+	default:
+		return nil
+	}
+	return schedule
+}
+
+func isSameRedeliverySchedule(a, b models.Consumer) bool {
+	scheduleA := getRedeliveryScheduleMs(a.RedeliveryBackoff, a.MaxAckTimeMs, a.MaxMsgDeliveries)
+	scheduleB := getRedeliveryScheduleMs(b.RedeliveryBackoff, b.MaxAckTimeMs, b.MaxMsgDeliveries)
+	if len(scheduleA) != len(scheduleB) {
+		return false
+	}
+	for i := range scheduleA {
+		if scheduleA[i] != scheduleB[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func isConsumerGroupExist(consumerGroup string, stationId primitive.ObjectID) (bool, models.Consumer, error) {
 	filter := bson.M{"consumers_group": consumerGroup, "station_id": stationId, "is_deleted": false}
 	var consumer models.Consumer
@@ -68,7 +177,7 @@ func GetConsumerGroupMembers(cgName string, station models.Station) ([]models.Cg
 		bson.D{{"$sort", bson.D{{"creation_date", -1}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"name", 1}, {"created_by_user", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"name", 1}, {"created_by_user", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"redelivery_backoff", 1}, {"content_filter", 1}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}},
 	})
 	if err != nil {
@@ -78,6 +187,9 @@ func GetConsumerGroupMembers(cgName string, station models.Station) ([]models.Cg
 	if err = cursor.All(context.TODO(), &consumers); err != nil {
 		return consumers, err
 	}
+	for i := range consumers {
+		consumers[i].State = livenessState(consumers[i].IsActive, consumers[i].IsDeleted, consumers[i].State)
+	}
 
 	var dedupedConsumers []models.CgMember
 	consumersNames := []string{}
@@ -97,14 +209,14 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 	var ccr createConsumerRequest
 	if err := json.Unmarshal(msg, &ccr); err != nil {
 		s.Errorf("createConsumerDirect: Failed creating consumer: %v\n%v", err.Error(), string(msg))
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	name := strings.ToLower(ccr.Name)
 	err := validateConsumerName(name)
 	if err != nil {
 		serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -113,7 +225,7 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 		err = validateConsumerName(consumerGroup)
 		if err != nil {
 			serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error())
-			respondWithErr(s, reply, err)
+			respondWithErr(s, c.acc, reply, err)
 			return
 		}
 	} else {
@@ -124,32 +236,46 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 	err = validateConsumerType(consumerType)
 	if err != nil {
 		serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	err = validateRedeliveryBackoff(ccr.RedeliveryBackoff, ccr.MaxMsgDeliveries)
+	if err != nil {
+		serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	_, err = compileContentFilter(ccr.ContentFilter)
+	if err != nil {
+		serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	connectionIdObj, err := primitive.ObjectIDFromHex(ccr.ConnectionId)
 	if err != nil {
 		serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": Connection ID is not valid")
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	exist, connection, err := IsConnectionExist(connectionIdObj)
 	if err != nil {
 		errMsg := "Consumer " + ccr.Name + ": " + err.Error()
 		serv.Errorf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	if !exist {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": Connection ID " + ccr.ConnectionId + " was not found"
 		serv.Warnf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, errors.New(errMsg))
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
 		return
 	}
 	if !connection.IsActive {
 		serv.Warnf("createConsumerDirect: Failed creating consumer " + ccr.Name + " at station " + ccr.StationName + ": Connection is not active")
-		respondWithErr(s, reply, errors.New("connection is not active"))
+		respondWithErr(s, c.acc, reply, errors.New("connection is not active"))
 		return
 	}
 
@@ -157,24 +283,24 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 		serv.Errorf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, tenantNameFromAccount(c.acc))
 	if err != nil {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 		serv.Errorf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	if !exist {
 		var created bool
-		station, created, err = CreateDefaultStation(s, stationName, connection.CreatedByUser)
+		station, created, err = CreateDefaultStation(s, stationName, connection.CreatedByUser, tenantNameFromAccount(c.acc))
 		if err != nil {
 			errMsg := "creating default station error: Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 			serv.Errorf("createConsumerDirect: " + errMsg)
-			respondWithErr(s, reply, err)
+			respondWithErr(s, c.acc, reply, err)
 			return
 		}
 
@@ -213,13 +339,13 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 		serv.Errorf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	if exist {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": Consumer name has to be unique per station"
 		serv.Warnf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, errors.New("memphis: "+errMsg))
+		respondWithErr(s, c.acc, reply, errors.New("memphis: "+errMsg))
 		return
 	}
 
@@ -227,32 +353,34 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 		serv.Errorf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	newConsumer := models.Consumer{
-		ID:               primitive.NewObjectID(),
-		Name:             name,
-		StationId:        station.ID,
-		Type:             consumerType,
-		ConnectionId:     connectionIdObj,
-		CreatedByUser:    connection.CreatedByUser,
-		ConsumersGroup:   consumerGroup,
-		IsActive:         true,
-		CreationDate:     time.Now(),
-		IsDeleted:        false,
-		MaxAckTimeMs:     int64(ccr.MaxAckTimeMillis),
-		MaxMsgDeliveries: ccr.MaxMsgDeliveries,
+		ID:                primitive.NewObjectID(),
+		Name:              name,
+		StationId:         station.ID,
+		Type:              consumerType,
+		ConnectionId:      connectionIdObj,
+		CreatedByUser:     connection.CreatedByUser,
+		ConsumersGroup:    consumerGroup,
+		IsActive:          true,
+		CreationDate:      time.Now(),
+		IsDeleted:         false,
+		MaxAckTimeMs:      int64(ccr.MaxAckTimeMillis),
+		MaxMsgDeliveries:  ccr.MaxMsgDeliveries,
+		RedeliveryBackoff: ccr.RedeliveryBackoff,
+		ContentFilter:     ccr.ContentFilter,
 	}
 
 	if consumerGroupExist {
-		if newConsumer.MaxAckTimeMs != consumerFromGroup.MaxAckTimeMs || newConsumer.MaxMsgDeliveries != consumerFromGroup.MaxMsgDeliveries {
+		if newConsumer.MaxAckTimeMs != consumerFromGroup.MaxAckTimeMs || newConsumer.MaxMsgDeliveries != consumerFromGroup.MaxMsgDeliveries || !isSameRedeliverySchedule(newConsumer, consumerFromGroup) || !isSameContentFilter(newConsumer.ContentFilter, consumerFromGroup.ContentFilter) {
 			err := s.CreateConsumer(newConsumer, station)
 			if err != nil {
 				errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 				serv.Errorf("createConsumerDirect: " + errMsg)
-				respondWithErr(s, reply, err)
+				respondWithErr(s, c.acc, reply, err)
 				return
 			}
 		}
@@ -261,7 +389,7 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 		if err != nil {
 			errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 			serv.Errorf("createConsumerDirect: " + errMsg)
-			respondWithErr(s, reply, err)
+			respondWithErr(s, c.acc, reply, err)
 			return
 		}
 	}
@@ -277,6 +405,8 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 			"creation_date":      newConsumer.CreationDate,
 			"max_ack_time_ms":    newConsumer.MaxAckTimeMs,
 			"max_msg_deliveries": newConsumer.MaxMsgDeliveries,
+			"redelivery_backoff": newConsumer.RedeliveryBackoff,
+			"content_filter":     newConsumer.ContentFilter,
 		},
 	}
 	opts := options.Update().SetUpsert(true)
@@ -284,13 +414,22 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
 		serv.Errorf("createConsumerDirect: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
+	if isPartitioned(station.PartitionsNumber) {
+		_, err = s.rebalancePartitions(station, consumerGroup)
+		if err != nil {
+			errMsg := "Consumer " + ccr.Name + " at station " + ccr.StationName + ": " + err.Error()
+			serv.Errorf("createConsumerDirect: " + errMsg)
+		}
+	}
+
 	if updateResults.MatchedCount == 0 {
 		message := "Consumer " + name + " has been created by user " + c.memphisInfo.username
 		serv.Noticef(message)
+		s.memphisWSPublishEvent(station.TenantName, memphisWS_Event_CgMemberJoined, stationName.Ext(), newConsumer)
 		var auditLogs []interface{}
 		newAuditLog := models.AuditLog{
 			ID:            primitive.NewObjectID(),
@@ -318,7 +457,7 @@ func (s *Server) createConsumerDirect(c *client, reply string, msg []byte) {
 		}
 	}
 
-	respondWithErr(s, reply, nil)
+	respondWithErr(s, c.acc, reply, nil)
 	return
 }
 
@@ -330,7 +469,7 @@ func (ch ConsumersHandler) GetAllConsumers(c *gin.Context) {
 		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"consumers_group", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"consumers_group", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"redelivery_backoff", 1}, {"content_filter", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 	})
 	if err != nil {
 		serv.Errorf("GetAllConsumers: " + err.Error())
@@ -343,6 +482,9 @@ func (ch ConsumersHandler) GetAllConsumers(c *gin.Context) {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
+	for i := range consumers {
+		consumers[i].State = livenessState(consumers[i].IsActive, consumers[i].IsDeleted, consumers[i].State)
+	}
 
 	if len(consumers) == 0 {
 		c.IndentedJSON(200, []string{})
@@ -360,7 +502,7 @@ func (ch ConsumersHandler) GetCgsByStation(stationName StationName, station mode
 		bson.D{{"$sort", bson.D{{"creation_date", -1}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"name", 1}, {"created_by_user", 1}, {"consumers_group", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"name", 1}, {"created_by_user", 1}, {"consumers_group", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"redelivery_backoff", 1}, {"content_filter", 1}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 		bson.D{{"$project", bson.D{{"connection", 0}}}},
 	})
 	if err != nil {
@@ -370,6 +512,9 @@ func (ch ConsumersHandler) GetCgsByStation(stationName StationName, station mode
 	if err = cursor.All(context.TODO(), &consumers); err != nil {
 		return cgs, cgs, cgs, err
 	}
+	for i := range consumers {
+		consumers[i].State = livenessState(consumers[i].IsActive, consumers[i].IsDeleted, consumers[i].State)
+	}
 
 	if len(consumers) == 0 {
 		return []models.Cg{}, []models.Cg{}, []models.Cg{}, nil
@@ -390,6 +535,9 @@ func (ch ConsumersHandler) GetCgsByStation(stationName StationName, station mode
 				Name:                  consumer.ConsumersGroup,
 				MaxAckTimeMs:          consumer.MaxAckTimeMs,
 				MaxMsgDeliveries:      consumer.MaxMsgDeliveries,
+				RedeliveryBackoff:     consumer.RedeliveryBackoff,
+				RedeliveryScheduleMs:  getRedeliveryScheduleMs(consumer.RedeliveryBackoff, consumer.MaxAckTimeMs, consumer.MaxMsgDeliveries),
+				ContentFilter:         consumer.ContentFilter,
 				ConnectedConsumers:    []models.ExtendedConsumer{},
 				DisconnectedConsumers: []models.ExtendedConsumer{},
 				DeletedConsumers:      []models.ExtendedConsumer{},
@@ -420,7 +568,7 @@ func (ch ConsumersHandler) GetCgsByStation(stationName StationName, station mode
 			cg.IsActive = false
 			cg.IsDeleted = true
 		} else { // not deleted
-			cgInfo, err := ch.S.GetCgInfo(stationName, cg.Name)
+			cgInfo, err := ch.S.GetCgInfo(station.TenantName, stationName, cg.Name)
 			if err != nil {
 				return cgs, cgs, cgs, err
 			}
@@ -433,6 +581,7 @@ func (ch ConsumersHandler) GetCgsByStation(stationName StationName, station mode
 			cg.InProcessMessages = cgInfo.NumAckPending
 			cg.UnprocessedMessages = int(cgInfo.NumPending)
 			cg.PoisonMessages = totalPoisonMsgs
+			cg.ContentFilterStats = cgInfo.ContentFilter
 		}
 
 		if len(cg.ConnectedConsumers) > 0 {
@@ -482,7 +631,7 @@ func (ch ConsumersHandler) GetAllConsumersByStation(c *gin.Context) { // for RES
 		return
 	}
 
-	exist, station, err := IsStationExist(sn)
+	exist, station, err := IsStationExist(sn, getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetAllConsumersByStation: At station " + body.StationName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -501,7 +650,7 @@ func (ch ConsumersHandler) GetAllConsumersByStation(c *gin.Context) { // for RES
 		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"consumers_group", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"consumers_group", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"max_ack_time_ms", 1}, {"max_msg_deliveries", 1}, {"redelivery_backoff", 1}, {"content_filter", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}},
 	})
 	if err != nil {
@@ -517,6 +666,9 @@ func (ch ConsumersHandler) GetAllConsumersByStation(c *gin.Context) { // for RES
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
+	for i := range consumers {
+		consumers[i].State = livenessState(consumers[i].IsActive, consumers[i].IsDeleted, consumers[i].State)
+	}
 
 	if len(consumers) == 0 {
 		c.IndentedJSON(200, []string{})
@@ -529,7 +681,7 @@ func (s *Server) destroyConsumerDirect(c *client, reply string, msg []byte) {
 	var dcr destroyConsumerRequest
 	if err := json.Unmarshal(msg, &dcr); err != nil {
 		s.Errorf("destroyConsumerDirect: %v", err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -537,16 +689,16 @@ func (s *Server) destroyConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Station " + dcr.StationName + ": " + err.Error()
 		serv.Errorf("DestroyConsumer: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	name := strings.ToLower(dcr.ConsumerName)
-	_, station, err := IsStationExist(stationName)
+	_, station, err := IsStationExist(stationName, tenantNameFromAccount(c.acc))
 	if err != nil {
 		errMsg := "Station " + dcr.StationName + ": " + err.Error()
 		serv.Errorf("DestroyConsumer: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -558,13 +710,13 @@ func (s *Server) destroyConsumerDirect(c *client, reply string, msg []byte) {
 	if err == mongo.ErrNoDocuments {
 		errMsg := "Consumer " + dcr.ConsumerName + " at station " + dcr.StationName + " does not exist"
 		serv.Warnf("DestroyConsumer: " + errMsg)
-		respondWithErr(s, reply, errors.New(errMsg))
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
 		return
 	}
 	if err != nil {
 		errMsg := "Consumer " + dcr.ConsumerName + " at station " + dcr.StationName + ": " + err.Error()
 		serv.Errorf("DestroyConsumer: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -575,7 +727,7 @@ func (s *Server) destroyConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Consumer " + dcr.ConsumerName + " at station " + dcr.StationName + ": " + err.Error()
 		serv.Errorf("DestroyConsumer: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -584,26 +736,32 @@ func (s *Server) destroyConsumerDirect(c *client, reply string, msg []byte) {
 	if err != nil {
 		errMsg := "Consumer " + dcr.ConsumerName + " at station " + dcr.StationName + ": " + err.Error()
 		serv.Errorf("DestroyConsumer: " + errMsg)
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	if count == 0 { // no other members in this group
-		err = s.RemoveConsumer(stationName, consumer.ConsumersGroup)
+		err = s.RemoveConsumer(station.TenantName, stationName, consumer.ConsumersGroup)
 		if err != nil && !IsNatsErr(err, JSConsumerNotFoundErr) {
 			errMsg := "Consumer group " + consumer.ConsumersGroup + " at station " + dcr.StationName + ": " + err.Error()
 			serv.Errorf("DestroyConsumer: " + errMsg)
-			respondWithErr(s, reply, err)
+			respondWithErr(s, c.acc, reply, err)
 			return
 		}
 
-		err = RemovePoisonedCg(stationName, consumer.ConsumersGroup)
+		err = RemovePoisonedCg(station.TenantName, stationName, consumer.ConsumersGroup)
 		if err != nil {
 			errMsg := "Consumer group " + consumer.ConsumersGroup + " at station " + dcr.StationName + ": " + err.Error()
 			serv.Errorf("DestroyConsumer: " + errMsg)
-			respondWithErr(s, reply, err)
+			respondWithErr(s, c.acc, reply, err)
 			return
 		}
+	} else if isPartitioned(station.PartitionsNumber) {
+		_, err = s.rebalancePartitions(station, consumer.ConsumersGroup)
+		if err != nil {
+			errMsg := "Consumer group " + consumer.ConsumersGroup + " at station " + dcr.StationName + ": " + err.Error()
+			serv.Errorf("DestroyConsumer: " + errMsg)
+		}
 	}
 
 	message := "Consumer " + name + " has been deleted by user " + c.memphisInfo.username
@@ -629,7 +787,7 @@ func (s *Server) destroyConsumerDirect(c *client, reply string, msg []byte) {
 		analytics.SendEvent(c.memphisInfo.username, "user-remove-consumer")
 	}
 
-	respondWithErr(s, reply, nil)
+	respondWithErr(s, c.acc, reply, nil)
 	return
 }
 
@@ -660,6 +818,7 @@ func (ch ConsumersHandler) KillConsumers(connectionId primitive.ObjectID) error
 			serv.Errorf("KillConsumers: " + errMsg)
 			return err
 		}
+		serv.rebalanceConnectionPartitions(connectionId)
 
 		userType := "application"
 		if consumers[0].CreatedByUser == "root" {
@@ -700,6 +859,7 @@ func (ch ConsumersHandler) ReliveConsumers(connectionId primitive.ObjectID) erro
 		serv.Errorf("ReliveConsumers: " + err.Error())
 		return err
 	}
+	serv.rebalanceConnectionPartitions(connectionId)
 
 	return nil
 }
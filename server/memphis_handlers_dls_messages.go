@@ -35,19 +35,20 @@ const (
 
 type PoisonMessagesHandler struct{ S *Server }
 
-func (s *Server) ListenForPoisonMessages() {
-	s.queueSubscribe("$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.>",
+func (s *Server) ListenForPoisonMessages(acc *Account) {
+	s.queueSubscribeOnAcc(acc, "$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES.>",
 		"$memphis_poison_messages_listeners_group",
-		createPoisonMessageHandler(s))
+		createPoisonMessageHandler(s, acc))
 }
 
-func createPoisonMessageHandler(s *Server) simplifiedMsgHandler {
+func createPoisonMessageHandler(s *Server, acc *Account) simplifiedMsgHandler {
 	return func(_ *client, _, _ string, msg []byte) {
-		go s.handleNewPoisonMessage(copyBytes(msg))
+		go s.handleNewPoisonMessage(acc, copyBytes(msg))
 	}
 }
 
-func (s *Server) handleNewPoisonMessage(msg []byte) {
+func (s *Server) handleNewPoisonMessage(acc *Account, msg []byte) {
+	tenantName := tenantNameFromAccount(acc)
 	var message map[string]interface{}
 	err := json.Unmarshal(msg, &message)
 	if err != nil {
@@ -57,7 +58,7 @@ func (s *Server) handleNewPoisonMessage(msg []byte) {
 
 	streamName := message["stream"].(string)
 	stationName := StationNameFromStreamName(streamName)
-	_, station, err := IsStationExist(stationName)
+	_, station, err := IsStationExist(stationName, tenantName)
 	if err != nil {
 		serv.Errorf("handleNewPoisonMessage: Error while getting notified about a poison message: " + err.Error())
 		return
@@ -67,11 +68,14 @@ func (s *Server) handleNewPoisonMessage(msg []byte) {
 	}
 
 	cgName := message["consumer"].(string)
+	if isPartitioned(station.PartitionsNumber) {
+		cgName = cgNameFromPartitionDurable(cgName)
+	}
 	cgName = revertDelimiters(cgName)
 	messageSeq := message["stream_seq"].(float64)
 	deliveriesCount := message["deliveries"].(float64)
 
-	poisonMessageContent, err := s.memphisGetMessage(stationName.Intern(), uint64(messageSeq))
+	poisonMessageContent, err := s.memphisGetMessage(tenantName, stationName.Intern(), uint64(messageSeq))
 	if err != nil {
 		serv.Errorf("handleNewPoisonMessage: Error while getting notified about a poison message: " + err.Error())
 		return
@@ -95,6 +99,11 @@ func (s *Server) handleNewPoisonMessage(msg []byte) {
 		Data:     hex.EncodeToString(poisonMessageContent.Data),
 		Headers:  headersJson,
 	}
+	if cc, chunked := getClaimCheck(headersJson); chunked {
+		messagePayload.Size = cc.payloadSize
+		messagePayload.Chunked = true
+		messagePayload.ChunksTotal = cc.chunksTotal
+	}
 
 	if station.IsNative {
 		connectionIdHeader := headersJson["$memphis_connectionId"]
@@ -143,6 +152,17 @@ func (s *Server) handleNewPoisonMessage(msg []byte) {
 			PoisoningTime:   time.Now(),
 			DeliveriesCount: int(deliveriesCount),
 		}
+
+		// keep the retry schedule the message went through, the group may change it later
+		cgExist, consumerFromGroup, err := isConsumerGroupExist(cgName, station.ID)
+		if err != nil {
+			serv.Errorf("handleNewPoisonMessage: Error while getting notified about a poison message: " + err.Error())
+			return
+		}
+		if cgExist {
+			poisonedCg.RedeliveryBackoff = consumerFromGroup.RedeliveryBackoff
+			poisonedCg.RedeliveryScheduleMs = getRedeliveryScheduleMs(consumerFromGroup.RedeliveryBackoff, consumerFromGroup.MaxAckTimeMs, consumerFromGroup.MaxMsgDeliveries)
+		}
 	}
 
 	id := GetDlsMsgId(stationName.Intern(), int(messageSeq), producedByHeader, poisonMessageContent.Time.String())
@@ -162,7 +182,8 @@ func (s *Server) handleNewPoisonMessage(msg []byte) {
 		serv.Errorf("handleNewPoisonMessage: Error while getting notified about a poison message: " + err.Error())
 		return
 	}
-	s.sendInternalAccountMsg(s.GlobalAccount(), poisonSubjectName, msgToSend)
+	s.sendInternalAccountMsg(acc, poisonSubjectName, msgToSend)
+	s.memphisWSPublishEvent(tenantName, memphisWS_Event_DlsMessageAdded, stationName.Ext(), models.LightDlsMessageResponse{MessageSeq: pmMessage.MessageSeq, ID: pmMessage.ID, Message: pmMessage.Message})
 
 	idForUrl := pmMessage.ID
 	var msgUrl = UI_url + "/stations/" + stationName.Ext() + "/" + idForUrl
@@ -189,7 +210,12 @@ func (pmh PoisonMessagesHandler) GetDlsMsgsByStationLight(station models.Station
 	durableName := "$memphis_fetch_dls_consumer_" + uid
 	var msgs []StoredMsg
 
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	acc, err := serv.getTenantAccount(station.TenantName)
+	if err != nil {
+		return []models.LightDlsMessageResponse{}, []models.LightDlsMessageResponse{}, 0, poisonedCgMap, err
+	}
+
+	streamInfo, err := serv.memphisStreamInfo(station.TenantName, streamName)
 	if err != nil {
 		return []models.LightDlsMessageResponse{}, []models.LightDlsMessageResponse{}, 0, poisonedCgMap, err
 	}
@@ -207,7 +233,7 @@ func (pmh PoisonMessagesHandler) GetDlsMsgsByStationLight(station models.Station
 		Durable:       durableName,
 	}
 
-	err = serv.memphisAddConsumer(streamName, &cc)
+	err = serv.memphisAddConsumer(station.TenantName, streamName, &cc)
 	if err != nil {
 		return []models.LightDlsMessageResponse{}, []models.LightDlsMessageResponse{}, 0, poisonedCgMap, err
 	}
@@ -217,10 +243,10 @@ func (pmh PoisonMessagesHandler) GetDlsMsgsByStationLight(station models.Station
 	reply := durableName + "_reply"
 	req := []byte(strconv.FormatUint(amount, 10))
 
-	sub, err := serv.subscribeOnGlobalAcc(reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
+	sub, err := serv.subscribeOnAcc(acc, reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
 		go func(respCh chan StoredMsg, subject, reply string, msg []byte) {
 			// ack
-			serv.sendInternalAccountMsg(serv.GlobalAccount(), reply, []byte(_EMPTY_))
+			serv.sendInternalAccountMsg(acc, reply, []byte(_EMPTY_))
 			rawTs := tokenAt(reply, 8)
 			seq, _, _ := ackReplyInfo(reply)
 
@@ -241,7 +267,7 @@ func (pmh PoisonMessagesHandler) GetDlsMsgsByStationLight(station models.Station
 		return []models.LightDlsMessageResponse{}, []models.LightDlsMessageResponse{}, 0, poisonedCgMap, err
 	}
 
-	serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), subject, reply, nil, req, true)
+	serv.sendInternalAccountMsgWithReply(acc, subject, reply, nil, req, true)
 
 	timer := time.NewTimer(timeout)
 	for i := uint64(0); i < amount; i++ {
@@ -255,8 +281,8 @@ func (pmh PoisonMessagesHandler) GetDlsMsgsByStationLight(station models.Station
 
 cleanup:
 	timer.Stop()
-	serv.unsubscribeOnGlobalAcc(sub)
-	err = serv.memphisRemoveConsumer(streamName, durableName)
+	serv.unsubscribeOnAcc(acc, sub)
+	err = serv.memphisRemoveConsumer(station.TenantName, streamName, durableName)
 	idCheck := make(map[string]bool)
 	if err != nil {
 		return []models.LightDlsMessageResponse{}, []models.LightDlsMessageResponse{}, 0, poisonedCgMap, err
@@ -321,7 +347,7 @@ func getDlsMessageById(station models.Station, sn StationName, dlsMsgId, dlsType
 	timeout := 500 * time.Millisecond
 	dlsStreamName := fmt.Sprintf(dlsStreamName, sn.Intern())
 
-	streamInfo, err := serv.memphisStreamInfo(dlsStreamName)
+	streamInfo, err := serv.memphisStreamInfo(station.TenantName, dlsStreamName)
 	if err != nil {
 		return models.DlsMessageResponse{}, err
 	}
@@ -343,7 +369,7 @@ func getDlsMessageById(station models.Station, sn StationName, dlsMsgId, dlsType
 		filterSubj = GetDlsSubject("poison", sn.Intern(), dlsMsgId, ">")
 	}
 
-	msgs, err := serv.memphisGetMessagesByFilter(dlsStreamName, filterSubj, startSeq, amount, timeout)
+	msgs, err := serv.memphisGetMessagesByFilter(station.TenantName, dlsStreamName, filterSubj, startSeq, amount, timeout)
 	if err != nil {
 		return models.DlsMessageResponse{}, err
 	}
@@ -392,7 +418,7 @@ func getDlsMessageById(station models.Station, sn StationName, dlsMsgId, dlsType
 			}
 
 			if msgType == "poison" {
-				cgInfo, err := serv.GetCgInfo(sn, dlsMsg.PoisonedCg.CgName)
+				cgInfo, err := serv.GetCgInfo(station.TenantName, sn, dlsMsg.PoisonedCg.CgName)
 				if err != nil {
 					return models.DlsMessageResponse{}, err
 				}
@@ -423,6 +449,18 @@ func getDlsMessageById(station models.Station, sn StationName, dlsMsgId, dlsType
 				}
 			}
 
+			// the returned message is the last one, show the beginning of its reassembled payload in case it is a large one
+			if cc, chunked := getClaimCheck(dlsMsg.Message.Headers); chunked && i == len(msgs)-1 {
+				preview, err := serv.getChunkedPayloadPreview(station.TenantName, sn.Intern(), cc)
+				if err != nil {
+					return models.DlsMessageResponse{}, err
+				}
+				dlsMsg.Message.Data = hex.EncodeToString(preview)
+				dlsMsg.Message.Size = cc.payloadSize
+				dlsMsg.Message.Chunked = true
+				dlsMsg.Message.ChunksTotal = cc.chunksTotal
+			}
+
 			for header := range dlsMsg.Message.Headers {
 				if strings.HasPrefix(header, "$memphis") {
 					delete(dlsMsg.Message.Headers, header)
@@ -450,7 +488,7 @@ func getDlsMessageById(station models.Station, sn StationName, dlsMsgId, dlsType
 	return result, nil
 }
 
-func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(stationName string) (int, error) {
+func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(tenantName, stationName string) (int, error) {
 	count := 0
 	timeout := 1 * time.Second
 	idCheck := make(map[string]bool)
@@ -465,7 +503,12 @@ func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(stationName string) (i
 	durableName := "$memphis_fetch_dls_consumer_" + uid
 	var msgs []StoredMsg
 
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	acc, err := serv.getTenantAccount(tenantName)
+	if err != nil {
+		return 0, err
+	}
+
+	streamInfo, err := serv.memphisStreamInfo(tenantName, streamName)
 	if err != nil {
 		return 0, err
 	}
@@ -483,7 +526,7 @@ func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(stationName string) (i
 		Durable:       durableName,
 	}
 
-	err = serv.memphisAddConsumer(streamName, &cc)
+	err = serv.memphisAddConsumer(tenantName, streamName, &cc)
 	if err != nil {
 		return 0, err
 	}
@@ -493,10 +536,10 @@ func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(stationName string) (i
 	reply := durableName + "_reply"
 	req := []byte(strconv.FormatUint(amount, 10))
 
-	sub, err := serv.subscribeOnGlobalAcc(reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
+	sub, err := serv.subscribeOnAcc(acc, reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
 		go func(respCh chan StoredMsg, subject, reply string, msg []byte) {
 			// ack
-			serv.sendInternalAccountMsg(serv.GlobalAccount(), reply, []byte(_EMPTY_))
+			serv.sendInternalAccountMsg(acc, reply, []byte(_EMPTY_))
 			rawTs := tokenAt(reply, 8)
 			seq, _, _ := ackReplyInfo(reply)
 
@@ -517,7 +560,7 @@ func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(stationName string) (i
 		return 0, err
 	}
 
-	serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), subject, reply, nil, req, true)
+	serv.sendInternalAccountMsgWithReply(acc, subject, reply, nil, req, true)
 
 	timer := time.NewTimer(timeout)
 	for i := uint64(0); i < amount; i++ {
@@ -531,8 +574,8 @@ func (pmh PoisonMessagesHandler) GetTotalDlsMsgsByStation(stationName string) (i
 
 cleanup:
 	timer.Stop()
-	serv.unsubscribeOnGlobalAcc(sub)
-	err = serv.memphisRemoveConsumer(streamName, durableName)
+	serv.unsubscribeOnAcc(acc, sub)
+	err = serv.memphisRemoveConsumer(tenantName, streamName, durableName)
 	if err != nil {
 		return 0, err
 	}
@@ -559,7 +602,7 @@ cleanup:
 	return count, nil
 }
 
-func RemovePoisonedCg(stationName StationName, cgName string) error {
+func RemovePoisonedCg(tenantName string, stationName StationName, cgName string) error {
 	timeout := 500 * time.Millisecond
 
 	streamName := fmt.Sprintf(dlsStreamName, stationName.Intern())
@@ -568,7 +611,12 @@ func RemovePoisonedCg(stationName StationName, cgName string) error {
 	durableName := "$memphis_fetch_dls_consumer_" + uid
 	var msgs []StoredMsg
 
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	acc, err := serv.getTenantAccount(tenantName)
+	if err != nil {
+		return err
+	}
+
+	streamInfo, err := serv.memphisStreamInfo(tenantName, streamName)
 	if err != nil {
 		return err
 	}
@@ -586,7 +634,7 @@ func RemovePoisonedCg(stationName StationName, cgName string) error {
 		Durable:       durableName,
 	}
 
-	err = serv.memphisAddConsumer(streamName, &cc)
+	err = serv.memphisAddConsumer(tenantName, streamName, &cc)
 	if err != nil {
 		return err
 	}
@@ -596,10 +644,10 @@ func RemovePoisonedCg(stationName StationName, cgName string) error {
 	reply := durableName + "_reply"
 	req := []byte(strconv.FormatUint(amount, 10))
 
-	sub, err := serv.subscribeOnGlobalAcc(reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
+	sub, err := serv.subscribeOnAcc(acc, reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
 		go func(respCh chan StoredMsg, subject, reply string, msg []byte) {
 			// ack
-			serv.sendInternalAccountMsg(serv.GlobalAccount(), reply, []byte(_EMPTY_))
+			serv.sendInternalAccountMsg(acc, reply, []byte(_EMPTY_))
 			rawTs := tokenAt(reply, 8)
 			seq, _, _ := ackReplyInfo(reply)
 
@@ -620,7 +668,7 @@ func RemovePoisonedCg(stationName StationName, cgName string) error {
 		return err
 	}
 
-	serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), subject, reply, nil, req, true)
+	serv.sendInternalAccountMsgWithReply(acc, subject, reply, nil, req, true)
 
 	timer := time.NewTimer(timeout)
 	for i := uint64(0); i < amount; i++ {
@@ -634,8 +682,8 @@ func RemovePoisonedCg(stationName StationName, cgName string) error {
 
 cleanup:
 	timer.Stop()
-	serv.unsubscribeOnGlobalAcc(sub)
-	err = serv.memphisRemoveConsumer(streamName, durableName)
+	serv.unsubscribeOnAcc(acc, sub)
+	err = serv.memphisRemoveConsumer(tenantName, streamName, durableName)
 	if err != nil {
 		return err
 	}
@@ -650,7 +698,7 @@ cleanup:
 		}
 		if msgType == "poison" {
 			if dlsMsg.PoisonedCg.CgName == cgName {
-				_, err = serv.memphisDeleteMsgFromStream(streamName, msg.Sequence)
+				_, err = serv.memphisDeleteMsgFromStream(tenantName, streamName, msg.Sequence)
 				if err != nil {
 					return err
 				}
@@ -661,7 +709,7 @@ cleanup:
 	return nil
 }
 
-func GetTotalPoisonMsgsByCg(stationName, cgName string) (int, error) {
+func GetTotalPoisonMsgsByCg(tenantName, stationName, cgName string) (int, error) {
 	timeout := 500 * time.Millisecond
 
 	sn, err := StationNameFromStr(stationName)
@@ -670,7 +718,7 @@ func GetTotalPoisonMsgsByCg(stationName, cgName string) (int, error) {
 	}
 	streamName := fmt.Sprintf(dlsStreamName, sn.Intern())
 
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	streamInfo, err := serv.memphisStreamInfo(tenantName, streamName)
 	if err != nil {
 		return 0, err
 	}
@@ -682,18 +730,18 @@ func GetTotalPoisonMsgsByCg(stationName, cgName string) (int, error) {
 	}
 	internalCgName := replaceDelimiters(cgName)
 	filter := GetDlsSubject("poison", sn.Intern(), "*", internalCgName)
-	msgs, err := serv.memphisGetMessagesByFilter(streamName, filter, startSeq, amount, timeout)
+	msgs, err := serv.memphisGetMessagesByFilter(tenantName, streamName, filter, startSeq, amount, timeout)
 	if err != nil {
 		return 0, err
 	}
 	return len(msgs), nil
 }
 
-func GetPoisonedCgsByMessage(stationNameInter string, message models.MessageDetails) ([]models.PoisonedCg, error) {
+func GetPoisonedCgsByMessage(tenantName, stationNameInter string, message models.MessageDetails) ([]models.PoisonedCg, error) {
 	timeout := 500 * time.Millisecond
 	poisonedCgs := []models.PoisonedCg{}
 	streamName := fmt.Sprintf(dlsStreamName, stationNameInter)
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	streamInfo, err := serv.memphisStreamInfo(tenantName, streamName)
 	if err != nil {
 		return []models.PoisonedCg{}, err
 	}
@@ -705,13 +753,13 @@ func GetPoisonedCgsByMessage(stationNameInter string, message models.MessageDeta
 	}
 	msgId := GetDlsMsgId(stationNameInter, message.MessageSeq, message.ProducedBy, message.TimeSent.String())
 	filter := GetDlsSubject("poison", stationNameInter, msgId, "*")
-	msgs, err := serv.memphisGetMessagesByFilter(streamName, filter, 0, amount, timeout)
+	msgs, err := serv.memphisGetMessagesByFilter(tenantName, streamName, filter, 0, amount, timeout)
 	if err != nil {
 		return []models.PoisonedCg{}, err
 	}
 
 	if uint64(len(msgs)) < amount && streamInfo.State.Msgs > amount && streamInfo.State.FirstSeq < startSeq {
-		return GetPoisonedCgsByMessage(stationNameInter, message)
+		return GetPoisonedCgsByMessage(tenantName, stationNameInter, message)
 	}
 
 	for _, msg := range msgs {
@@ -44,54 +44,39 @@ func (it IntegrationsHandler) CreateIntegration(c *gin.Context) {
 	if !ok {
 		return
 	}
+
+	integration, err := it.createIntegration(body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		user, _ := getUserDetailsFromMiddleware(c)
+		analytics.SendEvent(user.Username, "user-create-integration-"+strings.ToLower(body.Name))
+	}
+	c.IndentedJSON(200, integration)
+}
+
+func (it IntegrationsHandler) createIntegration(body models.CreateIntegrationSchema) (models.Integration, error) {
 	var integration models.Integration
 	integrationType := strings.ToLower(body.Name)
 	switch integrationType {
 	case "slack":
-		var authToken, channelID, uiUrl string
-		var pmAlert, svfAlert, disconnectAlert bool
-		authToken, ok := body.Keys["auth_token"]
-		if !ok {
-			serv.Warnf("CreateIntegration: Must provide auth token for slack integration")
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Must provide auth token for slack integration"})
-		}
-		channelID, ok = body.Keys["channel_id"]
-		if !ok {
-			if !ok {
-				serv.Warnf("CreateIntegration: Must provide channel ID for slack integration")
-				c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Must provide channel ID for slack integration"})
-			}
-		}
-		uiUrl = body.UIUrl
-		if uiUrl == "" {
-			serv.Warnf("CreateIntegration: Must provide channel ID for slack integration")
-			c.AbortWithStatusJSON(500, gin.H{"message": "Must provide UI url for slack integration"})
-		}
-
-		pmAlert, ok = body.Properties[notifications.PoisonMAlert]
-		if !ok {
-			pmAlert = false
-		}
-		svfAlert, ok = body.Properties[notifications.SchemaVAlert]
-		if !ok {
-			svfAlert = false
-		}
-		disconnectAlert, ok = body.Properties[notifications.DisconEAlert]
-		if !ok {
-			disconnectAlert = false
+		authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, err := getSlackIntegrationDetails("CreateIntegration", body)
+		if err != nil {
+			return integration, err
 		}
 
-		slackIntegration, err := createSlackIntegration(authToken, channelID, pmAlert, svfAlert, disconnectAlert, body.UIUrl)
+		slackIntegration, err := createSlackIntegration(authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, body.UIUrl)
 		if err != nil {
 			if strings.Contains(err.Error(), "Invalid auth token") || strings.Contains(err.Error(), "Invalid channel ID") || strings.Contains(err.Error(), "already exists") {
 				serv.Warnf("CreateSlackIntegration: " + err.Error())
-				c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-				return
-			} else {
-				serv.Errorf("CreateSlackIntegration: " + err.Error())
-				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-				return
+				return integration, newShowableError(err.Error())
 			}
+			serv.Errorf("CreateSlackIntegration: " + err.Error())
+			return integration, err
 		}
 		integration = slackIntegration
 		if integration.Keys["auth_token"] != "" {
@@ -99,15 +84,9 @@ func (it IntegrationsHandler) CreateIntegration(c *gin.Context) {
 		}
 	default:
 		serv.Warnf("CreateIntegration: Unsupported integration type")
-		c.AbortWithStatusJSON(400, gin.H{"message": "CreateIntegration error: Unsupported integration type"})
-	}
-
-	shouldSendAnalytics, _ := shouldSendAnalytics()
-	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
-		analytics.SendEvent(user.Username, "user-create-integration-"+integrationType)
+		return integration, newShowableErrorWithStatus(400, "CreateIntegration error: Unsupported integration type")
 	}
-	c.IndentedJSON(200, integration)
+	return integration, nil
 }
 
 func (it IntegrationsHandler) UpdateIntegration(c *gin.Context) {
@@ -120,66 +99,68 @@ func (it IntegrationsHandler) UpdateIntegration(c *gin.Context) {
 	if !ok {
 		return
 	}
+
+	integration, err := it.updateIntegration(body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.IndentedJSON(200, integration)
+}
+
+func (it IntegrationsHandler) updateIntegration(body models.CreateIntegrationSchema) (models.Integration, error) {
 	var integration models.Integration
 	switch strings.ToLower(body.Name) {
 	case "slack":
-		var authToken, channelID, uiUrl string
-		var pmAlert, svfAlert, disconnectAlert bool
-		authToken, ok := body.Keys["auth_token"]
-		if !ok {
-			serv.Warnf("CreateIntegration: Must provide auth token for slack integration")
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Must provide auth token for slack integration"})
-		}
-		channelID, ok = body.Keys["channel_id"]
-		if !ok {
-			if !ok {
-				serv.Warnf("CreateIntegration: Must provide channel ID for slack integration")
-				c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Must provide channel ID for slack integration"})
-			}
-		}
-		uiUrl = body.UIUrl
-		if uiUrl == "" {
-			serv.Warnf("CreateIntegration: Must provide channel ID for slack integration")
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Must provide channel ID for slack integration"})
-		}
-		pmAlert, ok = body.Properties[notifications.PoisonMAlert]
-		if !ok {
-			pmAlert = false
-		}
-		svfAlert, ok = body.Properties[notifications.SchemaVAlert]
-		if !ok {
-			svfAlert = false
-		}
-		disconnectAlert, ok = body.Properties[notifications.DisconEAlert]
-		if !ok {
-			disconnectAlert = false
+		authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, err := getSlackIntegrationDetails("UpdateIntegration", body)
+		if err != nil {
+			return integration, err
 		}
 
-		slackIntegration, err := updateSlackIntegration(authToken, channelID, pmAlert, svfAlert, disconnectAlert, body.UIUrl)
+		slackIntegration, err := updateSlackIntegration(authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, body.UIUrl)
 		if err != nil {
 			if strings.Contains(err.Error(), "Invalid auth token") || strings.Contains(err.Error(), "Invalid channel ID") {
 				serv.Warnf("UpdateSlackIntegration: " + err.Error())
-				c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-				return
-			} else {
-				serv.Errorf("UpdateSlackIntegration: " + err.Error())
-				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-				return
+				return integration, newShowableError(err.Error())
 			}
+			serv.Errorf("UpdateSlackIntegration: " + err.Error())
+			return integration, err
 		}
 		integration = slackIntegration
 		if integration.Keys["auth_token"] != "" {
 			integration.Keys["auth_token"] = "xoxb-****"
 		}
 	default:
-		serv.Warnf("CreateIntegration: Unsupported integration type - " + body.Name)
-		c.AbortWithStatusJSON(400, gin.H{"message": "CreateIntegration: Unsupported integration type - " + body.Name})
+		serv.Warnf("UpdateIntegration: Unsupported integration type - " + body.Name)
+		return integration, newShowableErrorWithStatus(400, "UpdateIntegration: Unsupported integration type - "+body.Name)
 	}
+	return integration, nil
+}
 
-	c.IndentedJSON(200, integration)
+func getSlackIntegrationDetails(caller string, body models.CreateIntegrationSchema) (string, string, bool, bool, bool, bool, error) {
+	authToken, ok := body.Keys["auth_token"]
+	if !ok {
+		serv.Warnf(caller + ": Must provide auth token for slack integration")
+		return "", "", false, false, false, false, newShowableError("Must provide auth token for slack integration")
+	}
+	channelID, ok := body.Keys["channel_id"]
+	if !ok {
+		serv.Warnf(caller + ": Must provide channel ID for slack integration")
+		return "", "", false, false, false, false, newShowableError("Must provide channel ID for slack integration")
+	}
+	if body.UIUrl == "" {
+		serv.Warnf(caller + ": Must provide UI url for slack integration")
+		return "", "", false, false, false, false, newShowableError("Must provide UI url for slack integration")
+	}
+
+	pmAlert := body.Properties[notifications.PoisonMAlert]
+	svfAlert := body.Properties[notifications.SchemaVAlert]
+	disconnectAlert := body.Properties[notifications.DisconEAlert]
+	rateLimitAlert := body.Properties[notifications.RateLAlert]
+	return authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, nil
 }
 
-func createSlackIntegration(authToken string, channelID string, pmAlert bool, svfAlert bool, disconnectAlert bool, uiUrl string) (models.Integration, error) {
+func createSlackIntegration(authToken string, channelID string, pmAlert bool, svfAlert bool, disconnectAlert bool, rateLimitAlert bool, uiUrl string) (models.Integration, error) {
 	var slackIntegration models.Integration
 	filter := bson.M{"name": "slack"}
 	err := integrationsCollection.FindOne(context.TODO(),
@@ -189,7 +170,7 @@ func createSlackIntegration(authToken string, channelID string, pmAlert bool, sv
 		if err != nil {
 			return slackIntegration, err
 		}
-		keys, properties := createSlackKeysAndProperties(authToken, channelID, pmAlert, svfAlert, disconnectAlert, uiUrl)
+		keys, properties := createSlackKeysAndProperties(authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, uiUrl)
 		slackIntegration = models.Integration{
 			ID:         primitive.NewObjectID(),
 			Name:       "slack",
@@ -228,7 +209,7 @@ func createSlackIntegration(authToken string, channelID string, pmAlert bool, sv
 	return slackIntegration, errors.New("Slack integration already exists")
 }
 
-func updateSlackIntegration(authToken string, channelID string, pmAlert bool, svfAlert bool, disconnectAlert bool, uiUrl string) (models.Integration, error) {
+func updateSlackIntegration(authToken string, channelID string, pmAlert bool, svfAlert bool, disconnectAlert bool, rateLimitAlert bool, uiUrl string) (models.Integration, error) {
 	var slackIntegration models.Integration
 	if authToken == "" {
 		var integrationFromDb models.Integration
@@ -244,7 +225,7 @@ func updateSlackIntegration(authToken string, channelID string, pmAlert bool, sv
 	if err != nil {
 		return slackIntegration, err
 	}
-	keys, properties := createSlackKeysAndProperties(authToken, channelID, pmAlert, svfAlert, disconnectAlert, uiUrl)
+	keys, properties := createSlackKeysAndProperties(authToken, channelID, pmAlert, svfAlert, disconnectAlert, rateLimitAlert, uiUrl)
 	filter := bson.M{"name": "slack"}
 	err = integrationsCollection.FindOneAndUpdate(context.TODO(),
 		filter,
@@ -310,7 +291,7 @@ func testSlackIntegration(authToken string, channelID string, message string) er
 	return nil
 }
 
-func createSlackKeysAndProperties(authToken string, channelID string, pmAlert bool, svfAlert bool, disconnectAlert bool, uiUrl string) (map[string]string, map[string]bool) {
+func createSlackKeysAndProperties(authToken string, channelID string, pmAlert bool, svfAlert bool, disconnectAlert bool, rateLimitAlert bool, uiUrl string) (map[string]string, map[string]bool) {
 	keys := make(map[string]string)
 	keys["auth_token"] = authToken
 	keys["channel_id"] = channelID
@@ -318,6 +299,7 @@ func createSlackKeysAndProperties(authToken string, channelID string, pmAlert bo
 	properties[notifications.PoisonMAlert] = pmAlert
 	properties[notifications.SchemaVAlert] = svfAlert
 	properties[notifications.DisconEAlert] = disconnectAlert
+	properties[notifications.RateLAlert] = rateLimitAlert
 	return keys, properties
 }
 
@@ -373,24 +355,37 @@ func (it IntegrationsHandler) DisconnectIntegration(c *gin.Context) {
 	if err := DenyForSandboxEnv(c); err != nil {
 		return
 	}
-	
+
 	var body models.DisconnectIntegrationSchema
 	ok := utils.Validate(c, &body, false, nil)
 	if !ok {
 		return
 	}
 
+	if err := it.disconnectIntegration(body); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		user, _ := getUserDetailsFromMiddleware(c)
+		analytics.SendEvent(user.Username, "user-disconnect-integration-"+strings.ToLower(body.Name))
+	}
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (it IntegrationsHandler) disconnectIntegration(body models.DisconnectIntegrationSchema) error {
 	integrationType := strings.ToLower(body.Name)
 	filter := bson.M{"name": integrationType}
 	_, err := integrationsCollection.DeleteOne(context.TODO(), filter)
 	if err != nil {
 		serv.Errorf("DisconnectIntegration: Integration " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 
 	integrationUpdate := models.Integration{
-		Name:       strings.ToLower(body.Name),
+		Name:       integrationType,
 		Keys:       nil,
 		Properties: nil,
 	}
@@ -398,14 +393,12 @@ func (it IntegrationsHandler) DisconnectIntegration(c *gin.Context) {
 	msg, err := json.Marshal(integrationUpdate)
 	if err != nil {
 		serv.Errorf("DisconnectIntegration: Integration " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 	err = serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), INTEGRATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
 	if err != nil {
 		serv.Errorf("DisconnectIntegration: Integration " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 
 	switch body.Name {
@@ -416,13 +409,7 @@ func (it IntegrationsHandler) DisconnectIntegration(c *gin.Context) {
 		}
 		serv.SendUpdateToClients(update)
 	}
-
-	shouldSendAnalytics, _ := shouldSendAnalytics()
-	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
-		analytics.SendEvent(user.Username, "user-disconnect-integration-"+integrationType)
-	}
-	c.IndentedJSON(200, gin.H{})
+	return nil
 }
 
 func InitializeIntegrations(c *mongo.Client) error {
@@ -436,7 +423,7 @@ func InitializeIntegrations(c *mongo.Client) error {
 	}
 
 	if configuration.SANDBOX_ENV == "true" {
-		createSlackIntegration(configuration.SANDBOX_SLACK_BOT_TOKEN, configuration.SANDBOX_SLACK_CHANNEL_ID, true, true, true, configuration.SANDBOX_UI_URL)
+		createSlackIntegration(configuration.SANDBOX_SLACK_BOT_TOKEN, configuration.SANDBOX_SLACK_CHANNEL_ID, true, true, true, true, configuration.SANDBOX_UI_URL)
 	}
 	return nil
 }
@@ -0,0 +1,706 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"memphis-broker/analytics"
+	"memphis-broker/models"
+	"memphis-broker/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type KvHandler struct{ S *Server }
+
+func validateCreateKvBucketSchema(body *models.CreateKvBucketSchema) error {
+	body.Name = strings.ToLower(body.Name)
+	err := validateKvBucketName(body.Name)
+	if err != nil {
+		return err
+	}
+	err = validateKvHistory(body.History)
+	if err != nil {
+		return err
+	}
+	if body.History == 0 {
+		body.History = kvDefaultHistory
+	}
+
+	if body.StorageType != "" {
+		body.StorageType = strings.ToLower(body.StorageType)
+		err = validateStorageType(body.StorageType)
+		if err != nil {
+			return err
+		}
+	} else {
+		body.StorageType = "file"
+	}
+
+	if body.Replicas > 0 {
+		err = validateReplicas(body.Replicas)
+		if err != nil {
+			return err
+		}
+	} else {
+		body.Replicas = 1
+	}
+	return nil
+}
+
+// createKvBucket creates the stream of the bucket and stores it, it returns false in case the bucket already exists
+func (s *Server) createKvBucket(bucket models.KvBucket) (bool, error) {
+	err := s.createKvBucketStream(bucket)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"name": bucket.Name, "tenant_name": bucket.TenantName, "is_deleted": false}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":             bucket.ID,
+			"history":         bucket.History,
+			"ttl_in_sec":      bucket.TTLSec,
+			"storage_type":    bucket.StorageType,
+			"replicas":        bucket.Replicas,
+			"created_by_user": bucket.CreatedByUser,
+			"creation_date":   bucket.CreationDate,
+		},
+	}
+	opts := options.Update().SetUpsert(true)
+	updateResults, err := kvBucketsCollection.UpdateOne(context.TODO(), filter, update, opts)
+	if err != nil {
+		return false, err
+	}
+	return updateResults.MatchedCount == 0, nil
+}
+
+func (s *Server) removeKvBucket(bucket models.KvBucket) error {
+	err := s.removeKvBucketStream(bucket.TenantName, bucket.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = kvBucketsCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": bucket.ID},
+		bson.M{"$set": bson.M{"is_deleted": true}},
+	)
+	if err != nil {
+		return err
+	}
+	DeleteTagsFromKvBucket(bucket.ID)
+	return nil
+}
+
+func (kh KvHandler) getExtendedKvBucket(bucket models.KvBucket) (models.ExtendedKvBucket, error) {
+	streamInfo, err := kh.S.memphisStreamInfo(bucket.TenantName, getKvBucketStreamName(bucket.Name))
+	if err != nil {
+		return models.ExtendedKvBucket{}, err
+	}
+	tags, err := TagsHandler{S: kh.S}.GetTagsByKvBucket(bucket.ID)
+	if err != nil {
+		return models.ExtendedKvBucket{}, err
+	}
+
+	return models.ExtendedKvBucket{
+		ID:            bucket.ID,
+		Name:          bucket.Name,
+		History:       bucket.History,
+		TTLSec:        bucket.TTLSec,
+		StorageType:   bucket.StorageType,
+		Replicas:      bucket.Replicas,
+		CreatedByUser: bucket.CreatedByUser,
+		CreationDate:  bucket.CreationDate,
+		TotalKeys:     streamInfo.State.NumSubjects,
+		TotalBytes:    streamInfo.State.Bytes,
+		LastUpdate:    streamInfo.State.LastTime,
+		Tags:          tags,
+	}, nil
+}
+
+func (kh KvHandler) CreateKvBucket(c *gin.Context) {
+	var body models.CreateKvBucketSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	err := validateCreateKvBucketSchema(&body)
+	if err != nil {
+		serv.Warnf("CreateKvBucket: Bucket " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	tenantName := getTenantNameFromMiddleware(c)
+	exist, _, err := IsKvBucketExist(body.Name, tenantName)
+	if err != nil {
+		serv.Errorf("CreateKvBucket: Bucket " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if exist {
+		errMsg := "KV bucket " + body.Name + " already exists"
+		serv.Warnf("CreateKvBucket: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateKvBucket: Bucket " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+
+	newBucket := models.KvBucket{
+		ID:            primitive.NewObjectID(),
+		Name:          body.Name,
+		History:       body.History,
+		TTLSec:        body.TTLSec,
+		StorageType:   body.StorageType,
+		Replicas:      body.Replicas,
+		CreatedByUser: user.Username,
+		CreationDate:  time.Now(),
+		IsDeleted:     false,
+		TenantName:    tenantName,
+	}
+	created, err := kh.S.createKvBucket(newBucket)
+	if err != nil {
+		if IsNatsErr(err, JSInsufficientResourcesErr) {
+			serv.Warnf("CreateKvBucket: Bucket " + body.Name + ": KV bucket can not be created, probably since replicas count is larger than the cluster size")
+			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "KV bucket can not be created, probably since replicas count is larger than the cluster size"})
+			return
+		}
+		serv.Errorf("CreateKvBucket: Bucket " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !created {
+		errMsg := "KV bucket " + body.Name + " already exists"
+		serv.Warnf("CreateKvBucket: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	if len(body.Tags) > 0 {
+		err = AddTagsToEntity(body.Tags, "kv_bucket", newBucket.ID)
+		if err != nil {
+			serv.Errorf("CreateKvBucket: Bucket " + body.Name + " Failed adding tags: " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+	}
+
+	serv.Noticef("KV bucket " + newBucket.Name + " has been created by user " + user.Username)
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analytics.SendEvent(user.Username, "user-create-kv-bucket")
+	}
+
+	bucket, err := kh.getExtendedKvBucket(newBucket)
+	if err != nil {
+		serv.Errorf("CreateKvBucket: Bucket " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	c.IndentedJSON(200, bucket)
+}
+
+func (kh KvHandler) RemoveKvBucket(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.RemoveKvBucketSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveKvBucket: " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+
+	tenantName := getTenantNameFromMiddleware(c)
+	for _, name := range body.BucketNames {
+		name = strings.ToLower(name)
+		exist, bucket, err := IsKvBucketExist(name, tenantName)
+		if err != nil {
+			serv.Errorf("RemoveKvBucket: Bucket " + name + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if !exist {
+			errMsg := "KV bucket " + name + " does not exist"
+			serv.Warnf("RemoveKvBucket: " + errMsg)
+			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+			return
+		}
+
+		err = kh.S.removeKvBucket(bucket)
+		if err != nil {
+			serv.Errorf("RemoveKvBucket: Bucket " + name + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		serv.Noticef("KV bucket " + name + " has been deleted by user " + user.Username)
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analytics.SendEvent(user.Username, "user-remove-kv-bucket")
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (kh KvHandler) GetAllKvBuckets(c *gin.Context) {
+	var buckets []models.KvBucket
+	cursor, err := kvBucketsCollection.Find(context.TODO(), bson.M{"tenant_name": getTenantNameFromMiddleware(c), "is_deleted": false})
+	if err != nil {
+		serv.Errorf("GetAllKvBuckets: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if err = cursor.All(context.TODO(), &buckets); err != nil {
+		serv.Errorf("GetAllKvBuckets: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	extendedBuckets := []models.ExtendedKvBucket{}
+	for _, bucket := range buckets {
+		extendedBucket, err := kh.getExtendedKvBucket(bucket)
+		if IsNatsErr(err, JSStreamNotFoundErr) {
+			continue
+		} else if err != nil {
+			serv.Errorf("GetAllKvBuckets: Bucket " + bucket.Name + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		extendedBuckets = append(extendedBuckets, extendedBucket)
+	}
+	c.IndentedJSON(200, extendedBuckets)
+}
+
+func (kh KvHandler) GetKvBucket(c *gin.Context) {
+	var body models.GetKvBucketSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	bucket, ok := getKvBucketForRequest(c, "GetKvBucket", body.BucketName)
+	if !ok {
+		return
+	}
+	extendedBucket, err := kh.getExtendedKvBucket(bucket)
+	if err != nil {
+		serv.Errorf("GetKvBucket: Bucket " + bucket.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	entries, err := kh.S.kvEntries(bucket.TenantName, bucket.Name)
+	if err != nil {
+		serv.Errorf("GetKvBucket: Bucket " + bucket.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, models.GetKvBucketResponse{Bucket: extendedBucket, Entries: entries})
+}
+
+// getKvBucketForRequest aborts the request with a showable error in case the bucket does not exist
+func getKvBucketForRequest(c *gin.Context, handlerName, bucketName string) (models.KvBucket, bool) {
+	bucketName = strings.ToLower(bucketName)
+	exist, bucket, err := IsKvBucketExist(bucketName, getTenantNameFromMiddleware(c))
+	if err != nil {
+		serv.Errorf(handlerName + ": Bucket " + bucketName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return models.KvBucket{}, false
+	}
+	if !exist {
+		errMsg := "KV bucket " + bucketName + " does not exist"
+		serv.Warnf(handlerName + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return models.KvBucket{}, false
+	}
+	return bucket, true
+}
+
+func (kh KvHandler) PutKvEntry(c *gin.Context) {
+	var body models.KvPutSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	if err := validateKvKey(body.Key); err != nil {
+		serv.Warnf("PutKvEntry: Bucket " + body.BucketName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	bucket, ok := getKvBucketForRequest(c, "PutKvEntry", body.BucketName)
+	if !ok {
+		return
+	}
+
+	revision, err := kh.S.kvPut(bucket.TenantName, bucket.Name, body.Key, []byte(body.Value), body.ExpectedRevision)
+	if err != nil {
+		if errors.Is(err, ErrKvWrongRevision) {
+			serv.Warnf("PutKvEntry: Bucket " + bucket.Name + ": " + err.Error())
+			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		serv.Errorf("PutKvEntry: Bucket " + bucket.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{"revision": revision})
+}
+
+func (kh KvHandler) GetKvEntry(c *gin.Context) {
+	var body models.KvKeySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	bucket, ok := getKvBucketForRequest(c, "GetKvEntry", body.BucketName)
+	if !ok {
+		return
+	}
+
+	entry, err := kh.S.kvGet(bucket.TenantName, bucket.Name, body.Key)
+	if err == ErrKvKeyNotFound {
+		errMsg := "Key " + body.Key + " does not exist"
+		serv.Warnf("GetKvEntry: Bucket " + bucket.Name + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	} else if err != nil {
+		serv.Errorf("GetKvEntry: Bucket " + bucket.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, entry)
+}
+
+func (kh KvHandler) GetKvHistory(c *gin.Context) {
+	var body models.KvKeySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	bucket, ok := getKvBucketForRequest(c, "GetKvHistory", body.BucketName)
+	if !ok {
+		return
+	}
+
+	entries, err := kh.S.kvHistory(bucket.TenantName, bucket.Name, body.Key)
+	if err == ErrKvKeyNotFound {
+		errMsg := "Key " + body.Key + " does not exist"
+		serv.Warnf("GetKvHistory: Bucket " + bucket.Name + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	} else if err != nil {
+		serv.Errorf("GetKvHistory: Bucket " + bucket.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, entries)
+}
+
+func (kh KvHandler) DeleteKvEntry(c *gin.Context) {
+	var body models.KvDeleteSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	if err := validateKvKey(body.Key); err != nil {
+		serv.Warnf("DeleteKvEntry: Bucket " + body.BucketName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	bucket, ok := getKvBucketForRequest(c, "DeleteKvEntry", body.BucketName)
+	if !ok {
+		return
+	}
+
+	revision, err := kh.S.kvDelete(bucket.TenantName, bucket.Name, body.Key, body.Purge)
+	if err != nil {
+		serv.Errorf("DeleteKvEntry: Bucket " + bucket.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err == nil {
+		serv.Noticef("Key " + body.Key + " of KV bucket " + bucket.Name + " has been deleted by user " + user.Username)
+	}
+	c.IndentedJSON(200, gin.H{"revision": revision})
+}
+
+func (s *Server) createKvBucketDirect(c *client, reply string, msg []byte) {
+	var ckr createKvBucketRequest
+	if err := json.Unmarshal(msg, &ckr); err != nil {
+		s.Errorf("createKvBucketDirect: failed creating KV bucket: %v", err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	body := models.CreateKvBucketSchema{
+		Name:        ckr.BucketName,
+		History:     ckr.History,
+		TTLSec:      ckr.TTLSec,
+		StorageType: ckr.StorageType,
+		Replicas:    ckr.Replicas,
+	}
+	err := validateCreateKvBucketSchema(&body)
+	if err == nil && body.TTLSec < 0 {
+		err = errors.New("ttl can not be negative")
+	}
+	if err != nil {
+		serv.Warnf("createKvBucketDirect: Bucket " + ckr.BucketName + ": " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	tenantName := tenantNameFromAccount(c.acc)
+	exist, _, err := IsKvBucketExist(body.Name, tenantName)
+	if err != nil {
+		serv.Errorf("createKvBucketDirect: Bucket " + body.Name + ": " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+	if exist {
+		errMsg := "KV bucket " + body.Name + " already exists"
+		serv.Warnf("createKvBucketDirect: " + errMsg)
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
+		return
+	}
+
+	newBucket := models.KvBucket{
+		ID:            primitive.NewObjectID(),
+		Name:          body.Name,
+		History:       body.History,
+		TTLSec:        body.TTLSec,
+		StorageType:   body.StorageType,
+		Replicas:      body.Replicas,
+		CreatedByUser: c.memphisInfo.username,
+		CreationDate:  time.Now(),
+		IsDeleted:     false,
+		TenantName:    tenantName,
+	}
+	created, err := s.createKvBucket(newBucket)
+	if err != nil {
+		serv.Errorf("createKvBucketDirect: Bucket " + body.Name + ": " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+	if !created {
+		errMsg := "KV bucket " + body.Name + " already exists"
+		serv.Warnf("createKvBucketDirect: " + errMsg)
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
+		return
+	}
+
+	serv.Noticef("KV bucket " + newBucket.Name + " has been created by user " + c.memphisInfo.username)
+	respondWithErr(s, c.acc, reply, nil)
+}
+
+func (s *Server) removeKvBucketDirect(c *client, reply string, msg []byte) {
+	var dkr destroyKvBucketRequest
+	if err := json.Unmarshal(msg, &dkr); err != nil {
+		s.Errorf("removeKvBucketDirect: " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	bucket, err := getKvBucketDirect(c, "removeKvBucketDirect", dkr.BucketName)
+	if err != nil {
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	err = s.removeKvBucket(bucket)
+	if err != nil {
+		serv.Errorf("removeKvBucketDirect: Bucket " + bucket.Name + ": " + err.Error())
+		respondWithErr(s, c.acc, reply, err)
+		return
+	}
+
+	serv.Noticef("KV bucket " + bucket.Name + " has been deleted by user " + c.memphisInfo.username)
+	respondWithErr(s, c.acc, reply, nil)
+}
+
+func getKvBucketDirect(c *client, handlerName, bucketName string) (models.KvBucket, error) {
+	bucketName = strings.ToLower(bucketName)
+	exist, bucket, err := IsKvBucketExist(bucketName, tenantNameFromAccount(c.acc))
+	if err != nil {
+		serv.Errorf(handlerName + ": Bucket " + bucketName + ": " + err.Error())
+		return models.KvBucket{}, err
+	}
+	if !exist {
+		errMsg := "KV bucket " + bucketName + " does not exist"
+		serv.Warnf(handlerName + ": " + errMsg)
+		return models.KvBucket{}, errors.New(errMsg)
+	}
+	return bucket, nil
+}
+
+// parseKvRequest responds with the error in case the request is invalid or its bucket does not exist
+func parseKvRequest(c *client, handlerName, reply string, msg []byte, validateKey func(string) error) (kvRequest, models.KvBucket, bool) {
+	var kr kvRequest
+	var resp kvResponse
+	if err := json.Unmarshal(msg, &kr); err != nil {
+		serv.Errorf(handlerName + ": " + err.Error())
+		respondWithRespErr(c.srv, c.acc, reply, err, &resp)
+		return kr, models.KvBucket{}, false
+	}
+	if err := validateKey(kr.Key); err != nil {
+		serv.Warnf(handlerName + ": Bucket " + kr.BucketName + ": " + err.Error())
+		respondWithRespErr(c.srv, c.acc, reply, err, &resp)
+		return kr, models.KvBucket{}, false
+	}
+	bucket, err := getKvBucketDirect(c, handlerName, kr.BucketName)
+	if err != nil {
+		respondWithRespErr(c.srv, c.acc, reply, err, &resp)
+		return kr, models.KvBucket{}, false
+	}
+	return kr, bucket, true
+}
+
+func kvEntriesResponse(entries []models.KvEntry) []kvEntryResponse {
+	entriesRes := []kvEntryResponse{}
+	for _, entry := range entries {
+		entriesRes = append(entriesRes, kvEntryResponse{
+			Key:       entry.Key,
+			Value:     []byte(entry.Value),
+			Revision:  entry.Revision,
+			Operation: entry.Operation,
+			Created:   entry.Created,
+		})
+	}
+	return entriesRes
+}
+
+func (s *Server) kvPutDirect(c *client, reply string, msg []byte) {
+	kr, bucket, ok := parseKvRequest(c, "kvPutDirect", reply, msg, validateKvKey)
+	if !ok {
+		return
+	}
+
+	var resp kvResponse
+	revision, err := s.kvPut(bucket.TenantName, bucket.Name, kr.Key, kr.Value, kr.ExpectedRevision)
+	if err != nil {
+		if !errors.Is(err, ErrKvWrongRevision) {
+			serv.Errorf("kvPutDirect: Bucket " + bucket.Name + ": " + err.Error())
+		}
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.Revision = revision
+	respondWithResp(s, c.acc, reply, &resp)
+}
+
+func (s *Server) kvGetDirect(c *client, reply string, msg []byte) {
+	kr, bucket, ok := parseKvRequest(c, "kvGetDirect", reply, msg, validateKvKey)
+	if !ok {
+		return
+	}
+
+	var resp kvResponse
+	entry, err := s.kvGet(bucket.TenantName, bucket.Name, kr.Key)
+	if err != nil {
+		if err != ErrKvKeyNotFound {
+			serv.Errorf("kvGetDirect: Bucket " + bucket.Name + ": " + err.Error())
+		}
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.Revision = entry.Revision
+	resp.Entries = kvEntriesResponse([]models.KvEntry{entry})
+	respondWithResp(s, c.acc, reply, &resp)
+}
+
+func (s *Server) kvHistoryDirect(c *client, reply string, msg []byte) {
+	kr, bucket, ok := parseKvRequest(c, "kvHistoryDirect", reply, msg, validateKvKey)
+	if !ok {
+		return
+	}
+
+	var resp kvResponse
+	entries, err := s.kvHistory(bucket.TenantName, bucket.Name, kr.Key)
+	if err != nil {
+		if err != ErrKvKeyNotFound {
+			serv.Errorf("kvHistoryDirect: Bucket " + bucket.Name + ": " + err.Error())
+		}
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.Revision = entries[len(entries)-1].Revision
+	resp.Entries = kvEntriesResponse(entries)
+	respondWithResp(s, c.acc, reply, &resp)
+}
+
+func (s *Server) kvDeleteDirect(c *client, reply string, msg []byte) {
+	kr, bucket, ok := parseKvRequest(c, "kvDeleteDirect", reply, msg, validateKvKey)
+	if !ok {
+		return
+	}
+
+	var resp kvResponse
+	revision, err := s.kvDelete(bucket.TenantName, bucket.Name, kr.Key, kr.Purge)
+	if err != nil {
+		serv.Errorf("kvDeleteDirect: Bucket " + bucket.Name + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.Revision = revision
+	respondWithResp(s, c.acc, reply, &resp)
+}
+
+// kvWatchDirect expects the client to already listen on the deliver subject before sending the request
+func (s *Server) kvWatchDirect(c *client, reply string, msg []byte) {
+	kr, bucket, ok := parseKvRequest(c, "kvWatchDirect", reply, msg, validateKvWatchKey)
+	if !ok {
+		return
+	}
+
+	var resp kvResponse
+	if kr.DeliverSubject == _EMPTY_ || !IsValidLiteralSubject(kr.DeliverSubject) {
+		err := errors.New("a valid deliver subject is required to watch a KV bucket")
+		serv.Warnf("kvWatchDirect: Bucket " + bucket.Name + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+
+	consumerName, err := s.kvWatch(bucket.TenantName, bucket.Name, kr.Key, kr.DeliverSubject, kr.IncludeHistory)
+	if err != nil {
+		serv.Errorf("kvWatchDirect: Bucket " + bucket.Name + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.ConsumerName = consumerName
+	respondWithResp(s, c.acc, reply, &resp)
+}
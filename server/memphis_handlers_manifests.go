@@ -0,0 +1,698 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"memphis-broker/analytics"
+	"memphis-broker/models"
+	"memphis-broker/utils"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	manifestActionCreate = "create"
+	manifestActionUpdate = "update"
+	manifestActionDelete = "delete"
+)
+
+type ManifestsHandler struct{ S *Server }
+
+type manifestState struct {
+	tenantName          string
+	stations            map[string]models.Station
+	stationTags         map[string][]models.CreateTag
+	schemas             map[string]models.Schema
+	schemaTags          map[string][]models.CreateTag
+	activeSchemaVersion map[string]models.SchemaVersion
+	schemaVersionsCount map[string]int
+	tags                map[string]models.Tag
+	ownedTags           map[string]bool
+	users               map[string]models.User
+	integrations        map[string]models.Integration
+}
+
+type manifestStep struct {
+	change models.ManifestChange
+	apply  func(user models.User) error
+}
+
+func parseManifest(content string) (models.Manifest, error) {
+	var manifest models.Manifest
+	// yaml is a superset of json so both formats are accepted here
+	err := yaml.Unmarshal([]byte(content), &manifest)
+	if err != nil {
+		return models.Manifest{}, errors.New("Manifest is invalid: " + err.Error())
+	}
+	return manifest, nil
+}
+
+// validateManifestEntry runs the same binding validations the REST handlers run on their request bodies
+func validateManifestEntry(kind, name string, entry interface{}) error {
+	if err := binding.Validator.ValidateStruct(entry); err != nil {
+		return errors.New(kind + " " + name + " is invalid: " + err.Error())
+	}
+	return nil
+}
+
+func loadManifestState(tenantName string) (manifestState, error) {
+	state := manifestState{
+		tenantName:          tenantName,
+		stations:            make(map[string]models.Station),
+		stationTags:         make(map[string][]models.CreateTag),
+		schemas:             make(map[string]models.Schema),
+		schemaTags:          make(map[string][]models.CreateTag),
+		activeSchemaVersion: make(map[string]models.SchemaVersion),
+		schemaVersionsCount: make(map[string]int),
+		tags:                make(map[string]models.Tag),
+		ownedTags:           make(map[string]bool),
+		users:               make(map[string]models.User),
+		integrations:        make(map[string]models.Integration),
+	}
+
+	var stations []models.Station
+	filter := bson.M{"tenant_name": tenantName, "$or": []interface{}{
+		bson.M{"is_deleted": bson.M{"$exists": false}},
+		bson.M{"is_deleted": false},
+	}}
+	cursor, err := stationsCollection.Find(context.TODO(), filter)
+	if err != nil {
+		return manifestState{}, err
+	}
+	if err = cursor.All(context.TODO(), &stations); err != nil {
+		return manifestState{}, err
+	}
+	stationNamesById := make(map[primitive.ObjectID]string)
+	for _, station := range stations {
+		state.stations[station.Name] = station
+		stationNamesById[station.ID] = station.Name
+	}
+
+	var schemas []models.Schema
+	cursor, err = schemasCollection.Find(context.TODO(), bson.M{"tenant_name": tenantName})
+	if err != nil {
+		return manifestState{}, err
+	}
+	if err = cursor.All(context.TODO(), &schemas); err != nil {
+		return manifestState{}, err
+	}
+	schemaNamesById := make(map[primitive.ObjectID]string)
+	schemaIds := []primitive.ObjectID{}
+	for _, schema := range schemas {
+		state.schemas[schema.Name] = schema
+		schemaNamesById[schema.ID] = schema.Name
+		schemaIds = append(schemaIds, schema.ID)
+	}
+
+	var versions []models.SchemaVersion
+	cursor, err = schemaVersionCollection.Find(context.TODO(), bson.M{"schema_id": bson.M{"$in": schemaIds}})
+	if err != nil {
+		return manifestState{}, err
+	}
+	if err = cursor.All(context.TODO(), &versions); err != nil {
+		return manifestState{}, err
+	}
+	for _, version := range versions {
+		schemaName, ok := schemaNamesById[version.SchemaId]
+		if !ok {
+			continue
+		}
+		state.schemaVersionsCount[schemaName]++
+		if version.Active {
+			state.activeSchemaVersion[schemaName] = version
+		}
+	}
+
+	var users []models.User
+	cursor, err = usersCollection.Find(context.TODO(), bson.M{"tenant_name": tenantName})
+	if err != nil {
+		return manifestState{}, err
+	}
+	if err = cursor.All(context.TODO(), &users); err != nil {
+		return manifestState{}, err
+	}
+	userIds := make(map[primitive.ObjectID]bool)
+	for _, user := range users {
+		state.users[user.Username] = user
+		userIds[user.ID] = true
+	}
+
+	// tags are shared by all the tenants, they are all loaded so existing names are not created twice
+	var tags []models.Tag
+	cursor, err = tagsCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return manifestState{}, err
+	}
+	if err = cursor.All(context.TODO(), &tags); err != nil {
+		return manifestState{}, err
+	}
+	state.addTags(tags, stationNamesById, schemaNamesById, userIds)
+
+	// integrations are configured for the whole broker so only the global tenant manages them
+	if tenantName != globalTenantName {
+		return state, nil
+	}
+	var integrations []models.Integration
+	cursor, err = integrationsCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return manifestState{}, err
+	}
+	if err = cursor.All(context.TODO(), &integrations); err != nil {
+		return manifestState{}, err
+	}
+	for _, integration := range integrations {
+		state.integrations[integration.Name] = integration
+	}
+
+	return state, nil
+}
+
+// addTags attaches the tags to the stations and schemas of the tenant, a tag is owned by the tenant
+// and can be pruned only when nothing outside of it is tagged with it
+func (state *manifestState) addTags(tags []models.Tag, stationNamesById, schemaNamesById map[primitive.ObjectID]string, userIds map[primitive.ObjectID]bool) {
+	for _, tag := range tags {
+		state.tags[tag.Name] = tag
+		tagToAttach := models.CreateTag{Name: tag.Name, Color: tag.Color}
+		owned := len(tag.KvBuckets) == 0
+		for _, id := range tag.Stations {
+			if name, ok := stationNamesById[id]; ok {
+				state.stationTags[name] = append(state.stationTags[name], tagToAttach)
+			} else {
+				owned = false
+			}
+		}
+		for _, id := range tag.Schemas {
+			if name, ok := schemaNamesById[id]; ok {
+				state.schemaTags[name] = append(state.schemaTags[name], tagToAttach)
+			} else {
+				owned = false
+			}
+		}
+		for _, id := range tag.Users {
+			if !userIds[id] {
+				owned = false
+			}
+		}
+		// a tag which is not attached to anything belongs to the global tenant
+		if len(tag.Stations) == 0 && len(tag.Schemas) == 0 && len(tag.Users) == 0 && state.tenantName != globalTenantName {
+			owned = false
+		}
+		if owned {
+			state.ownedTags[tag.Name] = true
+		}
+	}
+}
+
+// normalizeManifestStation fills the same defaults CreateStation applies so the desired state can be compared against the stored one
+func normalizeManifestStation(station models.CreateStationSchema) models.CreateStationSchema {
+	if station.RetentionType != "" && station.RetentionValue > 0 {
+		station.RetentionType = strings.ToLower(station.RetentionType)
+	} else {
+		station.RetentionType = "message_age_sec"
+		station.RetentionValue = 604800 // 1 week
+	}
+	if station.StorageType != "" {
+		station.StorageType = strings.ToLower(station.StorageType)
+	} else {
+		station.StorageType = "file"
+	}
+	if station.Replicas <= 0 {
+		station.Replicas = 1
+	}
+	if station.IdempotencyWindow <= 0 {
+		station.IdempotencyWindow = 120000 // default
+	} else if station.IdempotencyWindow < 100 {
+		station.IdempotencyWindow = 100 // minimum is 100 millis
+	}
+	station.SchemaName = strings.ToLower(station.SchemaName)
+	return station
+}
+
+func diffEntityTags(entityType, entityName string, desired, actual []models.CreateTag, prune bool) (models.UpdateTagsForEntitySchema, []string) {
+	update := models.UpdateTagsForEntitySchema{
+		TagsToAdd:    []models.CreateTag{},
+		TagsToRemove: []string{},
+		EntityType:   entityType,
+		EntityName:   entityName,
+	}
+	details := []string{}
+	actualTags := make(map[string]bool)
+	for _, tag := range actual {
+		actualTags[tag.Name] = true
+	}
+	desiredTags := make(map[string]bool)
+	for _, tag := range desired {
+		name := strings.ToLower(tag.Name)
+		desiredTags[name] = true
+		if !actualTags[name] {
+			update.TagsToAdd = append(update.TagsToAdd, models.CreateTag{Name: name, Color: tag.Color})
+			details = append(details, "add tag "+name)
+		}
+	}
+	if prune {
+		for _, tag := range actual {
+			if !desiredTags[tag.Name] {
+				update.TagsToRemove = append(update.TagsToRemove, tag.Name)
+				details = append(details, "remove tag "+tag.Name)
+			}
+		}
+	}
+	return update, details
+}
+
+func mapsDiffer[V comparable](desired, actual map[string]V) bool {
+	for key, value := range desired {
+		if actualValue, ok := actual[key]; !ok || actualValue != value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// planManifest compares the desired manifest against the current state and returns the ordered steps needed to reconcile them,
+// along with drift in fields that can not be changed after creation
+func (mh ManifestsHandler) planManifest(manifest models.Manifest, state manifestState, prune bool, callerUsername string) ([]manifestStep, []models.ManifestDrift, error) {
+	stationsHandler := StationsHandler{S: mh.S}
+	schemasHandler := SchemasHandler{S: mh.S}
+	tagsHandler := TagsHandler{S: mh.S}
+	userMgmtHandler := UserMgmtHandler{}
+	integrationsHandler := IntegrationsHandler{S: mh.S}
+
+	steps := []manifestStep{}
+	drift := []models.ManifestDrift{}
+
+	if len(manifest.Integrations) > 0 && state.tenantName != globalTenantName {
+		return nil, nil, errors.New("Integrations are shared by all the tenants and can only be managed by the global tenant")
+	}
+
+	referencedTags := make(map[string]bool)
+	for _, tag := range manifest.Tags {
+		name := strings.ToLower(tag.Name)
+		if err := validateManifestEntry("Tag", name, &tag); err != nil {
+			return nil, nil, err
+		}
+		referencedTags[name] = true
+		existing, ok := state.tags[name]
+		if !ok {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "tag", Name: name, Action: manifestActionCreate, Details: []string{}},
+				apply: func(user models.User) error {
+					_, err := tagsHandler.createNewTag(user, tag)
+					return err
+				},
+			})
+			continue
+		}
+		if tag.Color != "" && tag.Color != existing.Color {
+			drift = append(drift, models.ManifestDrift{Kind: "tag", Name: name, Field: "color", Desired: tag.Color, Actual: existing.Color})
+		}
+	}
+
+	desiredSchemas := make(map[string]bool)
+	plannedSchemaVersions := make(map[string]int)
+	for _, schema := range manifest.Schemas {
+		name := strings.ToLower(schema.Name)
+		if err := validateManifestEntry("Schema", name, &schema); err != nil {
+			return nil, nil, err
+		}
+		if desiredSchemas[name] {
+			return nil, nil, errors.New("Schema " + name + " is declared more than once")
+		}
+		desiredSchemas[name] = true
+		for _, tag := range schema.Tags {
+			referencedTags[strings.ToLower(tag.Name)] = true
+		}
+		existing, ok := state.schemas[name]
+		if !ok {
+			plannedSchemaVersions[name] = 1
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "schema", Name: name, Action: manifestActionCreate, Details: []string{}},
+				apply: func(user models.User) error {
+					_, err := schemasHandler.createNewSchema(user, schema)
+					return err
+				},
+			})
+			continue
+		}
+
+		active := state.activeSchemaVersion[name]
+		plannedSchemaVersions[name] = active.VersionNumber
+		if schema.Type != "" && strings.ToLower(schema.Type) != existing.Type {
+			drift = append(drift, models.ManifestDrift{Kind: "schema", Name: name, Field: "type", Desired: strings.ToLower(schema.Type), Actual: existing.Type})
+			continue
+		}
+
+		details := []string{}
+		var applies []func(user models.User) error
+		if schema.SchemaContent != active.SchemaContent || schema.MessageStructName != active.MessageStructName {
+			newVersion := state.schemaVersionsCount[name] + 1
+			plannedSchemaVersions[name] = newVersion
+			details = append(details, fmt.Sprintf("create and activate version %d", newVersion))
+			applies = append(applies,
+				func(user models.User) error {
+					_, err := schemasHandler.createNewVersion(user, models.CreateNewVersion{SchemaName: name, SchemaContent: schema.SchemaContent, MessageStructName: schema.MessageStructName})
+					return err
+				},
+				func(user models.User) error {
+					_, err := schemasHandler.rollBackVersion(user, models.RollBackVersion{SchemaName: name, VersionNumber: newVersion})
+					return err
+				},
+			)
+		}
+		tagsUpdate, tagDetails := diffEntityTags("schema", name, schema.Tags, state.schemaTags[name], prune)
+		if len(tagDetails) > 0 {
+			details = append(details, tagDetails...)
+			applies = append(applies, func(user models.User) error {
+				_, err := tagsHandler.updateTagsForEntity(user, tagsUpdate)
+				return err
+			})
+		}
+		if len(applies) > 0 {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "schema", Name: name, Action: manifestActionUpdate, Details: details},
+				apply:  chainSteps(applies...),
+			})
+		}
+	}
+
+	desiredStations := make(map[string]bool)
+	for _, station := range manifest.Stations {
+		stationName, err := StationNameFromStr(station.Name)
+		if err != nil {
+			return nil, nil, errors.New("Station " + station.Name + ": " + err.Error())
+		}
+		name := stationName.Ext()
+		if err := validateManifestEntry("Station", name, &station); err != nil {
+			return nil, nil, err
+		}
+		if desiredStations[name] {
+			return nil, nil, errors.New("Station " + name + " is declared more than once")
+		}
+		desiredStations[name] = true
+		for _, tag := range station.Tags {
+			referencedTags[strings.ToLower(tag.Name)] = true
+		}
+		existing, ok := state.stations[name]
+		if !ok {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "station", Name: name, Action: manifestActionCreate, Details: []string{}},
+				apply: func(user models.User) error {
+					_, err := stationsHandler.createStation(user, station)
+					return err
+				},
+			})
+			continue
+		}
+
+		desired := normalizeManifestStation(station)
+		if desired.RetentionType != existing.RetentionType {
+			drift = append(drift, models.ManifestDrift{Kind: "station", Name: name, Field: "retention_type", Desired: desired.RetentionType, Actual: existing.RetentionType})
+		}
+		if desired.RetentionValue != existing.RetentionValue {
+			drift = append(drift, models.ManifestDrift{Kind: "station", Name: name, Field: "retention_value", Desired: desired.RetentionValue, Actual: existing.RetentionValue})
+		}
+		if desired.StorageType != existing.StorageType {
+			drift = append(drift, models.ManifestDrift{Kind: "station", Name: name, Field: "storage_type", Desired: desired.StorageType, Actual: existing.StorageType})
+		}
+		if desired.Replicas != existing.Replicas {
+			drift = append(drift, models.ManifestDrift{Kind: "station", Name: name, Field: "replicas", Desired: desired.Replicas, Actual: existing.Replicas})
+		}
+		if desired.IdempotencyWindow != existing.IdempotencyWindow {
+			drift = append(drift, models.ManifestDrift{Kind: "station", Name: name, Field: "idempotency_window_in_ms", Desired: desired.IdempotencyWindow, Actual: existing.IdempotencyWindow})
+		}
+
+		details := []string{}
+		var applies []func(user models.User) error
+		if desired.SchemaName == "" && existing.Schema.SchemaName != "" {
+			details = append(details, "detach schema "+existing.Schema.SchemaName)
+			applies = append(applies, func(user models.User) error {
+				return stationsHandler.detachSchemaFromStation(user, models.RemoveSchemaFromStation{StationName: name})
+			})
+		} else if desired.SchemaName != "" {
+			version, known := plannedSchemaVersions[desired.SchemaName]
+			if !known {
+				version = state.activeSchemaVersion[desired.SchemaName].VersionNumber
+			}
+			if existing.Schema.SchemaName != desired.SchemaName || existing.Schema.VersionNumber != version {
+				details = append(details, fmt.Sprintf("attach schema %s version %d", desired.SchemaName, version))
+				applies = append(applies, func(user models.User) error {
+					_, err := stationsHandler.useSchema(user, models.UseSchema{StationNames: []string{name}, SchemaName: desired.SchemaName})
+					return err
+				})
+			}
+		}
+		if desired.DlsConfiguration != existing.DlsConfiguration {
+			details = append(details, "update dls configuration")
+			dlsUpdate := models.UpdateDlsConfigSchema{
+				StationName: name,
+				Poison:      desired.DlsConfiguration.Poison,
+				Schemaverse: desired.DlsConfiguration.Schemaverse,
+			}
+			applies = append(applies, func(user models.User) error {
+				return stationsHandler.updateDlsConfig(user, dlsUpdate)
+			})
+		}
+		tagsUpdate, tagDetails := diffEntityTags("station", name, station.Tags, state.stationTags[name], prune)
+		if len(tagDetails) > 0 {
+			details = append(details, tagDetails...)
+			applies = append(applies, func(user models.User) error {
+				_, err := tagsHandler.updateTagsForEntity(user, tagsUpdate)
+				return err
+			})
+		}
+		if len(applies) > 0 {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "station", Name: name, Action: manifestActionUpdate, Details: details},
+				apply:  chainSteps(applies...),
+			})
+		}
+	}
+
+	desiredUsers := make(map[string]bool)
+	for _, user := range manifest.Users {
+		username := strings.ToLower(user.Username)
+		if err := validateManifestEntry("User", username, &user); err != nil {
+			return nil, nil, err
+		}
+		desiredUsers[username] = true
+		existing, ok := state.users[username]
+		if !ok {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "user", Name: username, Action: manifestActionCreate, Details: []string{}},
+				apply: func(creator models.User) error {
+					_, err := userMgmtHandler.addUser(creator, user)
+					return err
+				},
+			})
+			continue
+		}
+		if strings.ToLower(user.UserType) != existing.UserType {
+			drift = append(drift, models.ManifestDrift{Kind: "user", Name: username, Field: "user_type", Desired: strings.ToLower(user.UserType), Actual: existing.UserType})
+		}
+	}
+
+	desiredIntegrations := make(map[string]bool)
+	for _, integration := range manifest.Integrations {
+		name := strings.ToLower(integration.Name)
+		if err := validateManifestEntry("Integration", name, &integration); err != nil {
+			return nil, nil, err
+		}
+		desiredIntegrations[name] = true
+		existing, ok := state.integrations[name]
+		if !ok {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "integration", Name: name, Action: manifestActionCreate, Details: []string{}},
+				apply: func(user models.User) error {
+					_, err := integrationsHandler.createIntegration(integration)
+					return err
+				},
+			})
+			continue
+		}
+		if mapsDiffer(integration.Keys, existing.Keys) || mapsDiffer(integration.Properties, existing.Properties) {
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "integration", Name: name, Action: manifestActionUpdate, Details: []string{"update keys and properties"}},
+				apply: func(user models.User) error {
+					_, err := integrationsHandler.updateIntegration(integration)
+					return err
+				},
+			})
+		}
+	}
+
+	if prune {
+		for _, name := range sortedKeys(state.stations) {
+			// stations created directly through nats are not managed by memphis
+			if desiredStations[name] || !state.stations[name].IsNative {
+				continue
+			}
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "station", Name: name, Action: manifestActionDelete, Details: []string{}},
+				apply: func(user models.User) error {
+					return stationsHandler.removeStations(user, models.RemoveStationSchema{StationNames: []string{name}})
+				},
+			})
+		}
+		for _, name := range sortedKeys(state.schemas) {
+			if desiredSchemas[name] {
+				continue
+			}
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "schema", Name: name, Action: manifestActionDelete, Details: []string{}},
+				apply: func(user models.User) error {
+					return schemasHandler.removeSchemas(user, models.RemoveSchema{SchemaNames: []string{name}})
+				},
+			})
+		}
+		for _, username := range sortedKeys(state.users) {
+			if desiredUsers[username] || username == callerUsername || state.users[username].UserType == "root" {
+				continue
+			}
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "user", Name: username, Action: manifestActionDelete, Details: []string{}},
+				apply: func(user models.User) error {
+					return userMgmtHandler.removeUser(user, models.RemoveUserSchema{Username: username})
+				},
+			})
+		}
+		for _, name := range sortedKeys(state.integrations) {
+			if desiredIntegrations[name] {
+				continue
+			}
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "integration", Name: name, Action: manifestActionDelete, Details: []string{}},
+				apply: func(user models.User) error {
+					return integrationsHandler.disconnectIntegration(models.DisconnectIntegrationSchema{Name: name})
+				},
+			})
+		}
+		for _, name := range sortedKeys(state.tags) {
+			if referencedTags[name] || !state.ownedTags[name] {
+				continue
+			}
+			tagId := state.tags[name].ID
+			steps = append(steps, manifestStep{
+				change: models.ManifestChange{Kind: "tag", Name: name, Action: manifestActionDelete, Details: []string{}},
+				apply: func(user models.User) error {
+					_, err := tagsCollection.DeleteOne(context.TODO(), bson.M{"_id": tagId})
+					return err
+				},
+			})
+		}
+	}
+
+	return steps, drift, nil
+}
+
+func chainSteps(applies ...func(user models.User) error) func(user models.User) error {
+	return func(user models.User) error {
+		for _, apply := range applies {
+			if err := apply(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (mh ManifestsHandler) ApplyManifest(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+
+	var body models.ApplyManifestSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	manifest, err := parseManifest(body.Manifest)
+	if err != nil {
+		serv.Warnf("ApplyManifest: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("ApplyManifest: " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+
+	state, err := loadManifestState(getUserTenantName(user))
+	if err != nil {
+		serv.Errorf("ApplyManifest: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	steps, drift, err := mh.planManifest(manifest, state, body.Prune, user.Username)
+	if err != nil {
+		serv.Warnf("ApplyManifest: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	response := models.ApplyManifestResponse{
+		DryRun:  body.DryRun,
+		Changes: []models.ManifestChange{},
+		Drift:   drift,
+	}
+	for _, step := range steps {
+		response.Changes = append(response.Changes, step.change)
+	}
+
+	if !body.DryRun {
+		for _, step := range steps {
+			err = step.apply(user)
+			if err != nil {
+				errMsg := "Failed applying " + step.change.Action + " of " + step.change.Kind + " " + step.change.Name + ": " + err.Error()
+				if isShowableError(err) {
+					serv.Warnf("ApplyManifest: " + errMsg)
+					c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+					return
+				}
+				serv.Errorf("ApplyManifest: " + errMsg)
+				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+				return
+			}
+		}
+		response.Applied = true
+		serv.Noticef(fmt.Sprintf("Manifest with %d changes has been applied by %s", len(steps), user.Username))
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analytics.SendEvent(user.Username, "user-apply-manifest")
+	}
+
+	c.IndentedJSON(200, response)
+}
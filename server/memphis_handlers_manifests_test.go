@@ -0,0 +1,164 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+
+//go:build !skip_js_tests
+// +build !skip_js_tests
+
+package server
+
+import (
+	"memphis-broker/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPlanManifest(t *testing.T) {
+	manifest, err := parseManifest(`
+stations:
+  - name: orders
+    retention_type: messages
+    retention_value: 10
+    schema_name: order
+  - name: payments
+schemas:
+  - name: order
+    type: json
+    schema_content: "{}"
+tags:
+  - name: prod
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing manifest: %v", err)
+	}
+
+	state := manifestState{
+		tenantName: globalTenantName,
+		stations: map[string]models.Station{
+			"orders": {Name: "orders", RetentionType: "message_age_sec", RetentionValue: 604800, StorageType: "file", Replicas: 1, IdempotencyWindow: 120000, IsNative: true},
+			"legacy": {Name: "legacy", IsNative: true},
+		},
+		schemas:             map[string]models.Schema{},
+		activeSchemaVersion: map[string]models.SchemaVersion{},
+		schemaVersionsCount: map[string]int{},
+		tags:                map[string]models.Tag{"prod": {Name: "prod"}},
+		ownedTags:           map[string]bool{"prod": true},
+		users:               map[string]models.User{"root": {Username: "root", UserType: "root"}},
+		integrations:        map[string]models.Integration{},
+	}
+
+	mh := ManifestsHandler{}
+	steps, drift, err := mh.planManifest(manifest, state, true, "root")
+	if err != nil {
+		t.Fatalf("Unexpected error planning manifest: %v", err)
+	}
+
+	expected := []models.ManifestChange{
+		{Kind: "schema", Name: "order", Action: manifestActionCreate},
+		{Kind: "station", Name: "orders", Action: manifestActionUpdate},
+		{Kind: "station", Name: "payments", Action: manifestActionCreate},
+		{Kind: "station", Name: "legacy", Action: manifestActionDelete},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d", len(expected), len(steps))
+	}
+	for i, step := range steps {
+		if step.change.Kind != expected[i].Kind || step.change.Name != expected[i].Name || step.change.Action != expected[i].Action {
+			t.Fatalf("Expected change %v, got %v", expected[i], step.change)
+		}
+	}
+
+	if len(drift) != 2 {
+		t.Fatalf("Expected retention drift only, got %v", drift)
+	}
+
+	_, _, err = mh.planManifest(models.Manifest{Stations: []models.CreateStationSchema{{Name: "a"}, {Name: "a"}}}, state, false, "root")
+	if err == nil {
+		t.Fatalf("Expected an error for a station declared twice")
+	}
+}
+
+func TestPlanManifestPruneTwoTenants(t *testing.T) {
+	newState := func(tenantName string) manifestState {
+		return manifestState{
+			tenantName:          tenantName,
+			stations:            map[string]models.Station{},
+			stationTags:         map[string][]models.CreateTag{},
+			schemas:             map[string]models.Schema{},
+			schemaTags:          map[string][]models.CreateTag{},
+			activeSchemaVersion: map[string]models.SchemaVersion{},
+			schemaVersionsCount: map[string]int{},
+			tags:                map[string]models.Tag{},
+			ownedTags:           map[string]bool{},
+			users:               map[string]models.User{},
+			integrations:        map[string]models.Integration{},
+		}
+	}
+	globalStation, acmeStation := primitive.NewObjectID(), primitive.NewObjectID()
+	tags := []models.Tag{
+		{ID: primitive.NewObjectID(), Name: "shared", Stations: []primitive.ObjectID{globalStation, acmeStation}},
+		{ID: primitive.NewObjectID(), Name: "global-only", Stations: []primitive.ObjectID{globalStation}},
+		{ID: primitive.NewObjectID(), Name: "acme-only", Stations: []primitive.ObjectID{acmeStation}},
+		{ID: primitive.NewObjectID(), Name: "unattached"},
+	}
+
+	acme := newState("acme")
+	acme.stations["orders"] = models.Station{ID: acmeStation, Name: "orders", IsNative: true}
+	acme.addTags(tags, map[primitive.ObjectID]string{acmeStation: "orders"}, map[primitive.ObjectID]string{}, map[primitive.ObjectID]bool{})
+	global := newState(globalTenantName)
+	global.stations["payments"] = models.Station{ID: globalStation, Name: "payments", IsNative: true}
+	global.integrations["slack"] = models.Integration{Name: "slack"}
+	global.addTags(tags, map[primitive.ObjectID]string{globalStation: "payments"}, map[primitive.ObjectID]string{}, map[primitive.ObjectID]bool{})
+
+	mh := ManifestsHandler{}
+	prunedNames := func(state manifestState) map[string]bool {
+		steps, _, err := mh.planManifest(models.Manifest{}, state, true, "root")
+		if err != nil {
+			t.Fatalf("Unexpected error planning manifest: %v", err)
+		}
+		pruned := make(map[string]bool)
+		for _, step := range steps {
+			if step.change.Action != manifestActionDelete {
+				t.Fatalf("Expected only deletions, got %v", step.change)
+			}
+			pruned[step.change.Kind+"/"+step.change.Name] = true
+		}
+		return pruned
+	}
+
+	acmePruned := prunedNames(acme)
+	expected := map[string]bool{"station/orders": true, "tag/acme-only": true}
+	if len(acmePruned) != len(expected) {
+		t.Fatalf("Expected tenant acme to prune %v, got %v", expected, acmePruned)
+	}
+	for name := range expected {
+		if !acmePruned[name] {
+			t.Fatalf("Expected tenant acme to prune %v, got %v", expected, acmePruned)
+		}
+	}
+
+	globalPruned := prunedNames(global)
+	expected = map[string]bool{"station/payments": true, "integration/slack": true, "tag/global-only": true, "tag/unattached": true}
+	if len(globalPruned) != len(expected) {
+		t.Fatalf("Expected the global tenant to prune %v, got %v", expected, globalPruned)
+	}
+	for name := range expected {
+		if !globalPruned[name] {
+			t.Fatalf("Expected the global tenant to prune %v, got %v", expected, globalPruned)
+		}
+	}
+
+	_, _, err := mh.planManifest(models.Manifest{Integrations: []models.CreateIntegrationSchema{{Name: "slack"}}}, acme, false, "root")
+	if err == nil {
+		t.Fatalf("Expected a tenant manifest with integrations to be rejected")
+	}
+}
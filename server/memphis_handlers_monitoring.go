@@ -190,13 +190,14 @@ func (mh MonitoringHandler) GetClusterInfo(c *gin.Context) {
 
 func (mh MonitoringHandler) GetMainOverviewData(c *gin.Context) {
 	stationsHandler := StationsHandler{S: mh.S}
-	stations, err := stationsHandler.GetAllStationsDetails()
+	tenantName := getTenantNameFromMiddleware(c)
+	stations, err := stationsHandler.GetAllStationsDetails(tenantName)
 	if err != nil {
 		serv.Errorf("GetMainOverviewData: GetAllStationsDetails: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
-	totalMessages, err := stationsHandler.GetTotalMessagesAcrossAllStations()
+	totalMessages, err := stationsHandler.GetTotalMessagesAcrossAllStations(tenantName)
 	if err != nil {
 		serv.Errorf("GetMainOverviewData: GetTotalMessagesAcrossAllStations: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -599,7 +600,7 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -628,7 +629,26 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
-	totalMessages, err := stationsHandler.GetTotalMessages(station.Name)
+	rateLimitViolations := getRateLimitViolations(station.TenantName, stationName.Intern())
+	partitions, err := mh.S.getStationPartitionsDetails(station)
+	if err != nil {
+		serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	mirror, err := mh.S.getMirrorDetails(station)
+	if err != nil {
+		serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	topology, err := mh.S.getStationTopology(station)
+	if err != nil {
+		serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	totalMessages, err := stationsHandler.GetTotalMessages(station.TenantName, station.Name)
 	if err != nil {
 		serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -640,6 +660,12 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
+	compression, err := stationsHandler.GetCompressionStats(station)
+	if err != nil {
+		serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
 
 	messagesToFetch := 1000
 	messages, err := stationsHandler.GetMessages(station, messagesToFetch)
@@ -687,7 +713,7 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 	// Check when the schema object in station is not empty, not optional for non native stations
 	if station.Schema != emptySchemaDetailsObj {
 		var schema models.Schema
-		err = schemasCollection.FindOne(context.TODO(), bson.M{"name": station.Schema.SchemaName}).Decode(&schema)
+		err = schemasCollection.FindOne(context.TODO(), bson.M{"name": station.Schema.SchemaName, "tenant_name": station.TenantName}).Decode(&schema)
 		if err != nil {
 			serv.Errorf("GetStationOverviewData: At station " + body.StationName + ": " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -712,6 +738,7 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 			"deleted_cgs":              deletedCgs,
 			"total_messages":           totalMessages,
 			"average_message_size":     avgMsgSize,
+			"compression":              compression,
 			"audit_logs":               auditLogs,
 			"messages":                 messages,
 			"poison_messages":          poisonMessages,
@@ -723,6 +750,12 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 			"idempotency_window_in_ms": station.IdempotencyWindow,
 			"dls_configuration":        station.DlsConfiguration,
 			"total_dls_messages":       totalDlsAmount,
+			"rate_limits":              station.RateLimits,
+			"rate_limit_violations":    rateLimitViolations,
+			"partitions_number":        station.PartitionsNumber,
+			"partitions":               partitions,
+			"mirror":                   mirror,
+			"topology":                 topology,
 		}
 	} else {
 		var emptyResponse struct{}
@@ -737,6 +770,7 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 				"deleted_cgs":              deletedCgs,
 				"total_messages":           totalMessages,
 				"average_message_size":     avgMsgSize,
+				"compression":              compression,
 				"audit_logs":               auditLogs,
 				"messages":                 messages,
 				"poison_messages":          poisonMessages,
@@ -748,6 +782,12 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 				"idempotency_window_in_ms": station.IdempotencyWindow,
 				"dls_configuration":        station.DlsConfiguration,
 				"total_dls_messages":       totalDlsAmount,
+				"rate_limits":              station.RateLimits,
+				"rate_limit_violations":    rateLimitViolations,
+				"partitions_number":        station.PartitionsNumber,
+				"partitions":               partitions,
+				"mirror":                   mirror,
+				"topology":                 topology,
 			}
 		} else {
 			response = gin.H{
@@ -759,6 +799,7 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 				"deleted_cgs":              deletedCgs,
 				"total_messages":           totalMessages,
 				"average_message_size":     avgMsgSize,
+				"compression":              compression,
 				"audit_logs":               auditLogs,
 				"messages":                 messages,
 				"poison_messages":          poisonMessages,
@@ -770,6 +811,12 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 				"idempotency_window_in_ms": station.IdempotencyWindow,
 				"dls_configuration":        station.DlsConfiguration,
 				"total_dls_messages":       totalDlsAmount,
+				"rate_limits":              station.RateLimits,
+				"rate_limit_violations":    rateLimitViolations,
+				"partitions_number":        station.PartitionsNumber,
+				"partitions":               partitions,
+				"mirror":                   mirror,
+				"topology":                 topology,
 			}
 		}
 	}
@@ -783,9 +830,26 @@ func (mh MonitoringHandler) GetStationOverviewData(c *gin.Context) {
 	c.IndentedJSON(200, response)
 }
 
+func (mh MonitoringHandler) GetRateLimitViolations(c *gin.Context) {
+	c.IndentedJSON(200, getRateLimitViolations(getTenantNameFromMiddleware(c), _EMPTY_))
+}
+
+// the system logs are the brokers' own, they are not split by tenant
+func validateGlobalTenantUser(c *gin.Context) bool {
+	if getTenantNameFromMiddleware(c) != globalTenantName {
+		serv.Warnf("System logs are available to the global tenant only")
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return false
+	}
+	return true
+}
+
 func (mh MonitoringHandler) GetSystemLogs(c *gin.Context) {
 	const amount = 100
 	const timeout = 500 * time.Millisecond
+	if !validateGlobalTenantUser(c) {
+		return
+	}
 
 	var request models.SystemLogsRequest
 	ok := utils.Validate(c, &request, false, nil)
@@ -828,6 +892,9 @@ func (mh MonitoringHandler) GetSystemLogs(c *gin.Context) {
 
 func (mh MonitoringHandler) DownloadSystemLogs(c *gin.Context) {
 	const timeout = 20 * time.Second
+	if !validateGlobalTenantUser(c) {
+		return
+	}
 	response, err := mh.S.GetSystemLogs(100, timeout, false, 0, _EMPTY_, true)
 	if err != nil {
 		serv.Errorf("DownloadSystemLogs: " + err.Error())
@@ -863,7 +930,7 @@ func (s *Server) GetSystemLogs(amount uint64,
 	durableName := "$memphis_fetch_logs_consumer_" + uid
 	var msgs []StoredMsg
 
-	streamInfo, err := s.memphisStreamInfo(syslogsStreamName)
+	streamInfo, err := s.memphisStreamInfo(globalTenantName, syslogsStreamName)
 	if err != nil {
 		return models.SystemLogsResponse{}, err
 	}
@@ -899,7 +966,7 @@ func (s *Server) GetSystemLogs(amount uint64,
 		cc.FilterSubject = filterSubject
 	}
 
-	err = s.memphisAddConsumer(syslogsStreamName, &cc)
+	err = s.memphisAddConsumer(globalTenantName, syslogsStreamName, &cc)
 	if err != nil {
 		return models.SystemLogsResponse{}, err
 	}
@@ -948,7 +1015,7 @@ func (s *Server) GetSystemLogs(amount uint64,
 cleanup:
 	timer.Stop()
 	s.unsubscribeOnGlobalAcc(sub)
-	err = s.memphisRemoveConsumer(syslogsStreamName, durableName)
+	err = s.memphisRemoveConsumer(globalTenantName, syslogsStreamName, durableName)
 	if err != nil {
 		return models.SystemLogsResponse{}, err
 	}
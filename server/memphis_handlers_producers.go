@@ -84,14 +84,14 @@ func (s *Server) createProducerDirectCommon(c *client, pName, pType, pConnection
 		return false, false, errors.New("memphis: " + errMsg)
 	}
 
-	exist, station, err := IsStationExist(pStationName)
+	exist, station, err := IsStationExist(pStationName, tenantNameFromAccount(c.acc))
 	if err != nil {
 		serv.Errorf("createProducerDirectCommon: Producer " + pName + " at station " + pStationName.external + ": " + err.Error())
 		return false, false, err
 	}
 	if !exist {
 		var created bool
-		station, created, err = CreateDefaultStation(s, pStationName, connection.CreatedByUser)
+		station, created, err = CreateDefaultStation(s, pStationName, connection.CreatedByUser, tenantNameFromAccount(c.acc))
 		if err != nil {
 			serv.Errorf("createProducerDirectCommon: creating default station error - producer " + pName + " at station " + pStationName.external + ": " + err.Error())
 			return false, false, err
@@ -127,6 +127,12 @@ func (s *Server) createProducerDirectCommon(c *client, pName, pType, pConnection
 		}
 	}
 
+	if isActiveMirror(station) {
+		errMsg := "Station " + pStationName.external + " is a mirror of station " + station.Mirror.StationName + " and can not be produced to until it is promoted"
+		serv.Warnf("createProducerDirectCommon: Producer " + pName + ": " + errMsg)
+		return false, false, errors.New("memphis: " + errMsg)
+	}
+
 	exist, _, err = IsProducerExist(name, station.ID)
 	if err != nil {
 		serv.Errorf("createProducerDirectCommon: Producer " + pName + " at station " + pStationName.external + ": " + err.Error())
@@ -206,12 +212,12 @@ func (s *Server) createProducerDirectCommon(c *client, pName, pType, pConnection
 func (s *Server) createProducerDirectV0(c *client, reply string, cpr createProducerRequestV0) {
 	sn, err := StationNameFromStr(cpr.StationName)
 	if err != nil {
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	_, _, err = s.createProducerDirectCommon(c, cpr.Name,
 		cpr.ProducerType, cpr.ConnectionId, sn)
-	respondWithErr(s, reply, err)
+	respondWithErr(s, c.acc, reply, err)
 }
 
 func (s *Server) createProducerDirect(c *client, reply string, msg []byte) {
@@ -222,7 +228,7 @@ func (s *Server) createProducerDirect(c *client, reply string, msg []byte) {
 		var cprV0 createProducerRequestV0
 		if err := json.Unmarshal(msg, &cprV0); err != nil {
 			s.Errorf("createProducerDirect: %v", err.Error())
-			respondWithRespErr(s, reply, err, &resp)
+			respondWithRespErr(s, c.acc, reply, err, &resp)
 			return
 		}
 		s.createProducerDirectV0(c, reply, cprV0)
@@ -232,31 +238,31 @@ func (s *Server) createProducerDirect(c *client, reply string, msg []byte) {
 	sn, err := StationNameFromStr(cpr.StationName)
 	if err != nil {
 		s.Errorf("createProducerDirect: Producer " + cpr.Name + " at station " + cpr.StationName + ": " + err.Error())
-		respondWithRespErr(s, reply, err, &resp)
+		respondWithRespErr(s, c.acc, reply, err, &resp)
 		return
 	}
 
 	clusterSendNotification, schemaVerseToDls, err := s.createProducerDirectCommon(c, cpr.Name, cpr.ProducerType, cpr.ConnectionId, sn)
 	if err != nil {
-		respondWithRespErr(s, reply, err, &resp)
+		respondWithRespErr(s, c.acc, reply, err, &resp)
 		return
 	}
 
 	resp.SchemaVerseToDls = schemaVerseToDls
 	resp.ClusterSendNotification = clusterSendNotification
-	schemaUpdate, err := getSchemaUpdateInitFromStation(sn)
+	schemaUpdate, err := getSchemaUpdateInitFromStation(sn, tenantNameFromAccount(c.acc))
 	if err == ErrNoSchema {
-		respondWithResp(s, reply, &resp)
+		respondWithResp(s, c.acc, reply, &resp)
 		return
 	}
 	if err != nil {
 		s.Errorf("createProducerDirect: Producer " + cpr.Name + " at station " + cpr.StationName + ": " + err.Error())
-		respondWithRespErr(s, reply, err, &resp)
+		respondWithRespErr(s, c.acc, reply, err, &resp)
 		return
 	}
 
 	resp.SchemaUpdate = *schemaUpdate
-	respondWithResp(s, reply, &resp)
+	respondWithResp(s, c.acc, reply, &resp)
 }
 
 func (ph ProducersHandler) GetAllProducers(c *gin.Context) {
@@ -267,7 +273,7 @@ func (ph ProducersHandler) GetAllProducers(c *gin.Context) {
 		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}},
 	})
 	if err != nil {
@@ -281,6 +287,9 @@ func (ph ProducersHandler) GetAllProducers(c *gin.Context) {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
+	for i := range producers {
+		producers[i].State = livenessState(producers[i].IsActive, producers[i].IsDeleted, producers[i].State)
+	}
 
 	if len(producers) == 0 {
 		c.IndentedJSON(200, []string{})
@@ -299,7 +308,7 @@ func (ph ProducersHandler) GetProducersByStation(station models.Station) ([]mode
 		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}},
 	})
 	if err != nil {
@@ -309,6 +318,9 @@ func (ph ProducersHandler) GetProducersByStation(station models.Station) ([]mode
 	if err = cursor.All(context.TODO(), &producers); err != nil {
 		return producers, producers, producers, err
 	}
+	for i := range producers {
+		producers[i].State = livenessState(producers[i].IsActive, producers[i].IsDeleted, producers[i].State)
+	}
 
 	var connectedProducers []models.ExtendedProducer
 	var disconnectedProducers []models.ExtendedProducer
@@ -362,7 +374,7 @@ func (ph ProducersHandler) GetAllProducersByStation(c *gin.Context) { // for the
 	}
 
 	stationName, err := StationNameFromStr(body.StationName)
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
 	if err != nil {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
@@ -380,7 +392,7 @@ func (ph ProducersHandler) GetAllProducersByStation(c *gin.Context) { // for the
 		bson.D{{"$unwind", bson.D{{"path", "$station"}, {"preserveNullAndEmptyArrays", true}}}},
 		bson.D{{"$lookup", bson.D{{"from", "connections"}, {"localField", "connection_id"}, {"foreignField", "_id"}, {"as", "connection"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$connection"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}}}},
+		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"type", 1}, {"connection_id", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"is_active", 1}, {"is_deleted", 1}, {"station_name", "$station.name"}, {"client_address", "$connection.client_address"}, {"state", "$connection.state"}, {"last_seen", "$connection.last_seen"}}}},
 		bson.D{{"$project", bson.D{{"station", 0}, {"connection", 0}}}},
 	})
 	if err != nil {
@@ -394,6 +406,9 @@ func (ph ProducersHandler) GetAllProducersByStation(c *gin.Context) { // for the
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
+	for i := range producers {
+		producers[i].State = livenessState(producers[i].IsActive, producers[i].IsDeleted, producers[i].State)
+	}
 
 	if len(producers) == 0 {
 		c.IndentedJSON(200, []string{})
@@ -406,22 +421,22 @@ func (s *Server) destroyProducerDirect(c *client, reply string, msg []byte) {
 	var dpr destroyProducerRequest
 	if err := json.Unmarshal(msg, &dpr); err != nil {
 		s.Errorf("destroyProducerDirect: %v", err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	stationName, err := StationNameFromStr(dpr.StationName)
 	if err != nil {
 		serv.Errorf("destroyProducerDirect: Producer " + dpr.ProducerName + "at station " + dpr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	name := strings.ToLower(dpr.ProducerName)
-	_, station, err := IsStationExist(stationName)
+	_, station, err := IsStationExist(stationName, tenantNameFromAccount(c.acc))
 	if err != nil {
 		serv.Errorf("destroyProducerDirect: Producer " + dpr.ProducerName + "at station " + dpr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -434,12 +449,12 @@ func (s *Server) destroyProducerDirect(c *client, reply string, msg []byte) {
 	if err == mongo.ErrNoDocuments {
 		errMsg := "Producer " + name + " at station " + dpr.StationName + " does not exist"
 		serv.Warnf("destroyProducerDirect: " + errMsg)
-		respondWithErr(s, reply, errors.New(errMsg))
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
 		return
 	}
 	if err != nil {
 		serv.Errorf("destroyProducerDirect: Producer " + name + "at station " + dpr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
@@ -465,7 +480,7 @@ func (s *Server) destroyProducerDirect(c *client, reply string, msg []byte) {
 		analytics.SendEvent(c.memphisInfo.username, "user-remove-producer")
 	}
 
-	respondWithErr(s, reply, nil)
+	respondWithErr(s, c.acc, reply, nil)
 }
 
 func (ph ProducersHandler) KillProducers(connectionId primitive.ObjectID) error {
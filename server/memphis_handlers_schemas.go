@@ -29,6 +29,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/graph-gophers/graphql-go"
+	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 	"go.mongodb.org/mongo-driver/bson"
@@ -49,13 +50,17 @@ var (
 	ErrNoSchema = errors.New("No schemas found")
 )
 
-func validateProtobufContent(schemaContent string) error {
+func parseProtobufContent(schemaContent string) ([]*desc.FileDescriptor, error) {
 	parser := protoparse.Parser{
 		Accessor: func(filename string) (io.ReadCloser, error) {
 			return ioutil.NopCloser(strings.NewReader(schemaContent)), nil
 		},
 	}
-	_, err := parser.ParseFiles("")
+	return parser.ParseFiles("")
+}
+
+func validateProtobufContent(schemaContent string) error {
+	_, err := parseProtobufContent(schemaContent)
 	if err != nil {
 		return errors.New("Your Proto file is invalid: " + err.Error())
 	}
@@ -199,8 +204,8 @@ func generateSchemaUpdateInit(schema models.Schema) (*models.ProducerSchemaUpdat
 	}, nil
 }
 
-func getSchemaUpdateInitFromStation(sn StationName) (*models.ProducerSchemaUpdateInit, error) {
-	schema, err := getSchemaByStationName(sn)
+func getSchemaUpdateInitFromStation(sn StationName, tenantName string) (*models.ProducerSchemaUpdateInit, error) {
+	schema, err := getSchemaByStationName(sn, tenantName)
 	if err != nil {
 		return nil, err
 	}
@@ -208,14 +213,19 @@ func getSchemaUpdateInitFromStation(sn StationName) (*models.ProducerSchemaUpdat
 	return generateSchemaUpdateInit(schema)
 }
 
-func (s *Server) updateStationProducersOfSchemaChange(sn StationName, schemaUpdate models.ProducerSchemaUpdate) {
+func (s *Server) updateStationProducersOfSchemaChange(tenantName string, sn StationName, schemaUpdate models.ProducerSchemaUpdate) {
 	subject := fmt.Sprintf(schemaUpdatesSubjectTemplate, sn.Intern())
 	msg, err := json.Marshal(schemaUpdate)
 	if err != nil {
 		s.Errorf("updateStationProducersOfSchemaChange: marshal failed at station " + sn.external)
 		return
 	}
-	s.sendInternalAccountMsg(s.GlobalAccount(), subject, msg)
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		s.Errorf("updateStationProducersOfSchemaChange: At station " + sn.external + ": " + err.Error())
+		return
+	}
+	s.sendInternalAccountMsg(acc, subject, msg)
 }
 
 func getSchemaVersionsBySchemaId(id primitive.ObjectID) ([]models.SchemaVersion, error) {
@@ -244,10 +254,10 @@ func getActiveVersionBySchemaId(schemaId primitive.ObjectID) (models.SchemaVersi
 	return schemaVersion, nil
 }
 
-func getSchemaByStationName(sn StationName) (models.Schema, error) {
+func getSchemaByStationName(sn StationName, tenantName string) (models.Schema, error) {
 	var schema models.Schema
 
-	exist, station, err := IsStationExist(sn)
+	exist, station, err := IsStationExist(sn, tenantName)
 	if err != nil {
 		serv.Errorf("getSchemaByStation: At station " + sn.external + ": " + err.Error())
 		return schema, err
@@ -261,7 +271,7 @@ func getSchemaByStationName(sn StationName) (models.Schema, error) {
 		return schema, ErrNoSchema
 	}
 
-	err = schemasCollection.FindOne(context.TODO(), bson.M{"name": station.Schema.SchemaName}).Decode(&schema)
+	err = schemasCollection.FindOne(context.TODO(), bson.M{"name": station.Schema.SchemaName, "tenant_name": station.TenantName}).Decode(&schema)
 	if err == mongo.ErrNoDocuments {
 		serv.Errorf("getSchemaByStation: Schema " + station.Schema.SchemaName + " does not exist")
 		return schema, ErrNoSchema
@@ -274,8 +284,8 @@ func getSchemaByStationName(sn StationName) (models.Schema, error) {
 	return schema, nil
 }
 
-func (sh SchemasHandler) GetSchemaByStationName(stationName StationName) (models.Schema, error) {
-	return getSchemaByStationName(stationName)
+func (sh SchemasHandler) GetSchemaByStationName(stationName StationName, tenantName string) (models.Schema, error) {
+	return getSchemaByStationName(stationName, tenantName)
 }
 
 func (sh SchemasHandler) GetSchemaVersion(stationVersion int, schemaId primitive.ObjectID) (models.SchemaVersion, error) {
@@ -317,11 +327,11 @@ func (sh SchemasHandler) getSchemaVersionsBySchemaId(schemaId primitive.ObjectID
 	return getSchemaVersionsBySchemaId(schemaId)
 }
 
-func (sh SchemasHandler) getUsingStationsByName(schemaName string) ([]string, error) {
+func (sh SchemasHandler) getUsingStationsByName(schemaName, tenantName string) ([]string, error) {
 	var stations []models.Station
 	cursor, err := stationsCollection.Aggregate(context.TODO(), mongo.Pipeline{
 		bson.D{{"$unwind", bson.D{{"path", "$schema"}, {"preserveNullAndEmptyArrays", true}}}},
-		bson.D{{"$match", bson.D{{"schema.name", schemaName}, {"is_deleted", false}}}},
+		bson.D{{"$match", bson.D{{"schema.name", schemaName}, {"tenant_name", tenantName}, {"is_deleted", false}}}},
 		bson.D{{"$project", bson.D{{"name", 1}}}},
 	})
 	if err != nil {
@@ -343,8 +353,8 @@ func (sh SchemasHandler) getUsingStationsByName(schemaName string) ([]string, er
 	return stationNames, nil
 }
 
-func (sh SchemasHandler) getStationsBySchemaCount(schemaName string) (int, error) {
-	filter := bson.M{"schema.name": schemaName, "is_deleted": false}
+func (sh SchemasHandler) getStationsBySchemaCount(schemaName, tenantName string) (int, error) {
+	filter := bson.M{"schema.name": schemaName, "tenant_name": tenantName, "is_deleted": false}
 	countStations, err := stationsCollection.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		return 0, err
@@ -373,7 +383,7 @@ func (sh SchemasHandler) getExtendedSchemaDetailsUpdateAvailable(schemaVersion i
 	}
 
 	var extedndedSchemaDetails models.ExtendedSchemaDetails
-	stations, err := sh.getUsingStationsByName(schema.Name)
+	stations, err := sh.getUsingStationsByName(schema.Name, schema.TenantName)
 	if err != nil {
 		return models.ExtendedSchemaDetails{}, err
 	}
@@ -403,7 +413,7 @@ func (sh SchemasHandler) getExtendedSchemaDetails(schema models.Schema) (models.
 	}
 
 	var extedndedSchemaDetails models.ExtendedSchemaDetails
-	stations, err := sh.getUsingStationsByName(schema.Name)
+	stations, err := sh.getUsingStationsByName(schema.Name, schema.TenantName)
 	if err != nil {
 		return models.ExtendedSchemaDetails{}, err
 	}
@@ -426,9 +436,9 @@ func (sh SchemasHandler) getExtendedSchemaDetails(schema models.Schema) (models.
 	return extedndedSchemaDetails, nil
 }
 
-func (sh SchemasHandler) getSchemaDetailsBySchemaName(schemaName string) (models.ExtendedSchemaDetails, error) {
+func (sh SchemasHandler) getSchemaDetailsBySchemaName(schemaName, tenantName string) (models.ExtendedSchemaDetails, error) {
 	var schema models.Schema
-	err := schemasCollection.FindOne(context.TODO(), bson.M{"name": schemaName}).Decode(&schema)
+	err := schemasCollection.FindOne(context.TODO(), bson.M{"name": schemaName, "tenant_name": tenantName}).Decode(&schema)
 	if err != nil {
 		return models.ExtendedSchemaDetails{}, err
 	}
@@ -441,9 +451,10 @@ func (sh SchemasHandler) getSchemaDetailsBySchemaName(schemaName string) (models
 	return extedndedSchemaDetails, nil
 }
 
-func (sh SchemasHandler) GetAllSchemasDetails() ([]models.ExtendedSchema, error) {
+func (sh SchemasHandler) GetAllSchemasDetails(tenantName string) ([]models.ExtendedSchema, error) {
 	var schemas []models.ExtendedSchema
 	cursor, err := schemasCollection.Aggregate(context.TODO(), mongo.Pipeline{
+		bson.D{{"$match", bson.D{{"tenant_name", tenantName}}}},
 		bson.D{{"$lookup", bson.D{{"from", "schema_versions"}, {"localField", "_id"}, {"foreignField", "schema_id"}, {"as", "extendedSchema"}}}},
 		bson.D{{"$unwind", bson.D{{"path", "$extendedSchema"}, {"preserveNullAndEmptyArrays", true}}}},
 		bson.D{{"$match", bson.D{{"extendedSchema.version_number", 1}}}},
@@ -466,7 +477,7 @@ func (sh SchemasHandler) GetAllSchemasDetails() ([]models.ExtendedSchema, error)
 
 	var extedndedSchemasDetails []models.ExtendedSchema
 	for i, schema := range schemas {
-		stations, err := sh.getStationsBySchemaCount(schema.Name)
+		stations, err := sh.getStationsBySchemaCount(schema.Name, tenantName)
 		if err != nil {
 			return []models.ExtendedSchema{}, err
 		}
@@ -523,45 +534,51 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateNewSchema: Schema " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	newSchema, err := sh.createNewSchema(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, newSchema)
+}
+
+func (sh SchemasHandler) createNewSchema(user models.User, body models.CreateNewSchema) (models.Schema, error) {
 	schemaName := strings.ToLower(body.Name)
 	err := validateSchemaName(schemaName)
 	if err != nil {
 		serv.Warnf("CreateNewSchema: " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return models.Schema{}, newShowableError(err.Error())
 	}
-	exist, _, err := IsSchemaExist(schemaName)
+	tenantName := getUserTenantName(user)
+	exist, _, err := IsSchemaExist(schemaName, tenantName)
 	if err != nil {
 		serv.Errorf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server Error"})
-		return
+		return models.Schema{}, err
 	}
 	if exist {
 		errMsg := "Schema with the name " + schemaName + " already exists"
 		serv.Warnf("CreateNewSchema: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
-	}
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-		return
+		return models.Schema{}, newShowableError(errMsg)
 	}
 	schemaType := strings.ToLower(body.Type)
 	err = validateSchemaType(schemaType)
 	if err != nil {
 		serv.Warnf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return models.Schema{}, newShowableError(err.Error())
 	}
 	messageStructName := body.MessageStructName
 	if schemaType == "protobuf" {
 		err := validateMessageStructName(messageStructName)
 		if err != nil {
 			serv.Warnf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return models.Schema{}, newShowableError(err.Error())
 		}
 	}
 
@@ -569,8 +586,7 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 	err = validateSchemaContent(schemaContent, schemaType)
 	if err != nil {
 		serv.Warnf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return models.Schema{}, newShowableErrorWithStatus(SCHEMA_VALIDATION_ERROR_STATUS_CODE, err.Error())
 	}
 	schemaVersionNumber := 1
 	descriptor := ""
@@ -578,18 +594,18 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 		descriptor, err = generateSchemaDescriptor(schemaName, schemaVersionNumber, schemaContent, schemaType)
 		if err != nil {
 			serv.Warnf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return models.Schema{}, newShowableError(err.Error())
 		}
 	}
 
 	newSchema := models.Schema{
-		ID:   primitive.NewObjectID(),
-		Name: schemaName,
-		Type: schemaType,
+		ID:         primitive.NewObjectID(),
+		Name:       schemaName,
+		Type:       schemaType,
+		TenantName: tenantName,
 	}
 
-	filter := bson.M{"name": newSchema.Name}
+	filter := bson.M{"name": newSchema.Name, "tenant_name": newSchema.TenantName}
 	update := bson.M{
 		"$setOnInsert": bson.M{
 			"_id":  newSchema.ID,
@@ -613,45 +629,40 @@ func (sh SchemasHandler) CreateNewSchema(c *gin.Context) {
 	updateResults, err := schemasCollection.UpdateOne(context.TODO(), filter, update, opts)
 	if err != nil {
 		serv.Errorf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.Schema{}, err
 	}
 	if updateResults.MatchedCount == 0 {
 		_, err = schemaVersionCollection.InsertOne(context.TODO(), newSchemaVersion)
 		if err != nil {
 			serv.Errorf("CreateNewSchema: Schema " + schemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return models.Schema{}, err
 		}
 		message := "Schema " + schemaName + " has been created by " + user.Username
 		serv.Noticef(message)
 	} else {
 		errMsg := "Schema with the name " + schemaName + " already exists"
 		serv.Warnf("CreateNewSchema: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return models.Schema{}, newShowableError(errMsg)
 	}
 
 	if len(body.Tags) > 0 {
 		err = AddTagsToEntity(body.Tags, "schema", newSchema.ID)
 		if err != nil {
 			serv.Errorf("CreateNewSchema: Failed creating tag at schema " + schemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return models.Schema{}, err
 		}
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
 		analytics.SendEvent(user.Username, "user-create-schema")
 	}
 
-	c.IndentedJSON(200, newSchema)
+	return newSchema, nil
 }
 
 func (sh SchemasHandler) GetAllSchemas(c *gin.Context) {
-	schemas, err := sh.GetAllSchemasDetails()
+	schemas, err := sh.GetAllSchemasDetails(getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetAllSchemas: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -674,7 +685,7 @@ func (sh SchemasHandler) GetSchemaDetails(c *gin.Context) {
 		return
 	}
 	schemaName := strings.ToLower(body.SchemaName)
-	exist, _, err := IsSchemaExist(schemaName)
+	exist, _, err := IsSchemaExist(schemaName, getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetSchemaDetails: Schema " + body.SchemaName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -687,7 +698,7 @@ func (sh SchemasHandler) GetSchemaDetails(c *gin.Context) {
 		return
 	}
 
-	schemaDetails, err := sh.getSchemaDetailsBySchemaName(schemaName)
+	schemaDetails, err := sh.getSchemaDetailsBySchemaName(schemaName, getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetSchemaDetails: Schema " + schemaName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -703,9 +714,9 @@ func (sh SchemasHandler) GetSchemaDetails(c *gin.Context) {
 	c.IndentedJSON(200, schemaDetails)
 }
 
-func deleteSchemaFromStations(s *Server, schemaName string) error {
+func deleteSchemaFromStations(s *Server, schemaName, tenantName string) error {
 	var stations []models.Station
-	cursor, err := stationsCollection.Find(nil, bson.M{"schema.name": schemaName})
+	cursor, err := stationsCollection.Find(nil, bson.M{"schema.name": schemaName, "tenant_name": tenantName})
 	if err != nil {
 		return err
 	}
@@ -719,7 +730,7 @@ func deleteSchemaFromStations(s *Server, schemaName string) error {
 		if err != nil {
 			return err
 		}
-		exist, station, err := IsStationExist(sn)
+		exist, station, err := IsStationExist(sn, tenantName)
 		if err != nil {
 			s.Errorf("deleteSchemaFromStations: Schema " + schemaName + " at station " + station.Name + ": " + err.Error())
 			return err
@@ -729,12 +740,13 @@ func deleteSchemaFromStations(s *Server, schemaName string) error {
 			continue
 		}
 
-		removeSchemaFromStation(s, sn, false)
+		removeSchemaFromStation(s, tenantName, sn, false)
 	}
 
 	_, err = stationsCollection.UpdateMany(context.TODO(),
 		bson.M{
 			"schema.name": schemaName,
+			"tenant_name": tenantName,
 		},
 		bson.M{"$set": bson.M{"schema": bson.M{}}},
 	)
@@ -756,23 +768,38 @@ func (sh SchemasHandler) RemoveSchema(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveSchema: " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	err = sh.removeSchemas(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (sh SchemasHandler) removeSchemas(user models.User, body models.RemoveSchema) error {
+	tenantName := getUserTenantName(user)
 	var schemaIds []primitive.ObjectID
 
 	for _, name := range body.SchemaNames {
 		schemaName := strings.ToLower(name)
-		exist, schema, err := IsSchemaExist(schemaName)
+		exist, schema, err := IsSchemaExist(schemaName, tenantName)
 		if err != nil {
 			serv.Errorf("RemoveSchema: Schema " + schemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return err
 		}
 		if exist {
 			DeleteTagsFromSchema(schema.ID)
-			err := deleteSchemaFromStations(sh.S, schema.Name)
+			err := deleteSchemaFromStations(sh.S, schema.Name, schema.TenantName)
 			if err != nil {
 				serv.Errorf("RemoveSchema: Schema " + schemaName + ": " + err.Error())
-				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-				return
+				return err
 			}
 
 			schemaIds = append(schemaIds, schema.ID)
@@ -783,8 +810,7 @@ func (sh SchemasHandler) RemoveSchema(c *gin.Context) {
 		err := sh.findAndDeleteSchema(schemaIds)
 		if err != nil {
 			serv.Errorf("RemoveSchema: " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return err
 		}
 		for _, name := range body.SchemaNames {
 			serv.Noticef("Schema " + name + " has been deleted")
@@ -793,11 +819,10 @@ func (sh SchemasHandler) RemoveSchema(c *gin.Context) {
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
 		analytics.SendEvent(user.Username, "user-remove-schema")
 	}
 
-	c.IndentedJSON(200, gin.H{})
+	return nil
 }
 
 func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
@@ -806,26 +831,33 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	extedndedSchemaDetails, err := sh.createNewVersion(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, extedndedSchemaDetails)
+}
 
+func (sh SchemasHandler) createNewVersion(user models.User, body models.CreateNewVersion) (models.ExtendedSchemaDetails, error) {
+	tenantName := getUserTenantName(user)
 	schemaName := strings.ToLower(body.SchemaName)
-	exist, schema, err := IsSchemaExist(schemaName)
+	exist, schema, err := IsSchemaExist(schemaName, tenantName)
 	if err != nil {
 		serv.Errorf("CreateNewVersion: Schema" + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server Error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 	if !exist {
 		errMsg := "Schema " + body.SchemaName + " does not exist"
 		serv.Warnf("CreateNewVersion: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
-	}
-
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-		return
+		return models.ExtendedSchemaDetails{}, newShowableError(errMsg)
 	}
 
 	messageStructName := body.MessageStructName
@@ -833,23 +865,20 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 		err := validateMessageStructName(messageStructName)
 		if err != nil {
 			serv.Errorf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return models.ExtendedSchemaDetails{}, newShowableError(err.Error())
 		}
 	}
 	schemaContent := body.SchemaContent
 	err = validateSchemaContent(schemaContent, schema.Type)
 	if err != nil {
 		serv.Warnf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return models.ExtendedSchemaDetails{}, newShowableErrorWithStatus(SCHEMA_VALIDATION_ERROR_STATUS_CODE, err.Error())
 	}
 
 	countVersions, err := sh.getVersionsCount(schema.ID)
 	if err != nil {
 		serv.Errorf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 
 	versionNumber := countVersions + 1
@@ -858,8 +887,7 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 		descriptor, err = generateSchemaDescriptor(schemaName, versionNumber, schemaContent, schema.Type)
 		if err != nil {
 			serv.Warnf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(SCHEMA_VALIDATION_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return models.ExtendedSchemaDetails{}, newShowableErrorWithStatus(SCHEMA_VALIDATION_ERROR_STATUS_CODE, err.Error())
 		}
 	}
 	newSchemaVersion := models.SchemaVersion{
@@ -891,31 +919,27 @@ func (sh SchemasHandler) CreateNewVersion(c *gin.Context) {
 	updateResults, err := schemaVersionCollection.UpdateOne(context.TODO(), filter, update, opts)
 	if err != nil {
 		serv.Errorf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 	if updateResults.MatchedCount == 0 {
 		message := "Schema Version " + strconv.Itoa(newSchemaVersion.VersionNumber) + " has been created by " + user.Username
 		serv.Noticef(message)
 	} else {
 		serv.Warnf("CreateNewVersion: Schema " + body.SchemaName + ": Version " + strconv.Itoa(newSchemaVersion.VersionNumber) + " already exists")
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Version already exists"})
-		return
+		return models.ExtendedSchemaDetails{}, newShowableError("Version already exists")
 	}
 	extedndedSchemaDetails, err := sh.getExtendedSchemaDetails(schema)
 	if err != nil {
 		serv.Errorf("CreateNewVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
 		analytics.SendEvent(user.Username, "user-create-new-schema-version")
 	}
 
-	c.IndentedJSON(200, extedndedSchemaDetails)
+	return extedndedSchemaDetails, nil
 }
 
 func (sh SchemasHandler) RollBackVersion(c *gin.Context) {
@@ -924,22 +948,36 @@ func (sh SchemasHandler) RollBackVersion(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RollBackVersion: Schema " + body.SchemaName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	extedndedSchemaDetails, err := sh.rollBackVersion(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, extedndedSchemaDetails)
+}
 
+func (sh SchemasHandler) rollBackVersion(user models.User, body models.RollBackVersion) (models.ExtendedSchemaDetails, error) {
+	tenantName := getUserTenantName(user)
 	var extedndedSchemaDetails models.ExtendedSchemaDetails
 
 	schemaName := strings.ToLower(body.SchemaName)
 
-	exist, schema, err := IsSchemaExist(schemaName)
+	exist, schema, err := IsSchemaExist(schemaName, tenantName)
 	if err != nil {
 		serv.Errorf("RollBackVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server Error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 	if !exist {
 		errMsg := "Schema " + body.SchemaName + " does not exist"
 		serv.Warnf("RollBackVersion: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return models.ExtendedSchemaDetails{}, newShowableError(errMsg)
 	}
 
 	schemaVersion := body.VersionNumber
@@ -947,44 +985,38 @@ func (sh SchemasHandler) RollBackVersion(c *gin.Context) {
 
 	if err != nil {
 		serv.Errorf("RollBackVersion: Schema " + body.SchemaName + " version " + strconv.Itoa(schemaVersion) + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 	if !exist {
 		errMsg := "Schema " + body.SchemaName + " version " + strconv.Itoa(schemaVersion) + " does not exist"
 		serv.Warnf("RollBackVersion: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return models.ExtendedSchemaDetails{}, newShowableError(errMsg)
 	}
 
 	countVersions, err := sh.getVersionsCount(schema.ID)
 	if err != nil {
 		serv.Errorf("RollBackVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 	if countVersions > 1 {
 		err = sh.updateActiveVersion(schema.ID, body.VersionNumber)
 		if err != nil {
 			serv.Errorf("RollBackVersion: Schema " + body.SchemaName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-			return
+			return models.ExtendedSchemaDetails{}, newShowableErrorWithStatus(500, err.Error())
 		}
 	}
 	extedndedSchemaDetails, err = sh.getExtendedSchemaDetails(schema)
 	if err != nil {
 		serv.Errorf("RollBackVersion: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.ExtendedSchemaDetails{}, err
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
 		analytics.SendEvent(user.Username, "user-rollback-schema-version")
 	}
 
-	c.IndentedJSON(200, extedndedSchemaDetails)
+	return extedndedSchemaDetails, nil
 }
 
 func (sh SchemasHandler) ValidateSchema(c *gin.Context) {
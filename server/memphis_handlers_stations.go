@@ -100,6 +100,14 @@ func validateStorageType(storageType string) error {
 	return nil
 }
 
+func validateCompression(compression string) error {
+	if compression != "none" && compression != "s2" && compression != "zstd" {
+		return errors.New("compression type can be one of the following none/s2/zstd")
+	}
+
+	return nil
+}
+
 func validateReplicas(replicas int) error {
 	if replicas > 5 {
 		return errors.New("max replicas in a cluster is 5")
@@ -127,17 +135,38 @@ func removeStationResources(s *Server, station models.Station, shouldDeleteStrea
 	}
 
 	if shouldDeleteStream {
-		err = s.RemoveStream(stationName.Intern())
+		err = s.RemoveStream(station.TenantName, stationName.Intern())
 		if err != nil {
 			return err
 		}
 	}
 
-	err = s.RemoveStream(fmt.Sprintf(dlsStreamName, stationName.Intern()))
+	err = s.RemoveStream(station.TenantName, fmt.Sprintf(dlsStreamName, stationName.Intern()))
+	if err != nil {
+		return err
+	}
+
+	err = s.removeObjectsStream(station.TenantName, stationName)
 	if err != nil {
 		return err
 	}
 
+	if rateLimitsEnabled(station.RateLimits) {
+		setStationRateLimits(station.TenantName, stationName, models.RateLimits{})
+		err = broadcastRateLimitsUpdate("station_rate_limits", models.RateLimitsUpdate{TenantName: station.TenantName, StationName: stationName.Ext()})
+		if err != nil {
+			return err
+		}
+	}
+
+	if isPartitioned(station.PartitionsNumber) {
+		setStationPartitions(station.TenantName, stationName, 0)
+		err = broadcastStationPartitionsUpdate(models.PartitionsUpdate{TenantName: station.TenantName, StationName: stationName.Ext()})
+		if err != nil {
+			return err
+		}
+	}
+
 	DeleteTagsFromStation(station.ID)
 
 	_, err = producersCollection.UpdateMany(context.TODO(),
@@ -168,7 +197,7 @@ func (s *Server) createStationDirect(c *client, reply string, msg []byte) {
 	var csr createStationRequest
 	if err := json.Unmarshal(msg, &csr); err != nil {
 		s.Errorf("createStationDirect: failed creating station: %v", err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	s.createStationDirectIntern(c, reply, &csr, true)
@@ -189,7 +218,8 @@ func (s *Server) createStationDirectIntern(c *client,
 		return
 	}
 
-	exist, _, err := IsStationExist(stationName)
+	tenantName := tenantNameFromAccount(c.acc)
+	exist, _, err := IsStationExist(stationName, tenantName)
 	if err != nil {
 		serv.Errorf("createStationDirect: Station " + csr.StationName + ": " + err.Error())
 		jsApiResp.Error = NewJSStreamCreateError(err)
@@ -209,7 +239,7 @@ func (s *Server) createStationDirectIntern(c *client,
 	var schemaDetails models.SchemaDetails
 	if schemaName != "" {
 		schemaName = strings.ToLower(csr.SchemaName)
-		exist, schema, err := IsSchemaExist(schemaName)
+		exist, schema, err := IsSchemaExist(schemaName, tenantName)
 		if err != nil {
 			serv.Errorf("createStationDirect: Station " + csr.StationName + ": " + err.Error())
 			jsApiResp.Error = NewJSStreamCreateError(err)
@@ -286,6 +316,49 @@ func (s *Server) createStationDirectIntern(c *client,
 		return
 	}
 
+	err = validatePartitionsNumber(csr.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("createStationDirect: " + err.Error())
+		jsApiResp.Error = NewJSStreamCreateError(err)
+		respondWithErrOrJsApiResp(!isNative, c, c.acc, _EMPTY_, reply, _EMPTY_, jsApiResp, err)
+		return
+	}
+
+	mirror, localOrigin, origin, err := validateMirror(stationName, tenantName, csr.Mirror, csr.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("createStationDirect: " + err.Error())
+		jsApiResp.Error = NewJSStreamCreateError(err)
+		respondWithErrOrJsApiResp(!isNative, c, c.acc, _EMPTY_, reply, _EMPTY_, jsApiResp, err)
+		return
+	}
+
+	sources, err := validateSources(stationName, tenantName, csr.Sources, csr.Mirror, csr.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("createStationDirect: " + err.Error())
+		jsApiResp.Error = NewJSStreamCreateError(err)
+		respondWithErrOrJsApiResp(!isNative, c, c.acc, _EMPTY_, reply, _EMPTY_, jsApiResp, err)
+		return
+	}
+
+	if csr.Compacted {
+		err = validateCompaction(csr.MaxMsgsPerKey, csr.PartitionsNumber, csr.Mirror, csr.Sources)
+		if err != nil {
+			serv.Warnf("createStationDirect: " + err.Error())
+			jsApiResp.Error = NewJSStreamCreateError(err)
+			respondWithErrOrJsApiResp(!isNative, c, c.acc, _EMPTY_, reply, _EMPTY_, jsApiResp, err)
+			return
+		}
+		if csr.MaxMsgsPerKey == 0 {
+			csr.MaxMsgsPerKey = defaultMaxMsgsPerKey
+		}
+	} else {
+		csr.MaxMsgsPerKey = 0
+	}
+	if localOrigin {
+		schemaDetails = origin.Schema
+		csr.DlsConfiguration = origin.DlsConfiguration
+	}
+
 	if csr.IdempotencyWindow <= 0 {
 		csr.IdempotencyWindow = 120000 // default
 	} else if csr.IdempotencyWindow < 100 {
@@ -296,6 +369,7 @@ func (s *Server) createStationDirectIntern(c *client,
 		ID:                primitive.NewObjectID(),
 		Name:              stationName.Ext(),
 		CreatedByUser:     c.memphisInfo.username,
+		TenantName:        tenantName,
 		CreationDate:      time.Now(),
 		IsDeleted:         false,
 		RetentionType:     retentionType,
@@ -310,6 +384,11 @@ func (s *Server) createStationDirectIntern(c *client,
 		IdempotencyWindow: csr.IdempotencyWindow,
 		IsNative:          isNative,
 		DlsConfiguration:  csr.DlsConfiguration,
+		PartitionsNumber:  csr.PartitionsNumber,
+		Mirror:            mirror,
+		Sources:           sources,
+		Compacted:         csr.Compacted,
+		MaxMsgsPerKey:     csr.MaxMsgsPerKey,
 	}
 
 	if shouldCreateStream {
@@ -317,12 +396,12 @@ func (s *Server) createStationDirectIntern(c *client,
 		if err != nil {
 			if IsNatsErr(err, JSInsufficientResourcesErr) {
 				serv.Warnf("CreateStation: Station " + stationName.Ext() + ": Station can not be created, probably since replicas count is larger than the cluster size")
-				respondWithErr(s, reply, errors.New("Station can not be created, probably since replicas count is larger than the cluster size"))
+				respondWithErr(s, c.acc, reply, errors.New("Station can not be created, probably since replicas count is larger than the cluster size"))
 				return
 			}
 
 			serv.Errorf("createStationDirect: Station " + csr.StationName + ": " + err.Error())
-			respondWithErr(s, reply, err)
+			respondWithErr(s, c.acc, reply, err)
 			return
 		}
 	}
@@ -330,16 +409,26 @@ func (s *Server) createStationDirectIntern(c *client,
 	err = s.CreateDlsStream(stationName, newStation)
 	if err != nil {
 		serv.Errorf("createStationDirect: Create DLS at station " + csr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	_, err = stationsCollection.InsertOne(context.TODO(), newStation)
 	if err != nil {
 		serv.Errorf("createStationDirect: Station " + csr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
+	s.memphisWSPublishEvent(tenantName, memphisWS_Event_StationCreated, stationName.Ext(), newStation)
+
+	if isPartitioned(newStation.PartitionsNumber) {
+		setStationPartitions(tenantName, stationName, newStation.PartitionsNumber)
+		err = broadcastStationPartitionsUpdate(models.PartitionsUpdate{TenantName: tenantName, StationName: stationName.Ext(), PartitionsNumber: newStation.PartitionsNumber})
+		if err != nil {
+			serv.Errorf("createStationDirect: Station " + csr.StationName + ": " + err.Error())
+		}
+	}
+
 	message := "Station " + stationName.Ext() + " has been created by user " + c.memphisInfo.username
 	serv.Noticef(message)
 
@@ -368,7 +457,7 @@ func (s *Server) createStationDirectIntern(c *client,
 		analytics.SendEventWithParams(c.memphisInfo.username, analyticsParams, "user-create-station")
 	}
 
-	respondWithErr(s, reply, nil)
+	respondWithErr(s, c.acc, reply, nil)
 }
 
 func (sh StationsHandler) GetStation(c *gin.Context) {
@@ -381,7 +470,8 @@ func (sh StationsHandler) GetStation(c *gin.Context) {
 
 	var station models.GetStationResponseSchema
 	err := stationsCollection.FindOne(context.TODO(), bson.M{
-		"name": body.StationName,
+		"name":        body.StationName,
+		"tenant_name": getTenantNameFromMiddleware(c),
 		"$or": []interface{}{
 			bson.M{"is_deleted": false},
 			bson.M{"is_deleted": bson.M{"$exists": false}},
@@ -411,11 +501,11 @@ func (sh StationsHandler) GetStation(c *gin.Context) {
 	c.IndentedJSON(200, station)
 }
 
-func (sh StationsHandler) GetStationsDetails() ([]models.ExtendedStationDetails, error) {
+func (sh StationsHandler) GetStationsDetails(tenantName string) ([]models.ExtendedStationDetails, error) {
 	var exStations []models.ExtendedStationDetails
 	var stations []models.Station
 
-	filter := bson.M{"$or": []interface{}{
+	filter := bson.M{"tenant_name": tenantName, "$or": []interface{}{
 		bson.M{"is_deleted": bson.M{"$exists": false}},
 		bson.M{"is_deleted": false},
 	}}
@@ -431,13 +521,13 @@ func (sh StationsHandler) GetStationsDetails() ([]models.ExtendedStationDetails,
 	if len(stations) == 0 {
 		return []models.ExtendedStationDetails{}, nil
 	} else {
-		allStreamInfo, err := serv.memphisAllStreamsInfo()
+		allStreamInfo, err := serv.memphisAllStreamsInfo(tenantName)
 		if err != nil {
 			return []models.ExtendedStationDetails{}, err
 		}
 		for _, info := range allStreamInfo {
 			streamName := info.Config.Name
-			if strings.Contains(streamName, "$memphis") && strings.Contains(streamName, "dls") {
+			if strings.HasPrefix(streamName, "$memphis-") && strings.HasSuffix(streamName, "-dls") {
 				splitName := strings.Split(streamName, "-")
 				stationName := splitName[1]
 				_, ok := streamInfoToDls[stationName]
@@ -482,14 +572,14 @@ func (sh StationsHandler) GetStationsDetails() ([]models.ExtendedStationDetails,
 	}
 }
 
-func (sh StationsHandler) GetAllStationsDetails() ([]models.ExtendedStation, error) {
+func (sh StationsHandler) GetAllStationsDetails(tenantName string) ([]models.ExtendedStation, error) {
 	var stations []models.ExtendedStation
 	cursor, err := stationsCollection.Aggregate(context.TODO(), mongo.Pipeline{
-		bson.D{{"$match", bson.D{{"$or", []interface{}{
+		bson.D{{"$match", bson.D{{"tenant_name", tenantName}, {"$or", []interface{}{
 			bson.D{{"is_deleted", false}},
 			bson.D{{"is_deleted", bson.D{{"$exists", false}}}},
 		}}}}},
-		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"retention_type", 1}, {"retention_value", 1}, {"storage_type", 1}, {"replicas", 1}, {"idempotency_window_in_ms", 1}, {"created_by_user", 1}, {"creation_date", 1}, {"last_update", 1}, {"functions", 1}, {"dls_configuration", 1}, {"is_native", 1}}}},
+		bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}, {"retention_type", 1}, {"retention_value", 1}, {"storage_type", 1}, {"replicas", 1}, {"idempotency_window_in_ms", 1}, {"created_by_user", 1}, {"tenant_name", 1}, {"creation_date", 1}, {"last_update", 1}, {"functions", 1}, {"dls_configuration", 1}, {"is_native", 1}}}},
 	})
 	if err != nil {
 		return stations, err
@@ -503,13 +593,13 @@ func (sh StationsHandler) GetAllStationsDetails() ([]models.ExtendedStation, err
 		return []models.ExtendedStation{}, nil
 	} else {
 		tagsHandler := TagsHandler{S: sh.S}
-		allStreamInfo, err := serv.memphisAllStreamsInfo()
+		allStreamInfo, err := serv.memphisAllStreamsInfo(tenantName)
 		if err != nil {
 			return []models.ExtendedStation{}, err
 		}
 		for _, info := range allStreamInfo {
 			streamName := info.Config.Name
-			if strings.Contains(streamName, "$memphis") && strings.Contains(streamName, "dls") {
+			if strings.HasPrefix(streamName, "$memphis-") && strings.HasSuffix(streamName, "-dls") {
 				splitName := strings.Split(streamName, "-")
 				stationName := splitName[1]
 				_, ok := streamInfoToDls[stationName]
@@ -552,7 +642,7 @@ func (sh StationsHandler) GetAllStationsDetails() ([]models.ExtendedStation, err
 }
 
 func (sh StationsHandler) GetStations(c *gin.Context) {
-	stations, err := sh.GetStationsDetails()
+	stations, err := sh.GetStationsDetails(getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetStations: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -564,7 +654,7 @@ func (sh StationsHandler) GetStations(c *gin.Context) {
 }
 
 func (sh StationsHandler) GetAllStations(c *gin.Context) {
-	stations, err := sh.GetAllStationsDetails()
+	stations, err := sh.GetAllStationsDetails(getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetAllStations: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -580,31 +670,38 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	newStation, err := sh.createStation(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, newStation)
+}
 
+func (sh StationsHandler) createStation(user models.User, body models.CreateStationSchema) (gin.H, error) {
 	stationName, err := StationNameFromStr(body.Name)
 	if err != nil {
 		serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return nil, newShowableError(err.Error())
 	}
 
-	exist, _, err := IsStationExist(stationName)
+	tenantName := getUserTenantName(user)
+	exist, _, err := IsStationExist(stationName, tenantName)
 	if err != nil {
 		serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return nil, err
 	}
 	if exist {
 		errMsg := "Station " + stationName.external + " already exists"
 		serv.Warnf("CreateStation: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
-	}
-
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return nil, newShowableError(errMsg)
 	}
 
 	schemaName := body.SchemaName
@@ -612,24 +709,21 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 	var schemaDetailsResponse models.StationOverviewSchemaDetails
 	if schemaName != "" {
 		schemaName = strings.ToLower(body.SchemaName)
-		exist, schema, err := IsSchemaExist(schemaName)
+		exist, schema, err := IsSchemaExist(schemaName, tenantName)
 		if err != nil {
 			serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server Error"})
-			return
+			return nil, err
 		}
 		if !exist {
 			errMsg := "Schema " + schemaName + " does not exist"
 			serv.Warnf("CreateStation: Station " + body.Name + ": " + errMsg)
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-			return
+			return nil, newShowableError(errMsg)
 		}
 
 		schemaVersion, err := getActiveVersionBySchemaId(schema.ID)
 		if err != nil {
 			serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-			return
+			return nil, newShowableErrorWithStatus(500, err.Error())
 		}
 
 		schemaDetailsResponse = models.StationOverviewSchemaDetails{SchemaName: schemaName, VersionNumber: schemaVersion.VersionNumber, UpdatesAvailable: true}
@@ -642,8 +736,7 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 		err = validateRetentionType(retentionType)
 		if err != nil {
 			serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return nil, newShowableError(err.Error())
 		}
 	} else {
 		retentionType = "message_age_sec"
@@ -655,8 +748,7 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 		err = validateStorageType(body.StorageType)
 		if err != nil {
 			serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return nil, newShowableError(err.Error())
 		}
 	} else {
 		body.StorageType = "file"
@@ -671,8 +763,7 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 		err = validateReplicas(body.Replicas)
 		if err != nil {
 			serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return nil, newShowableError(err.Error())
 		}
 	} else {
 		body.Replicas = 1
@@ -681,8 +772,66 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 	err = validateIdempotencyWindow(body.RetentionType, body.RetentionValue, body.IdempotencyWindow)
 	if err != nil {
 		serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return nil, newShowableError(err.Error())
+	}
+
+	err = validatePartitionsNumber(body.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
+		return nil, newShowableError(err.Error())
+	}
+
+	mirror, localOrigin, origin, err := validateMirror(stationName, tenantName, body.Mirror, body.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
+		return nil, newShowableError(err.Error())
+	}
+
+	sources, err := validateSources(stationName, tenantName, body.Sources, body.Mirror, body.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
+		return nil, newShowableError(err.Error())
+	}
+	if body.Compression != "" {
+		body.Compression = strings.ToLower(body.Compression)
+		err = validateCompression(body.Compression)
+		if err != nil {
+			serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
+			return nil, newShowableError(err.Error())
+		}
+		if body.Compression != "none" && body.StorageType == "memory" {
+			errMsg := "Compression is only supported for stations stored on disk"
+			serv.Warnf("CreateStation: Station " + body.Name + ": " + errMsg)
+			return nil, newShowableError(errMsg)
+		}
+	} else {
+		body.Compression = "none"
+	}
+
+	if body.Compacted {
+		err = validateCompaction(body.MaxMsgsPerKey, body.PartitionsNumber, body.Mirror, body.Sources)
+		if err != nil {
+			serv.Warnf("CreateStation: Station " + body.Name + ": " + err.Error())
+			return nil, newShowableError(err.Error())
+		}
+		if body.MaxMsgsPerKey == 0 {
+			body.MaxMsgsPerKey = defaultMaxMsgsPerKey
+		}
+	} else {
+		body.MaxMsgsPerKey = 0
+	}
+
+	if body.Encrypted && body.StorageType == "memory" {
+		errMsg := "Encryption at rest is only supported for stations stored on disk"
+		serv.Warnf("CreateStation: Station " + body.Name + ": " + errMsg)
+		return nil, newShowableError(errMsg)
+	}
+	if localOrigin {
+		// a mirror follows the schema and the DLS configuration of its origin
+		schemaName = origin.Schema.SchemaName
+		schemaDetails = origin.Schema
+		schemaDetailsResponse = models.StationOverviewSchemaDetails{SchemaName: origin.Schema.SchemaName, VersionNumber: origin.Schema.VersionNumber}
+		body.DlsConfiguration = origin.DlsConfiguration
 	}
 
 	if body.IdempotencyWindow <= 0 {
@@ -701,6 +850,7 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 		DedupEnabled:      body.DedupEnabled,    // TODO deprecated
 		DedupWindowInMs:   body.DedupWindowInMs, // TODO deprecated
 		CreatedByUser:     user.Username,
+		TenantName:        tenantName,
 		CreationDate:      time.Now(),
 		LastUpdate:        time.Now(),
 		Functions:         []models.Function{},
@@ -709,31 +859,44 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 		IdempotencyWindow: body.IdempotencyWindow,
 		DlsConfiguration:  body.DlsConfiguration,
 		IsNative:          true,
+		RateLimits:        body.RateLimits,
+		PartitionsNumber:  body.PartitionsNumber,
+		Mirror:            mirror,
+		Sources:           sources,
+		Encrypted:         body.Encrypted,
+		Compression:       body.Compression,
+		Compacted:         body.Compacted,
+		MaxMsgsPerKey:     body.MaxMsgsPerKey,
+	}
+
+	if newStation.Encrypted {
+		err = sh.S.encryptStation(tenantName, stationName)
+		if err != nil {
+			serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
+			return nil, err
+		}
 	}
 
 	err = sh.S.CreateStream(stationName, newStation)
 	if err != nil {
 		if IsNatsErr(err, JSInsufficientResourcesErr) {
 			serv.Warnf("CreateStation: Station " + body.Name + ": Station can not be created, probably since replicas count is larger than the cluster size")
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Station can not be created, probably since replicas count is larger than the cluster size"})
-			return
+			return nil, newShowableError("Station can not be created, probably since replicas count is larger than the cluster size")
 		}
 
 		serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return nil, err
 	}
 
 	err = sh.S.CreateDlsStream(stationName, newStation)
 	if err != nil {
 		serv.Errorf("CreateStation: Create DLS at station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return nil, err
 	}
 
 	var emptySchemaDetailsResponse struct{}
 	var update bson.M
-	filter := bson.M{"name": newStation.Name, "is_deleted": false}
+	filter := bson.M{"name": newStation.Name, "tenant_name": newStation.TenantName, "is_deleted": false}
 	if schemaName != "" {
 		update = bson.M{
 			"$setOnInsert": bson.M{
@@ -752,6 +915,14 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 				"idempotency_window_in_ms": newStation.IdempotencyWindow,
 				"dls_configuration":        newStation.DlsConfiguration,
 				"is_native":                newStation.IsNative,
+				"rate_limits":              newStation.RateLimits,
+				"partitions_number":        newStation.PartitionsNumber,
+				"mirror":                   newStation.Mirror,
+				"sources":                  newStation.Sources,
+				"encrypted":                newStation.Encrypted,
+				"compression":              newStation.Compression,
+				"compacted":                newStation.Compacted,
+				"max_msgs_per_key":         newStation.MaxMsgsPerKey,
 			},
 		}
 	} else {
@@ -772,6 +943,14 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 				"idempotency_window_in_ms": newStation.IdempotencyWindow,
 				"dls_configuration":        newStation.DlsConfiguration,
 				"is_native":                newStation.IsNative,
+				"rate_limits":              newStation.RateLimits,
+				"partitions_number":        newStation.PartitionsNumber,
+				"mirror":                   newStation.Mirror,
+				"sources":                  newStation.Sources,
+				"encrypted":                newStation.Encrypted,
+				"compression":              newStation.Compression,
+				"compacted":                newStation.Compacted,
+				"max_msgs_per_key":         newStation.MaxMsgsPerKey,
 			},
 		}
 	}
@@ -779,22 +958,36 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 	updateResults, err := stationsCollection.UpdateOne(context.TODO(), filter, update, opts)
 	if err != nil {
 		serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return nil, err
 	}
 	if updateResults.MatchedCount > 0 {
 		errMsg := "Station " + newStation.Name + " already exists"
 		serv.Warnf("CreateStation: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return nil, newShowableError(errMsg)
+	}
+	sh.S.memphisWSPublishEvent(tenantName, memphisWS_Event_StationCreated, stationName.Ext(), newStation)
+
+	if rateLimitsEnabled(newStation.RateLimits) {
+		setStationRateLimits(tenantName, stationName, newStation.RateLimits)
+		err = broadcastRateLimitsUpdate("station_rate_limits", models.RateLimitsUpdate{TenantName: tenantName, StationName: stationName.Ext(), RateLimits: newStation.RateLimits})
+		if err != nil {
+			serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
+		}
+	}
+
+	if isPartitioned(newStation.PartitionsNumber) {
+		setStationPartitions(tenantName, stationName, newStation.PartitionsNumber)
+		err = broadcastStationPartitionsUpdate(models.PartitionsUpdate{TenantName: tenantName, StationName: stationName.Ext(), PartitionsNumber: newStation.PartitionsNumber})
+		if err != nil {
+			serv.Errorf("CreateStation: Station " + body.Name + ": " + err.Error())
+		}
 	}
 
 	if len(body.Tags) > 0 {
 		err = AddTagsToEntity(body.Tags, "station", newStation.ID)
 		if err != nil {
 			serv.Errorf("CreateStation: : Station " + body.Name + " Failed adding tags: " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
 		}
 	}
 
@@ -826,7 +1019,7 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 	}
 
 	if schemaName != "" {
-		c.IndentedJSON(200, gin.H{
+		return gin.H{
 			"id":                       primitive.NewObjectID(),
 			"name":                     stationName.Ext(),
 			"retention_type":           retentionType,
@@ -843,9 +1036,12 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 			"schema":                   schemaDetailsResponse,
 			"idempotency_window_in_ms": newStation.IdempotencyWindow,
 			"dls_configuration":        newStation.DlsConfiguration,
-		})
+			"partitions_number":        newStation.PartitionsNumber,
+			"mirror":                   newStation.Mirror,
+			"sources":                  newStation.Sources,
+		}, nil
 	} else {
-		c.IndentedJSON(200, gin.H{
+		return gin.H{
 			"id":                       primitive.NewObjectID(),
 			"name":                     stationName.Ext(),
 			"retention_type":           retentionType,
@@ -862,7 +1058,10 @@ func (sh StationsHandler) CreateStation(c *gin.Context) {
 			"schema":                   emptySchemaDetailsResponse,
 			"idempotency_window_in_ms": newStation.IdempotencyWindow,
 			"dls_configuration":        newStation.DlsConfiguration,
-		})
+			"partitions_number":        newStation.PartitionsNumber,
+			"mirror":                   newStation.Mirror,
+			"sources":                  newStation.Sources,
+		}, nil
 	}
 }
 
@@ -875,42 +1074,54 @@ func (sh StationsHandler) RemoveStation(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveStation: " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	err = sh.removeStations(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.IndentedJSON(200, gin.H{})
+}
 
+func (sh StationsHandler) removeStations(user models.User, body models.RemoveStationSchema) error {
+	tenantName := getUserTenantName(user)
 	var stationNames []string
 	for _, name := range body.StationNames {
 		stationName, err := StationNameFromStr(name)
 		if err != nil {
 			serv.Warnf("RemoveStation: Station " + name + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return newShowableError(err.Error())
 		}
 
 		stationNames = append(stationNames, stationName.Ext())
 
-		exist, station, err := IsStationExist(stationName)
+		exist, station, err := IsStationExist(stationName, tenantName)
 		if err != nil {
 			serv.Errorf("RemoveStation: Station " + stationName.external + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return err
 		}
 		if !exist {
 			errMsg := "Station " + name + " does not exist"
 			serv.Warnf("RemoveStation: " + errMsg)
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-			return
+			return newShowableError(errMsg)
 		}
 
 		err = removeStationResources(sh.S, station, true)
 		if err != nil {
 			serv.Errorf("RemoveStation: Station " + stationName.external + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return err
 		}
 	}
 
 	_, err := stationsCollection.UpdateMany(context.TODO(),
 		bson.M{
-			"name": bson.M{"$in": stationNames},
+			"name":        bson.M{"$in": stationNames},
+			"tenant_name": tenantName,
 			"$or": []interface{}{
 				bson.M{"is_deleted": false},
 				bson.M{"is_deleted": bson.M{"$exists": false}},
@@ -920,39 +1131,26 @@ func (sh StationsHandler) RemoveStation(c *gin.Context) {
 	)
 	if err != nil {
 		serv.Errorf("RemoveStation: " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
 		analytics.SendEvent(user.Username, "user-remove-station")
 	}
 
-	for _, name := range body.StationNames {
-		stationName, err := StationNameFromStr(name)
-		if err != nil {
-			serv.Errorf("RemoveStation: Station " + name + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		}
-
-		user, err := getUserDetailsFromMiddleware(c)
-		if err != nil {
-			serv.Errorf("RemoveStation: Station " + name + ": " + err.Error())
-			c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-		}
-
-		serv.Noticef("Station " + stationName.Ext() + " has been deleted by user " + user.Username)
+	for _, name := range stationNames {
+		serv.Noticef("Station " + name + " has been deleted by user " + user.Username)
+		sh.S.memphisWSPublishEvent(tenantName, memphisWS_Event_StationDeleted, name, nil)
 	}
-	c.IndentedJSON(200, gin.H{})
+	return nil
 }
 
 func (s *Server) removeStationDirect(c *client, reply string, msg []byte) {
 	var dsr destroyStationRequest
 	if err := json.Unmarshal(msg, &dsr); err != nil {
 		s.Errorf("removeStationDirect: " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	s.removeStationDirectIntern(c, reply, &dsr, true)
@@ -973,7 +1171,7 @@ func (s *Server) removeStationDirectIntern(c *client,
 		return
 	}
 
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, tenantNameFromAccount(c.acc))
 	if err != nil {
 		serv.Errorf("removeStationDirect: Station " + dsr.StationName + ": " + err.Error())
 		jsApiResp.Error = NewJSStreamDeleteError(err)
@@ -992,13 +1190,14 @@ func (s *Server) removeStationDirectIntern(c *client,
 	err = removeStationResources(s, station, shouldDeleteStream)
 	if err != nil {
 		serv.Errorf("RemoveStation: Station " + dsr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	_, err = stationsCollection.UpdateOne(context.TODO(),
 		bson.M{
-			"name": stationName.Ext(),
+			"name":        stationName.Ext(),
+			"tenant_name": station.TenantName,
 			"$or": []interface{}{
 				bson.M{"is_deleted": false},
 				bson.M{"is_deleted": bson.M{"$exists": false}},
@@ -1008,12 +1207,13 @@ func (s *Server) removeStationDirectIntern(c *client,
 	)
 	if err != nil {
 		serv.Errorf("RemoveStation error: Station " + dsr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	message := "Station " + stationName.Ext() + " has been deleted by user " + c.memphisInfo.username
 	serv.Noticef(message)
+	s.memphisWSPublishEvent(station.TenantName, memphisWS_Event_StationDeleted, stationName.Ext(), nil)
 	var auditLogs []interface{}
 	newAuditLog := models.AuditLog{
 		ID:            primitive.NewObjectID(),
@@ -1028,21 +1228,21 @@ func (s *Server) removeStationDirectIntern(c *client,
 	if err != nil {
 		serv.Warnf("removeStationDirect: Station " + stationName.Ext() + " - create audit logs error: " + err.Error())
 	}
-	respondWithErr(s, reply, nil)
+	respondWithErr(s, c.acc, reply, nil)
 	return
 }
 
-func (sh StationsHandler) GetTotalMessages(stationNameExt string) (int, error) {
+func (sh StationsHandler) GetTotalMessages(tenantName, stationNameExt string) (int, error) {
 	stationName, err := StationNameFromStr(stationNameExt)
 	if err != nil {
 		return 0, err
 	}
-	totalMessages, err := sh.S.GetTotalMessagesInStation(stationName)
+	totalMessages, err := sh.S.GetTotalMessagesInStation(tenantName, stationName)
 	return totalMessages, err
 }
 
-func (sh StationsHandler) GetTotalMessagesAcrossAllStations() (int, error) {
-	totalMessages, err := sh.S.GetTotalMessagesAcrossAllStations()
+func (sh StationsHandler) GetTotalMessagesAcrossAllStations(tenantName string) (int, error) {
+	totalMessages, err := sh.S.GetTotalMessagesAcrossAllStations(tenantName)
 	return totalMessages, err
 }
 
@@ -1051,6 +1251,10 @@ func (sh StationsHandler) GetAvgMsgSize(station models.Station) (int64, error) {
 	return avgMsgSize, err
 }
 
+func (sh StationsHandler) GetCompressionStats(station models.Station) (*CompressionStats, error) {
+	return sh.S.GetCompressionStatsInStation(station)
+}
+
 func (sh StationsHandler) GetMessages(station models.Station, messagesToFetch int) ([]models.MessageDetails, error) {
 	messages, err := sh.S.GetMessages(station, messagesToFetch)
 	if err != nil {
@@ -1092,7 +1296,7 @@ func getCgStatus(members []models.CgMember) (bool, bool) {
 	return false, false
 }
 
-func (sh StationsHandler) GetDlsMessageJourneyDetails(dlsMsgId, dlsType string) (models.DlsMessageResponse, error) {
+func (sh StationsHandler) GetDlsMessageJourneyDetails(tenantName, dlsMsgId, dlsType string) (models.DlsMessageResponse, error) {
 	var dlsMessage models.DlsMessageResponse
 	splitId := strings.Split(dlsMsgId, dlsMsgSep)
 	stationName := splitId[0]
@@ -1100,7 +1304,7 @@ func (sh StationsHandler) GetDlsMessageJourneyDetails(dlsMsgId, dlsType string)
 	if err != nil {
 		return dlsMessage, err
 	}
-	exist, station, err := IsStationExist(sn)
+	exist, station, err := IsStationExist(sn, tenantName)
 	if err != nil {
 		return dlsMessage, err
 	}
@@ -1118,7 +1322,7 @@ func (sh StationsHandler) GetPoisonMessageJourney(c *gin.Context) {
 		return
 	}
 
-	poisonMessage, err := sh.GetDlsMessageJourneyDetails(body.MessageId, "poison")
+	poisonMessage, err := sh.GetDlsMessageJourneyDetails(getTenantNameFromMiddleware(c), body.MessageId, "poison")
 	if err != nil {
 		serv.Errorf("GetPoisonMessageJourney: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1134,7 +1338,107 @@ func (sh StationsHandler) GetPoisonMessageJourney(c *gin.Context) {
 	c.IndentedJSON(200, poisonMessage)
 }
 
-func dropPoisonDlsMessages(poisonMessageIds []string) error {
+func (sh StationsHandler) GetMessageJourney(c *gin.Context) {
+	var body models.GetMessageJourneySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	tenantName := getTenantNameFromMiddleware(c)
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("GetMessageJourney: Station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, station, err := IsStationExist(stationName, tenantName)
+	if err != nil {
+		serv.Errorf("GetMessageJourney: Station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + stationName.Ext() + " does not exist"
+		serv.Warnf("GetMessageJourney: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	journey, err := sh.S.GetMessageJourney(tenantName, stationName, station, uint64(body.MessageSeq))
+	if IsNatsErr(err, JSNoMessageFoundErr) {
+		errMsg := "Message " + strconv.Itoa(body.MessageSeq) + " does not exist in station " + stationName.Ext()
+		serv.Warnf("GetMessageJourney: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if err != nil {
+		serv.Errorf("GetMessageJourney: Station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		user, _ := getUserDetailsFromMiddleware(c)
+		analytics.SendEvent(user.Username, "user-enter-message-journey")
+	}
+
+	c.IndentedJSON(200, journey)
+}
+
+func (sh StationsHandler) GetStationMetrics(c *gin.Context) {
+	var body models.GetStationMetricsSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	tenantName := getTenantNameFromMiddleware(c)
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("GetStationMetrics: Station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	metricsRange, ok := stationMetricsRanges[body.Range]
+	if !ok {
+		errMsg := "Range has to be one of hour, day or week"
+		serv.Warnf("GetStationMetrics: Station " + body.StationName + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, _, err := IsStationExist(stationName, tenantName)
+	if err != nil {
+		serv.Errorf("GetStationMetrics: Station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + stationName.Ext() + " does not exist"
+		serv.Warnf("GetStationMetrics: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	points, err := sh.S.GetStationMetrics(tenantName, stationName, body.Range, time.Now())
+	if err != nil {
+		serv.Errorf("GetStationMetrics: Station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, models.StationMetricsResponse{
+		StationName: stationName.Ext(),
+		Range:       body.Range,
+		StepSec:     int(metricsRange.step.Seconds()),
+		Points:      points,
+	})
+}
+
+func dropPoisonDlsMessages(tenantName string, poisonMessageIds []string) error {
 	timeout := 500 * time.Millisecond
 	splitId := strings.Split(poisonMessageIds[0], dlsMsgSep)
 	stationName := splitId[0]
@@ -1143,19 +1447,19 @@ func dropPoisonDlsMessages(poisonMessageIds []string) error {
 		return errors.New("dropPoisonDlsMessages: " + err.Error())
 	}
 	streamName := fmt.Sprintf(dlsStreamName, sn.Intern())
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	streamInfo, err := serv.memphisStreamInfo(tenantName, streamName)
 	if err != nil {
 		return errors.New("dropPoisonDlsMessages: " + err.Error())
 	}
 	amount := streamInfo.State.Msgs
 	for _, msgId := range poisonMessageIds {
 		filter := GetDlsSubject("poison", sn.Intern(), msgId, "*")
-		msgs, err := serv.memphisGetMessagesByFilter(streamName, filter, 0, amount, timeout)
+		msgs, err := serv.memphisGetMessagesByFilter(tenantName, streamName, filter, 0, amount, timeout)
 		if err != nil {
 			return errors.New("dropPoisonDlsMessages: " + err.Error())
 		}
 		for _, msg := range msgs {
-			_, err = serv.memphisDeleteMsgFromStream(streamName, msg.Sequence)
+			_, err = serv.memphisDeleteMsgFromStream(tenantName, streamName, msg.Sequence)
 			if err != nil {
 				return errors.New("dropPoisonDlsMessages: " + err.Error())
 			}
@@ -1164,7 +1468,7 @@ func dropPoisonDlsMessages(poisonMessageIds []string) error {
 	return nil
 }
 
-func dropSchemaDlsMsg(schemaMessageIds []string) error {
+func dropSchemaDlsMsg(tenantName string, schemaMessageIds []string) error {
 	timeout := 500 * time.Millisecond
 	splitId := strings.Split(schemaMessageIds[0], dlsMsgSep)
 	stationName := splitId[0]
@@ -1176,7 +1480,7 @@ func dropSchemaDlsMsg(schemaMessageIds []string) error {
 	amount := uint64(1)
 	for _, msgId := range schemaMessageIds {
 		filter := GetDlsSubject("schema", sn.Intern(), msgId, _EMPTY_)
-		msgs, err := serv.memphisGetMessagesByFilter(streamName, filter, 0, amount, timeout)
+		msgs, err := serv.memphisGetMessagesByFilter(tenantName, streamName, filter, 0, amount, timeout)
 		if err != nil {
 			return errors.New("dropSchemaDlsMsg: " + err.Error())
 		}
@@ -1187,7 +1491,7 @@ func dropSchemaDlsMsg(schemaMessageIds []string) error {
 			return errors.New("dropSchemaDlsMsg: " + err.Error())
 		}
 		if msgId == dlsMsg.ID {
-			_, err = serv.memphisDeleteMsgFromStream(streamName, msg.Sequence)
+			_, err = serv.memphisDeleteMsgFromStream(tenantName, streamName, msg.Sequence)
 			if err != nil {
 				return errors.New("dropSchemaDlsMsg: " + err.Error())
 			}
@@ -1205,14 +1509,14 @@ func (sh StationsHandler) DropDlsMessages(c *gin.Context) {
 		return
 	}
 	if body.DlsMsgType == "poison" {
-		err := dropPoisonDlsMessages(body.DlsMessageIds)
+		err := dropPoisonDlsMessages(getTenantNameFromMiddleware(c), body.DlsMessageIds)
 		if err != nil {
 			serv.Errorf("DropDlsMessages: " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 			return
 		}
 	} else if body.DlsMsgType == "schema" {
-		err := dropSchemaDlsMsg(body.DlsMessageIds)
+		err := dropSchemaDlsMsg(getTenantNameFromMiddleware(c), body.DlsMessageIds)
 		if err != nil {
 			serv.Errorf("DropDlsMessages: " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1244,8 +1548,9 @@ func (sh StationsHandler) ResendPoisonMessages(c *gin.Context) {
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 		return
 	}
+	tenantName := getTenantNameFromMiddleware(c)
 	streamName := fmt.Sprintf(dlsStreamName, sn.Intern())
-	streamInfo, err := serv.memphisStreamInfo(streamName)
+	streamInfo, err := serv.memphisStreamInfo(tenantName, streamName)
 	if err != nil {
 		serv.Errorf("ResendPoisonMessages: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1254,7 +1559,7 @@ func (sh StationsHandler) ResendPoisonMessages(c *gin.Context) {
 	amount := streamInfo.State.Msgs
 	for _, msgId := range body.PoisonMessageIds {
 		filter := GetDlsSubject("poison", sn.Intern(), msgId, "*")
-		msgs, err := serv.memphisGetMessagesByFilter(streamName, filter, 0, amount, timeout)
+		msgs, err := serv.memphisGetMessagesByFilter(tenantName, streamName, filter, 0, amount, timeout)
 		if err != nil {
 			serv.Errorf("ResendPoisonMessages: " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1289,7 +1594,7 @@ func (sh StationsHandler) ResendPoisonMessages(c *gin.Context) {
 				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
 				return
 			}
-			err = sh.S.ResendPoisonMessage("$memphis_dls_"+stationName+"_"+cgName, []byte(data), headers)
+			err = sh.S.ResendPoisonMessage(tenantName, "$memphis_dls_"+stationName+"_"+cgName, []byte(data), headers)
 			if err != nil {
 				serv.Errorf("ResendPoisonMessages: Poisoned consumer group: " + dlsMsg.PoisonedCg.CgName + ": " + err.Error())
 				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1315,9 +1620,10 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 		return
 	}
 	msgId := body.MessageId
+	tenantName := getTenantNameFromMiddleware(c)
 
 	if body.IsDls {
-		poisonMessage, err := sh.GetDlsMessageJourneyDetails(msgId, body.DlsType)
+		poisonMessage, err := sh.GetDlsMessageJourneyDetails(tenantName, msgId, body.DlsType)
 		if err != nil {
 			serv.Errorf("GetMessageDetails: Message ID: " + msgId + ": " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1335,7 +1641,7 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 		return
 	}
 
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, tenantName)
 	if !exist {
 		errMsg := "Station " + stationName.external + " does not exist"
 		serv.Warnf("GetMessageDetails: " + errMsg)
@@ -1348,7 +1654,7 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 		return
 	}
 
-	sm, err := sh.S.GetMessage(stationName, uint64(body.MessageSeq))
+	sm, err := sh.S.GetMessage(tenantName, stationName, uint64(body.MessageSeq))
 	if err != nil {
 		serv.Errorf("GetMessageDetails: Message ID: Message ID: " + msgId + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1392,11 +1698,25 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 	connectionIdHeader := headersJson["$memphis_connectionId"]
 	producedByHeader := strings.ToLower(headersJson["$memphis_producedBy"])
 
-	for header := range headersJson {
-		if strings.HasPrefix(header, "$memphis") {
-			delete(headersJson, header)
-		}
-	}
+	// Large payloads are shown by the beginning of the reassembled payload instead of their claim check
+	size := len(sm.Subject) + len(sm.Data) + len(sm.Header)
+	data := sm.Data
+	cc, chunked := getClaimCheck(headersJson)
+	if chunked {
+		data, err = sh.S.getChunkedPayloadPreview(tenantName, stationName.Intern(), cc)
+		if err != nil {
+			serv.Warnf("GetMessageDetails: Message ID: " + msgId + ": " + err.Error())
+			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+			return
+		}
+		size = cc.payloadSize
+	}
+
+	for header := range headersJson {
+		if strings.HasPrefix(header, "$memphis") {
+			delete(headersJson, header)
+		}
+	}
 
 	// This check for backward compatability
 	if connectionIdHeader == "" || producedByHeader == "" {
@@ -1413,7 +1733,7 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 	poisonedCgs := make([]models.PoisonedCg, 0)
 	// Only native stations have CGs
 	if station.IsNative {
-		poisonedCgs, err = GetPoisonedCgsByMessage(stationName.Intern(), models.MessageDetails{MessageSeq: int(sm.Sequence), ProducedBy: producedByHeader, TimeSent: sm.Time})
+		poisonedCgs, err = GetPoisonedCgsByMessage(tenantName, stationName.Intern(), models.MessageDetails{MessageSeq: int(sm.Sequence), ProducedBy: producedByHeader, TimeSent: sm.Time})
 		if err != nil {
 			serv.Errorf("GetMessageDetails: Message ID: " + msgId + ": " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1421,7 +1741,7 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 		}
 
 		for i, cg := range poisonedCgs {
-			cgInfo, err := sh.S.GetCgInfo(stationName, cg.CgName)
+			cgInfo, err := sh.S.GetCgInfo(tenantName, stationName, cg.CgName)
 			if err != nil {
 				serv.Errorf("GetMessageDetails: Message ID: " + msgId + ": " + err.Error())
 				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1469,10 +1789,12 @@ func (sh StationsHandler) GetMessageDetails(c *gin.Context) {
 	msg := models.MessageResponse{
 		MessageSeq: body.MessageSeq,
 		Message: models.MessagePayload{
-			TimeSent: sm.Time,
-			Size:     len(sm.Subject) + len(sm.Data) + len(sm.Header),
-			Data:     hex.EncodeToString(sm.Data),
-			Headers:  headersJson,
+			TimeSent:    sm.Time,
+			Size:        size,
+			Data:        hex.EncodeToString(data),
+			Headers:     headersJson,
+			Chunked:     chunked,
+			ChunksTotal: cc.chunksTotal,
 		},
 		Producer: models.ProducerDetails{
 			Name:          producedByHeader,
@@ -1493,62 +1815,69 @@ func (sh StationsHandler) UseSchema(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UseSchema: Schema " + body.SchemaName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	schemaDetailsResponse, err := sh.useSchema(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
 
+	c.IndentedJSON(200, schemaDetailsResponse)
+}
+
+func (sh StationsHandler) useSchema(user models.User, body models.UseSchema) (models.StationOverviewSchemaDetails, error) {
+	tenantName := getUserTenantName(user)
 	schemaName := strings.ToLower(body.SchemaName)
-	exist, schema, err := IsSchemaExist(schemaName)
+	exist, schema, err := IsSchemaExist(schemaName, tenantName)
 	if err != nil {
 		serv.Errorf("UseSchema: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server Error"})
-		return
+		return models.StationOverviewSchemaDetails{}, err
 	}
 	if !exist {
 		errMsg := "Schema " + schemaName + " does not exist"
 		serv.Warnf("UseSchema: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return models.StationOverviewSchemaDetails{}, newShowableError(errMsg)
 	}
 
 	schemaVersion, err := getActiveVersionBySchemaId(schema.ID)
 	if err != nil {
 		serv.Errorf("UseSchema: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-		return
+		return models.StationOverviewSchemaDetails{}, newShowableErrorWithStatus(500, err.Error())
 	}
 	schemaDetailsResponse := models.StationOverviewSchemaDetails{SchemaName: schemaName, VersionNumber: schemaVersion.VersionNumber, UpdatesAvailable: false}
 	schemaDetails := models.SchemaDetails{SchemaName: schemaName, VersionNumber: schemaVersion.VersionNumber}
 
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("UseSchema: Schema " + body.SchemaName + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-	}
-
 	for _, stationName := range body.StationNames {
 		stationName, err := StationNameFromStr(stationName)
 		if err != nil {
 			serv.Warnf("UseSchema: Schema " + body.SchemaName + " at station " + stationName.Ext() + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return models.StationOverviewSchemaDetails{}, newShowableError(err.Error())
 		}
 
-		exist, station, err := IsStationExist(stationName)
+		exist, station, err := IsStationExist(stationName, tenantName)
 		if err != nil {
 			serv.Errorf("UseSchema: Schema " + body.SchemaName + " at station " + stationName.Ext() + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return models.StationOverviewSchemaDetails{}, err
 		}
 		if !exist {
 			errMsg := "Station " + station.Name + " does not exist"
 			serv.Warnf("UseSchema: Schema " + body.SchemaName + ": " + errMsg)
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-			return
+			return models.StationOverviewSchemaDetails{}, newShowableError(errMsg)
 		}
 
-		_, err = stationsCollection.UpdateOne(context.TODO(), bson.M{"name": stationName.Ext(), "is_deleted": false}, bson.M{"$set": bson.M{"schema": schemaDetails}})
+		_, err = stationsCollection.UpdateOne(context.TODO(), bson.M{"name": stationName.Ext(), "tenant_name": tenantName, "is_deleted": false}, bson.M{"$set": bson.M{"schema": schemaDetails}})
 		if err != nil {
 			serv.Errorf("UseSchema: Schema " + body.SchemaName + " at station " + stationName.Ext() + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-			return
+			return models.StationOverviewSchemaDetails{}, newShowableErrorWithStatus(500, err.Error())
+		}
+		err = syncMirrors(tenantName, stationName, bson.M{"schema": schemaDetails})
+		if err != nil {
+			serv.Errorf("UseSchema: Schema " + body.SchemaName + " at mirrors of station " + stationName.Ext() + ": " + err.Error())
 		}
 
 		message := "Schema " + schemaName + " has been attached to station " + stationName.Ext() + " by user " + user.Username
@@ -1572,22 +1901,21 @@ func (sh StationsHandler) UseSchema(c *gin.Context) {
 		updateContent, err := generateSchemaUpdateInit(schema)
 		if err != nil {
 			serv.Errorf("UseSchema: Schema " + body.SchemaName + " at station " + stationName.Ext() + ": " + err.Error())
-			return
+			return models.StationOverviewSchemaDetails{}, err
 		}
 		update := models.ProducerSchemaUpdate{
 			UpdateType: models.SchemaUpdateTypeInit,
 			Init:       *updateContent,
 		}
-		sh.S.updateStationProducersOfSchemaChange(stationName, update)
+		sh.S.updateStationProducersOfSchemaChange(tenantName, stationName, update)
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
 		analytics.SendEvent(user.Username, "user-attach-schema-to-station")
 	}
 
-	c.IndentedJSON(200, schemaDetailsResponse)
+	return schemaDetailsResponse, nil
 }
 
 func (s *Server) useSchemaDirect(c *client, reply string, msg []byte) {
@@ -1595,59 +1923,64 @@ func (s *Server) useSchemaDirect(c *client, reply string, msg []byte) {
 	if err := json.Unmarshal(msg, &asr); err != nil {
 		errMsg := "failed attaching schema " + asr.Name + ": " + err.Error()
 		s.Errorf("useSchemaDirect: At station " + asr.StationName + " " + errMsg)
-		respondWithErr(s, reply, errors.New(errMsg))
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
 		return
 	}
 	stationName, err := StationNameFromStr(asr.StationName)
 	if err != nil {
 		serv.Warnf("useSchemaDirect: Schema " + asr.Name + " at station " + asr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
-	exist, _, err := IsStationExist(stationName)
+	tenantName := tenantNameFromAccount(c.acc)
+	exist, _, err := IsStationExist(stationName, tenantName)
 	if err != nil {
 		serv.Errorf("useSchemaDirect: Schema " + asr.Name + " at station " + asr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
 	if !exist {
 		errMsg := "Station " + stationName.external + " does not exist"
 		serv.Warnf("useSchemaDirect: " + errMsg)
-		respondWithErr(s, reply, errors.New("memphis: "+errMsg))
+		respondWithErr(s, c.acc, reply, errors.New("memphis: "+errMsg))
 		return
 	}
 
 	var schemaDetails models.SchemaDetails
 	schemaName := strings.ToLower(asr.Name)
-	exist, schema, err := IsSchemaExist(schemaName)
+	exist, schema, err := IsSchemaExist(schemaName, tenantName)
 	if err != nil {
 		serv.Errorf("useSchemaDirect: Schema " + asr.Name + " at station " + asr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	if !exist {
 		errMsg := "Schema " + schemaName + " does not exist"
 		serv.Warnf("useSchemaDirect: " + errMsg)
-		respondWithErr(s, reply, errors.New(errMsg))
+		respondWithErr(s, c.acc, reply, errors.New(errMsg))
 		return
 	}
 
 	schemaVersion, err := getActiveVersionBySchemaId(schema.ID)
 	if err != nil {
 		serv.Errorf("useSchemaDirect: Schema " + asr.Name + " at station " + asr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	schemaDetails = models.SchemaDetails{SchemaName: schemaName, VersionNumber: schemaVersion.VersionNumber}
 
-	_, err = stationsCollection.UpdateOne(context.TODO(), bson.M{"name": stationName.Ext(), "is_deleted": false}, bson.M{"$set": bson.M{"schema": schemaDetails}})
+	_, err = stationsCollection.UpdateOne(context.TODO(), bson.M{"name": stationName.Ext(), "tenant_name": tenantName, "is_deleted": false}, bson.M{"$set": bson.M{"schema": schemaDetails}})
 	if err != nil {
 		serv.Errorf("useSchemaDirect: Schema " + asr.Name + " at station " + asr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
+	err = syncMirrors(tenantName, stationName, bson.M{"schema": schemaDetails})
+	if err != nil {
+		serv.Errorf("useSchemaDirect: Schema " + asr.Name + " at mirrors of station " + asr.StationName + ": " + err.Error())
+	}
 
 	username := c.getClientInfo(true).Name
 	message := "Schema " + schemaName + " has been attached to station " + stationName.Ext() + " by user " + username
@@ -1684,12 +2017,12 @@ func (s *Server) useSchemaDirect(c *client, reply string, msg []byte) {
 		Init:       *updateContent,
 	}
 
-	serv.updateStationProducersOfSchemaChange(stationName, update)
-	respondWithErr(s, reply, nil)
+	serv.updateStationProducersOfSchemaChange(tenantName, stationName, update)
+	respondWithErr(s, c.acc, reply, nil)
 }
 
-func removeSchemaFromStation(s *Server, sn StationName, updateDB bool) error {
-	exist, _, err := IsStationExist(sn)
+func removeSchemaFromStation(s *Server, tenantName string, sn StationName, updateDB bool) error {
+	exist, _, err := IsStationExist(sn, tenantName)
 	if err != nil {
 		return err
 	}
@@ -1700,7 +2033,8 @@ func removeSchemaFromStation(s *Server, sn StationName, updateDB bool) error {
 	if updateDB {
 		_, err = stationsCollection.UpdateOne(context.TODO(),
 			bson.M{
-				"name": sn.Ext(),
+				"name":        sn.Ext(),
+				"tenant_name": tenantName,
 				"$or": []interface{}{
 					bson.M{"is_deleted": false},
 					bson.M{"is_deleted": bson.M{"$exists": false}},
@@ -1711,13 +2045,17 @@ func removeSchemaFromStation(s *Server, sn StationName, updateDB bool) error {
 		if err != nil {
 			return err
 		}
+		err = syncMirrors(tenantName, sn, bson.M{"schema": bson.M{}})
+		if err != nil {
+			return err
+		}
 	}
 
 	update := models.ProducerSchemaUpdate{
 		UpdateType: models.SchemaUpdateTypeDrop,
 	}
 
-	s.updateStationProducersOfSchemaChange(sn, update)
+	s.updateStationProducersOfSchemaChange(tenantName, sn, update)
 	return nil
 }
 
@@ -1725,23 +2063,23 @@ func (s *Server) removeSchemaFromStationDirect(c *client, reply string, msg []by
 	var dsr detachSchemaRequest
 	if err := json.Unmarshal(msg, &dsr); err != nil {
 		s.Errorf("removeSchemaFromStationDirect: failed removing schema at station " + dsr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 	stationName, err := StationNameFromStr(dsr.StationName)
 	if err != nil {
 		serv.Warnf("removeSchemaFromStationDirect: At station " + dsr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
 
-	err = removeSchemaFromStation(serv, stationName, true)
+	err = removeSchemaFromStation(serv, tenantNameFromAccount(c.acc), stationName, true)
 	if err != nil {
 		serv.Errorf("removeSchemaFromStationDirect: At station " + dsr.StationName + ": " + err.Error())
-		respondWithErr(s, reply, err)
+		respondWithErr(s, c.acc, reply, err)
 		return
 	}
-	respondWithErr(s, reply, nil)
+	respondWithErr(s, c.acc, reply, nil)
 }
 
 func (sh StationsHandler) RemoveSchemaFromStation(c *gin.Context) {
@@ -1754,38 +2092,45 @@ func (sh StationsHandler) RemoveSchemaFromStation(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("RemoveSchemaFromStation: At station" + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	err = sh.detachSchemaFromStation(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
 
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (sh StationsHandler) detachSchemaFromStation(user models.User, body models.RemoveSchemaFromStation) error {
+	tenantName := getUserTenantName(user)
 	stationName, err := StationNameFromStr(body.StationName)
 	if err != nil {
 		serv.Warnf("RemoveSchemaFromStation: At station" + body.StationName + ": " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return newShowableError(err.Error())
 	}
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, tenantName)
 	if err != nil {
 		serv.Errorf("RemoveSchemaFromStation: At station" + body.StationName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 	if !exist {
 		errMsg := "Station " + body.StationName + " does not exist"
 		serv.Warnf("RemoveSchemaFromStation: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return newShowableError(errMsg)
 	}
 
-	err = removeSchemaFromStation(sh.S, stationName, true)
+	err = removeSchemaFromStation(sh.S, tenantName, stationName, true)
 	if err != nil {
 		serv.Errorf("RemoveSchemaFromStation: At station" + body.StationName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("RemoveSchemaFromStation: At station" + body.StationName + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-	}
 	message := "Schema " + station.Schema.SchemaName + " has been deleted from station " + stationName.Ext() + " by user " + user.Username
 	serv.Noticef(message)
 	var auditLogs []interface{}
@@ -1808,7 +2153,7 @@ func (sh StationsHandler) RemoveSchemaFromStation(c *gin.Context) {
 		analytics.SendEvent(user.Username, "user-remove-schema-from-station")
 	}
 
-	c.IndentedJSON(200, gin.H{})
+	return nil
 }
 
 func (sh StationsHandler) GetUpdatesForSchemaByStation(c *gin.Context) {
@@ -1825,7 +2170,7 @@ func (sh StationsHandler) GetUpdatesForSchemaByStation(c *gin.Context) {
 		return
 	}
 
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
 	if err != nil {
 		serv.Errorf("GetUpdatesForSchemaByStation: At station" + body.StationName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1839,7 +2184,7 @@ func (sh StationsHandler) GetUpdatesForSchemaByStation(c *gin.Context) {
 	}
 
 	var schema models.Schema
-	err = schemasCollection.FindOne(context.TODO(), bson.M{"name": station.Schema.SchemaName}).Decode(&schema)
+	err = schemasCollection.FindOne(context.TODO(), bson.M{"name": station.Schema.SchemaName, "tenant_name": station.TenantName}).Decode(&schema)
 	if err != nil {
 		serv.Errorf("GetUpdatesForSchemaByStation: At station" + body.StationName + ": " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -1880,25 +2225,38 @@ func (sh StationsHandler) UpdateDlsConfig(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("DlsConfiguration: At station" + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	err = sh.updateDlsConfig(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{"poison": body.Poison, "schemaverse": body.Schemaverse})
+}
 
+func (sh StationsHandler) updateDlsConfig(user models.User, body models.UpdateDlsConfigSchema) error {
+	tenantName := getUserTenantName(user)
 	stationName, err := StationNameFromStr(body.StationName)
 	if err != nil {
 		serv.Warnf("DlsConfiguration: At station" + body.StationName + ": " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return newShowableError(err.Error())
 	}
 
-	exist, station, err := IsStationExist(stationName)
+	exist, station, err := IsStationExist(stationName, tenantName)
 	if err != nil {
 		serv.Errorf("DlsConfiguration: At station" + body.StationName + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 	if !exist {
 		errMsg := "Station " + body.StationName + " does not exist"
 		serv.Warnf("DlsConfiguration: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return newShowableError(errMsg)
 	}
 
 	poisonConfigChanged := station.DlsConfiguration.Poison != body.Poison
@@ -1909,7 +2267,8 @@ func (sh StationsHandler) UpdateDlsConfig(c *gin.Context) {
 			Schemaverse: body.Schemaverse,
 		}
 		filter := bson.M{
-			"name": body.StationName,
+			"name":        body.StationName,
+			"tenant_name": station.TenantName,
 			"$or": []interface{}{
 				bson.M{"is_deleted": false},
 				bson.M{"is_deleted": bson.M{"$exists": false}},
@@ -1925,8 +2284,11 @@ func (sh StationsHandler) UpdateDlsConfig(c *gin.Context) {
 		_, err := stationsCollection.UpdateOne(context.TODO(), filter, update, opts)
 		if err != nil {
 			serv.Errorf("DlsConfiguration: At station" + body.StationName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return err
+		}
+		err = syncMirrors(station.TenantName, stationName, bson.M{"dls_configuration": dlsConfigurationNew})
+		if err != nil {
+			serv.Errorf("DlsConfiguration: At mirrors of station " + body.StationName + ": " + err.Error())
 		}
 	}
 	configUpdate := models.ConfigurationsUpdate{
@@ -1936,7 +2298,320 @@ func (sh StationsHandler) UpdateDlsConfig(c *gin.Context) {
 	}
 	serv.SendUpdateToClients(configUpdate)
 
-	c.IndentedJSON(200, gin.H{"poison": body.Poison, "schemaverse": body.Schemaverse})
+	return nil
+}
+
+func (sh StationsHandler) UpdateRateLimits(c *gin.Context) {
+	var body models.UpdateStationRateLimitsSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("UpdateRateLimits: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
+	if err != nil {
+		serv.Errorf("UpdateRateLimits: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + body.StationName + " does not exist"
+		serv.Warnf("UpdateRateLimits: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	_, err = stationsCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": station.ID},
+		bson.M{"$set": bson.M{"rate_limits": body.RateLimits}},
+	)
+	if err != nil {
+		serv.Errorf("UpdateRateLimits: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	setStationRateLimits(station.TenantName, stationName, body.RateLimits)
+	err = broadcastRateLimitsUpdate("station_rate_limits", models.RateLimitsUpdate{TenantName: station.TenantName, StationName: stationName.Ext(), RateLimits: body.RateLimits})
+	if err != nil {
+		serv.Errorf("UpdateRateLimits: At station " + body.StationName + ": " + err.Error())
+	}
+
+	user, _ := getUserDetailsFromMiddleware(c)
+	message := fmt.Sprintf("Rate limits of station %s have been set to %d messages and %d bytes per second by user %s", stationName.Ext(), body.RateLimits.MsgsPerSec, body.RateLimits.BytesPerSec, user.Username)
+	serv.Noticef(message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		ID:            primitive.NewObjectID(),
+		StationName:   stationName.Ext(),
+		Message:       message,
+		CreatedByUser: user.Username,
+		CreationDate:  time.Now(),
+		UserType:      user.UserType,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("UpdateRateLimits: At station " + body.StationName + " - create audit logs: " + err.Error())
+	}
+
+	c.IndentedJSON(200, body.RateLimits)
+}
+
+func (sh StationsHandler) UpdateSources(c *gin.Context) {
+	var body models.UpdateStationSourcesSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("UpdateSources: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
+	if err != nil {
+		serv.Errorf("UpdateSources: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + body.StationName + " does not exist"
+		serv.Warnf("UpdateSources: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	// consumers of an aggregate station are not filtered, so a station can not become or stop being an aggregate one
+	if !isAggregateStation(station) || len(body.Sources) == 0 {
+		errMsg := "Station " + body.StationName + " has to be an aggregate station with at least one source"
+		serv.Warnf("UpdateSources: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	sources, err := validateSources(stationName, station.TenantName, body.Sources, models.MirrorSchema{}, station.PartitionsNumber)
+	if err != nil {
+		serv.Warnf("UpdateSources: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = sh.S.updateStationSources(station, sources)
+	if err != nil {
+		serv.Errorf("UpdateSources: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	var sourcesNames []string
+	for _, source := range sources {
+		sourcesNames = append(sourcesNames, source.StationName)
+	}
+	user, _ := getUserDetailsFromMiddleware(c)
+	message := "Sources of station " + stationName.Ext() + " have been set to " + strings.Join(sourcesNames, ", ") + " by user " + user.Username
+	serv.Noticef(message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		ID:            primitive.NewObjectID(),
+		StationName:   stationName.Ext(),
+		Message:       message,
+		CreatedByUser: user.Username,
+		CreationDate:  time.Now(),
+		UserType:      user.UserType,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("UpdateSources: At station " + body.StationName + " - create audit logs: " + err.Error())
+	}
+
+	c.IndentedJSON(200, sources)
+}
+
+func (sh StationsHandler) PromoteMirror(c *gin.Context) {
+	var body models.PromoteMirrorSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("PromoteMirror: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
+	if err != nil {
+		serv.Errorf("PromoteMirror: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + body.StationName + " does not exist"
+		serv.Warnf("PromoteMirror: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if !isActiveMirror(station) {
+		errMsg := "Station " + body.StationName + " is not a mirror"
+		serv.Warnf("PromoteMirror: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	err = sh.S.promoteMirror(station)
+	if err != nil {
+		serv.Errorf("PromoteMirror: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	user, _ := getUserDetailsFromMiddleware(c)
+	message := "Station " + stationName.Ext() + " has been promoted from a mirror of station " + station.Mirror.StationName + " by user " + user.Username
+	serv.Noticef(message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		ID:            primitive.NewObjectID(),
+		StationName:   stationName.Ext(),
+		Message:       message,
+		CreatedByUser: user.Username,
+		CreationDate:  time.Now(),
+		UserType:      user.UserType,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("PromoteMirror: At station " + body.StationName + " - create audit logs: " + err.Error())
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (sh StationsHandler) RotateStationKey(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.StationEncryptionKeySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("RotateStationKey: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, station, err := IsStationExist(stationName, getTenantNameFromMiddleware(c))
+	if err != nil {
+		serv.Errorf("RotateStationKey: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + body.StationName + " does not exist"
+		serv.Warnf("RotateStationKey: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if !station.Encrypted {
+		errMsg := "Station " + body.StationName + " is not encrypted"
+		serv.Warnf("RotateStationKey: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	err = sh.S.rotateStationKey(station)
+	if err != nil {
+		serv.Errorf("RotateStationKey: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	user, _ := getUserDetailsFromMiddleware(c)
+	message := "The encryption key of station " + stationName.Ext() + " has been rotated by user " + user.Username
+	serv.Noticef(message)
+	var auditLogs []interface{}
+	newAuditLog := models.AuditLog{
+		ID:            primitive.NewObjectID(),
+		StationName:   stationName.Ext(),
+		Message:       message,
+		CreatedByUser: user.Username,
+		CreationDate:  time.Now(),
+		UserType:      user.UserType,
+	}
+	auditLogs = append(auditLogs, newAuditLog)
+	err = CreateAuditLogs(auditLogs)
+	if err != nil {
+		serv.Errorf("RotateStationKey: At station " + body.StationName + " - create audit logs: " + err.Error())
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (sh StationsHandler) ShredStation(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.StationEncryptionKeySchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	stationName, err := StationNameFromStr(body.StationName)
+	if err != nil {
+		serv.Warnf("ShredStation: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	tenantName := getTenantNameFromMiddleware(c)
+	exist, station, err := IsStationExist(stationName, tenantName)
+	if err != nil {
+		serv.Errorf("ShredStation: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Station " + body.StationName + " does not exist"
+		serv.Warnf("ShredStation: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if !station.Encrypted {
+		errMsg := "Station " + body.StationName + " is not encrypted"
+		serv.Warnf("ShredStation: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	err = sh.S.shredStation(station)
+	if err != nil {
+		serv.Errorf("ShredStation: At station " + body.StationName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	user, _ := getUserDetailsFromMiddleware(c)
+	serv.Noticef("Station " + stationName.Ext() + " has been crypto-shredded by user " + user.Username + ", backups taken before have to be destroyed separately")
+	sh.S.memphisWSPublishEvent(tenantName, memphisWS_Event_StationDeleted, stationName.Ext(), nil)
+
+	c.IndentedJSON(200, gin.H{})
 }
 
 func (s *Server) AlignOldStations() error {
@@ -1969,7 +2644,7 @@ func launchDlsForOldStations(s *Server) error {
 		}
 		streamName := fmt.Sprintf(dlsStreamName, sn.Intern())
 
-		_, err = s.memphisStreamInfo(streamName)
+		_, err = s.memphisStreamInfo(station.TenantName, streamName)
 		if err != nil {
 			if IsNatsErr(err, JSStreamNotFoundErr) {
 				dlsConfigurationNew := models.DlsConfiguration{
@@ -1977,7 +2652,8 @@ func launchDlsForOldStations(s *Server) error {
 					Schemaverse: true,
 				}
 				filter := bson.M{
-					"name": station.Name,
+					"name":        station.Name,
+					"tenant_name": station.TenantName,
 					"$or": []interface{}{
 						bson.M{"is_deleted": false},
 						bson.M{"is_deleted": bson.M{"$exists": false}},
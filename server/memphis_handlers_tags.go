@@ -30,7 +30,7 @@ type TagsHandler struct{ S *Server }
 
 func validateEntityType(entity string) error {
 	switch entity {
-	case "station", "schema", "user":
+	case "station", "schema", "user", "kv_bucket":
 		return nil
 	default:
 		return errors.New("Entity type is not valid")
@@ -44,32 +44,37 @@ func CreateTag(name string, entity_type string, entity_id primitive.ObjectID, co
 	stationArr := []primitive.ObjectID{}
 	schemaArr := []primitive.ObjectID{}
 	userArr := []primitive.ObjectID{}
+	kvBucketArr := []primitive.ObjectID{}
 	switch entity {
 	case "station":
 		stationArr = append(stationArr, entity_id)
 	case "schema":
 		schemaArr = append(schemaArr, entity_id)
+	case "kv_bucket":
+		kvBucketArr = append(kvBucketArr, entity_id)
 		// case "user":
 		// 	userArr = append(userArr, entity_id)
 	}
 	newTag = models.Tag{
-		ID:       primitive.NewObjectID(),
-		Name:     name,
-		Color:    color,
-		Stations: stationArr,
-		Schemas:  schemaArr,
-		Users:    userArr,
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		Color:     color,
+		Stations:  stationArr,
+		Schemas:   schemaArr,
+		Users:     userArr,
+		KvBuckets: kvBucketArr,
 	}
 
 	filter := bson.M{"name": newTag.Name}
 	update := bson.M{
 		"$setOnInsert": bson.M{
-			"_id":      newTag.ID,
-			"name":     newTag.Name,
-			"color":    newTag.Color,
-			"stations": newTag.Stations,
-			"schemas":  newTag.Schemas,
-			"users":    newTag.Users,
+			"_id":        newTag.ID,
+			"name":       newTag.Name,
+			"color":      newTag.Color,
+			"stations":   newTag.Stations,
+			"schemas":    newTag.Schemas,
+			"users":      newTag.Users,
+			"kv_buckets": newTag.KvBuckets,
 		},
 	}
 	opts := options.Update().SetUpsert(true)
@@ -108,6 +113,8 @@ func AddTagsToEntity(tags []models.CreateTag, entity_type string, entity_id prim
 				entityDBList = "schemas"
 			case "user":
 				entityDBList = "users"
+			case "kv_bucket":
+				entityDBList = "kv_buckets"
 			}
 			filter := bson.M{"name": tagToCreate.Name}
 			update := bson.M{
@@ -149,24 +156,46 @@ func DeleteTagsFromUser(id primitive.ObjectID) {
 	}
 }
 
+func DeleteTagsFromKvBucket(id primitive.ObjectID) {
+	_, err := tagsCollection.UpdateMany(context.TODO(), bson.M{}, bson.M{"$pull": bson.M{"kv_buckets": id}})
+	if err != nil {
+		serv.Errorf("DeleteTagsFromKvBucket: KV bucket ID " + id.Hex() + ": " + err.Error())
+		return
+	}
+}
+
 func (th TagsHandler) CreateNewTag(c *gin.Context) {
 	var body models.CreateTag
 	ok := utils.Validate(c, &body, false, nil)
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateNewTag: Tag " + body.Name + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	newTag, err := th.createNewTag(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.IndentedJSON(200, newTag)
+}
+
+func (th TagsHandler) createNewTag(user models.User, body models.CreateTag) (models.Tag, error) {
 	name := strings.ToLower(body.Name)
 	exist, _, err := IsTagExist(name)
 	if err != nil {
 		serv.Errorf("CreateNewTag: Tag " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.Tag{}, err
 	}
 	if exist {
 		errMsg := "Tag with the name " + body.Name + " already exists"
 		serv.Warnf("CreateNewTag: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return models.Tag{}, newShowableError(errMsg)
 	}
 	var color string
 	if len(body.Color) > 0 {
@@ -178,43 +207,39 @@ func (th TagsHandler) CreateNewTag(c *gin.Context) {
 	stationArr := []primitive.ObjectID{}
 	schemaArr := []primitive.ObjectID{}
 	userArr := []primitive.ObjectID{}
+	kvBucketArr := []primitive.ObjectID{}
 	newTag = models.Tag{
-		ID:       primitive.NewObjectID(),
-		Name:     name,
-		Color:    color,
-		Stations: stationArr,
-		Schemas:  schemaArr,
-		Users:    userArr,
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		Color:     color,
+		Stations:  stationArr,
+		Schemas:   schemaArr,
+		Users:     userArr,
+		KvBuckets: kvBucketArr,
 	}
 
 	filter := bson.M{"name": newTag.Name}
 	update := bson.M{
 		"$setOnInsert": bson.M{
-			"_id":      newTag.ID,
-			"name":     newTag.Name,
-			"color":    newTag.Color,
-			"stations": newTag.Stations,
-			"schemas":  newTag.Schemas,
-			"users":    newTag.Users,
+			"_id":        newTag.ID,
+			"name":       newTag.Name,
+			"color":      newTag.Color,
+			"stations":   newTag.Stations,
+			"schemas":    newTag.Schemas,
+			"users":      newTag.Users,
+			"kv_buckets": newTag.KvBuckets,
 		},
 	}
 	opts := options.Update().SetUpsert(true)
 	_, err = tagsCollection.UpdateOne(context.TODO(), filter, update, opts)
 	if err != nil {
 		serv.Errorf("CreateNewTag: Tag " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return models.Tag{}, err
 	}
 
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("CreateNewTag: Tag " + body.Name + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-	}
 	message := "New Tag " + newTag.Name + " has been created " + " by user " + user.Username
 	serv.Noticef(message)
-
-	c.IndentedJSON(200, newTag)
+	return newTag, nil
 }
 
 func (th TagsHandler) RemoveTag(c *gin.Context) {
@@ -249,7 +274,7 @@ func (th TagsHandler) RemoveTag(c *gin.Context) {
 			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
 			return
 		}
-		exist, station, err := IsStationExist(station_name)
+		exist, station, err := IsStationExist(station_name, getTenantNameFromMiddleware(c))
 		if err != nil {
 			serv.Errorf("RemoveTag: Tag " + body.Name + ": " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -265,7 +290,7 @@ func (th TagsHandler) RemoveTag(c *gin.Context) {
 		message = "Tag " + name + " has been deleted from station " + stationName + " by user " + user.Username
 
 	case "schema":
-		exist, schema, err := IsSchemaExist(body.EntityName)
+		exist, schema, err := IsSchemaExist(body.EntityName, getTenantNameFromMiddleware(c))
 		if err != nil {
 			serv.Errorf("RemoveTag: Tag " + body.Name + ": " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -279,6 +304,21 @@ func (th TagsHandler) RemoveTag(c *gin.Context) {
 		entityDBList = "schemas"
 		message = "Tag " + name + " has been deleted from schema" + schema.Name + " by user " + user.Username
 
+	case "kv_bucket":
+		exist, bucket, err := IsKvBucketExist(strings.ToLower(body.EntityName), getTenantNameFromMiddleware(c))
+		if err != nil {
+			serv.Errorf("RemoveTag: Tag " + body.Name + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if !exist {
+			c.IndentedJSON(200, []string{})
+			return
+		}
+		entity_id = bucket.ID
+		entityDBList = "kv_buckets"
+		message = "Tag " + name + " has been deleted from KV bucket " + bucket.Name + " by user " + user.Username
+
 	// case "user":
 	// 	exist, user, err := IsUserExist(body.EntityName)
 	// 	if err != nil {
@@ -333,54 +373,78 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 	if !ok {
 		return
 	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	tags, err := th.updateTagsForEntity(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.IndentedJSON(200, tags)
+}
+
+func (th TagsHandler) updateTagsForEntity(user models.User, body models.UpdateTagsForEntitySchema) ([]models.CreateTag, error) {
+	tenantName := getUserTenantName(user)
 	var entityDBList string
 	entity := strings.ToLower(body.EntityType)
 	err := validateEntityType(entity)
 	var entity_id primitive.ObjectID
 	if err != nil {
 		serv.Warnf("UpdateTagsForEntity: " + entity + " " + body.EntityName + ": " + err.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-		return
+		return nil, newShowableError(err.Error())
 	}
 	var stationName StationName
 	var schemaName string
+	var kvBucketName string
 	switch entity {
 	case "station":
 		station_name, err := StationNameFromStr(body.EntityName)
 		if err != nil {
 			serv.Warnf("UpdateTagsForEntity: " + entity + " " + body.EntityName + ": " + err.Error())
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
-			return
+			return nil, newShowableError(err.Error())
 		}
-		exist, station, err := IsStationExist(station_name)
+		exist, station, err := IsStationExist(station_name, tenantName)
 		if err != nil {
 			serv.Errorf("UpdateTagsForEntity: Station " + body.EntityName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
 		}
 		if !exist {
-			c.IndentedJSON(200, []string{})
-			return
+			return []models.CreateTag{}, nil
 		}
 		entity_id = station.ID
 		entityDBList = "stations"
 		stationName = station_name
 
 	case "schema":
-		exist, schema, err := IsSchemaExist(body.EntityName)
+		exist, schema, err := IsSchemaExist(body.EntityName, tenantName)
 		if err != nil {
 			serv.Errorf("UpdateTagsForEntity: Schema " + body.EntityName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
 		}
 		if !exist {
-			c.IndentedJSON(200, []string{})
-			return
+			return []models.CreateTag{}, nil
 		}
 		entity_id = schema.ID
 		entityDBList = "schemas"
 		schemaName = schema.Name
 
+	case "kv_bucket":
+		exist, bucket, err := IsKvBucketExist(strings.ToLower(body.EntityName), tenantName)
+		if err != nil {
+			serv.Errorf("UpdateTagsForEntity: KV bucket " + body.EntityName + ": " + err.Error())
+			return nil, err
+		}
+		if !exist {
+			return []models.CreateTag{}, nil
+		}
+		entity_id = bucket.ID
+		entityDBList = "kv_buckets"
+		kvBucketName = bucket.Name
+
 	// case "user":
 	// 	exist, user, err := IsUserExist(body.EntityName)
 	// 	if err != nil {
@@ -397,15 +461,9 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 
 	default:
 		serv.Warnf("UpdateTagsForEntity: " + entity + " " + body.EntityName + ": unsupported entity type")
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Could not remove tags, unsupported entity type"})
-		return
+		return nil, newShowableError("Could not remove tags, unsupported entity type")
 	}
 	var message string
-	user, err := getUserDetailsFromMiddleware(c)
-	if err != nil {
-		serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
-		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
-	}
 
 	if len(body.TagsToAdd) > 0 {
 		for _, tagToAdd := range body.TagsToAdd {
@@ -413,22 +471,19 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 			exist, tag, err := IsTagExist(name)
 			if err != nil {
 				serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
-				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-				return
+				return nil, err
 			}
 			if !exist {
 				err = CreateTag(name, body.EntityType, entity_id, tagToAdd.Color)
 				if err != nil {
 					serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
-					c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-					return
+					return nil, err
 				}
 			} else {
 				_, err = tagsCollection.UpdateOne(context.TODO(), bson.M{"_id": tag.ID}, bson.M{"$addToSet": bson.M{entityDBList: entity_id}})
 				if err != nil {
 					serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
-					c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-					return
+					return nil, err
 				}
 			}
 
@@ -456,6 +511,9 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 			} else if entity == "schema" {
 				message = "Tag " + name + " has been added to schema " + schemaName + " by user " + user.Username
 				analyticsEventName = "user-tag-schema"
+			} else if entity == "kv_bucket" {
+				message = "Tag " + name + " has been added to KV bucket " + kvBucketName + " by user " + user.Username
+				analyticsEventName = "user-tag-kv-bucket"
 			} else {
 				message = "Tag " + name + " has been added to user " + "by user " + user.Username
 				analyticsEventName = "user-tag-user"
@@ -480,16 +538,14 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 			exist, tag, err := IsTagExist(name)
 			if err != nil {
 				serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
-				c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-				return
+				return nil, err
 			}
 			if exist {
 				_, err = tagsCollection.UpdateOne(context.TODO(), bson.M{"_id": tag.ID},
 					bson.M{"$pull": bson.M{entityDBList: entity_id}})
 				if err != nil {
 					serv.Errorf("UpdateTagsForEntity: " + body.EntityType + " " + body.EntityName + ": " + err.Error())
-					c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-					return
+					return nil, err
 				}
 			}
 			if entity == "station" {
@@ -512,6 +568,8 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 				}
 			} else if entity == "schema" {
 				message = "Tag " + name + " has been deleted from schema " + schemaName + " by user " + user.Username
+			} else if entity == "kv_bucket" {
+				message = "Tag " + name + " has been deleted from KV bucket " + kvBucketName + " by user " + user.Username
 			} else {
 				message = "Tag " + name + " has been deleted " + "by user " + user.Username
 
@@ -525,25 +583,28 @@ func (th TagsHandler) UpdateTagsForEntity(c *gin.Context) {
 		tags, err = th.GetTagsByStation(entity_id)
 		if err != nil {
 			serv.Errorf("UpdateTagsForEntity: Station " + body.EntityName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
 		}
 	case "schema":
 		tags, err = th.GetTagsBySchema(entity_id)
 		if err != nil {
 			serv.Errorf("UpdateTagsForEntity: Schema " + body.EntityName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
 		}
 	case "user":
 		tags, err = th.GetTagsByUser(entity_id)
 		if err != nil {
 			serv.Errorf("UpdateTagsForEntity: User " + body.EntityName + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
+		}
+	case "kv_bucket":
+		tags, err = th.GetTagsByKvBucket(entity_id)
+		if err != nil {
+			serv.Errorf("UpdateTagsForEntity: KV bucket " + body.EntityName + ": " + err.Error())
+			return nil, err
 		}
 	}
-	c.IndentedJSON(200, tags)
+	return tags, nil
 }
 
 func (th TagsHandler) GetTagsByStation(station_id primitive.ObjectID) ([]models.CreateTag, error) {
@@ -615,6 +676,29 @@ func (th TagsHandler) GetTagsByUser(user_id primitive.ObjectID) ([]models.Create
 	return tagsRes, nil
 }
 
+func (th TagsHandler) GetTagsByKvBucket(bucket_id primitive.ObjectID) ([]models.CreateTag, error) {
+	var tags []models.Tag
+	var tagsRes []models.CreateTag
+	cursor, err := tagsCollection.Find(context.TODO(), bson.M{"kv_buckets": bucket_id})
+	if err != nil {
+		return tagsRes, err
+	}
+	if err = cursor.All(context.TODO(), &tags); err != nil {
+		return tagsRes, err
+	}
+	if len(tags) == 0 {
+		tagsRes = []models.CreateTag{}
+	}
+	for _, tag := range tags {
+		tagRes := models.CreateTag{
+			Name:  tag.Name,
+			Color: tag.Color,
+		}
+		tagsRes = append(tagsRes, tagRes)
+	}
+	return tagsRes, nil
+}
+
 func (th TagsHandler) GetTags(c *gin.Context) {
 	var body models.GetTagsSchema
 	ok := utils.Validate(c, &body, false, nil)
@@ -666,6 +750,19 @@ func (th TagsHandler) GetTags(c *gin.Context) {
 			return
 		}
 
+		if err = cursor.All(context.TODO(), &tags); err != nil {
+			serv.Errorf("GetTags: " + body.EntityType + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+	case "kv_bucket":
+		cursor, err := tagsCollection.Find(context.TODO(), bson.M{"kv_buckets": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}})
+		if err != nil {
+			serv.Errorf("GetTags: " + body.EntityType + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+
 		if err = cursor.All(context.TODO(), &tags); err != nil {
 			serv.Errorf("GetTags: " + body.EntityType + ": " + err.Error())
 			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -701,7 +798,7 @@ func (th TagsHandler) GetTags(c *gin.Context) {
 func (th TagsHandler) GetUsedTags(c *gin.Context) {
 	var tags []models.Tag
 	var tagsRes []models.CreateTag
-	filter := bson.M{"$or": []interface{}{bson.M{"schemas": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}, bson.M{"stations": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}, bson.M{"users": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}}}
+	filter := bson.M{"$or": []interface{}{bson.M{"schemas": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}, bson.M{"stations": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}, bson.M{"users": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}, bson.M{"kv_buckets": bson.M{"$exists": true, "$not": bson.M{"$size": 0}}}}}
 	cursor, err := tagsCollection.Find(context.TODO(), filter)
 	if err != nil {
 		serv.Errorf("GetUsedTags: " + err.Error())
@@ -0,0 +1,497 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"memphis-broker/analytics"
+	"memphis-broker/models"
+	"memphis-broker/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TenantsHandler struct{ S *Server }
+
+const (
+	globalTenantName      = "global"
+	tenantObjectName      = "Tenant"
+	tenantsUpdatesTimeout = 5 * time.Second
+	tenantActionPut       = "put"
+	tenantActionRemove    = "remove"
+)
+
+// the global tenant is served by the NATS global account, every other tenant gets an account named after it
+func tenantNameFromAccount(acc *Account) string {
+	if acc == nil || acc.GetName() == globalAccountName {
+		return globalTenantName
+	}
+	return acc.GetName()
+}
+
+func getUserTenantName(user models.User) string {
+	if user.TenantName == _EMPTY_ {
+		return globalTenantName
+	}
+	return user.TenantName
+}
+
+// getTenantNameFromMiddleware returns the tenant of the user behind a REST request
+func getTenantNameFromMiddleware(c *gin.Context) string {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		return globalTenantName
+	}
+	return getUserTenantName(user)
+}
+
+func (s *Server) getTenantAccount(tenantName string) (*Account, error) {
+	if tenantName == _EMPTY_ || tenantName == globalTenantName {
+		return s.GlobalAccount(), nil
+	}
+	return s.LookupAccount(tenantName)
+}
+
+func getTenantJsLimits(limits models.TenantLimits) map[string]JetStreamAccountLimits {
+	jsLimits := JetStreamAccountLimits{
+		MaxMemory:     -1,
+		MaxStore:      -1,
+		MaxStreams:    -1,
+		MaxConsumers:  -1,
+		MaxAckPending: -1,
+	}
+	if limits.MaxMemory > 0 {
+		jsLimits.MaxMemory = limits.MaxMemory
+	}
+	if limits.MaxStorage > 0 {
+		jsLimits.MaxStore = limits.MaxStorage
+	}
+	if limits.MaxStreams > 0 {
+		jsLimits.MaxStreams = limits.MaxStreams
+	}
+	if limits.MaxConsumers > 0 {
+		jsLimits.MaxConsumers = limits.MaxConsumers
+	}
+	return map[string]JetStreamAccountLimits{_EMPTY_: jsLimits}
+}
+
+func validateTenantLimits(limits models.TenantLimits) error {
+	if limits.MaxMemory < 0 || limits.MaxStorage < 0 || limits.MaxStreams < 0 || limits.MaxConsumers < 0 {
+		return errors.New("tenant limits can not be negative, use 0 for unlimited")
+	}
+	return nil
+}
+
+// registerTenantAccount creates the NATS account of the tenant (or updates its JetStream limits)
+// and starts the Memphis listeners inside it
+func (s *Server) registerTenantAccount(tenant models.Tenant) (*Account, error) {
+	acc, isNew := s.LookupOrRegisterAccount(tenant.Name)
+	limits := getTenantJsLimits(tenant.Limits)
+	if acc.JetStreamEnabled() {
+		return acc, acc.UpdateJetStreamLimits(limits)
+	}
+
+	err := acc.EnableJetStream(limits)
+	if err != nil {
+		return acc, err
+	}
+
+	if isNew {
+		s.initializeSDKHandlers(acc)
+		s.ListenForPoisonMessages(acc)
+		if err = s.ListenForPoisonMsgAcks(acc); err != nil {
+			return acc, err
+		}
+		if err = s.ListenForMessageJourneyEvents(acc); err != nil {
+			return acc, err
+		}
+	}
+	return acc, nil
+}
+
+// broadcastTenantUpdate applies a tenant change on every broker of the cluster,
+// tenants created while a broker is down are registered by it on startup from the db
+func (s *Server) broadcastTenantUpdate(action string, tenant models.Tenant) error {
+	msg, err := json.Marshal(models.TenantUpdate{Action: action, Tenant: tenant})
+	if err != nil {
+		return err
+	}
+	return s.broadcastToBrokers(TENANTS_UPDATES_SUBJ, msg, tenantsUpdatesTimeout)
+}
+
+func (s *Server) applyTenantUpdate(update models.TenantUpdate) error {
+	if update.Tenant.Name == _EMPTY_ || update.Tenant.Name == globalTenantName {
+		return errors.New("tenant update of the global tenant")
+	}
+	switch update.Action {
+	case tenantActionPut:
+		_, err := s.registerTenantAccount(update.Tenant)
+		return err
+	case tenantActionRemove:
+		// the account itself stays registered until the next restart, its JetStream resources are released now
+		acc, err := s.getTenantAccount(update.Tenant.Name)
+		if err != nil {
+			return nil
+		}
+		if acc.JetStreamEnabled() {
+			return acc.DisableJetStream()
+		}
+		return nil
+	default:
+		return errors.New("unknown tenant update action " + update.Action)
+	}
+}
+
+func (s *Server) initializeTenants() error {
+	// resources which were created before multi tenancy belong to the global tenant
+	missingTenantFilter := bson.M{"tenant_name": bson.M{"$exists": false}}
+	setGlobalTenant := bson.M{"$set": bson.M{"tenant_name": globalTenantName}}
+	for _, collection := range []*mongo.Collection{usersCollection, stationsCollection, schemasCollection} {
+		_, err := collection.UpdateMany(context.TODO(), missingTenantFilter, setGlobalTenant)
+		if err != nil {
+			return err
+		}
+	}
+
+	var tenants []models.Tenant
+	cursor, err := tenantsCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(context.TODO(), &tenants); err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		_, err = s.registerTenantAccount(tenant)
+		if err != nil {
+			s.Errorf("initializeTenants: Tenant " + tenant.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func IsTenantExist(tenantName string) (bool, models.Tenant, error) {
+	var tenant models.Tenant
+	if tenantName == globalTenantName {
+		return true, models.Tenant{Name: globalTenantName}, nil
+	}
+	err := tenantsCollection.FindOne(context.TODO(), bson.M{"name": tenantName}).Decode(&tenant)
+	if err == mongo.ErrNoDocuments {
+		return false, tenant, nil
+	} else if err != nil {
+		return false, tenant, err
+	}
+	return true, tenant, nil
+}
+
+// getAllTenantNames returns the global tenant followed by the tenants stored in the db
+func getAllTenantNames() ([]string, error) {
+	var tenants []models.Tenant
+	cursor, err := tenantsCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &tenants); err != nil {
+		return nil, err
+	}
+
+	tenantNames := []string{globalTenantName}
+	for _, tenant := range tenants {
+		tenantNames = append(tenantNames, tenant.Name)
+	}
+	return tenantNames, nil
+}
+
+func (s *Server) getTenantUsage(tenantName string) models.TenantUsage {
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return models.TenantUsage{}
+	}
+	stats := acc.JetStreamUsage()
+	return models.TenantUsage{
+		Memory:    stats.Memory,
+		Storage:   stats.Store,
+		Streams:   stats.Streams,
+		Consumers: stats.Consumers,
+	}
+}
+
+func (th TenantsHandler) CreateTenant(c *gin.Context) {
+	var body models.CreateTenantSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, ok := validateRootUser(c)
+	if !ok {
+		return
+	}
+
+	tenantName := strings.ToLower(body.Name)
+	err := validateName(tenantName, tenantObjectName)
+	if err != nil {
+		serv.Warnf("CreateTenant: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	err = validateTenantLimits(body.Limits)
+	if err != nil {
+		serv.Warnf("CreateTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, _, err := IsTenantExist(tenantName)
+	if err != nil {
+		serv.Errorf("CreateTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if exist {
+		errMsg := "Tenant " + tenantName + " already exists"
+		serv.Warnf("CreateTenant: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	newTenant := models.Tenant{
+		ID:            primitive.NewObjectID(),
+		Name:          tenantName,
+		CreatedByUser: user.Username,
+		CreationDate:  time.Now(),
+		Limits:        body.Limits,
+	}
+
+	err = th.S.broadcastTenantUpdate(tenantActionPut, newTenant)
+	if err != nil {
+		serv.Errorf("CreateTenant: Tenant " + tenantName + ": " + err.Error())
+		th.S.broadcastTenantUpdate(tenantActionRemove, newTenant)
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	_, err = tenantsCollection.InsertOne(context.TODO(), newTenant)
+	if err != nil {
+		serv.Errorf("CreateTenant: Tenant " + tenantName + ": " + err.Error())
+		th.S.broadcastTenantUpdate(tenantActionRemove, newTenant)
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	serv.Noticef("Tenant " + tenantName + " has been created by user " + user.Username)
+
+	shouldSendAnalytics, _ := shouldSendAnalytics()
+	if shouldSendAnalytics {
+		analytics.SendEvent(user.Username, "user-create-tenant")
+	}
+
+	c.IndentedJSON(200, newTenant)
+}
+
+func (th TenantsHandler) GetAllTenants(c *gin.Context) {
+	if _, ok := validateRootUser(c); !ok {
+		return
+	}
+
+	var tenants []models.Tenant
+	cursor, err := tenantsCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		serv.Errorf("GetAllTenants: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if err = cursor.All(context.TODO(), &tenants); err != nil {
+		serv.Errorf("GetAllTenants: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	tenants = append([]models.Tenant{{Name: globalTenantName}}, tenants...)
+
+	extTenants := make([]models.ExtendedTenant, 0, len(tenants))
+	for _, tenant := range tenants {
+		stationsCount, err := stationsCollection.CountDocuments(context.TODO(), bson.M{
+			"tenant_name": tenant.Name,
+			"$or": []interface{}{
+				bson.M{"is_deleted": false},
+				bson.M{"is_deleted": bson.M{"$exists": false}},
+			},
+		})
+		if err != nil {
+			serv.Errorf("GetAllTenants: Tenant " + tenant.Name + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		usersCount, err := usersCollection.CountDocuments(context.TODO(), bson.M{"tenant_name": tenant.Name})
+		if err != nil {
+			serv.Errorf("GetAllTenants: Tenant " + tenant.Name + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+
+		extTenants = append(extTenants, models.ExtendedTenant{
+			ID:            tenant.ID,
+			Name:          tenant.Name,
+			CreatedByUser: tenant.CreatedByUser,
+			CreationDate:  tenant.CreationDate,
+			Limits:        tenant.Limits,
+			Usage:         th.S.getTenantUsage(tenant.Name),
+			StationsCount: int(stationsCount),
+			UsersCount:    int(usersCount),
+		})
+	}
+
+	c.IndentedJSON(200, extTenants)
+}
+
+func (th TenantsHandler) UpdateTenantLimits(c *gin.Context) {
+	var body models.UpdateTenantLimitsSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, ok := validateRootUser(c)
+	if !ok {
+		return
+	}
+
+	tenantName := strings.ToLower(body.Name)
+	if tenantName == globalTenantName {
+		errMsg := "The limits of the global tenant are taken from the server configuration"
+		serv.Warnf("UpdateTenantLimits: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	err := validateTenantLimits(body.Limits)
+	if err != nil {
+		serv.Warnf("UpdateTenantLimits: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, tenant, err := IsTenantExist(tenantName)
+	if err != nil {
+		serv.Errorf("UpdateTenantLimits: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Tenant " + tenantName + " does not exist"
+		serv.Warnf("UpdateTenantLimits: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	tenant.Limits = body.Limits
+	err = th.S.broadcastTenantUpdate(tenantActionPut, tenant)
+	if err != nil {
+		serv.Warnf("UpdateTenantLimits: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	_, err = tenantsCollection.UpdateOne(context.TODO(), bson.M{"name": tenantName}, bson.M{"$set": bson.M{"limits": tenant.Limits}})
+	if err != nil {
+		serv.Errorf("UpdateTenantLimits: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	serv.Noticef("The limits of tenant " + tenantName + " have been updated by user " + user.Username)
+
+	c.IndentedJSON(200, tenant)
+}
+
+func (th TenantsHandler) RemoveTenant(c *gin.Context) {
+	var body models.RemoveTenantSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, ok := validateRootUser(c)
+	if !ok {
+		return
+	}
+
+	tenantName := strings.ToLower(body.Name)
+	if tenantName == globalTenantName {
+		errMsg := "The global tenant can not be removed"
+		serv.Warnf("RemoveTenant: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	exist, tenant, err := IsTenantExist(tenantName)
+	if err != nil {
+		serv.Errorf("RemoveTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist {
+		errMsg := "Tenant " + tenantName + " does not exist"
+		serv.Warnf("RemoveTenant: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	stationsCount, err := stationsCollection.CountDocuments(context.TODO(), bson.M{
+		"tenant_name": tenantName,
+		"$or": []interface{}{
+			bson.M{"is_deleted": false},
+			bson.M{"is_deleted": bson.M{"$exists": false}},
+		},
+	})
+	if err != nil {
+		serv.Errorf("RemoveTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	usersCount, err := usersCollection.CountDocuments(context.TODO(), bson.M{"tenant_name": tenantName})
+	if err != nil {
+		serv.Errorf("RemoveTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	schemasCount, err := schemasCollection.CountDocuments(context.TODO(), bson.M{"tenant_name": tenantName})
+	if err != nil {
+		serv.Errorf("RemoveTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if stationsCount > 0 || usersCount > 0 || schemasCount > 0 {
+		errMsg := "Tenant " + tenantName + " still has stations, schemas or users, remove them first"
+		serv.Warnf("RemoveTenant: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	_, err = tenantsCollection.DeleteOne(context.TODO(), bson.M{"name": tenantName})
+	if err != nil {
+		serv.Errorf("RemoveTenant: Tenant " + tenantName + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	err = th.S.broadcastTenantUpdate(tenantActionRemove, tenant)
+	if err != nil {
+		// the tenant is already gone from the db, brokers which missed the update drop it on their next restart
+		serv.Errorf("RemoveTenant: Tenant " + tenantName + ": " + err.Error())
+	}
+	serv.Noticef("Tenant " + tenantName + " has been removed by user " + user.Username)
+
+	c.IndentedJSON(200, gin.H{})
+}
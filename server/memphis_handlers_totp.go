@@ -0,0 +1,549 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"memphis-broker/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer              = "Memphis"
+	totpDigits              = 6
+	totpPeriodSec           = 30
+	totpSkewSteps           = 1
+	totpSecretSize          = 20
+	totpRecoveryCodesAmount = 10
+	totpRecoveryCodeSize    = 5
+	enforceTotpSystemKey    = "enforce_totp"
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTotpSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return _EMPTY_, err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// getTotpProvisioningUri returns the otpauth URI authenticator apps scan as a QR code
+func getTotpProvisioningUri(username, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(totpDigits))
+	params.Set("period", strconv.Itoa(totpPeriodSec))
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+username) + "?" + params.Encode()
+}
+
+// getTotpCode computes the RFC 6238 code of a secret for a given time step
+func getTotpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return _EMPTY_, err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod), nil
+}
+
+// validateTotpCode returns the time step a code belongs to, steps up to lastStep were already used and are rejected
+func validateTotpCode(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", _EMPTY_)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriodSec
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := getTotpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(code)), "-", _EMPTY_)
+}
+
+// generateRecoveryCodes returns the codes to show the user once and their hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, totpRecoveryCodesAmount)
+	hashes := make([]string, 0, totpRecoveryCodesAmount)
+	for i := 0; i < totpRecoveryCodesAmount; i++ {
+		raw := make([]byte, totpRecoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(raw))
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
+
+func matchRecoveryCode(hashes []string, code string) (string, bool) {
+	code = normalizeRecoveryCode(code)
+	if code == _EMPTY_ {
+		return _EMPTY_, false
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+			return hash, true
+		}
+	}
+	return _EMPTY_, false
+}
+
+func isTotpEnforced() (bool, error) {
+	var systemKey models.SystemKey
+	err := systemKeysCollection.FindOne(context.TODO(), bson.M{"key": enforceTotpSystemKey}).Decode(&systemKey)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	enforced, _ := strconv.ParseBool(systemKey.Value)
+	return enforced, nil
+}
+
+// verifyUserSecondFactor checks a TOTP or a recovery code of a user with 2FA enabled and burns it,
+// the conditional updates make sure a code can not be used twice even by concurrent logins
+func verifyUserSecondFactor(user models.User, code, recoveryCode string) (bool, error) {
+	if code != _EMPTY_ {
+		step, ok := validateTotpCode(user.TotpSecret, code, user.TotpLastStep, time.Now())
+		if !ok {
+			return false, nil
+		}
+		res, err := usersCollection.UpdateOne(context.TODO(),
+			bson.M{"_id": user.ID, "$or": []bson.M{{"totp_last_step": bson.M{"$lt": step}}, {"totp_last_step": bson.M{"$exists": false}}}},
+			bson.M{"$set": bson.M{"totp_last_step": step}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return res.MatchedCount == 1, nil
+	}
+
+	hash, ok := matchRecoveryCode(user.RecoveryCodes, recoveryCode)
+	if !ok {
+		return false, nil
+	}
+	res, err := usersCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID, "totp_recovery_codes": hash},
+		bson.M{"$pull": bson.M{"totp_recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func getTotpUserFromMiddleware(c *gin.Context, funcName string) (models.User, bool) {
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf(funcName + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return models.User{}, false
+	}
+	exist, dbUser, err := IsUserExist(user.Username)
+	if err != nil {
+		serv.Errorf(funcName + ": User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return models.User{}, false
+	}
+	if !exist || dbUser.UserType == "application" {
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return models.User{}, false
+	}
+	return dbUser, true
+}
+
+func (umh UserMgmtHandler) EnrollTotp(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	user, ok := getTotpUserFromMiddleware(c, "EnrollTotp")
+	if !ok {
+		return
+	}
+	if user.TotpEnabled {
+		errMsg := "Two factor authentication is already enabled"
+		serv.Warnf("EnrollTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	secret, err := generateTotpSecret()
+	if err != nil {
+		serv.Errorf("EnrollTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	_, err = usersCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"totp_secret": secret, "totp_last_step": 0}},
+	)
+	if err != nil {
+		serv.Errorf("EnrollTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{
+		"secret":           secret,
+		"provisioning_uri": getTotpProvisioningUri(user.Username, secret),
+	})
+}
+
+func (umh UserMgmtHandler) VerifyTotp(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.VerifyTotpSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, ok := getTotpUserFromMiddleware(c, "VerifyTotp")
+	if !ok {
+		return
+	}
+	if user.TotpEnabled {
+		errMsg := "Two factor authentication is already enabled"
+		serv.Warnf("VerifyTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if user.TotpSecret == _EMPTY_ {
+		errMsg := "Two factor authentication enrollment has not been started"
+		serv.Warnf("VerifyTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	step, valid := validateTotpCode(user.TotpSecret, body.Code, user.TotpLastStep, time.Now())
+	if !valid {
+		errMsg := "Invalid two factor authentication code"
+		serv.Warnf("VerifyTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		serv.Errorf("VerifyTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	res, err := usersCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID, "totp_secret": user.TotpSecret, "totp_enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"totp_enabled": true, "totp_recovery_codes": hashes, "totp_last_step": step}},
+	)
+	if err != nil {
+		serv.Errorf("VerifyTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if res.MatchedCount == 0 {
+		errMsg := "Two factor authentication enrollment has changed, please try again"
+		serv.Warnf("VerifyTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	// the current session proved the second factor, so it gets tokens that pass the refresh check
+	user.TotpEnabled = true
+	user.TotpVerified = true
+	token, refreshToken, err := CreateTokens(user)
+	if err != nil {
+		serv.Errorf("VerifyTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	domain := ""
+	secure := false
+	c.SetCookie("jwt-refresh-token", refreshToken, configuration.REFRESH_JWT_EXPIRES_IN_MINUTES*60*1000, "/", domain, secure, true)
+	c.IndentedJSON(200, gin.H{
+		"jwt":            token,
+		"expires_in":     configuration.JWT_EXPIRES_IN_MINUTES * 60 * 1000,
+		"totp_enabled":   true,
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+func (umh UserMgmtHandler) RegenerateTotpRecoveryCodes(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.VerifyTotpSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, ok := getTotpUserFromMiddleware(c, "RegenerateTotpRecoveryCodes")
+	if !ok {
+		return
+	}
+	if !user.TotpEnabled {
+		errMsg := "Two factor authentication is not enabled"
+		serv.Warnf("RegenerateTotpRecoveryCodes: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	verified, err := verifyUserSecondFactor(user, body.Code, _EMPTY_)
+	if err != nil {
+		serv.Errorf("RegenerateTotpRecoveryCodes: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !verified {
+		errMsg := "Invalid two factor authentication code"
+		serv.Warnf("RegenerateTotpRecoveryCodes: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		serv.Errorf("RegenerateTotpRecoveryCodes: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	_, err = usersCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"totp_recovery_codes": hashes}},
+	)
+	if err != nil {
+		serv.Errorf("RegenerateTotpRecoveryCodes: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{"recovery_codes": recoveryCodes})
+}
+
+func (umh UserMgmtHandler) DisableTotp(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.DisableTotpSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	user, ok := getTotpUserFromMiddleware(c, "DisableTotp")
+	if !ok {
+		return
+	}
+	if !user.TotpEnabled {
+		errMsg := "Two factor authentication is not enabled"
+		serv.Warnf("DisableTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	enforced, err := isTotpEnforced()
+	if err != nil {
+		serv.Errorf("DisableTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if enforced {
+		errMsg := "Two factor authentication is enforced and can not be disabled"
+		serv.Warnf("DisableTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	verified, err := verifyUserSecondFactor(user, body.Code, body.RecoveryCode)
+	if err != nil {
+		serv.Errorf("DisableTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !verified {
+		errMsg := "Invalid two factor authentication code"
+		serv.Warnf("DisableTotp: User " + user.Username + ": " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	err = resetUserTotp(user)
+	if err != nil {
+		serv.Errorf("DisableTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func resetUserTotp(user models.User) error {
+	_, err := usersCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": user.ID},
+		bson.M{
+			"$set":   bson.M{"totp_enabled": false},
+			"$unset": bson.M{"totp_secret": "", "totp_recovery_codes": "", "totp_last_step": ""},
+		},
+	)
+	return err
+}
+
+// validateTotpResetCaller allows only the root user and tenant admins to reset the second factor of other users,
+// and only from a session which has been verified with their own second factor
+func validateTotpResetCaller(user models.User) error {
+	isTenantAdmin := user.UserType == "management" && getUserTenantName(user) != globalTenantName
+	if user.UserType != "root" && !isTenantAdmin {
+		return errors.New("Only the root user or a tenant admin can reset two factor authentication")
+	}
+	if !user.TotpVerified {
+		return errors.New("You have to login with your own two factor authentication to reset the two factor authentication of other users")
+	}
+	return nil
+}
+
+func (umh UserMgmtHandler) ResetUserTotp(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.ResetUserTotpSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	username := strings.ToLower(body.Username)
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("ResetUserTotp: User " + body.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	if user.Username == username {
+		errMsg := "You can not reset your own two factor authentication"
+		serv.Warnf("ResetUserTotp: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if err = validateTotpResetCaller(user); err != nil {
+		serv.Warnf("ResetUserTotp: User " + user.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	exist, userToReset, err := IsUserExist(username)
+	if err != nil {
+		serv.Errorf("ResetUserTotp: User " + body.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist || (user.UserType != "root" && getUserTenantName(userToReset) != getUserTenantName(user)) {
+		serv.Warnf("ResetUserTotp: User does not exist")
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "User does not exist"})
+		return
+	}
+	if userToReset.UserType == "root" && user.UserType != "root" {
+		errMsg := "Reset root two factor authentication: This operation can be done only by the root user"
+		serv.Warnf("ResetUserTotp: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	err = resetUserTotp(userToReset)
+	if err != nil {
+		serv.Errorf("ResetUserTotp: User " + body.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	serv.Noticef("Two factor authentication of user " + username + " has been reset by " + user.Username)
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (umh UserMgmtHandler) EnforceTotp(c *gin.Context) {
+	if err := DenyForSandboxEnv(c); err != nil {
+		return
+	}
+	var body models.EnforceTotpSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+	if _, ok := validateRootUser(c); !ok {
+		return
+	}
+
+	_, err := systemKeysCollection.UpdateOne(context.TODO(),
+		bson.M{"key": enforceTotpSystemKey},
+		bson.M{"$set": bson.M{"value": strconv.FormatBool(body.Enforce)}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		serv.Errorf("EnforceTotp: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{"enforce": body.Enforce})
+}
+
+func (umh UserMgmtHandler) GetTotpEnforcement(c *gin.Context) {
+	enforced, err := isTotpEnforced()
+	if err != nil {
+		serv.Errorf("GetTotpEnforcement: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	c.IndentedJSON(200, gin.H{"enforce": enforced})
+}
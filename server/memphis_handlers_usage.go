@@ -0,0 +1,171 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"memphis-broker/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UsageHandler struct{ S *Server }
+
+func validateUsageGroupByAndFormat(groupBy, format *string) error {
+	if *groupBy == _EMPTY_ {
+		*groupBy = usageGroupByStation
+	}
+	if *groupBy != usageGroupByStation && *groupBy != usageGroupByTag && *groupBy != usageGroupByUser {
+		return errors.New("group by has to be one of station, tag or user")
+	}
+	if *format == _EMPTY_ {
+		*format = usageFormatJson
+	}
+	if *format != usageFormatJson && *format != usageFormatCsv {
+		return errors.New("format has to be one of json or csv")
+	}
+	return nil
+}
+
+func respondWithUsageReport(c *gin.Context, report models.UsageReport, format, fileName string) {
+	if format == usageFormatJson {
+		c.IndentedJSON(200, report)
+		return
+	}
+
+	data, err := usageReportToCsv(report.Rows)
+	if err != nil {
+		serv.Errorf("respondWithUsageReport: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", fileName))
+	c.Data(200, "text/csv", data)
+}
+
+func (uh UsageHandler) GetUsageReport(c *gin.Context) {
+	var body models.GetUsageReportSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	err := validateUsageGroupByAndFormat(&body.GroupBy, &body.Format)
+	if err != nil {
+		serv.Warnf("GetUsageReport: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+	from, err := time.Parse(usageDateLayout, body.From)
+	if err != nil {
+		errMsg := "From has to be a date in the format " + usageDateLayout
+		serv.Warnf("GetUsageReport: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	to, err := time.Parse(usageDateLayout, body.To)
+	if err != nil {
+		errMsg := "To has to be a date in the format " + usageDateLayout
+		serv.Warnf("GetUsageReport: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	// the report includes the whole last day
+	to = to.AddDate(0, 0, 1)
+	if !to.After(from) || to.Sub(from) > maxUsageReportDays*24*time.Hour {
+		errMsg := fmt.Sprintf("The report period has to be between 1 and %d days", maxUsageReportDays)
+		serv.Warnf("GetUsageReport: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+
+	stations, err := uh.S.getStationsUsageReport(getTenantNameFromMiddleware(c), from, to)
+	if err != nil {
+		serv.Errorf("GetUsageReport: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	report := models.UsageReport{
+		From:    from,
+		To:      to,
+		GroupBy: body.GroupBy,
+		Rows:    groupStationsUsage(stations, body.GroupBy),
+	}
+	respondWithUsageReport(c, report, body.Format, fmt.Sprintf("memphis-usage-%s-%s-by-%s", body.From, body.To, body.GroupBy))
+}
+
+func (uh UsageHandler) GetUsageSnapshots(c *gin.Context) {
+	var snapshots []models.UsageSnapshot
+	findOptions := options.Find().SetSort(bson.M{"from": -1}).SetProjection(bson.M{"stations": 0})
+	cursor, err := usageSnapshotsCollection.Find(context.TODO(), bson.M{"tenant_name": getTenantNameFromMiddleware(c)}, findOptions)
+	if err != nil {
+		serv.Errorf("GetUsageSnapshots: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if err = cursor.All(context.TODO(), &snapshots); err != nil {
+		serv.Errorf("GetUsageSnapshots: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if len(snapshots) == 0 {
+		snapshots = []models.UsageSnapshot{}
+	}
+
+	c.IndentedJSON(200, snapshots)
+}
+
+func (uh UsageHandler) GetUsageSnapshot(c *gin.Context) {
+	var body models.GetUsageSnapshotSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	err := validateUsageGroupByAndFormat(&body.GroupBy, &body.Format)
+	if err != nil {
+		serv.Warnf("GetUsageSnapshot: " + err.Error())
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": err.Error()})
+		return
+	}
+
+	var snapshot models.UsageSnapshot
+	filter := bson.M{"tenant_name": getTenantNameFromMiddleware(c), "period": body.Period}
+	err = usageSnapshotsCollection.FindOne(context.TODO(), filter).Decode(&snapshot)
+	if err == mongo.ErrNoDocuments {
+		errMsg := "Usage snapshot of " + body.Period + " does not exist"
+		serv.Warnf("GetUsageSnapshot: " + errMsg)
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
+		return
+	}
+	if err != nil {
+		serv.Errorf("GetUsageSnapshot: " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	report := models.UsageReport{
+		From:    snapshot.From,
+		To:      snapshot.To,
+		GroupBy: body.GroupBy,
+		Rows:    groupStationsUsage(snapshot.Stations, body.GroupBy),
+	}
+	respondWithUsageReport(c, report, body.Format, fmt.Sprintf("memphis-usage-%s-by-%s", snapshot.Period, body.GroupBy))
+}
@@ -239,6 +239,9 @@ func CreateTokens[U userToTokens](user U) (string, string, error) {
 		atClaims["creation_date"] = u.CreationDate
 		atClaims["already_logged_in"] = u.AlreadyLoggedIn
 		atClaims["avatar_id"] = u.AvatarId
+		atClaims["tenant_name"] = u.TenantName
+		atClaims["totp_verified"] = u.TotpVerified
+		atClaims["totp_enrollment_required"] = u.TotpEnrollmentRequired
 		atClaims["exp"] = time.Now().Add(time.Minute * time.Duration(configuration.JWT_EXPIRES_IN_MINUTES)).Unix()
 		at = jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
 	case models.SandboxUser:
@@ -248,6 +251,7 @@ func CreateTokens[U userToTokens](user U) (string, string, error) {
 		atClaims["creation_date"] = u.CreationDate
 		atClaims["already_logged_in"] = u.AlreadyLoggedIn
 		atClaims["avatar_id"] = u.AvatarId
+		atClaims["tenant_name"] = globalTenantName
 		atClaims["exp"] = time.Now().Add(time.Minute * time.Duration(configuration.JWT_EXPIRES_IN_MINUTES)).Unix()
 		at = jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
 	}
@@ -310,6 +314,7 @@ func CreateRootUserOnFirstSystemLoad() error {
 			HubUsername:     "",
 			HubPassword:     "",
 			UserType:        "root",
+			TenantName:      globalTenantName,
 			CreationDate:    time.Now(),
 			AlreadyLoggedIn: false,
 			AvatarId:        1,
@@ -412,6 +417,33 @@ func (umh UserMgmtHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if user.TotpEnabled {
+		if body.TotpCode == "" && body.RecoveryCode == "" {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Two factor authentication code is required", "totp_required": true})
+			return
+		}
+		verified, err := verifyUserSecondFactor(user, body.TotpCode, body.RecoveryCode)
+		if err != nil {
+			serv.Errorf("Login: User " + body.Username + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		if !verified {
+			serv.Warnf("Login: User " + body.Username + ": invalid two factor authentication code")
+			c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+			return
+		}
+		user.TotpVerified = true
+	} else {
+		enforced, err := isTotpEnforced()
+		if err != nil {
+			serv.Errorf("Login: User " + body.Username + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		user.TotpEnrollmentRequired = enforced
+	}
+
 	token, refreshToken, err := CreateTokens(user)
 	if err != nil {
 		serv.Errorf("Login: User " + body.Username + ": " + err.Error())
@@ -442,19 +474,21 @@ func (umh UserMgmtHandler) Login(c *gin.Context) {
 	secure := false
 	c.SetCookie("jwt-refresh-token", refreshToken, configuration.REFRESH_JWT_EXPIRES_IN_MINUTES*60*1000, "/", domain, secure, true)
 	c.IndentedJSON(200, gin.H{
-		"jwt":               token,
-		"expires_in":        configuration.JWT_EXPIRES_IN_MINUTES * 60 * 1000,
-		"user_id":           user.ID,
-		"username":          user.Username,
-		"user_type":         user.UserType,
-		"creation_date":     user.CreationDate,
-		"already_logged_in": user.AlreadyLoggedIn,
-		"avatar_id":         user.AvatarId,
-		"send_analytics":    shouldSendAnalytics,
-		"env":               env,
-		"namespace":         configuration.K8S_NAMESPACE,
-		"full_name":         user.FullName,
-		"skip_get_started":  user.SkipGetStarted,
+		"jwt":                      token,
+		"expires_in":               configuration.JWT_EXPIRES_IN_MINUTES * 60 * 1000,
+		"user_id":                  user.ID,
+		"username":                 user.Username,
+		"user_type":                user.UserType,
+		"creation_date":            user.CreationDate,
+		"already_logged_in":        user.AlreadyLoggedIn,
+		"avatar_id":                user.AvatarId,
+		"send_analytics":           shouldSendAnalytics,
+		"env":                      env,
+		"namespace":                configuration.K8S_NAMESPACE,
+		"full_name":                user.FullName,
+		"skip_get_started":         user.SkipGetStarted,
+		"totp_enabled":             user.TotpEnabled,
+		"totp_enrollment_required": user.TotpEnrollmentRequired,
 	})
 }
 
@@ -465,6 +499,7 @@ func (umh UserMgmtHandler) RefreshToken(c *gin.Context) {
 		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
 	}
 	username := user.Username
+	totpVerified := user.TotpVerified
 	exist, user, err := IsUserExist(username)
 	if err != nil {
 		serv.Errorf("RefreshToken: User " + username + ": " + err.Error())
@@ -518,6 +553,23 @@ func (umh UserMgmtHandler) RefreshToken(c *gin.Context) {
 	}
 	sendAnalytics, _ := strconv.ParseBool(systemKey.Value)
 
+	// a session opened before 2FA was enabled can not be refreshed, the user has to login again with a code
+	if user.TotpEnabled {
+		if !totpVerified {
+			c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+			return
+		}
+		user.TotpVerified = true
+	} else {
+		enforced, err := isTotpEnforced()
+		if err != nil {
+			serv.Errorf("RefreshToken: User " + username + ": " + err.Error())
+			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+			return
+		}
+		user.TotpEnrollmentRequired = enforced
+	}
+
 	token, refreshToken, err := CreateTokens(user)
 	if err != nil {
 		serv.Errorf("RefreshToken: User " + username + ": " + err.Error())
@@ -536,19 +588,21 @@ func (umh UserMgmtHandler) RefreshToken(c *gin.Context) {
 	secure := true
 	c.SetCookie("jwt-refresh-token", refreshToken, configuration.REFRESH_JWT_EXPIRES_IN_MINUTES*60*1000, "/", domain, secure, true)
 	c.IndentedJSON(200, gin.H{
-		"jwt":               token,
-		"expires_in":        configuration.JWT_EXPIRES_IN_MINUTES * 60 * 1000,
-		"user_id":           user.ID,
-		"username":          user.Username,
-		"user_type":         user.UserType,
-		"creation_date":     user.CreationDate,
-		"already_logged_in": user.AlreadyLoggedIn,
-		"avatar_id":         user.AvatarId,
-		"send_analytics":    sendAnalytics,
-		"env":               env,
-		"namespace":         configuration.K8S_NAMESPACE,
-		"full_name":         user.FullName,
-		"skip_get_started":  user.SkipGetStarted,
+		"jwt":                      token,
+		"expires_in":               configuration.JWT_EXPIRES_IN_MINUTES * 60 * 1000,
+		"user_id":                  user.ID,
+		"username":                 user.Username,
+		"user_type":                user.UserType,
+		"creation_date":            user.CreationDate,
+		"already_logged_in":        user.AlreadyLoggedIn,
+		"avatar_id":                user.AvatarId,
+		"send_analytics":           sendAnalytics,
+		"env":                      env,
+		"namespace":                configuration.K8S_NAMESPACE,
+		"full_name":                user.FullName,
+		"skip_get_started":         user.SkipGetStarted,
+		"totp_enabled":             user.TotpEnabled,
+		"totp_enrollment_required": user.TotpEnrollmentRequired,
 	})
 }
 
@@ -604,6 +658,7 @@ func (umh UserMgmtHandler) AddUserSignUp(c *gin.Context) {
 		FullName:        fullName,
 		Subscribtion:    subscription,
 		UserType:        "management",
+		TenantName:      globalTenantName,
 		CreationDate:    time.Now(),
 		AlreadyLoggedIn: false,
 		AvatarId:        1,
@@ -660,34 +715,64 @@ func (umh UserMgmtHandler) AddUser(c *gin.Context) {
 	if !ok {
 		return
 	}
+	creator, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	newUser, err := umh.addUser(creator, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
 
+	c.IndentedJSON(200, newUser)
+}
+
+func (umh UserMgmtHandler) addUser(creator models.User, body models.AddUserSchema) (gin.H, error) {
 	username := strings.ToLower(body.Username)
 	exist, _, err := IsUserExist(username)
 	if err != nil {
 		serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return nil, err
 	}
 	if exist {
 		errMsg := "A user with the name " + body.Username + " already exists"
 		serv.Warnf("CreateUser: " + errMsg)
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": errMsg})
-		return
+		return nil, newShowableError(errMsg)
 	}
 
 	userType := strings.ToLower(body.UserType)
 	userTypeError := validateUserType(userType)
 	if userTypeError != nil {
 		serv.Warnf("CreateUser: " + userTypeError.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": userTypeError.Error()})
-		return
+		return nil, newShowableError(userTypeError.Error())
 	}
 
 	usernameError := validateUsername(username)
 	if usernameError != nil {
 		serv.Warnf("CreateUser: " + usernameError.Error())
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": usernameError.Error()})
-		return
+		return nil, newShowableError(usernameError.Error())
+	}
+
+	tenantName := getUserTenantName(creator)
+	if body.TenantName != "" && body.TenantName != tenantName {
+		if creator.UserType != "root" {
+			serv.Warnf("CreateUser: Only root user can create users in another tenant")
+			return nil, newShowableError("Only root user can create users in another tenant")
+		}
+		exist, _, err := IsTenantExist(body.TenantName)
+		if err != nil {
+			serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
+			return nil, err
+		}
+		if !exist {
+			errMsg := "Tenant " + body.TenantName + " does not exist"
+			serv.Warnf("CreateUser: " + errMsg)
+			return nil, newShowableError(errMsg)
+		}
+		tenantName = body.TenantName
 	}
 
 	var hashedPwdString string
@@ -695,15 +780,13 @@ func (umh UserMgmtHandler) AddUser(c *gin.Context) {
 	if userType == "management" {
 		if body.Password == "" {
 			serv.Warnf("CreateUser: Password was not provided for user " + username)
-			c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Password was not provided"})
-			return
+			return nil, newShowableError("Password was not provided")
 		}
 
 		hashedPwd, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.MinCost)
 		if err != nil {
 			serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-			return
+			return nil, err
 		}
 		hashedPwdString = string(hashedPwd)
 
@@ -716,8 +799,7 @@ func (umh UserMgmtHandler) AddUser(c *gin.Context) {
 	err = validateHubCreds(body.HubUsername, body.HubPassword)
 	if err != nil {
 		serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-		return
+		return nil, newShowableErrorWithStatus(500, err.Error())
 	}
 
 	var brokerConnectionCreds string
@@ -725,8 +807,7 @@ func (umh UserMgmtHandler) AddUser(c *gin.Context) {
 		brokerConnectionCreds, err = AddUser(username)
 		if err != nil || len(username) == 0 {
 			serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
-			c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-			return
+			return nil, newShowableErrorWithStatus(500, err.Error())
 		}
 	}
 
@@ -737,6 +818,7 @@ func (umh UserMgmtHandler) AddUser(c *gin.Context) {
 		HubUsername:     body.HubUsername,
 		HubPassword:     body.HubPassword,
 		UserType:        userType,
+		TenantName:      tenantName,
 		CreationDate:    time.Now(),
 		AlreadyLoggedIn: false,
 		AvatarId:        avatarId,
@@ -745,28 +827,27 @@ func (umh UserMgmtHandler) AddUser(c *gin.Context) {
 	_, err = usersCollection.InsertOne(context.TODO(), newUser)
 	if err != nil || len(username) == 0 {
 		serv.Errorf("CreateUser: User " + body.Username + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return nil, err
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
 	if shouldSendAnalytics {
-		user, _ := getUserDetailsFromMiddleware(c)
-		analytics.SendEvent(user.Username, "user-add-user")
+		analytics.SendEvent(creator.Username, "user-add-user")
 	}
 
 	serv.Noticef("User " + username + " has been created")
-	c.IndentedJSON(200, gin.H{
+	return gin.H{
 		"id":                      newUser.ID,
 		"username":                username,
 		"hub_username":            body.HubUsername,
 		"hub_password":            body.HubPassword,
 		"user_type":               userType,
+		"tenant_name":             tenantName,
 		"creation_date":           newUser.CreationDate,
 		"already_logged_in":       false,
 		"avatar_id":               body.AvatarId,
 		"broker_connection_creds": brokerConnectionCreds,
-	})
+	}, nil
 }
 
 func (umh UserMgmtHandler) GetAllUsers(c *gin.Context) {
@@ -777,10 +858,11 @@ func (umh UserMgmtHandler) GetAllUsers(c *gin.Context) {
 		CreationDate    time.Time          `json:"creation_date" bson:"creation_date"`
 		AlreadyLoggedIn bool               `json:"already_logged_in" bson:"already_logged_in"`
 		AvatarId        int                `json:"avatar_id" bson:"avatar_id"`
+		TenantName      string             `json:"tenant_name" bson:"tenant_name"`
 	}
 	var users []filteredUser
 
-	cursor, err := usersCollection.Find(context.TODO(), bson.M{})
+	cursor, err := usersCollection.Find(context.TODO(), bson.M{"tenant_name": getTenantNameFromMiddleware(c)})
 	if err != nil {
 		serv.Errorf("GetAllUsers: " + err.Error())
 		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
@@ -809,48 +891,52 @@ func (umh UserMgmtHandler) RemoveUser(c *gin.Context) {
 	if !ok {
 		return
 	}
-
-	username := strings.ToLower(body.Username)
 	user, err := getUserDetailsFromMiddleware(c)
 	if err != nil {
 		serv.Errorf("RemoveUser: User " + body.Username + ": " + err.Error())
 		c.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+		return
+	}
+	err = umh.removeUser(user, body)
+	if err != nil {
+		abortWithError(c, err)
+		return
 	}
+
+	c.IndentedJSON(200, gin.H{})
+}
+
+func (umh UserMgmtHandler) removeUser(user models.User, body models.RemoveUserSchema) error {
+	username := strings.ToLower(body.Username)
 	if user.Username == username {
 		serv.Warnf("RemoveUser: You can not remove your own user")
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "You can not remove your own user"})
-		return
+		return newShowableError("You can not remove your own user")
 	}
 
 	exist, userToRemove, err := IsUserExist(username)
 	if err != nil {
 		serv.Errorf("RemoveUser: User " + body.Username + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
-	if !exist {
+	if !exist || (user.UserType != "root" && getUserTenantName(userToRemove) != getUserTenantName(user)) {
 		serv.Warnf("RemoveUser: User does not exist")
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "User does not exist"})
-		return
+		return newShowableError("User does not exist")
 	}
 	if userToRemove.UserType == "root" {
 		serv.Warnf("RemoveUser: You can not remove the root user")
-		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "You can not remove the root user"})
-		return
+		return newShowableError("You can not remove the root user")
 	}
 
 	err = updateUserResources(userToRemove)
 	if err != nil {
 		serv.Errorf("RemoveUser: User " + body.Username + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": err.Error()})
-		return
+		return newShowableErrorWithStatus(500, err.Error())
 	}
 
 	_, err = usersCollection.DeleteOne(context.TODO(), bson.M{"username": username})
 	if err != nil {
 		serv.Errorf("RemoveUser: User " + body.Username + ": " + err.Error())
-		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
-		return
+		return err
 	}
 
 	shouldSendAnalytics, _ := shouldSendAnalytics()
@@ -859,7 +945,7 @@ func (umh UserMgmtHandler) RemoveUser(c *gin.Context) {
 	}
 
 	serv.Noticef("User " + username + " has been deleted by user " + user.Username)
-	c.IndentedJSON(200, gin.H{})
+	return nil
 }
 
 func (umh UserMgmtHandler) RemoveMyUser(c *gin.Context) {
@@ -982,6 +1068,51 @@ func (umh UserMgmtHandler) EditAvatar(c *gin.Context) {
 	})
 }
 
+func (umh UserMgmtHandler) UpdateUserRateLimits(c *gin.Context) {
+	var body models.UpdateUserRateLimitsSchema
+	ok := utils.Validate(c, &body, false, nil)
+	if !ok {
+		return
+	}
+
+	username := strings.ToLower(body.Username)
+	exist, user, err := IsUserExist(username)
+	if err != nil {
+		serv.Errorf("UpdateUserRateLimits: User " + body.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+	if !exist || getUserTenantName(user) != getTenantNameFromMiddleware(c) {
+		serv.Warnf("UpdateUserRateLimits: User does not exist")
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "User does not exist"})
+		return
+	}
+	if user.UserType != "application" {
+		serv.Warnf("UpdateUserRateLimits: Rate limits can be set only for application users")
+		c.AbortWithStatusJSON(configuration.SHOWABLE_ERROR_STATUS_CODE, gin.H{"message": "Rate limits can be set only for application users"})
+		return
+	}
+
+	_, err = usersCollection.UpdateOne(context.TODO(),
+		bson.M{"username": username},
+		bson.M{"$set": bson.M{"rate_limits": body.RateLimits}},
+	)
+	if err != nil {
+		serv.Errorf("UpdateUserRateLimits: User " + body.Username + ": " + err.Error())
+		c.AbortWithStatusJSON(500, gin.H{"message": "Server error"})
+		return
+	}
+
+	setUserRateLimits(username, body.RateLimits)
+	err = broadcastRateLimitsUpdate("user_rate_limits", models.RateLimitsUpdate{Username: username, RateLimits: body.RateLimits})
+	if err != nil {
+		serv.Errorf("UpdateUserRateLimits: User " + body.Username + ": " + err.Error())
+	}
+
+	serv.Noticef("Rate limits of user " + username + " have been set to " + strconv.Itoa(body.RateLimits.MsgsPerSec) + " messages and " + strconv.Itoa(body.RateLimits.BytesPerSec) + " bytes per second")
+	c.IndentedJSON(200, body.RateLimits)
+}
+
 func (umh UserMgmtHandler) EditCompanyLogo(c *gin.Context) {
 	var file multipart.FileHeader
 	ok := utils.Validate(c, nil, true, &file)
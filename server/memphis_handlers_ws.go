@@ -18,6 +18,8 @@ import (
 	"memphis-broker/models"
 	"strings"
 	"time"
+
+	"github.com/dgrijalva/jwt-go"
 )
 
 const (
@@ -26,19 +28,40 @@ const (
 	memphisWS_Subj_Subs                 = "$memphis_ws_subs.>"
 	memphisWs_Cgroup_Subs               = "$memphis_ws_subs_cg"
 	memphisWS_TemplSubj_Publish         = "$memphis_ws_pubs.%s"
+	memphisWS_TemplSubj_PublishEvents   = "$memphis_ws_pubs_events.%s"
 	memphisWS_Subj_MainOverviewData     = "main_overview_data"
 	memphisWS_Subj_StationOverviewData  = "station_overview_data"
 	memphisWS_Subj_PoisonMsgJourneyData = "poison_message_journey_data"
 	memphisWS_Subj_AllStationsData      = "get_all_stations_data"
 	memphisWS_Subj_SysLogsData          = "syslogs_data"
 	memphisWS_Subj_AllSchemasData       = "get_all_schema_data"
+	memphisWS_Subj_Events               = "$memphis_ws_events"
+	memphisWS_Event_StationCreated      = "station_created"
+	memphisWS_Event_StationDeleted      = "station_deleted"
+	memphisWS_Event_CgMemberJoined      = "cg_member_joined"
+	memphisWS_Event_DlsMessageAdded     = "dls_message_added"
+	memphisWS_Event_ProducerDisconnect  = "producer_disconnected"
+	memphisWS_Event_SysLog              = "syslog"
+	memphisWS_SnapshotInterestTimeout   = 5 * time.Second
+	memphisWS_SnapshotRefreshInterval   = 30 * time.Second
 )
 
 type memphisWSReqFiller func() (any, error)
 
+// memphisWSSubscription is a view of the UI, its full data is sent on $memphis_ws_pubs as before
+// on subscribe, after an event made it stale and every memphisWS_SnapshotRefreshInterval for the
+// counters no event covers. The events themselves are sent on $memphis_ws_pubs_events
+type memphisWSSubscription struct {
+	tenantName   string
+	subj         string
+	reqFiller    memphisWSReqFiller
+	lastSnapshot time.Time
+	stale        bool
+}
+
 func (s *Server) initWS() {
 	ws := &s.memphis.ws
-	ws.subscriptions = make(map[string]memphisWSReqFiller)
+	ws.subscriptions = make(map[string]*memphisWSSubscription)
 	handlers := Handlers{
 		Producers:  ProducersHandler{S: s},
 		Consumers:  ConsumersHandler{S: s},
@@ -53,33 +76,37 @@ func (s *Server) initWS() {
 		memphisWs_Cgroup_Subs,
 		s.createWSRegistrationHandler(&handlers))
 
-	go memphisWSLoop(s, ws.subscriptions, ws.quitCh)
+	s.subscribeOnGlobalAcc(memphisWS_Subj_Events, memphisWS_Subj_Events+"_sid"+s.Name(), s.createWSEventHandler())
+	s.subscribeOnGlobalAcc(syslogsStreamName+".*."+syslogsExternalSubject, syslogsStreamName+"_ws_sid"+s.Name(), s.createWSSysLogHandler())
+
+	go memphisWSLoop(s, ws.quitCh)
 }
 
-func memphisWSLoop(s *Server, subs map[string]memphisWSReqFiller, quitCh chan struct{}) {
+// memphisWSLoop prunes subscriptions nobody listens to anymore and refreshes
+// the ones which are stale, throttled so the overview queries run only so often
+func memphisWSLoop(s *Server, quitCh chan struct{}) {
+	ws := &s.memphis.ws
 	ticker := time.NewTicker(5 * time.Second)
 	for {
 		select {
 		case <-ticker.C:
-			for k, updateFiller := range subs {
-				replySubj := fmt.Sprintf(memphisWS_TemplSubj_Publish, k+"."+configuration.SERVER_NAME)
-				if !s.GlobalAccount().SubscriptionInterest(replySubj) {
-					s.Debugf("removing memphis ws subscription %s", replySubj)
-					delete(subs, k)
-					continue
-				}
-				update, err := updateFiller()
-				if err != nil {
-					s.Errorf(err.Error())
+			now := time.Now()
+			refresh := make(map[string]memphisWSReqFiller)
+			ws.webSocketMu.Lock()
+			for k, sub := range ws.subscriptions {
+				if !s.memphisWSHasInterest(k) {
+					s.Debugf("removing memphis ws subscription %s", memphisWSReplySubj(k))
+					delete(ws.subscriptions, k)
 					continue
 				}
-				updateRaw, err := json.Marshal(update)
-				if err != nil {
-					s.Errorf(err.Error())
-					continue
+				if sub.stale || now.Sub(sub.lastSnapshot) >= memphisWS_SnapshotRefreshInterval {
+					sub.stale, sub.lastSnapshot = false, now
+					refresh[k] = sub.reqFiller
 				}
-
-				s.respondOnGlobalAcc(replySubj, updateRaw)
+			}
+			ws.webSocketMu.Unlock()
+			for k, reqFiller := range refresh {
+				s.memphisWSPublishSnapshot(k, reqFiller)
 			}
 		case <-quitCh:
 			ticker.Stop()
@@ -88,6 +115,159 @@ func memphisWSLoop(s *Server, subs map[string]memphisWSReqFiller, quitCh chan st
 	}
 }
 
+func memphisWSReplySubj(key string) string {
+	return fmt.Sprintf(memphisWS_TemplSubj_Publish, key+"."+configuration.SERVER_NAME)
+}
+
+func memphisWSEventsSubj(key string) string {
+	return fmt.Sprintf(memphisWS_TemplSubj_PublishEvents, key+"."+configuration.SERVER_NAME)
+}
+
+func (s *Server) memphisWSHasInterest(key string) bool {
+	return s.GlobalAccount().SubscriptionInterest(memphisWSReplySubj(key)) || s.GlobalAccount().SubscriptionInterest(memphisWSEventsSubj(key))
+}
+
+// memphisWSSendSnapshot waits for the UI to subscribe to the reply subject
+// (it does so only after getting the registration reply) and sends it the full data
+func (s *Server) memphisWSSendSnapshot(key string, reqFiller memphisWSReqFiller) {
+	replySubj := memphisWSReplySubj(key)
+	deadline := time.Now().Add(memphisWS_SnapshotInterestTimeout)
+	for !s.GlobalAccount().SubscriptionInterest(replySubj) {
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	s.memphisWSPublishSnapshot(key, reqFiller)
+}
+
+func (s *Server) memphisWSPublishSnapshot(key string, reqFiller memphisWSReqFiller) {
+	update, err := reqFiller()
+	if err != nil {
+		s.Errorf("memphis websocket: " + err.Error())
+		return
+	}
+	updateRaw, err := json.Marshal(update)
+	if err != nil {
+		s.Errorf("memphis websocket: " + err.Error())
+		return
+	}
+	s.respondOnGlobalAcc(memphisWSReplySubj(key), updateRaw)
+}
+
+// memphisWSPublishEvent notifies all the brokers about a change, each of them
+// pushes it to its own ws subscriptions the event is relevant to
+func (s *Server) memphisWSPublishEvent(tenantName, event, stationName string, data any) {
+	if tenantName == _EMPTY_ {
+		tenantName = globalTenantName
+	}
+	msg, err := json.Marshal(models.WSEvent{Event: event, TenantName: tenantName, StationName: stationName, Data: data})
+	if err != nil {
+		s.Errorf("memphisWSPublishEvent: " + err.Error())
+		return
+	}
+	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), memphisWS_Subj_Events, _EMPTY_, nil, msg, true)
+}
+
+func memphisWSEventMatchesSubscription(event models.WSEvent, subj string) bool {
+	switch tokenAt(subj, 1) {
+	case memphisWS_Subj_MainOverviewData, memphisWS_Subj_AllStationsData:
+		return event.Event == memphisWS_Event_StationCreated || event.Event == memphisWS_Event_StationDeleted
+	case memphisWS_Subj_StationOverviewData:
+		if event.StationName == _EMPTY_ || event.Event == memphisWS_Event_StationCreated {
+			return false
+		}
+		sn, err := StationNameFromStr(strings.Join(strings.Split(subj, ".")[1:], "."))
+		if err != nil {
+			return false
+		}
+		return sn.Ext() == event.StationName
+	case memphisWS_Subj_SysLogsData:
+		if event.Event != memphisWS_Event_SysLog {
+			return false
+		}
+		log, ok := event.Data.(models.Log)
+		if !ok {
+			return false
+		}
+		switch logLevel := tokenAt(subj, 2); logLevel {
+		case "err", "warn", "info":
+			return log.Type == logLevel
+		default:
+			return true
+		}
+	default:
+		return false
+	}
+}
+
+// memphisWSPushEvent sends the event to the matching subscriptions and marks them stale,
+// so UIs which do not handle events get the full data on the next refresh
+func (s *Server) memphisWSPushEvent(event models.WSEvent) {
+	ws := &s.memphis.ws
+	ws.webSocketMu.Lock()
+	keys := make([]string, 0)
+	tenantName := event.TenantName
+	if tenantName == _EMPTY_ {
+		tenantName = globalTenantName
+	}
+	for k, sub := range ws.subscriptions {
+		if sub.tenantName == tenantName && memphisWSEventMatchesSubscription(event, sub.subj) {
+			// a log line does not change anything the syslogs snapshot would not show next time
+			if event.Event != memphisWS_Event_SysLog {
+				sub.stale = true
+			}
+			keys = append(keys, k)
+		}
+	}
+	ws.webSocketMu.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+
+	eventRaw, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	for _, k := range keys {
+		s.respondOnGlobalAcc(memphisWSEventsSubj(k), eventRaw)
+	}
+}
+
+func (s *Server) createWSEventHandler() simplifiedMsgHandler {
+	return func(_ *client, subject, reply string, msg []byte) {
+		go func(msg []byte) {
+			var event models.WSEvent
+			err := json.Unmarshal(msg, &event)
+			if err != nil {
+				s.Errorf("memphis websocket: " + err.Error())
+				return
+			}
+			s.memphisWSPushEvent(event)
+		}(copyBytes(msg))
+	}
+}
+
+// createWSSysLogHandler pushes new log lines to the syslogs subscribers, it must
+// not log anything by itself since that would feed back into this handler
+func (s *Server) createWSSysLogHandler() simplifiedMsgHandler {
+	return func(_ *client, subject, reply string, msg []byte) {
+		logType := tokenAt(subject, 4)
+		switch logType {
+		case "info", "warn", "err":
+		default:
+			return
+		}
+		log := models.Log{
+			Type:     logType,
+			Source:   tokenAt(subject, 2),
+			Data:     string(msg),
+			TimeSent: time.Now(),
+		}
+		go s.memphisWSPushEvent(models.WSEvent{Event: memphisWS_Event_SysLog, TenantName: globalTenantName, Data: log})
+	}
+}
+
 func tokensFromToEnd(subject string, index uint8) string {
 	ti, start := uint8(1), 0
 	for i := 0; i < len(subject); i++ {
@@ -105,22 +285,81 @@ func tokensFromToEnd(subject string, index uint8) string {
 	return _EMPTY_
 }
 
+// memphisWSKey returns the key of a tenant's subscription, it is part of the subjects the data is sent on.
+// The subjects of the global tenant are the same as before multi tenancy
+func memphisWSKey(tenantName, subj string) string {
+	if tenantName == globalTenantName {
+		return subj
+	}
+	return "tenants." + strings.ReplaceAll(tenantName, ".", "#") + "." + subj
+}
+
+// memphisWSTenantName returns the tenant of the user whose access token came with a registration,
+// registrations without a token are served the global tenant only while there are no other tenants
+func memphisWSTenantName(token string) (string, error) {
+	if token == _EMPTY_ {
+		tenantNames, err := getAllTenantNames()
+		if err != nil {
+			return _EMPTY_, err
+		}
+		if len(tenantNames) > 1 {
+			return _EMPTY_, errors.New("an access token is required to subscribe")
+		}
+		return globalTenantName, nil
+	}
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(configuration.JWT_SECRET), nil
+	})
+	if err != nil {
+		return _EMPTY_, errors.New("invalid access token")
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return _EMPTY_, errors.New("invalid access token")
+	}
+	tenantName, _ := claims["tenant_name"].(string)
+	if tenantName == _EMPTY_ {
+		tenantName = globalTenantName
+	}
+	return tenantName, nil
+}
+
+// createWSRegistrationHandler handles "SUB <access token>" registrations, the access token decides
+// which tenant's data is sent, the reply holds the subjects the UI should subscribe to
 func (s *Server) createWSRegistrationHandler(h *Handlers) simplifiedMsgHandler {
 	return func(c *client, subj, reply string, msg []byte) {
-		s.Debugf("memphisWS registration - %s,%s", subj, string(msg))
-		subscriptions := s.memphis.ws.subscriptions
+		s.Debugf("memphisWS registration - %s", subj)
+		ws := &s.memphis.ws
 		filteredSubj := tokensFromToEnd(subj, 2)
-		trimmedMsg := strings.TrimSuffix(string(msg), "\r\n")
-		switch trimmedMsg {
+		op, token, _ := strings.Cut(strings.TrimSuffix(string(msg), "\r\n"), " ")
+		var key string
+		switch op {
 		case memphisWS_SubscribeMsg:
-			if _, ok := subscriptions[filteredSubj]; !ok {
-				reqFiller, err := memphisWSGetReqFillerFromSubj(s, h, filteredSubj)
+			tenantName, err := memphisWSTenantName(strings.TrimSpace(token))
+			if err != nil {
+				s.Warnf("memphis websocket: " + err.Error())
+				return
+			}
+			key = memphisWSKey(tenantName, filteredSubj)
+			ws.webSocketMu.Lock()
+			sub, ok := ws.subscriptions[key]
+			if !ok {
+				reqFiller, err := memphisWSGetReqFillerFromSubj(s, h, tenantName, filteredSubj)
 				if err != nil {
+					ws.webSocketMu.Unlock()
 					s.Errorf("memphis websocket: " + err.Error())
 					return
 				}
-				subscriptions[filteredSubj] = reqFiller
+				sub = &memphisWSSubscription{tenantName: tenantName, subj: filteredSubj, reqFiller: reqFiller}
+				ws.subscriptions[key] = sub
 			}
+			sub.lastSnapshot = time.Now()
+			ws.webSocketMu.Unlock()
+			// a full snapshot is sent on every subscribe, later on mostly events are pushed
+			go s.memphisWSSendSnapshot(key, sub.reqFiller)
 
 		default:
 			s.Errorf("memphis websocket: invalid sub/unsub operation")
@@ -130,10 +369,15 @@ func (s *Server) createWSRegistrationHandler(h *Handlers) simplifiedMsgHandler {
 		}
 
 		type brokerName struct {
-			Name string `json:"name"`
+			Name          string `json:"name"`
+			Subject       string `json:"subject,omitempty"`
+			EventsSubject string `json:"events_subject,omitempty"`
 		}
 
-		broName := brokerName{configuration.SERVER_NAME}
+		broName := brokerName{Name: configuration.SERVER_NAME}
+		if key != _EMPTY_ {
+			broName.Subject, broName.EventsSubject = memphisWSReplySubj(key), memphisWSEventsSubj(key)
+		}
 		serverName, err := json.Marshal(broName)
 
 		if err != nil {
@@ -145,17 +389,17 @@ func (s *Server) createWSRegistrationHandler(h *Handlers) simplifiedMsgHandler {
 	}
 }
 
-func unwrapHandlersFunc[T interface{}](f func(*Handlers) (T, error), h *Handlers) func() (any, error) {
+func unwrapHandlersFunc[T interface{}](f func(*Handlers, string) (T, error), h *Handlers, tenantName string) func() (any, error) {
 	return func() (any, error) {
-		return f(h)
+		return f(h, tenantName)
 	}
 }
 
-func memphisWSGetReqFillerFromSubj(s *Server, h *Handlers, subj string) (memphisWSReqFiller, error) {
+func memphisWSGetReqFillerFromSubj(s *Server, h *Handlers, tenantName, subj string) (memphisWSReqFiller, error) {
 	subjectHead := tokenAt(subj, 1)
 	switch subjectHead {
 	case memphisWS_Subj_MainOverviewData:
-		return unwrapHandlersFunc(memphisWSGetMainOverviewData, h), nil
+		return unwrapHandlersFunc(memphisWSGetMainOverviewData, h, tenantName), nil
 
 	case memphisWS_Subj_StationOverviewData:
 		stationName := strings.Join(strings.Split(subj, ".")[1:], ".")
@@ -163,7 +407,7 @@ func memphisWSGetReqFillerFromSubj(s *Server, h *Handlers, subj string) (memphis
 			return nil, errors.New("invalid station name")
 		}
 		return func() (any, error) {
-			return memphisWSGetStationOverviewData(s, h, stationName)
+			return memphisWSGetStationOverviewData(s, h, tenantName, stationName)
 		}, nil
 
 	case memphisWS_Subj_PoisonMsgJourneyData:
@@ -172,31 +416,35 @@ func memphisWSGetReqFillerFromSubj(s *Server, h *Handlers, subj string) (memphis
 			return nil, errors.New("invalid poison msg id")
 		}
 		return func() (any, error) {
-			return h.Stations.GetDlsMessageJourneyDetails(poisonMsgId, "poison")
+			return h.Stations.GetDlsMessageJourneyDetails(tenantName, poisonMsgId, "poison")
 		}, nil
 
 	case memphisWS_Subj_AllStationsData:
-		return unwrapHandlersFunc(memphisWSGetStationsOverviewData, h), nil
+		return unwrapHandlersFunc(memphisWSGetStationsOverviewData, h, tenantName), nil
 
 	case memphisWS_Subj_SysLogsData:
+		// the logs are the brokers' own, they are not split by tenant
+		if tenantName != globalTenantName {
+			return nil, errors.New("system logs are available to the global tenant only")
+		}
 		logLevel := tokenAt(subj, 2)
 		return func() (any, error) {
 			return memphisWSGetSystemLogs(h, logLevel)
 		}, nil
 
 	case memphisWS_Subj_AllSchemasData:
-		return unwrapHandlersFunc(memphisWSGetSchemasOverviewData, h), nil
+		return unwrapHandlersFunc(memphisWSGetSchemasOverviewData, h, tenantName), nil
 	default:
 		return nil, errors.New("invalid subject")
 	}
 }
 
-func memphisWSGetMainOverviewData(h *Handlers) (models.MainOverviewData, error) {
-	stations, err := h.Stations.GetAllStationsDetails()
+func memphisWSGetMainOverviewData(h *Handlers, tenantName string) (models.MainOverviewData, error) {
+	stations, err := h.Stations.GetAllStationsDetails(tenantName)
 	if err != nil {
 		return models.MainOverviewData{}, nil
 	}
-	totalMessages, err := h.Stations.GetTotalMessagesAcrossAllStations()
+	totalMessages, err := h.Stations.GetTotalMessagesAcrossAllStations(tenantName)
 	if err != nil {
 		return models.MainOverviewData{}, err
 	}
@@ -213,13 +461,13 @@ func memphisWSGetMainOverviewData(h *Handlers) (models.MainOverviewData, error)
 	}, nil
 }
 
-func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string) (map[string]any, error) {
+func memphisWSGetStationOverviewData(s *Server, h *Handlers, tenantName, stationName string) (map[string]any, error) {
 	sn, err := StationNameFromStr(stationName)
 	if err != nil {
 		return map[string]any{}, err
 	}
 
-	exist, station, err := IsStationExist(sn)
+	exist, station, err := IsStationExist(sn, tenantName)
 	if err != nil {
 		return map[string]any{}, err
 	}
@@ -239,7 +487,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 	if err != nil {
 		return map[string]any{}, err
 	}
-	totalMessages, err := h.Stations.GetTotalMessages(station.Name)
+	totalMessages, err := h.Stations.GetTotalMessages(station.TenantName, station.Name)
 	if err != nil {
 		return map[string]any{}, err
 	}
@@ -247,6 +495,10 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 	if err != nil {
 		return map[string]any{}, err
 	}
+	compression, err := h.Stations.GetCompressionStats(station)
+	if err != nil {
+		return map[string]any{}, err
+	}
 
 	messagesToFetch := 1000
 	messages, err := h.Stations.GetMessages(station, messagesToFetch)
@@ -277,7 +529,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 		return map[string]any{}, err
 	}
 
-	schema, err := h.Schemas.GetSchemaByStationName(sn)
+	schema, err := h.Schemas.GetSchemaByStationName(sn, tenantName)
 
 	if err != nil && err != ErrNoSchema {
 		return map[string]any{}, err
@@ -297,6 +549,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 				"deleted_cgs":              dc,
 				"total_messages":           totalMessages,
 				"average_message_size":     avgMsgSize,
+				"compression":              compression,
 				"audit_logs":               auditLogs,
 				"messages":                 messages,
 				"poison_messages":          poisonMessages,
@@ -319,6 +572,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 				"deleted_cgs":              deletedCgs,
 				"total_messages":           totalMessages,
 				"average_message_size":     avgMsgSize,
+				"compression":              compression,
 				"audit_logs":               auditLogs,
 				"messages":                 messages,
 				"poison_messages":          poisonMessages,
@@ -352,6 +606,7 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 		"deleted_cgs":              deletedCgs,
 		"total_messages":           totalMessages,
 		"average_message_size":     avgMsgSize,
+		"compression":              compression,
 		"audit_logs":               auditLogs,
 		"messages":                 messages,
 		"poison_messages":          poisonMessages,
@@ -368,16 +623,16 @@ func memphisWSGetStationOverviewData(s *Server, h *Handlers, stationName string)
 	return response, nil
 }
 
-func memphisWSGetSchemasOverviewData(h *Handlers) ([]models.ExtendedSchema, error) {
-	schemas, err := h.Schemas.GetAllSchemasDetails()
+func memphisWSGetSchemasOverviewData(h *Handlers, tenantName string) ([]models.ExtendedSchema, error) {
+	schemas, err := h.Schemas.GetAllSchemasDetails(tenantName)
 	if err != nil {
 		return schemas, err
 	}
 	return schemas, nil
 }
 
-func memphisWSGetStationsOverviewData(h *Handlers) ([]models.ExtendedStationDetails, error) {
-	stations, err := h.Stations.GetStationsDetails()
+func memphisWSGetStationsOverviewData(h *Handlers, tenantName string) ([]models.ExtendedStationDetails, error) {
+	stations, err := h.Stations.GetStationsDetails(tenantName)
 	if err != nil {
 		return stations, err
 	}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"memphis-broker/models"
 	"net/textproto"
 	"sort"
@@ -45,6 +46,7 @@ const (
 	syslogsErrSubject      = "extern.err"
 	syslogsSysSubject      = "intern.sys"
 	dlsStreamName          = "$memphis-%s-dls"
+	objectsStreamName      = "$memphis-%s-objects"
 )
 
 // JetStream API request kinds
@@ -53,12 +55,16 @@ const (
 	kindCreateConsumer = "$memphis_create_consumer"
 	kindDeleteConsumer = "$memphis_delete_consumer"
 	kindConsumerInfo   = "$memphis_consumer_info"
+	kindConsumerList   = "$memphis_consumer_list"
 	kindCreateStream   = "$memphis_create_stream"
 	kindUpdateStream   = "$memphis_update_stream"
 	kindDeleteStream   = "$memphis_delete_stream"
 	kindStreamList     = "$memphis_stream_list"
 	kindGetMsg         = "$memphis_get_msg"
 	kindDeleteMsg      = "$memphis_delete_msg"
+	kindSnapshotStream = "$memphis_snapshot_stream"
+	kindRestoreStream  = "$memphis_restore_stream"
+	kindKvPublish      = "$memphis_kv_publish"
 )
 
 // errors
@@ -66,6 +72,8 @@ var (
 	ErrBadHeader                   = errors.New("could not decode header")
 	LOGS_RETENTION_IN_DAYS         int
 	POISON_MSGS_RETENTION_IN_HOURS int
+	LIVENESS_SUSPECT_GRACE_SEC     = defaultSuspectGraceSec
+	LIVENESS_DISCONNECT_GRACE_SEC  = defaultDisconnectGraceSec
 )
 
 func (s *Server) MemphisInitialized() bool {
@@ -80,7 +88,44 @@ func createReplyHandler(s *Server, respCh chan []byte) simplifiedMsgHandler {
 	}
 }
 
-func jsApiRequest[R any](s *Server, subject, kind string, msg []byte, resp *R) error {
+// broadcastToBrokers sends msg to every broker of the cluster and waits until all of them replied,
+// a non-empty reply is the error the broker failed with
+func (s *Server) broadcastToBrokers(subject string, msg []byte, timeout time.Duration) error {
+	brokersCount := s.NumRoutes() + 1
+	replySubject := subject + "_reply_" + s.memphis.nuid.Next()
+	respCh := make(chan []byte, brokersCount)
+	sub, err := s.subscribeOnGlobalAcc(replySubject, replySubject+"_sid", createReplyHandler(s, respCh))
+	if err != nil {
+		return err
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), subject, replySubject, nil, msg, true)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for i := 0; i < brokersCount; i++ {
+		select {
+		case resp := <-respCh:
+			// the raw reply ends with the protocol CRLF
+			if resp = bytes.TrimSuffix(resp, []byte(CR_LF)); len(resp) > 0 {
+				return errors.New(string(resp))
+			}
+		case <-timer.C:
+			return errors.New("timed out waiting for the brokers to reply on " + subject)
+		}
+	}
+	return nil
+}
+
+func jsApiRequest[R any](s *Server, tenantName, subject, kind string, msg []byte, resp *R) error {
+	return jsApiRequestWithHeaders(s, tenantName, subject, kind, nil, msg, resp)
+}
+
+func jsApiRequestWithHeaders[R any](s *Server, tenantName, subject, kind string, hdr map[string]string, msg []byte, resp *R) error {
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return err
+	}
 	reply := s.getJsApiReplySubject()
 
 	s.memphis.jsApiMu.Lock()
@@ -88,21 +133,21 @@ func jsApiRequest[R any](s *Server, subject, kind string, msg []byte, resp *R) e
 
 	timeout := time.After(30 * time.Second)
 	respCh := make(chan []byte)
-	sub, err := s.subscribeOnGlobalAcc(reply, reply+"_sid", createReplyHandler(s, respCh))
+	sub, err := s.subscribeOnAcc(acc, reply, reply+"_sid", createReplyHandler(s, respCh))
 	if err != nil {
 		return err
 	}
-	// send on global account
-	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), subject, reply, nil, msg, true)
+	// send on the tenant account
+	s.sendInternalAccountMsgWithReply(acc, subject, reply, hdr, msg, true)
 
 	// wait for response to arrive
 	var rawResp []byte
 	select {
 	case rawResp = <-respCh:
-		s.unsubscribeOnGlobalAcc(sub)
+		s.unsubscribeOnAcc(acc, sub)
 		break
 	case <-timeout:
-		s.unsubscribeOnGlobalAcc(sub)
+		s.unsubscribeOnAcc(acc, sub)
 		return fmt.Errorf("jsapi request timeout for request type %q on %q", kind, subject)
 	}
 
@@ -125,6 +170,10 @@ func RemoveUser(username string) error {
 }
 
 func (s *Server) CreateStream(sn StationName, station models.Station) error {
+	return s.memphisAddStream(station.TenantName, getStationStreamConfig(sn, station))
+}
+
+func getStationStreamConfig(sn StationName, station models.Station) *StreamConfig {
 	var maxMsgs int
 	if station.RetentionType == "messages" && station.RetentionValue > 0 {
 		maxMsgs = station.RetentionValue
@@ -162,23 +211,44 @@ func (s *Server) CreateStream(sn StationName, station models.Station) error {
 		idempotencyWindow = time.Duration(station.IdempotencyWindow) * time.Millisecond
 	}
 
-	return s.
-		memphisAddStream(&StreamConfig{
-			Name:         sn.Intern(),
-			Subjects:     []string{sn.Intern() + ".>"},
-			Retention:    LimitsPolicy,
-			MaxConsumers: -1,
-			MaxMsgs:      int64(maxMsgs),
-			MaxBytes:     int64(maxBytes),
-			Discard:      DiscardOld,
-			MaxAge:       maxAge,
-			MaxMsgsPer:   -1,
-			MaxMsgSize:   int32(configuration.MAX_MESSAGE_SIZE_MB) * 1024 * 1024,
-			Storage:      storage,
-			Replicas:     station.Replicas,
-			NoAck:        false,
-			Duplicates:   idempotencyWindow,
-		})
+	streamConfig := &StreamConfig{
+		Name:         sn.Intern(),
+		Subjects:     []string{sn.Intern() + ".>"},
+		Retention:    LimitsPolicy,
+		MaxConsumers: -1,
+		MaxMsgs:      int64(maxMsgs),
+		MaxBytes:     int64(maxBytes),
+		Discard:      DiscardOld,
+		MaxAge:       maxAge,
+		MaxMsgsPer:   getStationMaxMsgsPerKey(station),
+		MaxMsgSize:   int32(configuration.MAX_MESSAGE_SIZE_MB) * 1024 * 1024,
+		Storage:      storage,
+		Compression:  getStationCompression(station),
+		Replicas:     station.Replicas,
+		NoAck:        false,
+		Duplicates:   idempotencyWindow,
+		AllowRollup:  station.Compacted,
+	}
+	if isActiveMirror(station) {
+		// a mirror stores the messages of its origin and can not be produced to until it is promoted
+		streamConfig.Subjects = nil
+		streamConfig.Duplicates = 0
+		streamConfig.Mirror = getStreamMirror(station.Mirror)
+	} else if isAggregateStation(station) {
+		streamConfig.Sources = getStreamSources(station.Sources)
+	}
+	return streamConfig
+}
+
+func getStationCompression(station models.Station) StoreCompression {
+	switch station.Compression {
+	case "s2":
+		return S2Compression
+	case "zstd":
+		return ZstdCompression
+	default:
+		return NoCompression
+	}
 }
 
 func (s *Server) CreateDlsStream(sn StationName, station models.Station) error {
@@ -196,7 +266,7 @@ func (s *Server) CreateDlsStream(sn StationName, station models.Station) error {
 	name := fmt.Sprintf(dlsStreamName, sn.Intern())
 
 	return s.
-		memphisAddStream(&StreamConfig{
+		memphisAddStream(station.TenantName, &StreamConfig{
 			Name:         (name),
 			Subjects:     []string{name + ".>"},
 			Retention:    LimitsPolicy,
@@ -208,6 +278,7 @@ func (s *Server) CreateDlsStream(sn StationName, station models.Station) error {
 			MaxMsgsPer:   -1,
 			MaxMsgSize:   int32(configuration.MAX_MESSAGE_SIZE_MB) * 1024 * 1024,
 			Storage:      storage,
+			Compression:  getStationCompression(station),
 			Replicas:     station.Replicas,
 			NoAck:        false,
 			Duplicates:   idempotencyWindow,
@@ -259,7 +330,7 @@ func (s *Server) CreateSystemLogsStream() {
 }
 
 func tryCreateSystemLogsStream(s *Server, retentionDur time.Duration, successCh chan error) {
-	err := s.memphisAddStream(&StreamConfig{
+	err := s.memphisAddStream(globalTenantName, &StreamConfig{
 		Name:         syslogsStreamName,
 		Subjects:     []string{syslogsStreamName + ".>"},
 		Retention:    LimitsPolicy,
@@ -292,7 +363,7 @@ func (s *Server) popFallbackLogs() {
 	}
 }
 
-func (s *Server) memphisAddStream(sc *StreamConfig) error {
+func (s *Server) memphisAddStream(tenantName string, sc *StreamConfig) error {
 	requestSubject := fmt.Sprintf(JSApiStreamCreateT, sc.Name)
 
 	request, err := json.Marshal(sc)
@@ -301,7 +372,7 @@ func (s *Server) memphisAddStream(sc *StreamConfig) error {
 	}
 
 	var resp JSApiStreamCreateResponse
-	err = jsApiRequest(s, requestSubject, kindCreateStream, request, &resp)
+	err = jsApiRequest(s, tenantName, requestSubject, kindCreateStream, request, &resp)
 	if err != nil {
 		return err
 	}
@@ -309,7 +380,7 @@ func (s *Server) memphisAddStream(sc *StreamConfig) error {
 	return resp.ToError()
 }
 
-func (s *Server) memphisUpdateStream(sc *StreamConfig) error {
+func (s *Server) memphisUpdateStream(tenantName string, sc *StreamConfig) error {
 	requestSubject := fmt.Sprintf(JSApiStreamUpdateT, sc.Name)
 
 	request, err := json.Marshal(sc)
@@ -318,7 +389,7 @@ func (s *Server) memphisUpdateStream(sc *StreamConfig) error {
 	}
 
 	var resp JSApiStreamUpdateResponse
-	err = jsApiRequest(s, requestSubject, kindUpdateStream, request, &resp)
+	err = jsApiRequest(s, tenantName, requestSubject, kindUpdateStream, request, &resp)
 	if err != nil {
 		return err
 	}
@@ -338,44 +409,68 @@ func (s *Server) CreateConsumer(consumer models.Consumer, station models.Station
 		consumerName = consumer.Name
 	}
 
-	consumerName = getInternalConsumerName(consumerName)
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+
+	cc := getConsumerConfig(consumerName, consumer, stationName, station)
+	if isPartitioned(station.PartitionsNumber) {
+		return s.createPartitionsConsumers(station, consumerName, cc)
+	}
 
-	var maxAckTimeMs int64
-	if consumer.MaxAckTimeMs <= 0 {
-		maxAckTimeMs = 30000 // 30 sec
-	} else {
-		maxAckTimeMs = consumer.MaxAckTimeMs
+	return s.memphisAddConsumer(station.TenantName, stationName.Intern(), &cc)
+}
+
+func getEffectiveMaxAckTimeMs(maxAckTimeMs int64) int64 {
+	if maxAckTimeMs <= 0 {
+		return 30000 // 30 sec
 	}
+	return maxAckTimeMs
+}
 
-	var MaxMsgDeliveries int
-	if consumer.MaxMsgDeliveries <= 0 || consumer.MaxMsgDeliveries > 10 {
-		MaxMsgDeliveries = 10
-	} else {
-		MaxMsgDeliveries = consumer.MaxMsgDeliveries
+func getEffectiveMaxMsgDeliveries(maxMsgDeliveries int) int {
+	if maxMsgDeliveries <= 0 || maxMsgDeliveries > 10 {
+		return 10
 	}
+	return maxMsgDeliveries
+}
 
-	stationName, err := StationNameFromStr(station.Name)
-	if err != nil {
-		return err
+func getConsumerConfig(consumerName string, consumer models.Consumer, stationName StationName, station models.Station) ConsumerConfig {
+	maxAckTimeMs := getEffectiveMaxAckTimeMs(consumer.MaxAckTimeMs)
+	MaxMsgDeliveries := getEffectiveMaxMsgDeliveries(consumer.MaxMsgDeliveries)
+
+	// a new group of a compacted station starts from the current value of every key and then follows the updates
+	deliverPolicy := DeliverAll
+	if station.Compacted {
+		deliverPolicy = DeliverLastPerSubject
 	}
 
-	err = s.memphisAddConsumer(stationName.Intern(), &ConsumerConfig{
-		Durable:       consumerName,
-		DeliverPolicy: DeliverAll,
+	var backOff []time.Duration
+	for _, windowMs := range getRedeliveryScheduleMs(consumer.RedeliveryBackoff, consumer.MaxAckTimeMs, consumer.MaxMsgDeliveries) {
+		backOff = append(backOff, time.Duration(windowMs)*time.Millisecond)
+	}
+
+	return ConsumerConfig{
+		Durable:       getInternalConsumerName(consumerName),
+		DeliverPolicy: deliverPolicy,
 		AckPolicy:     AckExplicit,
 		AckWait:       time.Duration(maxAckTimeMs) * time.Millisecond,
 		MaxDeliver:    MaxMsgDeliveries,
-		FilterSubject: stationName.Intern() + ".final",
+		BackOff:       backOff,
+		ContentFilter: consumer.ContentFilter,
+		FilterSubject: getStationFilterSubject(stationName, station),
 		ReplayPolicy:  ReplayInstant,
 		MaxAckPending: -1,
 		HeadersOnly:   false,
+		// ack latencies are reported to the message journey
+		SampleFrequency: journeyAckSampleFrequency,
 		// RateLimit: ,// Bits per sec
 		// Heartbeat: // time.Duration,
-	})
-	return err
+	}
 }
 
-func (s *Server) memphisAddConsumer(streamName string, cc *ConsumerConfig) error {
+func (s *Server) memphisAddConsumer(tenantName, streamName string, cc *ConsumerConfig) error {
 	requestSubject := fmt.Sprintf(JSApiConsumerCreateT, streamName)
 	if cc.Durable != _EMPTY_ {
 		requestSubject = fmt.Sprintf(JSApiDurableCreateT, streamName, cc.Durable)
@@ -387,7 +482,7 @@ func (s *Server) memphisAddConsumer(streamName string, cc *ConsumerConfig) error
 		return err
 	}
 	var resp JSApiConsumerCreateResponse
-	err = jsApiRequest(s, requestSubject, kindCreateConsumer, []byte(rawRequest), &resp)
+	err = jsApiRequest(s, tenantName, requestSubject, kindCreateConsumer, []byte(rawRequest), &resp)
 	if err != nil {
 		return err
 	}
@@ -395,15 +490,18 @@ func (s *Server) memphisAddConsumer(streamName string, cc *ConsumerConfig) error
 	return resp.ToError()
 }
 
-func (s *Server) RemoveConsumer(stationName StationName, cn string) error {
+func (s *Server) RemoveConsumer(tenantName string, stationName StationName, cn string) error {
+	if partitionsNumber := getStationPartitions(tenantName, stationName.Intern()); isPartitioned(partitionsNumber) {
+		return s.removePartitionsConsumers(tenantName, stationName, cn, partitionsNumber)
+	}
 	cn = getInternalConsumerName(cn)
-	return s.memphisRemoveConsumer(stationName.Intern(), cn)
+	return s.memphisRemoveConsumer(tenantName, stationName.Intern(), cn)
 }
 
-func (s *Server) memphisRemoveConsumer(streamName, cn string) error {
+func (s *Server) memphisRemoveConsumer(tenantName, streamName, cn string) error {
 	requestSubject := fmt.Sprintf(JSApiConsumerDeleteT, streamName, cn)
 	var resp JSApiConsumerDeleteResponse
-	err := jsApiRequest(s, requestSubject, kindDeleteConsumer, []byte(_EMPTY_), &resp)
+	err := jsApiRequest(s, tenantName, requestSubject, kindDeleteConsumer, []byte(_EMPTY_), &resp)
 	if err != nil {
 		return err
 	}
@@ -411,12 +509,15 @@ func (s *Server) memphisRemoveConsumer(streamName, cn string) error {
 	return resp.ToError()
 }
 
-func (s *Server) GetCgInfo(stationName StationName, cgName string) (*ConsumerInfo, error) {
+func (s *Server) GetCgInfo(tenantName string, stationName StationName, cgName string) (*ConsumerInfo, error) {
+	if partitionsNumber := getStationPartitions(tenantName, stationName.Intern()); isPartitioned(partitionsNumber) {
+		return s.getPartitionsCgInfo(tenantName, stationName, cgName, partitionsNumber)
+	}
 	cgName = replaceDelimiters(cgName)
 	requestSubject := fmt.Sprintf(JSApiConsumerInfoT, stationName.Intern(), cgName)
 
 	var resp JSApiConsumerInfoResponse
-	err := jsApiRequest(s, requestSubject, kindConsumerInfo, []byte(_EMPTY_), &resp)
+	err := jsApiRequest(s, tenantName, requestSubject, kindConsumerInfo, []byte(_EMPTY_), &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -429,11 +530,11 @@ func (s *Server) GetCgInfo(stationName StationName, cgName string) (*ConsumerInf
 	return resp.ConsumerInfo, nil
 }
 
-func (s *Server) RemoveStream(streamName string) error {
+func (s *Server) RemoveStream(tenantName, streamName string) error {
 	requestSubject := fmt.Sprintf(JSApiStreamDeleteT, streamName)
 
 	var resp JSApiStreamDeleteResponse
-	err := jsApiRequest(s, requestSubject, kindDeleteStream, []byte(_EMPTY_), &resp)
+	err := jsApiRequest(s, tenantName, requestSubject, kindDeleteStream, []byte(_EMPTY_), &resp)
 	if err != nil {
 		return err
 	}
@@ -441,8 +542,8 @@ func (s *Server) RemoveStream(streamName string) error {
 	return resp.ToError()
 }
 
-func (s *Server) GetTotalMessagesInStation(stationName StationName) (int, error) {
-	streamInfo, err := s.memphisStreamInfo(stationName.Intern())
+func (s *Server) GetTotalMessagesInStation(tenantName string, stationName StationName) (int, error) {
+	streamInfo, err := s.memphisStreamInfo(tenantName, stationName.Intern())
 	if err != nil {
 		return 0, err
 	}
@@ -450,16 +551,16 @@ func (s *Server) GetTotalMessagesInStation(stationName StationName) (int, error)
 	return int(streamInfo.State.Msgs), nil
 }
 
-func (s *Server) GetTotalMessagesAcrossAllStations() (int, error) {
+func (s *Server) GetTotalMessagesAcrossAllStations(tenantName string) (int, error) {
 	messagesCounter := 0
 
-	streams, err := s.memphisAllStreamsInfo()
+	streams, err := s.memphisAllStreamsInfo(tenantName)
 	if err != nil {
 		return messagesCounter, err
 	}
 
 	for _, streamInfo := range streams {
-		if !strings.HasPrefix(streamInfo.Config.Name, "$memphis") { // skip internal streams
+		if !strings.HasPrefix(streamInfo.Config.Name, "$memphis") && !strings.HasPrefix(streamInfo.Config.Name, kvBucketStreamPrefix) { // skip internal streams and KV buckets
 			messagesCounter = messagesCounter + int(streamInfo.State.Msgs)
 		}
 	}
@@ -468,11 +569,11 @@ func (s *Server) GetTotalMessagesAcrossAllStations() (int, error) {
 }
 
 // low level call, call only with internal station name (i.e stream name)!
-func (s *Server) memphisStreamInfo(streamName string) (*StreamInfo, error) {
+func (s *Server) memphisStreamInfo(tenantName, streamName string) (*StreamInfo, error) {
 	requestSubject := fmt.Sprintf(JSApiStreamInfoT, streamName)
 
 	var resp JSApiStreamInfoResponse
-	err := jsApiRequest(s, requestSubject, kindStreamInfo, []byte(_EMPTY_), &resp)
+	err := jsApiRequest(s, tenantName, requestSubject, kindStreamInfo, []byte(_EMPTY_), &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -485,7 +586,142 @@ func (s *Server) memphisStreamInfo(streamName string) (*StreamInfo, error) {
 	return resp.StreamInfo, nil
 }
 
-func (s *Server) memphisDeleteMsgFromStream(streamName string, seq uint64) (ApiResponse, error) {
+type snapshotChunk struct {
+	reply string
+	data  []byte
+}
+
+// memphisStreamSnapshot writes a snapshot of the stream (including its consumers) into w
+func (s *Server) memphisStreamSnapshot(tenantName, streamName string, w io.Writer) (*JSApiStreamSnapshotResponse, error) {
+	const stallTimeout = 10 * time.Second
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return nil, err
+	}
+	deliverSubject := "$memphis_snapshot_" + nuid.Next()
+	chunksCh := make(chan snapshotChunk, 1024)
+	sub, err := s.subscribeOnAcc(acc, deliverSubject, deliverSubject+"_sid", func(_ *client, _, reply string, msg []byte) {
+		chunksCh <- snapshotChunk{reply: reply, data: copyBytes(msg[:len(msg)-len(CR_LF)])}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer s.unsubscribeOnAcc(acc, sub)
+
+	request, err := json.Marshal(JSApiStreamSnapshotRequest{DeliverSubject: deliverSubject})
+	if err != nil {
+		return nil, err
+	}
+	var resp JSApiStreamSnapshotResponse
+	err = jsApiRequest(s, tenantName, fmt.Sprintf(JSApiStreamSnapshotT, streamName), kindSnapshotStream, request, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if err = resp.ToError(); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(stallTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case chunk := <-chunksCh:
+			// an empty chunk marks the end of the snapshot
+			if len(chunk.data) == 0 {
+				return &resp, nil
+			}
+			if _, err = w.Write(chunk.data); err != nil {
+				return nil, err
+			}
+			if chunk.reply != _EMPTY_ {
+				s.sendInternalAccountMsg(acc, chunk.reply, nil)
+			}
+			timer.Reset(stallTimeout)
+		case <-timer.C:
+			return nil, fmt.Errorf("snapshot of stream %s is stalled", streamName)
+		}
+	}
+}
+
+// memphisStreamRestore recreates a stream from a snapshot taken by memphisStreamSnapshot
+func (s *Server) memphisStreamRestore(tenantName string, config StreamConfig, state StreamState, r io.Reader) error {
+	const chunkSize = 128 * 1024
+	const chunkTimeout = 10 * time.Second
+	const finalizeTimeout = 2 * time.Minute
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return err
+	}
+
+	request, err := json.Marshal(JSApiStreamRestoreRequest{Config: config, State: state})
+	if err != nil {
+		return err
+	}
+	var resp JSApiStreamRestoreResponse
+	err = jsApiRequest(s, tenantName, fmt.Sprintf(JSApiStreamRestoreT, config.Name), kindRestoreStream, request, &resp)
+	if err != nil {
+		return err
+	}
+	if err = resp.ToError(); err != nil {
+		return err
+	}
+
+	// the restore acks are published by the account internal client without echo,
+	// so they have to be received on a dedicated client
+	ackClient := s.createInternalAccountClient()
+	if err = ackClient.registerWithAccount(acc); err != nil {
+		return err
+	}
+	defer ackClient.closeConnection(ClientClosed)
+
+	reply := s.getJsApiReplySubject()
+	ackCh := make(chan []byte)
+	replyHandler := createReplyHandler(s, ackCh)
+	_, err = ackClient.processSub([]byte(reply), nil, []byte(reply+"_sid"), func(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+		replyHandler(c, subject, reply, rmsg)
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			s.sendInternalAccountMsgWithReply(acc, resp.DeliverSubject, reply, nil, copyBytes(buf[:n]), true)
+			select {
+			case ack := <-ackCh:
+				ack = bytes.TrimSpace(ack)
+				if len(ack) > 0 {
+					return fmt.Errorf("restore of stream %s failed: %s", config.Name, string(ack))
+				}
+			case <-time.After(chunkTimeout):
+				return fmt.Errorf("restore of stream %s is stalled", config.Name)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	// an empty chunk marks the end of the snapshot, the reply holds the restored stream info
+	s.sendInternalAccountMsgWithReply(acc, resp.DeliverSubject, reply, nil, nil, true)
+	select {
+	case rawResp := <-ackCh:
+		var createResp JSApiStreamCreateResponse
+		if err = json.Unmarshal(rawResp, &createResp); err != nil {
+			return err
+		}
+		return createResp.ToError()
+	case <-time.After(finalizeTimeout):
+		return fmt.Errorf("restore of stream %s timed out", config.Name)
+	}
+}
+
+func (s *Server) memphisDeleteMsgFromStream(tenantName, streamName string, seq uint64) (ApiResponse, error) {
 	requestSubject := fmt.Sprintf(JSApiMsgDeleteT, streamName)
 
 	msg := JSApiMsgDeleteRequest{
@@ -498,7 +734,7 @@ func (s *Server) memphisDeleteMsgFromStream(streamName string, seq uint64) (ApiR
 	}
 
 	var resp JSApiMsgDeleteResponse
-	err = jsApiRequest(s, requestSubject, kindDeleteMsg, req, &resp)
+	err = jsApiRequest(s, tenantName, requestSubject, kindDeleteMsg, req, &resp)
 	if err != nil {
 		return ApiResponse{}, err
 	}
@@ -517,7 +753,7 @@ func (s *Server) GetAvgMsgSizeInStation(station models.Station) (int64, error) {
 		return 0, err
 	}
 
-	streamInfo, err := s.memphisStreamInfo(stationName.Intern())
+	streamInfo, err := s.memphisStreamInfo(station.TenantName, stationName.Intern())
 	if err != nil || streamInfo.State.Bytes == 0 {
 		return 0, err
 	}
@@ -525,7 +761,22 @@ func (s *Server) GetAvgMsgSizeInStation(station models.Station) (int64, error) {
 	return int64(streamInfo.State.Bytes / streamInfo.State.Msgs), nil
 }
 
-func (s *Server) memphisAllStreamsInfo() ([]*StreamInfo, error) {
+// GetCompressionStatsInStation returns nil when the station's messages are not compressed
+func (s *Server) GetCompressionStatsInStation(station models.Station) (*CompressionStats, error) {
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	streamInfo, err := s.memphisStreamInfo(station.TenantName, stationName.Intern())
+	if err != nil {
+		return nil, err
+	}
+
+	return streamInfo.Compression, nil
+}
+
+func (s *Server) memphisAllStreamsInfo(tenantName string) ([]*StreamInfo, error) {
 	requestSubject := fmt.Sprintf(JSApiStreamList)
 	streams := make([]*StreamInfo, 0)
 
@@ -537,7 +788,7 @@ func (s *Server) memphisAllStreamsInfo() ([]*StreamInfo, error) {
 		return nil, err
 	}
 	var resp JSApiStreamListResponse
-	err = jsApiRequest(s, requestSubject, kindStreamList, []byte(rawRequest), &resp)
+	err = jsApiRequest(s, tenantName, requestSubject, kindStreamList, []byte(rawRequest), &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -556,7 +807,7 @@ func (s *Server) memphisAllStreamsInfo() ([]*StreamInfo, error) {
 			return nil, err
 		}
 
-		err = jsApiRequest(s, requestSubject, kindStreamList, []byte(rawRequest), &resp)
+		err = jsApiRequest(s, tenantName, requestSubject, kindStreamList, []byte(rawRequest), &resp)
 		if err != nil {
 			return nil, err
 		}
@@ -576,7 +827,7 @@ func (s *Server) GetMessages(station models.Station, messagesToFetch int) ([]mod
 	if err != nil {
 		return []models.MessageDetails{}, err
 	}
-	streamInfo, err := s.memphisStreamInfo(stationName.Intern())
+	streamInfo, err := s.memphisStreamInfo(station.TenantName, stationName.Intern())
 	if err != nil {
 		return []models.MessageDetails{}, err
 	}
@@ -590,12 +841,16 @@ func (s *Server) GetMessages(station models.Station, messagesToFetch int) ([]mod
 		messagesToFetch = int(totalMessages)
 	}
 
-	filterSubj := stationName.Intern() + ".final"
+	filterSubj := getStationFilterSubject(stationName, station)
+	if isPartitioned(station.PartitionsNumber) {
+		filterSubj = stationName.Intern() + ".final.*"
+	}
 	if !station.IsNative {
 		filterSubj = ""
 	}
 
-	msgs, err := s.memphisGetMsgs(filterSubj,
+	msgs, err := s.memphisGetTenantMsgs(station.TenantName,
+		filterSubj,
 		stationName.Intern(),
 		startSequence,
 		messagesToFetch,
@@ -632,6 +887,10 @@ func (s *Server) GetMessages(station models.Station, messagesToFetch int) ([]mod
 			}
 			connectionIdHeader := headersJson["$memphis_connectionId"]
 			producedByHeader := strings.ToLower(headersJson["$memphis_producedBy"])
+			if cc, chunked := getClaimCheck(headersJson); chunked {
+				messageDetails.Chunked = true
+				messageDetails.Size = cc.payloadSize
+			}
 
 			//This check for backward compatability
 			if connectionIdHeader == "" || producedByHeader == "" {
@@ -687,31 +946,65 @@ func getHdrLastIdxFromRaw(msg []byte) int {
 }
 
 func (s *Server) memphisGetMsgs(filterSubj, streamName string, startSeq uint64, amount int, timeout time.Duration, findHeader bool) ([]StoredMsg, error) {
-	uid, _ := uuid.NewV4()
-	durableName := "$memphis_fetch_messages_consumer_" + uid.String()
+	return s.memphisGetTenantMsgs(globalTenantName, filterSubj, streamName, startSeq, amount, timeout, findHeader)
+}
 
+func (s *Server) memphisGetTenantMsgs(tenantName, filterSubj, streamName string, startSeq uint64, amount int, timeout time.Duration, findHeader bool) ([]StoredMsg, error) {
 	cc := ConsumerConfig{
 		FilterSubject: filterSubj,
 		OptStartSeq:   startSeq,
 		DeliverPolicy: DeliverByStartSequence,
-		Durable:       durableName,
 		AckPolicy:     AckExplicit,
 	}
+	return s.memphisFetchMsgs(tenantName, streamName, cc, amount, timeout, findHeader)
+}
+
+// memphisFetchMsgs reads messages through a temporary consumer created with the given start options,
+// a non positive amount fetches all the messages pending on the consumer
+func (s *Server) memphisFetchMsgs(tenantName, streamName string, cc ConsumerConfig, amount int, timeout time.Duration, findHeader bool) ([]StoredMsg, error) {
+	uid, _ := uuid.NewV4()
+	durableName := "$memphis_fetch_messages_consumer_" + uid.String()
+	cc.Durable = durableName
 
-	err := s.memphisAddConsumer(streamName, &cc)
+	acc, err := s.getTenantAccount(tenantName)
 	if err != nil {
 		return nil, err
 	}
+	err = s.memphisAddConsumer(tenantName, streamName, &cc)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount <= 0 {
+		var resp JSApiConsumerInfoResponse
+		err = jsApiRequest(s, tenantName, fmt.Sprintf(JSApiConsumerInfoT, streamName, durableName), kindConsumerInfo, []byte(_EMPTY_), &resp)
+		if err == nil {
+			err = resp.ToError()
+		}
+		if err != nil {
+			s.memphisRemoveConsumer(tenantName, streamName, durableName)
+			return nil, err
+		}
+		amount = int(resp.ConsumerInfo.NumPending)
+		if amount == 0 {
+			return []StoredMsg{}, s.memphisRemoveConsumer(tenantName, streamName, durableName)
+		}
+	}
 
 	responseChan := make(chan StoredMsg)
 	subject := fmt.Sprintf(JSApiRequestNextT, streamName, durableName)
 	reply := durableName + "_reply"
 	req := []byte(strconv.Itoa(amount))
 
-	sub, err := s.subscribeOnGlobalAcc(reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
-		go func(respCh chan StoredMsg, reply string, msg []byte, findHeader bool) {
+	sub, err := s.subscribeOnAcc(acc, reply, reply+"_sid", func(c *client, subject, reply string, msg []byte) {
+		// the headers length is only known while the message is being delivered
+		hdrLen := 0
+		if findHeader && c.pa.hdr > 0 {
+			hdrLen = c.pa.hdr
+		}
+		go func(respCh chan StoredMsg, subject, reply string, msg []byte, hdrLen int) {
 			// ack
-			s.sendInternalAccountMsg(s.GlobalAccount(), reply, []byte(_EMPTY_))
+			s.sendInternalAccountMsg(acc, reply, []byte(_EMPTY_))
 
 			rawTs := tokenAt(reply, 8)
 			seq, _, _ := ackReplyInfo(reply)
@@ -721,31 +1014,20 @@ func (s *Server) memphisGetMsgs(filterSubj, streamName string, startSeq uint64,
 				s.Errorf("memphisGetMsgs: " + err.Error())
 			}
 
-			dataFirstIdx := 0
-			dataLen := len(msg)
-			if findHeader {
-				dataFirstIdx = getHdrLastIdxFromRaw(msg) + 1
-				if dataFirstIdx > len(msg)-len(CR_LF) {
-					s.Errorf("memphisGetMsgs: memphis error parsing in station get messages")
-				}
-
-				dataLen = len(msg) - dataFirstIdx
-			}
-			dataLen -= len(CR_LF)
-
 			respCh <- StoredMsg{
+				Subject:  subject,
 				Sequence: uint64(seq),
-				Header:   msg[:dataFirstIdx],
-				Data:     msg[dataFirstIdx : dataFirstIdx+dataLen],
+				Header:   msg[:hdrLen],
+				Data:     msg[hdrLen : len(msg)-len(CR_LF)],
 				Time:     time.Unix(0, int64(intTs)),
 			}
-		}(responseChan, reply, copyBytes(msg), findHeader)
+		}(responseChan, subject, reply, copyBytes(msg), hdrLen)
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), subject, reply, nil, req, true)
+	s.sendInternalAccountMsgWithReply(acc, subject, reply, nil, req, true)
 
 	var msgs []StoredMsg
 	timer := time.NewTimer(timeout)
@@ -760,8 +1042,8 @@ func (s *Server) memphisGetMsgs(filterSubj, streamName string, startSeq uint64,
 
 cleanup:
 	timer.Stop()
-	s.unsubscribeOnGlobalAcc(sub)
-	err = s.memphisRemoveConsumer(streamName, durableName)
+	s.unsubscribeOnAcc(acc, sub)
+	err = s.memphisRemoveConsumer(tenantName, streamName, durableName)
 	if err != nil {
 		return nil, err
 	}
@@ -769,8 +1051,8 @@ cleanup:
 	return msgs, nil
 }
 
-func (s *Server) GetMessage(stationName StationName, msgSeq uint64) (*StoredMsg, error) {
-	return s.memphisGetMessage(stationName.Intern(), msgSeq)
+func (s *Server) GetMessage(tenantName string, stationName StationName, msgSeq uint64) (*StoredMsg, error) {
+	return s.memphisGetMessage(tenantName, stationName.Intern(), msgSeq)
 }
 
 func (s *Server) GetLeaderAndFollowers(station models.Station) (string, []string, error) {
@@ -780,7 +1062,7 @@ func (s *Server) GetLeaderAndFollowers(station models.Station) (string, []string
 		return "", followers, err
 	}
 
-	streamInfo, err := s.memphisStreamInfo(stationName.Intern())
+	streamInfo, err := s.memphisStreamInfo(station.TenantName, stationName.Intern())
 	if err != nil {
 		return "", followers, err
 	}
@@ -792,7 +1074,7 @@ func (s *Server) GetLeaderAndFollowers(station models.Station) (string, []string
 	return streamInfo.Cluster.Leader, followers, nil
 }
 
-func (s *Server) memphisGetMessage(streamName string, msgSeq uint64) (*StoredMsg, error) {
+func (s *Server) memphisGetMessage(tenantName, streamName string, msgSeq uint64) (*StoredMsg, error) {
 	requestSubject := fmt.Sprintf(JSApiMsgGetT, streamName)
 
 	request := JSApiMsgGetRequest{Seq: msgSeq}
@@ -803,7 +1085,32 @@ func (s *Server) memphisGetMessage(streamName string, msgSeq uint64) (*StoredMsg
 	}
 
 	var resp JSApiMsgGetResponse
-	err = jsApiRequest(s, requestSubject, kindGetMsg, rawRequest, &resp)
+	err = jsApiRequest(s, tenantName, requestSubject, kindGetMsg, rawRequest, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resp.ToError()
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Message, nil
+}
+
+// memphisGetNextMsg returns the first message stored under filterSubject starting at startSeq
+func (s *Server) memphisGetNextMsg(tenantName, streamName, filterSubject string, startSeq uint64) (*StoredMsg, error) {
+	requestSubject := fmt.Sprintf(JSApiMsgGetT, streamName)
+
+	request := JSApiMsgGetRequest{Seq: startSeq, NextFor: filterSubject}
+
+	rawRequest, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp JSApiMsgGetResponse
+	err = jsApiRequest(s, tenantName, requestSubject, kindGetMsg, rawRequest, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -816,7 +1123,41 @@ func (s *Server) memphisGetMessage(streamName string, msgSeq uint64) (*StoredMsg
 	return resp.Message, nil
 }
 
-func (s *Server) memphisGetMessagesByFilter(streamName, filterSubject string, startSeq, amount uint64, timeout time.Duration) ([]StoredMsg, error) {
+const ephemeralPushConsumerInactiveThreshold = 30 * time.Second
+
+// memphisAddEphemeralPushConsumer creates a consumer pushing the matching messages into deliverSubject without acks,
+// the consumer goes away once nobody listens on deliverSubject anymore
+func (s *Server) memphisAddEphemeralPushConsumer(tenantName, streamName, filterSubject, deliverSubject string, deliverPolicy DeliverPolicy) (string, error) {
+	requestSubject := fmt.Sprintf(JSApiConsumerCreateT, streamName)
+
+	request := CreateConsumerRequest{Stream: streamName, Config: ConsumerConfig{
+		DeliverSubject:    deliverSubject,
+		DeliverPolicy:     deliverPolicy,
+		AckPolicy:         AckNone,
+		FilterSubject:     filterSubject,
+		ReplayPolicy:      ReplayInstant,
+		InactiveThreshold: ephemeralPushConsumerInactiveThreshold,
+	}}
+	rawRequest, err := json.Marshal(request)
+	if err != nil {
+		return _EMPTY_, err
+	}
+
+	var resp JSApiConsumerCreateResponse
+	err = jsApiRequest(s, tenantName, requestSubject, kindCreateConsumer, rawRequest, &resp)
+	if err != nil {
+		return _EMPTY_, err
+	}
+
+	err = resp.ToError()
+	if err != nil {
+		return _EMPTY_, err
+	}
+
+	return resp.ConsumerInfo.Name, nil
+}
+
+func (s *Server) memphisGetMessagesByFilter(tenantName, streamName, filterSubject string, startSeq, amount uint64, timeout time.Duration) ([]StoredMsg, error) {
 	uid := serv.memphis.nuid.Next()
 	durableName := uid
 
@@ -832,7 +1173,11 @@ func (s *Server) memphisGetMessagesByFilter(streamName, filterSubject string, st
 		FilterSubject: filterSubject,
 	}
 	var msgs []StoredMsg
-	err := serv.memphisAddConsumer(streamName, &cc)
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return msgs, err
+	}
+	err = s.memphisAddConsumer(tenantName, streamName, &cc)
 	if err != nil {
 		return msgs, err
 	}
@@ -841,10 +1186,10 @@ func (s *Server) memphisGetMessagesByFilter(streamName, filterSubject string, st
 	subject := fmt.Sprintf(JSApiRequestNextT, streamName, durableName)
 	reply := durableName + "_reply"
 	req := []byte(strconv.FormatUint(amount, 10))
-	sub, err := serv.subscribeOnGlobalAcc(reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
+	sub, err := s.subscribeOnAcc(acc, reply, reply+"_sid", func(_ *client, subject, reply string, msg []byte) {
 		go func(respCh chan StoredMsg, subject, reply string, msg []byte) {
 			// ack
-			serv.sendInternalAccountMsg(serv.GlobalAccount(), reply, []byte(_EMPTY_))
+			s.sendInternalAccountMsg(acc, reply, []byte(_EMPTY_))
 			rawTs := tokenAt(reply, 8)
 			seq, _, _ := ackReplyInfo(reply)
 
@@ -865,7 +1210,7 @@ func (s *Server) memphisGetMessagesByFilter(streamName, filterSubject string, st
 		return msgs, err
 	}
 
-	serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), subject, reply, nil, req, true)
+	s.sendInternalAccountMsgWithReply(acc, subject, reply, nil, req, true)
 
 	timer := time.NewTimer(timeout)
 	for i := uint64(0); i < amount; i++ {
@@ -879,8 +1224,8 @@ func (s *Server) memphisGetMessagesByFilter(streamName, filterSubject string, st
 
 cleanup:
 	timer.Stop()
-	serv.unsubscribeOnGlobalAcc(sub)
-	err = serv.memphisRemoveConsumer(streamName, durableName)
+	s.unsubscribeOnAcc(acc, sub)
+	err = s.memphisRemoveConsumer(tenantName, streamName, durableName)
 	if err != nil {
 		return msgs, err
 	}
@@ -888,10 +1233,12 @@ cleanup:
 }
 
 func (s *Server) queueSubscribe(subj, queueGroupName string, cb simplifiedMsgHandler) error {
-	acc := s.GlobalAccount()
-	c := acc.ic
+	return s.queueSubscribeOnAcc(s.GlobalAccount(), subj, queueGroupName, cb)
+}
 
+func (s *Server) queueSubscribeOnAcc(acc *Account, subj, queueGroupName string, cb simplifiedMsgHandler) error {
 	acc.mu.Lock()
+	c := acc.internalClient()
 	acc.isid++
 	sid := strconv.FormatUint(acc.isid, 10)
 	acc.mu.Unlock()
@@ -916,7 +1263,9 @@ func (s *Server) subscribeOnGlobalAcc(subj, sid string, cb simplifiedMsgHandler)
 }
 
 func (s *Server) subscribeOnAcc(acc *Account, subj, sid string, cb simplifiedMsgHandler) (*subscription, error) {
-	c := acc.ic
+	acc.mu.Lock()
+	c := acc.internalClient()
+	acc.mu.Unlock()
 	wcb := func(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 		cb(c, subject, reply, rmsg)
 	}
@@ -931,16 +1280,22 @@ func (s *Server) unsubscribeOnGlobalAcc(sub *subscription) error {
 }
 
 func (s *Server) unsubscribeOnAcc(acc *Account, sub *subscription) error {
-	c := acc.ic
+	acc.mu.Lock()
+	c := acc.internalClient()
+	acc.mu.Unlock()
 	return c.processUnsub(sub.sid)
 }
 
 func (s *Server) respondOnGlobalAcc(reply string, msg []byte) {
-	acc := s.GlobalAccount()
-	s.sendInternalAccountMsg(acc, reply, msg)
+	s.respondOnAcc(s.GlobalAccount(), reply, msg)
+}
+
+// the reply is echoed since the requests of this broker are waited for through the same internal client
+func (s *Server) respondOnAcc(acc *Account, reply string, msg []byte) {
+	s.sendInternalAccountMsgWithReply(acc, reply, _EMPTY_, nil, msg, true)
 }
 
-func (s *Server) ResendPoisonMessage(subject string, data, headers []byte) error {
+func (s *Server) ResendPoisonMessage(tenantName, subject string, data, headers []byte) error {
 	hdrs := make(map[string]string)
 	err := json.Unmarshal(headers, &hdrs)
 	if err != nil {
@@ -953,7 +1308,11 @@ func (s *Server) ResendPoisonMessage(subject string, data, headers []byte) error
 		delete(hdrs, "producedBy")
 	}
 
-	s.sendInternalMsgWithHeaderLocked(s.GlobalAccount(), subject, hdrs, data)
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return err
+	}
+	s.sendInternalMsgWithHeaderLocked(acc, subject, hdrs, data)
 	return nil
 }
 
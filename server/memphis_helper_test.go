@@ -16,8 +16,26 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
 
 func TestMemphisGetMsgs(t *testing.T) {
@@ -110,6 +128,141 @@ func TestMemphisGetMsgs(t *testing.T) {
 	}
 }
 
+func TestMemphisGetTenantMsgs(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	acc, _ := s.LookupOrRegisterAccount("acme")
+	if err := acc.EnableJetStream(getTenantJsLimits(models.TenantLimits{})); err != nil {
+		t.Fatalf("Unexpected error enabling JetStream for the tenant: %v", err)
+	}
+	mset, err := acc.addStream(&StreamConfig{Name: "orders", Subjects: []string{"orders"}, Storage: MemoryStorage})
+	if err != nil {
+		t.Fatalf("Unexpected error adding stream: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		s.sendInternalAccountMsg(acc, "orders", []byte("order"))
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if state := mset.state(); state.Msgs != 2 {
+			return fmt.Errorf("Expected 2 messages, got %d", state.Msgs)
+		}
+		return nil
+	})
+
+	msgs, err := s.memphisGetTenantMsgs("acme", "", "orders", 1, 2, 1*time.Second, false)
+	if err != nil {
+		t.Fatalf("Unexpected error getting tenant messages: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 tenant messages, got %d", len(msgs))
+	}
+	if _, err := s.memphisGetMsgs("", "orders", 1, 1, 1*time.Second, false); err == nil {
+		t.Fatalf("Expected the tenant stream not to be visible from the global tenant")
+	}
+}
+
+func TestMemphisTenantUpdates(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	s.memphis.nuid = nuid.New()
+	if err := s.ListenForTenantsUpdates(); err != nil {
+		t.Fatalf("Unexpected error listening for tenants updates: %v", err)
+	}
+
+	tenant := models.Tenant{Name: "acme", Limits: models.TenantLimits{MaxStreams: 2}}
+	if err := s.broadcastTenantUpdate(tenantActionPut, tenant); err != nil {
+		t.Fatalf("Unexpected error broadcasting the tenant: %v", err)
+	}
+	acc, err := s.LookupAccount("acme")
+	if err != nil || !acc.JetStreamEnabled() {
+		t.Fatalf("Expected the tenant account to be served with JetStream: %v", err)
+	}
+	if limits := acc.JetStreamUsage().Limits.MaxStreams; limits != 2 {
+		t.Fatalf("Expected the tenant limits to be applied, got max streams %d", limits)
+	}
+
+	if err := s.broadcastTenantUpdate(tenantActionRemove, tenant); err != nil {
+		t.Fatalf("Unexpected error broadcasting the tenant removal: %v", err)
+	}
+	if acc.JetStreamEnabled() {
+		t.Fatalf("Expected JetStream to be disabled for the removed tenant")
+	}
+	if err := s.broadcastTenantUpdate(tenantActionPut, models.Tenant{Name: globalTenantName}); err == nil {
+		t.Fatalf("Expected an update of the global tenant to be rejected")
+	}
+}
+
+func TestMemphisTenantClientAuth(t *testing.T) {
+	opts := DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	opts.Authorization = "memphis"
+	s := RunServer(&opts)
+	defer s.Shutdown()
+
+	prevGetApplicationUserTenant := getApplicationUserTenant
+	defer func() { getApplicationUserTenant = prevGetApplicationUserTenant }()
+	getApplicationUserTenant = func(username string) (string, error) {
+		switch username {
+		case "app":
+			return "acme", nil
+		case "root":
+			return globalTenantName, nil
+		}
+		return _EMPTY_, errors.New("User " + username + " does not exist")
+	}
+
+	acc, err := s.registerTenantAccount(models.Tenant{Name: "acme"})
+	if err != nil {
+		t.Fatalf("Unexpected error registering the tenant: %v", err)
+	}
+	tenantOrders, err := acc.addStream(&StreamConfig{Name: "orders", Subjects: []string{"orders"}, Storage: MemoryStorage})
+	if err != nil {
+		t.Fatalf("Unexpected error adding the tenant stream: %v", err)
+	}
+	globalOrders, err := s.GlobalAccount().addStream(&StreamConfig{Name: "orders", Subjects: []string{"orders"}, Storage: MemoryStorage})
+	if err != nil {
+		t.Fatalf("Unexpected error adding the global stream: %v", err)
+	}
+
+	// NATS CLI connections are authenticated like every NATS SDK but skip the connections bookkeeping
+	nc := natsConnect(t, s.ClientURL(), nats.Name("NATS CLI"), nats.Token("app::memphis"))
+	defer nc.Close()
+	if _, err := nc.Request("orders", []byte("order"), 2*time.Second); err != nil {
+		t.Fatalf("Unexpected error producing to the tenant station: %v", err)
+	}
+	if state := tenantOrders.state(); state.Msgs != 1 {
+		t.Fatalf("Expected the message to be stored by the tenant, got %d messages", state.Msgs)
+	}
+	if state := globalOrders.state(); state.Msgs != 0 {
+		t.Fatalf("Expected the global stream to stay empty, got %d messages", state.Msgs)
+	}
+
+	rc := natsConnect(t, s.ClientURL(), nats.Name("NATS CLI"), nats.Token("root::memphis"))
+	defer rc.Close()
+	if _, err := rc.Request("orders", []byte("order"), 2*time.Second); err != nil {
+		t.Fatalf("Unexpected error producing to the global station: %v", err)
+	}
+	if state := globalOrders.state(); state.Msgs != 1 {
+		t.Fatalf("Expected the root user to produce in the global tenant, got %d messages", state.Msgs)
+	}
+
+	if _, err := nats.Connect(s.ClientURL(), nats.Name("NATS CLI"), nats.Token("nobody::memphis")); err == nil {
+		t.Fatalf("Expected a user without a tenant to be rejected")
+	}
+}
+
 func TestValidateName(t *testing.T) {
 	validName := "abc123._-"
 	invalidName := "$isWhatINeed(hey,hey)"
@@ -122,3 +275,1352 @@ func TestValidateName(t *testing.T) {
 		t.Error()
 	}
 }
+
+func TestMemphisStreamSnapshotRestore(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{
+		Name:     "foo",
+		Storage:  FileStorage,
+		Replicas: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error adding stream: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		s.sendInternalAccountMsg(s.GlobalAccount(), "foo", []byte("Hello World!"))
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if state := mset.state(); state.Msgs != 10 {
+			return fmt.Errorf("Expected 10 messages, got %d", state.Msgs)
+		}
+		return nil
+	})
+
+	var snapshot bytes.Buffer
+	resp, err := s.memphisStreamSnapshot(globalTenantName, "foo", &snapshot)
+	if err != nil {
+		t.Fatalf("Unexpected error taking snapshot: %v", err)
+	}
+	if snapshot.Len() == 0 {
+		t.Fatalf("Expected a non empty snapshot")
+	}
+
+	if err = mset.delete(); err != nil {
+		t.Fatalf("Unexpected error deleting stream: %v", err)
+	}
+
+	err = s.memphisStreamRestore(globalTenantName, *resp.Config, *resp.State, &snapshot)
+	if err != nil {
+		t.Fatalf("Unexpected error restoring snapshot: %v", err)
+	}
+
+	restored, err := s.GlobalAccount().lookupStream("foo")
+	if err != nil {
+		t.Fatalf("Expected restored stream: %v", err)
+	}
+	if state := restored.state(); state.Msgs != 10 {
+		t.Fatalf("Expected 10 restored messages, got %d", state.Msgs)
+	}
+}
+
+func TestMemphisPublishRateLimits(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	sn, err := StationNameFromStr("limited")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	setStationRateLimits(globalTenantName, sn, models.RateLimits{MsgsPerSec: 2})
+	defer setStationRateLimits(globalTenantName, sn, models.RateLimits{})
+	setUserRateLimits("app", models.RateLimits{BytesPerSec: 1000})
+	defer setUserRateLimits("app", models.RateLimits{})
+
+	c := &client{kind: CLIENT, memphisInfo: memphisClientInfo{username: "app"}}
+	acc := s.GlobalAccount()
+
+	// internal streams are never limited
+	for i := 0; i < 5; i++ {
+		if err := s.checkPublishRateLimits(c, acc, "$memphis_syslogs", nil, 10); err != nil {
+			t.Fatalf("Unexpected rate limit on an internal stream: %v", err)
+		}
+	}
+
+	// wait for the beginning of a fresh window so the whole check runs within the same second
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	for i := 0; i < 2; i++ {
+		if err := s.checkPublishRateLimits(c, acc, sn.Intern(), nil, 10); err != nil {
+			t.Fatalf("Unexpected rate limit error on message %d: %v", i, err)
+		}
+	}
+	if err := s.checkPublishRateLimits(c, acc, sn.Intern(), nil, 10); err == nil {
+		t.Fatalf("Expected the station rate limit to be exceeded")
+	}
+	if err := s.checkPublishRateLimits(c, acc, "other", nil, 970); err != nil {
+		t.Fatalf("Unexpected rate limit error: %v", err)
+	}
+	if err := s.checkPublishRateLimits(c, acc, "other", nil, 11); err == nil {
+		t.Fatalf("Expected the user rate limit to be exceeded")
+	}
+
+	violations := getRateLimitViolations(globalTenantName, sn.Intern())
+	if len(violations) != 1 || violations[0].Scope != rateLimitScopeStation || violations[0].Count == 0 {
+		t.Fatalf("Unexpected station violations: %+v", violations)
+	}
+}
+
+func TestMemphisStationPartitions(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	sn, err := StationNameFromStr("partitioned")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	setStationPartitions(globalTenantName, sn, 4)
+	defer setStationPartitions(globalTenantName, sn, 0)
+
+	acc := s.GlobalAccount()
+	hdr := genHeader(nil, partitionKeyHeader, "customer-1")
+	subject := partitionedSubject(acc, sn.Intern(), sn.Intern()+".final", hdr)
+	if subject != getPartitionSubject(sn.Intern(), getMessagePartition(hdr, 4)) {
+		t.Fatalf("Unexpected partition subject: %s", subject)
+	}
+	for i := 0; i < 10; i++ {
+		if other := partitionedSubject(acc, sn.Intern(), sn.Intern()+".final", hdr); other != subject {
+			t.Fatalf("Messages of the same key landed in different partitions: %s and %s", subject, other)
+		}
+	}
+	if subject := partitionedSubject(acc, "other", "other.final", hdr); subject != "other.final" {
+		t.Fatalf("Unexpected subject of a non partitioned station: %s", subject)
+	}
+
+	assignments := assignPartitions([]string{"c", "a", "b"}, 4)
+	expected := map[string][]int{"a": {0, 3}, "b": {1}, "c": {2}}
+	for member, partitions := range expected {
+		if fmt.Sprint(assignments[member]) != fmt.Sprint(partitions) {
+			t.Fatalf("Unexpected partitions of %s: %v", member, assignments[member])
+		}
+	}
+	if assignments = assignPartitions([]string{"a"}, 4); len(assignments["a"]) != 4 {
+		t.Fatalf("Expected a single member to get all the partitions: %v", assignments)
+	}
+}
+
+func TestMemphisMirrorPromotion(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	origin := models.Station{Name: "orders", TenantName: globalTenantName, StorageType: "memory", Replicas: 1}
+	originName, _ := StationNameFromStr(origin.Name)
+	if err := s.CreateStream(originName, origin); err != nil {
+		t.Fatalf("Unexpected error creating origin stream: %v", err)
+	}
+	mirror := models.Station{Name: "orders-dr", TenantName: globalTenantName, StorageType: "memory", Replicas: 1, Mirror: models.StationMirror{StationName: origin.Name}}
+	mirrorName, _ := StationNameFromStr(mirror.Name)
+	if err := s.CreateStream(mirrorName, mirror); err != nil {
+		t.Fatalf("Unexpected error creating mirror stream: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		s.sendInternalAccountMsg(s.GlobalAccount(), originName.Intern()+".final", []byte("order"))
+	}
+	mset, err := s.GlobalAccount().lookupStream(mirrorName.Intern())
+	if err != nil {
+		t.Fatalf("Expected mirror stream: %v", err)
+	}
+	checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+		if state := mset.state(); state.Msgs != 5 {
+			return fmt.Errorf("Expected 5 mirrored messages, got %d", state.Msgs)
+		}
+		return nil
+	})
+
+	promoted := mirror
+	promoted.Mirror.IsPromoted = true
+	if err := s.memphisUpdateStream(globalTenantName, getStationStreamConfig(mirrorName, promoted)); err != nil {
+		t.Fatalf("Unexpected error promoting the mirror: %v", err)
+	}
+	if mset.isMirror() {
+		t.Fatalf("Expected the promoted stream to stop mirroring")
+	}
+	s.sendInternalAccountMsg(s.GlobalAccount(), mirrorName.Intern()+".final", []byte("order"))
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if state := mset.state(); state.Msgs != 6 || state.LastSeq != 6 {
+			return fmt.Errorf("Expected 6 messages after promotion, got %d", state.Msgs)
+		}
+		return nil
+	})
+}
+
+func TestMemphisAggregateStation(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	for _, name := range []string{"orders-eu", "orders-us"} {
+		sn, _ := StationNameFromStr(name)
+		if err := s.CreateStream(sn, models.Station{Name: name, TenantName: globalTenantName, StorageType: "memory", Replicas: 1}); err != nil {
+			t.Fatalf("Unexpected error creating source stream: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			s.sendInternalAccountMsg(s.GlobalAccount(), sn.Intern()+".final", []byte("order"))
+			s.sendInternalAccountMsg(s.GlobalAccount(), sn.Intern()+".other", []byte("other"))
+		}
+	}
+
+	aggregate := models.Station{
+		Name:        "orders",
+		TenantName:  globalTenantName,
+		StorageType: "memory",
+		Replicas:    1,
+		Sources: []models.StationSource{
+			{StationName: "orders-eu", FilterSubject: "final"},
+			{StationName: "orders-us"},
+		},
+	}
+	sn, _ := StationNameFromStr(aggregate.Name)
+	if err := s.CreateStream(sn, aggregate); err != nil {
+		t.Fatalf("Unexpected error creating aggregate stream: %v", err)
+	}
+	mset, err := s.GlobalAccount().lookupStream(sn.Intern())
+	if err != nil {
+		t.Fatalf("Expected aggregate stream: %v", err)
+	}
+	checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+		if state := mset.state(); state.Msgs != 9 {
+			return fmt.Errorf("Expected 9 aggregated messages, got %d", state.Msgs)
+		}
+		return nil
+	})
+	if filter := getStationFilterSubject(sn, aggregate); filter != _EMPTY_ {
+		t.Fatalf("Expected consumers of an aggregate station not to be filtered, got %q", filter)
+	}
+}
+
+func TestMemphisRegistryCompatibility(t *testing.T) {
+	v1 := `{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}`
+	withOptional := `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"required":["id"]}`
+	withRequired := `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"required":["id","name"]}`
+	retyped := `{"type":"object","properties":{"id":{"type":"string"}},"required":["id"]}`
+	versions := []models.SchemaVersion{{VersionNumber: 1, SchemaContent: v1}}
+
+	for _, tc := range []struct {
+		level      string
+		content    string
+		compatible bool
+	}{
+		{"BACKWARD", withOptional, true},
+		{"BACKWARD", withRequired, false},
+		{"FORWARD", withRequired, true},
+		{"FULL", withRequired, false},
+		{"FULL", retyped, false},
+		{"NONE", retyped, true},
+	} {
+		compatible, err := checkRegistryCompatibility(tc.level, "json", tc.content, versions)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if compatible != tc.compatible {
+			t.Fatalf("Expected compatibility %v for level %s and schema %s", tc.compatible, tc.level, tc.content)
+		}
+	}
+
+	compatible, err := checkRegistryCompatibility("BACKWARD", "protobuf",
+		`syntax = "proto3"; message Order { string id = 1; }`,
+		[]models.SchemaVersion{{VersionNumber: 1, SchemaContent: `syntax = "proto3"; message Order { int32 id = 1; }`}})
+	if err != nil || compatible {
+		t.Fatalf("Expected a changed protobuf field type to be incompatible, got %v, %v", compatible, err)
+	}
+}
+
+func TestMemphisWSEventRouting(t *testing.T) {
+	for _, test := range []struct {
+		event models.WSEvent
+		key   string
+		match bool
+	}{
+		{models.WSEvent{Event: memphisWS_Event_StationCreated, StationName: "orders"}, memphisWS_Subj_MainOverviewData, true},
+		{models.WSEvent{Event: memphisWS_Event_StationDeleted, StationName: "orders"}, memphisWS_Subj_AllStationsData, true},
+		{models.WSEvent{Event: memphisWS_Event_StationCreated, StationName: "orders"}, memphisWS_Subj_StationOverviewData + ".orders", false},
+		{models.WSEvent{Event: memphisWS_Event_CgMemberJoined, StationName: "orders"}, memphisWS_Subj_StationOverviewData + ".orders", true},
+		{models.WSEvent{Event: memphisWS_Event_DlsMessageAdded, StationName: "orders"}, memphisWS_Subj_StationOverviewData + ".payments", false},
+		{models.WSEvent{Event: memphisWS_Event_ProducerDisconnect, StationName: "orders"}, memphisWS_Subj_MainOverviewData, false},
+		{models.WSEvent{Event: memphisWS_Event_SysLog, Data: models.Log{Type: "warn"}}, memphisWS_Subj_SysLogsData + ".warn", true},
+		{models.WSEvent{Event: memphisWS_Event_SysLog, Data: models.Log{Type: "info"}}, memphisWS_Subj_SysLogsData + ".err", false},
+		{models.WSEvent{Event: memphisWS_Event_SysLog, Data: models.Log{Type: "info"}}, memphisWS_Subj_SysLogsData + ".external", true},
+	} {
+		if match := memphisWSEventMatchesSubscription(test.event, test.key); match != test.match {
+			t.Fatalf("Expected event %s to match %s: %v, got %v", test.event.Event, test.key, test.match, match)
+		}
+	}
+}
+
+func TestMemphisWSEventMarksSubscriptionStale(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	filler := func() (any, error) { return models.MainOverviewData{}, nil }
+	overview := &memphisWSSubscription{tenantName: globalTenantName, subj: memphisWS_Subj_MainOverviewData, reqFiller: filler, lastSnapshot: time.Now()}
+	tenantOverview := &memphisWSSubscription{tenantName: "acme", subj: memphisWS_Subj_MainOverviewData, reqFiller: filler, lastSnapshot: time.Now()}
+	syslogs := &memphisWSSubscription{tenantName: globalTenantName, subj: memphisWS_Subj_SysLogsData + ".warn", reqFiller: filler, lastSnapshot: time.Now()}
+	s.memphis.ws.subscriptions = map[string]*memphisWSSubscription{
+		memphisWSKey(globalTenantName, overview.subj): overview,
+		memphisWSKey("acme", tenantOverview.subj):     tenantOverview,
+		memphisWSKey(globalTenantName, syslogs.subj):  syslogs,
+	}
+
+	s.memphisWSPushEvent(models.WSEvent{Event: memphisWS_Event_StationCreated, TenantName: globalTenantName, StationName: "orders"})
+	s.memphisWSPushEvent(models.WSEvent{Event: memphisWS_Event_SysLog, TenantName: globalTenantName, Data: models.Log{Type: "warn"}})
+	if !overview.stale {
+		t.Fatalf("Expected the main overview to be refreshed after a station was created")
+	}
+	if tenantOverview.stale {
+		t.Fatalf("Expected events of the global tenant to not reach other tenants")
+	}
+	if syslogs.stale {
+		t.Fatalf("Expected log lines to not refresh the syslogs snapshot")
+	}
+	if memphisWSReplySubj(memphisWS_Subj_MainOverviewData) == memphisWSEventsSubj(memphisWS_Subj_MainOverviewData) {
+		t.Fatalf("Expected events and snapshots to be sent on different subjects")
+	}
+	if key := memphisWSKey("acme.eu", memphisWS_Subj_MainOverviewData); key != "tenants.acme#eu."+memphisWS_Subj_MainOverviewData {
+		t.Fatalf("Unexpected tenant subscription key %q", key)
+	}
+}
+
+func TestMemphisWSTenantFromToken(t *testing.T) {
+	secret := configuration.JWT_SECRET
+	configuration.JWT_SECRET = "ws-test-secret"
+	defer func() { configuration.JWT_SECRET = secret }()
+
+	sign := func(secret string, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return token
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+
+	tenantName, err := memphisWSTenantName(sign("ws-test-secret", jwt.MapClaims{"tenant_name": "acme", "exp": exp}))
+	if err != nil || tenantName != "acme" {
+		t.Fatalf("Expected tenant acme, got %q, %v", tenantName, err)
+	}
+	tenantName, err = memphisWSTenantName(sign("ws-test-secret", jwt.MapClaims{"exp": exp}))
+	if err != nil || tenantName != globalTenantName {
+		t.Fatalf("Expected the global tenant for tokens without one, got %q, %v", tenantName, err)
+	}
+	if _, err = memphisWSTenantName(sign("other-secret", jwt.MapClaims{"tenant_name": globalTenantName, "exp": exp})); err == nil {
+		t.Fatalf("Expected a token signed with another secret to be rejected")
+	}
+	if _, err = memphisWSTenantName(sign("ws-test-secret", jwt.MapClaims{"tenant_name": "acme", "exp": time.Now().Add(-time.Minute).Unix()})); err == nil {
+		t.Fatalf("Expected an expired token to be rejected")
+	}
+}
+
+func TestMemphisLivenessState(t *testing.T) {
+	for _, test := range []struct {
+		isActive, isDeleted bool
+		connectionState     string
+		expected            string
+	}{
+		{true, false, livenessStateActive, livenessStateActive},
+		{true, false, _EMPTY_, livenessStateActive},
+		{true, false, livenessStateSuspect, livenessStateSuspect},
+		{false, false, livenessStateSuspect, livenessStateDisconnected},
+		{false, true, livenessStateActive, livenessStateDeleted},
+	} {
+		if state := livenessState(test.isActive, test.isDeleted, test.connectionState); state != test.expected {
+			t.Fatalf("Expected state %q for %+v, got %q", test.expected, test, state)
+		}
+	}
+
+	if err := validateLivenessGrace(defaultSuspectGraceSec, defaultDisconnectGraceSec); err != nil {
+		t.Fatalf("Expected the default grace periods to be valid: %v", err)
+	}
+	if err := validateLivenessGrace(livenessMinGraceSec-1, defaultDisconnectGraceSec); err == nil {
+		t.Fatalf("Expected a too short suspect grace period to be rejected")
+	}
+	if err := validateLivenessGrace(defaultSuspectGraceSec, defaultSuspectGraceSec); err == nil {
+		t.Fatalf("Expected a disconnect grace period not greater than the suspect one to be rejected")
+	}
+}
+
+func TestMemphisConnectionsHandlers(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	prevServ, prevConnections, prevProducers, prevConsumers := serv, connectionsCollection, producersCollection, consumersCollection
+	defer func() {
+		serv, connectionsCollection, producersCollection, consumersCollection = prevServ, prevConnections, prevProducers, prevConsumers
+	}()
+	serv = s
+	s.memphis.nuid = nuid.New()
+	if err := s.ListenForConnectionsStatsRequests(); err != nil {
+		t.Fatalf("Unexpected error listening for stats requests: %v", err)
+	}
+
+	// a memphis connection served by this broker
+	cli, _, _ := newClientForServer(s)
+	defer cli.close()
+	connectionId := primitive.NewObjectID()
+	cli.mu.Lock()
+	cli.memphisInfo.connectionId = connectionId
+	cli.mu.Unlock()
+
+	ch := ConnectionsHandler{S: s}
+	request := func(handler gin.HandlerFunc, user models.User, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", user)
+		handler(c)
+		return w
+	}
+	root := models.User{Username: "root", UserType: "root", TenantName: globalTenantName}
+	acmeUser := models.User{Username: "ops", UserType: "management", TenantName: "acme"}
+	connectionDoc := bson.D{{Key: "_id", Value: connectionId}, {Key: "is_active", Value: true}, {Key: "created_by_user", Value: "root"}, {Key: "tenant_name", Value: globalTenantName}}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("list", func(mt *mtest.T) {
+		connectionsCollection, producersCollection, consumersCollection = mt.Coll, mt.Coll, mt.Coll
+		stationId := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "memphis.connections", mtest.FirstBatch, connectionDoc),
+			mtest.CreateCursorResponse(0, "memphis.producers", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "name", Value: "orders-producer"}, {Key: "station_id", Value: stationId}, {Key: "connection_id", Value: connectionId}, {Key: "station_name", Value: "orders"}}),
+			mtest.CreateCursorResponse(0, "memphis.consumers", mtest.FirstBatch,
+				bson.D{{Key: "name", Value: "orders-consumer"}, {Key: "connection_id", Value: connectionId}, {Key: "consumers_group", Value: "billing"}, {Key: "station_name", Value: "orders"}}),
+		)
+		w := request(ch.GetActiveConnections, root, _EMPTY_)
+		if w.Code != 200 {
+			mt.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+		}
+		var connections []models.ExtendedConnection
+		if err := json.Unmarshal(w.Body.Bytes(), &connections); err != nil {
+			mt.Fatalf("Unexpected error decoding the response: %v", err)
+		}
+		if len(connections) != 1 || connections[0].ID != connectionId {
+			mt.Fatalf("Expected the active connection, got %+v", connections)
+		}
+		conn := connections[0]
+		if len(conn.Producers) != 1 || conn.Producers[0].Name != "orders-producer" || len(conn.Consumers) != 1 || conn.Consumers[0].ConsumersGroup != "billing" {
+			mt.Fatalf("Expected the connection's producer and consumer, got %+v %+v", conn.Producers, conn.Consumers)
+		}
+		if conn.Stats == nil || conn.Stats.ConnectionId != connectionId.Hex() {
+			mt.Fatalf("Expected the runtime stats of the connection, got %+v", conn.Stats)
+		}
+	})
+
+	mt.Run("filter", func(mt *mtest.T) {
+		connectionsCollection = mt.Coll
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "memphis.connections", mtest.FirstBatch))
+		w := request(ch.GetActiveConnections, acmeUser, _EMPTY_)
+		if w.Code != 200 || strings.TrimSpace(w.Body.String()) != "[]" {
+			mt.Fatalf("Expected an empty list, got %d: %s", w.Code, w.Body.String())
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if tenant, ok := filter.Lookup("tenant_name").StringValueOK(); !ok || tenant != "acme" {
+			mt.Fatalf("Expected the connections of the caller's tenant only, got filter %v", filter)
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "memphis.connections", mtest.FirstBatch))
+		request(ch.GetActiveConnections, root, _EMPTY_)
+		filter = mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if _, err := filter.LookupErr("$or"); err != nil {
+			mt.Fatalf("Expected the global tenant to include connections without a tenant, got filter %v", filter)
+		}
+	})
+
+	mt.Run("disconnect", func(mt *mtest.T) {
+		connectionsCollection, producersCollection, consumersCollection = mt.Coll, mt.Coll, mt.Coll
+		body := fmt.Sprintf(`{"connection_id":%q}`, connectionId.Hex())
+
+		if w := request(ch.DisconnectConnection, root, `{"connection_id":"invalid"}`); !strings.Contains(w.Body.String(), "Invalid connection id") {
+			mt.Fatalf("Expected an invalid connection id to be rejected, got %s", w.Body.String())
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "memphis.connections", mtest.FirstBatch, connectionDoc))
+		if w := request(ch.DisconnectConnection, acmeUser, body); !strings.Contains(w.Body.String(), "does not exist") {
+			mt.Fatalf("Expected a connection of another tenant to be hidden, got %s", w.Body.String())
+		}
+
+		disconnects := make(chan string, 1)
+		sub, err := s.subscribeOnGlobalAcc(CONNECTIONS_DISCONNECT_SUBJ, CONNECTIONS_DISCONNECT_SUBJ+"_test_sid", func(_ *client, _, _ string, msg []byte) {
+			disconnects <- strings.TrimSuffix(string(msg), CR_LF)
+		})
+		if err != nil {
+			mt.Fatalf("Unexpected error subscribing: %v", err)
+		}
+		defer s.unsubscribeOnGlobalAcc(sub)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "memphis.connections", mtest.FirstBatch, connectionDoc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, "memphis.producers", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "memphis.consumers", mtest.FirstBatch),
+		)
+		if w := request(ch.DisconnectConnection, root, body); w.Code != 200 {
+			mt.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+		}
+		var disconnectedBy string
+		for _, evt := range mt.GetAllStartedEvents() {
+			if evt.CommandName == "update" {
+				update := evt.Command.Lookup("updates").Array().Index(0).Value().Document()
+				disconnectedBy, _ = update.Lookup("u", "$set", "disconnected_by").StringValueOK()
+			}
+		}
+		if disconnectedBy != "root" {
+			mt.Fatalf("Expected the connection to be marked as disconnected by the user, got %q", disconnectedBy)
+		}
+		select {
+		case id := <-disconnects:
+			if id != connectionId.Hex() {
+				mt.Fatalf("Unexpected connection id sent to the brokers: %q", id)
+			}
+		case <-time.After(2 * time.Second):
+			mt.Fatalf("Expected the disconnection to be sent to the brokers")
+		}
+	})
+
+	// the broker serving the connection closes its clients, their disconnection fails to be stored without a db
+	dbClient, err := mongo.NewClient()
+	if err != nil {
+		t.Fatalf("Unexpected error creating a db client: %v", err)
+	}
+	connectionsCollection = dbClient.Database("memphis").Collection("connections")
+	if err := s.ListenForConnectionsDisconnectRequests(); err != nil {
+		t.Fatalf("Unexpected error listening for disconnect requests: %v", err)
+	}
+	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), CONNECTIONS_DISCONNECT_SUBJ, _EMPTY_, nil, []byte(connectionId.Hex()), true)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if n := s.NumClients(); n > 0 {
+			return fmt.Errorf("%d clients are still connected", n)
+		}
+		return nil
+	})
+}
+
+func TestMemphisRedeliveryBackoff(t *testing.T) {
+	for _, test := range []struct {
+		backoff          *models.RedeliveryBackoff
+		maxMsgDeliveries int
+		valid            bool
+	}{
+		{nil, 2, true},
+		{&models.RedeliveryBackoff{Policy: "Exponential"}, 5, true},
+		{&models.RedeliveryBackoff{Policy: "exponential"}, 2, false},
+		{&models.RedeliveryBackoff{Policy: "exponential", Multiplier: 0.5}, 5, false},
+		{&models.RedeliveryBackoff{Policy: "exponential", StepsMs: []int64{1000}}, 5, false},
+		{&models.RedeliveryBackoff{Policy: "stepped", StepsMs: []int64{1000, 5000, 10000}}, 5, true},
+		{&models.RedeliveryBackoff{Policy: "stepped", StepsMs: []int64{1000, 5000, 10000, 20000}}, 5, false},
+		{&models.RedeliveryBackoff{Policy: "stepped", StepsMs: []int64{0}}, 5, false},
+		{&models.RedeliveryBackoff{Policy: "stepped"}, 5, false},
+		{&models.RedeliveryBackoff{Policy: "linear"}, 5, false},
+	} {
+		if err := validateRedeliveryBackoff(test.backoff, test.maxMsgDeliveries); (err == nil) != test.valid {
+			t.Fatalf("Expected valid=%v for %+v with %d deliveries, got %v", test.valid, test.backoff, test.maxMsgDeliveries, err)
+		}
+	}
+
+	expected := []int64{1000, 500, 1000, 2000, 3000}
+	exponential := &models.RedeliveryBackoff{Policy: redeliveryBackoffExponential, InitialDelayMs: 500, MaxDelayMs: 3000}
+	if schedule := getRedeliveryScheduleMs(exponential, 1000, 6); fmt.Sprint(schedule) != fmt.Sprint(expected) {
+		t.Fatalf("Expected schedule %v, got %v", expected, schedule)
+	}
+	if schedule := getRedeliveryScheduleMs(nil, 1000, 6); schedule != nil {
+		t.Fatalf("Expected no schedule without a backoff, got %v", schedule)
+	}
+
+	consumer := models.Consumer{
+		MaxAckTimeMs:      1000,
+		MaxMsgDeliveries:  4,
+		RedeliveryBackoff: &models.RedeliveryBackoff{Policy: redeliveryBackoffStepped, StepsMs: []int64{5000, 10000}},
+	}
+	cc := getConsumerConfig("cg", consumer, StationName{}, models.Station{})
+	if len(cc.BackOff) != 3 || cc.BackOff[0] != time.Second || cc.BackOff[2] != 10*time.Second {
+		t.Fatalf("Unexpected backoff %v", cc.BackOff)
+	}
+	if cc.MaxDeliver <= len(cc.BackOff) {
+		t.Fatalf("Expected max deliver %d to exceed the backoff length %d", cc.MaxDeliver, len(cc.BackOff))
+	}
+
+	changed := consumer
+	changed.RedeliveryBackoff = &models.RedeliveryBackoff{Policy: redeliveryBackoffStepped, StepsMs: []int64{5000, 20000}}
+	if !isSameRedeliverySchedule(consumer, consumer) || isSameRedeliverySchedule(consumer, changed) {
+		t.Fatalf("Unexpected redelivery schedule comparison")
+	}
+}
+
+func TestMemphisContentFilter(t *testing.T) {
+	for _, test := range []struct {
+		filter  models.ContentFilter
+		hdr     map[string]string
+		payload string
+		matches bool
+	}{
+		{models.ContentFilter{JsonPath: "$.type", Operator: "eq", Value: "order"}, nil, `{"type":"order"}`, true},
+		{models.ContentFilter{JsonPath: "$.type", Operator: "eq", Value: "order"}, nil, `{"type":"refund"}`, false},
+		{models.ContentFilter{JsonPath: "items[1].qty", Operator: "gte", Value: "2"}, nil, `{"items":[{"qty":1},{"qty":2.0}]}`, true},
+		{models.ContentFilter{JsonPath: "$.amount", Operator: "eq", Value: "10"}, nil, `{"amount":10.0}`, true},
+		{models.ContentFilter{JsonPath: "$.amount", Operator: "lt", Value: "10"}, nil, `not json`, false},
+		{models.ContentFilter{JsonPath: "$.meta", Operator: "not_exists"}, nil, `{"type":"order"}`, true},
+		{models.ContentFilter{JsonPath: "$.type", Operator: "eq", Value: "order"}, nil, `{"meta":{"tags":["a",{"b":[1,2]}]},"type":"order"}`, true},
+		{models.ContentFilter{JsonPath: "$.meta", Operator: "contains", Value: `"b":[1,2]`}, nil, `{"meta":{"b":[1,2]},"type":"order"}`, true},
+		{models.ContentFilter{JsonPath: "items[2]", Operator: "exists"}, nil, `{"items":[1,2]}`, false},
+		{models.ContentFilter{Header: "region", Operator: "eq", Value: "eu"}, map[string]string{"region": "eu"}, `{}`, true},
+		{models.ContentFilter{Header: "region", Operator: "neq", Value: "eu"}, map[string]string{"region": "us"}, `{}`, true},
+		{models.ContentFilter{Header: "region", Operator: "exists"}, nil, `{}`, false},
+	} {
+		cf, err := compileContentFilter(&test.filter)
+		if err != nil {
+			t.Fatalf("Unexpected error compiling %+v: %v", test.filter, err)
+		}
+		var hdr []byte
+		if test.hdr != nil {
+			hdr = genHeader(nil, "region", test.hdr["region"])
+		}
+		if matches := cf.matches(hdr, []byte(test.payload)); matches != test.matches {
+			t.Fatalf("Expected match=%v for %+v on %s, got %v", test.matches, test.filter, test.payload, matches)
+		}
+	}
+
+	for _, invalid := range []models.ContentFilter{
+		{Operator: "eq", Value: "x"},
+		{Header: "a", JsonPath: "$.a", Operator: "eq"},
+		{JsonPath: "$.a[x]", Operator: "eq"},
+		{JsonPath: "$.", Operator: "exists"},
+		{JsonPath: "$.a", Operator: "gt", Value: "high"},
+		{JsonPath: "$.a", Operator: "matches"},
+	} {
+		if _, err := compileContentFilter(&invalid); err == nil {
+			t.Fatalf("Expected %+v to be rejected", invalid)
+		}
+	}
+
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	station := models.Station{Name: "filtered", TenantName: globalTenantName, StorageType: "memory", Replicas: 1}
+	stationName, _ := StationNameFromStr(station.Name)
+	if err := s.CreateStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		msgType := "order"
+		if i%2 == 1 {
+			msgType = "refund"
+		}
+		s.sendInternalAccountMsg(s.GlobalAccount(), stationName.Intern()+".final", []byte(fmt.Sprintf(`{"type":%q,"id":%d}`, msgType, i)))
+	}
+
+	consumer := models.Consumer{ContentFilter: &models.ContentFilter{JsonPath: "$.type", Operator: "eq", Value: "order"}}
+	cc := getConsumerConfig("orders-cg", consumer, stationName, station)
+	if err := s.memphisAddConsumer(globalTenantName, stationName.Intern(), &cc); err != nil {
+		t.Fatalf("Unexpected error creating consumer: %v", err)
+	}
+
+	delivered := make(chan []byte, 10)
+	sub, err := s.subscribeOnGlobalAcc("_INBOX.filtered", "_INBOX.filtered_sid", func(_ *client, _, reply string, msg []byte) {
+		if strings.HasPrefix(reply, jsAckPre) {
+			delivered <- copyBytes(msg)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+	nextSubj := fmt.Sprintf(JSApiRequestNextT, stationName.Intern(), cc.Durable)
+	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), nextSubj, "_INBOX.filtered", nil, []byte(`{"batch":10,"expires":500000000}`), true)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-delivered:
+			if !bytes.Contains(msg, []byte(`"order"`)) {
+				t.Fatalf("Unexpected message delivered: %s", msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected 3 matching messages, got %d", i)
+		}
+	}
+
+	mset, err := s.GlobalAccount().lookupStream(stationName.Intern())
+	if err != nil {
+		t.Fatalf("Unexpected error looking up stream: %v", err)
+	}
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		info := mset.lookupConsumer(cc.Durable).info()
+		if info.ContentFilter == nil || info.ContentFilter.Matched != 3 || info.ContentFilter.Skipped != 3 {
+			return fmt.Errorf("Unexpected content filter stats %+v", info.ContentFilter)
+		}
+		if info.NumPending != 0 {
+			return fmt.Errorf("Expected no pending messages, got %d", info.NumPending)
+		}
+		return nil
+	})
+	select {
+	case msg := <-delivered:
+		t.Fatalf("Unexpected message delivered: %s", msg)
+	default:
+	}
+
+	// Skipped messages must be acked in the consumer store, not only in memory.
+	state, err := mset.lookupConsumer(cc.Durable).store.State()
+	if err != nil {
+		t.Fatalf("Unexpected error reading consumer state: %v", err)
+	}
+	for _, seq := range []uint64{2, 4, 6} {
+		if _, ok := state.Pending[seq]; ok {
+			t.Fatalf("Expected skipped message %d to be acked in the store, state %+v", seq, state)
+		}
+	}
+}
+
+func TestMemphisCompactedStation(t *testing.T) {
+	if err := validateCompaction(0, 3, models.MirrorSchema{}, nil); err == nil {
+		t.Fatalf("Expected a partitioned compacted station to be rejected")
+	}
+	if err := validateCompaction(maxMsgsPerKeyLimit+1, 0, models.MirrorSchema{}, nil); err == nil {
+		t.Fatalf("Expected too many messages per key to be rejected")
+	}
+
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	station := models.Station{Name: "users", TenantName: globalTenantName, StorageType: "memory", Replicas: 1, Compacted: true}
+	stationName, _ := StationNameFromStr(station.Name)
+	if err := s.CreateStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+
+	acks := make(chan JSPubAckResponse, 10)
+	sub, err := s.subscribeOnGlobalAcc("_INBOX.compacted", "_INBOX.compacted_sid", func(_ *client, _, _ string, msg []byte) {
+		var ack JSPubAckResponse
+		json.Unmarshal(msg, &ack)
+		acks <- ack
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+
+	for _, produced := range []struct {
+		hdr       map[string]string
+		msg       string
+		expectErr bool
+	}{
+		{map[string]string{compactionKeyHeader: "user-1"}, `{"name":"a"}`, false},
+		{map[string]string{compactionKeyHeader: "user-2"}, `{"name":"b"}`, false},
+		{map[string]string{compactionKeyHeader: "user-1"}, `{"name":"c"}`, false},
+		{nil, `{"name":"d"}`, true},
+		{map[string]string{compactionKeyHeader: "user-1", JSMsgRollup: JSMsgRollupAll}, `{}`, true},
+		{map[string]string{compactionKeyHeader: "user-2", compactionTombstoneHeader: "true"}, ``, false},
+	} {
+		s.sendInternalAccountMsgWithReply(s.GlobalAccount(), stationName.Intern()+".final", "_INBOX.compacted", produced.hdr, []byte(produced.msg), true)
+		select {
+		case ack := <-acks:
+			if (ack.Error != nil) != produced.expectErr {
+				t.Fatalf("Unexpected publish result for %+v: %+v", produced, ack.Error)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a publish ack")
+		}
+	}
+
+	mset, err := s.GlobalAccount().lookupStream(stationName.Intern())
+	if err != nil {
+		t.Fatalf("Unexpected error looking up stream: %v", err)
+	}
+	if state := mset.state(); state.Msgs != 2 {
+		t.Fatalf("Expected the last value of 2 keys, got %d messages", state.Msgs)
+	}
+
+	cc := getConsumerConfig("state", models.Consumer{}, stationName, station)
+	if err := s.memphisAddConsumer(globalTenantName, stationName.Intern(), &cc); err != nil {
+		t.Fatalf("Unexpected error creating consumer: %v", err)
+	}
+	o := mset.lookupConsumer(cc.Durable)
+	if info := o.info(); info.NumPending != 2 {
+		t.Fatalf("Expected the consumer to bootstrap 2 keys, got %d", info.NumPending)
+	}
+	o.mu.Lock()
+	pmsg, _, err := o.getNextMsg()
+	o.mu.Unlock()
+	if err != nil {
+		t.Fatalf("Unexpected error loading the first key: %v", err)
+	}
+	if pmsg.subj != getCompactedKeySubject(stationName.Intern(), []byte("user-1")) || string(pmsg.msg) != `{"name":"c"}` {
+		t.Fatalf("Unexpected first value %q on %q", pmsg.msg, pmsg.subj)
+	}
+}
+
+func TestMemphisKvBucket(t *testing.T) {
+	if err := validateKvBucketName("config.prod"); err == nil {
+		t.Fatalf("Expected a bucket name with '.' to be rejected")
+	}
+	if err := validateKvKey("a b"); err == nil {
+		t.Fatalf("Expected a key with spaces to be rejected")
+	}
+	if err := validateKvWatchKey("services.*.>"); err != nil {
+		t.Fatalf("Unexpected error validating a wildcard watch key: %v", err)
+	}
+
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	bucket := models.KvBucket{Name: "config", TenantName: globalTenantName, History: 3, StorageType: "memory", Replicas: 1}
+	if err := s.createKvBucketStream(bucket); err != nil {
+		t.Fatalf("Unexpected error creating bucket: %v", err)
+	}
+
+	watched := make(chan string, 10)
+	sub, err := s.subscribeOnGlobalAcc("_INBOX.kv_watch", "_INBOX.kv_watch_sid", func(_ *client, subject, _ string, _ []byte) {
+		watched <- subject
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+
+	if _, err := s.kvWatch(globalTenantName, bucket.Name, "services.>", "_INBOX.kv_watch", false); err != nil {
+		t.Fatalf("Unexpected error watching bucket: %v", err)
+	}
+
+	rev1, err := s.kvPut(globalTenantName, bucket.Name, "services.a", []byte("1"), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error putting key: %v", err)
+	}
+	zero := uint64(0)
+	if _, err := s.kvPut(globalTenantName, bucket.Name, "services.a", []byte("x"), &zero); !errors.Is(err, ErrKvWrongRevision) {
+		t.Fatalf("Expected creating an existing key to fail, got %v", err)
+	}
+	rev2, err := s.kvPut(globalTenantName, bucket.Name, "services.a", []byte("2"), &rev1)
+	if err != nil {
+		t.Fatalf("Unexpected error updating key: %v", err)
+	}
+	if _, err := s.kvPut(globalTenantName, bucket.Name, "services.a", []byte("3"), &rev1); !errors.Is(err, ErrKvWrongRevision) {
+		t.Fatalf("Expected a stale revision to be rejected, got %v", err)
+	}
+
+	entry, err := s.kvGet(globalTenantName, bucket.Name, "services.a")
+	if err != nil || entry.Value != "2" || entry.Revision != rev2 {
+		t.Fatalf("Unexpected entry %+v: %v", entry, err)
+	}
+	history, err := s.kvHistory(globalTenantName, bucket.Name, "services.a")
+	if err != nil || len(history) != 2 {
+		t.Fatalf("Expected 2 revisions, got %+v: %v", history, err)
+	}
+
+	if _, err := s.kvPut(globalTenantName, bucket.Name, "services.b", []byte("1"), nil); err != nil {
+		t.Fatalf("Unexpected error putting key: %v", err)
+	}
+	if _, err := s.kvDelete(globalTenantName, bucket.Name, "services.b", false); err != nil {
+		t.Fatalf("Unexpected error deleting key: %v", err)
+	}
+	if _, err := s.kvGet(globalTenantName, bucket.Name, "services.b"); err != ErrKvKeyNotFound {
+		t.Fatalf("Expected a deleted key to be missing, got %v", err)
+	}
+	if _, err := s.kvPut(globalTenantName, bucket.Name, "services.b", []byte("again"), &zero); err != nil {
+		t.Fatalf("Expected a deleted key to be created again: %v", err)
+	}
+
+	// values are not mistaken for headers
+	if _, err := s.kvPut(globalTenantName, bucket.Name, "services.c", []byte("a\r\n\r\nb"), nil); err != nil {
+		t.Fatalf("Unexpected error putting key: %v", err)
+	}
+
+	entries, err := s.kvEntries(globalTenantName, bucket.Name)
+	if err != nil || len(entries) != 3 || entries[0].Key != "services.a" || entries[1].Value != "again" || entries[2].Value != "a\r\n\r\nb" {
+		t.Fatalf("Unexpected entries %+v: %v", entries, err)
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case subject := <-watched:
+			if !strings.HasPrefix(subject, getKvKeySubject(bucket.Name, "services.")) {
+				t.Fatalf("Unexpected watched subject %q", subject)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected update %d to be watched", i+1)
+		}
+	}
+}
+
+func TestMemphisLargePayload(t *testing.T) {
+	if _, _, err := parseClaimCheck([]byte("NATS/1.0\r\n" + claimCheckHeader + ": obj\r\n" + chunksTotalHeader + ": 0\r\n\r\n")); err == nil {
+		t.Fatalf("Expected a claim check without chunks to be rejected")
+	}
+
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	station := models.Station{Name: "videos", TenantName: globalTenantName, StorageType: "memory", Replicas: 1, IsNative: true}
+	stationName, _ := StationNameFromStr(station.Name)
+	if err := s.CreateStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+	if err := s.createObjectsStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating objects stream: %v", err)
+	}
+
+	acks := make(chan JSPubAckResponse, 10)
+	sub, err := s.subscribeOnGlobalAcc("_INBOX.large", "_INBOX.large_sid", func(_ *client, _, _ string, msg []byte) {
+		var ack JSPubAckResponse
+		json.Unmarshal(msg, &ack)
+		acks <- ack
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+
+	publish := func(subject string, hdr map[string]string, msg string) *ApiError {
+		s.sendInternalAccountMsgWithReply(s.GlobalAccount(), subject, "_INBOX.large", hdr, []byte(msg), true)
+		select {
+		case ack := <-acks:
+			return ack.Error
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a publish ack")
+		}
+		return nil
+	}
+
+	chunkSubject := getObjectSubject(stationName.Intern(), "obj1")
+	for _, chunk := range []string{"hello ", "large "} {
+		if err := publish(chunkSubject, nil, chunk); err != nil {
+			t.Fatalf("Unexpected error uploading chunk: %+v", err)
+		}
+	}
+
+	claim := map[string]string{claimCheckHeader: "obj1", chunksTotalHeader: "3", payloadSizeHeader: "19"}
+	if err := publish(stationName.Intern()+".final", claim, ""); err == nil {
+		t.Fatalf("Expected a claim check with missing chunks to be rejected")
+	}
+	if err := publish(chunkSubject, nil, "payload"); err != nil {
+		t.Fatalf("Unexpected error uploading chunk: %+v", err)
+	}
+	if err := publish(stationName.Intern()+".final", claim, ""); err != nil {
+		t.Fatalf("Unexpected error producing the claim check: %+v", err)
+	}
+	// the objects stream is looked up through its leader when it is placed on other servers
+	claimHdr := genHeader(genHeader(genHeader(nil, claimCheckHeader, "obj1"), chunksTotalHeader, "3"), payloadSizeHeader, "19")
+	if err := validateRemoteClaimCheck(s, s.GlobalAccount(), stationName.Intern(), claimHdr); err != nil {
+		t.Fatalf("Unexpected error validating the claim check through the JS API: %v", err)
+	}
+	claimHdr = genHeader(genHeader(genHeader(nil, claimCheckHeader, "obj2"), chunksTotalHeader, "1"), payloadSizeHeader, "1")
+	if err := validateRemoteClaimCheck(s, s.GlobalAccount(), stationName.Intern(), claimHdr); err == nil {
+		t.Fatalf("Expected a claim check without uploaded chunks to be rejected")
+	}
+
+	station.RetentionType, station.RetentionValue = "bytes", 1024*1024
+	if err := s.createObjectsStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error updating the objects stream: %v", err)
+	}
+	objects, err := s.GlobalAccount().lookupStream(getObjectsStreamName(stationName.Intern()))
+	if err != nil {
+		t.Fatalf("Unexpected error looking up the objects stream: %v", err)
+	}
+	if maxBytes := objects.config().MaxBytes; maxBytes != 1024*1024 {
+		t.Fatalf("Expected the chunks to follow the station retention, got max bytes %d", maxBytes)
+	}
+
+	cc := claimCheck{objectId: "obj1", chunksTotal: 3, payloadSize: 19}
+	payload, truncated, err := s.getLargePayload(globalTenantName, stationName.Intern(), cc, 1024)
+	if err != nil || truncated || string(payload) != "hello large payload" {
+		t.Fatalf("Unexpected reassembled payload %q, truncated %v: %v", payload, truncated, err)
+	}
+	if preview, truncated, _ := s.getLargePayload(globalTenantName, stationName.Intern(), cc, 5); !truncated || string(preview) != "hello" {
+		t.Fatalf("Unexpected preview %q", preview)
+	}
+}
+
+func TestMemphisClusteredRemoteClaimCheck(t *testing.T) {
+	c := createJetStreamClusterWithTemplateAndModHook(t, jsClusterTempl, "MEMPHIS", 3, func(serverName, _, _, conf string) string {
+		return conf + fmt.Sprintf("\nserver_tags: [\"node:%s\"]\n", serverName)
+	})
+	defer c.shutdown()
+
+	// the station is placed apart from its objects stream so its claim checks are validated remotely
+	s := c.servers[0]
+	station := models.Station{Name: "videos", TenantName: globalTenantName, StorageType: "memory", Replicas: 1, IsNative: true}
+	stationName, _ := StationNameFromStr(station.Name)
+	sc := getStationStreamConfig(stationName, station)
+	sc.Placement = &Placement{Tags: []string{"node:" + s.Name()}}
+	if err := s.memphisAddStream(globalTenantName, sc); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+	oc := getObjectsStreamConfig(stationName, station, s.getChunkSize())
+	oc.Placement = &Placement{Tags: []string{"node:" + c.servers[1].Name()}}
+	if err := s.memphisAddStream(globalTenantName, oc); err != nil {
+		t.Fatalf("Unexpected error creating objects stream: %v", err)
+	}
+	c.waitOnStreamLeader(globalAccountName, sc.Name)
+	c.waitOnStreamLeader(globalAccountName, oc.Name)
+
+	mset, err := s.GlobalAccount().lookupStream(sc.Name)
+	if err != nil {
+		t.Fatalf("Unexpected error looking up the stream: %v", err)
+	}
+	claimHdr := genHeader(genHeader(genHeader(nil, claimCheckHeader, "obj1"), chunksTotalHeader, "2"), payloadSizeHeader, "12")
+	if err := validateClaimCheck(s, s.GlobalAccount(), sc.Name, claimHdr); err != errClaimCheckNotLocal {
+		t.Fatalf("Expected the objects stream to be remote, got %v", err)
+	}
+
+	acks := make(chan JSPubAckResponse, 10)
+	sub, err := s.subscribeOnGlobalAcc("_INBOX.remote", "_INBOX.remote_sid", func(_ *client, _, _ string, msg []byte) {
+		var ack JSPubAckResponse
+		json.Unmarshal(msg, &ack)
+		acks <- ack
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+
+	send := func(subject string, hdr map[string]string, msg string) {
+		s.sendInternalAccountMsgWithReply(s.GlobalAccount(), subject, "_INBOX.remote", hdr, []byte(msg), true)
+	}
+	nextAck := func() JSPubAckResponse {
+		select {
+		case ack := <-acks:
+			return ack
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a publish ack")
+		}
+		return JSPubAckResponse{}
+	}
+
+	chunkSubject := getObjectSubject(sc.Name, "obj1")
+	for _, chunk := range []string{"hello ", "remote"} {
+		send(chunkSubject, nil, chunk)
+		if ack := nextAck(); ack.Error != nil {
+			t.Fatalf("Unexpected error uploading chunk: %+v", ack.Error)
+		}
+	}
+
+	// the messages published right after a remote claim check are stored after it
+	send(sc.Name+".final", map[string]string{claimCheckHeader: "obj1", chunksTotalHeader: "2", payloadSizeHeader: "12"}, "")
+	send(sc.Name+".final", map[string]string{claimCheckHeader: "obj2", chunksTotalHeader: "1", payloadSizeHeader: "1"}, "")
+	send(sc.Name+".final", nil, "after")
+	for i, expected := range []uint64{1, 0, 2} {
+		ack := nextAck()
+		if expected == 0 {
+			if ack.Error == nil {
+				t.Fatalf("Expected the claim check without uploaded chunks to be rejected")
+			}
+			continue
+		}
+		if ack.Error != nil || ack.PubAck.Sequence != expected {
+			t.Fatalf("Unexpected ack %d: %+v %+v", i, ack.PubAck, ack.Error)
+		}
+	}
+	sm, err := mset.getMsg(2)
+	if err != nil || string(sm.Data) != "after" {
+		t.Fatalf("Expected the regular message to follow the claim check, got %+v: %v", sm, err)
+	}
+
+	// the messages waiting behind remote claim checks are bounded
+	mset.claimChecks.mu.Lock()
+	atomic.StoreInt32(&mset.claimChecks.active, 1)
+	mset.claimChecks.pending = make([]claimCheckMsg, maxPendingClaimCheckMsgs)
+	mset.claimChecks.mu.Unlock()
+	send(sc.Name+".final", nil, "rejected")
+	if ack := nextAck(); ack.Error == nil || ack.Error.Code != 503 {
+		t.Fatalf("Expected a retryable rejection once the queue is full, got %+v", ack.Error)
+	}
+	mset.claimChecks.mu.Lock()
+	mset.claimChecks.pending = nil
+	atomic.StoreInt32(&mset.claimChecks.active, 0)
+	mset.claimChecks.mu.Unlock()
+}
+
+func TestMemphisMessageJourney(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	if err := s.ListenForMessageJourneyEvents(s.GlobalAccount()); err != nil {
+		t.Fatalf("Unexpected error listening for journey events: %v", err)
+	}
+
+	station := models.Station{Name: "orders", TenantName: globalTenantName, StorageType: "memory", Replicas: 1}
+	stationName, _ := StationNameFromStr(station.Name)
+	if err := s.CreateStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+	cc := getConsumerConfig("billing", models.Consumer{}, stationName, station)
+	if err := s.memphisAddConsumer(globalTenantName, stationName.Intern(), &cc); err != nil {
+		t.Fatalf("Unexpected error creating consumer: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		s.sendInternalAccountMsg(s.GlobalAccount(), stationName.Intern()+".final", []byte("order"))
+	}
+
+	delivered := make(chan string, 1)
+	sub, err := s.subscribeOnGlobalAcc("_INBOX.journey", "_INBOX.journey_sid", func(_ *client, _, reply string, _ []byte) {
+		delivered <- reply
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	defer s.unsubscribeOnGlobalAcc(sub)
+
+	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), fmt.Sprintf(JSApiRequestNextT, stationName.Intern(), cc.Durable), "_INBOX.journey", nil, []byte(`{"batch":1}`), true)
+	select {
+	case ackSubject := <-delivered:
+		s.sendInternalAccountMsg(s.GlobalAccount(), ackSubject, AckAck)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected a delivery")
+	}
+
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		journey, err := s.GetMessageJourney(globalTenantName, stationName, station, 1)
+		if err != nil {
+			return err
+		}
+		if len(journey.ConsumerGroups) != 1 || len(journey.Events) != 1 {
+			return fmt.Errorf("unexpected journey %+v", journey)
+		}
+		cg := journey.ConsumerGroups[0]
+		if cg.CgName != "billing" || cg.AckState != ackStateAcked || cg.DeliveriesCount != 1 || cg.AckLatencyMs < 0 {
+			return fmt.Errorf("unexpected consumer group journey %+v", cg)
+		}
+		return nil
+	})
+
+	journey, err := s.GetMessageJourney(globalTenantName, stationName, station, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error getting journey: %v", err)
+	}
+	if cg := journey.ConsumerGroups[0]; cg.AckState != ackStateNotDelivered || cg.DeliveriesCount != 0 {
+		t.Fatalf("Unexpected consumer group journey %+v", cg)
+	}
+}
+
+func TestMemphisStationMetrics(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	if err := s.createStationMetricsStream(); err != nil {
+		t.Fatalf("Unexpected error creating metrics stream: %v", err)
+	}
+	station := models.Station{Name: "payments", TenantName: globalTenantName, StorageType: "memory", Replicas: 1}
+	stationName, _ := StationNameFromStr(station.Name)
+	if err := s.CreateStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+	cc := getConsumerConfig("ledger", models.Consumer{}, stationName, station)
+	if err := s.memphisAddConsumer(globalTenantName, stationName.Intern(), &cc); err != nil {
+		t.Fatalf("Unexpected error creating consumer: %v", err)
+	}
+
+	mset, err := s.GlobalAccount().lookupStream(stationName.Intern())
+	if err != nil {
+		t.Fatalf("Unexpected error looking up stream: %v", err)
+	}
+	now := time.Now()
+	produce := func(amount int) {
+		expected := mset.state().Msgs + uint64(amount)
+		for i := 0; i < amount; i++ {
+			s.sendInternalAccountMsg(s.GlobalAccount(), stationName.Intern()+".final", []byte("payment"))
+		}
+		checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+			if msgs := mset.state().Msgs; msgs != expected {
+				return fmt.Errorf("expected %d messages, got %d", expected, msgs)
+			}
+			return nil
+		})
+	}
+	produce(2)
+	if err := s.sampleStationsMetrics(globalTenantName, now.Add(-2*time.Minute)); err != nil {
+		t.Fatalf("Unexpected error sampling: %v", err)
+	}
+	produce(6)
+	if err := s.sampleStationsMetrics(globalTenantName, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("Unexpected error sampling: %v", err)
+	}
+
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		points, err := s.GetStationMetrics(globalTenantName, stationName, "hour", now)
+		if err != nil {
+			return err
+		}
+		if len(points) != 2 {
+			return fmt.Errorf("expected 2 points, got %+v", points)
+		}
+		if points[1].Messages != 8 || points[1].IngestRate != 0.1 || points[1].CgsLag["ledger"] != 8 {
+			return fmt.Errorf("unexpected point %+v", points[1])
+		}
+		return nil
+	})
+}
+
+func TestMemphisStationsUsage(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer s.Shutdown()
+
+	if config := s.JetStreamConfig(); config != nil {
+		defer removeDir(t, config.StoreDir)
+	}
+
+	if err := s.createStationMetricsStream(); err != nil {
+		t.Fatalf("Unexpected error creating metrics stream: %v", err)
+	}
+	station := models.Station{Name: "invoices", TenantName: globalTenantName, StorageType: "memory", Replicas: 1}
+	stationName, _ := StationNameFromStr(station.Name)
+	if err := s.CreateStream(stationName, station); err != nil {
+		t.Fatalf("Unexpected error creating stream: %v", err)
+	}
+	mset, err := s.GlobalAccount().lookupStream(stationName.Intern())
+	if err != nil {
+		t.Fatalf("Unexpected error looking up stream: %v", err)
+	}
+
+	from := time.Now().Add(-10 * time.Minute)
+	for i, produced := range []int{3, 4, 5} {
+		expected := mset.state().Msgs + uint64(produced)
+		for j := 0; j < produced; j++ {
+			s.sendInternalAccountMsg(s.GlobalAccount(), stationName.Intern()+".final", []byte("invoice"))
+		}
+		checkFor(t, 2*time.Second, 10*time.Millisecond, func() error {
+			if msgs := mset.state().Msgs; msgs != expected {
+				return fmt.Errorf("expected %d messages, got %d", expected, msgs)
+			}
+			return nil
+		})
+		// the first sample is the baseline taken before the period
+		if err := s.sampleStationsMetrics(globalTenantName, from.Add(time.Duration(i*2-1)*time.Minute)); err != nil {
+			t.Fatalf("Unexpected error sampling: %v", err)
+		}
+	}
+
+	var usages map[string]models.Usage
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		usages, err = s.getStationsUsage(globalTenantName, from, time.Now())
+		if err != nil {
+			return err
+		}
+		if usage := usages[station.Name]; usage.MessagesIn != 9 {
+			return fmt.Errorf("unexpected usage %+v", usages)
+		}
+		return nil
+	})
+
+	rows := groupStationsUsage([]models.StationUsage{
+		{StationName: "invoices", CreatedByUser: "finance", Tags: []string{"billing", "prod"}, Usage: usages[station.Name]},
+		{StationName: "events", CreatedByUser: "finance", Usage: models.Usage{MessagesIn: 1}},
+	}, usageGroupByTag)
+	if len(rows) != 3 || rows[0].Name != "billing" || rows[2].Name != untaggedUsageName || rows[1].MessagesIn != 9 {
+		t.Fatalf("Unexpected usage rows %+v", rows)
+	}
+	data, err := usageReportToCsv(rows)
+	if err != nil {
+		t.Fatalf("Unexpected error exporting csv: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[1], "billing,") {
+		t.Fatalf("Unexpected csv %q", data)
+	}
+}
+
+func TestMemphisTotp(t *testing.T) {
+	// RFC 6238 SHA1 test secret "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for step, expected := range map[int64]string{1: "287082", 37037036: "081804", 41152263: "005924"} {
+		code, err := getTotpCode(secret, step)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if code != expected {
+			t.Fatalf("Expected code %s for step %d, got %s", expected, step, code)
+		}
+	}
+
+	now := time.Unix(1111111109, 0)
+	if step, ok := validateTotpCode(secret, "081 804", 0, now); !ok || step != 37037036 {
+		t.Fatalf("Expected code to be valid, got step %d", step)
+	}
+	if _, ok := validateTotpCode(secret, "081804", 37037036, now); ok {
+		t.Fatalf("Expected used code to be rejected")
+	}
+	if _, ok := validateTotpCode(secret, "081804", 0, now.Add(5*time.Minute)); ok {
+		t.Fatalf("Expected expired code to be rejected")
+	}
+
+	uri := getTotpProvisioningUri("john", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Memphis:john?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("Unexpected provisioning uri %s", uri)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(codes) != totpRecoveryCodesAmount || len(hashes) != totpRecoveryCodesAmount {
+		t.Fatalf("Unexpected recovery codes %v", codes)
+	}
+	if hash, ok := matchRecoveryCode(hashes, strings.ToUpper(codes[3])); !ok || hash != hashes[3] {
+		t.Fatalf("Expected recovery code to match")
+	}
+	if _, ok := matchRecoveryCode(hashes, "aaaa-aaaa"); ok {
+		t.Fatalf("Expected unknown recovery code not to match")
+	}
+
+	for _, test := range []struct {
+		caller  models.User
+		allowed bool
+	}{
+		{models.User{UserType: "root", TotpVerified: true}, true},
+		{models.User{UserType: "management", TenantName: "acme", TotpVerified: true}, true},
+		{models.User{UserType: "management", TenantName: "acme"}, false},
+		{models.User{UserType: "root"}, false},
+		{models.User{UserType: "management", TenantName: globalTenantName, TotpVerified: true}, false},
+		{models.User{UserType: "application", TenantName: "acme", TotpVerified: true}, false},
+	} {
+		if err := validateTotpResetCaller(test.caller); (err == nil) != test.allowed {
+			t.Fatalf("Expected reset by %+v allowed=%v, got %v", test.caller, test.allowed, err)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kvBucketStreamPrefix = "KV_"
+	kvSubjectPrefix      = "$KV."
+	kvOperationHeader    = "KV-Operation"
+	kvOperationPut       = "PUT"
+	kvOperationDel       = "DEL"
+	kvOperationPurge     = "PURGE"
+	kvDefaultHistory     = 1
+	kvMaxHistory         = 64
+	kvMaxKeyLen          = 256
+	kvFetchTimeout       = 10 * time.Second
+)
+
+var (
+	ErrKvKeyNotFound   = errors.New("key not found")
+	ErrKvWrongRevision = errors.New("wrong revision")
+	validKvKeyPattern  = regexp.MustCompile(`^[-/_=\.a-zA-Z0-9]+$`)
+)
+
+func validateKvBucketName(name string) error {
+	if err := validateName(name, "KV bucket"); err != nil {
+		return err
+	}
+	// the bucket name is a single token of the subjects its keys are stored under
+	if strings.Contains(name, ".") {
+		return errors.New("KV bucket name can not contain the '.' character")
+	}
+	return nil
+}
+
+func validateKvKey(key string) error {
+	if len(key) == 0 {
+		return errors.New("key can not be empty")
+	}
+	if len(key) > kvMaxKeyLen {
+		return fmt.Errorf("key should be under %d characters", kvMaxKeyLen)
+	}
+	if !validKvKeyPattern.MatchString(key) || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return errors.New("Only alphanumeric and the '-', '/', '_', '=', '.' characters are allowed in keys and a key can not start or end with '.'")
+	}
+	return nil
+}
+
+// validateKvWatchKey also accepts subject wildcards so a single watch can follow a group of keys
+func validateKvWatchKey(key string) error {
+	if key == _EMPTY_ {
+		return nil
+	}
+	for _, token := range strings.Split(key, ".") {
+		if token == "*" || token == ">" {
+			continue
+		}
+		if err := validateKvKey(token); err != nil {
+			return err
+		}
+	}
+	if i := strings.Index(key, ">"); i >= 0 && i != len(key)-1 {
+		return errors.New("the '>' wildcard can only be the last token of a key")
+	}
+	return nil
+}
+
+func validateKvHistory(history int) error {
+	if history < 0 || history > kvMaxHistory {
+		return fmt.Errorf("history has to be between 1 and %d", kvMaxHistory)
+	}
+	return nil
+}
+
+func getKvBucketStreamName(bucketName string) string {
+	return kvBucketStreamPrefix + bucketName
+}
+
+func getKvKeySubject(bucketName, key string) string {
+	return kvSubjectPrefix + bucketName + "." + key
+}
+
+func getKvBucketStreamConfig(bucket models.KvBucket) *StreamConfig {
+	history := bucket.History
+	if history <= 0 {
+		history = kvDefaultHistory
+	}
+
+	var storage StorageType
+	if bucket.StorageType == "memory" {
+		storage = MemoryStorage
+	} else {
+		storage = FileStorage
+	}
+
+	ttl := time.Duration(bucket.TTLSec) * time.Second
+	duplicates := 2 * time.Minute
+	if ttl > 0 && ttl < duplicates {
+		duplicates = ttl
+	}
+
+	return &StreamConfig{
+		Name:         getKvBucketStreamName(bucket.Name),
+		Subjects:     []string{getKvKeySubject(bucket.Name, ">")},
+		Retention:    LimitsPolicy,
+		MaxConsumers: -1,
+		MaxMsgs:      -1,
+		MaxBytes:     -1,
+		MaxAge:       ttl,
+		MaxMsgsPer:   int64(history),
+		MaxMsgSize:   int32(configuration.MAX_MESSAGE_SIZE_MB) * 1024 * 1024,
+		Discard:      DiscardNew,
+		Storage:      storage,
+		Replicas:     bucket.Replicas,
+		Duplicates:   duplicates,
+		AllowRollup:  true,
+		DenyDelete:   true,
+		AllowDirect:  true,
+	}
+}
+
+func (s *Server) createKvBucketStream(bucket models.KvBucket) error {
+	return s.memphisAddStream(bucket.TenantName, getKvBucketStreamConfig(bucket))
+}
+
+func (s *Server) removeKvBucketStream(tenantName, bucketName string) error {
+	err := s.RemoveStream(tenantName, getKvBucketStreamName(bucketName))
+	if err != nil && !IsNatsErr(err, JSStreamNotFoundErr) {
+		return err
+	}
+	return nil
+}
+
+func kvEntryFromStoredMsg(bucketName string, sm *StoredMsg) models.KvEntry {
+	operation := kvOperationPut
+	if op := getHeader(kvOperationHeader, sm.Header); len(op) > 0 {
+		operation = string(op)
+	}
+	return models.KvEntry{
+		Key:       strings.TrimPrefix(sm.Subject, kvSubjectPrefix+bucketName+"."),
+		Value:     string(sm.Data),
+		Revision:  sm.Sequence,
+		Operation: operation,
+		Created:   sm.Time,
+	}
+}
+
+func (s *Server) kvPublish(tenantName, bucketName, key string, hdr map[string]string, value []byte) (uint64, error) {
+	var resp JSPubAckResponse
+	err := jsApiRequestWithHeaders(s, tenantName, getKvKeySubject(bucketName, key), kindKvPublish, hdr, value, &resp)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Error != nil {
+		return 0, resp.Error
+	}
+	if resp.PubAck == nil {
+		return 0, errors.New("no publish acknowledgement has been received")
+	}
+	return resp.Sequence, nil
+}
+
+// kvPut stores the value as the new revision of the key, when expectedRevision is set the value is stored
+// only if the last revision of the key still is the expected one (0 means the key must not hold a value)
+func (s *Server) kvPut(tenantName, bucketName, key string, value []byte, expectedRevision *uint64) (uint64, error) {
+	if expectedRevision == nil {
+		return s.kvPublish(tenantName, bucketName, key, nil, value)
+	}
+
+	hdr := map[string]string{JSExpectedLastSubjSeq: strconv.FormatUint(*expectedRevision, 10)}
+	revision, err := s.kvPublish(tenantName, bucketName, key, hdr, value)
+	if err == nil || *expectedRevision != 0 || !IsNatsErr(err, JSStreamWrongLastSequenceErrF) {
+		if IsNatsErr(err, JSStreamWrongLastSequenceErrF) {
+			return 0, fmt.Errorf("%w: key %s has been updated since revision %d", ErrKvWrongRevision, key, *expectedRevision)
+		}
+		return revision, err
+	}
+
+	// a deleted key can be created again on top of its delete marker
+	sm, lerr := s.kvGetLastMsg(tenantName, bucketName, key)
+	if lerr != nil {
+		return 0, lerr
+	}
+	if entry := kvEntryFromStoredMsg(bucketName, sm); entry.Operation == kvOperationPut {
+		return 0, fmt.Errorf("%w: key %s already exists", ErrKvWrongRevision, key)
+	}
+	hdr[JSExpectedLastSubjSeq] = strconv.FormatUint(sm.Sequence, 10)
+	revision, err = s.kvPublish(tenantName, bucketName, key, hdr, value)
+	if IsNatsErr(err, JSStreamWrongLastSequenceErrF) {
+		return 0, fmt.Errorf("%w: key %s already exists", ErrKvWrongRevision, key)
+	}
+	return revision, err
+}
+
+// kvDelete marks the key as deleted while keeping its history, a purge also drops the history of the key
+func (s *Server) kvDelete(tenantName, bucketName, key string, purge bool) (uint64, error) {
+	hdr := map[string]string{kvOperationHeader: kvOperationDel}
+	if purge {
+		hdr[kvOperationHeader] = kvOperationPurge
+		hdr[JSMsgRollup] = JSMsgRollupSubject
+	}
+	return s.kvPublish(tenantName, bucketName, key, hdr, nil)
+}
+
+func (s *Server) kvGetMsg(tenantName, bucketName string, request JSApiMsgGetRequest) (*StoredMsg, error) {
+	rawRequest, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp JSApiMsgGetResponse
+	err = jsApiRequest(s, tenantName, fmt.Sprintf(JSApiMsgGetT, getKvBucketStreamName(bucketName)), kindGetMsg, rawRequest, &resp)
+	if err != nil {
+		return nil, err
+	}
+	err = resp.ToError()
+	if IsNatsErr(err, JSNoMessageFoundErr) {
+		return nil, ErrKvKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return resp.Message, nil
+}
+
+func (s *Server) kvGetLastMsg(tenantName, bucketName, key string) (*StoredMsg, error) {
+	return s.kvGetMsg(tenantName, bucketName, JSApiMsgGetRequest{LastFor: getKvKeySubject(bucketName, key)})
+}
+
+func (s *Server) kvGet(tenantName, bucketName, key string) (models.KvEntry, error) {
+	sm, err := s.kvGetLastMsg(tenantName, bucketName, key)
+	if err != nil {
+		return models.KvEntry{}, err
+	}
+	entry := kvEntryFromStoredMsg(bucketName, sm)
+	if entry.Operation != kvOperationPut {
+		return models.KvEntry{}, ErrKvKeyNotFound
+	}
+	return entry, nil
+}
+
+// kvHistory returns the revisions of the key that are still kept by the bucket, oldest first
+func (s *Server) kvHistory(tenantName, bucketName, key string) ([]models.KvEntry, error) {
+	entries := []models.KvEntry{}
+	subject := getKvKeySubject(bucketName, key)
+	seq := uint64(1)
+	for {
+		sm, err := s.kvGetMsg(tenantName, bucketName, JSApiMsgGetRequest{Seq: seq, NextFor: subject})
+		if err == ErrKvKeyNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, kvEntryFromStoredMsg(bucketName, sm))
+		seq = sm.Sequence + 1
+	}
+	if len(entries) == 0 {
+		return nil, ErrKvKeyNotFound
+	}
+	return entries, nil
+}
+
+// kvEntries returns the current value of every key of the bucket sorted by key,
+// the last revision of every key is read through a single consumer
+func (s *Server) kvEntries(tenantName, bucketName string) ([]models.KvEntry, error) {
+	cc := ConsumerConfig{
+		FilterSubject: getKvKeySubject(bucketName, ">"),
+		DeliverPolicy: DeliverLastPerSubject,
+		AckPolicy:     AckExplicit,
+	}
+	msgs, err := s.memphisFetchMsgs(tenantName, getKvBucketStreamName(bucketName), cc, 0, kvFetchTimeout, true)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.KvEntry{}
+	for i := range msgs {
+		entry := kvEntryFromStoredMsg(bucketName, &msgs[i])
+		if entry.Operation != kvOperationPut {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// kvWatch pushes the updates of the matching keys into deliverSubject until nobody listens on it anymore
+func (s *Server) kvWatch(tenantName, bucketName, key, deliverSubject string, includeHistory bool) (string, error) {
+	if key == _EMPTY_ {
+		key = ">"
+	}
+	deliverPolicy := DeliverLastPerSubject
+	if includeHistory {
+		deliverPolicy = DeliverAll
+	}
+	return s.memphisAddEphemeralPushConsumer(tenantName, getKvBucketStreamName(bucketName), getKvKeySubject(bucketName, key), deliverSubject, deliverPolicy)
+}
@@ -0,0 +1,444 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	claimCheckHeader         = "$memphis_claim_check"
+	chunksTotalHeader        = "$memphis_chunks_total"
+	payloadSizeHeader        = "$memphis_payload_size"
+	maxLargePayloadSizeMB    = 1024
+	chunkHeadersReserve      = 4 * 1024 // room for the headers producers attach to every chunk
+	chunkedPayloadPreviewLen = 64 * 1024
+	maxPendingClaimCheckMsgs = 1024 // messages of a stream held behind its remote claim checks
+)
+
+// claimCheck is what a station stores instead of a payload which has been uploaded in chunks into the objects stream of the station
+type claimCheck struct {
+	objectId    string
+	chunksTotal int
+	payloadSize int
+}
+
+func getObjectsStreamName(streamName string) string {
+	return fmt.Sprintf(objectsStreamName, streamName)
+}
+
+func getObjectSubject(streamName, objectId string) string {
+	return getObjectsStreamName(streamName) + "." + objectId
+}
+
+func newClaimCheck(objectId, chunksTotal, payloadSize string) (claimCheck, error) {
+	if objectId == _EMPTY_ || strings.ContainsAny(objectId, ".*> \t\r\n") {
+		return claimCheck{}, fmt.Errorf("%s header is not a valid object id", claimCheckHeader)
+	}
+	total, err := strconv.Atoi(chunksTotal)
+	if err != nil || total < 1 {
+		return claimCheck{}, fmt.Errorf("%s header has to be a positive number", chunksTotalHeader)
+	}
+	size, err := strconv.Atoi(payloadSize)
+	if err != nil || size < 0 {
+		return claimCheck{}, fmt.Errorf("%s header has to be a non negative number", payloadSizeHeader)
+	}
+	return claimCheck{objectId: objectId, chunksTotal: total, payloadSize: size}, nil
+}
+
+// parseClaimCheck returns false in case the raw headers do not hold a claim check
+func parseClaimCheck(hdr []byte) (claimCheck, bool, error) {
+	objectId := getHeader(claimCheckHeader, hdr)
+	if len(objectId) == 0 {
+		return claimCheck{}, false, nil
+	}
+	cc, err := newClaimCheck(string(objectId), string(getHeader(chunksTotalHeader, hdr)), string(getHeader(payloadSizeHeader, hdr)))
+	return cc, true, err
+}
+
+// getClaimCheck is used by views working with decoded headers, malformed claim checks are shown as regular messages
+func getClaimCheck(headers map[string]string) (claimCheck, bool) {
+	objectId, ok := headers[claimCheckHeader]
+	if !ok {
+		return claimCheck{}, false
+	}
+	cc, err := newClaimCheck(objectId, headers[chunksTotalHeader], headers[payloadSizeHeader])
+	if err != nil {
+		return claimCheck{}, false
+	}
+	return cc, true
+}
+
+var errClaimCheckNotLocal = errors.New("objects stream is not placed on this server")
+
+// validateClaimCheck makes sure all the chunks a claim check references have been uploaded before it is stored,
+// in a cluster the objects stream may be placed on other servers, errClaimCheckNotLocal is returned then
+// and the claim check has to be validated with validateRemoteClaimCheck
+func validateClaimCheck(s *Server, acc *Account, streamName string, hdr []byte) error {
+	cc, ok, err := parseClaimCheck(hdr)
+	if !ok || err != nil {
+		return err
+	}
+
+	objects, err := acc.lookupStream(getObjectsStreamName(streamName))
+	if err != nil {
+		if s.JetStreamIsClustered() {
+			return errClaimCheckNotLocal
+		}
+		return fmt.Errorf("object %s has not been uploaded", cc.objectId)
+	}
+	objects.mu.RLock()
+	store := objects.store
+	objects.mu.RUnlock()
+	if store == nil {
+		return errClaimCheckNotLocal
+	}
+	return checkUploadedChunks(cc, store.FilteredState(0, getObjectSubject(streamName, cc.objectId)).Msgs)
+}
+
+// validateRemoteClaimCheck counts the uploaded chunks through the leader of the objects stream,
+// it sends a JS API request so it must not be called on the inbound path of a stream
+func validateRemoteClaimCheck(s *Server, acc *Account, streamName string, hdr []byte) error {
+	cc, ok, err := parseClaimCheck(hdr)
+	if !ok || err != nil {
+		return err
+	}
+
+	subject := getObjectSubject(streamName, cc.objectId)
+	request, err := json.Marshal(JSApiStreamInfoRequest{SubjectsFilter: subject})
+	if err != nil {
+		return err
+	}
+	var resp JSApiStreamInfoResponse
+	err = jsApiRequest(s, tenantNameFromAccount(acc), fmt.Sprintf(JSApiStreamInfoT, getObjectsStreamName(streamName)), kindStreamInfo, request, &resp)
+	if err != nil {
+		return err
+	}
+	if err = resp.ToError(); err != nil {
+		if IsNatsErr(err, JSStreamNotFoundErr) {
+			return fmt.Errorf("object %s has not been uploaded", cc.objectId)
+		}
+		return err
+	}
+	return checkUploadedChunks(cc, resp.StreamInfo.State.Subjects[subject])
+}
+
+var errClaimCheckQueueFull = errors.New("too many messages are waiting for claim checks to be validated, retry later")
+
+type claimCheckMsg struct {
+	subject string
+	reply   string
+	hdr     []byte
+	msg     []byte
+	remote  bool
+}
+
+// claimCheckQueue keeps the publish order of a stream while its remote claim checks are validated,
+// once a remote claim check is pending every following message of the stream waits behind it
+type claimCheckQueue struct {
+	mu      sync.Mutex
+	active  int32 // set while the queue is being drained, read atomically on the inbound path
+	pending []claimCheckMsg
+}
+
+// queueClaimCheckMsg returns false in case nothing is pending and the message can take the regular inbound path,
+// otherwise the message is queued in order, or rejected with a retryable error when the queue is full
+func (mset *stream) queueClaimCheckMsg(subject, reply string, hdr, msg []byte, remote bool) (bool, error) {
+	q := &mset.claimChecks
+	if !remote && atomic.LoadInt32(&q.active) == 0 {
+		return false, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !remote && q.active == 0 {
+		return false, nil
+	}
+	if len(q.pending) >= maxPendingClaimCheckMsgs {
+		return true, errClaimCheckQueueFull
+	}
+	q.pending = append(q.pending, claimCheckMsg{subject: subject, reply: reply, hdr: copyBytes(hdr), msg: copyBytes(msg), remote: remote})
+	if q.active == 0 {
+		atomic.StoreInt32(&q.active, 1)
+		go mset.processClaimCheckQueue()
+	}
+	return true, nil
+}
+
+// processClaimCheckQueue validates the queued claim checks one at a time through the leader of the objects stream
+// and hands the messages over to the stream in the order they were published
+func (mset *stream) processClaimCheckQueue() {
+	mset.mu.RLock()
+	s, acc, name := mset.srv, mset.acc, mset.cfg.Name
+	mset.mu.RUnlock()
+
+	q := &mset.claimChecks
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			atomic.StoreInt32(&q.active, 0)
+			q.mu.Unlock()
+			return
+		}
+		m := q.pending[0]
+		q.pending[0] = claimCheckMsg{}
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		if m.remote {
+			if err := validateRemoteClaimCheck(s, acc, name, m.hdr); err != nil {
+				mset.sendPubAckError(m.reply, 400, err)
+				continue
+			}
+		}
+		mset.queueInbound(mset.msgs, m.subject, m.reply, m.hdr, m.msg)
+	}
+}
+
+func checkUploadedChunks(cc claimCheck, uploaded uint64) error {
+	if uploaded != uint64(cc.chunksTotal) {
+		return fmt.Errorf("object %s has %d uploaded chunks out of %d", cc.objectId, uploaded, cc.chunksTotal)
+	}
+	return nil
+}
+
+// getObjectsStreamConfig derives the limits of the chunks from the station retention so chunks are not kept
+// much longer than the claim checks referencing them, with a messages retention every retained message
+// may be made of as many chunks as the largest payload needs
+func getObjectsStreamConfig(sn StationName, station models.Station, chunkSize int) *StreamConfig {
+	var maxAge time.Duration
+	maxMsgs, maxBytes := int64(-1), int64(-1)
+	if station.RetentionValue > 0 {
+		switch station.RetentionType {
+		case "message_age_sec":
+			maxAge = time.Duration(station.RetentionValue) * time.Second
+		case "bytes":
+			maxBytes = int64(station.RetentionValue)
+		case "messages":
+			maxChunks := int64((maxLargePayloadSizeMB*1024*1024 + chunkSize - 1) / chunkSize)
+			maxMsgs = int64(station.RetentionValue) * maxChunks
+		}
+	}
+
+	var storage StorageType
+	if station.StorageType == "memory" {
+		storage = MemoryStorage
+	} else {
+		storage = FileStorage
+	}
+
+	duplicates := 2 * time.Minute
+	if maxAge > 0 && maxAge < duplicates {
+		duplicates = maxAge
+	}
+
+	name := getObjectsStreamName(sn.Intern())
+	return &StreamConfig{
+		Name:         name,
+		Subjects:     []string{name + ".>"},
+		Retention:    LimitsPolicy,
+		MaxConsumers: -1,
+		MaxMsgs:      maxMsgs,
+		MaxBytes:     maxBytes,
+		Discard:      DiscardOld,
+		MaxAge:       maxAge,
+		MaxMsgsPer:   -1,
+		MaxMsgSize:   int32(configuration.MAX_MESSAGE_SIZE_MB) * 1024 * 1024,
+		Storage:      storage,
+		Compression:  getStationCompression(station),
+		Replicas:     station.Replicas,
+		Duplicates:   duplicates,
+	}
+}
+
+// createObjectsStream is called before every upload, an existing stream is updated in case the station retention has changed
+func (s *Server) createObjectsStream(sn StationName, station models.Station) error {
+	sc := getObjectsStreamConfig(sn, station, s.getChunkSize())
+	err := s.memphisAddStream(station.TenantName, sc)
+	if IsNatsErr(err, JSStreamNameExistErr) {
+		return s.memphisUpdateStream(station.TenantName, sc)
+	}
+	return err
+}
+
+func (s *Server) removeObjectsStream(tenantName string, sn StationName) error {
+	err := s.RemoveStream(tenantName, getObjectsStreamName(sn.Intern()))
+	if err != nil && !IsNatsErr(err, JSStreamNotFoundErr) {
+		return err
+	}
+	return nil
+}
+
+// getChunkSize keeps every chunk within both the station message size and the client payload limits
+func (s *Server) getChunkSize() int {
+	chunkSize := int(s.getOpts().MaxPayload)
+	if maxMsgSize := configuration.MAX_MESSAGE_SIZE_MB * 1024 * 1024; maxMsgSize > 0 && maxMsgSize < chunkSize {
+		chunkSize = maxMsgSize
+	}
+	return chunkSize - chunkHeadersReserve
+}
+
+// getLargePayload reassembles the chunks of the object in order, it stops once maxLen bytes have been read
+// and reports whether the payload has been truncated
+func (s *Server) getLargePayload(tenantName, streamName string, cc claimCheck, maxLen int) ([]byte, bool, error) {
+	objectsStream := getObjectsStreamName(streamName)
+	subject := getObjectSubject(streamName, cc.objectId)
+	payload := make([]byte, 0, cc.payloadSize)
+	seq := uint64(1)
+	for chunks := 0; chunks < cc.chunksTotal; chunks++ {
+		if len(payload) >= maxLen {
+			return payload[:maxLen], true, nil
+		}
+		sm, err := s.memphisGetNextMsg(tenantName, objectsStream, subject, seq)
+		if IsNatsErr(err, JSNoMessageFoundErr) || IsNatsErr(err, JSStreamNotFoundErr) {
+			return nil, false, fmt.Errorf("object %s is missing chunks, it may have been removed by the station retention", cc.objectId)
+		} else if err != nil {
+			return nil, false, err
+		}
+		payload = append(payload, sm.Data...)
+		seq = sm.Sequence + 1
+	}
+	if len(payload) > maxLen {
+		return payload[:maxLen], true, nil
+	}
+	return payload, false, nil
+}
+
+func (s *Server) getChunkedPayloadPreview(tenantName, streamName string, cc claimCheck) ([]byte, error) {
+	preview, _, err := s.getLargePayload(tenantName, streamName, cc, chunkedPayloadPreviewLen)
+	return preview, err
+}
+
+func getStationForLargePayload(c *client, handlerName, stationNameStr string) (StationName, models.Station, error) {
+	stationName, err := StationNameFromStr(stationNameStr)
+	if err != nil {
+		serv.Warnf(handlerName + ": Station " + stationNameStr + ": " + err.Error())
+		return StationName{}, models.Station{}, err
+	}
+	exist, station, err := IsStationExist(stationName, tenantNameFromAccount(c.acc))
+	if err != nil {
+		serv.Errorf(handlerName + ": Station " + stationNameStr + ": " + err.Error())
+		return StationName{}, models.Station{}, err
+	}
+	if !exist {
+		errMsg := "Station " + stationName.Ext() + " does not exist"
+		serv.Warnf(handlerName + ": " + errMsg)
+		return StationName{}, models.Station{}, errors.New(errMsg)
+	}
+	return stationName, station, nil
+}
+
+func (s *Server) uploadLargePayloadDirect(c *client, reply string, msg []byte) {
+	var resp largePayloadUploadResponse
+	var ur largePayloadUploadRequest
+	if err := json.Unmarshal(msg, &ur); err != nil {
+		s.Errorf("uploadLargePayloadDirect: " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+
+	stationName, station, err := getStationForLargePayload(c, "uploadLargePayloadDirect", ur.StationName)
+	if err != nil {
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	if !station.IsNative || isActiveMirror(station) {
+		err = errors.New("large payloads can only be produced into native stations")
+		serv.Warnf("uploadLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	if ur.PayloadSize <= 0 || ur.PayloadSize > maxLargePayloadSizeMB*1024*1024 {
+		err = fmt.Errorf("payload size has to be between 1 byte and %d MB", maxLargePayloadSizeMB)
+		serv.Warnf("uploadLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+
+	err = s.createObjectsStream(stationName, station)
+	if err != nil {
+		serv.Errorf("uploadLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+
+	chunkSize := s.getChunkSize()
+	resp.ObjectId = s.memphis.nuid.Next()
+	resp.ChunkSubject = getObjectSubject(stationName.Intern(), resp.ObjectId)
+	resp.ChunkSize = chunkSize
+	resp.ChunksTotal = (ur.PayloadSize + chunkSize - 1) / chunkSize
+	respondWithResp(s, c.acc, reply, &resp)
+}
+
+func (s *Server) fetchLargePayloadDirect(c *client, reply string, msg []byte) {
+	var resp largePayloadFetchResponse
+	var fr largePayloadFetchRequest
+	if err := json.Unmarshal(msg, &fr); err != nil {
+		s.Errorf("fetchLargePayloadDirect: " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+
+	stationName, station, err := getStationForLargePayload(c, "fetchLargePayloadDirect", fr.StationName)
+	if err != nil {
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	cc, err := newClaimCheck(fr.ObjectId, strconv.Itoa(fr.ChunksTotal), strconv.Itoa(fr.PayloadSize))
+	if err != nil {
+		serv.Warnf("fetchLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.ChunksTotal = cc.chunksTotal
+
+	if fr.DeliverSubject != _EMPTY_ {
+		if !IsValidLiteralSubject(fr.DeliverSubject) {
+			err = errors.New("deliver subject is not valid")
+			serv.Warnf("fetchLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+			respondWithRespErr(s, c.acc, reply, err, &resp)
+			return
+		}
+		resp.ConsumerName, err = s.memphisAddEphemeralPushConsumer(station.TenantName, getObjectsStreamName(stationName.Intern()), getObjectSubject(stationName.Intern(), cc.objectId), fr.DeliverSubject, DeliverAll)
+		if err != nil {
+			serv.Errorf("fetchLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+			respondWithRespErr(s, c.acc, reply, err, &resp)
+			return
+		}
+		respondWithResp(s, c.acc, reply, &resp)
+		return
+	}
+
+	// the reassembled payload is sent base64 encoded within a single reply
+	maxLen := (int(s.getOpts().MaxPayload) - chunkHeadersReserve) / 4 * 3
+	payload, truncated, err := s.getLargePayload(station.TenantName, stationName.Intern(), cc, maxLen)
+	if err != nil {
+		serv.Warnf("fetchLargePayloadDirect: Station " + stationName.Ext() + ": " + err.Error())
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	if truncated {
+		err = errors.New("payload is too large to be delivered within a single reply, fetch it with a deliver subject")
+		respondWithRespErr(s, c.acc, reply, err, &resp)
+		return
+	}
+	resp.Payload = payload
+	respondWithResp(s, c.acc, reply, &resp)
+}
@@ -0,0 +1,329 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"memphis-broker/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	journeyAckSampleFrequency = "100%"
+	maxJourneyTrackedMsgs     = 100000
+
+	journeyEventAcked         = "acked"
+	journeyEventNacked        = "nacked"
+	journeyEventTerminated    = "terminated"
+	journeyEventMaxDeliveries = "max_deliveries_exceeded"
+	journeyEventStoredInDls   = "stored_in_dls"
+	journeyEventResentFromDls = "resent_from_dls"
+
+	ackStateAcked        = "acked"
+	ackStateInProcess    = "in_process"
+	ackStateNotDelivered = "not_delivered"
+	ackStatePoisoned     = "poisoned"
+	ackStateTerminated   = "terminated"
+)
+
+type journeyMsgKey struct {
+	tenantName string
+	stream     string
+	seq        uint64
+}
+
+type journeyEvent struct {
+	consumer string
+	event    models.MessageJourneyEvent
+}
+
+// journeyEvents keeps the consumer advisories of the latest messages, every server listens to them
+// so the journey of a message can be served by any server in the cluster
+type journeyEvents struct {
+	sync.Mutex
+	events map[journeyMsgKey][]journeyEvent
+	order  []journeyMsgKey
+}
+
+var messageJourneyEvents = journeyEvents{events: make(map[journeyMsgKey][]journeyEvent)}
+
+func (je *journeyEvents) add(key journeyMsgKey, event journeyEvent) {
+	je.Lock()
+	defer je.Unlock()
+	if _, ok := je.events[key]; !ok {
+		je.order = append(je.order, key)
+		if len(je.order) > maxJourneyTrackedMsgs {
+			delete(je.events, je.order[0])
+			je.order = je.order[1:]
+		}
+	}
+	je.events[key] = append(je.events[key], event)
+}
+
+func (je *journeyEvents) get(key journeyMsgKey) []journeyEvent {
+	je.Lock()
+	defer je.Unlock()
+	events := make([]journeyEvent, len(je.events[key]))
+	copy(events, je.events[key])
+	return events
+}
+
+func (s *Server) ListenForMessageJourneyEvents(acc *Account) error {
+	for _, prefix := range []string{JSMetricConsumerAckPre, JSAdvisoryConsumerMsgNakPre, JSAdvisoryConsumerMsgTerminatedPre, JSAdvisoryConsumerMaxDeliveryExceedPre} {
+		subject := prefix + ".>"
+		_, err := s.subscribeOnAcc(acc, subject, "$memphis_journey_"+subject+"_sid", createJourneyEventHandler(acc))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createJourneyEventHandler(acc *Account) simplifiedMsgHandler {
+	return func(_ *client, subject, _ string, msg []byte) {
+		handleJourneyEvent(tenantNameFromAccount(acc), subject, msg)
+	}
+}
+
+func handleJourneyEvent(tenantName, subject string, msg []byte) {
+	// all the consumer advisories share the fields of the ack metric
+	var advisory JSConsumerAckMetric
+	if err := json.Unmarshal(msg, &advisory); err != nil {
+		serv.Errorf("handleJourneyEvent: " + err.Error())
+		return
+	}
+	if strings.HasPrefix(advisory.Stream, "$memphis") || strings.HasPrefix(advisory.Stream, kvBucketStreamPrefix) {
+		return
+	}
+
+	event := models.MessageJourneyEvent{
+		Time:            advisory.Time,
+		DeliveriesCount: int(advisory.Deliveries),
+		AckLatencyMs:    -1,
+	}
+	switch {
+	case strings.HasPrefix(subject, JSMetricConsumerAckPre):
+		event.Type = journeyEventAcked
+		event.AckLatencyMs = advisory.Delay / int64(time.Millisecond)
+	case strings.HasPrefix(subject, JSAdvisoryConsumerMsgNakPre):
+		event.Type = journeyEventNacked
+	case strings.HasPrefix(subject, JSAdvisoryConsumerMsgTerminatedPre):
+		event.Type = journeyEventTerminated
+	case strings.HasPrefix(subject, JSAdvisoryConsumerMaxDeliveryExceedPre):
+		event.Type = journeyEventMaxDeliveries
+	default:
+		return
+	}
+
+	key := journeyMsgKey{tenantName: tenantName, stream: advisory.Stream, seq: advisory.StreamSeq}
+	messageJourneyEvents.add(key, journeyEvent{consumer: advisory.Consumer, event: event})
+}
+
+// journeyState returns whether the message is still waiting for an ack of the consumer,
+// along with its last delivery and delivery count
+func (o *consumer) journeyState(sseq uint64) (bool, time.Time, int) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	p, ok := o.pending[sseq]
+	if !ok {
+		return false, time.Time{}, 0
+	}
+	return true, time.Unix(0, p.Timestamp), int(o.rdc[sseq] + 1)
+}
+
+func (s *Server) memphisAllConsumersInfo(tenantName, streamName string) ([]*ConsumerInfo, error) {
+	requestSubject := fmt.Sprintf(JSApiConsumerListT, streamName)
+	consumers := make([]*ConsumerInfo, 0)
+
+	offset := 0
+	for {
+		request := JSApiConsumersRequest{ApiPagedRequest: ApiPagedRequest{Offset: offset}}
+		rawRequest, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp JSApiConsumerListResponse
+		err = jsApiRequest(s, tenantName, requestSubject, kindConsumerList, rawRequest, &resp)
+		if err != nil {
+			return nil, err
+		}
+		err = resp.ToError()
+		if err != nil {
+			return nil, err
+		}
+
+		consumers = append(consumers, resp.Consumers...)
+		if len(resp.Consumers) == 0 || len(consumers) >= resp.Total {
+			return consumers, nil
+		}
+		offset += len(resp.Consumers)
+	}
+}
+
+func getJourneyCg(seq uint64, ci *ConsumerInfo, o *consumer, events []models.MessageJourneyEvent) models.MessageJourneyCg {
+	cg := models.MessageJourneyCg{AckLatencyMs: -1}
+	delivered := seq <= ci.Delivered.Stream
+	for _, event := range events {
+		switch event.Type {
+		case journeyEventAcked:
+			cg.AckState = ackStateAcked
+			cg.AckLatencyMs = event.AckLatencyMs
+			cg.LastDelivery = event.Time.Add(-time.Duration(event.AckLatencyMs) * time.Millisecond)
+		case journeyEventMaxDeliveries:
+			cg.AckState = ackStatePoisoned
+		case journeyEventTerminated:
+			cg.AckState = ackStateTerminated
+		}
+		if event.DeliveriesCount > cg.DeliveriesCount {
+			cg.DeliveriesCount = event.DeliveriesCount
+		}
+		delivered = true
+	}
+
+	if o != nil {
+		if pending, lastDelivery, deliveries := o.journeyState(seq); pending {
+			cg.AckState = ackStateInProcess
+			cg.LastDelivery = lastDelivery
+			cg.DeliveriesCount = deliveries
+			return cg
+		}
+	}
+
+	if cg.AckState != _EMPTY_ {
+		return cg
+	}
+	switch {
+	case seq <= ci.AckFloor.Stream:
+		cg.AckState = ackStateAcked
+	case !delivered:
+		cg.AckState = ackStateNotDelivered
+	case o != nil:
+		// delivered messages which are no longer pending on the consumer have been acked above its ack floor
+		cg.AckState = ackStateAcked
+	default:
+		cg.AckState = ackStateInProcess
+	}
+	if delivered && cg.DeliveriesCount == 0 {
+		cg.DeliveriesCount = 1
+	}
+	return cg
+}
+
+// GetMessageJourney follows a station message through its consumer groups, it is built from the consumers state
+// and advisories, and from the dead letter station
+func (s *Server) GetMessageJourney(tenantName string, stationName StationName, station models.Station, seq uint64) (models.MessageJourney, error) {
+	sm, err := s.GetMessage(tenantName, stationName, seq)
+	if err != nil {
+		return models.MessageJourney{}, err
+	}
+
+	var headersJson map[string]string
+	if sm.Header != nil {
+		headersJson, err = DecodeHeader(sm.Header)
+		if err != nil {
+			return models.MessageJourney{}, err
+		}
+	}
+
+	journey := models.MessageJourney{
+		StationName:    stationName.Ext(),
+		MessageSeq:     int(sm.Sequence),
+		Subject:        sm.Subject,
+		Size:           len(sm.Subject) + len(sm.Data) + len(sm.Header),
+		IngestTime:     sm.Time,
+		ConsumerGroups: []models.MessageJourneyCg{},
+		Events:         []models.MessageJourneyEvent{},
+	}
+	if cc, chunked := getClaimCheck(headersJson); chunked {
+		journey.Size = cc.payloadSize
+	}
+
+	producedBy := headersJson["$memphis_producedBy"]
+	connectionId := headersJson["$memphis_connectionId"]
+	// This check for backward compatability
+	if producedBy == _EMPTY_ || connectionId == _EMPTY_ {
+		producedBy = headersJson["producedBy"]
+		connectionId = headersJson["connectionId"]
+	}
+	journey.Producer = models.MessageJourneyProducer{Name: producedBy, ConnectionId: connectionId}
+	if producedBy == "$memphis_dls" {
+		journey.Events = append(journey.Events, models.MessageJourneyEvent{Type: journeyEventResentFromDls, Time: sm.Time, AckLatencyMs: -1})
+	}
+
+	partitioned := isPartitioned(getStationPartitions(tenantName, stationName.Intern()))
+	cgNameFromConsumer := func(consumer string) string {
+		if partitioned {
+			consumer = cgNameFromPartitionDurable(consumer)
+		}
+		return revertDelimiters(consumer)
+	}
+
+	eventsByConsumer := make(map[string][]models.MessageJourneyEvent)
+	for _, je := range messageJourneyEvents.get(journeyMsgKey{tenantName: tenantName, stream: stationName.Intern(), seq: sm.Sequence}) {
+		je.event.CgName = cgNameFromConsumer(je.consumer)
+		eventsByConsumer[je.consumer] = append(eventsByConsumer[je.consumer], je.event)
+		journey.Events = append(journey.Events, je.event)
+	}
+
+	if station.IsNative {
+		poisonedCgs, err := GetPoisonedCgsByMessage(tenantName, stationName.Intern(), models.MessageDetails{MessageSeq: int(sm.Sequence), ProducedBy: producedBy, TimeSent: sm.Time})
+		if err != nil && !IsNatsErr(err, JSStreamNotFoundErr) {
+			return models.MessageJourney{}, err
+		}
+		for _, pcg := range poisonedCgs {
+			journey.Events = append(journey.Events, models.MessageJourneyEvent{
+				Type:            journeyEventStoredInDls,
+				CgName:          pcg.CgName,
+				Time:            pcg.PoisoningTime,
+				DeliveriesCount: pcg.DeliveriesCount,
+				AckLatencyMs:    -1,
+			})
+		}
+	}
+
+	consumers, err := s.memphisAllConsumersInfo(tenantName, stationName.Intern())
+	if err != nil {
+		return models.MessageJourney{}, err
+	}
+	var mset *stream
+	if acc, err := s.getTenantAccount(tenantName); err == nil {
+		mset, _ = acc.lookupStream(stationName.Intern())
+	}
+	for _, ci := range consumers {
+		if ci.Config == nil || ci.Config.Durable == _EMPTY_ {
+			continue
+		}
+		if ci.Config.FilterSubject != _EMPTY_ && !subjectIsSubsetMatch(sm.Subject, ci.Config.FilterSubject) {
+			continue
+		}
+		var o *consumer
+		if mset != nil {
+			o = mset.lookupConsumer(ci.Name)
+		}
+		cg := getJourneyCg(sm.Sequence, ci, o, eventsByConsumer[ci.Name])
+		cg.CgName = cgNameFromConsumer(ci.Name)
+		journey.ConsumerGroups = append(journey.ConsumerGroups, cg)
+	}
+
+	sort.Slice(journey.ConsumerGroups, func(i, j int) bool {
+		return journey.ConsumerGroups[i].CgName < journey.ConsumerGroups[j].CgName
+	})
+	sort.SliceStable(journey.Events, func(i, j int) bool {
+		return journey.Events[i].Time.Before(journey.Events[j].Time)
+	})
+	return journey, nil
+}
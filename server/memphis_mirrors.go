@@ -0,0 +1,198 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"errors"
+	"memphis-broker/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func isMirrorStation(station models.Station) bool {
+	return station.Mirror.StationName != _EMPTY_
+}
+
+func isActiveMirror(station models.Station) bool {
+	return isMirrorStation(station) && !station.Mirror.IsPromoted
+}
+
+// getStationFilterSubject returns the subject consumers of the station filter on,
+// messages of mirrors and aggregate stations keep the subjects of their origin so they are not filtered
+func getStationFilterSubject(sn StationName, station models.Station) string {
+	if isMirrorStation(station) || isAggregateStation(station) {
+		return _EMPTY_
+	}
+	if station.Compacted {
+		return sn.Intern() + ".final.>"
+	}
+	return sn.Intern() + ".final"
+}
+
+func getStreamMirror(mirror models.StationMirror) *StreamSource {
+	return getStreamSource(mirror.StationName, mirror.Domain, _EMPTY_)
+}
+
+// validateMirror returns the mirror configuration of a new station and its origin station when the origin is served by this cluster,
+// stations of other domains are reached over a leafnode and can not be validated
+func validateMirror(sn StationName, tenantName string, mirror models.MirrorSchema, partitionsNumber int) (models.StationMirror, bool, models.Station, error) {
+	if mirror.StationName == _EMPTY_ {
+		return models.StationMirror{}, false, models.Station{}, nil
+	}
+	if isPartitioned(partitionsNumber) {
+		return models.StationMirror{}, false, models.Station{}, errors.New("a mirror can not be partitioned, it keeps the partitions of its origin")
+	}
+	originName, err := StationNameFromStr(mirror.StationName)
+	if err != nil {
+		return models.StationMirror{}, false, models.Station{}, err
+	}
+	stationMirror := models.StationMirror{StationName: originName.Ext(), Domain: mirror.Domain}
+	if mirror.Domain != _EMPTY_ {
+		return stationMirror, false, models.Station{}, nil
+	}
+	if originName.Ext() == sn.Ext() {
+		return models.StationMirror{}, false, models.Station{}, errors.New("a station can not mirror itself")
+	}
+	exist, origin, err := IsStationExist(originName, tenantName)
+	if err != nil {
+		return models.StationMirror{}, false, models.Station{}, err
+	}
+	if !exist {
+		return models.StationMirror{}, false, models.Station{}, errors.New("station " + originName.Ext() + " does not exist")
+	}
+	if isPartitioned(origin.PartitionsNumber) {
+		return models.StationMirror{}, false, models.Station{}, errors.New("partitioned stations can not be mirrored")
+	}
+	return stationMirror, true, origin, nil
+}
+
+func (s *Server) getMirrorDetails(station models.Station) (models.MirrorDetails, error) {
+	details := models.MirrorDetails{StationName: station.Mirror.StationName, Domain: station.Mirror.Domain, IsPromoted: station.Mirror.IsPromoted}
+	if !isActiveMirror(station) {
+		return details, nil
+	}
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return details, err
+	}
+	streamInfo, err := s.memphisStreamInfo(station.TenantName, sn.Intern())
+	if err != nil {
+		return details, err
+	}
+	if streamInfo.Mirror != nil {
+		details.Lag = streamInfo.Mirror.Lag
+		details.ActiveMs = streamInfo.Mirror.Active.Milliseconds()
+		if streamInfo.Mirror.Error != nil {
+			details.Error = streamInfo.Mirror.Error.Description
+		}
+	}
+	return details, nil
+}
+
+// promoteMirror turns a mirror into a regular station that can be produced to,
+// consumer groups of a local origin are recreated from the position they have acknowledged
+func (s *Server) promoteMirror(station models.Station) error {
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+	promoted := station
+	promoted.Mirror.IsPromoted = true
+	promoted.Mirror.PromotionDate = time.Now()
+	err = s.memphisUpdateStream(station.TenantName, getStationStreamConfig(sn, promoted))
+	if err != nil {
+		return err
+	}
+
+	if station.Mirror.Domain == _EMPTY_ {
+		originName, err := StationNameFromStr(station.Mirror.StationName)
+		if err != nil {
+			return err
+		}
+		exist, origin, err := IsStationExist(originName, station.TenantName)
+		if err != nil {
+			return err
+		}
+		if exist {
+			err = s.copyConsumerGroups(origin, promoted)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = stationsCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": station.ID},
+		bson.M{"$set": bson.M{"mirror": promoted.Mirror, "last_update": time.Now()}},
+	)
+	return err
+}
+
+func (s *Server) copyConsumerGroups(origin, station models.Station) error {
+	originName, err := StationNameFromStr(origin.Name)
+	if err != nil {
+		return err
+	}
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+	var consumers []models.Consumer
+	cursor, err := consumersCollection.Find(context.TODO(), bson.M{"station_id": origin.ID, "is_deleted": false})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(context.TODO(), &consumers); err != nil {
+		return err
+	}
+
+	copied := make(map[string]bool)
+	for _, consumer := range consumers {
+		if copied[consumer.ConsumersGroup] {
+			continue
+		}
+		copied[consumer.ConsumersGroup] = true
+
+		cc := getConsumerConfig(consumer.ConsumersGroup, consumer, sn, station)
+		// a mirror keeps the sequences of its origin
+		cgInfo, err := s.GetCgInfo(origin.TenantName, originName, consumer.ConsumersGroup)
+		if err == nil && cgInfo.AckFloor.Stream > 0 {
+			cc.DeliverPolicy = DeliverByStartSequence
+			cc.OptStartSeq = cgInfo.AckFloor.Stream + 1
+		}
+		err = s.memphisAddConsumer(station.TenantName, sn.Intern(), &cc)
+		if err != nil {
+			return err
+		}
+
+		consumer.ID = primitive.NewObjectID()
+		consumer.StationId = station.ID
+		consumer.IsActive = false
+		_, err = consumersCollection.InsertOne(context.TODO(), consumer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMirrors applies a change made on a station to the local mirrors that still follow it
+func syncMirrors(tenantName string, sn StationName, update bson.M) error {
+	_, err := stationsCollection.UpdateMany(context.TODO(),
+		bson.M{"tenant_name": tenantName, "mirror.station_name": sn.Ext(), "mirror.domain": _EMPTY_, "mirror.is_promoted": false, "is_deleted": false},
+		bson.M{"$set": update},
+	)
+	return err
+}
@@ -0,0 +1,347 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"memphis-broker/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	partitionKeyHeader               = "$memphis_partition_key"
+	partitionsUpdatesSubjectTemplate = "$memphis_partitions_updates_%s"
+	maxPartitionsNumber              = 128
+)
+
+type stationPartitionsState struct {
+	mu         sync.RWMutex
+	partitions map[string]int
+}
+
+var stationPartitions = stationPartitionsState{
+	partitions: make(map[string]int),
+}
+
+func validatePartitionsNumber(partitionsNumber int) error {
+	if partitionsNumber < 0 || partitionsNumber > maxPartitionsNumber {
+		return fmt.Errorf("partitions number has to be between 0 and %d", maxPartitionsNumber)
+	}
+	return nil
+}
+
+func isPartitioned(partitionsNumber int) bool {
+	return partitionsNumber > 1
+}
+
+func setStationPartitions(tenantName string, sn StationName, partitionsNumber int) {
+	key := tenantStreamKey(tenantName, sn.Intern())
+	stationPartitions.mu.Lock()
+	defer stationPartitions.mu.Unlock()
+	if isPartitioned(partitionsNumber) {
+		stationPartitions.partitions[key] = partitionsNumber
+	} else {
+		delete(stationPartitions.partitions, key)
+	}
+}
+
+func getStationPartitions(tenantName, streamName string) int {
+	stationPartitions.mu.RLock()
+	defer stationPartitions.mu.RUnlock()
+	return stationPartitions.partitions[tenantStreamKey(tenantName, streamName)]
+}
+
+func (s *Server) initializeStationPartitions() error {
+	var stations []models.Station
+	cursor, err := stationsCollection.Find(context.TODO(), bson.M{"is_deleted": false, "partitions_number": bson.M{"$gt": 1}})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(context.TODO(), &stations); err != nil {
+		return err
+	}
+	for _, station := range stations {
+		sn, err := StationNameFromStr(station.Name)
+		if err != nil {
+			continue
+		}
+		setStationPartitions(station.TenantName, sn, station.PartitionsNumber)
+	}
+	return nil
+}
+
+// broadcastStationPartitionsUpdate lets the other brokers in the cluster route messages of the station into its partitions
+func broadcastStationPartitionsUpdate(update models.PartitionsUpdate) error {
+	msg, err := json.Marshal(models.ConfigurationsUpdate{StationName: update.StationName, Type: "station_partitions", Update: update})
+	if err != nil {
+		return err
+	}
+	return serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), CONFIGURATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+}
+
+func handleStationPartitionsUpdate(rawUpdate any) error {
+	data, err := json.Marshal(rawUpdate)
+	if err != nil {
+		return err
+	}
+	var update models.PartitionsUpdate
+	if err = json.Unmarshal(data, &update); err != nil {
+		return err
+	}
+	sn, err := StationNameFromStr(update.StationName)
+	if err != nil {
+		return err
+	}
+	setStationPartitions(update.TenantName, sn, update.PartitionsNumber)
+	return nil
+}
+
+func getPartitionSubject(streamName string, partition int) string {
+	return streamName + ".final." + strconv.Itoa(partition)
+}
+
+func getPartitionDurableName(cgName string, partition int) string {
+	return getInternalConsumerName(cgName) + "$" + strconv.Itoa(partition)
+}
+
+// getMessagePartition hashes the partition key so all the messages of a key land in the same partition,
+// messages without a key keep the order of their producer
+func getMessagePartition(hdr []byte, partitionsNumber int) int {
+	var key []byte
+	if len(hdr) > 0 {
+		key = getHeader(partitionKeyHeader, hdr)
+		if len(key) == 0 {
+			key = getHeader("$memphis_producedBy", hdr)
+		}
+	}
+	if len(key) == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(partitionsNumber))
+}
+
+// partitionedSubject returns the subject a message produced into a station should be stored under
+func partitionedSubject(acc *Account, streamName, subject string, hdr []byte) string {
+	if subject != streamName+".final" {
+		return subject
+	}
+	partitionsNumber := getStationPartitions(tenantNameFromAccount(acc), streamName)
+	if !isPartitioned(partitionsNumber) {
+		return subject
+	}
+	return getPartitionSubject(streamName, getMessagePartition(hdr, partitionsNumber))
+}
+
+// assignPartitions spreads the partitions over the members sorted by name, so every broker computes the same assignment
+func assignPartitions(members []string, partitionsNumber int) map[string][]int {
+	assignments := make(map[string][]int, len(members))
+	if len(members) == 0 {
+		return assignments
+	}
+	sort.Strings(members)
+	for _, member := range members {
+		assignments[member] = []int{}
+	}
+	for partition := 0; partition < partitionsNumber; partition++ {
+		member := members[partition%len(members)]
+		assignments[member] = append(assignments[member], partition)
+	}
+	return assignments
+}
+
+func (s *Server) createPartitionsConsumers(station models.Station, cgName string, cc ConsumerConfig) error {
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+	for partition := 0; partition < station.PartitionsNumber; partition++ {
+		partitionConfig := cc
+		partitionConfig.Durable = getPartitionDurableName(cgName, partition)
+		partitionConfig.FilterSubject = getPartitionSubject(stationName.Intern(), partition)
+		err = s.memphisAddConsumer(station.TenantName, stationName.Intern(), &partitionConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) removePartitionsConsumers(tenantName string, stationName StationName, cgName string, partitionsNumber int) error {
+	for partition := 0; partition < partitionsNumber; partition++ {
+		err := s.memphisRemoveConsumer(tenantName, stationName.Intern(), getPartitionDurableName(cgName, partition))
+		if err != nil && !IsNatsErr(err, JSConsumerNotFoundErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPartitionsCgInfo sums up the state of all the partitions consumers of a consumer group
+func (s *Server) getPartitionsCgInfo(tenantName string, stationName StationName, cgName string, partitionsNumber int) (*ConsumerInfo, error) {
+	var cgInfo *ConsumerInfo
+	for partition := 0; partition < partitionsNumber; partition++ {
+		requestSubject := fmt.Sprintf(JSApiConsumerInfoT, stationName.Intern(), getPartitionDurableName(cgName, partition))
+		var resp JSApiConsumerInfoResponse
+		err := jsApiRequest(s, tenantName, requestSubject, kindConsumerInfo, []byte(_EMPTY_), &resp)
+		if err != nil {
+			return nil, err
+		}
+		if err = resp.ToError(); err != nil {
+			return nil, err
+		}
+		if cgInfo == nil {
+			info := *resp.ConsumerInfo
+			info.Name = getInternalConsumerName(cgName)
+			cgInfo = &info
+			continue
+		}
+		cgInfo.NumAckPending += resp.NumAckPending
+		cgInfo.NumRedelivered += resp.NumRedelivered
+		cgInfo.NumWaiting += resp.NumWaiting
+		cgInfo.NumPending += resp.NumPending
+		if resp.ContentFilter != nil {
+			if cgInfo.ContentFilter == nil {
+				cgInfo.ContentFilter = &models.ContentFilterStats{}
+			}
+			cgInfo.ContentFilter.Matched += resp.ContentFilter.Matched
+			cgInfo.ContentFilter.Skipped += resp.ContentFilter.Skipped
+		}
+	}
+	if cgInfo == nil {
+		return nil, errors.New("station has no partitions")
+	}
+	return cgInfo, nil
+}
+
+func (s *Server) getStationPartitionsDetails(station models.Station) ([]models.StationPartitionDetails, error) {
+	partitions := []models.StationPartitionDetails{}
+	if !isPartitioned(station.PartitionsNumber) {
+		return partitions, nil
+	}
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return partitions, err
+	}
+	request, err := json.Marshal(JSApiStreamInfoRequest{SubjectsFilter: stationName.Intern() + ".final.*"})
+	if err != nil {
+		return partitions, err
+	}
+	var resp JSApiStreamInfoResponse
+	err = jsApiRequest(s, station.TenantName, fmt.Sprintf(JSApiStreamInfoT, stationName.Intern()), kindStreamInfo, request, &resp)
+	if err != nil {
+		return partitions, err
+	}
+	if err = resp.ToError(); err != nil {
+		return partitions, err
+	}
+	for partition := 0; partition < station.PartitionsNumber; partition++ {
+		partitions = append(partitions, models.StationPartitionDetails{
+			Partition:     partition,
+			TotalMessages: resp.State.Subjects[getPartitionSubject(stationName.Intern(), partition)],
+		})
+	}
+	return partitions, nil
+}
+
+// rebalancePartitions reassigns the partitions of the station between the active members of the consumer group
+// and publishes the new assignment to the consumers of the station
+func (s *Server) rebalancePartitions(station models.Station, cgName string) (models.PartitionsAssignment, error) {
+	assignment := models.PartitionsAssignment{StationName: station.Name, ConsumersGroup: cgName, PartitionsNumber: station.PartitionsNumber}
+	stationName, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return assignment, err
+	}
+	var consumers []models.Consumer
+	cursor, err := consumersCollection.Find(context.TODO(), bson.M{"station_id": station.ID, "consumers_group": cgName, "is_active": true, "is_deleted": false})
+	if err != nil {
+		return assignment, err
+	}
+	if err = cursor.All(context.TODO(), &consumers); err != nil {
+		return assignment, err
+	}
+	members := []string{}
+	seen := make(map[string]bool)
+	for _, consumer := range consumers {
+		if seen[consumer.Name] {
+			continue
+		}
+		seen[consumer.Name] = true
+		members = append(members, consumer.Name)
+	}
+	assignment.Assignments = assignPartitions(members, station.PartitionsNumber)
+
+	msg, err := json.Marshal(assignment)
+	if err != nil {
+		return assignment, err
+	}
+	acc, err := s.getTenantAccount(station.TenantName)
+	if err != nil {
+		return assignment, err
+	}
+	s.sendInternalAccountMsg(acc, fmt.Sprintf(partitionsUpdatesSubjectTemplate, stationName.Intern()), msg)
+	return assignment, nil
+}
+
+// rebalanceConnectionPartitions rebalances every partitioned station a connection consumes from,
+// called when the connection goes down or comes back
+func (s *Server) rebalanceConnectionPartitions(connectionId primitive.ObjectID) {
+	var consumers []models.Consumer
+	cursor, err := consumersCollection.Find(context.TODO(), bson.M{"connection_id": connectionId, "is_deleted": false})
+	if err != nil {
+		s.Errorf("rebalanceConnectionPartitions: " + err.Error())
+		return
+	}
+	if err = cursor.All(context.TODO(), &consumers); err != nil {
+		s.Errorf("rebalanceConnectionPartitions: " + err.Error())
+		return
+	}
+	rebalanced := make(map[string]bool)
+	for _, consumer := range consumers {
+		key := consumer.StationId.Hex() + "/" + consumer.ConsumersGroup
+		if rebalanced[key] {
+			continue
+		}
+		rebalanced[key] = true
+		var station models.Station
+		err = stationsCollection.FindOne(context.TODO(), bson.M{"_id": consumer.StationId}).Decode(&station)
+		if err != nil {
+			s.Errorf("rebalanceConnectionPartitions: At station ID " + consumer.StationId.Hex() + ": " + err.Error())
+			continue
+		}
+		if !isPartitioned(station.PartitionsNumber) {
+			continue
+		}
+		_, err = s.rebalancePartitions(station, consumer.ConsumersGroup)
+		if err != nil {
+			s.Errorf("rebalanceConnectionPartitions: At station " + station.Name + ": " + err.Error())
+		}
+	}
+}
+
+func cgNameFromPartitionDurable(durableName string) string {
+	if idx := strings.LastIndex(durableName, "$"); idx > 0 {
+		return durableName[:idx]
+	}
+	return durableName
+}
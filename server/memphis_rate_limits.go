@@ -0,0 +1,342 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"memphis-broker/models"
+	"memphis-broker/notifications"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	rateLimitScopeProducer = "producer"
+	rateLimitScopeUser     = "user"
+	rateLimitScopeStation  = "station"
+
+	RateLimitTitle                = "Rate limit exceeded"
+	rateLimitNotificationInterval = time.Minute
+	rateLimitViolationsRetention  = 24 * time.Hour
+)
+
+// rateWindow counts what was published under a single key during the current second
+type rateWindow struct {
+	mu    sync.Mutex
+	start int64
+	msgs  int
+	bytes int
+}
+
+// the limits are read on every publish while they rarely change, so they are guarded separately
+// from the windows, which have a lock of their own so publishes to different stations never contend
+type rateLimitsState struct {
+	configured   int32
+	mu           sync.RWMutex
+	stations     map[string]models.RateLimits
+	users        map[string]models.RateLimits
+	producer     models.RateLimits
+	windows      sync.Map
+	lastPrune    int64
+	violationsMu sync.Mutex
+	violations   map[string]*models.RateLimitViolation
+	lastNotified map[string]time.Time
+}
+
+var rateLimits = rateLimitsState{
+	stations:     make(map[string]models.RateLimits),
+	users:        make(map[string]models.RateLimits),
+	violations:   make(map[string]*models.RateLimitViolation),
+	lastNotified: make(map[string]time.Time),
+}
+
+type rateLimitCheck struct {
+	scope  string
+	name   string
+	key    string
+	limits models.RateLimits
+}
+
+func rateLimitsEnabled(limits models.RateLimits) bool {
+	return limits.MsgsPerSec > 0 || limits.BytesPerSec > 0
+}
+
+func tenantStreamKey(tenantName, streamName string) string {
+	return tenantName + "/" + streamName
+}
+
+// updateRateLimitsConfiguredLocked has to be called while holding rateLimits.mu for writing
+func updateRateLimitsConfiguredLocked() {
+	var configured int32
+	if len(rateLimits.stations) > 0 || len(rateLimits.users) > 0 || rateLimitsEnabled(rateLimits.producer) {
+		configured = 1
+	}
+	atomic.StoreInt32(&rateLimits.configured, configured)
+}
+
+func setStationRateLimits(tenantName string, sn StationName, limits models.RateLimits) {
+	key := tenantStreamKey(tenantName, sn.Intern())
+	rateLimits.mu.Lock()
+	defer rateLimits.mu.Unlock()
+	if rateLimitsEnabled(limits) {
+		rateLimits.stations[key] = limits
+	} else {
+		delete(rateLimits.stations, key)
+	}
+	updateRateLimitsConfiguredLocked()
+}
+
+// user limits are counted by each broker on its own, for the stations whose streams it leads,
+// so a user producing to stations led by different brokers can reach a multiple of its limit in total
+func setUserRateLimits(username string, limits models.RateLimits) {
+	rateLimits.mu.Lock()
+	defer rateLimits.mu.Unlock()
+	if rateLimitsEnabled(limits) {
+		rateLimits.users[username] = limits
+	} else {
+		delete(rateLimits.users, username)
+	}
+	updateRateLimitsConfiguredLocked()
+}
+
+func setProducerRateLimits(limits models.RateLimits) {
+	rateLimits.mu.Lock()
+	defer rateLimits.mu.Unlock()
+	rateLimits.producer = limits
+	updateRateLimitsConfiguredLocked()
+}
+
+func getProducerRateLimits() models.RateLimits {
+	rateLimits.mu.RLock()
+	defer rateLimits.mu.RUnlock()
+	return rateLimits.producer
+}
+
+func (s *Server) initializeRateLimits() error {
+	enabledFilter := []interface{}{
+		bson.M{"rate_limits.msgs_per_sec": bson.M{"$gt": 0}},
+		bson.M{"rate_limits.bytes_per_sec": bson.M{"$gt": 0}},
+	}
+	var stations []models.Station
+	cursor, err := stationsCollection.Find(context.TODO(), bson.M{"is_deleted": false, "$or": enabledFilter})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(context.TODO(), &stations); err != nil {
+		return err
+	}
+	for _, station := range stations {
+		sn, err := StationNameFromStr(station.Name)
+		if err != nil {
+			continue
+		}
+		setStationRateLimits(station.TenantName, sn, station.RateLimits)
+	}
+
+	var users []models.User
+	cursor, err = usersCollection.Find(context.TODO(), bson.M{"$or": enabledFilter})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(context.TODO(), &users); err != nil {
+		return err
+	}
+	for _, user := range users {
+		setUserRateLimits(user.Username, user.RateLimits)
+	}
+	return nil
+}
+
+// broadcastRateLimitsUpdate lets the other brokers in the cluster refresh their cached limits
+func broadcastRateLimitsUpdate(updateType string, update models.RateLimitsUpdate) error {
+	msg, err := json.Marshal(models.ConfigurationsUpdate{StationName: update.StationName, Type: updateType, Update: update})
+	if err != nil {
+		return err
+	}
+	return serv.sendInternalAccountMsgWithReply(serv.GlobalAccount(), CONFIGURATIONS_UPDATES_SUBJ, _EMPTY_, nil, msg, true)
+}
+
+func handleRateLimitsUpdate(updateType string, rawUpdate any) error {
+	data, err := json.Marshal(rawUpdate)
+	if err != nil {
+		return err
+	}
+	var update models.RateLimitsUpdate
+	if err = json.Unmarshal(data, &update); err != nil {
+		return err
+	}
+	switch updateType {
+	case "station_rate_limits":
+		sn, err := StationNameFromStr(update.StationName)
+		if err != nil {
+			return err
+		}
+		setStationRateLimits(update.TenantName, sn, update.RateLimits)
+	case "user_rate_limits":
+		setUserRateLimits(update.Username, update.RateLimits)
+	case "producer_rate_limits":
+		setProducerRateLimits(update.RateLimits)
+	}
+	return nil
+}
+
+// checkPublishRateLimits is called for every message a client publishes into a station stream,
+// a message is counted only when none of the producer, user and station limits is exceeded.
+// The limits are enforced by the broker leading the station's stream, see setUserRateLimits
+func (s *Server) checkPublishRateLimits(c *client, acc *Account, streamName string, hdr []byte, size int) error {
+	if atomic.LoadInt32(&rateLimits.configured) == 0 || strings.HasPrefix(streamName, "$memphis") {
+		return nil
+	}
+
+	tenantName := tenantNameFromAccount(acc)
+	username := c.memphisInfo.username
+
+	// the windows are always locked in this order, which keeps concurrent checks from deadlocking
+	var checks []rateLimitCheck
+	rateLimits.mu.RLock()
+	if rateLimitsEnabled(rateLimits.producer) && len(hdr) > 0 {
+		producerName := string(getHeader("$memphis_producedBy", hdr))
+		connectionId := string(getHeader("$memphis_connectionId", hdr))
+		if producerName != _EMPTY_ {
+			checks = append(checks, rateLimitCheck{scope: rateLimitScopeProducer, name: producerName, key: rateLimitScopeProducer + ":" + connectionId + "/" + producerName, limits: rateLimits.producer})
+		}
+	}
+	if limits, ok := rateLimits.users[username]; ok && username != _EMPTY_ {
+		checks = append(checks, rateLimitCheck{scope: rateLimitScopeUser, name: username, key: rateLimitScopeUser + ":" + username, limits: limits})
+	}
+	stationKey := tenantStreamKey(tenantName, streamName)
+	if limits, ok := rateLimits.stations[stationKey]; ok {
+		checks = append(checks, rateLimitCheck{scope: rateLimitScopeStation, name: streamName, key: rateLimitScopeStation + ":" + stationKey, limits: limits})
+	}
+	rateLimits.mu.RUnlock()
+	if len(checks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	sec := now.Unix()
+	if lastPrune := atomic.LoadInt64(&rateLimits.lastPrune); lastPrune != sec && atomic.CompareAndSwapInt64(&rateLimits.lastPrune, lastPrune, sec) {
+		go pruneRateWindows(sec)
+	}
+
+	windows := make([]*rateWindow, len(checks))
+	for i, check := range checks {
+		window, _ := rateLimits.windows.LoadOrStore(check.key, &rateWindow{start: sec})
+		windows[i] = window.(*rateWindow)
+		windows[i].mu.Lock()
+		defer windows[i].mu.Unlock()
+	}
+
+	for i, check := range checks {
+		window := windows[i]
+		if window.start != sec {
+			window.start, window.msgs, window.bytes = sec, 0, 0
+		}
+		exceeded := (check.limits.MsgsPerSec > 0 && window.msgs+1 > check.limits.MsgsPerSec) ||
+			(check.limits.BytesPerSec > 0 && window.bytes+size > check.limits.BytesPerSec)
+		if exceeded {
+			s.recordRateLimitViolation(check, tenantName, streamName, now)
+			return fmt.Errorf("memphis: %s %s exceeded its rate limit of %d messages and %d bytes per second", check.scope, check.name, check.limits.MsgsPerSec, check.limits.BytesPerSec)
+		}
+	}
+
+	for _, window := range windows {
+		window.msgs++
+		window.bytes += size
+	}
+	return nil
+}
+
+// pruneRateWindows drops the windows nothing was published under lately, e.g. of producers which disconnected,
+// a publish racing with the removal of its window may go uncounted
+func pruneRateWindows(sec int64) {
+	rateLimits.windows.Range(func(key, value any) bool {
+		window := value.(*rateWindow)
+		window.mu.Lock()
+		idle := window.start < sec-1
+		window.mu.Unlock()
+		if idle {
+			rateLimits.windows.Delete(key)
+		}
+		return true
+	})
+}
+
+// pruneRateLimitViolationsLocked has to be called while holding rateLimits.violationsMu
+func pruneRateLimitViolationsLocked(now time.Time) {
+	for key, violation := range rateLimits.violations {
+		if now.Sub(violation.LastViolation) > rateLimitViolationsRetention {
+			delete(rateLimits.violations, key)
+		}
+	}
+	for key, lastNotified := range rateLimits.lastNotified {
+		if now.Sub(lastNotified) >= rateLimitNotificationInterval {
+			delete(rateLimits.lastNotified, key)
+		}
+	}
+}
+
+func (s *Server) recordRateLimitViolation(check rateLimitCheck, tenantName, streamName string, now time.Time) {
+	rateLimits.violationsMu.Lock()
+	defer rateLimits.violationsMu.Unlock()
+	pruneRateLimitViolationsLocked(now)
+
+	violationKey := tenantName + "/" + streamName + "/" + check.key
+	violation, ok := rateLimits.violations[violationKey]
+	if !ok {
+		violation = &models.RateLimitViolation{
+			Scope:       check.scope,
+			Name:        check.name,
+			StationName: streamName,
+			TenantName:  tenantName,
+		}
+		rateLimits.violations[violationKey] = violation
+	}
+	violation.Count++
+	violation.LastViolation = now
+
+	if now.Sub(rateLimits.lastNotified[check.key]) < rateLimitNotificationInterval {
+		return
+	}
+	rateLimits.lastNotified[check.key] = now
+	msg := fmt.Sprintf("The %s %s has exceeded its rate limit of %d messages and %d bytes per second while producing to station %s", check.scope, check.name, check.limits.MsgsPerSec, check.limits.BytesPerSec, streamName)
+	s.Warnf("checkPublishRateLimits: " + msg)
+	go func() {
+		err := notifications.SendNotification(RateLimitTitle, msg, notifications.RateLAlert)
+		if err != nil {
+			s.Warnf("checkPublishRateLimits: Error while sending a rate limit notification: " + err.Error())
+		}
+	}()
+}
+
+func getRateLimitViolations(tenantName, streamName string) []models.RateLimitViolation {
+	rateLimits.violationsMu.Lock()
+	defer rateLimits.violationsMu.Unlock()
+	pruneRateLimitViolationsLocked(time.Now())
+	violations := []models.RateLimitViolation{}
+	for _, violation := range rateLimits.violations {
+		if violation.TenantName != tenantName {
+			continue
+		}
+		if streamName != _EMPTY_ && violation.StationName != streamName {
+			continue
+		}
+		violations = append(violations, *violation)
+	}
+	return violations
+}
@@ -0,0 +1,861 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"memphis-broker/models"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jhump/protoreflect/desc"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SchemaRegistryHandler serves a Confluent compatible schema registry API on top of
+// the Memphis schemas, a registry subject is a Memphis schema and a registry version is a schema version
+type SchemaRegistryHandler struct{ S *Server }
+
+const (
+	registryContentType                  = "application/vnd.schemaregistry.v1+json"
+	registryLastIdKey                    = "schema_registry_last_id"
+	registryCompatibilityKey             = "schema_registry_compatibility"
+	registryDefaultCompatibility         = "BACKWARD"
+	registryErrUnauthorized              = 40101
+	registryErrSubjectNotFound           = 40401
+	registryErrVersionNotFound           = 40402
+	registryErrSchemaNotFound            = 40403
+	registryErrIncompatibleSchema        = 40901
+	registryErrInvalidSchema             = 42201
+	registryErrInvalidVersion            = 42202
+	registryErrInvalidCompatibilityLevel = 42203
+	registryErrStore                     = 50001
+)
+
+var registryCompatibilityLevels = []string{"NONE", "BACKWARD", "BACKWARD_TRANSITIVE", "FORWARD", "FORWARD_TRANSITIVE", "FULL", "FULL_TRANSITIVE"}
+
+var (
+	ErrRegistryIncompatibleSchema = errors.New("Schema being registered is incompatible with an earlier schema")
+)
+
+func registryError(c *gin.Context, errorCode int, message string) {
+	c.Header("Content-Type", registryContentType)
+	c.AbortWithStatusJSON(errorCode/100, gin.H{"error_code": errorCode, "message": message})
+}
+
+func registryResponse(c *gin.Context, body interface{}) {
+	c.Header("Content-Type", registryContentType)
+	c.JSON(200, body)
+}
+
+// bindRegistryBody binds the body as json regardless of the content type, registry clients send
+// application/vnd.schemaregistry.v1+json which gin does not map to the json binding
+func bindRegistryBody(c *gin.Context, body interface{}) bool {
+	err := c.ShouldBindWith(body, binding.JSON)
+	if err != nil {
+		registryError(c, registryErrInvalidSchema, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func registrySchemaType(schemaType string) string {
+	return strings.ToUpper(schemaType)
+}
+
+func memphisSchemaType(registryType string) string {
+	if registryType == "" {
+		// the registry protocol defaults to avro when no type is given
+		return "avro"
+	}
+	return strings.ToLower(registryType)
+}
+
+func validateCompatibilityLevel(level string) error {
+	for _, l := range registryCompatibilityLevels {
+		if l == level {
+			return nil
+		}
+	}
+	return errors.New("Invalid compatibility level. Valid values are none, backward, forward, full, backward_transitive, forward_transitive, and full_transitive")
+}
+
+// RegistryAuthenticate is the schema registry middleware, registry clients authenticate with basic auth
+// using Memphis users credentials
+func RegistryAuthenticate(c *gin.Context) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		registryError(c, registryErrUnauthorized, "Unauthorized")
+		return
+	}
+	authenticated, user, err := authenticateUser(strings.ToLower(username), password)
+	if err != nil {
+		serv.Errorf("RegistryAuthenticate: User " + username + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	if !authenticated {
+		registryError(c, registryErrUnauthorized, "Unauthorized")
+		return
+	}
+	c.Set("user", user)
+	c.Next()
+}
+
+func getRegistryDefaultCompatibility() (string, error) {
+	var systemKey models.SystemKey
+	err := systemKeysCollection.FindOne(context.TODO(), bson.M{"key": registryCompatibilityKey}).Decode(&systemKey)
+	if err == mongo.ErrNoDocuments {
+		return registryDefaultCompatibility, nil
+	} else if err != nil {
+		return "", err
+	}
+	return systemKey.Value, nil
+}
+
+func getSubjectCompatibility(schema models.Schema) (string, error) {
+	if schema.CompatibilityLevel != "" {
+		return schema.CompatibilityLevel, nil
+	}
+	return getRegistryDefaultCompatibility()
+}
+
+// allocateRegistryId returns the next global registry id, ids are shared across tenants like in a single registry
+func allocateRegistryId() (int, error) {
+	var counter struct {
+		LastId int `bson:"last_id"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := systemKeysCollection.FindOneAndUpdate(context.TODO(),
+		bson.M{"key": registryLastIdKey},
+		bson.M{"$inc": bson.M{"last_id": 1}},
+		opts,
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.LastId, nil
+}
+
+// getVersionRegistryId lazily assigns registry ids to versions created through the Memphis API
+func getVersionRegistryId(version models.SchemaVersion) (int, error) {
+	if version.RegistryId != 0 {
+		return version.RegistryId, nil
+	}
+	id, err := allocateRegistryId()
+	if err != nil {
+		return 0, err
+	}
+	res, err := schemaVersionCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": version.ID, "registry_id": bson.M{"$in": bson.A{0, nil}}},
+		bson.M{"$set": bson.M{"registry_id": id}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if res.ModifiedCount == 0 {
+		var current models.SchemaVersion
+		err = schemaVersionCollection.FindOne(context.TODO(), bson.M{"_id": version.ID}).Decode(&current)
+		if err != nil {
+			return 0, err
+		}
+		return current.RegistryId, nil
+	}
+	return id, nil
+}
+
+func getSortedSchemaVersions(schemaId primitive.ObjectID) ([]models.SchemaVersion, error) {
+	versions, err := getSchemaVersionsBySchemaId(schemaId)
+	if err != nil {
+		return versions, err
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].VersionNumber < versions[j].VersionNumber
+	})
+	return versions, nil
+}
+
+func registryVersionResponse(schema models.Schema, version models.SchemaVersion) (models.RegistrySchemaVersion, error) {
+	id, err := getVersionRegistryId(version)
+	if err != nil {
+		return models.RegistrySchemaVersion{}, err
+	}
+	return models.RegistrySchemaVersion{
+		Subject:    schema.Name,
+		Id:         id,
+		Version:    version.VersionNumber,
+		SchemaType: registrySchemaType(schema.Type),
+		Schema:     version.SchemaContent,
+	}, nil
+}
+
+func getFirstProtobufMessageName(schemaContent string) (string, error) {
+	files, err := parseProtobufContent(schemaContent)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		for _, msg := range file.GetMessageTypes() {
+			return msg.GetName(), nil
+		}
+	}
+	return "", errors.New("Your Proto file has no message definitions")
+}
+
+// isJsonSchemaCompatible checks that data written with the writer schema can be read with the reader schema,
+// every property the reader requires has to be required by the writer and shared properties have to keep their type
+func isJsonSchemaCompatible(reader, writer string) (bool, error) {
+	var readerSchema, writerSchema map[string]interface{}
+	if err := json.Unmarshal([]byte(reader), &readerSchema); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(writer), &writerSchema); err != nil {
+		return false, err
+	}
+
+	writerRequired := map[string]bool{}
+	if required, ok := writerSchema["required"].([]interface{}); ok {
+		for _, r := range required {
+			writerRequired[r.(string)] = true
+		}
+	}
+	if required, ok := readerSchema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if !writerRequired[r.(string)] {
+				return false, nil
+			}
+		}
+	}
+
+	readerProps, _ := readerSchema["properties"].(map[string]interface{})
+	writerProps, _ := writerSchema["properties"].(map[string]interface{})
+	for name, readerProp := range readerProps {
+		writerProp, ok := writerProps[name]
+		if !ok {
+			continue
+		}
+		readerType, _ := readerProp.(map[string]interface{})
+		writerType, _ := writerProp.(map[string]interface{})
+		if readerType == nil || writerType == nil {
+			continue
+		}
+		if readerType["type"] != nil && writerType["type"] != nil && !reflect.DeepEqual(readerType["type"], writerType["type"]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isProtobufSchemaCompatible checks that fields sharing a number in messages sharing a name keep their type,
+// protobuf compatibility is symmetric so the reader and writer order does not matter
+func isProtobufSchemaCompatible(reader, writer string) (bool, error) {
+	readerFiles, err := parseProtobufContent(reader)
+	if err != nil {
+		return false, err
+	}
+	writerFiles, err := parseProtobufContent(writer)
+	if err != nil {
+		return false, err
+	}
+
+	writerMessages := map[string]*desc.MessageDescriptor{}
+	for _, file := range writerFiles {
+		for _, msg := range file.GetMessageTypes() {
+			writerMessages[msg.GetFullyQualifiedName()] = msg
+		}
+	}
+	for _, file := range readerFiles {
+		for _, msg := range file.GetMessageTypes() {
+			writerMsg, ok := writerMessages[msg.GetFullyQualifiedName()]
+			if !ok {
+				continue
+			}
+			for _, field := range msg.GetFields() {
+				writerField := writerMsg.FindFieldByNumber(field.GetNumber())
+				if writerField == nil {
+					continue
+				}
+				if writerField.GetType() != field.GetType() || writerField.IsRepeated() != field.IsRepeated() {
+					return false, nil
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+func isSchemaContentCompatible(schemaType, reader, writer string) (bool, error) {
+	switch schemaType {
+	case "json":
+		return isJsonSchemaCompatible(reader, writer)
+	case "protobuf":
+		return isProtobufSchemaCompatible(reader, writer)
+	default:
+		return true, nil
+	}
+}
+
+// checkRegistryCompatibility checks a new schema content against the existing versions according to the compatibility level,
+// non transitive levels check against the latest version only
+func checkRegistryCompatibility(level, schemaType, schemaContent string, versions []models.SchemaVersion) (bool, error) {
+	if level == "NONE" || len(versions) == 0 {
+		return true, nil
+	}
+	if !strings.HasSuffix(level, "_TRANSITIVE") {
+		versions = versions[len(versions)-1:]
+	}
+	level = strings.TrimSuffix(level, "_TRANSITIVE")
+	for _, version := range versions {
+		if level == "BACKWARD" || level == "FULL" {
+			compatible, err := isSchemaContentCompatible(schemaType, schemaContent, version.SchemaContent)
+			if err != nil || !compatible {
+				return false, err
+			}
+		}
+		if level == "FORWARD" || level == "FULL" {
+			compatible, err := isSchemaContentCompatible(schemaType, version.SchemaContent, schemaContent)
+			if err != nil || !compatible {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func (srh SchemaRegistryHandler) getSubject(c *gin.Context, funcName string) (models.Schema, bool) {
+	subject := strings.ToLower(c.Param("subject"))
+	exist, schema, err := IsSchemaExist(subject, getTenantNameFromMiddleware(c))
+	if err != nil {
+		serv.Errorf(funcName + ": Subject " + subject + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return schema, false
+	}
+	if !exist {
+		registryError(c, registryErrSubjectNotFound, "Subject '"+subject+"' not found.")
+		return schema, false
+	}
+	return schema, true
+}
+
+func (srh SchemaRegistryHandler) getSubjectVersion(c *gin.Context, funcName string, schema models.Schema) (models.SchemaVersion, bool) {
+	versions, err := getSortedSchemaVersions(schema.ID)
+	if err != nil {
+		serv.Errorf(funcName + ": Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return models.SchemaVersion{}, false
+	}
+	versionStr := c.Param("version")
+	if versionStr == "latest" || versionStr == "-1" {
+		if len(versions) == 0 {
+			registryError(c, registryErrVersionNotFound, "Version not found.")
+			return models.SchemaVersion{}, false
+		}
+		return versions[len(versions)-1], true
+	}
+	versionNumber, err := strconv.Atoi(versionStr)
+	if err != nil || versionNumber < 1 {
+		registryError(c, registryErrInvalidVersion, "The specified version '"+versionStr+"' is not a valid version id. Allowed values are between [1, 2^31-1] and the string \"latest\"")
+		return models.SchemaVersion{}, false
+	}
+	for _, version := range versions {
+		if version.VersionNumber == versionNumber {
+			return version, true
+		}
+	}
+	registryError(c, registryErrVersionNotFound, "Version "+versionStr+" not found.")
+	return models.SchemaVersion{}, false
+}
+
+func (srh SchemaRegistryHandler) GetSubjects(c *gin.Context) {
+	var schemas []models.Schema
+	cursor, err := schemasCollection.Find(context.TODO(), bson.M{"tenant_name": getTenantNameFromMiddleware(c)})
+	if err != nil {
+		serv.Errorf("GetSubjects: " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	if err = cursor.All(context.TODO(), &schemas); err != nil {
+		serv.Errorf("GetSubjects: " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+
+	subjects := []string{}
+	for _, schema := range schemas {
+		subjects = append(subjects, schema.Name)
+	}
+	sort.Strings(subjects)
+	registryResponse(c, subjects)
+}
+
+func (srh SchemaRegistryHandler) GetSubjectVersions(c *gin.Context) {
+	schema, ok := srh.getSubject(c, "GetSubjectVersions")
+	if !ok {
+		return
+	}
+	versions, err := getSortedSchemaVersions(schema.ID)
+	if err != nil {
+		serv.Errorf("GetSubjectVersions: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+
+	versionNumbers := []int{}
+	for _, version := range versions {
+		versionNumbers = append(versionNumbers, version.VersionNumber)
+	}
+	registryResponse(c, versionNumbers)
+}
+
+func (srh SchemaRegistryHandler) GetSubjectVersion(c *gin.Context) {
+	schema, ok := srh.getSubject(c, "GetSubjectVersion")
+	if !ok {
+		return
+	}
+	version, ok := srh.getSubjectVersion(c, "GetSubjectVersion", schema)
+	if !ok {
+		return
+	}
+	response, err := registryVersionResponse(schema, version)
+	if err != nil {
+		serv.Errorf("GetSubjectVersion: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	registryResponse(c, response)
+}
+
+func (srh SchemaRegistryHandler) GetSubjectVersionSchema(c *gin.Context) {
+	schema, ok := srh.getSubject(c, "GetSubjectVersionSchema")
+	if !ok {
+		return
+	}
+	version, ok := srh.getSubjectVersion(c, "GetSubjectVersionSchema", schema)
+	if !ok {
+		return
+	}
+	c.Data(200, registryContentType, []byte(version.SchemaContent))
+}
+
+func (srh SchemaRegistryHandler) RegisterSchema(c *gin.Context) {
+	var body models.RegistrySchema
+	if !bindRegistryBody(c, &body) {
+		return
+	}
+	user, err := getUserDetailsFromMiddleware(c)
+	if err != nil {
+		registryError(c, registryErrUnauthorized, "Unauthorized")
+		return
+	}
+	tenantName := getTenantNameFromMiddleware(c)
+	subject := strings.ToLower(c.Param("subject"))
+	err = validateSchemaName(subject)
+	if err != nil {
+		serv.Warnf("RegisterSchema: " + err.Error())
+		registryError(c, registryErrInvalidSchema, err.Error())
+		return
+	}
+	schemaType := memphisSchemaType(body.SchemaType)
+	err = validateSchemaType(schemaType)
+	if err != nil {
+		serv.Warnf("RegisterSchema: Subject " + subject + ": " + err.Error())
+		registryError(c, registryErrInvalidSchema, err.Error())
+		return
+	}
+	err = validateSchemaContent(body.Schema, schemaType)
+	if err != nil {
+		serv.Warnf("RegisterSchema: Subject " + subject + ": " + err.Error())
+		registryError(c, registryErrInvalidSchema, err.Error())
+		return
+	}
+
+	exist, schema, err := IsSchemaExist(subject, tenantName)
+	if err != nil {
+		serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	var versions []models.SchemaVersion
+	if exist {
+		if schema.Type != schemaType {
+			errMsg := "Subject " + subject + " has schema type " + registrySchemaType(schema.Type)
+			serv.Warnf("RegisterSchema: " + errMsg)
+			registryError(c, registryErrInvalidSchema, errMsg)
+			return
+		}
+		versions, err = getSortedSchemaVersions(schema.ID)
+		if err != nil {
+			serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+			registryError(c, registryErrStore, "Server error")
+			return
+		}
+		// registering an already registered schema is idempotent
+		for _, version := range versions {
+			if version.SchemaContent == body.Schema {
+				id, err := getVersionRegistryId(version)
+				if err != nil {
+					serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+					registryError(c, registryErrStore, "Server error")
+					return
+				}
+				registryResponse(c, gin.H{"id": id})
+				return
+			}
+		}
+		level, err := getSubjectCompatibility(schema)
+		if err != nil {
+			serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+			registryError(c, registryErrStore, "Server error")
+			return
+		}
+		compatible, err := checkRegistryCompatibility(level, schemaType, body.Schema, versions)
+		if err != nil || !compatible {
+			serv.Warnf("RegisterSchema: Subject " + subject + ": " + ErrRegistryIncompatibleSchema.Error())
+			registryError(c, registryErrIncompatibleSchema, ErrRegistryIncompatibleSchema.Error())
+			return
+		}
+	} else {
+		schema = models.Schema{
+			ID:         primitive.NewObjectID(),
+			Name:       subject,
+			Type:       schemaType,
+			TenantName: tenantName,
+		}
+	}
+
+	messageStructName := ""
+	descriptor := ""
+	versionNumber := len(versions) + 1
+	if schemaType == "protobuf" {
+		messageStructName, err = getFirstProtobufMessageName(body.Schema)
+		if err != nil {
+			serv.Warnf("RegisterSchema: Subject " + subject + ": " + err.Error())
+			registryError(c, registryErrInvalidSchema, err.Error())
+			return
+		}
+		descriptor, err = generateSchemaDescriptor(subject, versionNumber, body.Schema, schemaType)
+		if err != nil {
+			serv.Warnf("RegisterSchema: Subject " + subject + ": " + err.Error())
+			registryError(c, registryErrInvalidSchema, err.Error())
+			return
+		}
+	}
+
+	registryId, err := allocateRegistryId()
+	if err != nil {
+		serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	newSchemaVersion := models.SchemaVersion{
+		ID:                primitive.NewObjectID(),
+		VersionNumber:     versionNumber,
+		Active:            !exist,
+		CreatedByUser:     user.Username,
+		CreationDate:      time.Now(),
+		SchemaContent:     body.Schema,
+		SchemaId:          schema.ID,
+		MessageStructName: messageStructName,
+		Descriptor:        descriptor,
+		RegistryId:        registryId,
+	}
+
+	if !exist {
+		filter := bson.M{"name": schema.Name, "tenant_name": schema.TenantName}
+		update := bson.M{
+			"$setOnInsert": bson.M{
+				"_id":  schema.ID,
+				"type": schema.Type,
+			},
+		}
+		opts := options.Update().SetUpsert(true)
+		updateResults, err := schemasCollection.UpdateOne(context.TODO(), filter, update, opts)
+		if err != nil {
+			serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+			registryError(c, registryErrStore, "Server error")
+			return
+		}
+		if updateResults.MatchedCount > 0 {
+			serv.Warnf("RegisterSchema: Subject " + subject + " has been created concurrently")
+			registryError(c, registryErrIncompatibleSchema, "Subject "+subject+" has been created concurrently")
+			return
+		}
+	}
+
+	filter := bson.M{"schema_id": schema.ID, "version_number": newSchemaVersion.VersionNumber}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":                 newSchemaVersion.ID,
+			"active":              newSchemaVersion.Active,
+			"created_by_user":     newSchemaVersion.CreatedByUser,
+			"creation_date":       newSchemaVersion.CreationDate,
+			"schema_content":      newSchemaVersion.SchemaContent,
+			"message_struct_name": newSchemaVersion.MessageStructName,
+			"descriptor":          newSchemaVersion.Descriptor,
+			"registry_id":         newSchemaVersion.RegistryId,
+		},
+	}
+	opts := options.Update().SetUpsert(true)
+	updateResults, err := schemaVersionCollection.UpdateOne(context.TODO(), filter, update, opts)
+	if err != nil {
+		serv.Errorf("RegisterSchema: Subject " + subject + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	if updateResults.MatchedCount > 0 {
+		serv.Warnf("RegisterSchema: Subject " + subject + ": Version " + strconv.Itoa(versionNumber) + " already exists")
+		registryError(c, registryErrIncompatibleSchema, "Version "+strconv.Itoa(versionNumber)+" has been registered concurrently")
+		return
+	}
+
+	serv.Noticef("Schema " + subject + " version " + strconv.Itoa(versionNumber) + " has been registered by " + user.Username + " through the schema registry API")
+	registryResponse(c, gin.H{"id": registryId})
+}
+
+func (srh SchemaRegistryHandler) LookupSchema(c *gin.Context) {
+	var body models.RegistrySchema
+	if !bindRegistryBody(c, &body) {
+		return
+	}
+	schema, ok := srh.getSubject(c, "LookupSchema")
+	if !ok {
+		return
+	}
+	versions, err := getSortedSchemaVersions(schema.ID)
+	if err != nil {
+		serv.Errorf("LookupSchema: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	for _, version := range versions {
+		if version.SchemaContent != body.Schema {
+			continue
+		}
+		response, err := registryVersionResponse(schema, version)
+		if err != nil {
+			serv.Errorf("LookupSchema: Subject " + schema.Name + ": " + err.Error())
+			registryError(c, registryErrStore, "Server error")
+			return
+		}
+		registryResponse(c, response)
+		return
+	}
+	registryError(c, registryErrSchemaNotFound, "Schema not found")
+}
+
+func (srh SchemaRegistryHandler) DeleteSubject(c *gin.Context) {
+	schema, ok := srh.getSubject(c, "DeleteSubject")
+	if !ok {
+		return
+	}
+	versions, err := getSortedSchemaVersions(schema.ID)
+	if err != nil {
+		serv.Errorf("DeleteSubject: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+
+	DeleteTagsFromSchema(schema.ID)
+	err = deleteSchemaFromStations(srh.S, schema.Name, schema.TenantName)
+	if err != nil {
+		serv.Errorf("DeleteSubject: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	err = SchemasHandler{S: srh.S}.findAndDeleteSchema([]primitive.ObjectID{schema.ID})
+	if err != nil {
+		serv.Errorf("DeleteSubject: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	serv.Noticef("Schema " + schema.Name + " has been deleted through the schema registry API")
+
+	versionNumbers := []int{}
+	for _, version := range versions {
+		versionNumbers = append(versionNumbers, version.VersionNumber)
+	}
+	registryResponse(c, versionNumbers)
+}
+
+func (srh SchemaRegistryHandler) GetSchemaById(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		registryError(c, registryErrSchemaNotFound, "Schema "+c.Param("id")+" not found")
+		return
+	}
+	var version models.SchemaVersion
+	err = schemaVersionCollection.FindOne(context.TODO(), bson.M{"registry_id": id}).Decode(&version)
+	if err == mongo.ErrNoDocuments {
+		registryError(c, registryErrSchemaNotFound, "Schema "+c.Param("id")+" not found")
+		return
+	} else if err != nil {
+		serv.Errorf("GetSchemaById: Schema " + c.Param("id") + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	var schema models.Schema
+	err = schemasCollection.FindOne(context.TODO(), bson.M{"_id": version.SchemaId, "tenant_name": getTenantNameFromMiddleware(c)}).Decode(&schema)
+	if err == mongo.ErrNoDocuments {
+		registryError(c, registryErrSchemaNotFound, "Schema "+c.Param("id")+" not found")
+		return
+	} else if err != nil {
+		serv.Errorf("GetSchemaById: Schema " + c.Param("id") + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	registryResponse(c, gin.H{"schema": version.SchemaContent, "schemaType": registrySchemaType(schema.Type)})
+}
+
+func (srh SchemaRegistryHandler) GetSchemaTypes(c *gin.Context) {
+	registryResponse(c, []string{"JSON", "PROTOBUF", "GRAPHQL"})
+}
+
+func (srh SchemaRegistryHandler) TestCompatibility(c *gin.Context) {
+	var body models.RegistrySchema
+	if !bindRegistryBody(c, &body) {
+		return
+	}
+	schema, ok := srh.getSubject(c, "TestCompatibility")
+	if !ok {
+		return
+	}
+	schemaType := schema.Type
+	if body.SchemaType != "" && memphisSchemaType(body.SchemaType) != schemaType {
+		registryResponse(c, gin.H{"is_compatible": false})
+		return
+	}
+	err := validateSchemaContent(body.Schema, schemaType)
+	if err != nil {
+		registryError(c, registryErrInvalidSchema, err.Error())
+		return
+	}
+
+	var versions []models.SchemaVersion
+	level, err := getSubjectCompatibility(schema)
+	if err != nil {
+		serv.Errorf("TestCompatibility: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	if strings.HasSuffix(level, "_TRANSITIVE") {
+		versions, err = getSortedSchemaVersions(schema.ID)
+		if err != nil {
+			serv.Errorf("TestCompatibility: Subject " + schema.Name + ": " + err.Error())
+			registryError(c, registryErrStore, "Server error")
+			return
+		}
+	} else {
+		version, ok := srh.getSubjectVersion(c, "TestCompatibility", schema)
+		if !ok {
+			return
+		}
+		versions = []models.SchemaVersion{version}
+	}
+
+	compatible, err := checkRegistryCompatibility(level, schemaType, body.Schema, versions)
+	if err != nil {
+		compatible = false
+	}
+	registryResponse(c, gin.H{"is_compatible": compatible})
+}
+
+func (srh SchemaRegistryHandler) GetConfig(c *gin.Context) {
+	level, err := getRegistryDefaultCompatibility()
+	if err != nil {
+		serv.Errorf("GetConfig: " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	registryResponse(c, gin.H{"compatibilityLevel": level})
+}
+
+func (srh SchemaRegistryHandler) UpdateConfig(c *gin.Context) {
+	var body models.RegistryCompatibilityConfig
+	if !bindRegistryBody(c, &body) {
+		return
+	}
+	level := strings.ToUpper(body.Compatibility)
+	err := validateCompatibilityLevel(level)
+	if err != nil {
+		registryError(c, registryErrInvalidCompatibilityLevel, err.Error())
+		return
+	}
+	if user, _ := getUserDetailsFromMiddleware(c); user.UserType != "root" {
+		registryError(c, registryErrUnauthorized, "Only the root user can change the global compatibility level")
+		return
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err = systemKeysCollection.UpdateOne(context.TODO(),
+		bson.M{"key": registryCompatibilityKey},
+		bson.M{"$set": bson.M{"value": level}},
+		opts,
+	)
+	if err != nil {
+		serv.Errorf("UpdateConfig: " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	registryResponse(c, gin.H{"compatibility": level})
+}
+
+func (srh SchemaRegistryHandler) GetSubjectConfig(c *gin.Context) {
+	schema, ok := srh.getSubject(c, "GetSubjectConfig")
+	if !ok {
+		return
+	}
+	level, err := getSubjectCompatibility(schema)
+	if err != nil {
+		serv.Errorf("GetSubjectConfig: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	registryResponse(c, gin.H{"compatibilityLevel": level})
+}
+
+func (srh SchemaRegistryHandler) UpdateSubjectConfig(c *gin.Context) {
+	var body models.RegistryCompatibilityConfig
+	if !bindRegistryBody(c, &body) {
+		return
+	}
+	level := strings.ToUpper(body.Compatibility)
+	err := validateCompatibilityLevel(level)
+	if err != nil {
+		registryError(c, registryErrInvalidCompatibilityLevel, err.Error())
+		return
+	}
+	schema, ok := srh.getSubject(c, "UpdateSubjectConfig")
+	if !ok {
+		return
+	}
+
+	_, err = schemasCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": schema.ID},
+		bson.M{"$set": bson.M{"compatibility_level": level}},
+	)
+	if err != nil {
+		serv.Errorf("UpdateSubjectConfig: Subject " + schema.Name + ": " + err.Error())
+		registryError(c, registryErrStore, "Server error")
+		return
+	}
+	registryResponse(c, gin.H{"compatibility": level})
+}
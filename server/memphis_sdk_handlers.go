@@ -14,6 +14,7 @@ package server
 import (
 	"encoding/json"
 	"memphis-broker/models"
+	"time"
 )
 
 const configurationsUpdatesSubject = "$memphis_sdk_configurations_updates"
@@ -35,6 +36,11 @@ type createStationRequest struct {
 	DedupWindowMillis int                     `json:"dedup_window_in_ms"` // TODO deprecated
 	IdempotencyWindow int64                   `json:"idempotency_window_in_ms"`
 	DlsConfiguration  models.DlsConfiguration `json:"dls_configuration"`
+	PartitionsNumber  int                     `json:"partitions_number"`
+	Mirror            models.MirrorSchema     `json:"mirror"`
+	Sources           []models.StationSource  `json:"sources"`
+	Compacted         bool                    `json:"compacted"`
+	MaxMsgsPerKey     int                     `json:"max_msgs_per_key"`
 }
 
 type destroyStationRequest struct {
@@ -69,13 +75,15 @@ type destroyProducerRequest struct {
 }
 
 type createConsumerRequest struct {
-	Name             string `json:"name"`
-	StationName      string `json:"station_name"`
-	ConnectionId     string `json:"connection_id"`
-	ConsumerType     string `json:"consumer_type"`
-	ConsumerGroup    string `json:"consumers_group"`
-	MaxAckTimeMillis int    `json:"max_ack_time_ms"`
-	MaxMsgDeliveries int    `json:"max_msg_deliveries"`
+	Name              string                    `json:"name"`
+	StationName       string                    `json:"station_name"`
+	ConnectionId      string                    `json:"connection_id"`
+	ConsumerType      string                    `json:"consumer_type"`
+	ConsumerGroup     string                    `json:"consumers_group"`
+	MaxAckTimeMillis  int                       `json:"max_ack_time_ms"`
+	MaxMsgDeliveries  int                       `json:"max_msg_deliveries"`
+	RedeliveryBackoff *models.RedeliveryBackoff `json:"redelivery_backoff"`
+	ContentFilter     *models.ContentFilter     `json:"content_filter"`
 }
 
 type attachSchemaRequest struct {
@@ -92,42 +100,150 @@ type destroyConsumerRequest struct {
 	ConsumerName string `json:"name"`
 }
 
+type createKvBucketRequest struct {
+	BucketName  string `json:"name"`
+	History     int    `json:"history"`
+	TTLSec      int    `json:"ttl_in_sec"`
+	StorageType string `json:"storage_type"`
+	Replicas    int    `json:"replicas"`
+}
+
+type destroyKvBucketRequest struct {
+	BucketName string `json:"bucket_name"`
+}
+
+type kvRequest struct {
+	BucketName       string  `json:"bucket_name"`
+	Key              string  `json:"key"`
+	Value            []byte  `json:"value"`
+	ExpectedRevision *uint64 `json:"expected_revision"`
+	Purge            bool    `json:"purge"`
+	IncludeHistory   bool    `json:"include_history"`
+	DeliverSubject   string  `json:"deliver_subject"`
+}
+
+type kvEntryResponse struct {
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	Revision  uint64    `json:"revision"`
+	Operation string    `json:"operation"`
+	Created   time.Time `json:"created"`
+}
+
+type kvResponse struct {
+	Revision     uint64            `json:"revision"`
+	Entries      []kvEntryResponse `json:"entries"`
+	ConsumerName string            `json:"consumer_name"`
+	Err          string            `json:"error"`
+}
+
+type largePayloadUploadRequest struct {
+	StationName string `json:"station_name"`
+	PayloadSize int    `json:"payload_size"`
+}
+
+type largePayloadUploadResponse struct {
+	ObjectId     string `json:"object_id"`
+	ChunkSubject string `json:"chunk_subject"`
+	ChunkSize    int    `json:"chunk_size"`
+	ChunksTotal  int    `json:"chunks_total"`
+	Err          string `json:"error"`
+}
+
+type largePayloadFetchRequest struct {
+	StationName    string `json:"station_name"`
+	ObjectId       string `json:"object_id"`
+	ChunksTotal    int    `json:"chunks_total"`
+	PayloadSize    int    `json:"payload_size"`
+	DeliverSubject string `json:"deliver_subject"`
+}
+
+type largePayloadFetchResponse struct {
+	Payload      []byte `json:"payload"`
+	ChunksTotal  int    `json:"chunks_total"`
+	ConsumerName string `json:"consumer_name"`
+	Err          string `json:"error"`
+}
+
 func (cpr *createProducerResponse) SetError(err error) {
 	cpr.Err = err.Error()
 }
 
-func (s *Server) initializeSDKHandlers() {
+func (kr *kvResponse) SetError(err error) {
+	kr.Err = err.Error()
+}
+
+func (ur *largePayloadUploadResponse) SetError(err error) {
+	ur.Err = err.Error()
+}
+
+func (fr *largePayloadFetchResponse) SetError(err error) {
+	fr.Err = err.Error()
+}
+
+func (s *Server) initializeSDKHandlers(acc *Account) {
 	//stations
-	s.queueSubscribe("$memphis_station_creations",
+	s.queueSubscribeOnAcc(acc, "$memphis_station_creations",
 		"memphis_station_creations_listeners_group",
 		createStationHandler(s))
-	s.queueSubscribe("$memphis_station_destructions",
+	s.queueSubscribeOnAcc(acc, "$memphis_station_destructions",
 		"memphis_station_destructions_listeners_group",
 		destroyStationHandler(s))
 
 	// producers
-	s.queueSubscribe("$memphis_producer_creations",
+	s.queueSubscribeOnAcc(acc, "$memphis_producer_creations",
 		"memphis_producer_creations_listeners_group",
 		createProducerHandler(s))
-	s.queueSubscribe("$memphis_producer_destructions",
+	s.queueSubscribeOnAcc(acc, "$memphis_producer_destructions",
 		"memphis_producer_destructions_listeners_group",
 		destroyProducerHandler(s))
 
 	// consumers
-	s.queueSubscribe("$memphis_consumer_creations",
+	s.queueSubscribeOnAcc(acc, "$memphis_consumer_creations",
 		"memphis_consumer_creations_listeners_group",
 		createConsumerHandler(s))
-	s.queueSubscribe("$memphis_consumer_destructions",
+	s.queueSubscribeOnAcc(acc, "$memphis_consumer_destructions",
 		"memphis_consumer_destructions_listeners_group",
 		destroyConsumerHandler(s))
 
 	// schema attachements
-	s.queueSubscribe("$memphis_schema_attachments",
+	s.queueSubscribeOnAcc(acc, "$memphis_schema_attachments",
 		"memphis_schema_attachments_listeners_group",
 		attachSchemaHandler(s))
-	s.queueSubscribe("$memphis_schema_detachments",
+	s.queueSubscribeOnAcc(acc, "$memphis_schema_detachments",
 		"memphis_schema_detachments_listeners_group",
 		detachSchemaHandler(s))
+
+	// kv buckets
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_bucket_creations",
+		"memphis_kv_bucket_creations_listeners_group",
+		createKvBucketHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_bucket_destructions",
+		"memphis_kv_bucket_destructions_listeners_group",
+		destroyKvBucketHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_puts",
+		"memphis_kv_puts_listeners_group",
+		kvPutHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_gets",
+		"memphis_kv_gets_listeners_group",
+		kvGetHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_histories",
+		"memphis_kv_histories_listeners_group",
+		kvHistoryHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_deletions",
+		"memphis_kv_deletions_listeners_group",
+		kvDeleteHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_kv_watches",
+		"memphis_kv_watches_listeners_group",
+		kvWatchHandler(s))
+
+	// large payloads
+	s.queueSubscribeOnAcc(acc, "$memphis_large_payload_uploads",
+		"memphis_large_payload_uploads_listeners_group",
+		uploadLargePayloadHandler(s))
+	s.queueSubscribeOnAcc(acc, "$memphis_large_payload_fetches",
+		"memphis_large_payload_fetches_listeners_group",
+		fetchLargePayloadHandler(s))
 }
 
 func createStationHandler(s *Server) simplifiedMsgHandler {
@@ -178,12 +294,66 @@ func detachSchemaHandler(s *Server) simplifiedMsgHandler {
 	}
 }
 
-func respondWithErr(s *Server, replySubject string, err error) {
+func createKvBucketHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.createKvBucketDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func destroyKvBucketHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.removeKvBucketDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func kvPutHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.kvPutDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func kvGetHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.kvGetDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func kvHistoryHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.kvHistoryDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func kvDeleteHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.kvDeleteDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func kvWatchHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.kvWatchDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func uploadLargePayloadHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.uploadLargePayloadDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func fetchLargePayloadHandler(s *Server) simplifiedMsgHandler {
+	return func(c *client, subject, reply string, msg []byte) {
+		go s.fetchLargePayloadDirect(c, reply, copyBytes(msg))
+	}
+}
+
+func respondWithErr(s *Server, acc *Account, replySubject string, err error) {
 	resp := []byte("")
 	if err != nil {
 		resp = []byte(err.Error())
 	}
-	s.respondOnGlobalAcc(replySubject, resp)
+	s.respondOnAcc(acc, replySubject, resp)
 }
 
 func respondWithErrOrJsApiResp[T any](jsApi bool, c *client, acc *Account, subject, reply, msg string, resp T, err error) {
@@ -193,21 +363,21 @@ func respondWithErrOrJsApiResp[T any](jsApi bool, c *client, acc *Account, subje
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
 		return
 	}
-	respondWithErr(c.srv, reply, err)
+	respondWithErr(c.srv, acc, reply, err)
 }
 
-func respondWithResp(s *Server, replySubject string, resp memphisResponse) {
+func respondWithResp(s *Server, acc *Account, replySubject string, resp memphisResponse) {
 	rawResp, err := json.Marshal(resp)
 	if err != nil {
 		serv.Errorf("respondWithResp: response marshal error: " + err.Error())
 		return
 	}
-	s.respondOnGlobalAcc(replySubject, rawResp)
+	s.respondOnAcc(acc, replySubject, rawResp)
 }
 
-func respondWithRespErr(s *Server, replySubject string, err error, resp memphisResponse) {
+func respondWithRespErr(s *Server, acc *Account, replySubject string, err error, resp memphisResponse) {
 	resp.SetError(err)
-	respondWithResp(s, replySubject, resp)
+	respondWithResp(s, acc, replySubject, resp)
 }
 
 func (s *Server) SendUpdateToClients(configurationUpdate models.ConfigurationsUpdate) {
@@ -0,0 +1,409 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"memphis-broker/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	stationKeySize            = 32
+	defaultStationKeysFile    = "station_keys.json"
+	previousStationKeySuffix  = "/previous"
+	stationKeysUpdatesTimeout = 5 * time.Second
+	stationKeyActionPut       = "put"
+	stationKeyActionRotate    = "rotate"
+	stationKeyActionDelete    = "delete"
+)
+
+var errStationKeyNotFound = errors.New("station key not found")
+
+// StationKeyProvider holds the keys encrypted stations are stored with,
+// the default provider is a local key file and a KMS can be plugged in with SetStationKeyProvider
+type StationKeyProvider interface {
+	GetKey(keyId string) ([]byte, error)
+	PutKey(keyId string, key []byte) error
+	DeleteKey(keyId string) error
+}
+
+var (
+	stationKeyProvider     StationKeyProvider
+	stationKeyProviderOnce sync.Once
+)
+
+// SetStationKeyProvider replaces the local key file, it should be called before the server starts
+// so the streams of encrypted stations are recovered with it
+func SetStationKeyProvider(provider StationKeyProvider) {
+	stationKeyProvider = provider
+}
+
+func (s *Server) getStationKeyProvider() StationKeyProvider {
+	stationKeyProviderOnce.Do(func() {
+		if stationKeyProvider != nil {
+			return
+		}
+		path := configuration.STATION_KEYS_FILE
+		if path == _EMPTY_ {
+			storeDir := s.getOpts().StoreDir
+			if storeDir == _EMPTY_ {
+				storeDir = filepath.Join(os.TempDir(), JetStreamStoreDir)
+			}
+			path = filepath.Join(storeDir, defaultStationKeysFile)
+		}
+		stationKeyProvider = &localStationKeyProvider{path: path}
+	})
+	return stationKeyProvider
+}
+
+// localStationKeyProvider keeps the station keys in a json file on the broker's disk
+type localStationKeyProvider struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (p *localStationKeyProvider) load() (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	buf, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (p *localStationKeyProvider) store(keys map[string][]byte) error {
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), defaultDirPerms); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+func (p *localStationKeyProvider) GetKey(keyId string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keys[keyId]
+	if !ok {
+		return nil, errStationKeyNotFound
+	}
+	return key, nil
+}
+
+func (p *localStationKeyProvider) PutKey(keyId string, key []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys, err := p.load()
+	if err != nil {
+		return err
+	}
+	keys[keyId] = key
+	return p.store(keys)
+}
+
+func (p *localStationKeyProvider) DeleteKey(keyId string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys, err := p.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[keyId]; !ok {
+		return nil
+	}
+	delete(keys, keyId)
+	return p.store(keys)
+}
+
+// stationKeyId returns the id of the key a stream is encrypted with, the DLS stream shares the key of its station
+func stationKeyId(accName, streamName string) string {
+	dlsAffixes := strings.SplitN(dlsStreamName, "%s", 2)
+	if strings.HasPrefix(streamName, dlsAffixes[0]) && strings.HasSuffix(streamName, dlsAffixes[1]) {
+		streamName = strings.TrimSuffix(strings.TrimPrefix(streamName, dlsAffixes[0]), dlsAffixes[1])
+	}
+	return accName + "/" + streamName
+}
+
+func stationKeyGen(key []byte, info string) keyGen {
+	return func(context []byte) ([]byte, error) {
+		h := hmac.New(sha256.New, key)
+		if _, err := h.Write([]byte(info)); err != nil {
+			return nil, err
+		}
+		if _, err := h.Write(context); err != nil {
+			return nil, err
+		}
+		return h.Sum(nil), nil
+	}
+}
+
+// streamKeyGen returns the key generation function of a stream's file store,
+// streams of encrypted stations derive their keys from the station key instead of the server wide one
+func (s *Server) streamKeyGen(accName, streamName string) keyGen {
+	key, err := s.getStationKeyProvider().GetKey(stationKeyId(accName, streamName))
+	if err != nil {
+		if err == errStationKeyNotFound {
+			return s.jsKeyGen(accName)
+		}
+		// never fall back to a different key, the store has to fail instead
+		s.Errorf("streamKeyGen: Stream %s: %v", streamName, err)
+		return func(context []byte) ([]byte, error) {
+			return nil, err
+		}
+	}
+	return stationKeyGen(key, accName)
+}
+
+// streamPreviousKeyGen returns the key generation function of the station key a stream was encrypted with
+// before a rotation that has not completed yet on this broker, nil if there is none
+func (s *Server) streamPreviousKeyGen(accName, streamName string) keyGen {
+	key, err := s.getStationKeyProvider().GetKey(stationKeyId(accName, streamName) + previousStationKeySuffix)
+	if err != nil {
+		if err != errStationKeyNotFound {
+			s.Errorf("streamPreviousKeyGen: Stream %s: %v", streamName, err)
+		}
+		return nil
+	}
+	return stationKeyGen(key, accName)
+}
+
+func generateStationKey() ([]byte, error) {
+	key := make([]byte, stationKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *Server) newStationKeyUpdate(action, tenantName string, sn StationName, key []byte) (models.StationKeyUpdate, error) {
+	acc, err := s.getTenantAccount(tenantName)
+	if err != nil {
+		return models.StationKeyUpdate{}, err
+	}
+	return models.StationKeyUpdate{
+		Action:      action,
+		AccountName: acc.Name,
+		StreamNames: []string{sn.Intern(), fmt.Sprintf(dlsStreamName, sn.Intern())},
+		Key:         key,
+	}, nil
+}
+
+// broadcastStationKeyUpdate sends a station key update to all the brokers and waits until they applied it
+func (s *Server) broadcastStationKeyUpdate(update models.StationKeyUpdate) error {
+	msg, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	return s.broadcastToBrokers(STATION_KEYS_UPDATES_SUBJ, msg, stationKeysUpdatesTimeout)
+}
+
+// applyStationKeyUpdate updates the local copy of a station key, rotations re-encrypt the local streams in the background
+func (s *Server) applyStationKeyUpdate(update models.StationKeyUpdate) error {
+	if len(update.StreamNames) == 0 {
+		return errors.New("station key update without streams")
+	}
+	provider := s.getStationKeyProvider()
+	keyId := stationKeyId(update.AccountName, update.StreamNames[0])
+	switch update.Action {
+	case stationKeyActionPut:
+		return provider.PutKey(keyId, update.Key)
+	case stationKeyActionRotate:
+		// the previous key can not be replaced before all the blocks encrypted with it are rotated
+		if _, err := provider.GetKey(keyId + previousStationKeySuffix); err == nil {
+			return errors.New("the previous key rotation of this station is still in progress")
+		} else if err != errStationKeyNotFound {
+			return err
+		}
+		oldKey, err := provider.GetKey(keyId)
+		if err != nil {
+			return err
+		}
+		// the previous key is kept until the local streams are re-encrypted
+		if err := provider.PutKey(keyId+previousStationKeySuffix, oldKey); err != nil {
+			return err
+		}
+		if err := provider.PutKey(keyId, update.Key); err != nil {
+			return err
+		}
+		go s.reencryptLocalStreams(update, keyId)
+		return nil
+	case stationKeyActionDelete:
+		if err := provider.DeleteKey(keyId + previousStationKeySuffix); err != nil {
+			return err
+		}
+		return provider.DeleteKey(keyId)
+	default:
+		return errors.New("unknown station key action " + update.Action)
+	}
+}
+
+// reencryptLocalStreams re-encrypts the replicas of a station's streams served by this broker with the new station key
+func (s *Server) reencryptLocalStreams(update models.StationKeyUpdate, keyId string) {
+	acc, err := s.LookupAccount(update.AccountName)
+	if err != nil {
+		s.Errorf("reencryptLocalStreams: " + err.Error())
+		return
+	}
+	prf := stationKeyGen(update.Key, update.AccountName)
+	for _, streamName := range update.StreamNames {
+		mset, err := acc.lookupStream(streamName)
+		if err != nil {
+			// a replica that is not loaded yet still needs the previous key, the rotation resumes on the next start
+			if s.hasLocalStreamStore(update.AccountName, streamName) {
+				s.Noticef("reencryptLocalStreams: Stream %s is not loaded yet, the rotation resumes once it is", streamName)
+				return
+			}
+			continue
+		}
+		mset.mu.RLock()
+		fs, ok := mset.store.(*fileStore)
+		mset.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := fs.rotateEncryptionKeys(prf); err != nil {
+			s.Errorf("reencryptLocalStreams: Stream %s: %v", streamName, err)
+			return
+		}
+	}
+	if err := s.getStationKeyProvider().DeleteKey(keyId + previousStationKeySuffix); err != nil {
+		s.Errorf("reencryptLocalStreams: " + err.Error())
+		return
+	}
+	s.Noticef("Streams of station key %s have been re-encrypted", keyId)
+}
+
+// hasLocalStreamStore reports whether this broker stores a replica of the stream on disk
+func (s *Server) hasLocalStreamStore(accName, streamName string) bool {
+	js := s.getJetStream()
+	if js == nil {
+		return false
+	}
+	js.mu.RLock()
+	storeDir := js.config.StoreDir
+	js.mu.RUnlock()
+	_, err := os.Stat(filepath.Join(storeDir, accName, streamsDir, streamName))
+	return err == nil
+}
+
+// resumeStationKeyRotation finishes a station key rotation that was interrupted by a restart of this broker once one
+// of the station's streams is loaded, until then the stream opens the keys that were not rotated yet with the previous station key
+func (s *Server) resumeStationKeyRotation(accName, streamName string) {
+	keyId := stationKeyId(accName, streamName)
+	key, err := s.getStationKeyProvider().GetKey(keyId)
+	if err != nil {
+		s.Errorf("resumeStationKeyRotation: " + err.Error())
+		return
+	}
+	stationStreamName := strings.TrimPrefix(keyId, accName+"/")
+	update := models.StationKeyUpdate{
+		Action:      stationKeyActionRotate,
+		AccountName: accName,
+		StreamNames: []string{stationStreamName, fmt.Sprintf(dlsStreamName, stationStreamName)},
+		Key:         key,
+	}
+	s.Noticef("Resuming the rotation of station key %s", keyId)
+	s.reencryptLocalStreams(update, keyId)
+}
+
+// encryptStation creates the key of a new encrypted station on all the brokers, it has to exist before the streams are created
+func (s *Server) encryptStation(tenantName string, sn StationName) error {
+	key, err := generateStationKey()
+	if err != nil {
+		return err
+	}
+	update, err := s.newStationKeyUpdate(stationKeyActionPut, tenantName, sn, key)
+	if err != nil {
+		return err
+	}
+	return s.broadcastStationKeyUpdate(update)
+}
+
+// rotateStationKey replaces the key of an encrypted station, the brokers re-encrypt the station's streams in the background
+func (s *Server) rotateStationKey(station models.Station) error {
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+	key, err := generateStationKey()
+	if err != nil {
+		return err
+	}
+	update, err := s.newStationKeyUpdate(stationKeyActionRotate, station.TenantName, sn, key)
+	if err != nil {
+		return err
+	}
+	if err := s.broadcastStationKeyUpdate(update); err != nil {
+		return err
+	}
+	_, err = stationsCollection.UpdateOne(context.TODO(),
+		bson.M{"name": station.Name, "tenant_name": station.TenantName, "is_deleted": false},
+		bson.M{"$set": bson.M{"key_rotation_date": time.Now()}},
+	)
+	return err
+}
+
+// shredStation removes an encrypted station and deletes its key from all the brokers,
+// which leaves the station's data that is still encrypted with that key unreadable.
+// Backups are not covered: they hold decrypted snapshots together with the station keys,
+// so every backup taken while the station existed has to be destroyed separately
+func (s *Server) shredStation(station models.Station) error {
+	sn, err := StationNameFromStr(station.Name)
+	if err != nil {
+		return err
+	}
+	err = removeStationResources(s, station, true)
+	if err != nil {
+		return err
+	}
+	_, err = stationsCollection.UpdateOne(context.TODO(),
+		bson.M{"name": station.Name, "tenant_name": station.TenantName, "is_deleted": false},
+		bson.M{"$set": bson.M{"is_deleted": true}},
+	)
+	if err != nil {
+		return err
+	}
+	update, err := s.newStationKeyUpdate(stationKeyActionDelete, station.TenantName, sn, nil)
+	if err != nil {
+		return err
+	}
+	return s.broadcastStationKeyUpdate(update)
+}
@@ -0,0 +1,295 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"memphis-broker/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	stationMetricsStreamName     = "$memphis_station_metrics"
+	stationMetricsSampleInterval = time.Minute
+	stationMetricsRetention      = 7*24*time.Hour + time.Hour
+	stationMetricsFetchTimeout   = 10 * time.Second
+)
+
+type stationMetricsRange struct {
+	duration time.Duration
+	step     time.Duration
+}
+
+var stationMetricsRanges = map[string]stationMetricsRange{
+	"hour": {duration: time.Hour, step: time.Minute},
+	"day":  {duration: 24 * time.Hour, step: 10 * time.Minute},
+	"week": {duration: 7 * 24 * time.Hour, step: time.Hour},
+}
+
+func getStationMetricsSubject(tenantName, streamName string) string {
+	return stationMetricsStreamName + "." + replaceDelimiters(tenantName) + "." + streamName
+}
+
+func (s *Server) createStationMetricsStream() error {
+	err := s.memphisAddStream(globalTenantName, &StreamConfig{
+		Name:         stationMetricsStreamName,
+		Subjects:     []string{stationMetricsStreamName + ".>"},
+		Retention:    LimitsPolicy,
+		MaxAge:       stationMetricsRetention,
+		MaxConsumers: -1,
+		MaxMsgs:      -1,
+		MaxBytes:     -1,
+		Discard:      DiscardOld,
+		Storage:      FileStorage,
+	})
+	if err != nil && !IsNatsErr(err, JSStreamNameExistErr) {
+		return err
+	}
+	return nil
+}
+
+// SampleStationsMetrics stores a sample of every station once a minute, only the leader samples in a cluster
+func (s *Server) SampleStationsMetrics() {
+	streamCreated := false
+	for range time.Tick(stationMetricsSampleInterval) {
+		if s.JetStreamIsClustered() && !s.JetStreamIsLeader() {
+			continue
+		}
+		if !streamCreated {
+			if err := s.createStationMetricsStream(); err != nil {
+				s.Warnf("SampleStationsMetrics: " + err.Error())
+				continue
+			}
+			streamCreated = true
+		}
+
+		tenantNames, err := getAllTenantNames()
+		if err != nil {
+			s.Errorf("SampleStationsMetrics: " + err.Error())
+			continue
+		}
+		for _, tenantName := range tenantNames {
+			if err := s.sampleStationsMetrics(tenantName, time.Now()); err != nil {
+				s.Warnf("SampleStationsMetrics: Tenant " + tenantName + ": " + err.Error())
+			}
+		}
+	}
+}
+
+func (s *Server) sampleStationsMetrics(tenantName string, now time.Time) error {
+	streams, err := s.memphisAllStreamsInfo(tenantName)
+	if err != nil {
+		return err
+	}
+
+	dlsStates := make(map[string]StreamState)
+	for _, info := range streams {
+		streamName := info.Config.Name
+		if strings.HasPrefix(streamName, "$memphis-") && strings.HasSuffix(streamName, "-dls") {
+			dlsStates[strings.TrimSuffix(strings.TrimPrefix(streamName, "$memphis-"), "-dls")] = info.State
+		}
+	}
+
+	acc, err := s.getTenantAccount(globalTenantName)
+	if err != nil {
+		return err
+	}
+	for _, info := range streams {
+		streamName := info.Config.Name
+		if strings.HasPrefix(streamName, "$memphis") || strings.HasPrefix(streamName, kvBucketStreamPrefix) {
+			continue
+		}
+
+		cgsLag, cgsDelivered, err := s.getCgsStats(tenantName, streamName)
+		if err != nil {
+			s.Warnf("sampleStationsMetrics: Station " + StationNameFromStreamName(streamName).Ext() + ": " + err.Error())
+			continue
+		}
+		sample := models.StationMetricsSample{
+			StreamName:   streamName,
+			Time:         now,
+			Messages:     info.State.Msgs,
+			Bytes:        info.State.Bytes,
+			LastSeq:      info.State.LastSeq,
+			DlsMessages:  dlsStates[streamName].Msgs,
+			DlsBytes:     dlsStates[streamName].Bytes,
+			CgsLag:       cgsLag,
+			CgsDelivered: cgsDelivered,
+		}
+		data, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		s.sendInternalAccountMsg(acc, getStationMetricsSubject(tenantName, streamName), data)
+	}
+	return nil
+}
+
+// getCgsStats sums the messages every consumer group has yet to ack and the deliveries it got so far
+// across the partitions of the station
+func (s *Server) getCgsStats(tenantName, streamName string) (map[string]uint64, map[string]uint64, error) {
+	consumers, err := s.memphisAllConsumersInfo(tenantName, streamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	partitioned := isPartitioned(getStationPartitions(tenantName, streamName))
+	cgsLag := make(map[string]uint64)
+	cgsDelivered := make(map[string]uint64)
+	for _, ci := range consumers {
+		if ci.Config == nil || ci.Config.Durable == _EMPTY_ || strings.HasPrefix(ci.Name, "$memphis") {
+			continue
+		}
+		cgName := ci.Name
+		if partitioned {
+			cgName = cgNameFromPartitionDurable(cgName)
+		}
+		cgName = revertDelimiters(cgName)
+		cgsLag[cgName] += ci.NumPending + uint64(ci.NumAckPending)
+		cgsDelivered[cgName] += ci.Delivered.Consumer
+	}
+	return cgsLag, cgsDelivered, nil
+}
+
+func (s *Server) GetStationMetrics(tenantName string, stationName StationName, rangeName string, now time.Time) ([]models.StationMetricsPoint, error) {
+	metricsRange := stationMetricsRanges[rangeName]
+	from := now.Add(-metricsRange.duration).Truncate(metricsRange.step)
+	// the step before the range is read as well so the rates of the first point can be computed
+	samples, err := s.getStationMetricsSamples(getStationMetricsSubject(tenantName, stationName.Intern()), from.Add(-metricsRange.step), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return downsampleStationMetrics(samples, from, metricsRange.step), nil
+}
+
+// countStationMetricsSamples returns the amount of samples stored under filterSubject since startTime
+func (s *Server) countStationMetricsSamples(filterSubject string, startTime time.Time) (int, error) {
+	uid, _ := uuid.NewV4()
+	durableName := "$memphis_count_metrics_consumer_" + uid.String()
+	cc := ConsumerConfig{
+		Durable:       durableName,
+		FilterSubject: filterSubject,
+		DeliverPolicy: DeliverByStartTime,
+		OptStartTime:  &startTime,
+		AckPolicy:     AckExplicit,
+	}
+	err := s.memphisAddConsumer(globalTenantName, stationMetricsStreamName, &cc)
+	if err != nil {
+		return 0, err
+	}
+	defer s.memphisRemoveConsumer(globalTenantName, stationMetricsStreamName, durableName)
+
+	var resp JSApiConsumerInfoResponse
+	err = jsApiRequest(s, globalTenantName, fmt.Sprintf(JSApiConsumerInfoT, stationMetricsStreamName, durableName), kindConsumerInfo, []byte(_EMPTY_), &resp)
+	if err == nil {
+		err = resp.ToError()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.ConsumerInfo.NumPending), nil
+}
+
+// getStationMetricsSamples returns the samples stored under filterSubject between startTime and endTime ordered by time,
+// a zero endTime reads up to the latest sample
+func (s *Server) getStationMetricsSamples(filterSubject string, startTime, endTime time.Time) ([]models.StationMetricsSample, error) {
+	// the samples from endTime on are counted first so samples added meanwhile only make the amount larger than needed,
+	// the extra samples are dropped below
+	amount := 0
+	if !endTime.IsZero() && endTime.Before(time.Now()) {
+		after, err := s.countStationMetricsSamples(filterSubject, endTime)
+		if IsNatsErr(err, JSStreamNotFoundErr) {
+			return []models.StationMetricsSample{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		since, err := s.countStationMetricsSamples(filterSubject, startTime)
+		if err != nil {
+			return nil, err
+		}
+		amount = since - after
+		if amount <= 0 {
+			return []models.StationMetricsSample{}, nil
+		}
+	}
+
+	cc := ConsumerConfig{
+		FilterSubject: filterSubject,
+		DeliverPolicy: DeliverByStartTime,
+		OptStartTime:  &startTime,
+		AckPolicy:     AckExplicit,
+	}
+	msgs, err := s.memphisFetchMsgs(globalTenantName, stationMetricsStreamName, cc, amount, stationMetricsFetchTimeout, false)
+	if IsNatsErr(err, JSStreamNotFoundErr) {
+		return []models.StationMetricsSample{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].Sequence < msgs[j].Sequence
+	})
+
+	samples := make([]models.StationMetricsSample, 0, len(msgs))
+	for _, msg := range msgs {
+		var sample models.StationMetricsSample
+		if err := json.Unmarshal(msg.Data, &sample); err != nil {
+			return nil, err
+		}
+		if !endTime.IsZero() && !sample.Time.Before(endTime) {
+			break
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// downsampleStationMetrics keeps the last sample of every step, rates are computed against the last sample of the previous step
+func downsampleStationMetrics(samples []models.StationMetricsSample, from time.Time, step time.Duration) []models.StationMetricsPoint {
+	points := []models.StationMetricsPoint{}
+	var prev *models.StationMetricsSample
+	for i := range samples {
+		sample := &samples[i]
+		bucket := sample.Time.Truncate(step)
+		if i+1 < len(samples) && samples[i+1].Time.Truncate(step).Equal(bucket) {
+			continue
+		}
+		if bucket.Before(from) {
+			prev = sample
+			continue
+		}
+
+		point := models.StationMetricsPoint{
+			Time:        bucket,
+			Messages:    sample.Messages,
+			Bytes:       sample.Bytes,
+			DlsMessages: sample.DlsMessages,
+			CgsLag:      sample.CgsLag,
+		}
+		if prev != nil {
+			// a station which has been recreated starts its sequences over
+			if elapsed := sample.Time.Sub(prev.Time).Seconds(); elapsed > 0 && sample.LastSeq >= prev.LastSeq {
+				point.IngestRate = float64(sample.LastSeq-prev.LastSeq) / elapsed
+			}
+			point.DlsGrowth = int64(sample.DlsMessages) - int64(prev.DlsMessages)
+		}
+		points = append(points, point)
+		prev = sample
+	}
+	return points
+}
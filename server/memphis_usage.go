@@ -0,0 +1,410 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"memphis-broker/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	usageGroupByStation = "station"
+	usageGroupByTag     = "tag"
+	usageGroupByUser    = "user"
+	usageFormatJson     = "json"
+	usageFormatCsv      = "csv"
+	usageDateLayout     = "2006-01-02"
+	usagePeriodLayout   = "2006-01"
+	untaggedUsageName   = "untagged"
+	maxUsageReportDays  = 366
+	// days still held by the metrics stream which are rolled up in case the broker was down when they ended
+	usageRollupDays = 6
+)
+
+var usageCsvHeader = []string{"name", "avg_bytes_stored", "messages_in", "messages_out", "dls_messages", "avg_dls_bytes"}
+
+type usageTotals struct {
+	usage        models.Usage
+	bytesDays    float64
+	dlsBytesDays float64
+}
+
+// add accumulates the usage of a period which lasted the given amount of days
+func (ut *usageTotals) add(usage models.Usage, days float64) {
+	ut.usage.MessagesIn += usage.MessagesIn
+	ut.usage.MessagesOut += usage.MessagesOut
+	ut.usage.DlsMessages += usage.DlsMessages
+	ut.bytesDays += float64(usage.AvgBytesStored) * days
+	ut.dlsBytesDays += float64(usage.AvgDlsBytes) * days
+}
+
+func (ut *usageTotals) average(days float64) models.Usage {
+	usage := ut.usage
+	if days > 0 {
+		usage.AvgBytesStored = uint64(ut.bytesDays / days)
+		usage.AvgDlsBytes = uint64(ut.dlsBytesDays / days)
+	}
+	return usage
+}
+
+func counterDelta(prev, cur uint64) uint64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	// the counter has started over since the station has been recreated
+	return cur
+}
+
+// getStationsUsage computes the usage of every station of the tenant between from and to out of the metrics samples,
+// samples which were not taken count as an empty station
+func (s *Server) getStationsUsage(tenantName string, from, to time.Time) (map[string]models.Usage, error) {
+	// the last sample before the period is the baseline of its counters
+	samples, err := s.getStationMetricsSamples(getStationMetricsSubject(tenantName, ">"), from.Add(-2*stationMetricsSampleInterval), to)
+	if err != nil {
+		return nil, err
+	}
+
+	type stationTotals struct {
+		prev     *models.StationMetricsSample
+		inPeriod bool
+		bytes    uint64
+		dlsBytes uint64
+		usage    models.Usage
+	}
+	totals := make(map[string]*stationTotals)
+	for i := range samples {
+		sample := &samples[i]
+		if !sample.Time.Before(to) {
+			continue
+		}
+		st, ok := totals[sample.StreamName]
+		if !ok {
+			st = &stationTotals{}
+			totals[sample.StreamName] = st
+		}
+		if sample.Time.Before(from) {
+			st.prev = sample
+			continue
+		}
+
+		st.inPeriod = true
+		st.bytes += sample.Bytes
+		st.dlsBytes += sample.DlsBytes
+		if st.prev != nil {
+			st.usage.MessagesIn += counterDelta(st.prev.LastSeq, sample.LastSeq)
+			if sample.DlsMessages > st.prev.DlsMessages {
+				st.usage.DlsMessages += sample.DlsMessages - st.prev.DlsMessages
+			}
+			for cgName, delivered := range sample.CgsDelivered {
+				st.usage.MessagesOut += counterDelta(st.prev.CgsDelivered[cgName], delivered)
+			}
+		}
+		st.prev = sample
+	}
+
+	expectedSamples := uint64(to.Sub(from) / stationMetricsSampleInterval)
+	if expectedSamples == 0 {
+		expectedSamples = 1
+	}
+	usages := make(map[string]models.Usage)
+	for streamName, st := range totals {
+		if !st.inPeriod {
+			continue
+		}
+		st.usage.AvgBytesStored = st.bytes / expectedSamples
+		st.usage.AvgDlsBytes = st.dlsBytes / expectedSamples
+		usages[StationNameFromStreamName(streamName).Ext()] = st.usage
+	}
+	return usages, nil
+}
+
+// getMissingUsageDays returns the days between from and to which have not been rolled up for the tenant yet
+func getMissingUsageDays(tenantName string, from, to time.Time) ([]time.Time, error) {
+	dates, err := stationsUsageCollection.Distinct(context.TODO(), "date", bson.M{"tenant_name": tenantName, "date": bson.M{"$gte": from, "$lt": to}})
+	if err != nil {
+		return nil, err
+	}
+	rolledUp := make(map[int64]bool, len(dates))
+	for _, date := range dates {
+		if dt, ok := date.(primitive.DateTime); ok {
+			rolledUp[dt.Time().UTC().Truncate(24*time.Hour).Unix()] = true
+		}
+	}
+
+	var days []time.Time
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		if !rolledUp[day.Unix()] {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+func (s *Server) rollupStationsDailyUsage(tenantName string, day time.Time) error {
+	usages, err := s.getStationsUsage(tenantName, day, day.Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	var dailyUsages []interface{}
+	for stationName, usage := range usages {
+		dailyUsages = append(dailyUsages, models.StationDailyUsage{
+			ID:          primitive.NewObjectID(),
+			TenantName:  tenantName,
+			StationName: stationName,
+			Date:        day,
+			Usage:       usage,
+		})
+	}
+	if len(dailyUsages) == 0 {
+		return nil
+	}
+	_, err = stationsUsageCollection.InsertMany(context.TODO(), dailyUsages)
+	return err
+}
+
+// getStationsOwnership returns the user who created every station of the tenant along with its tags,
+// a station which has been recreated belongs to its latest owner
+func getStationsOwnership(tenantName string) (map[string]models.StationUsage, error) {
+	var stations []models.Station
+	findOptions := options.Find().SetSort(bson.M{"creation_date": 1})
+	cursor, err := stationsCollection.Find(context.TODO(), bson.M{"tenant_name": tenantName}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &stations); err != nil {
+		return nil, err
+	}
+
+	stationIds := make([]primitive.ObjectID, 0, len(stations))
+	for _, station := range stations {
+		stationIds = append(stationIds, station.ID)
+	}
+	// tags are shared by all tenants, only the ones attached to stations of the tenant are read
+	var tags []models.Tag
+	cursor, err = tagsCollection.Find(context.TODO(), bson.M{"stations": bson.M{"$in": stationIds}})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &tags); err != nil {
+		return nil, err
+	}
+	stationTags := make(map[primitive.ObjectID][]string)
+	for _, tag := range tags {
+		for _, stationId := range tag.Stations {
+			stationTags[stationId] = append(stationTags[stationId], tag.Name)
+		}
+	}
+
+	ownership := make(map[string]models.StationUsage)
+	for _, station := range stations {
+		ownership[station.Name] = models.StationUsage{
+			StationName:   station.Name,
+			CreatedByUser: station.CreatedByUser,
+			Tags:          stationTags[station.ID],
+		}
+	}
+	return ownership, nil
+}
+
+// getStationsUsageReport sums the daily rollups of the period, the current day is computed out of the metrics samples
+func (s *Server) getStationsUsageReport(tenantName string, from, to time.Time) ([]models.StationUsage, error) {
+	totals := make(map[string]*usageTotals)
+	addUsage := func(stationName string, usage models.Usage, days float64) {
+		ut, ok := totals[stationName]
+		if !ok {
+			ut = &usageTotals{}
+			totals[stationName] = ut
+		}
+		ut.add(usage, days)
+	}
+
+	var dailyUsages []models.StationDailyUsage
+	cursor, err := stationsUsageCollection.Find(context.TODO(), bson.M{"tenant_name": tenantName, "date": bson.M{"$gte": from, "$lt": to}})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.TODO(), &dailyUsages); err != nil {
+		return nil, err
+	}
+	for _, dailyUsage := range dailyUsages {
+		addUsage(dailyUsage.StationName, dailyUsage.Usage, 1)
+	}
+
+	end := to
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	if to.After(today) && from.Before(now) {
+		end = now
+		liveFrom := today
+		if from.After(today) {
+			liveFrom = from
+		}
+		usages, err := s.getStationsUsage(tenantName, liveFrom, now)
+		if err != nil {
+			return nil, err
+		}
+		for stationName, usage := range usages {
+			addUsage(stationName, usage, now.Sub(liveFrom).Hours()/24)
+		}
+	}
+
+	ownership, err := getStationsOwnership(tenantName)
+	if err != nil {
+		return nil, err
+	}
+	days := end.Sub(from).Hours() / 24
+	stations := make([]models.StationUsage, 0, len(totals))
+	for stationName, ut := range totals {
+		stationUsage, ok := ownership[stationName]
+		if !ok {
+			stationUsage = models.StationUsage{StationName: stationName}
+		}
+		stationUsage.Usage = ut.average(days)
+		stations = append(stations, stationUsage)
+	}
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].StationName < stations[j].StationName
+	})
+	return stations, nil
+}
+
+func (s *Server) createMonthlyUsageSnapshot(tenantName string, month time.Time) error {
+	period := month.Format(usagePeriodLayout)
+	count, err := usageSnapshotsCollection.CountDocuments(context.TODO(), bson.M{"tenant_name": tenantName, "period": period})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	to := month.AddDate(0, 1, 0)
+	stations, err := s.getStationsUsageReport(tenantName, month, to)
+	if err != nil {
+		return err
+	}
+	snapshot := models.UsageSnapshot{
+		ID:           primitive.NewObjectID(),
+		TenantName:   tenantName,
+		Period:       period,
+		From:         month,
+		To:           to,
+		Stations:     stations,
+		CreationDate: time.Now(),
+	}
+	_, err = usageSnapshotsCollection.InsertOne(context.TODO(), snapshot)
+	return err
+}
+
+// ScheduleUsageReports rolls up the daily usage of every station and snapshots the usage of the previous month,
+// only the leader runs it in a cluster
+func (s *Server) ScheduleUsageReports() {
+	for range time.Tick(time.Hour) {
+		if s.JetStreamIsClustered() && !s.JetStreamIsLeader() {
+			continue
+		}
+
+		tenantNames, err := getAllTenantNames()
+		if err != nil {
+			s.Errorf("ScheduleUsageReports: " + err.Error())
+			continue
+		}
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		previousMonth := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+		for _, tenantName := range tenantNames {
+			days, err := getMissingUsageDays(tenantName, today.AddDate(0, 0, -usageRollupDays), today)
+			if err != nil {
+				s.Warnf("ScheduleUsageReports: Tenant " + tenantName + ": " + err.Error())
+			}
+			for _, day := range days {
+				if err := s.rollupStationsDailyUsage(tenantName, day); err != nil {
+					s.Warnf("ScheduleUsageReports: Tenant " + tenantName + ": " + err.Error())
+				}
+			}
+			if err := s.createMonthlyUsageSnapshot(tenantName, previousMonth); err != nil {
+				s.Warnf("ScheduleUsageReports: Tenant " + tenantName + ": " + err.Error())
+			}
+		}
+	}
+}
+
+func groupStationsUsage(stations []models.StationUsage, groupBy string) []models.UsageReportRow {
+	groups := make(map[string]*models.Usage)
+	for _, station := range stations {
+		var names []string
+		switch groupBy {
+		case usageGroupByTag:
+			names = station.Tags
+			if len(names) == 0 {
+				names = []string{untaggedUsageName}
+			}
+		case usageGroupByUser:
+			names = []string{station.CreatedByUser}
+		default:
+			names = []string{station.StationName}
+		}
+
+		for _, name := range names {
+			group, ok := groups[name]
+			if !ok {
+				group = &models.Usage{}
+				groups[name] = group
+			}
+			group.AvgBytesStored += station.AvgBytesStored
+			group.MessagesIn += station.MessagesIn
+			group.MessagesOut += station.MessagesOut
+			group.DlsMessages += station.DlsMessages
+			group.AvgDlsBytes += station.AvgDlsBytes
+		}
+	}
+
+	rows := make([]models.UsageReportRow, 0, len(groups))
+	for name, usage := range groups {
+		rows = append(rows, models.UsageReportRow{Name: name, Usage: *usage})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+func usageReportToCsv(rows []models.UsageReportRow) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(usageCsvHeader); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := []string{
+			row.Name,
+			strconv.FormatUint(row.AvgBytesStored, 10),
+			strconv.FormatUint(row.MessagesIn, 10),
+			strconv.FormatUint(row.MessagesOut, 10),
+			strconv.FormatUint(row.DlsMessages, 10),
+			strconv.FormatUint(row.AvgDlsBytes, 10),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
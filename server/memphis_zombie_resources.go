@@ -13,54 +13,162 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"memphis-broker/analytics"
 	"memphis-broker/models"
 	"strconv"
-	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func killRelevantConnections(zombieConnections []primitive.ObjectID) error {
-	_, err := connectionsCollection.UpdateMany(context.TODO(),
-		bson.M{"_id": bson.M{"$in": zombieConnections}},
-		bson.M{"$set": bson.M{"is_active": false}},
-	)
-	if err != nil {
-		serv.Errorf("killRelevantConnections: " + err.Error())
-		return err
-	}
+const (
+	livenessStateActive       = "active"
+	livenessStateSuspect      = "suspect"
+	livenessStateDisconnected = "disconnected"
+	livenessStateDeleted      = "deleted"
+
+	livenessReportInterval     = 10 * time.Second
+	livenessCheckInterval      = 10 * time.Second
+	livenessMinGraceSec        = 30
+	defaultSuspectGraceSec     = 150 // longer than the default client ping interval
+	defaultDisconnectGraceSec  = 300
+	zombieResourcesIntervalSec = 60
+)
 
-	return nil
+// livenessState resolves the lifecycle state of a producer/consumer out of its own
+// flags and the state of the connection it belongs to
+func livenessState(isActive, isDeleted bool, connectionState string) string {
+	switch {
+	case isDeleted:
+		return livenessStateDeleted
+	case !isActive:
+		return livenessStateDisconnected
+	case connectionState == livenessStateSuspect:
+		return livenessStateSuspect
+	default:
+		return livenessStateActive
+	}
 }
 
-func killProducersByConnections(connectionIds []primitive.ObjectID) error {
-	_, err := producersCollection.UpdateMany(context.TODO(),
-		bson.M{"connection_id": bson.M{"$in": connectionIds}},
-		bson.M{"$set": bson.M{"is_active": false}},
-	)
-	if err != nil {
-		serv.Errorf("killProducersByConnections: " + err.Error())
-		return err
+func latestTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
 	}
+	return a
+}
 
-	return nil
+// ReportConnectionsLiveness runs on every broker and writes the last time each of its
+// memphis connections has been seen (pings, pongs and activity) to the db
+func (s *Server) ReportConnectionsLiveness() {
+	for range time.Tick(livenessReportInterval) {
+		lastSeen := make(map[primitive.ObjectID]time.Time)
+
+		s.mu.Lock()
+		clients := make([]*client, 0, len(s.clients))
+		for _, c := range s.clients {
+			clients = append(clients, c)
+		}
+		s.mu.Unlock()
+		for _, c := range clients {
+			c.mu.Lock()
+			if !c.memphisInfo.connectionId.IsZero() {
+				seen := latestTime(c.memphisInfo.lastSeen, c.last)
+				if seen.After(c.memphisInfo.reportedSeen) {
+					c.memphisInfo.reportedSeen = seen
+					lastSeen[c.memphisInfo.connectionId] = latestTime(lastSeen[c.memphisInfo.connectionId], seen)
+				}
+			}
+			c.mu.Unlock()
+		}
+
+		s.kafka.mu.Lock()
+		kafkaConns := make([]*kafkaConn, 0, len(s.kafka.conns))
+		for kc := range s.kafka.conns {
+			kafkaConns = append(kafkaConns, kc)
+		}
+		s.kafka.mu.Unlock()
+		for _, kc := range kafkaConns {
+			kc.mu.Lock()
+			if !kc.connectionId.IsZero() && kc.lastSeen.After(kc.reportedSeen) {
+				kc.reportedSeen = kc.lastSeen
+				lastSeen[kc.connectionId] = kc.lastSeen
+			}
+			kc.mu.Unlock()
+		}
+
+		if len(lastSeen) == 0 {
+			continue
+		}
+		updates := make([]mongo.WriteModel, 0, len(lastSeen))
+		for connId, seen := range lastSeen {
+			updates = append(updates, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": connId, "is_active": true}).
+				SetUpdate(bson.M{"$set": bson.M{"last_seen": seen}}))
+		}
+		_, err := connectionsCollection.BulkWrite(context.TODO(), updates, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			s.Errorf("ReportConnectionsLiveness: " + err.Error())
+		}
+	}
 }
 
-func killConsumersByConnections(connectionIds []primitive.ObjectID) error {
-	_, err := consumersCollection.UpdateMany(context.TODO(),
-		bson.M{"connection_id": bson.M{"$in": connectionIds}},
-		bson.M{"$set": bson.M{"is_active": false}},
+func setConnectionState(connectionId primitive.ObjectID, state string) error {
+	_, err := connectionsCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": connectionId, "is_active": true},
+		bson.M{"$set": bson.M{"state": state}},
 	)
+	return err
+}
+
+// checkConnectionsLiveness moves connections that have not been seen for the suspect
+// grace period to suspect, and the ones not seen for the disconnect grace period to
+// disconnected together with their producers and consumers
+func (s *Server) checkConnectionsLiveness() {
+	connections, err := getActiveConnections()
 	if err != nil {
-		serv.Errorf("killConsumersByConnections: " + err.Error())
-		return err
+		s.Errorf("checkConnectionsLiveness: " + err.Error())
+		return
 	}
 
-	return nil
+	now := time.Now()
+	suspectBefore := now.Add(-time.Duration(LIVENESS_SUSPECT_GRACE_SEC) * time.Second)
+	disconnectBefore := now.Add(-time.Duration(LIVENESS_DISCONNECT_GRACE_SEC) * time.Second)
+	for _, conn := range connections {
+		var err error
+		if conn.LastSeen.IsZero() {
+			// connections created before liveness was tracked get a full grace period
+			_, err = connectionsCollection.UpdateOne(context.TODO(),
+				bson.M{"_id": conn.ID},
+				bson.M{"$set": bson.M{"last_seen": now, "state": livenessStateActive}},
+			)
+			if err != nil {
+				s.Errorf("checkConnectionsLiveness: " + err.Error())
+			}
+			continue
+		}
+
+		switch {
+		case conn.LastSeen.Before(disconnectBefore):
+			s.Warnf("checkConnectionsLiveness: Connection %v has not been seen since %v, marking it as disconnected", conn.ID.Hex(), conn.LastSeen)
+			mci := memphisClientInfo{username: conn.CreatedByUser, connectionId: conn.ID}
+			err = mci.updateDisconnection()
+		case conn.LastSeen.Before(suspectBefore):
+			if conn.State != livenessStateSuspect {
+				s.Debugf("Connection %v has not been seen since %v, marking it as suspect", conn.ID.Hex(), conn.LastSeen)
+				err = setConnectionState(conn.ID, livenessStateSuspect)
+			}
+		default:
+			if conn.State != livenessStateActive {
+				err = setConnectionState(conn.ID, livenessStateActive)
+			}
+		}
+		if err != nil {
+			s.Errorf("checkConnectionsLiveness: Connection " + conn.ID.Hex() + ": " + err.Error())
+		}
+	}
 }
 
 func (srv *Server) removeStaleStations() {
@@ -77,11 +185,11 @@ func (srv *Server) removeStaleStations() {
 	for _, s := range stations {
 		go func(srv *Server, s models.Station) {
 			stationName, _ := StationNameFromStr(s.Name)
-			_, err = srv.memphisStreamInfo(stationName.Intern())
+			_, err = srv.memphisStreamInfo(s.TenantName, stationName.Intern())
 			if IsNatsErr(err, JSStreamNotFoundErr) {
 				srv.Warnf("removeRedundantStations: Found zombie station to delete: " + s.Name)
 				_, err := stationsCollection.UpdateMany(nil,
-					bson.M{"name": s.Name, "is_deleted": false},
+					bson.M{"name": s.Name, "tenant_name": s.TenantName, "is_deleted": false},
 					bson.M{"$set": bson.M{"is_deleted": true}})
 				if err != nil {
 					srv.Errorf("removeRedundantStations: " + err.Error())
@@ -135,81 +243,6 @@ func updateActiveProducersAndConsumers() {
 	}
 }
 
-func aggregateClientConnections(s *Server) (map[string]string, error) {
-	connectionIds := make(map[string]string)
-	var lock sync.Mutex
-	replySubject := CONN_STATUS_SUBJ + "_reply_" + s.memphis.nuid.Next()
-	sub, err := s.subscribeOnGlobalAcc(replySubject, replySubject+"_sid", func(_ *client, subject, reply string, msg []byte) {
-		go func(msg []byte) {
-			var incomingConnIds map[string]string
-			err := json.Unmarshal(msg, &incomingConnIds)
-			if err != nil {
-				s.Errorf("aggregateClientConnections: " + err.Error())
-				return
-			}
-
-			for k := range incomingConnIds {
-				lock.Lock()
-				connectionIds[k] = ""
-				lock.Unlock()
-			}
-		}(copyBytes(msg))
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	// send message to all brokers to get their connections
-	s.sendInternalAccountMsgWithReply(s.GlobalAccount(), CONN_STATUS_SUBJ, replySubject, nil, _EMPTY_, true)
-	timeout := time.After(50 * time.Second)
-	<-timeout
-	s.unsubscribeOnGlobalAcc(sub)
-	return connectionIds, nil
-}
-
-func killFunc(s *Server) {
-	connections, err := getActiveConnections()
-	if err != nil {
-		serv.Errorf("killFunc: getActiveConnections: " + err.Error())
-		return
-	}
-
-	if len(connections) > 0 {
-		var zombieConnections []primitive.ObjectID
-		clientConnectionIds, err := aggregateClientConnections(s)
-		if err != nil {
-			serv.Errorf("killFunc: aggregateClientConnections: " + err.Error())
-			return
-		}
-		for _, conn := range connections {
-			if _, exist := clientConnectionIds[(conn.ID).Hex()]; exist { // existence check
-				continue
-			} else {
-				zombieConnections = append(zombieConnections, conn.ID)
-			}
-		}
-
-		if len(zombieConnections) > 0 {
-			serv.Warnf("Zombie connections found, killing")
-			err := killRelevantConnections(zombieConnections)
-			if err != nil {
-				serv.Errorf("killFunc: killRelevantConnections: " + err.Error())
-			} else {
-				err = killProducersByConnections(zombieConnections)
-				if err != nil {
-					serv.Errorf("killFunc: killProducersByConnections: " + err.Error())
-				}
-
-				err = killConsumersByConnections(zombieConnections)
-				if err != nil {
-					serv.Errorf("killFunc: killConsumersByConnections: " + err.Error())
-				}
-			}
-		}
-	}
-	s.removeStaleStations()
-}
-
 func (s *Server) KillZombieResources() {
 	if s.JetStreamIsClustered() {
 		count := 0
@@ -223,9 +256,16 @@ func (s *Server) KillZombieResources() {
 		}
 	}
 
-	for range time.Tick(time.Second * 60) {
-		s.Debugf("Killing Zombie resources iteration")
-		killFunc(s)
-		updateActiveProducersAndConsumers() // TODO to be deleted
+	livenessTicker := time.NewTicker(livenessCheckInterval)
+	zombieTicker := time.NewTicker(time.Second * zombieResourcesIntervalSec)
+	for {
+		select {
+		case <-livenessTicker.C:
+			s.checkConnectionsLiveness()
+		case <-zombieTicker.C:
+			s.Debugf("Killing Zombie resources iteration")
+			s.removeStaleStations()
+			updateActiveProducersAndConsumers() // TODO to be deleted
+		}
 	}
 }
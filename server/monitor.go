@@ -2611,6 +2611,8 @@ type StreamDetail struct {
 	Consumer []*ConsumerInfo     `json:"consumer_detail,omitempty"`
 	Mirror   *StreamSourceInfo   `json:"mirror,omitempty"`
 	Sources  []*StreamSourceInfo `json:"sources,omitempty"`
+	// Compression is set for streams with compressed message blocks.
+	Compression *CompressionStats `json:"compression,omitempty"`
 }
 
 type AccountDetail struct {
@@ -2686,12 +2688,13 @@ func (s *Server) accountDetail(jsa *jsAccount, optStreams, optConsumers, optCfg
 				cfg = &c
 			}
 			sdet := StreamDetail{
-				Name:    stream.name(),
-				State:   stream.state(),
-				Cluster: ci,
-				Config:  cfg,
-				Mirror:  stream.mirrorInfo(),
-				Sources: stream.sourcesInfo(),
+				Name:        stream.name(),
+				State:       stream.state(),
+				Cluster:     ci,
+				Config:      cfg,
+				Mirror:      stream.mirrorInfo(),
+				Sources:     stream.sourcesInfo(),
+				Compression: stream.compressionStats(),
 			}
 			if optConsumers {
 				for _, consumer := range stream.getPublicConsumers() {
@@ -273,6 +273,10 @@ type mqttSession struct {
 	tmaxack  int
 	clean    bool
 	domainTk string
+
+	rmax        uint16      // MQTT 5 client's receive maximum, 0 if none
+	expiry      uint32      // MQTT 5 session expiry interval, in seconds
+	expiryTimer *time.Timer // removes the session once expired, if no client resumed it
 }
 
 type mqttPersistedSession struct {
@@ -312,6 +316,12 @@ type mqtt struct {
 	asm  *mqttAccountSessionManager // quick reference to account session manager, immutable after processConnect()
 	sess *mqttSession               // quick reference to session, immutable after processConnect()
 	cid  string                     // client ID
+	v5   bool                       // MQTT 5 client, immutable after processConnect()
+
+	topicAliases map[uint16]*mqttTopicAlias // MQTT 5 topic aliases registered by the client
+
+	producers   map[string]struct{}        // stations this client is registered as a producer of
+	stationSubs map[string]*mqttStationSub // subscriptions on bridged topics, key is the sid
 }
 
 type mqttPending struct {
@@ -324,6 +334,9 @@ type mqttConnectProto struct {
 	rd    time.Duration
 	will  *mqttWill
 	flags byte
+	props *mqttProperties // MQTT 5 only
+	// MQTT 5 client connected with an empty client ID, the one assigned is returned in the CONNACK.
+	cidAssigned bool
 }
 
 type mqttIOReader interface {
@@ -349,11 +362,14 @@ type mqttWill struct {
 	message []byte
 	qos     byte
 	retain  bool
+	props   *mqttProperties // MQTT 5 only
 }
 
 type mqttFilter struct {
 	filter string
 	qos    byte
+	// MQTT 5 retain handling subscription option.
+	rh byte
 	// Used only for tracing and should not be used after parsing of (un)sub protocols.
 	ttopic []byte
 }
@@ -365,6 +381,7 @@ type mqttPublish struct {
 	sz      int
 	pi      uint16
 	flags   byte
+	props   *mqttProperties // MQTT 5 only
 }
 
 func (s *Server) startMQTT() {
@@ -580,6 +597,14 @@ func validateMQTTOptions(o *Options) error {
 		return fmt.Errorf("mqtt: consumer_replicas (%v) cannot be higher than stream_replicas (%v)",
 			mo.ConsumerReplicas, mo.StreamReplicas)
 	}
+	if mo.StationsPrefix != _EMPTY_ {
+		if strings.HasPrefix(mo.StationsPrefix, "$") || strings.ContainsAny(mo.StationsPrefix, "+#") {
+			return fmt.Errorf("mqtt: stations_prefix %q can not start with '$' or contain wildcards", mo.StationsPrefix)
+		}
+		if _, err := mqttTopicToNATSPubSubject([]byte(mo.StationsPrefix)); err != nil {
+			return fmt.Errorf("mqtt: invalid stations_prefix %q: %v", mo.StationsPrefix, err)
+		}
+	}
 	return nil
 }
 
@@ -669,6 +694,17 @@ func (c *client) mqttParse(buf []byte) error {
 			}
 			if err == nil {
 				err = s.mqttProcessPub(c, pp)
+				// A MQTT 5 client is told that the message could not be
+				// processed instead of being disconnected.
+				if err != nil && pp.pi > 0 && c.mqtt.v5 {
+					c.Errorf("Unable to process PUBLISH on %q: %v", pp.topic, err)
+					c.mqttEnqueuePubAckV5(pp.pi, mqttReasonUnspecifiedError)
+					if trace {
+						c.traceOutOp("PUBACK", []byte(fmt.Sprintf("pi=%v rc=%v", pp.pi, mqttReasonUnspecifiedError)))
+					}
+					err = nil
+					break
+				}
 			}
 			if err == nil && pp.pi > 0 {
 				c.mqttEnqueuePubAck(pp.pi)
@@ -710,14 +746,22 @@ func (c *client) mqttParse(buf []byte) error {
 			if trace {
 				c.traceInOp("UNSUBSCRIBE", errOrTrace(err, mqttUnsubscribeTrace(pi, filters)))
 			}
+			var rcs []byte
 			if err == nil {
+				if c.mqtt.v5 {
+					rcs = c.mqttUnsubReasonCodes(filters)
+				}
 				err = c.mqttProcessUnsubs(filters)
 				if err == nil && trace {
 					c.traceOutOp("UNSUBACK", []byte(fmt.Sprintf("pi=%v", pi)))
 				}
 			}
 			if err == nil {
-				c.mqttEnqueueUnsubAck(pi)
+				if c.mqtt.v5 {
+					c.mqttEnqueueUnsubAckV5(pi, rcs)
+				} else {
+					c.mqttEnqueueUnsubAck(pi)
+				}
 			}
 		case mqttPacketPing:
 			if trace {
@@ -761,16 +805,31 @@ func (c *client) mqttParse(buf []byte) error {
 				}
 			}
 		case mqttPacketDisconnect:
+			var rc byte
+			var props *mqttProperties
+			if c.mqtt.v5 {
+				if rc, props, err = mqttParseDisconnect(r, pl); err != nil {
+					break
+				}
+			}
 			if trace {
-				c.traceInOp("DISCONNECT", nil)
+				var dt []byte
+				if rc != mqttReasonSuccess {
+					dt = []byte(fmt.Sprintf("rc=%v", rc))
+				}
+				c.traceInOp("DISCONNECT", dt)
 			}
-			// Normal disconnect, we need to discard the will.
+			// Normal disconnect, we need to discard the will, unless a MQTT 5
+			// client asks for it to be sent.
 			// Spec [MQTT-3.1.2-8]
 			c.mu.Lock()
-			if c.mqtt.cp != nil {
+			if c.mqtt.cp != nil && rc != mqttReasonDisconnectWithWill {
 				c.mqtt.cp.will = nil
 			}
 			c.mu.Unlock()
+			if props != nil && props.hasSessExpiry {
+				c.mqttUpdateSessionExpiry(props.sessExpiry)
+			}
 			s.mqttHandleClosedClient(c)
 			c.closeConnection(ClientClosed)
 			return nil
@@ -783,6 +842,10 @@ func (c *client) mqttParse(buf []byte) error {
 	if err == nil && rd > 0 {
 		r.reader.SetReadDeadline(time.Now().Add(rd))
 	}
+	// Spec v5 [MQTT-4.13.1-1]: a MQTT 5 client is told why it is disconnected.
+	if err != nil && connected && c.mqtt.v5 {
+		c.mqttEnqueueDisconnect(mqttDisconnectReasonCode(err))
+	}
 	return err
 }
 
@@ -802,6 +865,8 @@ func (c *client) mqttTraceMsg(msg []byte) {
 // Runs from the client's readLoop.
 // No lock held on entry.
 func (s *Server) mqttHandleClosedClient(c *client) {
+	c.mqttStopStationSubs()
+
 	c.mu.Lock()
 	asm := c.mqtt.asm
 	sess := c.mqtt.sess
@@ -824,6 +889,15 @@ func (s *Server) mqttHandleClosedClient(c *client) {
 	sess.mu.Lock()
 	sess.c = nil
 	doClean := sess.clean
+	// A MQTT 5 session with an expiry interval is removed once it elapsed,
+	// unless a client resumes it in the meantime.
+	if !doClean && sess.expiry != mqttSessionExpiryNever && sess.expiry > 0 {
+		sess.expiryTimer = time.AfterFunc(time.Duration(sess.expiry)*time.Second, func() {
+			if err := asm.expireSession(sess); err != nil {
+				s.Errorf("Unable to remove expired MQTT session %q: %v", sess.id, err)
+			}
+		})
+	}
 	sess.mu.Unlock()
 	// If it was a clean session, then we remove from the account manager,
 	// and we will call clear() outside of any lock.
@@ -1945,7 +2019,7 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 
 	// Helper that sets the sub's mqtt fields and possibly serialize retained messages.
 	// Assumes account manager and session lock held.
-	setupSub := func(sub *subscription, qos byte) {
+	setupSub := func(sub *subscription, qos byte, sendRetained bool) {
 		subs := []*subscription{sub}
 		if len(sub.shadow) > 0 {
 			subs = append(subs, sub.shadow...)
@@ -1955,13 +2029,14 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 				sub.mqtt = &mqttSub{}
 			}
 			sub.mqtt.qos = qos
-			if fromSubProto {
+			if sendRetained {
 				as.serializeRetainedMsgsForSub(sess, c, sub, trace)
 			}
 		}
 	}
 
 	var err error
+	stationsPrefix := mqttStationsSubjectPrefix(c.srv.getOpts())
 	subs := make([]*subscription, 0, len(filters))
 	for _, f := range filters {
 		if f.qos > 1 {
@@ -1970,27 +2045,58 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 		subject := f.filter
 		sid := subject
 
+		// Bridged topics are consumed from the station instead of the MQTT messages stream.
+		if station, cgName, ok := mqttStationFromSubject(stationsPrefix, subject, true); ok {
+			sub, err := c.mqttProcessStationSub(sess, sid, station, cgName, f.qos)
+			if err != nil {
+				c.Errorf("Unable to subscribe to station %q: %v", station, err)
+				f.qos = mqttSubAckFailure
+				continue
+			}
+			subs = append(subs, sub)
+			continue
+		}
+
+		// Shared subscriptions are queue subscriptions on the filter, the
+		// group being the queue group. Spec v5 [MQTT-4.8.2-2]
+		var queue []byte
+		if group, shared, ok := mqttSharedSubject(subject); ok {
+			subject, queue = shared, []byte(group)
+		}
+
 		if strings.HasPrefix(subject, mqttSubPrefix) {
 			f.qos = mqttSubAckFailure
 			continue
 		}
 
+		// Retained messages are sent for new subscriptions unless the MQTT 5
+		// retain handling option says otherwise. Spec v5 [MQTT-3.8.4-4].
+		// Shared subscriptions never get them. Spec v5 [MQTT-4.8.2-6]
+		sendRetained := fromSubProto && queue == nil
+		switch f.rh {
+		case 1:
+			_, existing := sess.subs[sid]
+			sendRetained = sendRetained && !existing
+		case mqttRetainHandlingNone:
+			sendRetained = false
+		}
+
 		var jscons *ConsumerConfig
 		var jssub *subscription
 
 		// Note that if a subscription already exists on this subject,
 		// the existing sub is returned. Need to update the qos.
 		asAndSessLock()
-		sub, err := c.processSub([]byte(subject), nil, []byte(sid), mqttDeliverMsgCbQos0, false)
+		sub, err := c.processSub([]byte(subject), queue, []byte(sid), mqttDeliverMsgCbQos0, false)
 		if err == nil {
-			setupSub(sub, f.qos)
+			setupSub(sub, f.qos, sendRetained)
 		}
 		asAndSessUnlock()
 		if err == nil {
 			// This will create (if not already exist) a JS consumer for subscriptions
 			// of QoS >= 1. But if a JS consumer already exists and the subscription
 			// for same subject is now a QoS==0, then the JS consumer will be deleted.
-			jscons, jssub, err = sess.processJSConsumer(c, subject, sid, f.qos, fromSubProto)
+			jscons, jssub, err = sess.processJSConsumer(c, subject, sid, string(queue), f.qos, fromSubProto)
 		}
 		if err != nil {
 			// c.processSub already called c.Errorf(), so no need here.
@@ -2006,16 +2112,16 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 			// Say subject is "foo.>", remove the ".>" so that it becomes "foo"
 			fwcsubject := subject[:len(subject)-2]
 			// Change the sid to "foo fwc"
-			fwcsid := fwcsubject + mqttMultiLevelSidSuffix
+			fwcsid := sid[:len(sid)-2] + mqttMultiLevelSidSuffix
 			// See note above about existing subscription.
 			asAndSessLock()
-			fwcsub, err = c.processSub([]byte(fwcsubject), nil, []byte(fwcsid), mqttDeliverMsgCbQos0, false)
+			fwcsub, err = c.processSub([]byte(fwcsubject), queue, []byte(fwcsid), mqttDeliverMsgCbQos0, false)
 			if err == nil {
-				setupSub(fwcsub, f.qos)
+				setupSub(fwcsub, f.qos, sendRetained)
 			}
 			asAndSessUnlock()
 			if err == nil {
-				fwjscons, fwjssub, err = sess.processJSConsumer(c, fwcsubject, fwcsid, f.qos, fromSubProto)
+				fwjscons, fwjssub, err = sess.processJSConsumer(c, fwcsubject, fwcsid, string(queue), f.qos, fromSubProto)
 			}
 			if err != nil {
 				// c.processSub already called c.Errorf(), so no need here.
@@ -2051,6 +2157,10 @@ func (as *mqttAccountSessionManager) serializeRetainedMsgsForSub(sess *mqttSessi
 	}
 	var rmsa [64]*mqttRetainedMsg
 	rms := rmsa[:0]
+	var props []byte
+	if c.mqtt.v5 {
+		props = []byte{}
+	}
 
 	as.getRetainedPublishMsgs(string(sub.subject), &rms)
 	for _, rm := range rms {
@@ -2061,7 +2171,7 @@ func (as *mqttAccountSessionManager) serializeRetainedMsgsForSub(sess *mqttSessi
 		pi := sess.getPubAckIdentifier(mqttGetQoS(rm.Flags), sub)
 		// Need to use the subject for the retained message, not the `sub` subject.
 		// We can find the published retained message in rm.sub.subject.
-		flags := mqttSerializePublishMsg(prm, pi, false, true, []byte(rm.Topic), rm.Msg)
+		flags := mqttSerializePublishMsg(prm, pi, false, true, []byte(rm.Topic), props, rm.Msg)
 		if trace {
 			pp := mqttPublish{
 				topic: []byte(rm.Topic),
@@ -2291,7 +2401,10 @@ func (sess *mqttSession) clear() error {
 		durs = make([]string, 0, l)
 		for sid, cc := range sess.cons {
 			delete(sess.cons, sid)
-			durs = append(durs, cc.Durable)
+			// Durables of shared subscriptions are not owned by the session.
+			if cc.DeliverGroup == _EMPTY_ {
+				durs = append(durs, cc.Durable)
+			}
 		}
 	}
 	sess.subs, sess.pending, sess.cpending, sess.seq, sess.tmaxack = nil, nil, nil, 0, 0
@@ -2413,7 +2526,9 @@ func (sess *mqttSession) trackPending(pQos byte, reply string, sub *subscription
 	}
 	if pi == 0 {
 		// sess.maxp will always have a value > 0.
-		if len(sess.pending) >= int(sess.maxp) {
+		// Spec v5 [MQTT-3.3.4-9]: a MQTT 5 client may also limit the number of
+		// QoS 1 messages it has not acknowledged yet.
+		if len(sess.pending) >= int(sess.maxp) || (sess.rmax > 0 && len(sess.pending) >= int(sess.rmax)) {
 			// Indicate that we did not assign a packet identifier.
 			// The caller will not send the message to the subscription
 			// and JS will redeliver later, based on consumer's AckWait.
@@ -2433,6 +2548,21 @@ func (sess *mqttSession) trackPending(pQos byte, reply string, sub *subscription
 	return pi, dup
 }
 
+// Drops the pending acks of the given JS consumer.
+//
+// Lock held on entry
+func (sess *mqttSession) removePending(jsDur string) {
+	if seqPis, ok := sess.cpending[jsDur]; ok {
+		delete(sess.cpending, jsDur)
+		for _, pi := range seqPis {
+			delete(sess.pending, pi)
+		}
+		if len(sess.pending) == 0 {
+			sess.ppi = 0
+		}
+	}
+}
+
 // Sends a request to create a JS Durable Consumer based on the given consumer's config.
 // This will wait in place for the reply from the server handling the requests.
 //
@@ -2452,7 +2582,10 @@ func (sess *mqttSession) createConsumer(consConfig *ConsumerConfig) error {
 func (sess *mqttSession) deleteConsumer(cc *ConsumerConfig) {
 	sess.mu.Lock()
 	sess.tmaxack -= cc.MaxAckPending
-	sess.jsa.sendq.push(&mqttJSPubMsg{subj: sess.jsa.prefixDomain(fmt.Sprintf(JSApiConsumerDeleteT, mqttStreamName, cc.Durable))})
+	// Other members of a shared subscription may still use its durable.
+	if cc.DeliverGroup == _EMPTY_ {
+		sess.jsa.sendq.push(&mqttJSPubMsg{subj: sess.jsa.prefixDomain(fmt.Sprintf(JSApiConsumerDeleteT, mqttStreamName, cc.Durable))})
+	}
 	sess.mu.Unlock()
 }
 
@@ -2485,9 +2618,10 @@ func (c *client) mqttParseConnect(r *mqttReader, pl int) (byte, *mqttConnectProt
 		return 0, nil, err
 	}
 	// Spec [MQTT-3.1.2-2]
-	if level != mqttProtoLevel {
+	if level != mqttProtoLevel && level != mqttProtoLevel5 {
 		return mqttConnAckRCUnacceptableProtocolVersion, nil, fmt.Errorf("unacceptable protocol version of %v", level)
 	}
+	c.mqtt.v5 = level == mqttProtoLevel5
 
 	cp := &mqttConnectProto{}
 	// Connect flags
@@ -2542,6 +2676,16 @@ func (c *client) mqttParseConnect(r *mqttReader, pl int) (byte, *mqttConnectProt
 		cp.rd = time.Duration(float64(ka)*1.5) * time.Second
 	}
 
+	if c.mqtt.v5 {
+		if cp.props, err = r.readProperties("connect"); err != nil {
+			return 0, nil, err
+		}
+		// Enhanced authentication is not supported.
+		if cp.props.authMethod != _EMPTY_ {
+			return mqttReasonBadAuthMethod, nil, fmt.Errorf("authentication method %q not supported", cp.props.authMethod)
+		}
+	}
+
 	// Payload starts here and order is mandated by:
 	// Spec [MQTT-3.1.3-1]: client ID, will topic, will message, username, password
 
@@ -2552,7 +2696,11 @@ func (c *client) mqttParseConnect(r *mqttReader, pl int) (byte, *mqttConnectProt
 	}
 	// Spec [MQTT-3.1.3-7]
 	if c.mqtt.cid == _EMPTY_ {
-		if cp.flags&mqttConnFlagCleanSession == 0 {
+		// Spec v5 [MQTT-3.1.3-6]: MQTT 5 clients are assigned a client ID regardless
+		// of the Clean Start flag and are told about it in the CONNACK.
+		if c.mqtt.v5 {
+			cp.cidAssigned = true
+		} else if cp.flags&mqttConnFlagCleanSession == 0 {
 			return mqttConnAckRCIdentifierRejected, nil, errMQTTCIDEmptyNeedsCleanFlag
 		}
 		// Spec [MQTT-3.1.3-6]
@@ -2568,6 +2716,11 @@ func (c *client) mqttParseConnect(r *mqttReader, pl int) (byte, *mqttConnectProt
 			qos:    wqos,
 			retain: wretain,
 		}
+		if c.mqtt.v5 {
+			if cp.will.props, err = r.readProperties("Will"); err != nil {
+				return 0, nil, err
+			}
+		}
 		var topic []byte
 		// Need to make a copy since we need to hold to this topic after the
 		// parsing of this protocol.
@@ -2674,6 +2827,15 @@ func (s *Server) mqttProcessConnect(c *client, cp *mqttConnectProto, trace bool)
 		c.authViolation()
 		return ErrAuthentication
 	}
+	// With the stations bridge, the client is bound to its Memphis user and tenant
+	// before the account's session manager is looked up.
+	if s.getOpts().MQTT.StationsPrefix != _EMPTY_ {
+		if err := s.mqttRegisterMemphisConnection(c); err != nil {
+			c.Warnf("Unable to register MQTT client %q with Memphis: %v", cid, err)
+			sendConnAck(mqttConnAckRCNotAuthorized, false)
+			return err
+		}
+	}
 	// Now that we are are authenticated, we have the client bound to the account.
 	// Get the account's level MQTT sessions manager. If it does not exists yet,
 	// this will create it along with the streams where sessions and messages
@@ -2738,6 +2900,15 @@ CHECK:
 
 	// Is the client requesting a clean session or not.
 	cleanSess := cp.flags&mqttConnFlagCleanSession != 0
+	// With MQTT 5, the flag only asks for a clean start. The session is then
+	// discarded with the connection unless the client gave it an expiry.
+	cleanOnClose := cleanSess
+	var sessExpiry uint32
+	var rmax uint16
+	if c.mqtt.v5 {
+		sessExpiry, rmax = cp.props.sessExpiry, cp.props.receiveMax
+		cleanOnClose = sessExpiry == 0
+	}
 	// Session present? Assume false, will be set to true only when applicable.
 	sessp := false
 	// Do we have an existing session for this client ID
@@ -2777,13 +2948,21 @@ CHECK:
 		es.mu.Lock()
 		ec := es.c
 		es.c = c
-		es.clean = cleanSess
+		es.clean, es.expiry, es.rmax = cleanOnClose, sessExpiry, rmax
+		if es.expiryTimer != nil {
+			es.expiryTimer.Stop()
+			es.expiryTimer = nil
+		}
 		es.mu.Unlock()
 		if ec != nil {
 			// Remove "will" of existing client before closing
 			ec.mu.Lock()
 			ec.mqtt.cp.will = nil
+			ecV5 := ec.mqtt.v5
 			ec.mu.Unlock()
+			if ecV5 {
+				ec.mqttEnqueueDisconnect(mqttReasonSessionTakenOver)
+			}
 			// Add to the map of the flappers
 			asm.mu.Lock()
 			asm.addSessToFlappers(cid)
@@ -2796,7 +2975,7 @@ CHECK:
 		// Spec [MQTT-3.2.2-3]: if the Server does not have stored Session state,
 		// it MUST set Session Present to 0 in the CONNACK packet.
 		es.mu.Lock()
-		es.c, es.clean = c, cleanSess
+		es.c, es.clean, es.expiry, es.rmax = c, cleanOnClose, sessExpiry, rmax
 		es.mu.Unlock()
 		// Now add this new session into the account sessions
 		asm.addSession(es, true)
@@ -2832,6 +3011,10 @@ CHECK:
 }
 
 func (c *client) mqttEnqueueConnAck(rc byte, sessionPresent bool) {
+	if c.mqtt.v5 {
+		c.mqttEnqueueConnAckV5(rc, sessionPresent)
+		return
+	}
 	proto := [4]byte{mqttPacketConnectAck, 2, 0, rc}
 	c.mu.Lock()
 	// Spec [MQTT-3.2.2-4]. If return code is different from 0, then
@@ -2862,6 +3045,7 @@ func (s *Server) mqttHandleWill(c *client) {
 	pp.msg = will.message
 	pp.sz = len(will.message)
 	pp.pi = 0
+	pp.props = will.props
 	pp.flags = will.qos << 1
 	if will.retain {
 		pp.flags |= mqttPubFlagRetain
@@ -2879,7 +3063,9 @@ func (s *Server) mqttHandleWill(c *client) {
 
 func (c *client) mqttParsePub(r *mqttReader, pl int, pp *mqttPublish) error {
 	qos := mqttGetQoS(pp.flags)
-	if qos > 1 {
+	if qos == 2 {
+		return errMQTTQoS2NotSupported
+	} else if qos > 1 {
 		return fmt.Errorf("publish QoS=%v not supported", qos)
 	}
 	// Keep track of where we are when starting to read the variable header
@@ -2890,17 +3076,22 @@ func (c *client) mqttParsePub(r *mqttReader, pl int, pp *mqttPublish) error {
 	if err != nil {
 		return err
 	}
-	if len(pp.topic) == 0 {
+	pp.props = nil
+	// With MQTT 5, an empty topic is resolved through the topic alias.
+	if len(pp.topic) == 0 && !c.mqtt.v5 {
 		return errMQTTTopicIsEmpty
 	}
-	// Convert the topic to a NATS subject. This call will also check that
-	// there is no MQTT wildcards (Spec [MQTT-3.3.2-2] and [MQTT-4.7.1-1])
-	// Note that this may not result in a copy if there is no conversion.
-	// It is good because after the message is processed we won't have a
-	// reference to the buffer and we save a copy.
-	pp.subject, err = mqttTopicToNATSPubSubject(pp.topic)
-	if err != nil {
-		return err
+	pp.subject = nil
+	if len(pp.topic) > 0 {
+		// Convert the topic to a NATS subject. This call will also check that
+		// there is no MQTT wildcards (Spec [MQTT-3.3.2-2] and [MQTT-4.7.1-1])
+		// Note that this may not result in a copy if there is no conversion.
+		// It is good because after the message is processed we won't have a
+		// reference to the buffer and we save a copy.
+		pp.subject, err = mqttTopicToNATSPubSubject(pp.topic)
+		if err != nil {
+			return err
+		}
 	}
 
 	if qos > 0 {
@@ -2915,6 +3106,15 @@ func (c *client) mqttParsePub(r *mqttReader, pl int, pp *mqttPublish) error {
 		pp.pi = 0
 	}
 
+	if c.mqtt.v5 {
+		if pp.props, err = r.readProperties("publish"); err != nil {
+			return err
+		}
+		if err = c.mqttResolveTopicAlias(pp); err != nil {
+			return err
+		}
+	}
+
 	// The message payload will be the total packet length minus
 	// what we have consumed for the variable header
 	pp.sz = pl - (r.pos - start)
@@ -2945,6 +3145,9 @@ func mqttPubTrace(pp *mqttPublish) string {
 // Runs from the client's readLoop.
 // No lock held on entry.
 func (s *Server) mqttProcessPub(c *client, pp *mqttPublish) error {
+	if station, _, ok := mqttStationFromSubject(mqttStationsSubjectPrefix(s.getOpts()), string(pp.subject), false); ok {
+		return s.mqttProcessStationPub(c, pp, station)
+	}
 	c.pa.subject, c.pa.hdr, c.pa.size, c.pa.reply = pp.subject, -1, pp.sz, nil
 
 	bb := bytes.Buffer{}
@@ -2953,6 +3156,7 @@ func (s *Server) mqttProcessPub(c *client, pp *mqttPublish) error {
 	bb.WriteByte(':')
 	bb.WriteByte('0' + mqttGetQoS(pp.flags))
 	bb.WriteString(_CRLF_)
+	mqttWritePropertiesHeaders(&bb, pp.props)
 	bb.WriteString(_CRLF_)
 	c.pa.hdr = bb.Len()
 	c.pa.hdb = []byte(strconv.FormatInt(int64(c.pa.hdr), 10))
@@ -3168,6 +3372,11 @@ func mqttParsePubAck(r *mqttReader, pl int) (uint16, error) {
 	if pi == 0 {
 		return 0, errMQTTPacketIdentifierIsZero
 	}
+	// A MQTT 5 PUBACK may carry a reason code and properties, which
+	// do not change how the message is acknowledged.
+	if pl > 2 {
+		r.pos += pl - 2
+	}
 	return pi, nil
 }
 
@@ -3244,6 +3453,11 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 		return 0, nil, fmt.Errorf("reading packet identifier: %v", err)
 	}
 	end := r.pos + (pl - 2)
+	if c.mqtt.v5 {
+		if _, err = r.readProperties(action + "subscribe"); err != nil {
+			return 0, nil, err
+		}
+	}
 	var filters []*mqttFilter
 	for r.pos < end {
 		// Don't make a copy now because, this will happen during conversion
@@ -3259,7 +3473,7 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 		if !utf8.Valid(topic) {
 			return 0, nil, fmt.Errorf("invalid utf8 for topic filter %q", topic)
 		}
-		var qos byte
+		var qos, rh byte
 		// We are going to report if we had an error during the conversion,
 		// but we don't fail the parsing. When processing the sub, we will
 		// have an error then, and the processing of subs code will send
@@ -3274,12 +3488,23 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 			if err != nil {
 				return 0, nil, err
 			}
+			// With MQTT 5, the QoS is part of the subscription options.
+			if c.mqtt.v5 {
+				// Spec v5 [MQTT-3.8.3-5]
+				if qos&mqttSubOptReserved != 0 {
+					return 0, nil, errMQTTSubOptionsReserved
+				}
+				qos, rh = qos&mqttSubOptQoS, (qos&mqttSubOptRetainHandling)>>4
+				if rh == 3 {
+					return 0, nil, fmt.Errorf("retain handling value must be 0, 1 or 2, got %v", rh)
+				}
+			}
 			// Spec [MQTT-3-8.3-4].
 			if qos > 2 {
 				return 0, nil, fmt.Errorf("subscribe QoS value must be 0, 1 or 2, got %v", qos)
 			}
 		}
-		f := &mqttFilter{ttopic: topic, filter: string(filter), qos: qos}
+		f := &mqttFilter{ttopic: topic, filter: string(filter), qos: qos, rh: rh}
 		filters = append(filters, f)
 	}
 	// Spec [MQTT-3.8.3-3], [MQTT-3.10.3-2]
@@ -3379,8 +3604,13 @@ func mqttDeliverMsgCbQos0(sub *subscription, pc *client, _ *Account, subject, _
 		topic = natsSubjectToMQTTTopic(subject)
 	}
 
+	// Spec v5 [MQTT-3.3.2-5]: expired messages are not delivered.
+	if mqttMsgExpired(hdr) {
+		return
+	}
+
 	// Message never has a packet identifier nor is marked as duplicate.
-	pc.mqttDeliver(cc, sub, 0, false, retained, topic, msg)
+	pc.mqttDeliver(cc, sub, 0, false, retained, topic, hdr, msg)
 }
 
 // This is the callback attached to a JS durable subscription for a MQTT Qos1 sub.
@@ -3391,6 +3621,12 @@ func mqttDeliverMsgCbQos0(sub *subscription, pc *client, _ *Account, subject, _
 func mqttDeliverMsgCbQos1(sub *subscription, pc *client, _ *Account, subject, reply string, rmsg []byte) {
 	var retained bool
 
+	// Queue subscriptions, which are used for shared subscriptions, get the messages
+	// on the JS durable's delivery subject, the stream subject is the one delivered.
+	if sub.queue != nil && pc.kind == JETSTREAM && len(pc.pa.deliver) > 0 {
+		subject = string(pc.pa.deliver)
+	}
+
 	// Message on foo.bar is stored under $MQTT.msgs.foo.bar, so the subject has to be
 	// at least as long as the stream subject prefix "$MQTT.msgs.", and after removing
 	// the prefix, has to be at least 1 character long.
@@ -3425,6 +3661,13 @@ func mqttDeliverMsgCbQos1(sub *subscription, pc *client, _ *Account, subject, re
 		sess.mu.Unlock()
 		return
 	}
+	// Spec v5 [MQTT-3.3.2-5]: expired messages are not delivered, so they
+	// are acknowledged to prevent redeliveries.
+	if mqttMsgExpired(hdr) {
+		sess.jsa.sendq.push(&mqttJSPubMsg{subj: reply, hdr: -1})
+		sess.mu.Unlock()
+		return
+	}
 	// This is a QoS1 message for a QoS1 subscription, so get the pi and keep
 	// track of ack subject.
 	pQoS := byte(1)
@@ -3438,16 +3681,21 @@ func mqttDeliverMsgCbQos1(sub *subscription, pc *client, _ *Account, subject, re
 	}
 	topic := natsSubjectToMQTTTopic(string(subject[len(mqttStreamSubjectPrefix):]))
 
-	pc.mqttDeliver(cc, sub, pi, dup, retained, topic, msg)
+	pc.mqttDeliver(cc, sub, pi, dup, retained, topic, hdr, msg)
 }
 
 // Common function to mqtt delivery callbacks to serialize and send the message
-// to the `cc` client.
-func (c *client) mqttDeliver(cc *client, sub *subscription, pi uint16, dup, retained bool, topic, msg []byte) {
+// to the `cc` client. For a MQTT 5 client, the message headers are sent as
+// PUBLISH properties.
+func (c *client) mqttDeliver(cc *client, sub *subscription, pi uint16, dup, retained bool, topic, hdr, msg []byte) {
 	sw := mqttWriter{}
 	w := &sw
 
-	flags := mqttSerializePublishMsg(w, pi, dup, retained, topic, msg)
+	var props []byte
+	if cc.mqtt.v5 {
+		props = mqttPublishProperties(hdr)
+	}
+	flags := mqttSerializePublishMsg(w, pi, dup, retained, topic, props, msg)
 
 	cc.mu.Lock()
 	if sub.mqtt.prm != nil {
@@ -3469,10 +3717,15 @@ func (c *client) mqttDeliver(cc *client, sub *subscription, pi uint16, dup, reta
 }
 
 // Serializes to the given writer the message for the given subject.
-func mqttSerializePublishMsg(w *mqttWriter, pi uint16, dup, retained bool, topic, msg []byte) byte {
+// The properties are nil for a MQTT 3.1.1 client, and never nil, even
+// if empty, for a MQTT 5 client.
+func mqttSerializePublishMsg(w *mqttWriter, pi uint16, dup, retained bool, topic, props, msg []byte) byte {
 
 	// Compute len (will have to add packet id if message is sent as QoS>=1)
 	pkLen := 2 + len(topic) + len(msg)
+	if props != nil {
+		pkLen += mqttVarIntLen(len(props)) + len(props)
+	}
 
 	var flags byte
 
@@ -3495,6 +3748,9 @@ func mqttSerializePublishMsg(w *mqttWriter, pi uint16, dup, retained bool, topic
 	if pi > 0 {
 		w.WriteUint16(pi)
 	}
+	if props != nil {
+		w.WriteProperties(props)
+	}
 	w.Write(msg)
 
 	return flags
@@ -3553,7 +3809,7 @@ func (sess *mqttSession) cleanupFailedSub(c *client, sub *subscription, cc *Cons
 // its NATS subscription on a delivery subject.
 //
 // Lock not held on entry, but session is in the locked map.
-func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
+func (sess *mqttSession) processJSConsumer(c *client, subject, sid, group string,
 	qos byte, fromSubProto bool) (*ConsumerConfig, *subscription, error) {
 
 	// Check if we are already a JS consumer for this SID.
@@ -3589,6 +3845,14 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 	var inbox string
 	if exists {
 		inbox = cc.DeliverSubject
+		// The durable of a shared subscription may have been removed by the
+		// inactive threshold while this session was offline.
+		if cc.DeliverGroup != _EMPTY_ {
+			if err := sess.createConsumer(cc); err != nil {
+				c.Errorf("Unable to add JetStream consumer for shared subscription on %q: err=%v", subject, err)
+				return nil, nil, err
+			}
+		}
 	} else {
 		inbox = mqttSubPrefix + nuid.Next()
 		opts := c.srv.getOpts()
@@ -3624,6 +3888,16 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 		if opts.MQTT.ConsumerInactiveThreshold > 0 {
 			cc.InactiveThreshold = opts.MQTT.ConsumerInactiveThreshold
 		}
+		// All members of a shared subscription use the same durable, delivering
+		// to its queue group. It is removed once the group has no member left.
+		if group != _EMPTY_ {
+			durName = "share_" + string(getHash(group+" "+subject))
+			inbox = mqttSubPrefix + durName
+			cc.Durable, cc.DeliverSubject, cc.DeliverGroup = durName, inbox, group
+			if cc.InactiveThreshold == 0 {
+				cc.InactiveThreshold = mqttSharedConsumerInactiveThreshold
+			}
+		}
 		if err := sess.createConsumer(cc); err != nil {
 			c.Errorf("Unable to add JetStream consumer for subscription on %q: err=%v", subject, err)
 			return nil, nil, err
@@ -3632,8 +3906,12 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 	}
 	// This is an internal subscription on subject like "$MQTT.sub.<nuid>" that is setup
 	// for the JS durable's deliver subject.
+	var queue []byte
+	if cc.DeliverGroup != _EMPTY_ {
+		queue = []byte(cc.DeliverGroup)
+	}
 	sess.mu.Lock()
-	sub, err := c.processSub([]byte(inbox), nil, []byte(inbox), mqttDeliverMsgCbQos1, false)
+	sub, err := c.processSub([]byte(inbox), queue, []byte(inbox), mqttDeliverMsgCbQos1, false)
 	if err != nil {
 		sess.mu.Unlock()
 		sess.deleteConsumer(cc)
@@ -3664,6 +3942,10 @@ func (c *client) mqttSendRetainedMsgsToNewSubs(subs []*subscription) {
 }
 
 func (c *client) mqttEnqueueSubAck(pi uint16, filters []*mqttFilter) {
+	if c.mqtt.v5 {
+		c.mqttEnqueueSubAckV5(pi, filters)
+		return
+	}
 	w := &mqttWriter{}
 	w.WriteByte(mqttPacketSubAck)
 	// packet length is 2 (for packet identifier) and 1 byte per filter.
@@ -3714,28 +3996,27 @@ func (c *client) mqttProcessUnsubs(filters []*mqttFilter) error {
 			sess.deleteConsumer(cc)
 			// Need lock here since these are accessed by callbacks
 			sess.mu.Lock()
-			if seqPis, ok := sess.cpending[cc.Durable]; ok {
-				delete(sess.cpending, cc.Durable)
-				for _, pi := range seqPis {
-					delete(sess.pending, pi)
-				}
-				if len(sess.pending) == 0 {
-					sess.ppi = 0
-				}
-			}
+			sess.removePending(cc.Durable)
 			sess.mu.Unlock()
 		}
 	}
 	for _, f := range filters {
 		sid := f.filter
+		if c.mqttRemoveStationSub(sess, sid) {
+			continue
+		}
 		// Remove JS Consumer if one exists for this sid
 		removeJSCons(sid)
 		if err := c.processUnsub([]byte(sid)); err != nil {
 			c.Errorf("error unsubscribing from %q: %v", sid, err)
 		}
-		if mqttNeedSubForLevelUp(sid) {
-			subject := sid[:len(sid)-2]
-			sid = subject + mqttMultiLevelSidSuffix
+		subject := sid
+		if _, shared, ok := mqttSharedSubject(sid); ok {
+			subject = shared
+		}
+		if mqttNeedSubForLevelUp(subject) {
+			subject = subject[:len(subject)-2]
+			sid = sid[:len(sid)-2] + mqttMultiLevelSidSuffix
 			removeJSCons(sid)
 			if err := c.processUnsub([]byte(sid)); err != nil {
 				c.Errorf("error unsubscribing from %q: %v", subject, err)
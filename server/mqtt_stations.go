@@ -0,0 +1,523 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"memphis-broker/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mqttSharedSubPrefix       = "$share."
+	mqttStationPullExpiration = time.Second
+)
+
+// mqttStationSub is a subscription on a bridged topic, it pulls the messages of the station
+// through the durable(s) of its consumers group and hands them over to the MQTT client
+type mqttStationSub struct {
+	mu           sync.Mutex
+	sub          *subscription
+	partsSub     *subscription
+	topic        []byte
+	stream       string
+	cgName       string
+	consumerName string
+	stationId    primitive.ObjectID
+	partitioned  bool
+	partitions   []int
+	quitCh       chan struct{}
+}
+
+// mqttStationsSubjectPrefix returns the NATS subject prefix bridged topics are converted to,
+// or an empty string when the bridge is disabled
+func mqttStationsSubjectPrefix(opts *Options) string {
+	if opts.MQTT.StationsPrefix == _EMPTY_ {
+		return _EMPTY_
+	}
+	prefix, err := mqttTopicToNATSPubSubject([]byte(opts.MQTT.StationsPrefix))
+	if err != nil {
+		return _EMPTY_
+	}
+	return string(prefix) + string(btsep)
+}
+
+// mqttStationFromSubject returns the station a NATS subject converted from an MQTT topic is bridged to,
+// along with the consumers group requested through a "$share/<group>/" shared subscription
+func mqttStationFromSubject(prefix, subject string, sharedOk bool) (string, string, bool) {
+	if prefix == _EMPTY_ {
+		return _EMPTY_, _EMPTY_, false
+	}
+	var cgName string
+	if sharedOk && strings.HasPrefix(subject, mqttSharedSubPrefix) {
+		rest := subject[len(mqttSharedSubPrefix):]
+		i := strings.IndexByte(rest, btsep)
+		if i <= 0 {
+			return _EMPTY_, _EMPTY_, false
+		}
+		cgName, subject = rest[:i], rest[i+1:]
+	}
+	if !strings.HasPrefix(subject, prefix) {
+		return _EMPTY_, _EMPTY_, false
+	}
+	station := subject[len(prefix):]
+	if station == _EMPTY_ || station == pwcs || station == fwcs || strings.ContainsAny(station, "./") {
+		return _EMPTY_, _EMPTY_, false
+	}
+	return station, cgName, true
+}
+
+// mqttMemphisClientName derives the producer and consumer name of an MQTT client from its client ID,
+// IDs which are not valid Memphis names are replaced by a stable hash
+func mqttMemphisClientName(clientID string) string {
+	name := strings.ToLower(clientID)
+	if err := validateName(name, "MQTT client"); err == nil {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(clientID))
+	return fmt.Sprintf("mqtt-%08x", h.Sum32())
+}
+
+// mqttRegisterMemphisConnection binds an MQTT client of the stations bridge to its Memphis user and
+// tenant, the password is the same "<username>::<token>" NATS SDKs connect with
+func (s *Server) mqttRegisterMemphisConnection(c *client) error {
+	username := c.opts.Username
+	if tokenSplit := strings.Split(c.opts.Token, connectItemSep); len(tokenSplit) == 2 {
+		username = tokenSplit[0]
+	}
+	username = strings.ToLower(username)
+	exist, user, err := IsUserExist(username)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return errors.New("User " + username + " does not exist")
+	}
+	if user.UserType != "root" && user.UserType != "application" {
+		return errors.New("Please use a user of type Root/Application and not Management")
+	}
+
+	if tenantName := getUserTenantName(user); tenantName != globalTenantName {
+		acc, err := s.getTenantAccount(tenantName)
+		if err != nil {
+			return err
+		}
+		if err = c.registerWithAccount(acc); err != nil {
+			return err
+		}
+	}
+
+	connectionId := primitive.NewObjectID()
+	metadata := models.ConnectionMetadata{SdkLang: "mqtt", SdkVersion: "3.1.1", ClientName: c.mqtt.cid}
+	if c.mqtt.v5 {
+		metadata.SdkVersion = "5"
+	}
+	if err = connectionsHandler.CreateConnection(username, c.RemoteAddress().String(), connectionId, metadata); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.memphisInfo = memphisClientInfo{username: username, connectionId: connectionId}
+	c.mu.Unlock()
+	return nil
+}
+
+// Produces an MQTT PUBLISH into a station, the client is registered as a producer of the station
+// the first time it publishes to it.
+//
+// Runs from the client's readLoop.
+// No lock held on entry.
+func (s *Server) mqttProcessStationPub(c *client, pp *mqttPublish, stationName string) error {
+	sn, err := StationNameFromStr(stationName)
+	if err != nil {
+		return err
+	}
+	producerName := mqttMemphisClientName(c.mqtt.cid)
+	if _, ok := c.mqtt.producers[sn.Intern()]; !ok {
+		if _, _, err = s.createProducerDirectCommon(c, producerName, "application", c.memphisInfo.connectionId.Hex(), sn); err != nil {
+			return err
+		}
+		if c.mqtt.producers == nil {
+			c.mqtt.producers = make(map[string]struct{})
+		}
+		c.mqtt.producers[sn.Intern()] = struct{}{}
+	}
+
+	// Stations keep their own retention, retained MQTT messages do not apply to them
+	pp.flags &^= mqttPubFlagRetain
+	qos := mqttGetQoS(pp.flags)
+	subject := sn.Intern() + ".final"
+
+	bb := bytes.Buffer{}
+	bb.WriteString(hdrLine)
+	bb.Write(mqttNatsHeaderB)
+	bb.WriteByte(':')
+	bb.WriteByte('0' + qos)
+	bb.WriteString(_CRLF_)
+	bb.WriteString("$memphis_connectionId:" + c.memphisInfo.connectionId.Hex() + _CRLF_)
+	bb.WriteString("$memphis_producedBy:" + producerName + _CRLF_)
+	mqttWritePropertiesHeaders(&bb, pp.props)
+	bb.WriteString(_CRLF_)
+	hdr := bb.Len()
+	bb.Write(pp.msg)
+	msgToSend := bb.Bytes()
+
+	// QoS1 messages are acknowledged to the client only once they are stored in the station
+	if qos > 0 {
+		if c.perms != nil && (c.perms.pub.allow != nil || c.perms.pub.deny != nil) && !c.pubAllowed(subject) {
+			c.pubPermissionViolation([]byte(subject))
+			return nil
+		}
+		_, err = c.mqtt.sess.jsa.storeMsg(subject, hdr, msgToSend)
+		return err
+	}
+
+	c.pa.subject, c.pa.hdr, c.pa.reply = []byte(subject), hdr, nil
+	c.pa.hdb = []byte(strconv.Itoa(hdr))
+	c.pa.size = len(msgToSend)
+	c.pa.szb = []byte(strconv.Itoa(c.pa.size))
+	c.processInboundClientMsg(msgToSend)
+	c.pa.subject, c.pa.hdr, c.pa.size, c.pa.szb, c.pa.reply = nil, -1, 0, nil, nil
+	return nil
+}
+
+// mqttGetStation returns the station a subscription consumes from, stations are created on demand
+// the same way the Memphis SDKs create them
+func (c *client) mqttGetStation(sn StationName) (models.Station, error) {
+	tenantName := tenantNameFromAccount(c.acc)
+	exist, station, err := IsStationExist(sn, tenantName)
+	if err != nil || exist {
+		return station, err
+	}
+	station, created, err := CreateDefaultStation(c.srv, sn, c.memphisInfo.username, tenantName)
+	if err != nil {
+		return station, err
+	}
+	if created {
+		message := "Station " + sn.Ext() + " has been created by user " + c.memphisInfo.username + " through the MQTT bridge"
+		c.srv.Noticef(message)
+		var auditLogs []interface{}
+		newAuditLog := models.AuditLog{
+			ID:            primitive.NewObjectID(),
+			StationName:   sn.Ext(),
+			Message:       message,
+			CreatedByUser: c.memphisInfo.username,
+			CreationDate:  time.Now(),
+			UserType:      "application",
+		}
+		auditLogs = append(auditLogs, newAuditLog)
+		if err = CreateAuditLogs(auditLogs); err != nil {
+			c.Errorf("mqttGetStation: Station " + sn.Ext() + ": " + err.Error())
+		}
+	}
+	return station, nil
+}
+
+// mqttRegisterStationConsumer registers the client as a member of the consumers group, the group's
+// durable is created with the MQTT ack wait when the group does not exist yet
+func (c *client) mqttRegisterStationConsumer(station models.Station, consumerName, cgName string) error {
+	ackWait := c.srv.getOpts().MQTT.AckWait
+	if ackWait == 0 {
+		ackWait = mqttDefaultAckWait
+	}
+	newConsumer := models.Consumer{
+		ID:             primitive.NewObjectID(),
+		Name:           consumerName,
+		StationId:      station.ID,
+		Type:           "application",
+		ConnectionId:   c.memphisInfo.connectionId,
+		CreatedByUser:  c.memphisInfo.username,
+		ConsumersGroup: cgName,
+		IsActive:       true,
+		CreationDate:   time.Now(),
+		MaxAckTimeMs:   ackWait.Milliseconds(),
+	}
+	exist, consumerFromGroup, err := isConsumerGroupExist(cgName, station.ID)
+	if err != nil {
+		return err
+	}
+	if exist {
+		newConsumer.MaxAckTimeMs = consumerFromGroup.MaxAckTimeMs
+		newConsumer.MaxMsgDeliveries = consumerFromGroup.MaxMsgDeliveries
+		newConsumer.RedeliveryBackoff = consumerFromGroup.RedeliveryBackoff
+		newConsumer.ContentFilter = consumerFromGroup.ContentFilter
+	} else if err = c.srv.CreateConsumer(newConsumer, station); err != nil {
+		return err
+	}
+
+	filter := bson.M{"name": newConsumer.Name, "station_id": station.ID, "is_active": true, "is_deleted": false}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":                newConsumer.ID,
+			"type":               newConsumer.Type,
+			"connection_id":      newConsumer.ConnectionId,
+			"created_by_user":    newConsumer.CreatedByUser,
+			"consumers_group":    newConsumer.ConsumersGroup,
+			"creation_date":      newConsumer.CreationDate,
+			"max_ack_time_ms":    newConsumer.MaxAckTimeMs,
+			"max_msg_deliveries": newConsumer.MaxMsgDeliveries,
+			"redelivery_backoff": newConsumer.RedeliveryBackoff,
+			"content_filter":     newConsumer.ContentFilter,
+		},
+	}
+	opts := options.Update().SetUpsert(true)
+	updateResults, err := consumersCollection.UpdateOne(context.TODO(), filter, update, opts)
+	if err != nil {
+		return err
+	}
+	if updateResults.MatchedCount == 0 {
+		c.srv.memphisWSPublishEvent(station.TenantName, memphisWS_Event_CgMemberJoined, station.Name, newConsumer)
+	}
+	return nil
+}
+
+// Creates (or updates the QoS of) a subscription on a bridged topic. The client joins the consumers
+// group of the station, so unacknowledged QoS1 messages are redelivered and end up in the station's
+// dead-letter station once the group's max deliveries are exceeded.
+//
+// Runs from the client's readLoop.
+// Lock not held on entry, but session is in the locked map.
+func (c *client) mqttProcessStationSub(sess *mqttSession, sid, stationName, cgName string, qos byte) (*subscription, error) {
+	c.mu.Lock()
+	ss := c.mqtt.stationSubs[sid]
+	c.mu.Unlock()
+	if ss != nil {
+		sess.mu.Lock()
+		ss.sub.mqtt.qos = qos
+		sess.mu.Unlock()
+		return ss.sub, nil
+	}
+
+	sn, err := StationNameFromStr(stationName)
+	if err != nil {
+		return nil, err
+	}
+	consumerName := mqttMemphisClientName(c.mqtt.cid)
+	if cgName == _EMPTY_ {
+		cgName = consumerName
+	} else {
+		cgName = strings.ToLower(cgName)
+		if err = validateConsumerName(cgName); err != nil {
+			return nil, err
+		}
+	}
+	station, err := c.mqttGetStation(sn)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.mqttRegisterStationConsumer(station, consumerName, cgName); err != nil {
+		return nil, err
+	}
+
+	ss = &mqttStationSub{
+		topic:        []byte(c.srv.getOpts().MQTT.StationsPrefix + string(mqttTopicLevelSep) + stationName),
+		stream:       sn.Intern(),
+		cgName:       cgName,
+		consumerName: consumerName,
+		stationId:    station.ID,
+		partitioned:  isPartitioned(station.PartitionsNumber),
+		quitCh:       make(chan struct{}),
+	}
+	if ss.partitioned {
+		ss.partsSub, err = c.srv.subscribeOnAcc(c.acc, fmt.Sprintf(partitionsUpdatesSubjectTemplate, ss.stream), fmt.Sprintf(partitionsUpdatesSubjectTemplate, ss.stream)+"_mqtt_"+nuid.Next(), ss.handlePartitionsUpdate)
+		if err != nil {
+			return nil, err
+		}
+		assignment, err := c.srv.rebalancePartitions(station, cgName)
+		if err != nil {
+			c.Errorf("mqttProcessStationSub: Station " + sn.Ext() + ": " + err.Error())
+		}
+		ss.partitions = assignment.Assignments[consumerName]
+	}
+
+	inbox := mqttSubPrefix + nuid.Next()
+	sess.mu.Lock()
+	sub, err := c.processSub([]byte(inbox), nil, []byte(inbox), ss.deliverMsg, false)
+	if err != nil {
+		sess.mu.Unlock()
+		if ss.partsSub != nil {
+			c.srv.unsubscribeOnAcc(c.acc, ss.partsSub)
+		}
+		return nil, err
+	}
+	sub.mqtt = &mqttSub{qos: qos, jsDur: ss.stream + "_" + getInternalConsumerName(cgName)}
+	batch := int(sess.maxp)
+	sess.mu.Unlock()
+	ss.sub = sub
+
+	c.mu.Lock()
+	if c.mqtt.stationSubs == nil {
+		c.mqtt.stationSubs = make(map[string]*mqttStationSub)
+	}
+	c.mqtt.stationSubs[sid] = ss
+	c.mu.Unlock()
+
+	go ss.pullLoop(sess.jsa, inbox, batch)
+	return sub, nil
+}
+
+func (ss *mqttStationSub) handlePartitionsUpdate(_ *client, _, _ string, msg []byte) {
+	var assignment models.PartitionsAssignment
+	if err := json.Unmarshal(msg, &assignment); err != nil || assignment.ConsumersGroup != ss.cgName {
+		return
+	}
+	ss.mu.Lock()
+	ss.partitions = assignment.Assignments[ss.consumerName]
+	ss.mu.Unlock()
+}
+
+// durables returns the durables the subscription pulls from, a partitioned station is consumed
+// through the durables of the partitions assigned to this member
+func (ss *mqttStationSub) durables() []string {
+	if !ss.partitioned {
+		return []string{getInternalConsumerName(ss.cgName)}
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	durables := make([]string, 0, len(ss.partitions))
+	for _, p := range ss.partitions {
+		durables = append(durables, getPartitionDurableName(ss.cgName, p))
+	}
+	return durables
+}
+
+// pullLoop keeps pull requests pending on the consumers group durable(s) until the subscription is removed,
+// the messages are delivered to the subscription's inbox
+func (ss *mqttStationSub) pullLoop(jsa *mqttJSA, inbox string, batch int) {
+	req, _ := json.Marshal(JSApiConsumerGetNextRequest{Batch: batch, Expires: mqttStationPullExpiration})
+	ticker := time.NewTicker(mqttStationPullExpiration)
+	defer ticker.Stop()
+	for {
+		for _, durable := range ss.durables() {
+			subj := jsa.prefixDomain(fmt.Sprintf(JSApiRequestNextT, ss.stream, durable))
+			jsa.sendq.push(&mqttJSPubMsg{subj: subj, reply: inbox, hdr: -1, msg: copyBytes(req)})
+		}
+		select {
+		case <-ticker.C:
+		case <-ss.quitCh:
+			return
+		}
+	}
+}
+
+// This is the callback of the inbox a station subscription pulls into. QoS0 subscriptions
+// acknowledge the messages as soon as they are handed to the client, QoS1 subscriptions
+// when the client sends the PUBACK.
+func (ss *mqttStationSub) deliverMsg(sub *subscription, pc *client, _ *Account, _, reply string, rmsg []byte) {
+	// Status messages, such as an expired pull request, carry no ack subject
+	if reply == _EMPTY_ {
+		return
+	}
+	hdr, msg := pc.msgParts(rmsg)
+	if len(msg) > mqttMaxPayloadSize {
+		msg = msg[:mqttMaxPayloadSize]
+	}
+
+	cc := sub.client
+	sess := cc.mqtt.sess
+	sess.mu.Lock()
+	if sess.c != cc || sub.mqtt == nil {
+		sess.mu.Unlock()
+		return
+	}
+	// Spec v5 [MQTT-3.3.2-5]: expired messages are acknowledged without being delivered
+	if mqttMsgExpired(hdr) {
+		sess.jsa.sendq.push(&mqttJSPubMsg{subj: reply, hdr: -1})
+		sess.mu.Unlock()
+		return
+	}
+	qos := sub.mqtt.qos
+	pi, dup := sess.trackPending(qos, reply, sub)
+	if qos == 0 {
+		sess.jsa.sendq.push(&mqttJSPubMsg{subj: reply, hdr: -1})
+	}
+	sess.mu.Unlock()
+	if qos > 0 && pi == 0 {
+		// Max pending reached, the message is redelivered after the group's ack wait
+		return
+	}
+	pc.mqttDeliver(cc, sub, pi, dup, false, ss.topic, hdr, msg)
+}
+
+// stop ends the pull loop, the inbox subscription is removed by the caller or with the client
+func (ss *mqttStationSub) stop(c *client) {
+	close(ss.quitCh)
+	if ss.partsSub != nil {
+		c.srv.unsubscribeOnAcc(c.acc, ss.partsSub)
+	}
+}
+
+// Removes the station subscription of the given sid, if any, and deletes the
+// client's consumer from the station. Returns false if sid is not a station subscription.
+//
+// Runs from the client's readLoop.
+// Lock not held on entry, but session is in the locked map.
+func (c *client) mqttRemoveStationSub(sess *mqttSession, sid string) bool {
+	c.mu.Lock()
+	ss, ok := c.mqtt.stationSubs[sid]
+	delete(c.mqtt.stationSubs, sid)
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	ss.stop(c)
+	if err := c.processUnsub(ss.sub.sid); err != nil {
+		c.Errorf("error unsubscribing from %q: %v", sid, err)
+	}
+	sess.mu.Lock()
+	sess.removePending(ss.sub.mqtt.jsDur)
+	sess.mu.Unlock()
+
+	_, err := consumersCollection.UpdateMany(context.TODO(),
+		bson.M{"name": ss.consumerName, "station_id": ss.stationId, "consumers_group": ss.cgName, "is_active": true},
+		bson.M{"$set": bson.M{"is_active": false, "is_deleted": true}},
+	)
+	if err != nil {
+		c.Errorf("mqttRemoveStationSub: Consumer " + ss.consumerName + ": " + err.Error())
+		return true
+	}
+	if ss.partitioned {
+		var station models.Station
+		if err = stationsCollection.FindOne(context.TODO(), bson.M{"_id": ss.stationId}).Decode(&station); err == nil {
+			_, err = c.srv.rebalancePartitions(station, ss.cgName)
+		}
+		if err != nil {
+			c.Errorf("mqttRemoveStationSub: Consumer " + ss.consumerName + ": " + err.Error())
+		}
+	}
+	return true
+}
+
+// Stops the pull loops of all the station subscriptions of a closed client, its
+// consumers are marked as disconnected along with its Memphis connection.
+func (c *client) mqttStopStationSubs() {
+	c.mu.Lock()
+	stationSubs := c.mqtt.stationSubs
+	c.mqtt.stationSubs = nil
+	c.mu.Unlock()
+	for _, ss := range stationSubs {
+		ss.stop(c)
+	}
+}
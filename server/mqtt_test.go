@@ -6430,3 +6430,115 @@ func BenchmarkMQTT_QoS1_PubSub2_256b_Payload(b *testing.B) {
 func BenchmarkMQTT_QoS1_PubSub2___1K_Payload(b *testing.B) {
 	mqttBenchPubQoS1(b, mqttPubSubj, sizedString(1024), 2)
 }
+
+func TestMQTTStationsBridge(t *testing.T) {
+	opts := testMQTTDefaultOptions()
+	if prefix := mqttStationsSubjectPrefix(opts); prefix != _EMPTY_ {
+		t.Fatalf("Expected the bridge to be disabled, got prefix %q", prefix)
+	}
+	opts.MQTT.StationsPrefix = "memphis/stations"
+	prefix := mqttStationsSubjectPrefix(opts)
+	if prefix != "memphis.stations." {
+		t.Fatalf("Unexpected prefix %q", prefix)
+	}
+
+	for _, tc := range []struct {
+		subject  string
+		sharedOk bool
+		station  string
+		cgName   string
+		ok       bool
+	}{
+		{"memphis.stations.orders", false, "orders", _EMPTY_, true},
+		{"$share.billing.memphis.stations.orders", true, "orders", "billing", true},
+		{"$share.billing.memphis.stations.orders", false, _EMPTY_, _EMPTY_, false},
+		{"memphis.stations.*", true, _EMPTY_, _EMPTY_, false},
+		{"memphis.stations.orders.eu", false, _EMPTY_, _EMPTY_, false},
+		{"memphis.orders", false, _EMPTY_, _EMPTY_, false},
+	} {
+		station, cgName, ok := mqttStationFromSubject(prefix, tc.subject, tc.sharedOk)
+		if station != tc.station || cgName != tc.cgName || ok != tc.ok {
+			t.Fatalf("Subject %q: expected %q, %q, %v got %q, %q, %v", tc.subject, tc.station, tc.cgName, tc.ok, station, cgName, ok)
+		}
+	}
+
+	if name := mqttMemphisClientName("Sensor-12"); name != "sensor-12" {
+		t.Fatalf("Unexpected client name %q", name)
+	}
+	name := mqttMemphisClientName("sensor/12")
+	if name != mqttMemphisClientName("sensor/12") || validateConsumerName(name) != nil {
+		t.Fatalf("Expected a stable valid name for an invalid client ID, got %q", name)
+	}
+}
+
+func TestMQTTv5Properties(t *testing.T) {
+	props := &mqttWriter{}
+	props.WriteByte(mqttPropMessageExpiry)
+	props.WriteUint32(60)
+	props.WriteByte(mqttPropContentType)
+	props.WriteString("application/json")
+	props.WriteByte(mqttPropUserProperty)
+	props.WriteString("region")
+	props.WriteString("eu-west")
+	props.WriteByte(mqttPropUserProperty)
+	props.WriteString("Nmqtt-Pub")
+	props.WriteString("1")
+	props.WriteByte(mqttPropTopicAlias)
+	props.WriteUint16(3)
+	w := &mqttWriter{}
+	w.WriteProperties(props.Bytes())
+
+	r := &mqttReader{}
+	r.reset(w.Bytes())
+	pp, err := r.readProperties("publish")
+	if err != nil {
+		t.Fatalf("Error reading properties: %v", err)
+	}
+	if pp.msgExpiry != 60 || pp.contentType != "application/json" || pp.topicAlias != 3 || len(pp.userProps) != 2 {
+		t.Fatalf("Unexpected properties %+v", pp)
+	}
+
+	bb := bytes.Buffer{}
+	bb.WriteString(hdrLine)
+	mqttWritePropertiesHeaders(&bb, pp)
+	bb.WriteString(_CRLF_)
+	hdr := bb.Bytes()
+	if v := getHeader("region", hdr); string(v) != "eu-west" {
+		t.Fatalf("Expected the user property as a header, got %q", v)
+	}
+	if v := getHeader(mqttNatsHeader, hdr); v != nil {
+		t.Fatalf("Reserved user property should have been dropped, got %q", v)
+	}
+	if mqttMsgExpired(hdr) {
+		t.Fatal("Message should not be expired")
+	}
+
+	out := mqttPublishProperties(hdr)
+	r.reset(append([]byte{byte(len(out))}, out...))
+	op, err := r.readProperties("publish")
+	if err != nil {
+		t.Fatalf("Error reading outbound properties: %v", err)
+	}
+	if op.msgExpiry == 0 || op.msgExpiry > 60 || op.contentType != "application/json" ||
+		len(op.userProps) != 1 || op.userProps[0] != (mqttUserProperty{"region", "eu-west"}) {
+		t.Fatalf("Unexpected outbound properties %+v", op)
+	}
+
+	c := &client{mqtt: &mqtt{v5: true}}
+	pub := &mqttPublish{topic: []byte("a/b"), subject: []byte("a.b"), props: &mqttProperties{topicAlias: 3}}
+	if err := c.mqttResolveTopicAlias(pub); err != nil {
+		t.Fatalf("Error registering alias: %v", err)
+	}
+	pub = &mqttPublish{props: &mqttProperties{topicAlias: 3}}
+	if err := c.mqttResolveTopicAlias(pub); err != nil || string(pub.subject) != "a.b" {
+		t.Fatalf("Expected the alias to resolve to a.b, got %q (%v)", pub.subject, err)
+	}
+	pub = &mqttPublish{props: &mqttProperties{topicAlias: 4}}
+	if err := c.mqttResolveTopicAlias(pub); err != errMQTTTopicAliasUnknown {
+		t.Fatalf("Expected unknown alias error, got %v", err)
+	}
+
+	if group, subject, ok := mqttSharedSubject("$share.workers.sensors.>"); !ok || group != "workers" || subject != "sensors.>" {
+		t.Fatalf("Unexpected shared subscription parsing: %q %q %v", group, subject, ok)
+	}
+}
@@ -0,0 +1,604 @@
+// Copyright 2022-2023 The Memphis.dev Authors
+// Licensed under the Memphis Business Source License 1.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// Changed License: [Apache License, Version 2.0 (https://www.apache.org/licenses/LICENSE-2.0), as published by the Apache Foundation.
+//
+// https://github.com/memphisdev/memphis-broker/blob/master/LICENSE
+//
+// Additional Use Grant: You may make use of the Licensed Work (i) only as part of your own product or service, provided it is not a message broker or a message queue product or service; and (ii) provided that you do not use, provide, distribute, or make available the Licensed Work as a Service.
+// A "Service" is a commercial offering, product, hosted, or managed service, that allows third parties (other than your own employees and contractors acting on your behalf) to access and/or use the Licensed Work or a substantial set of the features or functionality of the Licensed Work to third parties as a software-as-a-service, platform-as-a-service, infrastructure-as-a-service or other similar services that compete with Licensor products or services.
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// References to "spec v5" here are from https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.pdf
+
+const (
+	mqttProtoLevel5 = byte(0x5)
+
+	// Properties identifiers
+	mqttPropPayloadFormat        = 0x01
+	mqttPropMessageExpiry        = 0x02
+	mqttPropContentType          = 0x03
+	mqttPropResponseTopic        = 0x08
+	mqttPropCorrelationData      = 0x09
+	mqttPropSubscriptionId       = 0x0B
+	mqttPropSessionExpiry        = 0x11
+	mqttPropAssignedClientId     = 0x12
+	mqttPropAuthMethod           = 0x15
+	mqttPropAuthData             = 0x16
+	mqttPropRequestProblemInfo   = 0x17
+	mqttPropWillDelay            = 0x18
+	mqttPropRequestResponseInfo  = 0x19
+	mqttPropReasonString         = 0x1F
+	mqttPropReceiveMaximum       = 0x21
+	mqttPropTopicAliasMaximum    = 0x22
+	mqttPropTopicAlias           = 0x23
+	mqttPropMaximumQoS           = 0x24
+	mqttPropUserProperty         = 0x26
+	mqttPropMaximumPacketSize    = 0x27
+	mqttPropSubscriptionIdsAvail = 0x29
+
+	// Reason codes
+	mqttReasonSuccess               = byte(0x00)
+	mqttReasonDisconnectWithWill    = byte(0x04)
+	mqttReasonNoSubscriptionExisted = byte(0x11)
+	mqttReasonUnspecifiedError      = byte(0x80)
+	mqttReasonMalformedPacket       = byte(0x81)
+	mqttReasonProtocolError         = byte(0x82)
+	mqttReasonUnsupportedProtocol   = byte(0x84)
+	mqttReasonClientIdNotValid      = byte(0x85)
+	mqttReasonBadUserOrPassword     = byte(0x86)
+	mqttReasonNotAuthorized         = byte(0x87)
+	mqttReasonServerUnavailable     = byte(0x88)
+	mqttReasonBadAuthMethod         = byte(0x8C)
+	mqttReasonSessionTakenOver      = byte(0x8E)
+	mqttReasonTopicAliasInvalid     = byte(0x94)
+	mqttReasonQoSNotSupported       = byte(0x9B)
+
+	// Subscription options
+	mqttSubOptQoS            = byte(0x03)
+	mqttSubOptRetainHandling = byte(0x30)
+	mqttSubOptReserved       = byte(0xC0)
+
+	// Retain handling value for "do not send retained messages at the time of the subscribe"
+	mqttRetainHandlingNone = byte(0x2)
+
+	// Session expiry interval for a session that does not expire
+	mqttSessionExpiryNever = uint32(0xFFFFFFFF)
+
+	// Number of topic aliases a client can register on a connection
+	mqttTopicAliasMaximum = 1024
+
+	// Inactive threshold of the durables of shared subscriptions when none is configured
+	mqttSharedConsumerInactiveThreshold = 5 * time.Minute
+
+	// Those are the header keys carrying MQTT 5 PUBLISH properties in NATS messages,
+	// the expiry is the unix time in seconds at which the message expires
+	mqttNatsExpiryHeader          = "Nmqtt-Expiry"
+	mqttNatsContentTypeHeader     = "Nmqtt-Content-Type"
+	mqttNatsResponseTopicHeader   = "Nmqtt-Response-Topic"
+	mqttNatsCorrelationDataHeader = "Nmqtt-Correlation-Data"
+	mqttNatsPayloadFormatHeader   = "Nmqtt-Payload-Format"
+
+	// Prefix of the header keys reserved to the MQTT layer
+	mqttNatsHeaderPrefix = "Nmqtt-"
+)
+
+var (
+	errMQTTTopicAliasInvalid  = errors.New("topic alias invalid")
+	errMQTTTopicAliasUnknown  = errors.New("topic alias not registered on this connection")
+	errMQTTSubOptionsReserved = errors.New("subscription options reserved bits not set to 0")
+	errMQTTQoS2NotSupported   = errors.New("publish QoS=2 not supported")
+)
+
+// mqttProperties are the properties of an MQTT 5 packet this server handles,
+// the other ones are validated and dropped
+type mqttProperties struct {
+	payloadFormat   byte
+	msgExpiry       uint32
+	contentType     string
+	responseTopic   string
+	correlationData []byte
+	sessExpiry      uint32
+	hasSessExpiry   bool
+	receiveMax      uint16
+	topicAlias      uint16
+	authMethod      string
+	userProps       []mqttUserProperty
+}
+
+type mqttUserProperty struct {
+	key   string
+	value string
+}
+
+type mqttTopicAlias struct {
+	topic   []byte
+	subject []byte
+}
+
+// Reads a variable byte integer out of a packet that has already been fully received.
+func (r *mqttReader) readVarInt(field string) (int, error) {
+	m := 1
+	v := 0
+	for {
+		b, err := r.readByte(field)
+		if err != nil {
+			return 0, err
+		}
+		v += int(b&0x7f) * m
+		if b&0x80 == 0 {
+			return v, nil
+		}
+		m *= 0x80
+		if m > 0x200000 {
+			return 0, errMQTTMalformedVarInt
+		}
+	}
+}
+
+func (r *mqttReader) readUint32(field string) (uint32, error) {
+	if len(r.buf)-r.pos < 4 {
+		return 0, fmt.Errorf("error reading %s: %v", field, io.ErrUnexpectedEOF)
+	}
+	start := r.pos
+	r.pos += 4
+	b := r.buf[start:r.pos]
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]), nil
+}
+
+func (r *mqttReader) readUTF8(field string) (string, error) {
+	s, err := r.readString(field)
+	if err == nil && !utf8.ValidString(s) {
+		err = fmt.Errorf("invalid utf8 for %s %q", field, s)
+	}
+	return s, err
+}
+
+// Reads the properties of a packet. Properties that are not allowed in the given
+// packet type can only be sent by a broken client, so they are not checked per packet.
+func (r *mqttReader) readProperties(field string) (*mqttProperties, error) {
+	l, err := r.readVarInt(field + " properties length")
+	if err != nil {
+		return nil, err
+	}
+	end := r.pos + l
+	if end > len(r.buf) {
+		return nil, fmt.Errorf("error reading %s properties: %v", field, io.ErrUnexpectedEOF)
+	}
+	props := &mqttProperties{}
+	for r.pos < end {
+		id, err := r.readVarInt(field + " property identifier")
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case mqttPropPayloadFormat:
+			props.payloadFormat, err = r.readByte("payload format indicator")
+		case mqttPropMessageExpiry:
+			props.msgExpiry, err = r.readUint32("message expiry interval")
+		case mqttPropContentType:
+			props.contentType, err = r.readUTF8("content type")
+		case mqttPropResponseTopic:
+			props.responseTopic, err = r.readUTF8("response topic")
+		case mqttPropCorrelationData:
+			props.correlationData, err = r.readBytes("correlation data", true)
+		case mqttPropSubscriptionId:
+			_, err = r.readVarInt("subscription identifier")
+		case mqttPropSessionExpiry:
+			props.sessExpiry, err = r.readUint32("session expiry interval")
+			props.hasSessExpiry = true
+		case mqttPropAuthMethod:
+			props.authMethod, err = r.readUTF8("authentication method")
+		case mqttPropAuthData:
+			_, err = r.readBytes("authentication data", false)
+		case mqttPropRequestProblemInfo, mqttPropRequestResponseInfo:
+			_, err = r.readByte("request information")
+		case mqttPropWillDelay, mqttPropMaximumPacketSize:
+			_, err = r.readUint32("interval")
+		case mqttPropReasonString:
+			_, err = r.readUTF8("reason string")
+		case mqttPropReceiveMaximum:
+			if props.receiveMax, err = r.readUint16("receive maximum"); err == nil && props.receiveMax == 0 {
+				err = errors.New("receive maximum cannot be 0")
+			}
+		case mqttPropTopicAliasMaximum:
+			_, err = r.readUint16("topic alias maximum")
+		case mqttPropTopicAlias:
+			props.topicAlias, err = r.readUint16("topic alias")
+		case mqttPropUserProperty:
+			var up mqttUserProperty
+			if up.key, err = r.readUTF8("user property name"); err == nil {
+				up.value, err = r.readUTF8("user property value")
+			}
+			props.userProps = append(props.userProps, up)
+		default:
+			err = fmt.Errorf("unknown %s property identifier 0x%x", field, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.pos != end {
+		return nil, fmt.Errorf("error reading %s properties: length mismatch", field)
+	}
+	return props, nil
+}
+
+func (w *mqttWriter) WriteUint32(i uint32) {
+	w.WriteByte(byte(i >> 24))
+	w.WriteByte(byte(i >> 16))
+	w.WriteByte(byte(i >> 8))
+	w.WriteByte(byte(i))
+}
+
+// Writes the properties length followed by the properties.
+func (w *mqttWriter) WriteProperties(props []byte) {
+	w.WriteVarInt(len(props))
+	w.Write(props)
+}
+
+// Returns the MQTT 5 reason code for a CONNACK return code of MQTT 3.1.1.
+func mqttConnAckReasonCode(rc byte) byte {
+	switch rc {
+	case mqttConnAckRCConnectionAccepted:
+		return mqttReasonSuccess
+	case mqttConnAckRCUnacceptableProtocolVersion:
+		return mqttReasonUnsupportedProtocol
+	case mqttConnAckRCIdentifierRejected:
+		return mqttReasonClientIdNotValid
+	case mqttConnAckRCServerUnavailable:
+		return mqttReasonServerUnavailable
+	case mqttConnAckRCBadUserOrPassword:
+		return mqttReasonBadUserOrPassword
+	case mqttConnAckRCNotAuthorized:
+		return mqttReasonNotAuthorized
+	}
+	// Already a MQTT 5 reason code
+	return rc
+}
+
+// Returns the reason code of the DISCONNECT sent to a MQTT 5 client failed
+// because of the given protocol error.
+func mqttDisconnectReasonCode(err error) byte {
+	switch {
+	case errors.Is(err, errMQTTTopicAliasInvalid):
+		return mqttReasonTopicAliasInvalid
+	case errors.Is(err, errMQTTTopicAliasUnknown), errors.Is(err, errMQTTSecondConnectPacket):
+		return mqttReasonProtocolError
+	case errors.Is(err, errMQTTQoS2NotSupported):
+		return mqttReasonQoSNotSupported
+	}
+	return mqttReasonMalformedPacket
+}
+
+// Parses the MQTT 5 DISCONNECT packet, the reason code and properties are
+// optional.
+func mqttParseDisconnect(r *mqttReader, pl int) (byte, *mqttProperties, error) {
+	if pl == 0 {
+		return mqttReasonSuccess, nil, nil
+	}
+	rc, err := r.readByte("reason code")
+	if err != nil || pl == 1 {
+		return rc, nil, err
+	}
+	props, err := r.readProperties("disconnect")
+	return rc, props, err
+}
+
+// Sends a DISCONNECT to a MQTT 5 client before the server closes the connection.
+func (c *client) mqttEnqueueDisconnect(rc byte) {
+	proto := [3]byte{mqttPacketDisconnect, 1, rc}
+	c.mu.Lock()
+	c.enqueueProto(proto[:])
+	c.mu.Unlock()
+}
+
+// Resolves the topic of a PUBLISH through its topic alias. A PUBLISH with a topic
+// and an alias (re)registers the alias, one with an empty topic reuses it.
+//
+// Runs from the client's readLoop.
+func (c *client) mqttResolveTopicAlias(pp *mqttPublish) error {
+	alias := pp.props.topicAlias
+	if alias == 0 {
+		if len(pp.topic) == 0 {
+			return errMQTTTopicIsEmpty
+		}
+		return nil
+	}
+	if alias > mqttTopicAliasMaximum {
+		return errMQTTTopicAliasInvalid
+	}
+	if len(pp.topic) == 0 {
+		ta, ok := c.mqtt.topicAliases[alias]
+		if !ok {
+			return errMQTTTopicAliasUnknown
+		}
+		pp.topic, pp.subject = ta.topic, ta.subject
+		return nil
+	}
+	if c.mqtt.topicAliases == nil {
+		c.mqtt.topicAliases = make(map[uint16]*mqttTopicAlias)
+	}
+	c.mqtt.topicAliases[alias] = &mqttTopicAlias{topic: copyBytes(pp.topic), subject: copyBytes(pp.subject)}
+	return nil
+}
+
+// Updates the session expiry on a MQTT 5 DISCONNECT. Spec v5 [MQTT-3.14.2-2]: a session
+// that was set to expire with the connection can not be given an expiry afterwards.
+//
+// Runs from the client's readLoop.
+// No lock held on entry.
+func (c *client) mqttUpdateSessionExpiry(expiry uint32) {
+	sess := c.mqtt.sess
+	if sess == nil {
+		return
+	}
+	sess.mu.Lock()
+	if sess.c == c && sess.expiry != 0 {
+		sess.expiry = expiry
+		sess.clean = expiry == 0
+	}
+	sess.mu.Unlock()
+}
+
+// Removes a session whose expiry interval elapsed without a client resuming it.
+//
+// Runs from the session's expiry timer.
+// No lock held on entry.
+func (as *mqttAccountSessionManager) expireSession(sess *mqttSession) error {
+	// Locking with a nil client fails if a client has resumed the session.
+	if err := as.lockSession(sess, nil); err != nil {
+		return nil
+	}
+	defer as.unlockSession(sess)
+
+	as.mu.Lock()
+	sess.mu.Lock()
+	sess.expiryTimer = nil
+	sess.mu.Unlock()
+	as.removeSession(sess, false)
+	as.mu.Unlock()
+	return sess.clear()
+}
+
+// Adds the user properties and the other PUBLISH properties that must be forwarded
+// to subscribers as headers of the NATS message. The final CRLF of the header is
+// left to the caller.
+func mqttWritePropertiesHeaders(bb *bytes.Buffer, props *mqttProperties) {
+	if props == nil {
+		return
+	}
+	writeHdr := func(key, value string) {
+		bb.WriteString(key)
+		bb.WriteString(": ")
+		bb.WriteString(value)
+		bb.WriteString(_CRLF_)
+	}
+	if props.msgExpiry > 0 {
+		deadline := time.Now().Unix() + int64(props.msgExpiry)
+		writeHdr(mqttNatsExpiryHeader, strconv.FormatInt(deadline, 10))
+	}
+	if props.payloadFormat != 0 {
+		writeHdr(mqttNatsPayloadFormatHeader, strconv.Itoa(int(props.payloadFormat)))
+	}
+	if props.contentType != _EMPTY_ && mqttIsValidHeaderValue(props.contentType) {
+		writeHdr(mqttNatsContentTypeHeader, props.contentType)
+	}
+	if props.responseTopic != _EMPTY_ && mqttIsValidHeaderValue(props.responseTopic) {
+		writeHdr(mqttNatsResponseTopicHeader, props.responseTopic)
+	}
+	if len(props.correlationData) > 0 {
+		writeHdr(mqttNatsCorrelationDataHeader, base64.StdEncoding.EncodeToString(props.correlationData))
+	}
+	for _, up := range props.userProps {
+		// Keys reserved to the server, or that can not be carried by a NATS
+		// header, are dropped.
+		if mqttIsReservedHeader(up.key) || !mqttIsValidHeaderKey(up.key) || !mqttIsValidHeaderValue(up.value) {
+			continue
+		}
+		writeHdr(up.key, up.value)
+	}
+}
+
+func mqttIsReservedHeader(key string) bool {
+	return strings.HasPrefix(key, mqttNatsHeaderPrefix) || strings.HasPrefix(key, "Nats-") || strings.HasPrefix(key, "$memphis")
+}
+
+func mqttIsValidHeaderKey(key string) bool {
+	return key != _EMPTY_ && !strings.ContainsAny(key, ": \t\r\n")
+}
+
+func mqttIsValidHeaderValue(value string) bool {
+	return !strings.ContainsAny(value, "\r\n")
+}
+
+// Returns true if the NATS message carries a MQTT 5 message expiry that has elapsed.
+func mqttMsgExpired(hdr []byte) bool {
+	v := getHeader(mqttNatsExpiryHeader, hdr)
+	if len(v) == 0 {
+		return false
+	}
+	deadline, err := strconv.ParseInt(string(v), 10, 64)
+	return err == nil && time.Now().Unix() >= deadline
+}
+
+// Builds the properties of a PUBLISH sent to a MQTT 5 client from the headers of the
+// NATS message. Headers that are not reserved are sent as user properties.
+// The returned slice is never nil since it is what tells mqttSerializePublishMsg
+// to serialize a MQTT 5 PUBLISH.
+func mqttPublishProperties(hdr []byte) []byte {
+	props := []byte{}
+	if len(hdr) == 0 {
+		return props
+	}
+	w := &mqttWriter{}
+	lines := strings.Split(string(hdr), _CRLF_)
+	// The first line is the NATS header version and status.
+	for _, line := range lines[1:] {
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch key {
+		case mqttNatsExpiryHeader:
+			deadline, err := strconv.ParseInt(value, 10, 64)
+			if remaining := deadline - time.Now().Unix(); err == nil && remaining > 0 {
+				w.WriteByte(mqttPropMessageExpiry)
+				w.WriteUint32(uint32(remaining))
+			}
+		case mqttNatsPayloadFormatHeader:
+			if pf, err := strconv.Atoi(value); err == nil {
+				w.WriteByte(mqttPropPayloadFormat)
+				w.WriteByte(byte(pf))
+			}
+		case mqttNatsContentTypeHeader:
+			w.WriteByte(mqttPropContentType)
+			w.WriteString(value)
+		case mqttNatsResponseTopicHeader:
+			w.WriteByte(mqttPropResponseTopic)
+			w.WriteString(value)
+		case mqttNatsCorrelationDataHeader:
+			if cd, err := base64.StdEncoding.DecodeString(value); err == nil {
+				w.WriteByte(mqttPropCorrelationData)
+				w.WriteBytes(cd)
+			}
+		default:
+			if mqttIsReservedHeader(key) {
+				continue
+			}
+			w.WriteByte(mqttPropUserProperty)
+			w.WriteString(key)
+			w.WriteString(value)
+		}
+	}
+	return append(props, w.Bytes()...)
+}
+
+// Returns the group and subject of a "$share/<group>/<filter>" shared subscription
+// converted to a NATS subject, the group being used as the queue group.
+func mqttSharedSubject(subject string) (string, string, bool) {
+	if !strings.HasPrefix(subject, mqttSharedSubPrefix) {
+		return _EMPTY_, _EMPTY_, false
+	}
+	rest := subject[len(mqttSharedSubPrefix):]
+	i := strings.IndexByte(rest, btsep)
+	if i <= 0 || i == len(rest)-1 || strings.ContainsAny(rest[:i], "*>") {
+		return _EMPTY_, _EMPTY_, false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// Serializes a MQTT 5 packet made of the fixed header and the given variable header and payload.
+func mqttPacketV5(pt byte, body *mqttWriter) []byte {
+	w := &mqttWriter{}
+	w.WriteByte(pt)
+	w.WriteVarInt(body.Len())
+	w.Write(body.Bytes())
+	return w.Bytes()
+}
+
+// Sends the MQTT 5 CONNACK, which advertises what the server supports of the
+// protocol through its properties.
+func (c *client) mqttEnqueueConnAckV5(rc byte, sessionPresent bool) {
+	rc = mqttConnAckReasonCode(rc)
+	body := &mqttWriter{}
+	props := &mqttWriter{}
+	c.mu.Lock()
+	if rc == mqttReasonSuccess {
+		if sessionPresent {
+			body.WriteByte(1)
+		} else {
+			body.WriteByte(0)
+		}
+		props.WriteByte(mqttPropTopicAliasMaximum)
+		props.WriteUint16(mqttTopicAliasMaximum)
+		// QoS 2 is not supported.
+		props.WriteByte(mqttPropMaximumQoS)
+		props.WriteByte(1)
+		props.WriteByte(mqttPropSubscriptionIdsAvail)
+		props.WriteByte(0)
+		if cp := c.mqtt.cp; cp != nil && cp.cidAssigned {
+			props.WriteByte(mqttPropAssignedClientId)
+			props.WriteString(c.mqtt.cid)
+		}
+	} else {
+		// Spec v5 [MQTT-3.2.2-6]
+		body.WriteByte(0)
+	}
+	body.WriteByte(rc)
+	body.WriteProperties(props.Bytes())
+	c.enqueueProto(mqttPacketV5(mqttPacketConnectAck, body))
+	c.mu.Unlock()
+}
+
+// Sends a MQTT 5 PUBACK with a reason code, which is only needed when the
+// message was not accepted.
+func (c *client) mqttEnqueuePubAckV5(pi uint16, rc byte) {
+	proto := [5]byte{mqttPacketPubAck, 0x3, byte(pi >> 8), byte(pi), rc}
+	c.mu.Lock()
+	c.enqueueProto(proto[:])
+	c.mu.Unlock()
+}
+
+// Returns the number of bytes of the variable byte integer encoding of the given value.
+func mqttVarIntLen(value int) int {
+	n := 1
+	for value >>= 7; value > 0; value >>= 7 {
+		n++
+	}
+	return n
+}
+
+func (c *client) mqttEnqueueSubAckV5(pi uint16, filters []*mqttFilter) {
+	body := &mqttWriter{}
+	body.WriteUint16(pi)
+	body.WriteProperties(nil)
+	for _, f := range filters {
+		body.WriteByte(f.qos)
+	}
+	c.mu.Lock()
+	c.enqueueProto(mqttPacketV5(mqttPacketSubAck, body))
+	c.mu.Unlock()
+}
+
+// Returns the UNSUBACK reason code of each filter, which needs to be done
+// before the filters are removed from the session.
+//
+// Runs from the client's readLoop.
+func (c *client) mqttUnsubReasonCodes(filters []*mqttFilter) []byte {
+	sess := c.mqtt.sess
+	rcs := make([]byte, 0, len(filters))
+	for _, f := range filters {
+		if _, ok := sess.subs[f.filter]; ok {
+			rcs = append(rcs, mqttReasonSuccess)
+		} else {
+			rcs = append(rcs, mqttReasonNoSubscriptionExisted)
+		}
+	}
+	return rcs
+}
+
+func (c *client) mqttEnqueueUnsubAckV5(pi uint16, rcs []byte) {
+	body := &mqttWriter{}
+	body.WriteUint16(pi)
+	body.WriteProperties(nil)
+	body.Write(rcs)
+	c.mu.Lock()
+	c.enqueueProto(mqttPacketV5(mqttPacketUnsubAck, body))
+	c.mu.Unlock()
+}
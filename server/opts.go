@@ -252,6 +252,7 @@ type Options struct {
 	JsAccDefaultDomain    map[string]string `json:"-"` // account to domain name mapping
 	Websocket             WebsocketOpts     `json:"-"`
 	MQTT                  MQTTOpts          `json:"-"`
+	Kafka                 KafkaOpts         `json:"-"`
 	ProfPort              int               `json:"-"`
 	PidFile               string            `json:"-"`
 	PortsFileDir          string            `json:"-"`
@@ -472,6 +473,38 @@ type MQTTOpts struct {
 	// subscription ending with "#" will use 2 times the MaxAckPending value.
 	// Note that changes to this option is applied only to new subscriptions.
 	MaxAckPending uint16
+
+	// StationsPrefix enables the stations bridge. Publishing to or subscribing
+	// on "<prefix>/<station>" produces to or consumes from that station, with
+	// the MQTT client registered as a Memphis producer or consumer.
+	// Empty disables the bridge.
+	StationsPrefix string
+}
+
+// KafkaOpts are options for the Kafka protocol listener
+type KafkaOpts struct {
+	// The server will accept Kafka client connections on this hostname/IP.
+	Host string
+	// The server will accept Kafka client connections on this port.
+	Port int
+
+	// The host:port Kafka clients are told to connect to in the metadata
+	// responses. Defaults to the listen host and port.
+	Advertise string
+
+	// Timeout for the SASL authentication of a connection.
+	AuthTimeout float64
+
+	// By default the server will enforce the use of TLS since SASL PLAIN
+	// credentials are sent in clear. If no TLS configuration is provided,
+	// you need to explicitly set NoTLS to true to allow the server to start
+	// without TLS configuration.
+	NoTLS bool
+
+	// TLS configuration is required unless NoTLS is set.
+	TLSConfig *tls.Config
+	// Timeout for the TLS handshake
+	TLSTimeout float64
 }
 
 type netResolver interface {
@@ -1321,6 +1354,11 @@ func (o *Options) processConfigFileLine(k string, v interface{}, errors *[]error
 			*errors = append(*errors, err)
 			return
 		}
+	case "kafka":
+		if err := parseKafka(tk, o, errors, warnings); err != nil {
+			*errors = append(*errors, err)
+			return
+		}
 	case "server_tags":
 		var err error
 		switch v := v.(type) {
@@ -4205,7 +4243,74 @@ func parseMQTT(v interface{}, o *Options, errors *[]error, warnings *[]error) er
 			o.MQTT.ConsumerMemoryStorage = mv.(bool)
 		case "consumer_inactive_threshold", "consumer_auto_cleanup":
 			o.MQTT.ConsumerInactiveThreshold = parseDuration("consumer_inactive_threshold", tk, mv, errors, warnings)
+		case "stations_prefix":
+			o.MQTT.StationsPrefix = strings.TrimSuffix(mv.(string), "/")
+
+		default:
+			if !tk.IsUsedVariable() {
+				err := &unknownConfigFieldErr{
+					field: mk,
+					configErr: configErr{
+						token: tk,
+					},
+				}
+				*errors = append(*errors, err)
+				continue
+			}
+		}
+	}
+	return nil
+}
+
+func parseKafka(v interface{}, o *Options, errors *[]error, warnings *[]error) error {
+	var lt token
+	defer convertPanicToErrorList(&lt, errors)
 
+	tk, v := unwrapValue(v, &lt)
+	gm, ok := v.(map[string]interface{})
+	if !ok {
+		return &configErr{tk, fmt.Sprintf("Expected kafka to be a map, got %T", v)}
+	}
+	for mk, mv := range gm {
+		// Again, unwrap token value if line check is required.
+		tk, mv = unwrapValue(mv, &lt)
+		switch strings.ToLower(mk) {
+		case "listen":
+			hp, err := parseListen(mv)
+			if err != nil {
+				err := &configErr{tk, err.Error()}
+				*errors = append(*errors, err)
+				continue
+			}
+			o.Kafka.Host = hp.host
+			o.Kafka.Port = hp.port
+		case "port":
+			o.Kafka.Port = int(mv.(int64))
+		case "host", "net":
+			o.Kafka.Host = mv.(string)
+		case "advertise":
+			o.Kafka.Advertise = mv.(string)
+		case "tls":
+			tc, err := parseTLS(tk, true)
+			if err != nil {
+				*errors = append(*errors, err)
+				continue
+			}
+			if o.Kafka.TLSConfig, err = GenTLSConfig(tc); err != nil {
+				err := &configErr{tk, err.Error()}
+				*errors = append(*errors, err)
+				continue
+			}
+			o.Kafka.TLSTimeout = tc.Timeout
+		case "no_tls":
+			o.Kafka.NoTLS = mv.(bool)
+		case "auth_timeout":
+			switch mv := mv.(type) {
+			case int64:
+				o.Kafka.AuthTimeout = float64(mv)
+			case float64:
+				o.Kafka.AuthTimeout = mv
+			}
 		default:
 			if !tk.IsUsedVariable() {
 				err := &unknownConfigFieldErr{
@@ -4579,6 +4684,11 @@ func setBaselineOptions(opts *Options) {
 			opts.MQTT.TLSTimeout = float64(TLS_TIMEOUT) / float64(time.Second)
 		}
 	}
+	if opts.Kafka.Port != 0 {
+		if opts.Kafka.TLSTimeout == 0 {
+			opts.Kafka.TLSTimeout = float64(TLS_TIMEOUT) / float64(time.Second)
+		}
+	}
 	// JetStream
 	if opts.JetStreamMaxMemory == 0 && !opts.maxMemSet {
 		opts.JetStreamMaxMemory = -1
@@ -944,7 +944,7 @@ func imposeOrder(value interface{}) error {
 		sort.Strings(value.AllowedOrigins)
 	case string, bool, uint8, int, int32, int64, time.Duration, float64, nil, LeafNodeOpts, ClusterOpts, *tls.Config, PinnedCertSet,
 		*URLAccResolver, *MemAccResolver, *DirAccResolver, *CacheDirAccResolver, Authentication, MQTTOpts, jwt.TagList,
-		*OCSPConfig, map[string]string, JSLimitOpts:
+		*OCSPConfig, map[string]string, JSLimitOpts, KafkaOpts:
 		// explicitly skipped types
 	default:
 		// this will fail during unit tests
@@ -1304,6 +1304,16 @@ func (s *Server) diffOptions(newOpts *Options) ([]option, error) {
 			tmpNew.ConsumerReplicas = newValue.(MQTTOpts).ConsumerReplicas
 			tmpNew.ConsumerMemoryStorage = newValue.(MQTTOpts).ConsumerMemoryStorage
 			tmpNew.ConsumerInactiveThreshold = newValue.(MQTTOpts).ConsumerInactiveThreshold
+		case "kafka":
+			// The Kafka listener can not be changed without a restart, the TLS
+			// configuration is picked up by new connections.
+			tmpOld := oldValue.(KafkaOpts)
+			tmpNew := newValue.(KafkaOpts)
+			tmpOld.TLSConfig, tmpNew.TLSConfig = nil, nil
+			if !reflect.DeepEqual(tmpOld, tmpNew) {
+				return nil, fmt.Errorf("config reload not supported for %s: old=%v, new=%v",
+					field.Name, oldValue, newValue)
+			}
 		case "connecterrorreports":
 			diffOpts = append(diffOpts, &connectErrorReports{newValue: newValue.(int)})
 		case "reconnecterrorreports":
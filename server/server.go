@@ -243,6 +243,9 @@ type Server struct {
 	// MQTT structure
 	mqtt srvMQTT
 
+	// Kafka structure
+	kafka srvKafka
+
 	// OCSP monitoring
 	ocsps []*OCSPMonitor
 
@@ -713,6 +716,9 @@ func validateOptions(o *Options) error {
 	if err := validateMQTTOptions(o); err != nil {
 		return err
 	}
+	if err := validateKafkaOptions(o); err != nil {
+		return err
+	}
 	if err := validateJetStreamOptions(o); err != nil {
 		return err
 	}
@@ -1834,6 +1840,11 @@ func (s *Server) Start() {
 		s.startMQTT()
 	}
 
+	// Kafka
+	if opts.Kafka.Port != 0 {
+		s.startKafka()
+	}
+
 	// Start up routing as well if needed.
 	if opts.Cluster.Port != 0 {
 		s.startGoRoutine(func() {
@@ -1954,6 +1965,14 @@ func (s *Server) Shutdown() {
 		s.mqtt.listener = nil
 	}
 
+	// Kick Kafka accept loop
+	if s.kafka.listener != nil {
+		doneExpected++
+		s.kafka.listener.Close()
+		s.kafka.listener = nil
+		s.closeKafkaConns()
+	}
+
 	// Kick leafnodes AcceptLoop()
 	if s.leafNodeListener != nil {
 		doneExpected++
@@ -34,6 +34,18 @@ const (
 	AnyStorage = StorageType(44)
 )
 
+// StoreCompression determines how sealed message blocks are compressed on disk.
+type StoreCompression int
+
+const (
+	// NoCompression stores message blocks as they are written.
+	NoCompression StoreCompression = iota
+	// S2Compression compresses sealed message blocks with S2.
+	S2Compression
+	// ZstdCompression compresses sealed message blocks with zstd.
+	ZstdCompression
+)
+
 var (
 	// ErrStoreClosed is returned when the store has been closed
 	ErrStoreClosed = errors.New("store is closed")
@@ -148,6 +160,15 @@ type StreamState struct {
 	Consumers   int               `json:"consumer_count"`
 }
 
+// CompressionStats reports how well the message blocks of a stream compress on disk.
+type CompressionStats struct {
+	Algorithm        StoreCompression `json:"algorithm"`
+	CompressedBlocks int              `json:"compressed_blocks"`
+	RawBytes         uint64           `json:"raw_bytes"`
+	StoredBytes      uint64           `json:"stored_bytes"`
+	Ratio            float64          `json:"ratio"`
+}
+
 // SimpleState for filtered subject specific state.
 type SimpleState struct {
 	Msgs  uint64 `json:"messages"`
@@ -408,6 +429,52 @@ func (st *StorageType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+const (
+	noCompressionString   = "none"
+	s2CompressionString   = "s2"
+	zstdCompressionString = "zstd"
+)
+
+func (sc StoreCompression) String() string {
+	switch sc {
+	case NoCompression:
+		return "None"
+	case S2Compression:
+		return "S2"
+	case ZstdCompression:
+		return "Zstd"
+	default:
+		return "Unknown Store Compression"
+	}
+}
+
+func (sc StoreCompression) MarshalJSON() ([]byte, error) {
+	switch sc {
+	case NoCompression:
+		return json.Marshal(noCompressionString)
+	case S2Compression:
+		return json.Marshal(s2CompressionString)
+	case ZstdCompression:
+		return json.Marshal(zstdCompressionString)
+	default:
+		return nil, fmt.Errorf("can not marshal %v", sc)
+	}
+}
+
+func (sc *StoreCompression) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case jsonString(noCompressionString), jsonString(_EMPTY_):
+		*sc = NoCompression
+	case jsonString(s2CompressionString):
+		*sc = S2Compression
+	case jsonString(zstdCompressionString):
+		*sc = ZstdCompression
+	default:
+		return fmt.Errorf("can not unmarshal %q", data)
+	}
+	return nil
+}
+
 const (
 	ackNonePolicyString     = "none"
 	ackAllPolicyString      = "all"
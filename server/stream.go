@@ -37,25 +37,26 @@ import (
 // StreamConfig will determine the name, subjects and retention policy
 // for a given stream. If subjects is empty the name will be used.
 type StreamConfig struct {
-	Name         string          `json:"name"`
-	Description  string          `json:"description,omitempty"`
-	Subjects     []string        `json:"subjects,omitempty"`
-	Retention    RetentionPolicy `json:"retention"`
-	MaxConsumers int             `json:"max_consumers"`
-	MaxMsgs      int64           `json:"max_msgs"`
-	MaxBytes     int64           `json:"max_bytes"`
-	MaxAge       time.Duration   `json:"max_age"`
-	MaxMsgsPer   int64           `json:"max_msgs_per_subject"`
-	MaxMsgSize   int32           `json:"max_msg_size,omitempty"`
-	Discard      DiscardPolicy   `json:"discard"`
-	Storage      StorageType     `json:"storage"`
-	Replicas     int             `json:"num_replicas"`
-	NoAck        bool            `json:"no_ack,omitempty"`
-	Template     string          `json:"template_owner,omitempty"`
-	Duplicates   time.Duration   `json:"duplicate_window,omitempty"`
-	Placement    *Placement      `json:"placement,omitempty"`
-	Mirror       *StreamSource   `json:"mirror,omitempty"`
-	Sources      []*StreamSource `json:"sources,omitempty"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description,omitempty"`
+	Subjects     []string         `json:"subjects,omitempty"`
+	Retention    RetentionPolicy  `json:"retention"`
+	MaxConsumers int              `json:"max_consumers"`
+	MaxMsgs      int64            `json:"max_msgs"`
+	MaxBytes     int64            `json:"max_bytes"`
+	MaxAge       time.Duration    `json:"max_age"`
+	MaxMsgsPer   int64            `json:"max_msgs_per_subject"`
+	MaxMsgSize   int32            `json:"max_msg_size,omitempty"`
+	Discard      DiscardPolicy    `json:"discard"`
+	Storage      StorageType      `json:"storage"`
+	Compression  StoreCompression `json:"compression,omitempty"`
+	Replicas     int              `json:"num_replicas"`
+	NoAck        bool             `json:"no_ack,omitempty"`
+	Template     string           `json:"template_owner,omitempty"`
+	Duplicates   time.Duration    `json:"duplicate_window,omitempty"`
+	Placement    *Placement       `json:"placement,omitempty"`
+	Mirror       *StreamSource    `json:"mirror,omitempty"`
+	Sources      []*StreamSource  `json:"sources,omitempty"`
 
 	// Allow republish of the message after being sequenced and stored.
 	RePublish *RePublish `json:"republish,omitempty"`
@@ -111,14 +112,15 @@ type PubAck struct {
 
 // StreamInfo shows config and current state for this stream.
 type StreamInfo struct {
-	Config     StreamConfig        `json:"config"`
-	Created    time.Time           `json:"created"`
-	State      StreamState         `json:"state"`
-	Domain     string              `json:"domain,omitempty"`
-	Cluster    *ClusterInfo        `json:"cluster,omitempty"`
-	Mirror     *StreamSourceInfo   `json:"mirror,omitempty"`
-	Sources    []*StreamSourceInfo `json:"sources,omitempty"`
-	Alternates []StreamAlternate   `json:"alternates,omitempty"`
+	Config      StreamConfig        `json:"config"`
+	Created     time.Time           `json:"created"`
+	State       StreamState         `json:"state"`
+	Domain      string              `json:"domain,omitempty"`
+	Cluster     *ClusterInfo        `json:"cluster,omitempty"`
+	Mirror      *StreamSourceInfo   `json:"mirror,omitempty"`
+	Sources     []*StreamSourceInfo `json:"sources,omitempty"`
+	Alternates  []StreamAlternate   `json:"alternates,omitempty"`
+	Compression *CompressionStats   `json:"compression,omitempty"`
 }
 
 type StreamAlternate struct {
@@ -236,6 +238,9 @@ type stream struct {
 
 	// Direct get subscription.
 	directSub *subscription
+
+	// Messages waiting for remote claim checks to be validated.
+	claimChecks claimCheckQueue
 }
 
 type sourceInfo struct {
@@ -475,6 +480,11 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 		return nil, NewJSStreamStoreFailedError(err)
 	}
 
+	// Finish a station key rotation that was interrupted by a restart.
+	if s.streamPreviousKeyGen(a.Name, cfg.Name) != nil {
+		go s.resumeStationKeyRotation(a.Name, cfg.Name)
+	}
+
 	// Create our pubAck template here. Better than json marshal each time on success.
 	if domain := s.getOpts().JetStreamDomain; domain != _EMPTY_ {
 		mset.pubAck = []byte(fmt.Sprintf("{%q:%q, %q:%q, %q:", "stream", cfg.Name, "domain", domain, "seq"))
@@ -1193,6 +1203,17 @@ func (mset *stream) config() StreamConfig {
 	return mset.cfg
 }
 
+// compressionStats returns nil for streams that are not stored on disk.
+func (mset *stream) compressionStats() *CompressionStats {
+	mset.mu.RLock()
+	fs, ok := mset.store.(*fileStore)
+	mset.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return fs.compressionStats()
+}
+
 func (mset *stream) fileStoreConfig() (FileStoreConfig, error) {
 	mset.mu.Lock()
 	defer mset.mu.Unlock()
@@ -1245,8 +1266,8 @@ func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server) (*Str
 	if !cfg.DenyPurge && old.DenyPurge {
 		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not cancel deny purge"))
 	}
-	// Check for mirror changes which are not allowed.
-	if !reflect.DeepEqual(cfg.Mirror, old.Mirror) {
+	// Check for mirror changes which are not allowed, removing the mirror promotes it to a regular stream.
+	if !reflect.DeepEqual(cfg.Mirror, old.Mirror) && !isMirrorPromotion(old, &cfg) {
 		return nil, NewJSStreamMirrorNotUpdatableError()
 	}
 
@@ -1336,6 +1357,11 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool)
 	}
 
 	mset.mu.Lock()
+	// A promoted mirror stops following its origin before subscribing to its own subjects.
+	if isMirrorPromotion(&ocfg, cfg) && mset.mirror != nil {
+		mset.cancelMirrorConsumer()
+		mset.mirror = nil
+	}
 	if mset.isLeader() {
 		// Now check for subject interest differences.
 		current := make(map[string]struct{}, len(ocfg.Subjects))
@@ -1540,6 +1566,11 @@ func (mset *stream) eraseMsg(seq uint64) (bool, error) {
 	return mset.store.EraseMsg(seq)
 }
 
+// isMirrorPromotion returns true when an update turns a mirror into a regular stream.
+func isMirrorPromotion(old, cfg *StreamConfig) bool {
+	return old.Mirror != nil && cfg.Mirror == nil
+}
+
 // Are we a mirror?
 func (mset *stream) isMirror() bool {
 	mset.mu.RLock()
@@ -3071,7 +3102,7 @@ func (mset *stream) setupStore(fsCfg *FileStoreConfig) error {
 		mset.store = ms
 	case FileStorage:
 		s := mset.srv
-		fs, err := newFileStoreWithCreated(*fsCfg, mset.cfg, mset.created, s.jsKeyGen(mset.acc.Name))
+		fs, err := newFileStoreWithKeys(*fsCfg, mset.cfg, mset.created, s.streamKeyGen(mset.acc.Name, mset.cfg.Name), s.streamPreviousKeyGen(mset.acc.Name, mset.cfg.Name))
 		if err != nil {
 			mset.mu.Unlock()
 			return err
@@ -3376,6 +3407,8 @@ func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
 func (mset *stream) processInboundJetStreamMsg(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	mset.mu.RLock()
 	isLeader, isClustered, isSealed := mset.isLeader(), mset.isClustered(), mset.cfg.Sealed
+	s, acc, name := mset.srv, mset.acc, mset.cfg.Name
+	isCompacted := isCompactedStream(&mset.cfg)
 	mset.mu.RUnlock()
 
 	// If we are not the leader just ignore.
@@ -3395,6 +3428,46 @@ func (mset *stream) processInboundJetStreamMsg(_ *subscription, c *client, _ *Ac
 
 	hdr, msg := c.msgParts(rmsg)
 
+	// Messages produced into a partitioned station are stored under the subject of their partition.
+	subject = partitionedSubject(acc, name, subject, hdr)
+
+	// Messages produced into a compacted station are stored under the subject of their key.
+	if isCompacted {
+		var err error
+		if subject, hdr, err = compactedSubject(name, subject, hdr); err != nil {
+			mset.sendPubAckError(reply, 400, err)
+			return
+		}
+	}
+
+	// Claim checks of large payloads are stored only once all of their chunks have been uploaded.
+	var remoteClaimCheck bool
+	if len(hdr) > 0 {
+		if err := validateClaimCheck(s, acc, name, hdr); err == errClaimCheckNotLocal {
+			remoteClaimCheck = true
+		} else if err != nil {
+			mset.sendPubAckError(reply, 400, err)
+			return
+		}
+	}
+
+	// Publish rate limits are enforced only on messages coming directly from clients.
+	if c.kind == CLIENT {
+		if err := s.checkPublishRateLimits(c, acc, name, hdr, len(hdr)+len(msg)); err != nil {
+			mset.sendPubAckError(reply, 429, err)
+			return
+		}
+	}
+
+	// The chunks of a claim check whose objects stream is placed on other servers are counted by the leader
+	// of the objects stream, so the claim check and the messages published after it wait in order meanwhile.
+	if queued, err := mset.queueClaimCheckMsg(subject, reply, hdr, msg, remoteClaimCheck); queued {
+		if err != nil {
+			mset.sendPubAckError(reply, 503, err)
+		}
+		return
+	}
+
 	// If we are not receiving directly from a client we should move this to another Go routine.
 	if c.kind != CLIENT {
 		mset.queueInboundMsg(subject, reply, hdr, msg)
@@ -3409,6 +3482,19 @@ func (mset *stream) processInboundJetStreamMsg(_ *subscription, c *client, _ *Ac
 	}
 }
 
+// sendPubAckError responds to a publish that was rejected before being stored.
+func (mset *stream) sendPubAckError(reply string, code int, err error) {
+	if reply == _EMPTY_ {
+		return
+	}
+	var resp = JSPubAckResponse{
+		PubAck: &PubAck{Stream: mset.name()},
+		Error:  &ApiError{Code: code, Description: err.Error()},
+	}
+	b, _ := json.Marshal(resp)
+	mset.outq.sendMsg(reply, b)
+}
+
 var (
 	errLastSeqMismatch = errors.New("last sequence mismatch")
 	errMsgIdDuplicate  = errors.New("msgid is duplicate")